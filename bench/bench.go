@@ -0,0 +1,57 @@
+// Package bench contains the benchmarks of rendering templates against large
+// data sets, and the generators of that data. The data is generated the same
+// way on every run, so results can be compared between revisions with
+// benchstat:
+//
+//	go test -run='^$' -bench=. -benchmem -count=10 ./bench/ > old.txt
+//
+// Nothing in this package is used by Consul Template itself.
+package bench
+
+import (
+	"fmt"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// KVTree returns n keys under the given prefix, spread over nested folders of
+// at most 10 keys each, as returned by a tree query for the prefix.
+func KVTree(prefix string, n int) []*dep.KeyPair {
+	pairs := make([]*dep.KeyPair, 0, n)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("%d/%d/key%d", i/100, i/10%10, i)
+		pairs = append(pairs, &dep.KeyPair{
+			Path:        prefix + "/" + key,
+			Key:         key,
+			Value:       fmt.Sprintf("value-%d", i),
+			CreateIndex: uint64(i + 1),
+			ModifyIndex: uint64(i + 1),
+		})
+	}
+	return pairs
+}
+
+// HealthServices returns n healthy instances of the named service, running on
+// n/4 nodes, as returned by a health service query for it. Each instance has
+// one of four tags.
+func HealthServices(name string, n int) []*dep.HealthService {
+	tags := []string{"primary", "secondary", "canary", "legacy"}
+
+	services := make([]*dep.HealthService, 0, n)
+	for i := 0; i < n; i++ {
+		node := i / 4
+		services = append(services, &dep.HealthService{
+			Node:        fmt.Sprintf("node-%d", node),
+			NodeID:      fmt.Sprintf("00000000-0000-0000-0000-%012d", node),
+			NodeAddress: fmt.Sprintf("10.0.%d.%d", node/256, node%256),
+			NodeMeta:    map[string]string{"rack": fmt.Sprintf("rack-%d", node%8)},
+			Address:     fmt.Sprintf("10.0.%d.%d", node/256, node%256),
+			ID:          fmt.Sprintf("%s-%d", name, i),
+			Name:        name,
+			Tags:        dep.ServiceTags{tags[i%len(tags)]},
+			Status:      "passing",
+			Port:        8000 + i%4,
+		})
+	}
+	return services
+}
@@ -0,0 +1,164 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/manager"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// benchmarkExecute executes the contents against the brain b.N times.
+func benchmarkExecute(b *testing.B, contents string, brain *template.Brain) {
+	tmpl, err := template.NewTemplate(&template.NewTemplateInput{
+		Contents: contents,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// Check the template renders before timing it.
+	result, err := tmpl.Execute(&template.ExecuteInput{Brain: brain})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if result.Missing.Len() > 0 {
+		b.Fatalf("missing data for %s", result.Missing.String())
+	}
+	b.SetBytes(int64(len(result.Output)))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tmpl.Execute(&template.ExecuteInput{Brain: brain}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTemplate_kvTree(b *testing.B) {
+	cases := []struct {
+		name     string
+		contents string
+	}{
+		{
+			"range",
+			`{{ range tree "app" }}{{ .Key }}={{ .Value }}
+{{ end }}`,
+		},
+		{
+			"explode",
+			`{{ tree "app" | explode | toJSON }}`,
+		},
+	}
+
+	for _, keys := range []int{1000, 10000} {
+		d, err := dep.NewKVListQuery("app")
+		if err != nil {
+			b.Fatal(err)
+		}
+		brain := template.NewBrain()
+		brain.Remember(d, KVTree("app", keys))
+
+		for _, tc := range cases {
+			b.Run(fmt.Sprintf("%s_%d", tc.name, keys), func(b *testing.B) {
+				benchmarkExecute(b, tc.contents, brain)
+			})
+		}
+	}
+}
+
+func BenchmarkTemplate_services(b *testing.B) {
+	d, err := dep.NewHealthServiceQuery("web")
+	if err != nil {
+		b.Fatal(err)
+	}
+	brain := template.NewBrain()
+	brain.Remember(d, HealthServices("web", 1000))
+
+	cases := []struct {
+		name     string
+		contents string
+	}{
+		{
+			"range",
+			`{{ range service "web" }}server {{ .Node }} {{ .Address }}:{{ .Port }}
+{{ end }}`,
+		},
+		{
+			"byTag",
+			`{{ range $tag, $services := service "web" | byTag }}{{ $tag }}:{{ range $services }} {{ .ID }}{{ end }}
+{{ end }}`,
+		},
+	}
+
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			benchmarkExecute(b, tc.contents, brain)
+		})
+	}
+}
+
+// BenchmarkRunner_templates renders 100 templates from a local file in once
+// mode, from the creation of the runner until it stops.
+func BenchmarkRunner_templates(b *testing.B) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	values := make(map[string]string, 100)
+	for i := 0; i < 100; i++ {
+		values[fmt.Sprintf("key%d", i)] = fmt.Sprintf("value-%d", i)
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		b.Fatal(err)
+	}
+	source := filepath.Join(dir, "data.json")
+	if err := ioutil.WriteFile(source, data, 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	templates := make(config.TemplateConfigs, 0, 100)
+	for i := 0; i < 100; i++ {
+		templates = append(templates, &config.TemplateConfig{
+			Contents: config.String(fmt.Sprintf(
+				`{{ range $k, $v := file %q | parseJSON }}{{ $k }}=%d-{{ $v }}
+{{ end }}`, source, i)),
+			Destination: config.String(filepath.Join(dir, fmt.Sprintf("out%d", i))),
+		})
+	}
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Templates: &templates,
+	})
+	c.Finalize()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r, err := manager.NewRunner(c, false, true)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		go r.Start()
+		select {
+		case err := <-r.ErrCh:
+			b.Fatal(err)
+		case <-r.DoneCh:
+		}
+	}
+}
@@ -3,6 +3,7 @@ package child
 import (
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"log"
 	"math/rand"
@@ -45,7 +46,11 @@ type Child struct {
 	killSignal  os.Signal
 	killTimeout time.Duration
 
-	splay time.Duration
+	splay     time.Duration
+	splayMin  time.Duration
+	splaySeed string
+
+	sandbox *Sandbox
 
 	// cmd is the actual child process under management.
 	cmd *exec.Cmd
@@ -59,6 +64,11 @@ type Child struct {
 	stopLock sync.RWMutex
 	stopCh   chan struct{}
 	stopped  bool
+
+	// cancelCh is closed to interrupt the wait of Start for a command with a
+	// timeout, which Stop cannot do since Start holds the lock.
+	cancelCh   chan struct{}
+	cancelOnce sync.Once
 }
 
 // NewInput is input to the NewChild function.
@@ -102,6 +112,18 @@ type NewInput struct {
 	// prevents multiple processes from all signaling at the same time. This value
 	// may be zero (which disables the splay entirely).
 	Splay time.Duration
+
+	// SplayMin is the minimum amount of time to wait before sending signals,
+	// so the splay is between SplayMin and Splay.
+	SplayMin time.Duration
+
+	// SplaySeed makes the splay deterministic. If it is not empty, the amount of
+	// time to wait is derived from the seed instead of chosen randomly, so the
+	// same seed always waits the same amount of time.
+	SplaySeed string
+
+	// Sandbox confines the child process. This value may be nil.
+	Sandbox *Sandbox
 }
 
 // New creates a new child process for management with high-level APIs for
@@ -128,7 +150,11 @@ func New(i *NewInput) (*Child, error) {
 		killSignal:   i.KillSignal,
 		killTimeout:  i.KillTimeout,
 		splay:        i.Splay,
+		splayMin:     i.SplayMin,
+		splaySeed:    i.SplaySeed,
+		sandbox:      i.Sandbox,
 		stopCh:       make(chan struct{}, 1),
+		cancelCh:     make(chan struct{}),
 	}
 
 	return child, nil
@@ -220,6 +246,16 @@ func (c *Child) Kill() {
 	c.kill()
 }
 
+// Cancel interrupts Start while it waits for a command which was given a
+// timeout, killing the process like Kill does, so Start returns an error
+// before the timeout. It does not wait for Start to return. It has no effect
+// on processes Start does not wait for, which Stop terminates.
+func (c *Child) Cancel() {
+	c.cancelOnce.Do(func() {
+		close(c.cancelCh)
+	})
+}
+
 // Stop behaves almost identical to Kill except it supresses future processes
 // from being started by this child and it prevents the killing of the child
 // process from sending its value back up the exit channel. This is useful
@@ -247,7 +283,12 @@ func (c *Child) start() error {
 	cmd.Stdout = c.stdout
 	cmd.Stderr = c.stderr
 	cmd.Env = c.env
-	if err := cmd.Start(); err != nil {
+	if c.sandbox != nil {
+		if err := c.sandbox.wrap(cmd); err != nil {
+			return err
+		}
+	}
+	if err := StartCommand(cmd); err != nil {
 		return err
 	}
 	c.cmd = cmd
@@ -257,7 +298,7 @@ func (c *Child) start() error {
 	exitCh := make(chan int, 1)
 	go func() {
 		var code int
-		err := cmd.Wait()
+		err := WaitCommand(cmd)
 		if err == nil {
 			code = ExitCodeOK
 		} else {
@@ -300,6 +341,16 @@ func (c *Child) start() error {
 					c.Command(),
 				)
 			}
+		case <-c.cancelCh:
+			log.Printf("[INFO] (child) cancelling process")
+			c.kill()
+
+			return fmt.Errorf(
+				"command was cancelled before it exited:\n"+
+					"\n"+
+					"    %s",
+				c.Command(),
+			)
 		case <-time.After(c.timeout):
 			// Force-kill the process
 			c.stopLock.Lock()
@@ -391,15 +442,34 @@ func (c *Child) running() bool {
 }
 
 func (c *Child) randomSplay() <-chan time.Time {
-	if c.splay == 0 {
+	if c.splay == 0 && c.splayMin == 0 {
 		return time.After(0)
 	}
 
-	ns := c.splay.Nanoseconds()
-	offset := rand.Int63n(ns)
-	t := time.Duration(offset)
+	t := splayDuration(c.splayMin, c.splay, c.splaySeed)
 
-	log.Printf("[DEBUG] (child) waiting %.2fs for random splay", t.Seconds())
+	log.Printf("[DEBUG] (child) waiting %.2fs for splay", t.Seconds())
 
 	return time.After(t)
 }
+
+// splayDuration returns the amount of time to wait between min and max, or
+// min if max is not greater. If a seed is given, the duration is derived from a
+// hash of the seed instead of chosen randomly.
+func splayDuration(min, max time.Duration, seed string) time.Duration {
+	if max <= min {
+		return min
+	}
+
+	ns := int64(max - min)
+	var offset int64
+	if seed != "" {
+		h := fnv.New64a()
+		h.Write([]byte(seed))
+		offset = int64(h.Sum64() % uint64(ns))
+	} else {
+		offset = rand.Int63n(ns)
+	}
+
+	return min + time.Duration(offset)
+}
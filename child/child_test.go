@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -105,6 +106,54 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestSplayDuration(t *testing.T) {
+	t.Parallel()
+
+	min, max := 5*time.Second, 30*time.Second
+
+	seeded := splayDuration(min, max, "host-a")
+	if seeded < min || seeded >= max {
+		t.Errorf("expected %s to be in [%s, %s)", seeded, min, max)
+	}
+	for i := 0; i < 10; i++ {
+		if d := splayDuration(min, max, "host-a"); d != seeded {
+			t.Fatalf("expected seeded splay to be stable, got %s and %s", seeded, d)
+		}
+	}
+	if d := splayDuration(min, max, "host-b"); d == seeded {
+		t.Errorf("expected different seeds to give different splays, got %s", d)
+	}
+
+	for i := 0; i < 10; i++ {
+		if d := splayDuration(min, max, ""); d < min || d >= max {
+			t.Errorf("expected %s to be in [%s, %s)", d, min, max)
+		}
+	}
+
+	if d := splayDuration(max, min, "host-a"); d != max {
+		t.Errorf("expected min above max to wait %s, got %s", max, d)
+	}
+}
+
+func TestChild_randomSplay(t *testing.T) {
+	t.Parallel()
+
+	c := &Child{}
+	select {
+	case <-c.randomSplay():
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("expected no splay to not wait")
+	}
+
+	// Without a splay, the minimum is still waited.
+	c = &Child{splayMin: 100 * time.Millisecond}
+	start := time.Now()
+	<-c.randomSplay()
+	if d := time.Since(start); d < c.splayMin {
+		t.Errorf("expected to wait at least %s, waited %s", c.splayMin, d)
+	}
+}
+
 func TestNew_errMissingCommand(t *testing.T) {
 	t.Parallel()
 
@@ -394,3 +443,37 @@ func TestKill_noProcess(t *testing.T) {
 	c.killSignal = syscall.SIGUSR1
 	c.Kill()
 }
+
+func TestCancel(t *testing.T) {
+	t.Parallel()
+
+	c := testChild(t)
+	c.command = "bash"
+	c.args = []string{"-c", "while true; do sleep 0.2; done"}
+	c.timeout = 10 * time.Second
+	c.killTimeout = 20 * time.Millisecond
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.Start()
+	}()
+
+	// For some reason bash doesn't start immediately
+	time.Sleep(fileWaitSleepDelay)
+
+	c.Cancel()
+	c.Cancel()
+
+	select {
+	case err := <-errCh:
+		if err == nil || !strings.Contains(err.Error(), "cancelled") {
+			t.Errorf("expected a cancellation error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("start was not cancelled")
+	}
+
+	if c.cmd != nil {
+		t.Errorf("expected cmd to be nil")
+	}
+}
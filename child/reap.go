@@ -0,0 +1,63 @@
+package child
+
+import (
+	"os/exec"
+	"sync"
+)
+
+var (
+	// reapLock is held for reading while a command is started and for writing
+	// while orphans are reaped, so the reaper never sees the process of a
+	// command before it is tracked.
+	reapLock sync.RWMutex
+
+	// tracked is the set of pids of the commands started by this process. They
+	// are waited for by their own exec.Cmd, so the reaper must leave them be.
+	// trackedLock guards it, since commands are started concurrently.
+	tracked     = make(map[int]struct{})
+	trackedLock sync.Mutex
+)
+
+// StartCommand starts the command and tracks its process until WaitCommand
+// returns, so it is not reaped from under its exec.Cmd when orphaned processes
+// are reaped. Commands run by this process must be started with it.
+func StartCommand(cmd *exec.Cmd) error {
+	reapLock.RLock()
+	defer reapLock.RUnlock()
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	trackedLock.Lock()
+	tracked[cmd.Process.Pid] = struct{}{}
+	trackedLock.Unlock()
+	return nil
+}
+
+// WaitCommand waits for a command started with StartCommand to exit and stops
+// tracking its process.
+func WaitCommand(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+
+	trackedLock.Lock()
+	delete(tracked, cmd.Process.Pid)
+	trackedLock.Unlock()
+	return err
+}
+
+// RunCommand starts the command with StartCommand and waits for it to exit.
+func RunCommand(cmd *exec.Cmd) error {
+	if err := StartCommand(cmd); err != nil {
+		return err
+	}
+	return WaitCommand(cmd)
+}
+
+// isTracked returns true if the pid is the process of a started command.
+func isTracked(pid int) bool {
+	trackedLock.Lock()
+	defer trackedLock.Unlock()
+	_, ok := tracked[pid]
+	return ok
+}
@@ -0,0 +1,127 @@
+// +build linux
+
+package child
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+)
+
+// prSetChildSubreaper is the prctl option which makes orphaned descendants of
+// the process reparent to it instead of to init.
+const prSetChildSubreaper = 36
+
+// ReapOrphans makes this process the reaper of its orphaned descendants, such
+// as the daemons left behind by the processes it runs, and reaps each of them
+// when it exits until the stop channel is closed. This is the job of init, so
+// it is needed when running as PID 1 in a container, where orphans would
+// otherwise pile up as zombies.
+func ReapOrphans(stopCh <-chan struct{}) error {
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL,
+		prSetChildSubreaper, 1, 0, 0, 0, 0); errno != 0 {
+		return fmt.Errorf("reap: failed becoming a subreaper: %s", errno)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGCHLD)
+
+	go func() {
+		defer signal.Stop(sigCh)
+		for {
+			reapOrphans()
+
+			select {
+			case <-sigCh:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// reapOrphans reaps the exited children of this process which are not the
+// process of a started command, and returns how many were reaped.
+func reapOrphans() int {
+	reapLock.Lock()
+	defer reapLock.Unlock()
+
+	pids, err := zombies(os.Getpid())
+	if err != nil {
+		log.Printf("[WARN] (child) listing exited processes: %s", err)
+		return 0
+	}
+
+	var n int
+	for _, pid := range pids {
+		if isTracked(pid) {
+			continue
+		}
+
+		var status syscall.WaitStatus
+		wpid, err := syscall.Wait4(pid, &status, syscall.WNOHANG, nil)
+		if err != nil || wpid != pid {
+			continue
+		}
+		log.Printf("[DEBUG] (child) reaped orphaned process %d (exit status %d)",
+			pid, status.ExitStatus())
+		n++
+	}
+	return n
+}
+
+// zombies returns the pids of the exited, but not yet reaped, children of the
+// given process.
+func zombies(ppid int) ([]int, error) {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+
+		// The process may be gone by now.
+		stat, err := ioutil.ReadFile("/proc/" + e.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+
+		state, parent, ok := parseStat(stat)
+		if ok && state == 'Z' && parent == ppid {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// parseStat returns the state and parent pid of a process from the contents of
+// its /proc/<pid>/stat file. The command name is skipped from its last closing
+// parenthesis, since it may contain spaces and parentheses itself.
+func parseStat(stat []byte) (byte, int, bool) {
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, 0, false
+	}
+
+	fields := bytes.Fields(stat[i+1:])
+	if len(fields) < 2 || len(fields[0]) != 1 {
+		return 0, 0, false
+	}
+
+	ppid, err := strconv.Atoi(string(fields[1]))
+	if err != nil {
+		return 0, 0, false
+	}
+	return fields[0][0], ppid, true
+}
@@ -0,0 +1,115 @@
+// +build linux
+
+package child
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseStat(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		stat  string
+		state byte
+		ppid  int
+		ok    bool
+	}{
+		{
+			"running",
+			"42 (sleep) S 1 42 42 0 -1 4194560",
+			'S',
+			1,
+			true,
+		},
+		{
+			"zombie",
+			"43 (sh) Z 7 43 43 0 -1 4227084",
+			'Z',
+			7,
+			true,
+		},
+		{
+			"name_with_parens",
+			"44 (a) Z (b) R 9 44 44 0",
+			'R',
+			9,
+			true,
+		},
+		{
+			"truncated",
+			"45 (sh) Z",
+			0,
+			0,
+			false,
+		},
+		{
+			"garbage",
+			"garbage",
+			0,
+			0,
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			state, ppid, ok := parseStat([]byte(tc.stat))
+			if ok != tc.ok {
+				t.Fatalf("expected ok to be %t", tc.ok)
+			}
+			if state != tc.state || ppid != tc.ppid {
+				t.Errorf("expected %q %d to be %q %d", state, ppid, tc.state, tc.ppid)
+			}
+		})
+	}
+}
+
+func TestReapOrphans(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	if err := ReapOrphans(stopCh); err != nil {
+		t.Fatal(err)
+	}
+
+	// The shell exits before its background process, which is orphaned and
+	// reparented to this process.
+	var out bytes.Buffer
+	cmd := exec.Command("sh", "-c", "sleep 0.5 >/dev/null & echo $!")
+	cmd.Stdout = &out
+	if err := RunCommand(cmd); err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(string(bytes.TrimSpace(out.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once orphaned, it is a child of this process, so only this process can
+	// reap it.
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ppid, _ := parseStat(stat); ppid != os.Getpid() {
+		t.Fatalf("expected %d to be reparented to %d, got %d", pid, os.Getpid(), ppid)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(fmt.Sprintf("/proc/%d", pid)); os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("expected orphaned process %d to be reaped", pid)
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package child
+
+// ReapOrphans is a no-op on platforms other than Linux, where reaping orphaned
+// processes is not supported.
+func ReapOrphans(stopCh <-chan struct{}) error {
+	return nil
+}
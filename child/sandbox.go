@@ -0,0 +1,55 @@
+package child
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// sandboxEnv is the environment variable which holds the sandbox of a
+// command while this binary confines itself before running the command.
+const sandboxEnv = "CONSUL_TEMPLATE_COMMAND_SANDBOX"
+
+// Sandbox confines a child process. It is only supported on Linux, where the
+// child first runs this binary again, which confines itself in new namespaces
+// and then replaces itself with the command. This requires the binary to call
+// InitSandbox on startup.
+type Sandbox struct {
+	// NoNetwork runs the command in its own network namespace, which has no
+	// interfaces, not even loopback.
+	NoNetwork bool `json:"no_network"`
+
+	// NoNewPrivs prevents the command from gaining privileges, such as through
+	// setuid binaries.
+	NoNewPrivs bool `json:"no_new_privs"`
+
+	// ReadOnlyPaths are the paths which are mounted read-only, along with all
+	// mounts beneath them, in the mount namespace of the command.
+	ReadOnlyPaths []string `json:"read_only_paths"`
+}
+
+// sandboxSpec is what the sandbox of a command is given in sandboxEnv.
+type sandboxSpec struct {
+	Sandbox
+
+	// Path is the resolved path of the command.
+	Path string `json:"path"`
+}
+
+// InitSandbox confines this process and replaces it with the command when it
+// was started as the sandbox of a command, and does nothing otherwise. It must
+// be called at the very start of main, before anything else happens.
+func InitSandbox() {
+	spec, ok := os.LookupEnv(sandboxEnv)
+	if !ok {
+		return
+	}
+
+	// The confinement which is not applied to the whole process must be
+	// applied to the thread which runs the command.
+	runtime.LockOSThread()
+
+	err := execSandbox(spec)
+	fmt.Fprintf(os.Stderr, "command sandbox: %s\n", err)
+	os.Exit(ExitCodeError)
+}
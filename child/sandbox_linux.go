@@ -0,0 +1,220 @@
+// +build linux
+
+package child
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// capSysAdmin is the capability to mount, which the sandbox keeps in its
+	// user namespace until it runs the command.
+	capSysAdmin = 21
+
+	prSetNoNewPrivs      = 38
+	prCapAmbient         = 47
+	prCapAmbientClearAll = 4
+
+	// sandboxExe is this binary, which the child runs first to confine itself.
+	sandboxExe = "/proc/self/exe"
+)
+
+// mountLockedFlags maps the statfs flags of a mount to the mount flags which
+// cannot be cleared from a less privileged mount namespace, and so must be
+// kept when remounting it read-only.
+var mountLockedFlags = map[uint64]uintptr{
+	2:    syscall.MS_NOSUID,
+	4:    syscall.MS_NODEV,
+	8:    syscall.MS_NOEXEC,
+	1024: syscall.MS_NOATIME,
+	2048: syscall.MS_NODIRATIME,
+	4096: syscall.MS_RELATIME,
+}
+
+// wrap makes the command first run this binary in new namespaces, which
+// confines itself with the sandbox and then replaces itself with the command.
+// When not running as root, the namespaces are created in a new user
+// namespace.
+func (s *Sandbox) wrap(cmd *exec.Cmd) error {
+	path, err := exec.LookPath(cmd.Path)
+	if err != nil {
+		return err
+	}
+
+	spec := &sandboxSpec{Sandbox: *s, Path: path}
+	spec.ReadOnlyPaths = make([]string, 0, len(s.ReadOnlyPaths))
+	for _, p := range s.ReadOnlyPaths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		spec.ReadOnlyPaths = append(spec.ReadOnlyPaths, abs)
+	}
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env[:len(env):len(env)], sandboxEnv+"="+string(b))
+	cmd.Path = sandboxExe
+
+	attr := &syscall.SysProcAttr{}
+	if len(spec.ReadOnlyPaths) > 0 {
+		attr.Cloneflags |= syscall.CLONE_NEWNS
+	}
+	if s.NoNetwork {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	if attr.Cloneflags != 0 && os.Geteuid() != 0 {
+		uid, gid := os.Geteuid(), os.Getegid()
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+		attr.AmbientCaps = []uintptr{capSysAdmin}
+	}
+	cmd.SysProcAttr = attr
+	return nil
+}
+
+// execSandbox confines this process with the given sandbox and replaces it
+// with the command. It only returns on failure.
+func execSandbox(s string) error {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(s), &spec); err != nil {
+		return err
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, sandboxEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+
+	if len(spec.ReadOnlyPaths) > 0 {
+		// The mounts of the sandbox must not propagate to the mount namespace
+		// of Consul Template.
+		if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+			return fmt.Errorf("making mounts private: %s", err)
+		}
+		for _, path := range spec.ReadOnlyPaths {
+			if err := mountReadOnly(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The capability to mount, which the sandbox has when it is not root, is
+	// not passed on to the command.
+	if os.Geteuid() != 0 {
+		if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL,
+			prCapAmbient, prCapAmbientClearAll, 0, 0, 0, 0); errno != 0 {
+			return fmt.Errorf("dropping capabilities: %s", errno)
+		}
+	}
+
+	if spec.NoNewPrivs {
+		if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL,
+			prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+			return fmt.Errorf("setting no_new_privs: %s", errno)
+		}
+	}
+
+	if err := syscall.Exec(spec.Path, os.Args, env); err != nil {
+		return fmt.Errorf("running %q: %s", spec.Path, err)
+	}
+	return nil
+}
+
+// mountReadOnly bind mounts the path onto itself, along with the mounts
+// beneath it, and remounts all of them read-only.
+func mountReadOnly(path string) error {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Mount(path, path, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("mounting %q: %s", path, err)
+	}
+
+	mounts, err := mountsBeneath(path)
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(m, &st); err != nil {
+			return err
+		}
+
+		flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY)
+		for f, locked := range mountLockedFlags {
+			if uint64(st.Flags)&f != 0 {
+				flags |= locked
+			}
+		}
+		if err := syscall.Mount("", m, "", flags, ""); err != nil {
+			return fmt.Errorf("mounting %q read-only: %s", m, err)
+		}
+	}
+	return nil
+}
+
+// mountsBeneath returns the mount points of this process at or beneath the
+// given path.
+func mountsBeneath(path string) ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		m := unescapeMountPoint(fields[4])
+		if m == path || strings.HasPrefix(m, strings.TrimSuffix(path, "/")+"/") {
+			mounts = append(mounts, m)
+		}
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPoint decodes the octal escapes of spaces, tabs, newlines and
+// backslashes in a mount point of mountinfo.
+func unescapeMountPoint(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+// +build linux
+
+package child
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain lets the test binary serve as the sandbox of the commands of the
+// tests, as the consul-template binary does.
+func TestMain(m *testing.M) {
+	InitSandbox()
+	os.Exit(m.Run())
+}
+
+// runSandboxed runs the shell script in the sandbox and returns its output,
+// skipping the test when the sandbox cannot be applied in this environment.
+func runSandboxed(t *testing.T, s *Sandbox, script string) (string, int) {
+	var out bytes.Buffer
+	c, err := New(&NewInput{
+		Stdout:  &out,
+		Stderr:  &out,
+		Command: "sh",
+		Args:    []string{"-c", script},
+		Sandbox: s,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	code := <-c.ExitCh()
+
+	if strings.HasPrefix(out.String(), "command sandbox: ") {
+		t.Skip(out.String())
+	}
+	return out.String(), code
+}
+
+func TestSandbox_noNewPrivs(t *testing.T) {
+	out, code := runSandboxed(t, &Sandbox{NoNewPrivs: true},
+		"grep NoNewPrivs /proc/self/status")
+	if code != 0 || !strings.Contains(out, "NoNewPrivs:\t1") {
+		t.Errorf("expected no_new_privs to be set, got %d: %s", code, out)
+	}
+}
+
+func TestSandbox_noNetwork(t *testing.T) {
+	out, code := runSandboxed(t, &Sandbox{NoNetwork: true},
+		"tail -n +3 /proc/net/dev | cut -d: -f1")
+	if code != 0 || strings.TrimSpace(out) != "lo" {
+		t.Errorf("expected only the loopback interface, got %d: %s", code, out)
+	}
+}
+
+func TestSandbox_readOnlyPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file")
+	out, code := runSandboxed(t, &Sandbox{ReadOnlyPaths: []string{dir}},
+		"echo denied > "+path)
+	if code == 0 || !strings.Contains(out, "Read-only file system") {
+		t.Errorf("expected writing to fail, got %d: %s", code, out)
+	}
+
+	// The read-only mount is not visible outside of the sandbox.
+	if err := ioutil.WriteFile(path, []byte("allowed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnescapeMountPoint(t *testing.T) {
+	if act := unescapeMountPoint(`/mnt/a\040b\134c`); act != `/mnt/a b\c` {
+		t.Errorf("unexpected mount point %q", act)
+	}
+}
@@ -0,0 +1,18 @@
+// +build !linux
+
+package child
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// wrap is not supported on platforms other than Linux.
+func (s *Sandbox) wrap(cmd *exec.Cmd) error {
+	return fmt.Errorf("the command sandbox is only supported on Linux")
+}
+
+// execSandbox is not supported on platforms other than Linux.
+func execSandbox(s string) error {
+	return fmt.Errorf("the command sandbox is only supported on Linux")
+}
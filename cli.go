@@ -1,17 +1,24 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/hashicorp/consul-template/child"
 	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/exitcodes"
 	"github.com/hashicorp/consul-template/logging"
 	"github.com/hashicorp/consul-template/manager"
 	"github.com/hashicorp/consul-template/signals"
@@ -19,19 +26,44 @@ import (
 
 // Exit codes are int values that represent an exit code for a particular error.
 // Sub-systems may check this unique error to determine the cause of an error
-// without parsing the output or help text.
-//
-// Errors start at 10
+// without parsing the output or help text. They are defined by the exitcodes
+// package, which documents each of them.
 const (
-	ExitCodeOK int = 0
+	ExitCodeOK              = exitcodes.OK
+	ExitCodeError           = exitcodes.Error
+	ExitCodeInterrupt       = exitcodes.Interrupt
+	ExitCodeParseFlagsError = exitcodes.ParseFlagsError
+	ExitCodeRunnerError     = exitcodes.RunnerError
+	ExitCodeConfigError     = exitcodes.ConfigError
+)
 
-	ExitCodeError = 10 + iota
-	ExitCodeInterrupt
-	ExitCodeParseFlagsError
-	ExitCodeRunnerError
-	ExitCodeConfigError
+// Commands are the subcommands of the CLI, given as the first argument.
+// Without a command, the CLI runs as a daemon, or renders once if the -once
+// flag is given.
+const (
+	commandCompletion    = "completion"
+	commandDaemon        = "daemon"
+	commandDeps          = "deps"
+	commandMigrateConfig = "migrate-config"
+	commandRender        = "render"
+	commandValidate      = "validate"
 )
 
+// commands is the sorted list of commands.
+var commands = []string{
+	commandCompletion,
+	commandDaemon,
+	commandDeps,
+	commandMigrateConfig,
+	commandRender,
+	commandValidate,
+}
+
+// signalBufferSize is the number of signals the CLI buffers. Signals which do
+// not fit are dropped, so a burst of SIGCHLD from exiting processes, which is
+// common when running as PID 1, must not crowd out a SIGTERM to forward.
+const signalBufferSize = 32
+
 // CLI is the main entry point.
 type CLI struct {
 	sync.Mutex
@@ -53,7 +85,7 @@ func NewCLI(out, err io.Writer) *CLI {
 	return &CLI{
 		outStream: out,
 		errStream: err,
-		signalCh:  make(chan os.Signal, 1),
+		signalCh:  make(chan os.Signal, signalBufferSize),
 		stopCh:    make(chan struct{}),
 	}
 }
@@ -61,26 +93,51 @@ func NewCLI(out, err io.Writer) *CLI {
 // Run accepts a slice of arguments and returns an int representing the exit
 // status from the command.
 func (cli *CLI) Run(args []string) int {
-	// Parse the flags
-	config, paths, once, dry, version, err := cli.ParseFlags(args[1:])
+	// Parse the command and flags
+	command, args := parseCommand(args[1:])
+	switch command {
+	case commandCompletion:
+		return cli.completion(args)
+	case commandMigrateConfig:
+		return cli.migrateConfig(args)
+	}
+	config, v, err := cli.ParseFlags(args)
 	if err != nil {
 		if err == flag.ErrHelp {
 			return 0
 		}
 		return cli.handleError(err, ExitCodeParseFlagsError)
 	}
+	if command == commandRender {
+		v.once = true
+	}
 
 	// Save original config (defaults + parsed flags) for handling reloads
 	cliConfig := config.Copy()
 
 	// Load configuration paths, with CLI taking precendence
-	config, err = loadConfigs(paths, cliConfig)
+	config, err = loadConfigs(v.configPaths, cliConfig)
 	if err != nil {
 		return cli.handleError(err, ExitCodeConfigError)
 	}
 
 	config.Finalize()
 
+	// If the configuration was requested, print it and exit before anything is
+	// started or logged.
+	if v.printConfig {
+		format := "hcl"
+		if v.jsonOutput {
+			format = "json"
+		}
+		b, err := config.Dump(format)
+		if err != nil {
+			return cli.handleError(err, ExitCodeError)
+		}
+		fmt.Fprintf(cli.outStream, "%s", b)
+		return ExitCodeOK
+	}
+
 	// Setup the config and logging
 	config, err = cli.setup(config)
 	if err != nil {
@@ -93,22 +150,70 @@ func (cli *CLI) Run(args []string) int {
 	// If the version was requested, return an "error" containing the version
 	// information. This might sound weird, but most *nix applications actually
 	// print their version on stderr anyway.
-	if version {
+	if v.version {
 		log.Printf("[DEBUG] (cli) version flag was given, exiting now")
+		if v.jsonOutput {
+			b, err := jsonVersion()
+			if err != nil {
+				return cli.handleError(err, ExitCodeError)
+			}
+			fmt.Fprintf(cli.outStream, "%s\n", b)
+			return ExitCodeOK
+		}
 		fmt.Fprintf(cli.errStream, "%s\n", humanVersion)
 		return ExitCodeOK
 	}
 
-	// Initial runner
-	runner, err := manager.NewRunner(config, dry, once)
+	// If an encrypted file was given, print its contents and exit.
+	if v.decrypt != "" {
+		contents, err := manager.DecryptFile(config, v.decrypt)
+		if err != nil {
+			return cli.handleError(err, ExitCodeError)
+		}
+		fmt.Fprintf(cli.outStream, "%s", contents)
+		return ExitCodeOK
+	}
+
+	switch command {
+	case commandValidate:
+		return cli.validate(config)
+	case commandDeps:
+		return cli.deps(config, v.jsonOutput)
+	}
+
+	// As PID 1 in a container, there is no init to reap the orphaned processes
+	// left behind by the commands, so they are reaped here.
+	if os.Getpid() == 1 {
+		if err := child.ReapOrphans(cli.stopCh); err != nil {
+			log.Printf("[WARN] (cli) %s", err)
+		} else {
+			log.Printf("[INFO] (cli) running as PID 1, reaping orphaned processes")
+		}
+	}
+
+	// The checksum is taken before setup, which is repeated on reloads.
+	checksum := reloadChecksum(config)
+
+	// Initial runner. It fails if the configuration or templates are invalid.
+	runner, err := manager.NewRunner(config, v.dry, v.once)
 	if err != nil {
-		return cli.handleError(err, ExitCodeRunnerError)
+		return cli.handleError(err, ExitCodeConfigError)
 	}
 	go runner.Start()
 
 	// Listen for signals
 	signal.Notify(cli.signalCh)
 
+	// Reload signals are debounced, so reloadCh is the channel of the pending
+	// reload, or nil if there is none.
+	var reloadTimer *time.Timer
+	var reloadCh <-chan time.Time
+	defer func() {
+		if reloadTimer != nil {
+			reloadTimer.Stop()
+		}
+	}()
+
 	for {
 		select {
 		case err := <-runner.ErrCh:
@@ -118,6 +223,7 @@ func (cli *CLI) Run(args []string) int {
 			if typed, ok := err.(manager.ErrExitable); ok {
 				code = typed.ExitStatus()
 			}
+			runner.FlushNotifications()
 			return cli.handleError(err, code)
 		case <-runner.DoneCh:
 			return ExitCodeOK
@@ -126,30 +232,40 @@ func (cli *CLI) Run(args []string) int {
 
 			switch s {
 			case *config.ReloadSignal:
-				fmt.Fprintf(cli.errStream, "Reloading configuration...\n")
-				runner.Stop()
-
-				// Re-parse any configuration files or paths
-				config, err = loadConfigs(paths, cliConfig)
-				if err != nil {
-					return cli.handleError(err, ExitCodeConfigError)
+				debounce := *config.ReloadDebounce
+				if reloadTimer != nil {
+					reloadTimer.Stop()
 				}
-				config.Finalize()
-
-				// Load the new configuration from disk
-				config, err = cli.setup(config)
-				if err != nil {
-					return cli.handleError(err, ExitCodeConfigError)
+				reloadTimer = time.NewTimer(debounce)
+				reloadCh = reloadTimer.C
+				log.Printf("[DEBUG] (cli) reloading in %s", debounce)
+			case *config.KillSignal:
+				fmt.Fprintf(cli.errStream, "Cleaning up...\n")
+				runner.Shutdown()
+				return ExitCodeInterrupt
+			case *config.ApproveSignal:
+				fmt.Fprintf(cli.errStream, "Approving staged templates...\n")
+				runner.Approve()
+			case *config.DumpSignal:
+				if path, err := runner.Dump(*config.DumpDir); err != nil {
+					log.Printf("[ERR] (cli) writing dump: %s", err)
+				} else {
+					fmt.Fprintf(cli.errStream, "Wrote dump to %s...\n", path)
 				}
-
-				runner, err = manager.NewRunner(config, dry, once)
-				if err != nil {
-					return cli.handleError(err, ExitCodeRunnerError)
+			case *config.LogLevelSignal:
+				// This lasts until the configuration is reloaded, which sets the
+				// configured level again.
+				if level, err := logging.CycleLevel(); err != nil {
+					log.Printf("[ERR] (cli) changing log level: %s", err)
+				} else {
+					fmt.Fprintf(cli.errStream, "Changed log level to %s...\n", level)
 				}
-				go runner.Start()
-			case *config.KillSignal:
+			case signals.SignalLookup["SIGTERM"]:
+				// SIGTERM is how service managers and container runtimes stop
+				// processes, so it always stops gracefully like the kill signal,
+				// unless it is configured for another action above.
 				fmt.Fprintf(cli.errStream, "Cleaning up...\n")
-				runner.Stop()
+				runner.Shutdown()
 				return ExitCodeInterrupt
 			case signals.SignalLookup["SIGCHLD"]:
 				// The SIGCHLD signal is sent to the parent of a child process when it
@@ -162,12 +278,216 @@ func (cli *CLI) Run(args []string) int {
 				// Propogate the signal to the child process
 				runner.Signal(s)
 			}
+		case <-reloadCh:
+			reloadCh = nil
+
+			// Re-parse any configuration files or paths
+			newConfig, err := loadConfigs(v.configPaths, cliConfig)
+			if err != nil {
+				runner.Shutdown()
+				return cli.handleError(err, ExitCodeConfigError)
+			}
+			newConfig.Finalize()
+
+			// Tearing down the watches is expensive, so nothing is done if
+			// neither the configuration nor the templates changed.
+			sum := reloadChecksum(newConfig)
+			if sum != "" && sum == checksum {
+				log.Printf("[INFO] (cli) configuration is unchanged, skipping reload")
+				continue
+			}
+			checksum = sum
+
+			fmt.Fprintf(cli.errStream, "Reloading configuration...\n")
+			runner.Stop()
+
+			// Load the new configuration from disk
+			config, err = cli.setup(newConfig)
+			if err != nil {
+				return cli.handleError(err, ExitCodeConfigError)
+			}
+
+			runner, err = manager.NewRunner(config, v.dry, v.once, manager.WithReload())
+			if err != nil {
+				return cli.handleError(err, ExitCodeConfigError)
+			}
+			go runner.Start()
 		case <-cli.stopCh:
 			return ExitCodeOK
 		}
 	}
 }
 
+// reloadChecksum returns a checksum of the configuration and the contents of
+// the files it references, like the template files and the TLS certificates,
+// which are read when the runner is created, so a reload which would change
+// none of them can be skipped. It returns an empty string if the checksum
+// cannot be taken, in which case the reload is never skipped.
+func reloadChecksum(c *config.Config) string {
+	sum, err := c.Checksum()
+	if err != nil {
+		log.Printf("[WARN] (cli) %s", err)
+		return ""
+	}
+
+	h := sha256.New()
+	io.WriteString(h, sum)
+	for _, path := range reloadFiles(c) {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return ""
+		}
+		fmt.Fprintf(h, "%s:%d:", path, len(b))
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// reloadFiles returns the paths of the files the configuration references
+// whose contents are read when the runner is created.
+func reloadFiles(c *config.Config) []string {
+	var paths []string
+	add := func(s *string) {
+		if path := config.StringVal(s); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	for _, ssl := range []*config.SSLConfig{c.Consul.SSL, c.Vault.SSL, c.Syslog.SSL} {
+		if ssl == nil {
+			continue
+		}
+		add(ssl.CaCert)
+		add(ssl.Cert)
+		add(ssl.Key)
+
+		// Every certificate in the directory is loaded.
+		if dir := config.StringVal(ssl.CaPath); dir != "" {
+			infos, err := ioutil.ReadDir(dir)
+			if err != nil {
+				// The directory is read again, which fails the checksum.
+				paths = append(paths, dir)
+				continue
+			}
+			for _, info := range infos {
+				if !info.IsDir() {
+					paths = append(paths, filepath.Join(dir, info.Name()))
+				}
+			}
+		}
+	}
+
+	if c.Snapshot != nil && c.Snapshot.Encryption != nil {
+		add(c.Snapshot.Encryption.KeyFile)
+	}
+
+	for _, t := range *c.Templates {
+		add(t.Source)
+		add(t.VarsFile)
+		if t.Encryption != nil {
+			add(t.Encryption.KeyFile)
+		}
+	}
+	return paths
+}
+
+// parseCommand returns the command given as the first argument, if any, and
+// the remaining arguments.
+func parseCommand(args []string) (string, []string) {
+	if len(args) > 0 {
+		for _, c := range commands {
+			if args[0] == c {
+				return c, args[1:]
+			}
+		}
+	}
+	return commandDaemon, args
+}
+
+// validate checks the configuration and executes the first pass of the
+// templates, which parses them, without rendering them or fetching anything.
+// The runner is not stopped, since that would remove the pid file of a daemon
+// running with the same configuration.
+func (cli *CLI) validate(c *config.Config) int {
+	runner, err := manager.NewRunner(c, true, true)
+	if err != nil {
+		return cli.handleError(err, ExitCodeConfigError)
+	}
+	if _, err := runner.Dependencies(); err != nil {
+		return cli.handleError(err, ExitCodeConfigError)
+	}
+
+	fmt.Fprintf(cli.outStream, "The configuration and its %d templates are valid\n",
+		len(*c.Templates))
+	return ExitCodeOK
+}
+
+// deps prints the dependencies of each template, as text or JSON. Only the
+// dependencies of the first pass of each template are known.
+func (cli *CLI) deps(c *config.Config, jsonOutput bool) int {
+	runner, err := manager.NewRunner(c, true, true)
+	if err != nil {
+		return cli.handleError(err, ExitCodeConfigError)
+	}
+
+	list, err := runner.Dependencies()
+	if err != nil {
+		return cli.handleError(err, ExitCodeError)
+	}
+
+	if jsonOutput {
+		b, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return cli.handleError(err, ExitCodeError)
+		}
+		fmt.Fprintf(cli.outStream, "%s\n", b)
+		return ExitCodeOK
+	}
+
+	for _, t := range list {
+		fmt.Fprintf(cli.outStream, "%s\n", t.Template)
+		for _, d := range t.Dependencies {
+			fmt.Fprintf(cli.outStream, "  %s\n", d)
+		}
+	}
+	return ExitCodeOK
+}
+
+// migrateConfig prints the configuration file given as the only argument
+// rewritten into the current schema, and lists the deprecated options which
+// were rewritten on stderr.
+func (cli *CLI) migrateConfig(args []string) int {
+	if len(args) != 1 {
+		return cli.handleError(fmt.Errorf("cli: migrate-config requires the "+
+			"path of one configuration file"), ExitCodeParseFlagsError)
+	}
+
+	contents, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return cli.handleError(err, ExitCodeConfigError)
+	}
+
+	// The deprecation warnings of the parser are listed as changes instead.
+	log.SetOutput(ioutil.Discard)
+
+	b, changes, err := config.Migrate(string(contents))
+	if err != nil {
+		return cli.handleError(fmt.Errorf("%s: %s", args[0], err), ExitCodeConfigError)
+	}
+
+	fmt.Fprintf(cli.outStream, "%s", b)
+	if len(changes) == 0 {
+		fmt.Fprintf(cli.errStream, "No deprecated options found in %s\n", args[0])
+		return ExitCodeOK
+	}
+	fmt.Fprintf(cli.errStream, "Migrated %d deprecated options in %s:\n",
+		len(changes), args[0])
+	for _, c := range changes {
+		fmt.Fprintf(cli.errStream, "  %s\n", c)
+	}
+	return ExitCodeOK
+}
+
 // stop is used internally to shutdown a running CLI
 func (cli *CLI) stop() {
 	cli.Lock()
@@ -184,25 +504,82 @@ func (cli *CLI) stop() {
 // ParseFlags is a helper function for parsing command line flags using Go's
 // Flag library. This is extracted into a helper to keep the main function
 // small, but it also makes writing tests for parsing command line arguments
-// much easier and cleaner.
-func (cli *CLI) ParseFlags(args []string) (*config.Config, []string, bool, bool, bool, error) {
-	var dry, once, version bool
-
+// much easier and cleaner. It returns the configuration set by the flags, and
+// the values of the other flags.
+func (cli *CLI) ParseFlags(args []string) (*config.Config, *flagValues, error) {
 	c := config.DefaultConfig()
+	v := &flagValues{configPaths: make([]string, 0, 6)}
+	flags := cli.newFlagSet(c, v)
+
+	// TODO: Deprecations
+	for i, a := range args {
+		if a == "-auth" || strings.HasPrefix(a, "-auth=") {
+			log.Println("[WARN] -auth has been renamed to -consul-auth")
+			args[i] = strings.Replace(a, "-auth", "-consul-auth", 1)
+		}
+
+		if a == "-consul" || strings.HasPrefix(a, "-consul=") {
+			log.Println("[WARN] -consul has been renamed to -consul-addr")
+			args[i] = strings.Replace(a, "-consul", "-consul-addr", 1)
+		}
+
+		if strings.HasPrefix(a, "-ssl") {
+			log.Println("[WARN] -ssl options should be prefixed with -consul")
+			args[i] = strings.Replace(a, "-ssl", "-consul-ssl", 1)
+		}
 
+		if a == "-token" || strings.HasPrefix(a, "-token=") {
+			log.Println("[WARN] -token has been renamed to -consul-token")
+			args[i] = strings.Replace(a, "-token", "-consul-token", 1)
+		}
+	}
+
+	// If there was a parser error, stop
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	// Error if extra arguments are present
+	args = flags.Args()
+	if len(args) > 0 {
+		return nil, nil, fmt.Errorf("cli: extra args: %q", args)
+	}
+
+	return c, v, nil
+}
+
+// flagValues are the values of the flags which are not part of the
+// configuration.
+type flagValues struct {
 	// configPaths stores the list of configuration paths on disk
-	configPaths := make([]string, 0, 6)
+	configPaths []string
 
-	// Parse the flags and options
+	dry, once, version, jsonOutput, printConfig bool
+	decrypt                                     string
+}
+
+// newFlagSet returns the flags of the CLI, which set the options of the
+// configuration and the other values. The completion scripts are generated
+// from these definitions.
+func (cli *CLI) newFlagSet(c *config.Config, v *flagValues) *flag.FlagSet {
 	flags := flag.NewFlagSet(Name, flag.ContinueOnError)
 	flags.SetOutput(cli.errStream)
 	flags.Usage = func() { fmt.Fprintf(cli.errStream, usage, Name) }
 
 	flags.Var((funcVar)(func(s string) error {
-		configPaths = append(configPaths, s)
+		v.configPaths = append(v.configPaths, s)
 		return nil
 	}), "config", "")
 
+	flags.Var((funcVar)(func(s string) error {
+		sig, err := signals.Parse(s)
+		if err != nil {
+			return err
+		}
+		c.ApproveSignal = config.Signal(sig)
+		return nil
+	}), "approve-signal", "")
+
 	flags.Var((funcVar)(func(s string) error {
 		c.Consul.Address = config.String(s)
 		return nil
@@ -302,7 +679,23 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, []string, bool, bool,
 		return nil
 	}), "dedup", "")
 
-	flags.BoolVar(&dry, "dry", false, "")
+	flags.StringVar(&v.decrypt, "decrypt", "", "")
+
+	flags.BoolVar(&v.dry, "dry", false, "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.DumpDir = config.String(s)
+		return nil
+	}), "dump-dir", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		sig, err := signals.Parse(s)
+		if err != nil {
+			return err
+		}
+		c.DumpSignal = config.Signal(sig)
+		return nil
+	}), "dump-signal", "")
 
 	flags.Var((funcVar)(func(s string) error {
 		c.Exec.Enabled = config.Bool(true)
@@ -338,6 +731,26 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, []string, bool, bool,
 		return nil
 	}), "exec-splay", "")
 
+	flags.Var((funcDurationVar)(func(d time.Duration) error {
+		c.Exec.SplayMin = config.TimeDuration(d)
+		return nil
+	}), "exec-splay-min", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Exec.SplaySeed = config.String(s)
+		return nil
+	}), "exec-splay-seed", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.FakeData = config.String(s)
+		return nil
+	}), "fake-data", "")
+
+	flags.Var((funcDurationVar)(func(d time.Duration) error {
+		c.FirstPassTimeout = config.TimeDuration(d)
+		return nil
+	}), "first-pass-timeout", "")
+
 	flags.Var((funcVar)(func(s string) error {
 		sig, err := signals.Parse(s)
 		if err != nil {
@@ -352,17 +765,57 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, []string, bool, bool,
 		return nil
 	}), "log-level", "")
 
+	flags.Var((funcVar)(func(s string) error {
+		sig, err := signals.Parse(s)
+		if err != nil {
+			return err
+		}
+		c.LogLevelSignal = config.Signal(sig)
+		return nil
+	}), "log-level-signal", "")
+
+	flags.Var((funcOptionalVar)(func(s string) error {
+		pretty, err := logging.ParsePretty(s)
+		if err != nil {
+			return err
+		}
+		c.LogPretty = config.String(pretty)
+		return nil
+	}), "log-pretty", "")
+
 	flags.Var((funcDurationVar)(func(d time.Duration) error {
 		c.MaxStale = config.TimeDuration(d)
 		return nil
 	}), "max-stale", "")
 
-	flags.BoolVar(&once, "once", false, "")
+	flags.BoolVar(&v.once, "once", false, "")
 	flags.Var((funcVar)(func(s string) error {
 		c.PidFile = config.String(s)
 		return nil
 	}), "pid-file", "")
 
+	flags.Var((funcBoolVar)(func(b bool) error {
+		c.Preflight = config.Bool(b)
+		return nil
+	}), "preflight", "")
+
+	flags.BoolVar(&v.printConfig, "print-config", false, "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Profile = config.String(s)
+		return nil
+	}), "profile", "")
+
+	flags.Var((funcBoolVar)(func(b bool) error {
+		c.ProfileRender = config.Bool(b)
+		return nil
+	}), "profile-render", "")
+
+	flags.Var((funcDurationVar)(func(d time.Duration) error {
+		c.ReloadDebounce = config.TimeDuration(d)
+		return nil
+	}), "reload-debounce", "")
+
 	flags.Var((funcVar)(func(s string) error {
 		sig, err := signals.Parse(s)
 		if err != nil {
@@ -372,11 +825,21 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, []string, bool, bool,
 		return nil
 	}), "reload-signal", "")
 
+	flags.Var((funcDurationVar)(func(d time.Duration) error {
+		c.RenderTimeout = config.TimeDuration(d)
+		return nil
+	}), "render-timeout", "")
+
 	flags.Var((funcDurationVar)(func(d time.Duration) error {
 		c.Consul.Retry.Backoff = config.TimeDuration(d)
 		return nil
 	}), "retry", "")
 
+	flags.Var((funcDurationVar)(func(d time.Duration) error {
+		c.ShutdownGrace = config.TimeDuration(d)
+		return nil
+	}), "shutdown-grace", "")
+
 	flags.Var((funcBoolVar)(func(b bool) error {
 		c.Syslog.Enabled = config.Bool(b)
 		return nil
@@ -421,6 +884,11 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, []string, bool, bool,
 		return nil
 	}), "vault-retry-backoff", "")
 
+	flags.Var((funcBoolVar)(func(b bool) error {
+		c.Vault.RevokeOnShutdown = config.Bool(b)
+		return nil
+	}), "vault-revoke-on-shutdown", "")
+
 	flags.Var((funcBoolVar)(func(b bool) error {
 		c.Vault.SSL.Enabled = config.Bool(b)
 		return nil
@@ -500,44 +968,11 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, []string, bool, bool,
 		return nil
 	}), "wait", "")
 
-	flags.BoolVar(&version, "v", false, "")
-	flags.BoolVar(&version, "version", false, "")
-
-	// TODO: Deprecations
-	for i, a := range args {
-		if a == "-auth" || strings.HasPrefix(a, "-auth=") {
-			log.Println("[WARN] -auth has been renamed to -consul-auth")
-			args[i] = strings.Replace(a, "-auth", "-consul-auth", 1)
-		}
-
-		if a == "-consul" || strings.HasPrefix(a, "-consul=") {
-			log.Println("[WARN] -consul has been renamed to -consul-addr")
-			args[i] = strings.Replace(a, "-consul", "-consul-addr", 1)
-		}
+	flags.BoolVar(&v.version, "v", false, "")
+	flags.BoolVar(&v.version, "version", false, "")
+	flags.BoolVar(&v.jsonOutput, "json", false, "")
 
-		if strings.HasPrefix(a, "-ssl") {
-			log.Println("[WARN] -ssl options should be prefixed with -consul")
-			args[i] = strings.Replace(a, "-ssl", "-consul-ssl", 1)
-		}
-
-		if a == "-token" || strings.HasPrefix(a, "-token=") {
-			log.Println("[WARN] -token has been renamed to -consul-token")
-			args[i] = strings.Replace(a, "-token", "-consul-token", 1)
-		}
-	}
-
-	// If there was a parser error, stop
-	if err := flags.Parse(args); err != nil {
-		return nil, nil, false, false, false, err
-	}
-
-	// Error if extra arguments are present
-	args = flags.Args()
-	if len(args) > 0 {
-		return nil, nil, false, false, false, fmt.Errorf("cli: extra args: %q", args)
-	}
-
-	return c, configPaths, once, dry, version, nil
+	return flags
 }
 
 // loadConfigs loads the configuration from the list of paths. The optional
@@ -556,7 +991,19 @@ func loadConfigs(paths []string, o *config.Config) (*config.Config, error) {
 		finalC = finalC.Merge(c)
 	}
 
+	// The profile is merged over the configuration files, but under the
+	// command line flags.
+	profile := config.StringVal(o.Profile)
+	if profile == "" {
+		profile = os.Getenv("CONSUL_TEMPLATE_PROFILE")
+	}
+	finalC, err := finalC.WithProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
 	finalC = finalC.Merge(o)
+	finalC.Profile = config.String(profile)
 	finalC.Finalize()
 	return finalC, nil
 }
@@ -572,9 +1019,25 @@ func (cli *CLI) setup(conf *config.Config) (*config.Config, error) {
 	if err := logging.Setup(&logging.Config{
 		Name:           Name,
 		Level:          config.StringVal(conf.LogLevel),
+		Pretty:         config.StringVal(conf.LogPretty),
 		Syslog:         config.BoolVal(conf.Syslog.Enabled),
 		SyslogFacility: config.StringVal(conf.Syslog.Facility),
-		Writer:         cli.errStream,
+		SyslogAddress:  config.StringVal(conf.Syslog.Address),
+		SyslogFormat:   config.StringVal(conf.Syslog.Format),
+
+		SyslogSSLCACert:     config.StringVal(conf.Syslog.SSL.CaCert),
+		SyslogSSLCAPath:     config.StringVal(conf.Syslog.SSL.CaPath),
+		SyslogSSLCert:       config.StringVal(conf.Syslog.SSL.Cert),
+		SyslogSSLKey:        config.StringVal(conf.Syslog.SSL.Key),
+		SyslogSSLServerName: config.StringVal(conf.Syslog.SSL.ServerName),
+		SyslogSSLVerify:     config.BoolVal(conf.Syslog.SSL.Verify),
+
+		Sampling:           config.BoolVal(conf.LogSampling.Enabled),
+		SamplingInitial:    config.IntVal(conf.LogSampling.Initial),
+		SamplingThereafter: config.IntVal(conf.LogSampling.Thereafter),
+		SamplingPeriod:     config.TimeDurationVal(conf.LogSampling.Period),
+
+		Writer: cli.errStream,
 	}); err != nil {
 		return nil, err
 	}
@@ -583,14 +1046,43 @@ func (cli *CLI) setup(conf *config.Config) (*config.Config, error) {
 }
 
 const usage = `
-Usage: %s [options]
+Usage: %s [command] [options]
 
   Watches a series of templates on the file system, writing new changes when
   Consul is updated. It runs until an interrupt is received unless the -once
   flag is specified.
 
+Commands:
+
+  completion <bash|fish|zsh>
+      Print the completion script for the shell, generated from the commands
+      and flags of this binary
+
+  daemon
+      Render the templates and keep watching them for changes. This is the
+      default when no command is given
+
+  deps
+      Print the dependencies of each template without fetching them, or as
+      JSON with -json. Dependencies which depend on the data of others are
+      not listed
+
+  migrate-config <path>
+      Print the configuration file rewritten into the current schema, with
+      deprecated options replaced and a comment above each replacement
+
+  render
+      Render the templates once and exit, like -once
+
+  validate
+      Check the configuration and parse the templates without rendering
+      them, and exit
+
 Options:
 
+  -approve-signal=<signal>
+      Signal to listen to approve templates staged for manual approval
+
   -config=<path>
       Sets the path to a configuration file or folder on disk. This can be
       specified multiple times to load multiple files or folders. If multiple
@@ -657,9 +1149,21 @@ Options:
       Enable de-duplication mode - reduces load on Consul when many instances of
       Consul Template are rendering a common template
 
+  -decrypt=<path>
+      Print the contents of a backup or snapshot which was encrypted at rest,
+      using the key it was encrypted with, and exit
+
   -dry
       Print generated templates to stdout instead of rendering
 
+  -dump-dir=<path>
+      Directory to write debug dumps to - defaults to the system's temporary
+      directory
+
+  -dump-signal=<signal>
+      Signal to listen to write goroutine stacks and the internal state of the
+      runner to a file without stopping - disabled by default
+
   -exec=<command>
       Enable exec mode to run as a supervisor-like process - the given command
       will receive all signals provided to the parent process and will receive a
@@ -677,12 +1181,44 @@ Options:
   -exec-splay=<duration>
       Amount of time to wait before sending signals
 
+  -exec-splay-min=<duration>
+      Minimum amount of time to wait before sending signals
+
+  -exec-splay-seed=<seed>
+      Seed to derive the splay from instead of choosing it randomly - use
+      "hostname" for a stable per-host splay
+
+  -fake-data=<path>
+      Render templates once with the data of each dependency in the YAML or
+      JSON document at the path instead of querying Consul and Vault - this
+      requires -dry
+
+  -first-pass-timeout=<duration>
+      Maximum time to spend resolving dependencies in parallel waves before
+      the first render - the default of 0 disables this
+
+  -json
+      Print the version given by -version as JSON, listing the template
+      functions, backends, destinations, engines and configuration schema
+      version supported by this daemon, the configuration given by
+      -print-config, or the dependencies listed by the deps command as JSON
+      instead of HCL or text
+
   -kill-signal=<signal>
       Signal to listen to gracefully terminate the process
 
   -log-level=<level>
       Set the logging level - values are "debug", "info", "warn", and "err"
 
+  -log-level-signal=<signal>
+      Signal to listen to change the logging level to the next more verbose
+      level, wrapping around after "trace"
+
+  -log-pretty[=<mode>]
+      Write human-friendly logs with colored levels, relative timestamps, and
+      condensed dependency names - modes are "auto", the default when the
+      flag is given, which only applies to a terminal, "always", and "never"
+
   -max-stale=<duration>
       Set the maximum staleness and allow stale queries to Consul which will
       distribute work among all servers instead of just the leader
@@ -693,13 +1229,41 @@ Options:
   -pid-file=<path>
       Path on disk to write the PID of the process
 
+  -preflight
+      Check that the dependencies of the templates can be read with the
+      configured credentials before watching them, and exit with a report of
+      those which cannot
+
+  -print-config
+      Print the merged and finalized configuration, with secrets redacted, and
+      exit
+
+  -profile=<name>
+      Name of the profile block to merge over the configuration, which may
+      also be set with the CONSUL_TEMPLATE_PROFILE environment variable
+
+  -profile-render
+      Record the time spent executing and rendering each template, and print
+      a report of it when Consul Template stops
+
+  -reload-debounce=<duration>
+      Amount of time to wait for further reload signals before reloading, so
+      a burst of them causes a single reload
+
   -reload-signal=<signal>
       Signal to listen to reload configuration
 
+  -render-timeout=<duration>
+      Maximum amount of time the execution of a template may take
+
   -retry=<duration>
       The amount of time to wait if Consul returns an error when communicating
       with the API
 
+  -shutdown-grace=<duration>
+      Maximum amount of time to wait on shutdown for running template commands
+      to finish
+
   -syslog
       Send the output to syslog instead of standard error and standard out. The
       syslog facility defaults to LOCAL0 and can be changed using a
@@ -729,6 +1293,9 @@ Options:
       The base amount to use for the backoff duration. This number will be
       increased exponentially for each retry attempt.
 
+  -vault-revoke-on-shutdown
+      Revoke the leases of Vault secrets read by this process when it stops
+
   -vault-ssl
       Specifies is communications with Vault should be done via SSL
 
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"syscall"
@@ -167,6 +171,14 @@ func TestCLI_ParseFlags(t *testing.T) {
 			&config.Config{},
 			false,
 		},
+		{
+			"approve-signal",
+			[]string{"-approve-signal", "SIGUSR2"},
+			&config.Config{
+				ApproveSignal: config.Signal(syscall.SIGUSR2),
+			},
+			false,
+		},
 		{
 			"consul_addr",
 			[]string{"-consul-addr", "1.2.3.4"},
@@ -459,6 +471,26 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"exec-splay-min",
+			[]string{"-exec-splay-min", "5s"},
+			&config.Config{
+				Exec: &config.ExecConfig{
+					SplayMin: config.TimeDuration(5 * time.Second),
+				},
+			},
+			false,
+		},
+		{
+			"exec-splay-seed",
+			[]string{"-exec-splay-seed", "hostname"},
+			&config.Config{
+				Exec: &config.ExecConfig{
+					SplaySeed: config.String("hostname"),
+				},
+			},
+			false,
+		},
 		{
 			"kill-signal",
 			[]string{"-kill-signal", "SIGUSR1"},
@@ -475,6 +507,52 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"dump-dir",
+			[]string{"-dump-dir", "/var/tmp"},
+			&config.Config{
+				DumpDir: config.String("/var/tmp"),
+			},
+			false,
+		},
+		{
+			"dump-signal",
+			[]string{"-dump-signal", "SIGUSR1"},
+			&config.Config{
+				DumpSignal: config.Signal(syscall.SIGUSR1),
+			},
+			false,
+		},
+		{
+			"log-level-signal",
+			[]string{"-log-level-signal", "SIGUSR2"},
+			&config.Config{
+				LogLevelSignal: config.Signal(syscall.SIGUSR2),
+			},
+			false,
+		},
+		{
+			"log-pretty",
+			[]string{"-log-pretty"},
+			&config.Config{
+				LogPretty: config.String("auto"),
+			},
+			false,
+		},
+		{
+			"log-pretty-mode",
+			[]string{"-log-pretty=always"},
+			&config.Config{
+				LogPretty: config.String("always"),
+			},
+			false,
+		},
+		{
+			"log-pretty-invalid",
+			[]string{"-log-pretty=sometimes"},
+			nil,
+			true,
+		},
 		{
 			"max-stale",
 			[]string{"-max-stale", "10s"},
@@ -491,6 +569,38 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"preflight",
+			[]string{"-preflight"},
+			&config.Config{
+				Preflight: config.Bool(true),
+			},
+			false,
+		},
+		{
+			"profile",
+			[]string{"-profile", "production"},
+			&config.Config{
+				Profile: config.String("production"),
+			},
+			false,
+		},
+		{
+			"profile-render",
+			[]string{"-profile-render"},
+			&config.Config{
+				ProfileRender: config.Bool(true),
+			},
+			false,
+		},
+		{
+			"reload-debounce",
+			[]string{"-reload-debounce", "5s"},
+			&config.Config{
+				ReloadDebounce: config.TimeDuration(5 * time.Second),
+			},
+			false,
+		},
 		{
 			"reload-signal",
 			[]string{"-reload-signal", "SIGUSR1"},
@@ -499,6 +609,38 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"fake-data",
+			[]string{"-fake-data", "data.yaml"},
+			&config.Config{
+				FakeData: config.String("data.yaml"),
+			},
+			false,
+		},
+		{
+			"first-pass-timeout",
+			[]string{"-first-pass-timeout", "10s"},
+			&config.Config{
+				FirstPassTimeout: config.TimeDuration(10 * time.Second),
+			},
+			false,
+		},
+		{
+			"render-timeout",
+			[]string{"-render-timeout", "30s"},
+			&config.Config{
+				RenderTimeout: config.TimeDuration(30 * time.Second),
+			},
+			false,
+		},
+		{
+			"shutdown-grace",
+			[]string{"-shutdown-grace", "30s"},
+			&config.Config{
+				ShutdownGrace: config.TimeDuration(30 * time.Second),
+			},
+			false,
+		},
 		{
 			"retry",
 			[]string{"-retry", "30s"},
@@ -599,6 +741,16 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"vault-revoke-on-shutdown",
+			[]string{"-vault-revoke-on-shutdown"},
+			&config.Config{
+				Vault: &config.VaultConfig{
+					RevokeOnShutdown: config.Bool(true),
+				},
+			},
+			false,
+		},
 		{
 			"vault-ssl",
 			[]string{"-vault-ssl"},
@@ -792,7 +944,7 @@ func TestCLI_ParseFlags(t *testing.T) {
 			out := gatedio.NewByteBuffer()
 			cli := NewCLI(out, out)
 
-			a, _, _, _, _, err := cli.ParseFlags(tc.f)
+			a, _, err := cli.ParseFlags(tc.f)
 			if (err != nil) != tc.err {
 				t.Fatal(err)
 			}
@@ -808,6 +960,105 @@ func TestCLI_ParseFlags(t *testing.T) {
 	}
 }
 
+func TestLoadConfigs_profile(t *testing.T) {
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`
+		log_level = "info"
+		max_stale = "5s"
+
+		profile "production" {
+			log_level = "warn"
+			max_stale = "1s"
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		flag     string
+		env      string
+		logLevel string
+		maxStale time.Duration
+		err      bool
+	}{
+		{
+			"none",
+			"",
+			"",
+			"info",
+			5 * time.Second,
+			false,
+		},
+		{
+			"flag",
+			"production",
+			"",
+			"warn",
+			1 * time.Second,
+			false,
+		},
+		{
+			"env",
+			"",
+			"production",
+			"warn",
+			1 * time.Second,
+			false,
+		},
+		{
+			"flag_over_env",
+			"staging",
+			"production",
+			"",
+			0,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if err := os.Setenv("CONSUL_TEMPLATE_PROFILE", tc.env); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Unsetenv("CONSUL_TEMPLATE_PROFILE")
+
+			// Command line flags take precedence over the profile.
+			o := &config.Config{
+				Profile: config.String(tc.flag),
+				Wait: &config.WaitConfig{
+					Min: config.TimeDuration(1 * time.Second),
+				},
+			}
+
+			c, err := loadConfigs([]string{f.Name()}, o)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if c == nil {
+				return
+			}
+
+			if v := config.StringVal(c.LogLevel); v != tc.logLevel {
+				t.Errorf("expected log_level %q, got %q", tc.logLevel, v)
+			}
+			if v := config.TimeDurationVal(c.MaxStale); v != tc.maxStale {
+				t.Errorf("expected max_stale %s, got %s", tc.maxStale, v)
+			}
+			if v := config.TimeDurationVal(c.Wait.Min); v != 1*time.Second {
+				t.Errorf("expected wait min %s, got %s", 1*time.Second, v)
+			}
+			if c.Profiles != nil {
+				t.Errorf("expected profiles to be removed, got %#v", c.Profiles)
+			}
+		})
+	}
+}
+
 func TestCLI_Run(t *testing.T) {
 	t.Parallel()
 
@@ -859,6 +1110,211 @@ func TestCLI_Run(t *testing.T) {
 		})
 	}
 
+	t.Run("version_json", func(t *testing.T) {
+		out := gatedio.NewByteBuffer()
+		cli := NewCLI(out, ioutil.Discard)
+
+		exit := cli.Run([]string{"consul-template", "-version", "-json"})
+		if exit != 0 {
+			t.Fatalf("expected 0 exit, got %d", exit)
+		}
+
+		var info versionInfo
+		if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+			t.Fatalf("%s: %q", err, out.String())
+		}
+		if info.ConfigSchemaVersion != config.SchemaVersion {
+			t.Errorf("expected schema version %d, got %d",
+				config.SchemaVersion, info.ConfigSchemaVersion)
+		}
+		if len(info.Backends) == 0 || len(info.Functions) == 0 {
+			t.Errorf("expected backends and functions, got %#v", info)
+		}
+	})
+
+	t.Run("print_config", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(f.Name())
+		if _, err := f.WriteString(`consul { token = "abcd1234" }`); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, format := range []string{"hcl", "json"} {
+			out := gatedio.NewByteBuffer()
+			cli := NewCLI(out, ioutil.Discard)
+
+			args := []string{"consul-template", "-config", f.Name(),
+				"-log-level", "debug", "-print-config"}
+			if format == "json" {
+				args = append(args, "-json")
+			}
+			if exit := cli.Run(args); exit != 0 {
+				t.Fatalf("%s: expected 0 exit, got %d", format, exit)
+			}
+
+			c, err := config.Parse(out.String())
+			if err != nil {
+				t.Fatalf("%s: %s: %q", format, err, out.String())
+			}
+			if exp, act := "<redacted>", config.StringVal(c.Consul.Token); exp != act {
+				t.Errorf("%s: expected token %q, got %q", format, exp, act)
+			}
+			if exp, act := "debug", config.StringVal(c.LogLevel); exp != act {
+				t.Errorf("%s: expected log level %q, got %q", format, exp, act)
+			}
+		}
+	})
+
+	t.Run("decrypt", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		keyFile := filepath.Join(dir, "key")
+		key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+		if err := ioutil.WriteFile(keyFile, []byte(key), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		dest := filepath.Join(dir, "out")
+		if err := ioutil.WriteFile(dest, []byte("before"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		configFile := filepath.Join(dir, "config.hcl")
+		if err := ioutil.WriteFile(configFile, []byte(fmt.Sprintf(`
+			template {
+				contents    = "after"
+				destination = %q
+				backup      = true
+				encryption {
+					key_file = %q
+				}
+			}`, dest, keyFile)), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		cli := NewCLI(ioutil.Discard, ioutil.Discard)
+		if exit := cli.Run([]string{"consul-template", "-config", configFile,
+			"-once"}); exit != 0 {
+			t.Fatalf("expected 0 exit, got %d", exit)
+		}
+
+		out := gatedio.NewByteBuffer()
+		cli = NewCLI(out, ioutil.Discard)
+		if exit := cli.Run([]string{"consul-template", "-decrypt", dest + ".bak"}); exit != 0 {
+			t.Fatalf("expected 0 exit, got %d", exit)
+		}
+		if exp, act := "before", out.String(); exp != act {
+			t.Errorf("expected %q, got %q", exp, act)
+		}
+	})
+
+	t.Run("commands", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		dest := filepath.Join(dir, "out")
+		configFile := filepath.Join(dir, "config.hcl")
+		if err := ioutil.WriteFile(configFile, []byte(fmt.Sprintf(`
+			template {
+				contents    = "{{ key \"foo\" }}{{ service \"web\" }}"
+				destination = %q
+			}`, dest)), 0600); err != nil {
+			t.Fatal(err)
+		}
+		invalidFile := filepath.Join(dir, "invalid.hcl")
+		if err := ioutil.WriteFile(invalidFile, []byte(fmt.Sprintf(`
+			template {
+				contents    = "{{ key "
+				destination = %q
+			}`, dest)), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		cases := []struct {
+			name string
+			args []string
+			code int
+			exp  string
+		}{
+			{
+				"validate",
+				[]string{"validate", "-config", configFile},
+				ExitCodeOK,
+				"The configuration and its 1 templates are valid\n",
+			},
+			{
+				"validate_invalid",
+				[]string{"validate", "-config", invalidFile},
+				ExitCodeConfigError,
+				"",
+			},
+			{
+				"deps",
+				[]string{"deps", "-config", configFile},
+				ExitCodeOK,
+				fmt.Sprintf("\"(dynamic)\" => %q\n  health.service(web|passing)\n  kv.block(foo)\n", dest),
+			},
+			{
+				"deps_json",
+				[]string{"deps", "-config", configFile, "-json"},
+				ExitCodeOK,
+				`"dependencies": [
+      "health.service(web|passing)",
+      "kv.block(foo)"
+    ]`,
+			},
+		}
+
+		for i, tc := range cases {
+			t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+				out := gatedio.NewByteBuffer()
+				cli := NewCLI(out, ioutil.Discard)
+
+				code := cli.Run(append([]string{"consul-template"}, tc.args...))
+				if code != tc.code {
+					t.Fatalf("expected %d exit, got %d", tc.code, code)
+				}
+				if !strings.Contains(out.String(), tc.exp) {
+					t.Errorf("\nexp: %q\nact: %q", tc.exp, out.String())
+				}
+			})
+		}
+
+		// Nothing is rendered until the render command.
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			t.Fatalf("expected %q not to be rendered: %v", dest, err)
+		}
+		renderFile := filepath.Join(dir, "render.hcl")
+		if err := ioutil.WriteFile(renderFile, []byte(fmt.Sprintf(`
+			template {
+				contents    = "hello"
+				destination = %q
+			}`, dest)), 0600); err != nil {
+			t.Fatal(err)
+		}
+		cli := NewCLI(ioutil.Discard, ioutil.Discard)
+		if exit := cli.Run([]string{"consul-template", "render", "-config",
+			renderFile}); exit != 0 {
+			t.Fatalf("expected 0 exit, got %d", exit)
+		}
+		b, err := ioutil.ReadFile(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := "hello", string(b); exp != act {
+			t.Errorf("expected %q, got %q", exp, act)
+		}
+	})
+
 	t.Run("once", func(t *testing.T) {
 		t.Parallel()
 
@@ -957,3 +1413,241 @@ func TestCLI_Run(t *testing.T) {
 		}
 	})
 }
+
+func TestCLI_Run_terminate(t *testing.T) {
+	t.Parallel()
+
+	// SIGTERM stops gracefully like the kill signal, instead of being
+	// forwarded to the child process.
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`hello`); err != nil {
+		t.Fatal(err)
+	}
+
+	out := gatedio.NewByteBuffer()
+	cli := NewCLI(out, out)
+
+	ch := make(chan int, 1)
+	go func() {
+		ch <- cli.Run([]string{"consul-template",
+			"-dry",
+			"-template", f.Name(),
+		})
+	}()
+
+	test.WaitForContents(t, 2*time.Second, f.Name(), "hello")
+	cli.signalCh <- syscall.SIGTERM
+
+	select {
+	case status := <-ch:
+		if status != ExitCodeInterrupt {
+			t.Errorf("\nexp: %#v\nact: %#v", ExitCodeInterrupt, status)
+		}
+		if !strings.Contains(out.String(), "Cleaning up...") {
+			t.Errorf("expected a graceful stop: %q", out.String())
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("timeout: %q", out.String())
+	}
+}
+
+func TestCLI_completion(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		code int
+		exp  []string
+	}{
+		{
+			"bash",
+			[]string{"bash"},
+			ExitCodeOK,
+			[]string{
+				"complete -o default -F _consul_template consul-template",
+				"-once -pid-file",
+				"|-config|",
+				"completion daemon deps migrate-config render validate",
+			},
+		},
+		{
+			"fish",
+			[]string{"fish"},
+			ExitCodeOK,
+			[]string{
+				"complete -c consul-template -o once\n",
+				"complete -c consul-template -o config -r\n",
+				"-a validate -d 'Check the configuration and templates'",
+			},
+		},
+		{
+			"zsh",
+			[]string{"zsh"},
+			ExitCodeOK,
+			[]string{
+				"#compdef consul-template",
+				"'-once' \\\n",
+				"'-config=:value:_files' \\\n",
+				"'deps:Print the dependencies of each template'",
+			},
+		},
+		{
+			"no_shell",
+			nil,
+			ExitCodeParseFlagsError,
+			[]string{"completion requires one of bash, fish, zsh"},
+		},
+		{
+			"unsupported_shell",
+			[]string{"tcsh"},
+			ExitCodeParseFlagsError,
+			[]string{`unsupported shell "tcsh"`},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			out := gatedio.NewByteBuffer()
+			cli := NewCLI(out, out)
+
+			args := append([]string{"consul-template", "completion"}, tc.args...)
+			if code := cli.Run(args); code != tc.code {
+				t.Fatalf("expected %d exit, got %d: %s", tc.code, code, out.String())
+			}
+			for _, exp := range tc.exp {
+				if !strings.Contains(out.String(), exp) {
+					t.Errorf("expected %q in %q", exp, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCLI_migrateConfig(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "old.hcl")
+	if err := ioutil.WriteFile(path, []byte(`
+		vault {
+			renew = true
+		}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{
+			"migrates",
+			[]string{path},
+			ExitCodeOK,
+			"vault {\n" +
+				"  # Migrated: renamed from \"vault.renew\".\n" +
+				"  renew_token = true\n" +
+				"}\n",
+			"Migrated 1 deprecated options in " + path + ":\n" +
+				"  vault.renew_token: renamed from \"vault.renew\"\n",
+		},
+		{
+			"no_path",
+			nil,
+			ExitCodeParseFlagsError,
+			"",
+			"migrate-config requires the path of one configuration file",
+		},
+		{
+			"missing_file",
+			[]string{filepath.Join(dir, "nope.hcl")},
+			ExitCodeConfigError,
+			"",
+			"no such file or directory",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			stdout, stderr := gatedio.NewByteBuffer(), gatedio.NewByteBuffer()
+			cli := NewCLI(stdout, stderr)
+
+			args := append([]string{"consul-template", "migrate-config"}, tc.args...)
+			if code := cli.Run(args); code != tc.code {
+				t.Fatalf("expected %d exit, got %d: %s", tc.code, code, stderr.String())
+			}
+			if stdout.String() != tc.stdout {
+				t.Errorf("\nexp: %q\nact: %q", tc.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("expected %q in %q", tc.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestReloadChecksum(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	source := write("in.tpl", "{{ key \"foo\" }}")
+	cert := write("cert.pem", "cert")
+	vars := write("vars.json", "{}")
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Vault: &config.VaultConfig{
+			SSL: &config.SSLConfig{Cert: config.String(cert)},
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Source:   config.String(source),
+				VarsFile: config.String(vars),
+			},
+		},
+	})
+	c.Finalize()
+
+	sum := reloadChecksum(c)
+	if sum == "" {
+		t.Fatal("expected a checksum")
+	}
+	if act := reloadChecksum(c); act != sum {
+		t.Errorf("expected the checksum to be stable, got %q and %q", sum, act)
+	}
+
+	for _, path := range []string{source, cert, vars} {
+		if err := ioutil.WriteFile(path, []byte("changed"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		act := reloadChecksum(c)
+		if act == sum {
+			t.Errorf("expected the checksum to change with %s", path)
+		}
+		sum = act
+	}
+}
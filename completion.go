@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+// completionShells are the shells which completion scripts are generated for.
+var completionShells = []string{"bash", "fish", "zsh"}
+
+// commandDescriptions are the descriptions of the commands in the completion
+// scripts.
+var commandDescriptions = map[string]string{
+	commandCompletion:    "Print a shell completion script",
+	commandDaemon:        "Render the templates and keep watching them",
+	commandDeps:          "Print the dependencies of each template",
+	commandMigrateConfig: "Rewrite deprecated options of a configuration file",
+	commandRender:        "Render the templates once and exit",
+	commandValidate:      "Check the configuration and templates",
+}
+
+// completionFlag is a flag as listed by the completion scripts.
+type completionFlag struct {
+	name string
+
+	// value is true if the flag takes a value, unlike boolean flags.
+	value bool
+}
+
+// completion prints the completion script for the shell given as the only
+// argument.
+func (cli *CLI) completion(args []string) int {
+	if len(args) != 1 {
+		return cli.handleError(fmt.Errorf("cli: completion requires one of %s",
+			strings.Join(completionShells, ", ")), ExitCodeParseFlagsError)
+	}
+
+	// The name is only set by release builds.
+	name := Name
+	if name == "" {
+		name = "consul-template"
+	}
+
+	flags := cli.completionFlags()
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion(name, flags)
+	case "fish":
+		script = fishCompletion(name, flags)
+	case "zsh":
+		script = zshCompletion(name, flags)
+	default:
+		return cli.handleError(fmt.Errorf("cli: unsupported shell %q, expected one of %s",
+			args[0], strings.Join(completionShells, ", ")), ExitCodeParseFlagsError)
+	}
+
+	fmt.Fprint(cli.outStream, script)
+	return ExitCodeOK
+}
+
+// completionFlags returns the flags defined by the flag set of the CLI, sorted
+// by name.
+func (cli *CLI) completionFlags() []completionFlag {
+	var list []completionFlag
+	cli.newFlagSet(config.DefaultConfig(), &flagValues{}).VisitAll(func(f *flag.Flag) {
+		b, ok := f.Value.(interface {
+			IsBoolFlag() bool
+		})
+		list = append(list, completionFlag{
+			name:  f.Name,
+			value: !ok || !b.IsBoolFlag(),
+		})
+	})
+	return list
+}
+
+// bashCompletion returns the bash completion script of the named binary. The values of flags are
+// completed as file names.
+func bashCompletion(name string, flags []completionFlag) string {
+	var names, values []string
+	for _, f := range flags {
+		names = append(names, "-"+f.name)
+		if f.value {
+			values = append(values, "-"+f.name)
+		}
+	}
+
+	fn := "_" + strings.Replace(name, "-", "_", -1)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# bash completion for %s\n", name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	fmt.Fprintf(&b, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	fmt.Fprintf(&b, "    local prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n\n")
+	fmt.Fprintf(&b, "    case \"$prev\" in\n")
+	fmt.Fprintf(&b, "        %s)\n", commandCompletion)
+	fmt.Fprintf(&b, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n",
+		strings.Join(completionShells, " "))
+	fmt.Fprintf(&b, "            return ;;\n")
+	fmt.Fprintf(&b, "        %s)\n", strings.Join(values, "|"))
+	fmt.Fprintf(&b, "            return ;;\n")
+	fmt.Fprintf(&b, "    esac\n\n")
+	fmt.Fprintf(&b, "    if [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n",
+		strings.Join(names, " "))
+	fmt.Fprintf(&b, "    elif [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n",
+		strings.Join(commands, " "))
+	fmt.Fprintf(&b, "    fi\n")
+	fmt.Fprintf(&b, "}\n")
+	fmt.Fprintf(&b, "complete -o default -F %s %s\n", fn, name)
+	return b.String()
+}
+
+// fishCompletion returns the fish completion script of the named binary.
+func fishCompletion(name string, flags []completionFlag) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# fish completion for %s\n", name)
+	for _, c := range commands {
+		fmt.Fprintf(&b, "complete -c %s -f -n '__fish_use_subcommand' -a %s -d '%s'\n",
+			name, c, commandDescriptions[c])
+	}
+	fmt.Fprintf(&b, "complete -c %s -f -n '__fish_seen_subcommand_from %s' -a '%s'\n",
+		name, commandCompletion, strings.Join(completionShells, " "))
+	for _, f := range flags {
+		if f.value {
+			fmt.Fprintf(&b, "complete -c %s -o %s -r\n", name, f.name)
+			continue
+		}
+		fmt.Fprintf(&b, "complete -c %s -o %s\n", name, f.name)
+	}
+	return b.String()
+}
+
+// zshCompletion returns the zsh completion script of the named binary. The values of flags are
+// completed as file names.
+func zshCompletion(name string, flags []completionFlag) string {
+	fn := "_" + strings.Replace(name, "-", "_", -1)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "#compdef %s\n\n", name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	fmt.Fprintf(&b, "  local context state state_descr line\n")
+	fmt.Fprintf(&b, "  typeset -A opt_args\n")
+	fmt.Fprintf(&b, "  local -a commands\n")
+	fmt.Fprintf(&b, "  commands=(\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "    '%s:%s'\n", c, commandDescriptions[c])
+	}
+	fmt.Fprintf(&b, "  )\n\n")
+	fmt.Fprintf(&b, "  _arguments -C \\\n")
+	for _, f := range flags {
+		if f.value {
+			fmt.Fprintf(&b, "    '-%s=:value:_files' \\\n", f.name)
+			continue
+		}
+		fmt.Fprintf(&b, "    '-%s' \\\n", f.name)
+	}
+	fmt.Fprintf(&b, "    '1: :->command' \\\n")
+	fmt.Fprintf(&b, "    '*:: :->args'\n\n")
+	fmt.Fprintf(&b, "  case $state in\n")
+	fmt.Fprintf(&b, "    command)\n")
+	fmt.Fprintf(&b, "      _describe 'command' commands ;;\n")
+	fmt.Fprintf(&b, "    args)\n")
+	fmt.Fprintf(&b, "      [[ $line[1] == %s ]] && _values 'shell' %s ;;\n",
+		commandCompletion, strings.Join(completionShells, " "))
+	fmt.Fprintf(&b, "  esac\n")
+	fmt.Fprintf(&b, "}\n\n")
+	fmt.Fprintf(&b, "compdef %s %s\n", fn, name)
+	return b.String()
+}
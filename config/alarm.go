@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultAlarmErrorWindow is the default window over which watcher errors
+	// are counted against the error threshold.
+	DefaultAlarmErrorWindow = 1 * time.Minute
+
+	// DefaultAlarmTimeout is the default amount of time to wait for the alarm
+	// command to exit.
+	DefaultAlarmTimeout = 30 * time.Second
+)
+
+// AlarmConfig is used to run a command when templates are not being kept up to
+// date, for integration with local alerting.
+type AlarmConfig struct {
+	// Command is the command to execute when an alarm fires. Details about the
+	// alarm are passed in environment variables.
+	Command *string `mapstructure:"command"`
+
+	// Enabled controls if alarms are enabled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// ErrorThreshold is the number of watcher errors within the error window
+	// which fires an alarm. Zero disables the alarm.
+	ErrorThreshold *int `mapstructure:"error_threshold"`
+
+	// ErrorWindow is the window over which watcher errors are counted.
+	ErrorWindow *time.Duration `mapstructure:"error_window"`
+
+	// RenderStaleness is the amount of time a template may be unable to render
+	// before an alarm fires. Zero disables the alarm.
+	RenderStaleness *time.Duration `mapstructure:"render_staleness"`
+
+	// Timeout is the maximum amount of time to wait for the command to exit.
+	Timeout *time.Duration `mapstructure:"timeout"`
+}
+
+// DefaultAlarmConfig returns a configuration that is populated with the
+// default values.
+func DefaultAlarmConfig() *AlarmConfig {
+	return &AlarmConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *AlarmConfig) Copy() *AlarmConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o AlarmConfig
+	o.Command = c.Command
+	o.Enabled = c.Enabled
+	o.ErrorThreshold = c.ErrorThreshold
+	o.ErrorWindow = c.ErrorWindow
+	o.RenderStaleness = c.RenderStaleness
+	o.Timeout = c.Timeout
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *AlarmConfig) Merge(o *AlarmConfig) *AlarmConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Command != nil {
+		r.Command = o.Command
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.ErrorThreshold != nil {
+		r.ErrorThreshold = o.ErrorThreshold
+	}
+
+	if o.ErrorWindow != nil {
+		r.ErrorWindow = o.ErrorWindow
+	}
+
+	if o.RenderStaleness != nil {
+		r.RenderStaleness = o.RenderStaleness
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *AlarmConfig) Finalize() {
+	if c.Command == nil {
+		c.Command = String("")
+	}
+
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Command))
+	}
+
+	if c.ErrorThreshold == nil {
+		c.ErrorThreshold = Int(0)
+	}
+
+	if c.ErrorWindow == nil {
+		c.ErrorWindow = TimeDuration(DefaultAlarmErrorWindow)
+	}
+
+	if c.RenderStaleness == nil {
+		c.RenderStaleness = TimeDuration(0)
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultAlarmTimeout)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *AlarmConfig) GoString() string {
+	if c == nil {
+		return "(*AlarmConfig)(nil)"
+	}
+	return fmt.Sprintf("&AlarmConfig{"+
+		"Command:%s, "+
+		"Enabled:%s, "+
+		"ErrorThreshold:%s, "+
+		"ErrorWindow:%s, "+
+		"RenderStaleness:%s, "+
+		"Timeout:%s"+
+		"}",
+		StringGoString(c.Command),
+		BoolGoString(c.Enabled),
+		IntGoString(c.ErrorThreshold),
+		TimeDurationGoString(c.ErrorWindow),
+		TimeDurationGoString(c.RenderStaleness),
+		TimeDurationGoString(c.Timeout),
+	)
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAlarmConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *AlarmConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&AlarmConfig{},
+		},
+		{
+			"copy",
+			&AlarmConfig{
+				Command:         String("alert.sh"),
+				Enabled:         Bool(true),
+				ErrorThreshold:  Int(10),
+				ErrorWindow:     TimeDuration(5 * time.Minute),
+				RenderStaleness: TimeDuration(10 * time.Minute),
+				Timeout:         TimeDuration(5 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestAlarmConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *AlarmConfig
+		b    *AlarmConfig
+		r    *AlarmConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&AlarmConfig{},
+			&AlarmConfig{},
+		},
+		{
+			"nil_b",
+			&AlarmConfig{},
+			nil,
+			&AlarmConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&AlarmConfig{},
+			&AlarmConfig{},
+			&AlarmConfig{},
+		},
+		{
+			"command_overrides",
+			&AlarmConfig{Command: String("alert.sh")},
+			&AlarmConfig{Command: String("")},
+			&AlarmConfig{Command: String("")},
+		},
+		{
+			"command_empty_one",
+			&AlarmConfig{Command: String("alert.sh")},
+			&AlarmConfig{},
+			&AlarmConfig{Command: String("alert.sh")},
+		},
+		{
+			"command_empty_two",
+			&AlarmConfig{},
+			&AlarmConfig{Command: String("alert.sh")},
+			&AlarmConfig{Command: String("alert.sh")},
+		},
+		{
+			"command_same",
+			&AlarmConfig{Command: String("alert.sh")},
+			&AlarmConfig{Command: String("alert.sh")},
+			&AlarmConfig{Command: String("alert.sh")},
+		},
+		{
+			"enabled_overrides",
+			&AlarmConfig{Enabled: Bool(true)},
+			&AlarmConfig{Enabled: Bool(false)},
+			&AlarmConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&AlarmConfig{Enabled: Bool(true)},
+			&AlarmConfig{},
+			&AlarmConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&AlarmConfig{},
+			&AlarmConfig{Enabled: Bool(true)},
+			&AlarmConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&AlarmConfig{Enabled: Bool(true)},
+			&AlarmConfig{Enabled: Bool(true)},
+			&AlarmConfig{Enabled: Bool(true)},
+		},
+		{
+			"error_threshold_overrides",
+			&AlarmConfig{ErrorThreshold: Int(10)},
+			&AlarmConfig{ErrorThreshold: Int(0)},
+			&AlarmConfig{ErrorThreshold: Int(0)},
+		},
+		{
+			"error_threshold_empty_one",
+			&AlarmConfig{ErrorThreshold: Int(10)},
+			&AlarmConfig{},
+			&AlarmConfig{ErrorThreshold: Int(10)},
+		},
+		{
+			"error_threshold_empty_two",
+			&AlarmConfig{},
+			&AlarmConfig{ErrorThreshold: Int(10)},
+			&AlarmConfig{ErrorThreshold: Int(10)},
+		},
+		{
+			"error_threshold_same",
+			&AlarmConfig{ErrorThreshold: Int(10)},
+			&AlarmConfig{ErrorThreshold: Int(10)},
+			&AlarmConfig{ErrorThreshold: Int(10)},
+		},
+		{
+			"error_window_overrides",
+			&AlarmConfig{ErrorWindow: TimeDuration(5 * time.Minute)},
+			&AlarmConfig{ErrorWindow: TimeDuration(0 * time.Second)},
+			&AlarmConfig{ErrorWindow: TimeDuration(0 * time.Second)},
+		},
+		{
+			"error_window_empty_one",
+			&AlarmConfig{ErrorWindow: TimeDuration(5 * time.Minute)},
+			&AlarmConfig{},
+			&AlarmConfig{ErrorWindow: TimeDuration(5 * time.Minute)},
+		},
+		{
+			"error_window_empty_two",
+			&AlarmConfig{},
+			&AlarmConfig{ErrorWindow: TimeDuration(5 * time.Minute)},
+			&AlarmConfig{ErrorWindow: TimeDuration(5 * time.Minute)},
+		},
+		{
+			"error_window_same",
+			&AlarmConfig{ErrorWindow: TimeDuration(5 * time.Minute)},
+			&AlarmConfig{ErrorWindow: TimeDuration(5 * time.Minute)},
+			&AlarmConfig{ErrorWindow: TimeDuration(5 * time.Minute)},
+		},
+		{
+			"render_staleness_overrides",
+			&AlarmConfig{RenderStaleness: TimeDuration(10 * time.Minute)},
+			&AlarmConfig{RenderStaleness: TimeDuration(0 * time.Second)},
+			&AlarmConfig{RenderStaleness: TimeDuration(0 * time.Second)},
+		},
+		{
+			"render_staleness_empty_one",
+			&AlarmConfig{RenderStaleness: TimeDuration(10 * time.Minute)},
+			&AlarmConfig{},
+			&AlarmConfig{RenderStaleness: TimeDuration(10 * time.Minute)},
+		},
+		{
+			"render_staleness_empty_two",
+			&AlarmConfig{},
+			&AlarmConfig{RenderStaleness: TimeDuration(10 * time.Minute)},
+			&AlarmConfig{RenderStaleness: TimeDuration(10 * time.Minute)},
+		},
+		{
+			"render_staleness_same",
+			&AlarmConfig{RenderStaleness: TimeDuration(10 * time.Minute)},
+			&AlarmConfig{RenderStaleness: TimeDuration(10 * time.Minute)},
+			&AlarmConfig{RenderStaleness: TimeDuration(10 * time.Minute)},
+		},
+		{
+			"timeout_overrides",
+			&AlarmConfig{Timeout: TimeDuration(5 * time.Second)},
+			&AlarmConfig{Timeout: TimeDuration(0 * time.Second)},
+			&AlarmConfig{Timeout: TimeDuration(0 * time.Second)},
+		},
+		{
+			"timeout_empty_one",
+			&AlarmConfig{Timeout: TimeDuration(5 * time.Second)},
+			&AlarmConfig{},
+			&AlarmConfig{Timeout: TimeDuration(5 * time.Second)},
+		},
+		{
+			"timeout_empty_two",
+			&AlarmConfig{},
+			&AlarmConfig{Timeout: TimeDuration(5 * time.Second)},
+			&AlarmConfig{Timeout: TimeDuration(5 * time.Second)},
+		},
+		{
+			"timeout_same",
+			&AlarmConfig{Timeout: TimeDuration(5 * time.Second)},
+			&AlarmConfig{Timeout: TimeDuration(5 * time.Second)},
+			&AlarmConfig{Timeout: TimeDuration(5 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestAlarmConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *AlarmConfig
+		r    *AlarmConfig
+	}{
+		{
+			"empty",
+			&AlarmConfig{},
+			&AlarmConfig{
+				Command:         String(""),
+				Enabled:         Bool(false),
+				ErrorThreshold:  Int(0),
+				ErrorWindow:     TimeDuration(DefaultAlarmErrorWindow),
+				RenderStaleness: TimeDuration(0),
+				Timeout:         TimeDuration(DefaultAlarmTimeout),
+			},
+		},
+		{
+			"with_command",
+			&AlarmConfig{
+				Command:         String("alert.sh"),
+				RenderStaleness: TimeDuration(10 * time.Minute),
+			},
+			&AlarmConfig{
+				Command:         String("alert.sh"),
+				Enabled:         Bool(true),
+				ErrorThreshold:  Int(0),
+				ErrorWindow:     TimeDuration(DefaultAlarmErrorWindow),
+				RenderStaleness: TimeDuration(10 * time.Minute),
+				Timeout:         TimeDuration(DefaultAlarmTimeout),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -16,7 +16,7 @@ var (
 type AuthConfig struct {
 	Enabled  *bool   `mapstructure:"enabled"`
 	Username *string `mapstructure:"username"`
-	Password *string `mapstructure:"password"`
+	Password *string `mapstructure:"password" json:"-"`
 }
 
 // DefaultAuthConfig is the default configuration.
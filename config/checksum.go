@@ -0,0 +1,69 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// Checksum returns a SHA-256 checksum of the configuration, which changes
+// when any option changes, including the options tagged `json:"-"` which hold
+// secrets.
+func (c *Config) Checksum() (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("config: checksum: %s", err)
+	}
+
+	h := sha256.New()
+	h.Write(b)
+	writeSecrets(h, reflect.ValueOf(c))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeSecrets writes the values of the options tagged `json:"-"` in the
+// given value to the writer, in a stable order.
+func writeSecrets(w io.Writer, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			writeSecrets(w, v.Elem())
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			if f.Tag.Get("json") != "-" {
+				writeSecrets(w, v.Field(i))
+				continue
+			}
+
+			fv := v.Field(i)
+			if fv.Kind() == reflect.Ptr && fv.IsNil() {
+				fmt.Fprintf(w, "%s:<nil>\n", f.Name)
+				continue
+			}
+			fmt.Fprintf(w, "%s:%q\n", f.Name, fmt.Sprint(reflect.Indirect(fv).Interface()))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			writeSecrets(w, v.Index(i))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			fmt.Fprintf(w, "%q:", fmt.Sprint(k.Interface()))
+			writeSecrets(w, v.MapIndex(k))
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_Checksum(t *testing.T) {
+	t.Parallel()
+
+	checksum := func(c *Config) string {
+		c.Finalize()
+		sum, err := c.Checksum()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+
+	base := checksum(&Config{})
+
+	if sum := checksum(&Config{}); sum != base {
+		t.Errorf("expected the same checksum for the same configuration")
+	}
+
+	for name, c := range map[string]*Config{
+		"option": &Config{
+			MaxStale: TimeDuration(10 * time.Second),
+		},
+		"secret": &Config{
+			Vault: &VaultConfig{
+				Token: String("s.token"),
+			},
+		},
+		"nested_secret": &Config{
+			Consul: &ConsulConfig{
+				FallbackTokens: []string{"fallback"},
+			},
+		},
+	} {
+		if sum := checksum(c); sum == base {
+			t.Errorf("%s: expected a different checksum", name)
+		}
+	}
+}
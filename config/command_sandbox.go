@@ -0,0 +1,127 @@
+package config
+
+import "fmt"
+
+// CommandSandboxConfig is the configuration for confining the command of a
+// template, which limits what a hook script can do when the data which
+// triggers it is not trusted.
+type CommandSandboxConfig struct {
+	// Enabled controls whether the command is confined.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Network controls whether the command may use the network. When false,
+	// the command runs in its own network namespace without any interfaces.
+	Network *bool `mapstructure:"network"`
+
+	// NoNewPrivs prevents the command from gaining privileges, such as
+	// through setuid binaries.
+	NoNewPrivs *bool `mapstructure:"no_new_privs"`
+
+	// ReadOnlyPaths is the list of paths the command may not write beneath.
+	ReadOnlyPaths []string `mapstructure:"read_only_paths"`
+}
+
+// DefaultCommandSandboxConfig returns a configuration that is populated with
+// the default values.
+func DefaultCommandSandboxConfig() *CommandSandboxConfig {
+	return &CommandSandboxConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *CommandSandboxConfig) Copy() *CommandSandboxConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o CommandSandboxConfig
+
+	o.Enabled = c.Enabled
+
+	o.Network = c.Network
+
+	o.NoNewPrivs = c.NoNewPrivs
+
+	if c.ReadOnlyPaths != nil {
+		o.ReadOnlyPaths = append([]string{}, c.ReadOnlyPaths...)
+	}
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *CommandSandboxConfig) Merge(o *CommandSandboxConfig) *CommandSandboxConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Network != nil {
+		r.Network = o.Network
+	}
+
+	if o.NoNewPrivs != nil {
+		r.NoNewPrivs = o.NoNewPrivs
+	}
+
+	if o.ReadOnlyPaths != nil {
+		r.ReadOnlyPaths = append(r.ReadOnlyPaths, o.ReadOnlyPaths...)
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *CommandSandboxConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(false ||
+			c.Network != nil ||
+			c.NoNewPrivs != nil ||
+			len(c.ReadOnlyPaths) > 0)
+	}
+
+	if c.Network == nil {
+		c.Network = Bool(true)
+	}
+
+	if c.NoNewPrivs == nil {
+		c.NoNewPrivs = Bool(true)
+	}
+
+	if c.ReadOnlyPaths == nil {
+		c.ReadOnlyPaths = []string{}
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *CommandSandboxConfig) GoString() string {
+	if c == nil {
+		return "(*CommandSandboxConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&CommandSandboxConfig{"+
+		"Enabled:%s, "+
+		"Network:%s, "+
+		"NoNewPrivs:%s, "+
+		"ReadOnlyPaths:%v"+
+		"}",
+		BoolGoString(c.Enabled),
+		BoolGoString(c.Network),
+		BoolGoString(c.NoNewPrivs),
+		c.ReadOnlyPaths,
+	)
+}
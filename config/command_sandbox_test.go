@@ -0,0 +1,208 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCommandSandboxConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *CommandSandboxConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&CommandSandboxConfig{},
+		},
+		{
+			"copy",
+			&CommandSandboxConfig{
+				Enabled:       Bool(true),
+				Network:       Bool(false),
+				NoNewPrivs:    Bool(true),
+				ReadOnlyPaths: []string{"/etc"},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestCommandSandboxConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *CommandSandboxConfig
+		b    *CommandSandboxConfig
+		r    *CommandSandboxConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{},
+		},
+		{
+			"nil_b",
+			&CommandSandboxConfig{},
+			nil,
+			&CommandSandboxConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{},
+		},
+		{
+			"enabled_overrides",
+			&CommandSandboxConfig{Enabled: Bool(true)},
+			&CommandSandboxConfig{Enabled: Bool(false)},
+			&CommandSandboxConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&CommandSandboxConfig{Enabled: Bool(true)},
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{Enabled: Bool(true)},
+			&CommandSandboxConfig{Enabled: Bool(true)},
+		},
+		{
+			"network_overrides",
+			&CommandSandboxConfig{Network: Bool(true)},
+			&CommandSandboxConfig{Network: Bool(false)},
+			&CommandSandboxConfig{Network: Bool(false)},
+		},
+		{
+			"network_empty_one",
+			&CommandSandboxConfig{Network: Bool(false)},
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{Network: Bool(false)},
+		},
+		{
+			"network_empty_two",
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{Network: Bool(false)},
+			&CommandSandboxConfig{Network: Bool(false)},
+		},
+		{
+			"no_new_privs_overrides",
+			&CommandSandboxConfig{NoNewPrivs: Bool(true)},
+			&CommandSandboxConfig{NoNewPrivs: Bool(false)},
+			&CommandSandboxConfig{NoNewPrivs: Bool(false)},
+		},
+		{
+			"no_new_privs_empty_one",
+			&CommandSandboxConfig{NoNewPrivs: Bool(false)},
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{NoNewPrivs: Bool(false)},
+		},
+		{
+			"no_new_privs_empty_two",
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{NoNewPrivs: Bool(false)},
+			&CommandSandboxConfig{NoNewPrivs: Bool(false)},
+		},
+		{
+			"read_only_paths_merges",
+			&CommandSandboxConfig{ReadOnlyPaths: []string{"/a"}},
+			&CommandSandboxConfig{ReadOnlyPaths: []string{"/b"}},
+			&CommandSandboxConfig{ReadOnlyPaths: []string{"/a", "/b"}},
+		},
+		{
+			"read_only_paths_empty_one",
+			&CommandSandboxConfig{ReadOnlyPaths: []string{"/a"}},
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{ReadOnlyPaths: []string{"/a"}},
+		},
+		{
+			"read_only_paths_empty_two",
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{ReadOnlyPaths: []string{"/a"}},
+			&CommandSandboxConfig{ReadOnlyPaths: []string{"/a"}},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestCommandSandboxConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *CommandSandboxConfig
+		r    *CommandSandboxConfig
+	}{
+		{
+			"empty",
+			&CommandSandboxConfig{},
+			&CommandSandboxConfig{
+				Enabled:       Bool(false),
+				Network:       Bool(true),
+				NoNewPrivs:    Bool(true),
+				ReadOnlyPaths: []string{},
+			},
+		},
+		{
+			"with_network",
+			&CommandSandboxConfig{
+				Network: Bool(false),
+			},
+			&CommandSandboxConfig{
+				Enabled:       Bool(true),
+				Network:       Bool(false),
+				NoNewPrivs:    Bool(true),
+				ReadOnlyPaths: []string{},
+			},
+		},
+		{
+			"with_read_only_paths",
+			&CommandSandboxConfig{
+				ReadOnlyPaths: []string{"/etc"},
+			},
+			&CommandSandboxConfig{
+				Enabled:       Bool(true),
+				Network:       Bool(true),
+				NoNewPrivs:    Bool(true),
+				ReadOnlyPaths: []string{"/etc"},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
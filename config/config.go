@@ -1,3 +1,7 @@
+// Package config defines the configuration of Consul Template and parses it
+// from HCL and JSON. Programs embedding Consul Template build a *Config with
+// Parse or DefaultConfig, Merge and Finalize it, and pass it to
+// manager.NewRunner.
 package config
 
 import (
@@ -6,6 +10,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -23,6 +28,9 @@ const (
 	// DefaultLogLevel is the default logging level.
 	DefaultLogLevel = "WARN"
 
+	// DefaultLogPretty is the default mode of human-friendly logs.
+	DefaultLogPretty = "never"
+
 	// DefaultMaxStale is the default staleness permitted. This enables stale
 	// queries by default for performance reasons.
 	DefaultMaxStale = 2 * time.Second
@@ -30,8 +38,23 @@ const (
 	// DefaultReloadSignal is the default signal for reload.
 	DefaultReloadSignal = syscall.SIGHUP
 
+	// DefaultReloadDebounce is the default amount of time to wait for further
+	// reload signals before reloading.
+	DefaultReloadDebounce = 1 * time.Second
+
 	// DefaultKillSignal is the default signal for termination.
 	DefaultKillSignal = syscall.SIGINT
+
+	// DefaultFirstPassTimeout is the default maximum amount of time to spend
+	// resolving dependencies before the first render, which is disabled.
+	DefaultFirstPassTimeout = 0 * time.Second
+
+	// SchemaVersion is the version of the configuration format. It is
+	// increased once in each release which adds options or changes their
+	// meaning, rather than with every such change, so tooling can detect which
+	// configuration a binary understands. Version 1 is the configuration of
+	// Consul Template 0.18.2.
+	SchemaVersion = 1
 )
 
 var (
@@ -41,36 +64,153 @@ var (
 
 // Config is used to configure Consul Template
 type Config struct {
+	// Alarm is the configuration for running a command when templates are not
+	// being kept up to date.
+	Alarm *AlarmConfig `mapstructure:"alarm"`
+
+	// ApproveSignal is the signal to listen for to promote any template
+	// renders staged for manual approval. It is disabled by default.
+	ApproveSignal *os.Signal `mapstructure:"approve_signal"`
+
 	// Consul is the configuration for connecting to a Consul cluster.
 	Consul *ConsulConfig `mapstructure:"consul"`
 
 	// Dedup is used to configure the dedup settings
 	Dedup *DedupConfig `mapstructure:"deduplicate"`
 
+	// DependencyGC is the configuration for stopping the watches of
+	// dependencies which are no longer used by any template.
+	DependencyGC *DependencyGCConfig `mapstructure:"dependency_gc"`
+
+	// DumpDir is the directory debug dumps are written to. The system's
+	// temporary directory is used when it is empty.
+	DumpDir *string `mapstructure:"dump_dir"`
+
+	// DumpSignal is the signal to listen for to write the goroutine stacks and
+	// the internal state of the runner to a file, without stopping.
+	DumpSignal *os.Signal `mapstructure:"dump_signal"`
+
 	// Exec is the configuration for exec/supervise mode.
 	Exec *ExecConfig `mapstructure:"exec"`
 
+	// FakeData is the path of a YAML or JSON document with the data of each
+	// dependency, which is rendered instead of querying the backends. It is
+	// only allowed in dry mode, and implies once mode.
+	FakeData *string `mapstructure:"fake_data"`
+
+	// FirstPassTimeout is the maximum amount of time to spend resolving the
+	// dependencies of templates in parallel waves before the first render.
+	// Zero disables resolving dependencies before the first render.
+	FirstPassTimeout *time.Duration `mapstructure:"first_pass_timeout"`
+
+	// HoldDown is the hold-down timers of templates which do not set their
+	// own.
+	HoldDown *WaitConfig `mapstructure:"hold_down"`
+
 	// KillSignal is the signal to listen for a graceful terminate event.
 	KillSignal *os.Signal `mapstructure:"kill_signal"`
 
 	// LogLevel is the level with which to log for this config.
 	LogLevel *string `mapstructure:"log_level"`
 
+	// LogLevelSignal is the signal to listen for to change the log level to the
+	// next more verbose level at runtime, wrapping around after TRACE. It is
+	// disabled by default.
+	LogLevelSignal *os.Signal `mapstructure:"log_level_signal"`
+
+	// LogPretty is the mode of human-friendly logs, which are only written to a
+	// terminal in "auto" mode. They are disabled by default.
+	LogPretty *string `mapstructure:"log_pretty"`
+
+	// LogSampling is the configuration for sampling repeated identical log
+	// messages.
+	LogSampling *LogSamplingConfig `mapstructure:"log_sampling"`
+
 	// MaxStale is the maximum amount of time for staleness from Consul as given
 	// by LastContact. If supplied, Consul Template will query all servers instead
 	// of just the leader.
 	MaxStale *time.Duration `mapstructure:"max_stale"`
 
+	// Notifications is the configuration for POSTing render events to a
+	// webhook.
+	Notifications *NotificationsConfig `mapstructure:"notifications"`
+
 	// PidFile is the path on disk where a PID file should be written containing
 	// this processes PID.
 	PidFile *string `mapstructure:"pid_file"`
 
+	// Preflight checks that the dependencies of the templates can be read with
+	// the configured credentials before watching them, and fails with a report
+	// of those which cannot.
+	Preflight *bool `mapstructure:"preflight"`
+
+	// Profile is the name of the profile which was merged over this
+	// configuration. It is selected with the -profile flag or the
+	// CONSUL_TEMPLATE_PROFILE environment variable, not in configuration files.
+	Profile *string `mapstructure:"-"`
+
+	// Profiles are the named configurations declared with profile blocks,
+	// which are merged over the base configuration when selected.
+	Profiles map[string]*Config `mapstructure:"-"`
+
+	// ProfileRender records the time spent executing and rendering each
+	// template, and writes a report of it when Consul Template stops.
+	ProfileRender *bool `mapstructure:"profile_render"`
+
+	// Registration is the configuration for registering this instance as an
+	// instance of a Consul service, which the runners function lists.
+	Registration *RegistrationConfig `mapstructure:"registration"`
+
+	// ReloadDebounce is the amount of time to wait after a reload signal for
+	// further reload signals, so a burst of them causes a single reload.
+	ReloadDebounce *time.Duration `mapstructure:"reload_debounce"`
+
 	// ReloadSignal is the signal to listen for a reload event.
 	ReloadSignal *os.Signal `mapstructure:"reload_signal"`
 
+	// RenderGroups are the named groups of templates which are committed
+	// together, so either all of their destinations are updated or none are.
+	RenderGroups *RenderGroupConfigs `mapstructure:"render_group"`
+
+	// RenderTimeout is the maximum amount of time the execution of a template
+	// may take, for templates which do not set their own. Zero means no limit.
+	RenderTimeout *time.Duration `mapstructure:"render_timeout"`
+
+	// Retry is the fallback retry configuration of the Consul and Vault
+	// backends, for the options which their own retry blocks do not set.
+	Retry *RetryConfig `mapstructure:"retry"`
+
+	// Sandbox is the configuration for confining the process to the declared
+	// destinations and backends.
+	Sandbox *SandboxConfig `mapstructure:"sandbox"`
+
+	// ShutdownGrace is the maximum amount of time to wait on shutdown for the
+	// template commands which are running to finish, after no new renders are
+	// started. Commands still running then are stopped with their kill signal.
+	// Zero does not wait.
+	ShutdownGrace *time.Duration `mapstructure:"shutdown_grace"`
+
+	// Snapshot is the configuration for persisting watch state across restarts.
+	Snapshot *SnapshotConfig `mapstructure:"snapshot"`
+
+	// Status is the configuration for the history of recent render events and
+	// the status file it is written to.
+	Status *StatusConfig `mapstructure:"status"`
+
+	// Strict controls whether unknown keys in the configuration are an error.
+	// When false, they are logged as warnings and ignored, which eases sharing
+	// configuration between versions during rolling upgrades. Since it changes
+	// how a file is parsed, it applies to the file it is set in.
+	Strict *bool `mapstructure:"strict"`
+
 	// Syslog is the configuration for syslog.
 	Syslog *SyslogConfig `mapstructure:"syslog"`
 
+	// TemplateEnv controls which environment variables the env template
+	// function may read, so user-provided templates cannot read sensitive
+	// variables from the environment of Consul Template.
+	TemplateEnv *EnvConfig `mapstructure:"template_env"`
+
 	// Templates is the list of templates.
 	Templates *TemplateConfigs `mapstructure:"template"`
 
@@ -86,6 +226,12 @@ type Config struct {
 func (c *Config) Copy() *Config {
 	var o Config
 
+	if c.Alarm != nil {
+		o.Alarm = c.Alarm.Copy()
+	}
+
+	o.ApproveSignal = c.ApproveSignal
+
 	o.Consul = c.Consul
 
 	if c.Consul != nil {
@@ -96,24 +242,101 @@ func (c *Config) Copy() *Config {
 		o.Dedup = c.Dedup.Copy()
 	}
 
+	if c.DependencyGC != nil {
+		o.DependencyGC = c.DependencyGC.Copy()
+	}
+
+	o.DumpDir = c.DumpDir
+
+	o.DumpSignal = c.DumpSignal
+
 	if c.Exec != nil {
 		o.Exec = c.Exec.Copy()
 	}
 
+	o.FakeData = c.FakeData
+
+	o.FirstPassTimeout = c.FirstPassTimeout
+
+	if c.HoldDown != nil {
+		o.HoldDown = c.HoldDown.Copy()
+	}
+
 	o.KillSignal = c.KillSignal
 
 	o.LogLevel = c.LogLevel
 
+	o.LogLevelSignal = c.LogLevelSignal
+
+	o.LogPretty = c.LogPretty
+
+	if c.LogSampling != nil {
+		o.LogSampling = c.LogSampling.Copy()
+	}
+
 	o.MaxStale = c.MaxStale
 
+	if c.Notifications != nil {
+		o.Notifications = c.Notifications.Copy()
+	}
+
 	o.PidFile = c.PidFile
 
+	o.Preflight = c.Preflight
+
+	o.Profile = c.Profile
+
+	o.ProfileRender = c.ProfileRender
+
+	if c.Profiles != nil {
+		o.Profiles = make(map[string]*Config, len(c.Profiles))
+		for k, v := range c.Profiles {
+			o.Profiles[k] = v.Copy()
+		}
+	}
+
+	if c.Registration != nil {
+		o.Registration = c.Registration.Copy()
+	}
+
+	o.ReloadDebounce = c.ReloadDebounce
+
 	o.ReloadSignal = c.ReloadSignal
 
+	if c.RenderGroups != nil {
+		o.RenderGroups = c.RenderGroups.Copy()
+	}
+
+	o.RenderTimeout = c.RenderTimeout
+
+	if c.Retry != nil {
+		o.Retry = c.Retry.Copy()
+	}
+
+	if c.Sandbox != nil {
+		o.Sandbox = c.Sandbox.Copy()
+	}
+
+	o.ShutdownGrace = c.ShutdownGrace
+
+	if c.Snapshot != nil {
+		o.Snapshot = c.Snapshot.Copy()
+	}
+
+	if c.Status != nil {
+		o.Status = c.Status.Copy()
+	}
+
+	o.Strict = c.Strict
+
 	if c.Syslog != nil {
 		o.Syslog = c.Syslog.Copy()
 	}
 
+	if c.TemplateEnv != nil {
+		o.TemplateEnv = c.TemplateEnv.Copy()
+	}
+
 	if c.Templates != nil {
 		o.Templates = c.Templates.Copy()
 	}
@@ -145,6 +368,14 @@ func (c *Config) Merge(o *Config) *Config {
 
 	r := c.Copy()
 
+	if o.Alarm != nil {
+		r.Alarm = r.Alarm.Merge(o.Alarm)
+	}
+
+	if o.ApproveSignal != nil {
+		r.ApproveSignal = o.ApproveSignal
+	}
+
 	if o.Consul != nil {
 		r.Consul = r.Consul.Merge(o.Consul)
 	}
@@ -153,10 +384,34 @@ func (c *Config) Merge(o *Config) *Config {
 		r.Dedup = r.Dedup.Merge(o.Dedup)
 	}
 
+	if o.DependencyGC != nil {
+		r.DependencyGC = r.DependencyGC.Merge(o.DependencyGC)
+	}
+
+	if o.DumpDir != nil {
+		r.DumpDir = o.DumpDir
+	}
+
+	if o.DumpSignal != nil {
+		r.DumpSignal = o.DumpSignal
+	}
+
 	if o.Exec != nil {
 		r.Exec = r.Exec.Merge(o.Exec)
 	}
 
+	if o.FakeData != nil {
+		r.FakeData = o.FakeData
+	}
+
+	if o.FirstPassTimeout != nil {
+		r.FirstPassTimeout = o.FirstPassTimeout
+	}
+
+	if o.HoldDown != nil {
+		r.HoldDown = r.HoldDown.Merge(o.HoldDown)
+	}
+
 	if o.KillSignal != nil {
 		r.KillSignal = o.KillSignal
 	}
@@ -165,22 +420,103 @@ func (c *Config) Merge(o *Config) *Config {
 		r.LogLevel = o.LogLevel
 	}
 
+	if o.LogLevelSignal != nil {
+		r.LogLevelSignal = o.LogLevelSignal
+	}
+
+	if o.LogPretty != nil {
+		r.LogPretty = o.LogPretty
+	}
+
+	if o.LogSampling != nil {
+		r.LogSampling = r.LogSampling.Merge(o.LogSampling)
+	}
+
 	if o.MaxStale != nil {
 		r.MaxStale = o.MaxStale
 	}
 
+	if o.Notifications != nil {
+		r.Notifications = r.Notifications.Merge(o.Notifications)
+	}
+
 	if o.PidFile != nil {
 		r.PidFile = o.PidFile
 	}
 
+	if o.Preflight != nil {
+		r.Preflight = o.Preflight
+	}
+
+	if o.Profile != nil {
+		r.Profile = o.Profile
+	}
+
+	if o.ProfileRender != nil {
+		r.ProfileRender = o.ProfileRender
+	}
+
+	if o.Profiles != nil {
+		if r.Profiles == nil {
+			r.Profiles = make(map[string]*Config, len(o.Profiles))
+		}
+		for k, v := range o.Profiles {
+			r.Profiles[k] = r.Profiles[k].Merge(v)
+		}
+	}
+
+	if o.Registration != nil {
+		r.Registration = r.Registration.Merge(o.Registration)
+	}
+
+	if o.ReloadDebounce != nil {
+		r.ReloadDebounce = o.ReloadDebounce
+	}
+
 	if o.ReloadSignal != nil {
 		r.ReloadSignal = o.ReloadSignal
 	}
 
+	if o.RenderGroups != nil {
+		r.RenderGroups = r.RenderGroups.Merge(o.RenderGroups)
+	}
+
+	if o.RenderTimeout != nil {
+		r.RenderTimeout = o.RenderTimeout
+	}
+
+	if o.Retry != nil {
+		r.Retry = r.Retry.Merge(o.Retry)
+	}
+
+	if o.Sandbox != nil {
+		r.Sandbox = r.Sandbox.Merge(o.Sandbox)
+	}
+
+	if o.ShutdownGrace != nil {
+		r.ShutdownGrace = o.ShutdownGrace
+	}
+
+	if o.Snapshot != nil {
+		r.Snapshot = r.Snapshot.Merge(o.Snapshot)
+	}
+
+	if o.Status != nil {
+		r.Status = r.Status.Merge(o.Status)
+	}
+
+	if o.Strict != nil {
+		r.Strict = o.Strict
+	}
+
 	if o.Syslog != nil {
 		r.Syslog = r.Syslog.Merge(o.Syslog)
 	}
 
+	if o.TemplateEnv != nil {
+		r.TemplateEnv = r.TemplateEnv.Merge(o.TemplateEnv)
+	}
+
 	if o.Templates != nil {
 		r.Templates = r.Templates.Merge(o.Templates)
 	}
@@ -196,59 +532,267 @@ func (c *Config) Merge(o *Config) *Config {
 	return r
 }
 
+// WithProfile returns a copy of this configuration with the named profile
+// merged over it, and without any profiles. An empty name selects no profile.
+// It is an error to select a profile which was not declared.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	var p *Config
+	if name != "" {
+		var ok bool
+		if p, ok = c.Profiles[name]; !ok {
+			names := make([]string, 0, len(c.Profiles))
+			for k := range c.Profiles {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("profile %q is not declared, declared "+
+				"profiles are %q", name, names)
+		}
+	}
+
+	r := c.Merge(p)
+	r.Profile = String(name)
+	r.Profiles = nil
+	return r, nil
+}
+
 // Parse parses the given string contents as a config
 func Parse(s string) (*Config, error) {
+	c, _, err := parse(s)
+	return c, err
+}
+
+// parse parses the given string contents as a config, and returns the
+// deprecated options which were rewritten into the current schema.
+func parse(s string) (*Config, []migration, error) {
 	var shadow interface{}
 	if err := hcl.Decode(&shadow, s); err != nil {
-		return nil, errors.Wrap(err, "error decoding config")
+		return nil, nil, errors.Wrap(err, "error decoding config")
 	}
 
 	// Convert to a map and flatten the keys we want to flatten
 	parsed, ok := shadow.(map[string]interface{})
 	if !ok {
-		return nil, errors.New("error converting config")
+		return nil, nil, errors.New("error converting config")
+	}
+
+	// Strict mode must be known before decoding, since it controls how
+	// unknown keys are handled.
+	strict := true
+	if v, ok := parsed["strict"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, nil, fmt.Errorf("strict: expected a boolean, got %T", v)
+		}
+		strict = b
+	}
+
+	// Profiles are full configurations of their own, so they are decoded
+	// separately from the base configuration.
+	profiles, unused, profileMigrations, err := parseProfiles(parsed, strict)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c, u, migrations, err := decodeConfig(parsed, strict)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.Profiles = profiles
+
+	if unused = append(unused, u...); len(unused) > 0 {
+		sort.Strings(unused)
+		log.Printf("[WARN] (config) ignoring unknown keys: %s",
+			strings.Join(unused, ", "))
+	}
+
+	return c, append(migrations, profileMigrations...), nil
+}
+
+// parseProfiles removes the profile blocks from the parsed configuration and
+// decodes each of them as a configuration. The unknown keys of the profiles are
+// returned if not in strict mode, along with their rewritten deprecated
+// options.
+func parseProfiles(parsed map[string]interface{}, strict bool) (map[string]*Config, []string, []migration, error) {
+	raw, ok := parsed["profile"]
+	if !ok {
+		return nil, nil, nil, nil
+	}
+	delete(parsed, "profile")
+
+	var list []map[string]interface{}
+	switch typed := raw.(type) {
+	case []map[string]interface{}:
+		list = typed
+	case map[string]interface{}:
+		list = []map[string]interface{}{typed}
+	default:
+		return nil, nil, nil, fmt.Errorf("profile: expected named blocks, got %T", raw)
+	}
+
+	profiles := make(map[string]*Config)
+	var unused []string
+	var migrations []migration
+	for _, m := range list {
+		for name, body := range m {
+			if _, ok := profiles[name]; ok {
+				return nil, nil, nil, fmt.Errorf("profile %q: declared more than once", name)
+			}
+
+			var bodies []map[string]interface{}
+			switch typed := body.(type) {
+			case []map[string]interface{}:
+				bodies = typed
+			case map[string]interface{}:
+				bodies = []map[string]interface{}{typed}
+			default:
+				return nil, nil, nil, fmt.Errorf("profile %q: expected a block, got %T", name, body)
+			}
+
+			var c *Config
+			for _, b := range bodies {
+				if _, ok := b["profile"]; ok {
+					return nil, nil, nil, fmt.Errorf("profile %q: profiles cannot be nested", name)
+				}
+				pc, u, ms, err := decodeConfig(b, strict)
+				if err != nil {
+					return nil, nil, nil, errors.Wrapf(err, "profile %q", name)
+				}
+				for _, k := range u {
+					unused = append(unused, "profile."+name+"."+k)
+				}
+				for _, m := range ms {
+					m.Path = "profile." + name + "." + m.Path
+					migrations = append(migrations, m)
+				}
+				c = c.Merge(pc)
+			}
+			profiles[name] = c
+		}
 	}
+	return profiles, unused, migrations, nil
+}
 
+// decodeConfig decodes the parsed HCL or JSON of a configuration. In strict
+// mode, unknown keys are an error. Otherwise they are ignored and returned.
+// The deprecated options which are rewritten into the current schema are
+// returned too.
+func decodeConfig(parsed map[string]interface{}, strict bool) (*Config, []string, []migration, error) {
 	flattenKeys(parsed, []string{
+		"alarm",
 		"auth",
 		"consul",
 		"consul.auth",
+		"consul.headers",
+		"consul.hold_down",
+		"consul.oauth2",
 		"consul.retry",
 		"consul.ssl",
+		"consul.tokens",
 		"consul.transport",
 		"deduplicate",
+		"dependency_gc",
 		"env",
 		"exec",
 		"exec.env",
+		"hold_down",
+		"log_sampling",
+		"notifications",
+		"registration",
+		"retry",
+		"sandbox",
+		"snapshot",
+		"snapshot.encryption",
+		"status",
 		"ssl",
 		"syslog",
+		"syslog.ssl",
+		"template_env",
 		"vault",
+		"vault.headers",
+		"vault.hold_down",
 		"vault.retry",
 		"vault.ssl",
 		"vault.transport",
 		"wait",
 	})
 
+	// Render groups are named blocks, which are decoded as a list.
+	if err := namedBlocks(parsed, "render_group"); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var migrations []migration
+
 	// FlattenFlatten keys belonging to the templates. We cannot do this above
 	// because it is an array of tmeplates.
 	if templates, ok := parsed["template"].([]map[string]interface{}); ok {
-		for _, template := range templates {
+		for i, template := range templates {
 			flattenKeys(template, []string{
+				"command_sandbox",
+				"diff",
+				"encryption",
 				"env",
 				"exec",
 				"exec.env",
+				"hold_down",
+				"http",
+				"rollout",
 				"wait",
 			})
+
+			// Waits given as strings are converted when decoding.
+			for _, k := range []string{"hold_down", "wait"} {
+				if v, ok := template[k].(string); ok {
+					migrations = append(migrations, migration{
+						Path: fmt.Sprintf("template[%d].%s", i, k),
+						Note: fmt.Sprintf("converted from %s = %q", k, v),
+					})
+				}
+			}
+
+			if val, ok := template["create_dest_dirs_perms"]; ok {
+				log.Println(`[WARN] template.create_dest_dirs_perms has been renamed to ` +
+					`template.dest_dir_perms. Update your configuration files and change ` +
+					`"create_dest_dirs_perms" to "dest_dir_perms".`)
+				if _, ok := template["dest_dir_perms"]; !ok {
+					template["dest_dir_perms"] = val
+				}
+				delete(template, "create_dest_dirs_perms")
+				migrations = append(migrations, migration{
+					Path: fmt.Sprintf("template[%d].dest_dir_perms", i),
+					Note: `renamed from "create_dest_dirs_perms"`,
+				})
+			}
 		}
 	}
 
 	// TODO: Deprecations
+	// The address of Consul given as a string is converted first, since
+	// other deprecated options are moved into the consul block.
+	if consul, ok := parsed["consul"].(string); ok {
+		log.Println("[WARN] consul now accepts a stanza instead of a string. " +
+			"Update your configuration files and change consul = \"\" to " +
+			"consul { } instead.")
+		parsed["consul"] = map[string]interface{}{
+			"address": consul,
+		}
+		migrations = append(migrations, migration{
+			Path: "consul.address",
+			Note: fmt.Sprintf("converted from consul = %q", consul),
+		})
+	}
+
 	if vault, ok := parsed["vault"].(map[string]interface{}); ok {
 		if val, ok := vault["renew"]; ok {
 			log.Println(`[WARN] vault.renew has been renamed to vault.renew_token. ` +
 				`Update your configuration files and change "renew" to "renew_token".`)
 			vault["renew_token"] = val
 			delete(vault, "renew")
+			migrations = append(migrations, migration{
+				Path: "vault.renew_token",
+				Note: `renamed from "vault.renew"`,
+			})
 		}
 	}
 
@@ -260,6 +804,10 @@ func Parse(s string) (*Config, error) {
 		}
 		parsed["consul"].(map[string]interface{})["auth"] = auth
 		delete(parsed, "auth")
+		migrations = append(migrations, migration{
+			Path: "consul.auth",
+			Note: `moved from the top-level "auth" block`,
+		})
 	}
 
 	if retry, ok := parsed["retry"].(string); ok {
@@ -272,6 +820,10 @@ func Parse(s string) (*Config, error) {
 			"backoff": retry,
 		}
 		delete(parsed, "retry")
+		migrations = append(migrations, migration{
+			Path: "consul.retry",
+			Note: fmt.Sprintf("converted from the top-level retry = %q", retry),
+		})
 	}
 
 	if ssl, ok := parsed["ssl"].(map[string]interface{}); ok {
@@ -282,6 +834,10 @@ func Parse(s string) (*Config, error) {
 		}
 		parsed["consul"].(map[string]interface{})["ssl"] = ssl
 		delete(parsed, "ssl")
+		migrations = append(migrations, migration{
+			Path: "consul.ssl",
+			Note: `moved from the top-level "ssl" block`,
+		})
 	}
 
 	if token, ok := parsed["token"].(string); ok {
@@ -292,6 +848,29 @@ func Parse(s string) (*Config, error) {
 		}
 		parsed["consul"].(map[string]interface{})["token"] = token
 		delete(parsed, "token")
+		migrations = append(migrations, migration{
+			Path: "consul.token",
+			Note: `moved from the top-level "token"`,
+		})
+	}
+
+	// Waits given as strings are converted when decoding.
+	for _, k := range []string{"hold_down", "wait"} {
+		if v, ok := parsed[k].(string); ok {
+			migrations = append(migrations, migration{
+				Path: k,
+				Note: fmt.Sprintf("converted from %s = %q", k, v),
+			})
+		}
+	}
+	for _, k := range []string{"consul", "vault"} {
+		block, _ := parsed[k].(map[string]interface{})
+		if v, ok := block["hold_down"].(string); ok {
+			migrations = append(migrations, migration{
+				Path: k + ".hold_down",
+				Note: fmt.Sprintf("converted from hold_down = %q", v),
+			})
+		}
 	}
 
 	// Create a new, empty config
@@ -308,18 +887,21 @@ func Parse(s string) (*Config, error) {
 			mapstructure.StringToSliceHookFunc(","),
 			mapstructure.StringToTimeDurationHookFunc(),
 		),
-		ErrorUnused: true,
+		ErrorUnused: strict,
 		Metadata:    &md,
 		Result:      &c,
 	})
 	if err != nil {
-		return nil, errors.Wrap(err, "mapstructure decoder creation failed")
+		return nil, nil, nil, errors.Wrap(err, "mapstructure decoder creation failed")
 	}
 	if err := decoder.Decode(parsed); err != nil {
-		return nil, errors.Wrap(err, "mapstructure decode failed")
+		return nil, nil, nil, errors.Wrap(err, "mapstructure decode failed")
 	}
 
-	return &c, nil
+	if strict {
+		return &c, nil, migrations, nil
+	}
+	return &c, md.Unused, migrations, nil
 }
 
 // Must returns a config object that must compile. If there are any errors, this
@@ -421,28 +1003,82 @@ func (c *Config) GoString() string {
 	}
 
 	return fmt.Sprintf("&Config{"+
+		"Alarm:%#v, "+
+		"ApproveSignal:%s, "+
 		"Consul:%#v, "+
 		"Dedup:%#v, "+
+		"DependencyGC:%#v, "+
+		"DumpDir:%s, "+
+		"DumpSignal:%s, "+
 		"Exec:%#v, "+
+		"FakeData:%s, "+
+		"FirstPassTimeout:%s, "+
+		"HoldDown:%#v, "+
 		"KillSignal:%s, "+
 		"LogLevel:%s, "+
+		"LogLevelSignal:%s, "+
+		"LogPretty:%s, "+
+		"LogSampling:%#v, "+
 		"MaxStale:%s, "+
+		"Notifications:%#v, "+
 		"PidFile:%s, "+
+		"Preflight:%s, "+
+		"Profile:%s, "+
+		"Profiles:%#v, "+
+		"ProfileRender:%s, "+
+		"Registration:%#v, "+
+		"ReloadDebounce:%s, "+
 		"ReloadSignal:%s, "+
+		"RenderGroups:%#v, "+
+		"RenderTimeout:%s, "+
+		"Retry:%#v, "+
+		"Sandbox:%#v, "+
+		"ShutdownGrace:%s, "+
+		"Snapshot:%#v, "+
+		"Status:%#v, "+
+		"Strict:%s, "+
 		"Syslog:%#v, "+
+		"TemplateEnv:%#v, "+
 		"Templates:%#v, "+
 		"Vault:%#v, "+
 		"Wait:%#v"+
 		"}",
+		c.Alarm,
+		SignalGoString(c.ApproveSignal),
 		c.Consul,
 		c.Dedup,
+		c.DependencyGC,
+		StringGoString(c.DumpDir),
+		SignalGoString(c.DumpSignal),
 		c.Exec,
+		StringGoString(c.FakeData),
+		TimeDurationGoString(c.FirstPassTimeout),
+		c.HoldDown,
 		SignalGoString(c.KillSignal),
 		StringGoString(c.LogLevel),
+		SignalGoString(c.LogLevelSignal),
+		StringGoString(c.LogPretty),
+		c.LogSampling,
 		TimeDurationGoString(c.MaxStale),
+		c.Notifications,
 		StringGoString(c.PidFile),
+		BoolGoString(c.Preflight),
+		StringGoString(c.Profile),
+		c.Profiles,
+		BoolGoString(c.ProfileRender),
+		c.Registration,
+		TimeDurationGoString(c.ReloadDebounce),
 		SignalGoString(c.ReloadSignal),
+		c.RenderGroups,
+		TimeDurationGoString(c.RenderTimeout),
+		c.Retry,
+		c.Sandbox,
+		TimeDurationGoString(c.ShutdownGrace),
+		c.Snapshot,
+		c.Status,
+		BoolGoString(c.Strict),
 		c.Syslog,
+		c.TemplateEnv,
 		c.Templates,
 		c.Vault,
 		c.Wait,
@@ -453,13 +1089,24 @@ func (c *Config) GoString() string {
 // variables may be set which control the values for the default configuration.
 func DefaultConfig() *Config {
 	return &Config{
-		Consul:    DefaultConsulConfig(),
-		Dedup:     DefaultDedupConfig(),
-		Exec:      DefaultExecConfig(),
-		Syslog:    DefaultSyslogConfig(),
-		Templates: DefaultTemplateConfigs(),
-		Vault:     DefaultVaultConfig(),
-		Wait:      DefaultWaitConfig(),
+		Alarm:         DefaultAlarmConfig(),
+		Consul:        DefaultConsulConfig(),
+		Dedup:         DefaultDedupConfig(),
+		DependencyGC:  DefaultDependencyGCConfig(),
+		Exec:          DefaultExecConfig(),
+		HoldDown:      DefaultWaitConfig(),
+		LogSampling:   DefaultLogSamplingConfig(),
+		Notifications: DefaultNotificationsConfig(),
+		Registration:  DefaultRegistrationConfig(),
+		RenderGroups:  DefaultRenderGroupConfigs(),
+		Sandbox:       DefaultSandboxConfig(),
+		Snapshot:      DefaultSnapshotConfig(),
+		Status:        DefaultStatusConfig(),
+		Syslog:        DefaultSyslogConfig(),
+		TemplateEnv:   DefaultEnvConfig(),
+		Templates:     DefaultTemplateConfigs(),
+		Vault:         DefaultVaultConfig(),
+		Wait:          DefaultWaitConfig(),
 	}
 }
 
@@ -469,9 +1116,25 @@ func DefaultConfig() *Config {
 // data was given, but the user did not explicitly add "Enabled: true" to the
 // configuration.
 func (c *Config) Finalize() {
+	if c.Alarm == nil {
+		c.Alarm = DefaultAlarmConfig()
+	}
+	c.Alarm.Finalize()
+
+	if c.ApproveSignal == nil {
+		c.ApproveSignal = Signal(signals.SIGNIL)
+	}
+
+	// The top-level retry is the fallback for the retry of each backend, so
+	// it is merged under them before they are finalized.
+	if c.Retry == nil {
+		c.Retry = DefaultRetryConfig()
+	}
+
 	if c.Consul == nil {
 		c.Consul = DefaultConsulConfig()
 	}
+	c.Consul.Retry = c.Retry.Merge(c.Consul.Retry)
 	c.Consul.Finalize()
 
 	if c.Dedup == nil {
@@ -479,11 +1142,37 @@ func (c *Config) Finalize() {
 	}
 	c.Dedup.Finalize()
 
+	if c.DependencyGC == nil {
+		c.DependencyGC = DefaultDependencyGCConfig()
+	}
+	c.DependencyGC.Finalize()
+
+	if c.DumpDir == nil {
+		c.DumpDir = String("")
+	}
+
+	if c.DumpSignal == nil {
+		c.DumpSignal = Signal(signals.SIGNIL)
+	}
+
 	if c.Exec == nil {
 		c.Exec = DefaultExecConfig()
 	}
 	c.Exec.Finalize()
 
+	if c.FakeData == nil {
+		c.FakeData = String("")
+	}
+
+	if c.FirstPassTimeout == nil {
+		c.FirstPassTimeout = TimeDuration(DefaultFirstPassTimeout)
+	}
+
+	if c.HoldDown == nil {
+		c.HoldDown = DefaultWaitConfig()
+	}
+	c.HoldDown.Finalize()
+
 	if c.KillSignal == nil {
 		c.KillSignal = Signal(DefaultKillSignal)
 	}
@@ -495,23 +1184,99 @@ func (c *Config) Finalize() {
 		}, DefaultLogLevel)
 	}
 
+	if c.LogLevelSignal == nil {
+		c.LogLevelSignal = Signal(signals.SIGNIL)
+	}
+
+	if c.LogPretty == nil {
+		c.LogPretty = String(DefaultLogPretty)
+	}
+
+	if c.LogSampling == nil {
+		c.LogSampling = DefaultLogSamplingConfig()
+	}
+	c.LogSampling.Finalize()
+
 	if c.MaxStale == nil {
 		c.MaxStale = TimeDuration(DefaultMaxStale)
 	}
 
+	if c.Notifications == nil {
+		c.Notifications = DefaultNotificationsConfig()
+	}
+	c.Notifications.Finalize()
+
 	if c.PidFile == nil {
 		c.PidFile = String("")
 	}
 
+	if c.Preflight == nil {
+		c.Preflight = Bool(false)
+	}
+
+	if c.Profile == nil {
+		c.Profile = String("")
+	}
+
+	if c.ProfileRender == nil {
+		c.ProfileRender = Bool(false)
+	}
+
+	if c.Registration == nil {
+		c.Registration = DefaultRegistrationConfig()
+	}
+	c.Registration.Finalize()
+
+	if c.ReloadDebounce == nil {
+		c.ReloadDebounce = TimeDuration(DefaultReloadDebounce)
+	}
+
 	if c.ReloadSignal == nil {
 		c.ReloadSignal = Signal(DefaultReloadSignal)
 	}
 
+	if c.RenderGroups == nil {
+		c.RenderGroups = DefaultRenderGroupConfigs()
+	}
+	c.RenderGroups.Finalize()
+
+	if c.RenderTimeout == nil {
+		c.RenderTimeout = TimeDuration(0)
+	}
+
+	if c.Sandbox == nil {
+		c.Sandbox = DefaultSandboxConfig()
+	}
+	c.Sandbox.Finalize()
+
+	if c.ShutdownGrace == nil {
+		c.ShutdownGrace = TimeDuration(0)
+	}
+
+	if c.Snapshot == nil {
+		c.Snapshot = DefaultSnapshotConfig()
+	}
+	c.Snapshot.Finalize()
+
+	if c.Status == nil {
+		c.Status = DefaultStatusConfig()
+	}
+	c.Status.Finalize()
+
+	if c.Strict == nil {
+		c.Strict = Bool(true)
+	}
+
 	if c.Syslog == nil {
 		c.Syslog = DefaultSyslogConfig()
 	}
 	c.Syslog.Finalize()
 
+	if c.TemplateEnv == nil {
+		c.TemplateEnv = DefaultEnvConfig()
+	}
+	c.TemplateEnv.Finalize()
+
 	if c.Templates == nil {
 		c.Templates = DefaultTemplateConfigs()
 	}
@@ -520,8 +1285,13 @@ func (c *Config) Finalize() {
 	if c.Vault == nil {
 		c.Vault = DefaultVaultConfig()
 	}
+	c.Vault.Retry = c.Retry.Merge(c.Vault.Retry)
 	c.Vault.Finalize()
 
+	// The top-level retry is finalized only after it was merged under the
+	// retry of each backend, so only the options it sets are merged.
+	c.Retry.Finalize()
+
 	if c.Wait == nil {
 		c.Wait = DefaultWaitConfig()
 	}
@@ -1,10 +1,13 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"reflect"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -36,6 +39,34 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"alarm",
+			`alarm {
+				command          = "alert.sh"
+				error_threshold  = 10
+				error_window     = "5m"
+				render_staleness = "10m"
+				timeout          = "5s"
+			}`,
+			&Config{
+				Alarm: &AlarmConfig{
+					Command:         String("alert.sh"),
+					ErrorThreshold:  Int(10),
+					ErrorWindow:     TimeDuration(5 * time.Minute),
+					RenderStaleness: TimeDuration(10 * time.Minute),
+					Timeout:         TimeDuration(5 * time.Second),
+				},
+			},
+			false,
+		},
+		{
+			"approve_signal",
+			`approve_signal = "SIGUSR2"`,
+			&Config{
+				ApproveSignal: Signal(syscall.SIGUSR2),
+			},
+			false,
+		},
 		{
 			"consul",
 			`consul = "1.2.3.4"`,
@@ -209,19 +240,104 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"consul_headers",
+			`consul {
+				headers {
+					"X-Org" = "infra"
+				}
+			}`,
+			&Config{
+				Consul: &ConsulConfig{
+					Headers: map[string]string{"X-Org": "infra"},
+				},
+			},
+			false,
+		},
+		{
+			"consul_hold_down",
+			`consul {
+				hold_down = "5s:20s"
+			}`,
+			&Config{
+				Consul: &ConsulConfig{
+					HoldDown: &WaitConfig{
+						Min: TimeDuration(5 * time.Second),
+						Max: TimeDuration(20 * time.Second),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"consul_required_policies",
+			`consul {
+				required_policies = ["kv-read", "service-read"]
+			}`,
+			&Config{
+				Consul: &ConsulConfig{
+					RequiredPolicies: []string{"kv-read", "service-read"},
+				},
+			},
+			false,
+		},
+		{
+			"consul_tokens",
+			`consul {
+				fallback_tokens = ["efgh5678"]
+				tokens {
+					agent       = "abcd1234"
+					replication = "ijkl9012"
+				}
+			}`,
+			&Config{
+				Consul: &ConsulConfig{
+					FallbackTokens: []string{"efgh5678"},
+					Tokens: &ConsulTokensConfig{
+						Agent:       String("abcd1234"),
+						Replication: String("ijkl9012"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"consul_oauth2",
+			`consul {
+				oauth2 {
+					token_url     = "https://idp.example.com/oauth2/token"
+					client_id     = "consul-template"
+					client_secret = "s3cr3t"
+					scopes        = ["consul"]
+				}
+			}`,
+			&Config{
+				Consul: &ConsulConfig{
+					OAuth2: &OAuth2Config{
+						ClientID:     String("consul-template"),
+						ClientSecret: String("s3cr3t"),
+						Scopes:       []string{"consul"},
+						TokenURL:     String("https://idp.example.com/oauth2/token"),
+					},
+				},
+			},
+			false,
+		},
 		{
 			"consul_retry",
 			`consul {
 				retry {
-					backoff  = "2s"
-					attempts = 10
+					backoff     = "2s"
+					attempts    = 10
+					max_backoff = "30s"
 				}
 			}`,
 			&Config{
 				Consul: &ConsulConfig{
 					Retry: &RetryConfig{
-						Attempts: Int(10),
-						Backoff:  TimeDuration(2 * time.Second),
+						Attempts:   Int(10),
+						Backoff:    TimeDuration(2 * time.Second),
+						MaxBackoff: TimeDuration(30 * time.Second),
 					},
 				},
 			},
@@ -351,6 +467,38 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"consul_ssl_tls_cipher_suites",
+			`consul {
+				ssl {
+					tls_cipher_suites = ["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"]
+				}
+			}`,
+			&Config{
+				Consul: &ConsulConfig{
+					SSL: &SSLConfig{
+						TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"consul_ssl_tls_min_version",
+			`consul {
+				ssl {
+					tls_min_version = "tls12"
+				}
+			}`,
+			&Config{
+				Consul: &ConsulConfig{
+					SSL: &SSLConfig{
+						TLSMinVersion: String("tls12"),
+					},
+				},
+			},
+			false,
+		},
 		{
 			"consul_token",
 			`consul {
@@ -446,17 +594,19 @@ func TestParse(t *testing.T) {
 		{
 			"deduplicate",
 			`deduplicate {
-				enabled   = true
-				prefix    = "foo/bar"
-				max_stale = "100s"
-				TTL       = "500s"
+				enabled     = true
+				fingerprint = false
+				prefix      = "foo/bar"
+				max_stale   = "100s"
+				TTL         = "500s"
 			}`,
 			&Config{
 				Dedup: &DedupConfig{
-					Enabled:  Bool(true),
-					Prefix:   String("foo/bar"),
-					MaxStale: TimeDuration(100 * time.Second),
-					TTL:      TimeDuration(500 * time.Second),
+					Enabled:     Bool(true),
+					Fingerprint: Bool(false),
+					Prefix:      String("foo/bar"),
+					MaxStale:    TimeDuration(100 * time.Second),
+					TTL:         TimeDuration(500 * time.Second),
 				},
 			},
 			false,
@@ -617,6 +767,30 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"exec_splay_min",
+			`exec {
+				splay_min = "10s"
+			 }`,
+			&Config{
+				Exec: &ExecConfig{
+					SplayMin: TimeDuration(10 * time.Second),
+				},
+			},
+			false,
+		},
+		{
+			"exec_splay_seed",
+			`exec {
+				splay_seed = "hostname"
+			 }`,
+			&Config{
+				Exec: &ExecConfig{
+					SplaySeed: String("hostname"),
+				},
+			},
+			false,
+		},
 		{
 			"exec_timeout",
 			`exec {
@@ -645,6 +819,46 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"log_level_signal",
+			`log_level_signal = "SIGUSR2"`,
+			&Config{
+				LogLevelSignal: Signal(syscall.SIGUSR2),
+			},
+			false,
+		},
+		{
+			"log_pretty",
+			`log_pretty = "auto"`,
+			&Config{
+				LogPretty: String("auto"),
+			},
+			false,
+		},
+		{
+			"fake_data",
+			`fake_data = "data.yaml"`,
+			&Config{
+				FakeData: String("data.yaml"),
+			},
+			false,
+		},
+		{
+			"dump_dir",
+			`dump_dir = "/var/tmp"`,
+			&Config{
+				DumpDir: String("/var/tmp"),
+			},
+			false,
+		},
+		{
+			"dump_signal",
+			`dump_signal = "SIGUSR1"`,
+			&Config{
+				DumpSignal: Signal(syscall.SIGUSR1),
+			},
+			false,
+		},
 		{
 			"max_stale",
 			`max_stale = "10s"`,
@@ -654,202 +868,776 @@ func TestParse(t *testing.T) {
 			false,
 		},
 		{
-			"pid_file",
-			`pid_file = "/var/pid"`,
+			"notifications",
+			`notifications {
+				webhook = "https://hooks.example.com/ct"
+				on      = ["error", "rendered"]
+				timeout = "10s"
+			}`,
 			&Config{
-				PidFile: String("/var/pid"),
+				Notifications: &NotificationsConfig{
+					On:      []string{"error", "rendered"},
+					Timeout: TimeDuration(10 * time.Second),
+					Webhook: String("https://hooks.example.com/ct"),
+				},
 			},
 			false,
 		},
 		{
-			"reload_signal",
-			`reload_signal = "SIGUSR1"`,
+			"pid_file",
+			`pid_file = "/var/pid"`,
 			&Config{
-				ReloadSignal: Signal(syscall.SIGUSR1),
+				PidFile: String("/var/pid"),
 			},
 			false,
 		},
 		{
-			"syslog",
-			`syslog {}`,
+			"preflight",
+			`preflight = true`,
 			&Config{
-				Syslog: &SyslogConfig{},
+				Preflight: Bool(true),
 			},
 			false,
 		},
 		{
-			"syslog_enabled",
-			`syslog {
-				enabled = true
-			}`,
+			"profile_render",
+			`profile_render = true`,
 			&Config{
-				Syslog: &SyslogConfig{
-					Enabled: Bool(true),
-				},
+				ProfileRender: Bool(true),
 			},
 			false,
 		},
 		{
-			"syslog_facility",
-			`syslog {
-				facility = "facility"
+			"registration",
+			`registration {
+				service = "edge-templates"
+				tags    = ["edge"]
+				ttl     = "10s"
 			}`,
 			&Config{
-				Syslog: &SyslogConfig{
-					Facility: String("facility"),
+				Registration: &RegistrationConfig{
+					Service: String("edge-templates"),
+					Tags:    []string{"edge"},
+					TTL:     TimeDuration(10 * time.Second),
 				},
 			},
 			false,
 		},
 		{
-			"template",
-			`template {}`,
+			"reload_debounce",
+			`reload_debounce = "5s"`,
 			&Config{
-				Templates: &TemplateConfigs{
-					&TemplateConfig{},
-				},
+				ReloadDebounce: TimeDuration(5 * time.Second),
 			},
 			false,
 		},
 		{
-			"template_multi",
-			`template {}
-			template {}`,
+			"reload_signal",
+			`reload_signal = "SIGUSR1"`,
 			&Config{
-				Templates: &TemplateConfigs{
-					&TemplateConfig{},
-					&TemplateConfig{},
-				},
+				ReloadSignal: Signal(syscall.SIGUSR1),
 			},
 			false,
 		},
 		{
-			"template_backup",
-			`template {
-				backup = true
+			"render_group",
+			`render_group "frontend" {
+				templates = ["/etc/haproxy/haproxy.cfg", "/etc/haproxy/cert.pem"]
+			}
+			render_group "backend" {
+				templates = ["/etc/app.conf"]
 			}`,
 			&Config{
-				Templates: &TemplateConfigs{
-					&TemplateConfig{
-						Backup: Bool(true),
+				RenderGroups: &RenderGroupConfigs{
+					&RenderGroupConfig{
+						Name:      String("frontend"),
+						Templates: []string{"/etc/haproxy/haproxy.cfg", "/etc/haproxy/cert.pem"},
+					},
+					&RenderGroupConfig{
+						Name:      String("backend"),
+						Templates: []string{"/etc/app.conf"},
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_command",
-			`template {
-				command = "command"
+			"render_group_unlabeled",
+			`render_group {
+				templates = ["/etc/app.conf"]
 			}`,
+			nil,
+			true,
+		},
+		{
+			"first_pass_timeout",
+			`first_pass_timeout = "10s"`,
 			&Config{
-				Templates: &TemplateConfigs{
-					&TemplateConfig{
-						Command: String("command"),
-					},
-				},
+				FirstPassTimeout: TimeDuration(10 * time.Second),
 			},
 			false,
 		},
 		{
-			"template_command_timeout",
-			`template {
-				command_timeout = "10s"
-			}`,
+			"hold_down",
+			`hold_down = "15s"`,
 			&Config{
-				Templates: &TemplateConfigs{
-					&TemplateConfig{
-						CommandTimeout: TimeDuration(10 * time.Second),
-					},
+				HoldDown: &WaitConfig{
+					Min: TimeDuration(15 * time.Second),
+					Max: TimeDuration(60 * time.Second),
 				},
 			},
 			false,
 		},
 		{
-			"template_contents",
-			`template {
-				contents = "contents"
+			"retry_block",
+			`retry {
+				attempts = 3
+				backoff  = "1s"
 			}`,
 			&Config{
-				Templates: &TemplateConfigs{
-					&TemplateConfig{
-						Contents: String("contents"),
-					},
+				Retry: &RetryConfig{
+					Attempts: Int(3),
+					Backoff:  TimeDuration(1 * time.Second),
 				},
 			},
 			false,
 		},
 		{
-			"template_destination",
-			`template {
-				destination = "destination"
-			}`,
+			"render_timeout",
+			`render_timeout = "30s"`,
 			&Config{
-				Templates: &TemplateConfigs{
-					&TemplateConfig{
-						Destination: String("destination"),
-					},
-				},
+				RenderTimeout: TimeDuration(30 * time.Second),
 			},
 			false,
 		},
 		{
-			"template_exec",
-			`template {
-				exec {}
-			}`,
+			"shutdown_grace",
+			`shutdown_grace = "30s"`,
 			&Config{
-				Templates: &TemplateConfigs{
-					&TemplateConfig{
-						Exec: &ExecConfig{},
-					},
-				},
+				ShutdownGrace: TimeDuration(30 * time.Second),
 			},
 			false,
 		},
 		{
-			"template_exec_command",
-			`template {
-				exec {
-					command = "command"
+			"profile",
+			`max_stale = "5s"
+			profile "production" {
+				max_stale = "1s"
+				consul {
+					address = "consul.prod:8500"
 				}
+			}
+			profile "dev" {
+				log_level = "debug"
 			}`,
 			&Config{
-				Templates: &TemplateConfigs{
-					&TemplateConfig{
-						Exec: &ExecConfig{
-							Command: String("command"),
+				MaxStale: TimeDuration(5 * time.Second),
+				Profiles: map[string]*Config{
+					"production": &Config{
+						Consul: &ConsulConfig{
+							Address: String("consul.prod:8500"),
 						},
+						MaxStale: TimeDuration(1 * time.Second),
+					},
+					"dev": &Config{
+						LogLevel: String("debug"),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_enabled",
-			`template {
-				exec {
-					enabled = true
-				}
-			 }`,
+			"profile_json",
+			`{"profile": {"production": {"max_stale": "1s"}}}`,
 			&Config{
-				Templates: &TemplateConfigs{
-					&TemplateConfig{
-						Exec: &ExecConfig{
-							Enabled: Bool(true),
-						},
+				Profiles: map[string]*Config{
+					"production": &Config{
+						MaxStale: TimeDuration(1 * time.Second),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_env",
-			`template {
-				exec {
-					env {}
-				}
-			 }`,
+			"profile_nested",
+			`profile "production" {
+				profile "dev" {}
+			}`,
+			nil,
+			true,
+		},
+		{
+			"profile_invalid_key",
+			`profile "production" {
+				foo = "bar"
+			}`,
+			nil,
+			true,
+		},
+		{
+			"strict",
+			`strict = false`,
+			&Config{
+				Strict: Bool(false),
+			},
+			false,
+		},
+		{
+			"strict_unknown_keys",
+			`foo = "bar"`,
+			nil,
+			true,
+		},
+		{
+			"strict_false_unknown_keys",
+			`strict = false
+			foo = "bar"
+			consul {
+				address = "1.2.3.4"
+				bar = "baz"
+			}
+			profile "production" {
+				baz = "qux"
+			}`,
+			&Config{
+				Consul: &ConsulConfig{
+					Address: String("1.2.3.4"),
+				},
+				Profiles: map[string]*Config{
+					"production": &Config{},
+				},
+				Strict: Bool(false),
+			},
+			false,
+		},
+		{
+			"strict_invalid",
+			`strict = "maybe"`,
+			nil,
+			true,
+		},
+		{
+			"sandbox",
+			`sandbox {
+				allowed_ports  = [8125]
+				writable_paths = ["/var/lib/app"]
+			}`,
+			&Config{
+				Sandbox: &SandboxConfig{
+					AllowedPorts:  []int{8125},
+					WritablePaths: []string{"/var/lib/app"},
+				},
+			},
+			false,
+		},
+		{
+			"dependency_gc",
+			`dependency_gc {
+				grace_period = "5m"
+				interval = "30s"
+			}`,
+			&Config{
+				DependencyGC: &DependencyGCConfig{
+					GracePeriod: TimeDuration(5 * time.Minute),
+					Interval:    TimeDuration(30 * time.Second),
+				},
+			},
+			false,
+		},
+		{
+			"log_sampling",
+			`log_sampling {
+				initial    = 5
+				thereafter = 100
+			}`,
+			&Config{
+				LogSampling: &LogSamplingConfig{
+					Initial:    Int(5),
+					Thereafter: Int(100),
+				},
+			},
+			false,
+		},
+		{
+			"status",
+			`status {
+				path    = "/var/run/consul-template/status.json"
+				history = 20
+			}`,
+			&Config{
+				Status: &StatusConfig{
+					History: Int(20),
+					Path:    String("/var/run/consul-template/status.json"),
+				},
+			},
+			false,
+		},
+		{
+			"snapshot",
+			`snapshot {}`,
+			&Config{
+				Snapshot: &SnapshotConfig{},
+			},
+			false,
+		},
+		{
+			"snapshot_enabled",
+			`snapshot {
+				enabled = true
+			}`,
+			&Config{
+				Snapshot: &SnapshotConfig{
+					Enabled: Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"snapshot_path",
+			`snapshot {
+				path = "/var/lib/ct.snapshot"
+			}`,
+			&Config{
+				Snapshot: &SnapshotConfig{
+					Path: String("/var/lib/ct.snapshot"),
+				},
+			},
+			false,
+		},
+		{
+			"snapshot_encryption",
+			`snapshot {
+				path = "/var/lib/ct.snapshot"
+				encryption {
+					vault_transit_key = "consul-template"
+				}
+			}`,
+			&Config{
+				Snapshot: &SnapshotConfig{
+					Encryption: &EncryptionConfig{
+						VaultTransitKey: String("consul-template"),
+					},
+					Path: String("/var/lib/ct.snapshot"),
+				},
+			},
+			false,
+		},
+		{
+			"syslog",
+			`syslog {}`,
+			&Config{
+				Syslog: &SyslogConfig{},
+			},
+			false,
+		},
+		{
+			"syslog_enabled",
+			`syslog {
+				enabled = true
+			}`,
+			&Config{
+				Syslog: &SyslogConfig{
+					Enabled: Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"syslog_facility",
+			`syslog {
+				facility = "facility"
+			}`,
+			&Config{
+				Syslog: &SyslogConfig{
+					Facility: String("facility"),
+				},
+			},
+			false,
+		},
+		{
+			"syslog_address",
+			`syslog {
+				address = "tls://logs.example.com:6514"
+				format  = "rfc5424"
+				ssl {
+					ca_cert = "ca.pem"
+				}
+			}`,
+			&Config{
+				Syslog: &SyslogConfig{
+					Address: String("tls://logs.example.com:6514"),
+					Format:  String("rfc5424"),
+					SSL: &SSLConfig{
+						CaCert: String("ca.pem"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_env",
+			`template_env {
+				whitelist = ["APP_*"]
+				blacklist = ["*TOKEN*"]
+			}`,
+			&Config{
+				TemplateEnv: &EnvConfig{
+					Blacklist: []string{"*TOKEN*"},
+					Whitelist: []string{"APP_*"},
+				},
+			},
+			false,
+		},
+		{
+			"template",
+			`template {}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{},
+				},
+			},
+			false,
+		},
+		{
+			"template_multi",
+			`template {}
+			template {}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{},
+					&TemplateConfig{},
+				},
+			},
+			false,
+		},
+		{
+			"template_approval",
+			`template {
+				approval = "manual"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Approval: String("manual"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_backup",
+			`template {
+				backup = true
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Backup: Bool(true),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_change_report",
+			`template {
+				change_report = true
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						ChangeReport: Bool(true),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_command",
+			`template {
+				command = "command"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Command: String("command"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_command_timeout",
+			`template {
+				command_timeout = "10s"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						CommandTimeout: TimeDuration(10 * time.Second),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_contents",
+			`template {
+				contents = "contents"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Contents: String("contents"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_create_dest_dirs",
+			`template {
+				create_dest_dirs = false
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						CreateDestDirs: Bool(false),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_dest_dir_group",
+			`template {
+				dest_dir_group = "group"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						DestDirGroup: String("group"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_dest_dir_perms",
+			`template {
+				dest_dir_perms = "0700"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						CreateDestDirsPerms: FileMode(0700),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_create_dest_dirs_perms_deprecated",
+			`template {
+				create_dest_dirs_perms = "0700"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						CreateDestDirsPerms: FileMode(0700),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_dest_dir_user",
+			`template {
+				dest_dir_user = "user"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						DestDirUser: String("user"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_destination",
+			`template {
+				destination = "destination"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Destination: String("destination"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_diff",
+			`template {
+				diff {
+					dir = "/tmp/diffs"
+					format = "json_patch"
+				}
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Diff: &DiffConfig{
+							Dir:    String("/tmp/diffs"),
+							Format: String("json_patch"),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_encryption",
+			`template {
+				backup = true
+				encryption {
+					key_file = "/etc/consul-template/backup.key"
+				}
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Backup: Bool(true),
+						Encryption: &EncryptionConfig{
+							KeyFile: String("/etc/consul-template/backup.key"),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_engine",
+			`template {
+				engine = "gotemplate"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Engine: String("gotemplate"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_guard",
+			`template {
+				guard = "ge (len (service \"web\")) 2"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Guard: String(`ge (len (service "web")) 2`),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_hold_down",
+			`template {
+				hold_down = "15s:30s"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						HoldDown: &WaitConfig{
+							Min: TimeDuration(15 * time.Second),
+							Max: TimeDuration(30 * time.Second),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_hold_down_block",
+			`template {
+				hold_down {
+					min = "15s"
+				}
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						HoldDown: &WaitConfig{
+							Min: TimeDuration(15 * time.Second),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec",
+			`template {
+				exec {}
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_command",
+			`template {
+				exec {
+					command = "command"
+				}
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Command: String("command"),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_enabled",
+			`template {
+				exec {
+					enabled = true
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Enabled: Bool(true),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_env",
+			`template {
+				exec {
+					env {}
+				}
+			 }`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
@@ -950,35 +1738,112 @@ func TestParse(t *testing.T) {
 			false,
 		},
 		{
-			"template_exec_kill_signal",
+			"template_exec_kill_signal",
+			`template {
+				exec {
+					kill_signal = "SIGUSR1"
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							KillSignal: Signal(syscall.SIGUSR1),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_kill_timeout",
+			`template {
+				exec {
+					kill_timeout = "30s"
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							KillTimeout: TimeDuration(30 * time.Second),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_reload_signal",
+			`template {
+				exec {
+					reload_signal = "SIGUSR1"
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							ReloadSignal: Signal(syscall.SIGUSR1),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_splay",
+			`template {
+				exec {
+					splay = "30s"
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Splay: TimeDuration(30 * time.Second),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_timeout",
 			`template {
 				exec {
-					kill_signal = "SIGUSR1"
+					timeout = "30s"
 				}
 			 }`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
 						Exec: &ExecConfig{
-							KillSignal: Signal(syscall.SIGUSR1),
+							Timeout: TimeDuration(30 * time.Second),
 						},
 					},
 				},
 			},
 			false,
 		},
+
 		{
-			"template_exec_kill_timeout",
+			"template_command_sandbox",
 			`template {
-				exec {
-					kill_timeout = "30s"
+				command_sandbox {
+					no_new_privs = true
+					read_only_paths = ["/etc", "/usr"]
+					network = false
 				}
-			 }`,
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							KillTimeout: TimeDuration(30 * time.Second),
+						CommandSandbox: &CommandSandboxConfig{
+							Network:       Bool(false),
+							NoNewPrivs:    Bool(true),
+							ReadOnlyPaths: []string{"/etc", "/usr"},
 						},
 					},
 				},
@@ -986,17 +1851,23 @@ func TestParse(t *testing.T) {
 			false,
 		},
 		{
-			"template_exec_reload_signal",
+			"template_http",
 			`template {
-				exec {
-					reload_signal = "SIGUSR1"
+				http {
+					method = "PUT"
+					headers = ["X-Foo: bar"]
+					success_codes = [200, 202]
+					timeout = "10s"
 				}
-			 }`,
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							ReloadSignal: Signal(syscall.SIGUSR1),
+						HTTP: &HTTPDestinationConfig{
+							Headers:      []string{"X-Foo: bar"},
+							Method:       String("PUT"),
+							SuccessCodes: []int{200, 202},
+							Timeout:      TimeDuration(10 * time.Second),
 						},
 					},
 				},
@@ -1004,51 +1875,50 @@ func TestParse(t *testing.T) {
 			false,
 		},
 		{
-			"template_exec_splay",
+			"template_perms",
 			`template {
-				exec {
-					splay = "30s"
-				}
-			 }`,
+				perms = "0600"
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							Splay: TimeDuration(30 * time.Second),
-						},
+						Perms: FileMode(0600),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_timeout",
+			"template_name_after",
 			`template {
-				exec {
-					timeout = "30s"
-				}
-			 }`,
+				name = "certs"
+			}
+			template {
+				name  = "proxy"
+				after = ["certs"]
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							Timeout: TimeDuration(30 * time.Second),
-						},
+						Name: String("certs"),
+					},
+					&TemplateConfig{
+						After: []string{"certs"},
+						Name:  String("proxy"),
 					},
 				},
 			},
 			false,
 		},
-
 		{
-			"template_perms",
+			"template_render_timeout",
 			`template {
-				perms = "0600"
+				render_timeout = "5s"
 			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Perms: FileMode(0600),
+						RenderTimeout: TimeDuration(5 * time.Second),
 					},
 				},
 			},
@@ -1088,6 +1958,20 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"template_vars_file",
+			`template {
+				vars_file = "/etc/ct/values.yaml"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						VarsFile: String("/etc/ct/values.yaml"),
+					},
+				},
+			},
+			false,
+		},
 		{
 			"template_wait_as_string",
 			`template {
@@ -1105,6 +1989,86 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"template_rollout",
+			`template {
+				rollout {
+					max_parallel = 5
+					prefix = "rollout/"
+					stagger = "30s"
+				}
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Rollout: &RolloutConfig{
+							MaxParallel: Int(5),
+							Prefix:      String("rollout/"),
+							Stagger:     TimeDuration(30 * time.Second),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_skip_first_command",
+			`template {
+				skip_first_command = true
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						SkipFirstCommand: Bool(true),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_selinux_label",
+			`template {
+				selinux_label   = "system_u:object_r:httpd_config_t:s0"
+				preserve_xattrs = false
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						PreserveXattrs: Bool(false),
+						SELinuxLabel:   String("system_u:object_r:httpd_config_t:s0"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_watch_destination",
+			`template {
+				watch_destination = true
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						WatchDestination: Bool(true),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_windows_acl",
+			`template {
+				windows_acl = "D:P(A;;FA;;;SY)"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						WindowsACL: String("D:P(A;;FA;;;SY)"),
+					},
+				},
+			},
+			false,
+		},
 		{
 			"template_left_delimiter",
 			`template {
@@ -1257,6 +2221,18 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"vault_revoke_on_shutdown",
+			`vault {
+				revoke_on_shutdown = true
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					RevokeOnShutdown: Bool(true),
+				},
+			},
+			false,
+		},
 		{
 			"vault_unwrap_token",
 			`vault {
@@ -1281,6 +2257,110 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"vault_headers",
+			`vault {
+				headers {
+					"X-Org" = "infra"
+				}
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					Headers: map[string]string{"X-Org": "infra"},
+				},
+			},
+			false,
+		},
+		{
+			"vault_hold_down",
+			`vault {
+				hold_down {
+					min = "30s"
+				}
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					HoldDown: &WaitConfig{
+						Min: TimeDuration(30 * time.Second),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"vault_batch_reads",
+			`vault {
+				batch_reads = true
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					BatchReads: Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"vault_shared_backoff",
+			`vault {
+				shared_backoff = true
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					SharedBackoff: Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"vault_max_concurrent_requests",
+			`vault {
+				max_concurrent_requests = 4
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					MaxConcurrentRequests: Int(4),
+				},
+			},
+			false,
+		},
+		{
+			"vault_addresses",
+			`vault {
+				addresses = ["https://vault2:8200"]
+				health_check = true
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					Addresses:   []string{"https://vault2:8200"},
+					HealthCheck: Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"vault_kv_mount_cache_ttl",
+			`vault {
+				kv_mount_cache_ttl = "30s"
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					KVMountCacheTTL: TimeDuration(30 * time.Second),
+				},
+			},
+			false,
+		},
+		{
+			"vault_renew_jitter",
+			`vault {
+				renew_jitter = 0.25
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					RenewJitter: Float64(0.25),
+				},
+			},
+			false,
+		},
 		{
 			"vault_renew_token",
 			`vault {
@@ -1481,6 +2561,38 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"vault_ssl_tls_cipher_suites",
+			`vault {
+				ssl {
+					tls_cipher_suites = ["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"]
+				}
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					SSL: &SSLConfig{
+						TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"vault_ssl_tls_min_version",
+			`vault {
+				ssl {
+					tls_min_version = "tls12"
+				}
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					SSL: &SSLConfig{
+						TLSMinVersion: String("tls12"),
+					},
+				},
+			},
+			false,
+		},
 		{
 			"wait",
 			`wait {
@@ -1617,6 +2729,68 @@ func TestConfig_Merge(t *testing.T) {
 			&Config{},
 			&Config{},
 		},
+		{
+			"alarm",
+			&Config{
+				Alarm: &AlarmConfig{
+					Command: String("alert.sh"),
+				},
+			},
+			&Config{
+				Alarm: &AlarmConfig{
+					Command: String("page.sh"),
+				},
+			},
+			&Config{
+				Alarm: &AlarmConfig{
+					Command: String("page.sh"),
+				},
+			},
+		},
+		{
+			"approve_signal",
+			&Config{
+				ApproveSignal: Signal(syscall.SIGUSR1),
+			},
+			&Config{
+				ApproveSignal: Signal(syscall.SIGUSR2),
+			},
+			&Config{
+				ApproveSignal: Signal(syscall.SIGUSR2),
+			},
+		},
+		{
+			"profiles",
+			&Config{
+				Profiles: map[string]*Config{
+					"production": &Config{
+						LogLevel: String("warn"),
+						MaxStale: TimeDuration(1 * time.Second),
+					},
+				},
+			},
+			&Config{
+				Profiles: map[string]*Config{
+					"production": &Config{
+						LogLevel: String("err"),
+					},
+					"dev": &Config{
+						LogLevel: String("debug"),
+					},
+				},
+			},
+			&Config{
+				Profiles: map[string]*Config{
+					"production": &Config{
+						LogLevel: String("err"),
+						MaxStale: TimeDuration(1 * time.Second),
+					},
+					"dev": &Config{
+						LogLevel: String("debug"),
+					},
+				},
+			},
+		},
 		{
 			"consul",
 			&Config{
@@ -1695,6 +2869,66 @@ func TestConfig_Merge(t *testing.T) {
 				LogLevel: String("log_level-diff"),
 			},
 		},
+		{
+			"log_level_signal",
+			&Config{
+				LogLevelSignal: Signal(syscall.SIGUSR1),
+			},
+			&Config{
+				LogLevelSignal: Signal(syscall.SIGUSR2),
+			},
+			&Config{
+				LogLevelSignal: Signal(syscall.SIGUSR2),
+			},
+		},
+		{
+			"log_pretty",
+			&Config{
+				LogPretty: String("auto"),
+			},
+			&Config{
+				LogPretty: String("always"),
+			},
+			&Config{
+				LogPretty: String("always"),
+			},
+		},
+		{
+			"fake_data",
+			&Config{
+				FakeData: String("a.yaml"),
+			},
+			&Config{
+				FakeData: String("b.yaml"),
+			},
+			&Config{
+				FakeData: String("b.yaml"),
+			},
+		},
+		{
+			"dump_dir",
+			&Config{
+				DumpDir: String("/tmp"),
+			},
+			&Config{
+				DumpDir: String("/var/tmp"),
+			},
+			&Config{
+				DumpDir: String("/var/tmp"),
+			},
+		},
+		{
+			"dump_signal",
+			&Config{
+				DumpSignal: Signal(syscall.SIGQUIT),
+			},
+			&Config{
+				DumpSignal: Signal(syscall.SIGUSR1),
+			},
+			&Config{
+				DumpSignal: Signal(syscall.SIGUSR1),
+			},
+		},
 		{
 			"max_stale",
 			&Config{
@@ -1707,6 +2941,24 @@ func TestConfig_Merge(t *testing.T) {
 				MaxStale: TimeDuration(20 * time.Second),
 			},
 		},
+		{
+			"notifications",
+			&Config{
+				Notifications: &NotificationsConfig{
+					Webhook: String("https://a.example.com"),
+				},
+			},
+			&Config{
+				Notifications: &NotificationsConfig{
+					Webhook: String("https://b.example.com"),
+				},
+			},
+			&Config{
+				Notifications: &NotificationsConfig{
+					Webhook: String("https://b.example.com"),
+				},
+			},
+		},
 		{
 			"pid_file",
 			&Config{
@@ -1719,6 +2971,60 @@ func TestConfig_Merge(t *testing.T) {
 				PidFile: String("pid_file-diff"),
 			},
 		},
+		{
+			"preflight",
+			&Config{
+				Preflight: Bool(true),
+			},
+			&Config{
+				Preflight: Bool(false),
+			},
+			&Config{
+				Preflight: Bool(false),
+			},
+		},
+		{
+			"profile_render",
+			&Config{
+				ProfileRender: Bool(true),
+			},
+			&Config{
+				ProfileRender: Bool(false),
+			},
+			&Config{
+				ProfileRender: Bool(false),
+			},
+		},
+		{
+			"registration",
+			&Config{
+				Registration: &RegistrationConfig{
+					Service: String("a"),
+				},
+			},
+			&Config{
+				Registration: &RegistrationConfig{
+					Service: String("b"),
+				},
+			},
+			&Config{
+				Registration: &RegistrationConfig{
+					Service: String("b"),
+				},
+			},
+		},
+		{
+			"reload_debounce",
+			&Config{
+				ReloadDebounce: TimeDuration(5 * time.Second),
+			},
+			&Config{
+				ReloadDebounce: TimeDuration(0),
+			},
+			&Config{
+				ReloadDebounce: TimeDuration(0),
+			},
+		},
 		{
 			"reload_signal",
 			&Config{
@@ -1731,6 +3037,117 @@ func TestConfig_Merge(t *testing.T) {
 				ReloadSignal: Signal(syscall.SIGUSR2),
 			},
 		},
+		{
+			"first_pass_timeout",
+			&Config{
+				FirstPassTimeout: TimeDuration(10 * time.Second),
+			},
+			&Config{
+				FirstPassTimeout: TimeDuration(20 * time.Second),
+			},
+			&Config{
+				FirstPassTimeout: TimeDuration(20 * time.Second),
+			},
+		},
+		{
+			"hold_down",
+			&Config{
+				HoldDown: &WaitConfig{
+					Min: TimeDuration(10 * time.Second),
+				},
+			},
+			&Config{
+				HoldDown: &WaitConfig{
+					Min: TimeDuration(20 * time.Second),
+				},
+			},
+			&Config{
+				HoldDown: &WaitConfig{
+					Min: TimeDuration(20 * time.Second),
+				},
+			},
+		},
+		{
+			"retry",
+			&Config{
+				Retry: &RetryConfig{
+					Attempts: Int(3),
+					Enabled:  Bool(true),
+				},
+			},
+			&Config{
+				Retry: &RetryConfig{
+					Attempts: Int(5),
+				},
+			},
+			&Config{
+				Retry: &RetryConfig{
+					Attempts: Int(5),
+					Enabled:  Bool(true),
+				},
+			},
+		},
+		{
+			"render_timeout",
+			&Config{
+				RenderTimeout: TimeDuration(10 * time.Second),
+			},
+			&Config{
+				RenderTimeout: TimeDuration(20 * time.Second),
+			},
+			&Config{
+				RenderTimeout: TimeDuration(20 * time.Second),
+			},
+		},
+		{
+			"shutdown_grace",
+			&Config{
+				ShutdownGrace: TimeDuration(10 * time.Second),
+			},
+			&Config{
+				ShutdownGrace: TimeDuration(20 * time.Second),
+			},
+			&Config{
+				ShutdownGrace: TimeDuration(20 * time.Second),
+			},
+		},
+		{
+			"dependency_gc",
+			&Config{
+				DependencyGC: &DependencyGCConfig{
+					GracePeriod: TimeDuration(5 * time.Minute),
+				},
+			},
+			&Config{
+				DependencyGC: &DependencyGCConfig{
+					Interval: TimeDuration(30 * time.Second),
+				},
+			},
+			&Config{
+				DependencyGC: &DependencyGCConfig{
+					GracePeriod: TimeDuration(5 * time.Minute),
+					Interval:    TimeDuration(30 * time.Second),
+				},
+			},
+		},
+		{
+			"snapshot",
+			&Config{
+				Snapshot: &SnapshotConfig{
+					Enabled: Bool(true),
+				},
+			},
+			&Config{
+				Snapshot: &SnapshotConfig{
+					Enabled: Bool(false),
+				},
+			},
+			&Config{
+				Snapshot: &SnapshotConfig{
+					Enabled: Bool(false),
+				},
+			},
+		},
 		{
 			"syslog",
 			&Config{
@@ -1827,6 +3244,95 @@ func TestConfig_Merge(t *testing.T) {
 	}
 }
 
+func TestParse_unknownKeysWarning(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	if _, err := Parse(`
+		strict = false
+		foo = "bar"
+		consul {
+			bar = "baz"
+		}
+		profile "production" {
+			baz = "qux"
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "[WARN] (config) ignoring unknown keys: consul.bar, foo, profile.production.baz\n"
+	if !strings.HasSuffix(buf.String(), exp) {
+		t.Errorf("expected a single warning %q, got %q", exp, buf.String())
+	}
+	if n := strings.Count(buf.String(), "unknown keys"); n != 1 {
+		t.Errorf("expected 1 warning, got %d", n)
+	}
+}
+
+func TestConfig_WithProfile(t *testing.T) {
+	c := &Config{
+		LogLevel: String("info"),
+		MaxStale: TimeDuration(5 * time.Second),
+		Profiles: map[string]*Config{
+			"production": &Config{
+				MaxStale: TimeDuration(1 * time.Second),
+			},
+		},
+	}
+
+	cases := []struct {
+		name    string
+		profile string
+		e       *Config
+		err     bool
+	}{
+		{
+			"none",
+			"",
+			&Config{
+				LogLevel: String("info"),
+				MaxStale: TimeDuration(5 * time.Second),
+				Profile:  String(""),
+			},
+			false,
+		},
+		{
+			"production",
+			"production",
+			&Config{
+				LogLevel: String("info"),
+				MaxStale: TimeDuration(1 * time.Second),
+				Profile:  String("production"),
+			},
+			false,
+		},
+		{
+			"undeclared",
+			"staging",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r, err := c.WithProfile(tc.profile)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.e, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, r)
+			}
+		})
+	}
+
+	if c.Profiles == nil {
+		t.Errorf("expected the original config to be unchanged")
+	}
+}
+
 func TestFromPath(t *testing.T) {
 	f, err := ioutil.TempFile("", "")
 	if err != nil {
@@ -1920,6 +3426,97 @@ func TestFromPath(t *testing.T) {
 	}
 }
 
+func TestConfig_Finalize_retry(t *testing.T) {
+	cases := []struct {
+		name   string
+		c      *Config
+		consul *RetryConfig
+		vault  *RetryConfig
+	}{
+		{
+			"defaults",
+			&Config{},
+			&RetryConfig{
+				Attempts:   Int(DefaultRetryAttempts),
+				Backoff:    TimeDuration(DefaultRetryBackoff),
+				Enabled:    Bool(true),
+				MaxBackoff: TimeDuration(DefaultRetryMaxBackoff),
+			},
+			&RetryConfig{
+				Attempts:   Int(DefaultRetryAttempts),
+				Backoff:    TimeDuration(DefaultRetryBackoff),
+				Enabled:    Bool(true),
+				MaxBackoff: TimeDuration(DefaultRetryMaxBackoff),
+			},
+		},
+		{
+			"fallback",
+			&Config{
+				Retry: &RetryConfig{
+					Attempts: Int(10),
+					Backoff:  TimeDuration(1 * time.Second),
+				},
+			},
+			&RetryConfig{
+				Attempts:   Int(10),
+				Backoff:    TimeDuration(1 * time.Second),
+				Enabled:    Bool(true),
+				MaxBackoff: TimeDuration(DefaultRetryMaxBackoff),
+			},
+			&RetryConfig{
+				Attempts:   Int(10),
+				Backoff:    TimeDuration(1 * time.Second),
+				Enabled:    Bool(true),
+				MaxBackoff: TimeDuration(DefaultRetryMaxBackoff),
+			},
+		},
+		{
+			"backends_override",
+			&Config{
+				Retry: &RetryConfig{
+					Attempts: Int(10),
+					Backoff:  TimeDuration(1 * time.Second),
+				},
+				Consul: &ConsulConfig{
+					Retry: &RetryConfig{
+						Attempts: Int(0),
+					},
+				},
+				Vault: &VaultConfig{
+					Retry: &RetryConfig{
+						Backoff: TimeDuration(5 * time.Second),
+						Enabled: Bool(false),
+					},
+				},
+			},
+			&RetryConfig{
+				Attempts:   Int(0),
+				Backoff:    TimeDuration(1 * time.Second),
+				Enabled:    Bool(true),
+				MaxBackoff: TimeDuration(DefaultRetryMaxBackoff),
+			},
+			&RetryConfig{
+				Attempts:   Int(10),
+				Backoff:    TimeDuration(5 * time.Second),
+				Enabled:    Bool(false),
+				MaxBackoff: TimeDuration(DefaultRetryMaxBackoff),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.c.Finalize()
+			if !reflect.DeepEqual(tc.consul, tc.c.Consul.Retry) {
+				t.Errorf("consul\nexp: %#v\nact: %#v", tc.consul, tc.c.Consul.Retry)
+			}
+			if !reflect.DeepEqual(tc.vault, tc.c.Vault.Retry) {
+				t.Errorf("vault\nexp: %#v\nact: %#v", tc.vault, tc.c.Vault.Retry)
+			}
+		})
+	}
+}
+
 func TestDefaultConfig(t *testing.T) {
 	cases := []struct {
 		env string
@@ -1,6 +1,9 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 // ConsulConfig contains the configurations options for connecting to a
 // Consul cluster.
@@ -11,6 +14,29 @@ type ConsulConfig struct {
 	// Auth is the HTTP basic authentication for communicating with Consul.
 	Auth *AuthConfig `mapstructure:"auth"`
 
+	// FallbackTokens are tried in order when a request is denied with the
+	// token of its class, so a request can be retried with a token which has
+	// the permissions it needs.
+	FallbackTokens []string `mapstructure:"fallback_tokens" json:"-"`
+
+	// Headers are added to each request to Consul, for proxies and service
+	// meshes which route on them.
+	Headers map[string]string `mapstructure:"headers"`
+
+	// HoldDown is the hold-down timers of Consul dependencies, which hold the
+	// changes of each dependency after one was rendered.
+	HoldDown *WaitConfig `mapstructure:"hold_down"`
+
+	// OAuth2 is the configuration for authenticating to an OAuth2 or OIDC
+	// protected gateway in front of Consul.
+	OAuth2 *OAuth2Config `mapstructure:"oauth2"`
+
+	// RequiredPolicies is the list of ACL policies the token must be linked to,
+	// directly or through its roles. They are checked when starting, so a token
+	// without the permissions templates need fails fast instead of leaving
+	// templates unrendered.
+	RequiredPolicies []string `mapstructure:"required_policies"`
+
 	// Retry is the configuration for specifying how to behave on failure.
 	Retry *RetryConfig `mapstructure:"retry"`
 
@@ -19,7 +45,11 @@ type ConsulConfig struct {
 	SSL *SSLConfig `mapstructure:"ssl"`
 
 	// Token is the token to communicate with Consul securely.
-	Token *string
+	Token *string `json:"-"`
+
+	// Tokens are the tokens for classes of requests, which take precedence
+	// over Token.
+	Tokens *ConsulTokensConfig `mapstructure:"tokens"`
 
 	// Transport configures the low-level network connection details.
 	Transport *TransportConfig `mapstructure:"transport"`
@@ -30,8 +60,11 @@ type ConsulConfig struct {
 func DefaultConsulConfig() *ConsulConfig {
 	return &ConsulConfig{
 		Auth:      DefaultAuthConfig(),
+		HoldDown:  DefaultWaitConfig(),
+		OAuth2:    DefaultOAuth2Config(),
 		Retry:     DefaultRetryConfig(),
 		SSL:       DefaultSSLConfig(),
+		Tokens:    DefaultConsulTokensConfig(),
 		Transport: DefaultTransportConfig(),
 	}
 }
@@ -50,6 +83,24 @@ func (c *ConsulConfig) Copy() *ConsulConfig {
 		o.Auth = c.Auth.Copy()
 	}
 
+	if c.FallbackTokens != nil {
+		o.FallbackTokens = append([]string{}, c.FallbackTokens...)
+	}
+
+	o.Headers = copyHeaders(c.Headers)
+
+	if c.HoldDown != nil {
+		o.HoldDown = c.HoldDown.Copy()
+	}
+
+	if c.OAuth2 != nil {
+		o.OAuth2 = c.OAuth2.Copy()
+	}
+
+	if c.RequiredPolicies != nil {
+		o.RequiredPolicies = append([]string{}, c.RequiredPolicies...)
+	}
+
 	if c.Retry != nil {
 		o.Retry = c.Retry.Copy()
 	}
@@ -60,6 +111,10 @@ func (c *ConsulConfig) Copy() *ConsulConfig {
 
 	o.Token = c.Token
 
+	if c.Tokens != nil {
+		o.Tokens = c.Tokens.Copy()
+	}
+
 	if c.Transport != nil {
 		o.Transport = c.Transport.Copy()
 	}
@@ -93,6 +148,26 @@ func (c *ConsulConfig) Merge(o *ConsulConfig) *ConsulConfig {
 		r.Auth = r.Auth.Merge(o.Auth)
 	}
 
+	if o.FallbackTokens != nil {
+		r.FallbackTokens = append(r.FallbackTokens, o.FallbackTokens...)
+	}
+
+	if o.Headers != nil {
+		r.Headers = mergeHeaders(r.Headers, o.Headers)
+	}
+
+	if o.HoldDown != nil {
+		r.HoldDown = r.HoldDown.Merge(o.HoldDown)
+	}
+
+	if o.OAuth2 != nil {
+		r.OAuth2 = r.OAuth2.Merge(o.OAuth2)
+	}
+
+	if o.RequiredPolicies != nil {
+		r.RequiredPolicies = append(r.RequiredPolicies, o.RequiredPolicies...)
+	}
+
 	if o.Retry != nil {
 		r.Retry = r.Retry.Merge(o.Retry)
 	}
@@ -105,6 +180,10 @@ func (c *ConsulConfig) Merge(o *ConsulConfig) *ConsulConfig {
 		r.Token = o.Token
 	}
 
+	if o.Tokens != nil {
+		r.Tokens = r.Tokens.Merge(o.Tokens)
+	}
+
 	if o.Transport != nil {
 		r.Transport = r.Transport.Merge(o.Transport)
 	}
@@ -125,6 +204,28 @@ func (c *ConsulConfig) Finalize() {
 	}
 	c.Auth.Finalize()
 
+	if c.FallbackTokens == nil {
+		c.FallbackTokens = []string{}
+	}
+
+	if c.Headers == nil {
+		c.Headers = map[string]string{}
+	}
+
+	if c.HoldDown == nil {
+		c.HoldDown = DefaultWaitConfig()
+	}
+	c.HoldDown.Finalize()
+
+	if c.OAuth2 == nil {
+		c.OAuth2 = DefaultOAuth2Config()
+	}
+	c.OAuth2.Finalize()
+
+	if c.RequiredPolicies == nil {
+		c.RequiredPolicies = []string{}
+	}
+
 	if c.Retry == nil {
 		c.Retry = DefaultRetryConfig()
 	}
@@ -142,6 +243,11 @@ func (c *ConsulConfig) Finalize() {
 		}, "")
 	}
 
+	if c.Tokens == nil {
+		c.Tokens = DefaultConsulTokensConfig()
+	}
+	c.Tokens.Finalize()
+
 	if c.Transport == nil {
 		c.Transport = DefaultTransportConfig()
 	}
@@ -157,16 +263,69 @@ func (c *ConsulConfig) GoString() string {
 	return fmt.Sprintf("&ConsulConfig{"+
 		"Address:%s, "+
 		"Auth:%#v, "+
+		"FallbackTokens:%d, "+
+		"Headers:%s, "+
+		"HoldDown:%#v, "+
+		"OAuth2:%#v, "+
+		"RequiredPolicies:%v, "+
 		"Retry:%#v, "+
 		"SSL:%#v, "+
 		"Token:%t, "+
+		"Tokens:%#v, "+
 		"Transport:%#v"+
 		"}",
 		StringGoString(c.Address),
 		c.Auth,
+		len(c.FallbackTokens),
+		headersGoString(c.Headers),
+		c.HoldDown,
+		c.OAuth2,
+		c.RequiredPolicies,
 		c.Retry,
 		c.SSL,
 		StringPresent(c.Token),
+		c.Tokens,
 		c.Transport,
 	)
 }
+
+// copyHeaders returns a copy of the given headers.
+func copyHeaders(h map[string]string) map[string]string {
+	if h == nil {
+		return nil
+	}
+
+	o := make(map[string]string, len(h))
+	for k, v := range h {
+		o[k] = v
+	}
+	return o
+}
+
+// mergeHeaders returns the headers of both maps, with the values in the
+// second map taking precedence.
+func mergeHeaders(a, b map[string]string) map[string]string {
+	r := copyHeaders(a)
+	if r == nil {
+		r = make(map[string]string, len(b))
+	}
+	for k, v := range b {
+		r[k] = v
+	}
+	return r
+}
+
+// headersGoString returns the printable version of the headers. Only the names
+// are printed, since the values may be credentials.
+func headersGoString(h map[string]string) string {
+	if h == nil {
+		return "(map[string]string)(nil)"
+	}
+
+	names := make([]string, 0, len(h))
+	for k := range h {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("%q", names)
+}
@@ -23,11 +23,16 @@ func TestConsulConfig_Copy(t *testing.T) {
 		{
 			"same_enabled",
 			&ConsulConfig{
-				Address: String("1.2.3.4"),
-				Auth:    &AuthConfig{Enabled: Bool(true)},
-				Retry:   &RetryConfig{Enabled: Bool(true)},
-				SSL:     &SSLConfig{Enabled: Bool(true)},
-				Token:   String("abcd1234"),
+				Address:          String("1.2.3.4"),
+				Auth:             &AuthConfig{Enabled: Bool(true)},
+				FallbackTokens:   []string{"efgh5678"},
+				Headers:          map[string]string{"X-Org": "infra"},
+				OAuth2:           &OAuth2Config{Enabled: Bool(true)},
+				RequiredPolicies: []string{"kv-read"},
+				Retry:            &RetryConfig{Enabled: Bool(true)},
+				SSL:              &SSLConfig{Enabled: Bool(true)},
+				Token:            String("abcd1234"),
+				Tokens:           &ConsulTokensConfig{Agent: String("ijkl9012")},
 				Transport: &TransportConfig{
 					DialKeepAlive: TimeDuration(20 * time.Second),
 				},
@@ -220,6 +225,78 @@ func TestConsulConfig_Merge(t *testing.T) {
 			&ConsulConfig{Transport: &TransportConfig{DialKeepAlive: TimeDuration(10 * time.Second)}},
 			&ConsulConfig{Transport: &TransportConfig{DialKeepAlive: TimeDuration(10 * time.Second)}},
 		},
+		{
+			"headers_merges",
+			&ConsulConfig{Headers: map[string]string{"X-Org": "infra", "X-Env": "dev"}},
+			&ConsulConfig{Headers: map[string]string{"X-Env": "prod"}},
+			&ConsulConfig{Headers: map[string]string{"X-Org": "infra", "X-Env": "prod"}},
+		},
+		{
+			"headers_empty_one",
+			&ConsulConfig{Headers: map[string]string{"X-Org": "infra"}},
+			&ConsulConfig{},
+			&ConsulConfig{Headers: map[string]string{"X-Org": "infra"}},
+		},
+		{
+			"headers_empty_two",
+			&ConsulConfig{},
+			&ConsulConfig{Headers: map[string]string{"X-Org": "infra"}},
+			&ConsulConfig{Headers: map[string]string{"X-Org": "infra"}},
+		},
+		{
+			"required_policies_merges",
+			&ConsulConfig{RequiredPolicies: []string{"kv-read"}},
+			&ConsulConfig{RequiredPolicies: []string{"vault-read"}},
+			&ConsulConfig{RequiredPolicies: []string{"kv-read", "vault-read"}},
+		},
+		{
+			"required_policies_empty_one",
+			&ConsulConfig{RequiredPolicies: []string{"kv-read"}},
+			&ConsulConfig{},
+			&ConsulConfig{RequiredPolicies: []string{"kv-read"}},
+		},
+		{
+			"required_policies_empty_two",
+			&ConsulConfig{},
+			&ConsulConfig{RequiredPolicies: []string{"kv-read"}},
+			&ConsulConfig{RequiredPolicies: []string{"kv-read"}},
+		},
+		{
+			"fallback_tokens_merges",
+			&ConsulConfig{FallbackTokens: []string{"a"}},
+			&ConsulConfig{FallbackTokens: []string{"b"}},
+			&ConsulConfig{FallbackTokens: []string{"a", "b"}},
+		},
+		{
+			"tokens_merges",
+			&ConsulConfig{Tokens: &ConsulTokensConfig{Agent: String("a")}},
+			&ConsulConfig{Tokens: &ConsulTokensConfig{Default: String("b")}},
+			&ConsulConfig{Tokens: &ConsulTokensConfig{Agent: String("a"), Default: String("b")}},
+		},
+		{
+			"oauth2_overrides",
+			&ConsulConfig{OAuth2: &OAuth2Config{Enabled: Bool(true)}},
+			&ConsulConfig{OAuth2: &OAuth2Config{Enabled: Bool(false)}},
+			&ConsulConfig{OAuth2: &OAuth2Config{Enabled: Bool(false)}},
+		},
+		{
+			"oauth2_empty_one",
+			&ConsulConfig{OAuth2: &OAuth2Config{Enabled: Bool(true)}},
+			&ConsulConfig{},
+			&ConsulConfig{OAuth2: &OAuth2Config{Enabled: Bool(true)}},
+		},
+		{
+			"oauth2_empty_two",
+			&ConsulConfig{},
+			&ConsulConfig{OAuth2: &OAuth2Config{Enabled: Bool(true)}},
+			&ConsulConfig{OAuth2: &OAuth2Config{Enabled: Bool(true)}},
+		},
+		{
+			"oauth2_same",
+			&ConsulConfig{OAuth2: &OAuth2Config{Enabled: Bool(true)}},
+			&ConsulConfig{OAuth2: &OAuth2Config{Enabled: Bool(true)}},
+			&ConsulConfig{OAuth2: &OAuth2Config{Enabled: Bool(true)}},
+		},
 	}
 
 	for i, tc := range cases {
@@ -248,21 +325,44 @@ func TestConsulConfig_Finalize(t *testing.T) {
 					Username: String(""),
 					Password: String(""),
 				},
+				FallbackTokens: []string{},
+				Headers:        map[string]string{},
+				HoldDown: &WaitConfig{
+					Enabled: Bool(false),
+					Min:     TimeDuration(0),
+					Max:     TimeDuration(0),
+				},
+				OAuth2: &OAuth2Config{
+					ClientID:     String(""),
+					ClientSecret: String(""),
+					Enabled:      Bool(false),
+					Scopes:       []string{},
+					TokenURL:     String(""),
+				},
+				RequiredPolicies: []string{},
 				Retry: &RetryConfig{
-					Backoff:  TimeDuration(DefaultRetryBackoff),
-					Enabled:  Bool(true),
-					Attempts: Int(DefaultRetryAttempts),
+					Backoff:    TimeDuration(DefaultRetryBackoff),
+					Enabled:    Bool(true),
+					Attempts:   Int(DefaultRetryAttempts),
+					MaxBackoff: TimeDuration(DefaultRetryMaxBackoff),
 				},
 				SSL: &SSLConfig{
-					CaCert:     String(""),
-					CaPath:     String(""),
-					Cert:       String(""),
-					Enabled:    Bool(false),
-					Key:        String(""),
-					ServerName: String(""),
-					Verify:     Bool(true),
+					CaCert:          String(""),
+					CaPath:          String(""),
+					Cert:            String(""),
+					Enabled:         Bool(false),
+					Key:             String(""),
+					ServerName:      String(""),
+					Verify:          Bool(true),
+					TLSCipherSuites: []string{},
+					TLSMinVersion:   String(""),
 				},
 				Token: String(""),
+				Tokens: &ConsulTokensConfig{
+					Agent:       String(""),
+					Default:     String(""),
+					Replication: String(""),
+				},
 				Transport: &TransportConfig{
 					DialKeepAlive:       TimeDuration(DefaultDialKeepAlive),
 					DialTimeout:         TimeDuration(DefaultDialTimeout),
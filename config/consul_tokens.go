@@ -0,0 +1,104 @@
+package config
+
+import "fmt"
+
+// ConsulTokensConfig is the configuration of the tokens used for classes of
+// requests to Consul, matching how some deployments segment their ACLs. Each
+// class falls back to the default token when its token is not set.
+type ConsulTokensConfig struct {
+	// Agent is the token for requests to the agent endpoints, such as the
+	// lookup of the local node.
+	Agent *string `mapstructure:"agent" json:"-"`
+
+	// Default is the token for all other requests. It takes precedence over
+	// the token of the Consul configuration.
+	Default *string `mapstructure:"default" json:"-"`
+
+	// Replication is the token for requests to the ACL endpoints, such as the
+	// check of the required policies.
+	Replication *string `mapstructure:"replication" json:"-"`
+}
+
+// DefaultConsulTokensConfig returns a configuration that is populated with
+// the default values.
+func DefaultConsulTokensConfig() *ConsulTokensConfig {
+	return &ConsulTokensConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *ConsulTokensConfig) Copy() *ConsulTokensConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o ConsulTokensConfig
+	o.Agent = c.Agent
+	o.Default = c.Default
+	o.Replication = c.Replication
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *ConsulTokensConfig) Merge(o *ConsulTokensConfig) *ConsulTokensConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Agent != nil {
+		r.Agent = o.Agent
+	}
+
+	if o.Default != nil {
+		r.Default = o.Default
+	}
+
+	if o.Replication != nil {
+		r.Replication = o.Replication
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *ConsulTokensConfig) Finalize() {
+	if c.Agent == nil {
+		c.Agent = String("")
+	}
+
+	if c.Default == nil {
+		c.Default = String("")
+	}
+
+	if c.Replication == nil {
+		c.Replication = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *ConsulTokensConfig) GoString() string {
+	if c == nil {
+		return "(*ConsulTokensConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&ConsulTokensConfig{"+
+		"Agent:%t, "+
+		"Default:%t, "+
+		"Replication:%t"+
+		"}",
+		StringPresent(c.Agent),
+		StringPresent(c.Default),
+		StringPresent(c.Replication),
+	)
+}
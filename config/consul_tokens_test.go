@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConsulTokensConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ConsulTokensConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&ConsulTokensConfig{},
+		},
+		{
+			"copy",
+			&ConsulTokensConfig{
+				Agent:       String("agent"),
+				Default:     String("default"),
+				Replication: String("replication"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestConsulTokensConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ConsulTokensConfig
+		b    *ConsulTokensConfig
+		r    *ConsulTokensConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&ConsulTokensConfig{},
+			&ConsulTokensConfig{},
+		},
+		{
+			"nil_b",
+			&ConsulTokensConfig{},
+			nil,
+			&ConsulTokensConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&ConsulTokensConfig{},
+			&ConsulTokensConfig{},
+			&ConsulTokensConfig{},
+		},
+		{
+			"agent_overrides",
+			&ConsulTokensConfig{Agent: String("a")},
+			&ConsulTokensConfig{Agent: String("b")},
+			&ConsulTokensConfig{Agent: String("b")},
+		},
+		{
+			"default_empty_one",
+			&ConsulTokensConfig{Default: String("a")},
+			&ConsulTokensConfig{},
+			&ConsulTokensConfig{Default: String("a")},
+		},
+		{
+			"replication_empty_two",
+			&ConsulTokensConfig{},
+			&ConsulTokensConfig{Replication: String("b")},
+			&ConsulTokensConfig{Replication: String("b")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestConsulTokensConfig_Finalize(t *testing.T) {
+	c := &ConsulTokensConfig{Agent: String("agent")}
+	c.Finalize()
+
+	exp := &ConsulTokensConfig{
+		Agent:       String("agent"),
+		Default:     String(""),
+		Replication: String(""),
+	}
+	if !reflect.DeepEqual(exp, c) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, c)
+	}
+}
+
+func TestConsulTokensConfig_GoString(t *testing.T) {
+	c := &ConsulTokensConfig{
+		Agent:       String("s3cr3t"),
+		Default:     String("s3cr3t"),
+		Replication: String("s3cr3t"),
+	}
+	if s := c.GoString(); strings.Contains(s, "s3cr3t") {
+		t.Errorf("expected %q to not contain the tokens", s)
+	}
+}
@@ -71,6 +71,37 @@ func FileModePresent(o *os.FileMode) bool {
 	return *o != 0
 }
 
+// Float64 returns a pointer to the given float64.
+func Float64(f float64) *float64 {
+	return &f
+}
+
+// Float64Val returns the value of the float64 at the pointer, or 0 if the
+// pointer is nil.
+func Float64Val(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
+
+// Float64GoString returns the value of the float64 for printing in a string.
+func Float64GoString(f *float64) string {
+	if f == nil {
+		return "(*float64)(nil)"
+	}
+	return fmt.Sprintf("%g", *f)
+}
+
+// Float64Present returns a boolean indiciating if the pointer is nil, or if
+// the pointer is pointing to the zero value.
+func Float64Present(f *float64) bool {
+	if f == nil {
+		return false
+	}
+	return *f != 0
+}
+
 // Int returns a pointer to the given int.
 func Int(i int) *int {
 	return &i
@@ -233,6 +233,120 @@ func TestFileModePresent(t *testing.T) {
 	}
 }
 
+func TestFloat64(t *testing.T) {
+	cases := []struct {
+		name string
+		f    float64
+	}{
+		{
+			"zero",
+			0,
+		},
+		{
+			"present",
+			0.25,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			a := Float64(tc.f)
+			if tc.f != *a {
+				t.Errorf("\nexp: %g\nact: %g", tc.f, *a)
+			}
+		})
+	}
+}
+
+func TestFloat64Val(t *testing.T) {
+	cases := []struct {
+		name string
+		f    *float64
+		e    float64
+	}{
+		{
+			"nil",
+			nil,
+			0,
+		},
+		{
+			"present",
+			Float64(0.5),
+			0.5,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			a := Float64Val(tc.f)
+			if tc.e != a {
+				t.Errorf("\nexp: %g\nact: %g", tc.e, a)
+			}
+		})
+	}
+}
+
+func TestFloat64GoString(t *testing.T) {
+	cases := []struct {
+		name string
+		f    *float64
+		e    string
+	}{
+		{
+			"nil",
+			nil,
+			"(*float64)(nil)",
+		},
+		{
+			"present",
+			Float64(0.125),
+			"0.125",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			s := Float64GoString(tc.f)
+			if tc.e != s {
+				t.Errorf("\nexp: %q\nact: %q", tc.e, s)
+			}
+		})
+	}
+}
+
+func TestFloat64Present(t *testing.T) {
+	cases := []struct {
+		name string
+		f    *float64
+		e    bool
+	}{
+		{
+			"nil",
+			nil,
+			false,
+		},
+		{
+			"present",
+			Float64(0.1),
+			true,
+		},
+		{
+			"present_zero_value",
+			Float64(0),
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			a := Float64Present(tc.f)
+			if tc.e != a {
+				t.Errorf("\nexp: %t\nact: %t", tc.e, a)
+			}
+		})
+	}
+}
+
 func TestInt(t *testing.T) {
 	cases := []struct {
 		name string
@@ -24,6 +24,11 @@ type DedupConfig struct {
 	// Controls if deduplication mode is enabled
 	Enabled *bool `mapstructure:"enabled"`
 
+	// Fingerprint controls if the template hash which keys the shared data
+	// includes a fingerprint of the template functions and the format of the
+	// data, so that instances of different versions do not share data.
+	Fingerprint *bool `mapstructure:"fingerprint"`
+
 	// MaxStale is the maximum amount of time to allow for stale queries.
 	MaxStale *time.Duration `mapstructure:"max_stale"`
 
@@ -48,6 +53,7 @@ func (c *DedupConfig) Copy() *DedupConfig {
 
 	var o DedupConfig
 	o.Enabled = c.Enabled
+	o.Fingerprint = c.Fingerprint
 	o.MaxStale = c.MaxStale
 	o.Prefix = c.Prefix
 	o.TTL = c.TTL
@@ -76,6 +82,10 @@ func (c *DedupConfig) Merge(o *DedupConfig) *DedupConfig {
 		r.Enabled = o.Enabled
 	}
 
+	if o.Fingerprint != nil {
+		r.Fingerprint = o.Fingerprint
+	}
+
 	if o.MaxStale != nil {
 		r.MaxStale = o.MaxStale
 	}
@@ -100,6 +110,10 @@ func (c *DedupConfig) Finalize() {
 			TimeDurationPresent(c.TTL))
 	}
 
+	if c.Fingerprint == nil {
+		c.Fingerprint = Bool(true)
+	}
+
 	if c.MaxStale == nil {
 		c.MaxStale = TimeDuration(DefaultDedupMaxStale)
 	}
@@ -120,11 +134,13 @@ func (c *DedupConfig) GoString() string {
 	}
 	return fmt.Sprintf("&DedupConfig{"+
 		"Enabled:%s, "+
+		"Fingerprint:%s, "+
 		"MaxStale:%s, "+
 		"Prefix:%s, "+
 		"TTL:%s"+
 		"}",
 		BoolGoString(c.Enabled),
+		BoolGoString(c.Fingerprint),
 		TimeDurationGoString(c.MaxStale),
 		StringGoString(c.Prefix),
 		TimeDurationGoString(c.TTL),
@@ -23,10 +23,11 @@ func TestDedupConfig_Copy(t *testing.T) {
 		{
 			"copy",
 			&DedupConfig{
-				Enabled:  Bool(true),
-				MaxStale: TimeDuration(30 * time.Second),
-				Prefix:   String("prefix"),
-				TTL:      TimeDuration(10 * time.Second),
+				Enabled:     Bool(true),
+				Fingerprint: Bool(false),
+				MaxStale:    TimeDuration(30 * time.Second),
+				Prefix:      String("prefix"),
+				TTL:         TimeDuration(10 * time.Second),
 			},
 		},
 	}
@@ -96,6 +97,24 @@ func TestDedupConfig_Merge(t *testing.T) {
 			&DedupConfig{Enabled: Bool(true)},
 			&DedupConfig{Enabled: Bool(true)},
 		},
+		{
+			"fingerprint_overrides",
+			&DedupConfig{Fingerprint: Bool(true)},
+			&DedupConfig{Fingerprint: Bool(false)},
+			&DedupConfig{Fingerprint: Bool(false)},
+		},
+		{
+			"fingerprint_empty_one",
+			&DedupConfig{Fingerprint: Bool(false)},
+			&DedupConfig{},
+			&DedupConfig{Fingerprint: Bool(false)},
+		},
+		{
+			"fingerprint_empty_two",
+			&DedupConfig{},
+			&DedupConfig{Fingerprint: Bool(false)},
+			&DedupConfig{Fingerprint: Bool(false)},
+		},
 		{
 			"max_stale_overrides",
 			&DedupConfig{MaxStale: TimeDuration(10 * time.Second)},
@@ -190,10 +209,24 @@ func TestDedupConfig_Finalize(t *testing.T) {
 			"empty",
 			&DedupConfig{},
 			&DedupConfig{
-				Enabled:  Bool(false),
-				MaxStale: TimeDuration(DefaultDedupMaxStale),
-				Prefix:   String(DefaultDedupPrefix),
-				TTL:      TimeDuration(DefaultDedupTTL),
+				Enabled:     Bool(false),
+				Fingerprint: Bool(true),
+				MaxStale:    TimeDuration(DefaultDedupMaxStale),
+				Prefix:      String(DefaultDedupPrefix),
+				TTL:         TimeDuration(DefaultDedupTTL),
+			},
+		},
+		{
+			"with_fingerprint",
+			&DedupConfig{
+				Fingerprint: Bool(false),
+			},
+			&DedupConfig{
+				Enabled:     Bool(false),
+				Fingerprint: Bool(false),
+				MaxStale:    TimeDuration(DefaultDedupMaxStale),
+				Prefix:      String(DefaultDedupPrefix),
+				TTL:         TimeDuration(DefaultDedupTTL),
 			},
 		},
 		{
@@ -202,10 +235,11 @@ func TestDedupConfig_Finalize(t *testing.T) {
 				MaxStale: TimeDuration(10 * time.Second),
 			},
 			&DedupConfig{
-				Enabled:  Bool(true),
-				MaxStale: TimeDuration(10 * time.Second),
-				Prefix:   String(DefaultDedupPrefix),
-				TTL:      TimeDuration(DefaultDedupTTL),
+				Enabled:     Bool(true),
+				Fingerprint: Bool(true),
+				MaxStale:    TimeDuration(10 * time.Second),
+				Prefix:      String(DefaultDedupPrefix),
+				TTL:         TimeDuration(DefaultDedupTTL),
 			},
 		},
 		{
@@ -214,10 +248,11 @@ func TestDedupConfig_Finalize(t *testing.T) {
 				Prefix: String("prefix"),
 			},
 			&DedupConfig{
-				Enabled:  Bool(true),
-				MaxStale: TimeDuration(DefaultDedupMaxStale),
-				Prefix:   String("prefix"),
-				TTL:      TimeDuration(DefaultDedupTTL),
+				Enabled:     Bool(true),
+				Fingerprint: Bool(true),
+				MaxStale:    TimeDuration(DefaultDedupMaxStale),
+				Prefix:      String("prefix"),
+				TTL:         TimeDuration(DefaultDedupTTL),
 			},
 		},
 		{
@@ -226,10 +261,11 @@ func TestDedupConfig_Finalize(t *testing.T) {
 				TTL: TimeDuration(10 * time.Second),
 			},
 			&DedupConfig{
-				Enabled:  Bool(true),
-				MaxStale: TimeDuration(DefaultDedupMaxStale),
-				Prefix:   String(DefaultDedupPrefix),
-				TTL:      TimeDuration(10 * time.Second),
+				Enabled:     Bool(true),
+				Fingerprint: Bool(true),
+				MaxStale:    TimeDuration(DefaultDedupMaxStale),
+				Prefix:      String(DefaultDedupPrefix),
+				TTL:         TimeDuration(10 * time.Second),
 			},
 		},
 	}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DependencyGCConfig is the configuration for stopping the watches of
+// dependencies which are no longer used by any template.
+type DependencyGCConfig struct {
+	// GracePeriod is the amount of time a dependency must have been unused
+	// before its watch is stopped. A dependency which is used again within the
+	// grace period keeps its watch and data, which avoids re-fetching data for
+	// templates whose dependencies come and go. The default is to stop watches
+	// as soon as they are found to be unused.
+	GracePeriod *time.Duration `mapstructure:"grace_period"`
+
+	// Interval is the amount of time between sweeps for unused dependencies.
+	// The default is to sweep each time the templates are evaluated.
+	Interval *time.Duration `mapstructure:"interval"`
+}
+
+// DefaultDependencyGCConfig returns a configuration that is populated with the
+// default values.
+func DefaultDependencyGCConfig() *DependencyGCConfig {
+	return &DependencyGCConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *DependencyGCConfig) Copy() *DependencyGCConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o DependencyGCConfig
+	o.GracePeriod = c.GracePeriod
+	o.Interval = c.Interval
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *DependencyGCConfig) Merge(o *DependencyGCConfig) *DependencyGCConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.GracePeriod != nil {
+		r.GracePeriod = o.GracePeriod
+	}
+
+	if o.Interval != nil {
+		r.Interval = o.Interval
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *DependencyGCConfig) Finalize() {
+	if c.GracePeriod == nil {
+		c.GracePeriod = TimeDuration(0)
+	}
+
+	if c.Interval == nil {
+		c.Interval = TimeDuration(0)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *DependencyGCConfig) GoString() string {
+	if c == nil {
+		return "(*DependencyGCConfig)(nil)"
+	}
+	return fmt.Sprintf("&DependencyGCConfig{"+
+		"GracePeriod:%s, "+
+		"Interval:%s"+
+		"}",
+		TimeDurationGoString(c.GracePeriod),
+		TimeDurationGoString(c.Interval),
+	)
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDependencyGCConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *DependencyGCConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&DependencyGCConfig{},
+		},
+		{
+			"same",
+			&DependencyGCConfig{
+				GracePeriod: TimeDuration(5 * time.Minute),
+				Interval:    TimeDuration(30 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestDependencyGCConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *DependencyGCConfig
+		b    *DependencyGCConfig
+		r    *DependencyGCConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&DependencyGCConfig{},
+			&DependencyGCConfig{},
+		},
+		{
+			"nil_b",
+			&DependencyGCConfig{},
+			nil,
+			&DependencyGCConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&DependencyGCConfig{},
+			&DependencyGCConfig{},
+			&DependencyGCConfig{},
+		},
+		{
+			"grace_period_overrides",
+			&DependencyGCConfig{GracePeriod: TimeDuration(5 * time.Minute)},
+			&DependencyGCConfig{GracePeriod: TimeDuration(0)},
+			&DependencyGCConfig{GracePeriod: TimeDuration(0)},
+		},
+		{
+			"grace_period_empty_one",
+			&DependencyGCConfig{GracePeriod: TimeDuration(5 * time.Minute)},
+			&DependencyGCConfig{},
+			&DependencyGCConfig{GracePeriod: TimeDuration(5 * time.Minute)},
+		},
+		{
+			"grace_period_empty_two",
+			&DependencyGCConfig{},
+			&DependencyGCConfig{GracePeriod: TimeDuration(5 * time.Minute)},
+			&DependencyGCConfig{GracePeriod: TimeDuration(5 * time.Minute)},
+		},
+		{
+			"interval_overrides",
+			&DependencyGCConfig{Interval: TimeDuration(30 * time.Second)},
+			&DependencyGCConfig{Interval: TimeDuration(0)},
+			&DependencyGCConfig{Interval: TimeDuration(0)},
+		},
+		{
+			"interval_empty_one",
+			&DependencyGCConfig{Interval: TimeDuration(30 * time.Second)},
+			&DependencyGCConfig{},
+			&DependencyGCConfig{Interval: TimeDuration(30 * time.Second)},
+		},
+		{
+			"interval_empty_two",
+			&DependencyGCConfig{},
+			&DependencyGCConfig{Interval: TimeDuration(30 * time.Second)},
+			&DependencyGCConfig{Interval: TimeDuration(30 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestDependencyGCConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *DependencyGCConfig
+		r    *DependencyGCConfig
+	}{
+		{
+			"empty",
+			&DependencyGCConfig{},
+			&DependencyGCConfig{
+				GracePeriod: TimeDuration(0),
+				Interval:    TimeDuration(0),
+			},
+		},
+		{
+			"with_grace_period",
+			&DependencyGCConfig{
+				GracePeriod: TimeDuration(5 * time.Minute),
+			},
+			&DependencyGCConfig{
+				GracePeriod: TimeDuration(5 * time.Minute),
+				Interval:    TimeDuration(0),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import "fmt"
+
+const (
+	// DiffFormatUnified writes a unified diff of the contents.
+	DiffFormatUnified = "unified"
+
+	// DiffFormatJSONPatch writes an RFC 6902 JSON Patch of the contents, which
+	// must be JSON.
+	DiffFormatJSONPatch = "json_patch"
+
+	// DefaultDiffFormat is the default format of diff artifacts.
+	DefaultDiffFormat = DiffFormatUnified
+)
+
+// DiffConfig is the configuration for writing an artifact describing each
+// change to a template's destination, for use by external review tooling.
+type DiffConfig struct {
+	// Dir is the directory where the artifacts are written.
+	Dir *string `mapstructure:"dir"`
+
+	// Enabled controls if artifacts are written.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Format is the format of the artifacts, either "unified" or "json_patch".
+	Format *string `mapstructure:"format"`
+}
+
+// DefaultDiffConfig returns a configuration that is populated with the
+// default values.
+func DefaultDiffConfig() *DiffConfig {
+	return &DiffConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *DiffConfig) Copy() *DiffConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o DiffConfig
+	o.Dir = c.Dir
+	o.Enabled = c.Enabled
+	o.Format = c.Format
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *DiffConfig) Merge(o *DiffConfig) *DiffConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Dir != nil {
+		r.Dir = o.Dir
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Format != nil {
+		r.Format = o.Format
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *DiffConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Dir))
+	}
+
+	if c.Dir == nil {
+		c.Dir = String("")
+	}
+
+	if c.Format == nil {
+		c.Format = String(DefaultDiffFormat)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *DiffConfig) GoString() string {
+	if c == nil {
+		return "(*DiffConfig)(nil)"
+	}
+	return fmt.Sprintf("&DiffConfig{"+
+		"Dir:%s, "+
+		"Enabled:%s, "+
+		"Format:%s"+
+		"}",
+		StringGoString(c.Dir),
+		BoolGoString(c.Enabled),
+		StringGoString(c.Format),
+	)
+}
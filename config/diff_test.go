@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDiffConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *DiffConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&DiffConfig{},
+		},
+		{
+			"copy",
+			&DiffConfig{
+				Dir:     String("/tmp/diffs"),
+				Enabled: Bool(true),
+				Format:  String("json_patch"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestDiffConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *DiffConfig
+		b    *DiffConfig
+		r    *DiffConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&DiffConfig{},
+			&DiffConfig{},
+		},
+		{
+			"nil_b",
+			&DiffConfig{},
+			nil,
+			&DiffConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&DiffConfig{},
+			&DiffConfig{},
+			&DiffConfig{},
+		},
+		{
+			"dir_overrides",
+			&DiffConfig{Dir: String("/tmp/diffs")},
+			&DiffConfig{Dir: String("")},
+			&DiffConfig{Dir: String("")},
+		},
+		{
+			"dir_empty_one",
+			&DiffConfig{Dir: String("/tmp/diffs")},
+			&DiffConfig{},
+			&DiffConfig{Dir: String("/tmp/diffs")},
+		},
+		{
+			"dir_empty_two",
+			&DiffConfig{},
+			&DiffConfig{Dir: String("/tmp/diffs")},
+			&DiffConfig{Dir: String("/tmp/diffs")},
+		},
+		{
+			"dir_same",
+			&DiffConfig{Dir: String("/tmp/diffs")},
+			&DiffConfig{Dir: String("/tmp/diffs")},
+			&DiffConfig{Dir: String("/tmp/diffs")},
+		},
+		{
+			"enabled_overrides",
+			&DiffConfig{Enabled: Bool(true)},
+			&DiffConfig{Enabled: Bool(false)},
+			&DiffConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&DiffConfig{Enabled: Bool(true)},
+			&DiffConfig{},
+			&DiffConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&DiffConfig{},
+			&DiffConfig{Enabled: Bool(true)},
+			&DiffConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&DiffConfig{Enabled: Bool(true)},
+			&DiffConfig{Enabled: Bool(true)},
+			&DiffConfig{Enabled: Bool(true)},
+		},
+		{
+			"format_overrides",
+			&DiffConfig{Format: String("json_patch")},
+			&DiffConfig{Format: String("unified")},
+			&DiffConfig{Format: String("unified")},
+		},
+		{
+			"format_empty_one",
+			&DiffConfig{Format: String("json_patch")},
+			&DiffConfig{},
+			&DiffConfig{Format: String("json_patch")},
+		},
+		{
+			"format_empty_two",
+			&DiffConfig{},
+			&DiffConfig{Format: String("json_patch")},
+			&DiffConfig{Format: String("json_patch")},
+		},
+		{
+			"format_same",
+			&DiffConfig{Format: String("json_patch")},
+			&DiffConfig{Format: String("json_patch")},
+			&DiffConfig{Format: String("json_patch")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestDiffConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *DiffConfig
+		r    *DiffConfig
+	}{
+		{
+			"empty",
+			&DiffConfig{},
+			&DiffConfig{
+				Dir:     String(""),
+				Enabled: Bool(false),
+				Format:  String(DefaultDiffFormat),
+			},
+		},
+		{
+			"with_dir",
+			&DiffConfig{
+				Dir: String("/tmp/diffs"),
+			},
+			&DiffConfig{
+				Dir:     String("/tmp/diffs"),
+				Enabled: Bool(true),
+				Format:  String(DefaultDiffFormat),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-template/signals"
+)
+
+// redacted replaces the value of secrets in dumped configuration.
+const redacted = "<redacted>"
+
+var (
+	// durationType, fileModeType and signalType are the types which are dumped
+	// as they are written in configuration files instead of by their kind.
+	durationType = reflect.TypeOf(time.Duration(0))
+	fileModeType = reflect.TypeOf(os.FileMode(0))
+	signalType   = reflect.TypeOf((*os.Signal)(nil)).Elem()
+
+	// hclIdentRe matches keys which do not need quoting in HCL.
+	hclIdentRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_\-]*$`)
+
+	// secretHeaders are the lowercased names of the headers which carry
+	// credentials, and whose values are redacted.
+	secretHeaders = map[string]struct{}{
+		"authorization":       struct{}{},
+		"proxy-authorization": struct{}{},
+		"x-consul-token":      struct{}{},
+		"x-vault-token":       struct{}{},
+	}
+)
+
+// Dump returns the configuration in the given format, "hcl" or "json", as it
+// would be written in a configuration file. This is mostly useful for showing
+// the effect of defaults and merging on a finalized configuration. Unset
+// options are omitted, and the values of options tagged `json:"-"`, which hold
+// secrets, are redacted, as are the values of headers carrying credentials.
+func (c *Config) Dump(format string) ([]byte, error) {
+	m, _ := dumpValue(reflect.ValueOf(c), false).(map[string]interface{})
+
+	var b bytes.Buffer
+	switch format {
+	case "hcl":
+		writeHCLBody(&b, m, "", nil, 0)
+		return b.Bytes(), nil
+	case "json":
+		// Template contents are likely to contain HTML, which is kept as is.
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(m); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("config: unknown format %q", format)
+	}
+}
+
+// redact returns the value of a secret as dumped, or of each secret of a
+// list. Empty secrets are kept, since they are not set.
+func redact(val interface{}) interface{} {
+	switch v := val.(type) {
+	case string:
+		if v == "" {
+			return v
+		}
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i := range v {
+			l[i] = redact(v[i])
+		}
+		return l
+	}
+	return redacted
+}
+
+// redactHeaders returns the headers as dumped, with the values of the headers
+// carrying credentials redacted. The headers are either a map of names to
+// values, or a list of "Name: value" strings.
+func redactHeaders(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, h := range v {
+			if _, ok := secretHeaders[strings.ToLower(k)]; ok {
+				h = redact(h)
+			}
+			m[k] = h
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, h := range v {
+			l[i] = h
+			s, ok := h.(string)
+			if !ok {
+				continue
+			}
+			parts := strings.SplitN(s, ":", 2)
+			if _, ok := secretHeaders[strings.ToLower(strings.TrimSpace(parts[0]))]; ok && len(parts) == 2 {
+				l[i] = parts[0] + ": " + redacted
+			}
+		}
+		return l
+	}
+	return val
+}
+
+// dumpValue converts the given value into maps, slices and scalars, returning
+// nil for unset values. Secrets are redacted unless they are to be kept.
+func dumpValue(v reflect.Value, secrets bool) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Implements(signalType) {
+			return signalName(v.Interface().(os.Signal))
+		}
+		return dumpValue(v.Elem(), secrets)
+	}
+
+	switch v.Type() {
+	case durationType:
+		return time.Duration(v.Int()).String()
+	case fileModeType:
+		return fmt.Sprintf("%04o", v.Uint())
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+
+			name := f.Tag.Get("mapstructure")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+
+			val := dumpValue(v.Field(i), secrets)
+			if val == nil {
+				continue
+			}
+			if !secrets {
+				switch {
+				case f.Tag.Get("json") == "-":
+					val = redact(val)
+				case name == "headers":
+					val = redactHeaders(val)
+				}
+			}
+			m[name] = val
+		}
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	case reflect.Map:
+		if v.Len() == 0 {
+			return nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			if val := dumpValue(v.MapIndex(k), secrets); val != nil {
+				m[fmt.Sprintf("%v", k.Interface())] = val
+			}
+		}
+		return m
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return nil
+		}
+		l := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if val := dumpValue(v.Index(i), secrets); val != nil {
+				l = append(l, val)
+			}
+		}
+		return l
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	default:
+		return nil
+	}
+}
+
+// signalName returns the name the given signal is parsed from, or an empty
+// string for the nil signal.
+func signalName(s os.Signal) string {
+	if s == signals.SIGNIL {
+		return ""
+	}
+	for _, name := range signals.ValidSignals {
+		if signals.SignalLookup[name] == s {
+			return name
+		}
+	}
+	return s.String()
+}
+
+// writeHCLBody writes the given map as HCL, with nested maps as blocks and
+// lists of maps as repeated blocks. The comments are keyed by the path of the
+// option they are written above, such as "vault.renew_token", where repeated
+// blocks are indexed like "template[0].wait". The path is the prefix of the
+// options of the map.
+func writeHCLBody(b *bytes.Buffer, m map[string]interface{}, path string, comments map[string][]string, depth int) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	indent := strings.Repeat("  ", depth)
+	for _, k := range keys {
+		key := k
+		if !hclIdentRe.MatchString(k) {
+			key = strconv.Quote(k)
+		}
+
+		for _, comment := range comments[path+k] {
+			fmt.Fprintf(b, "%s# %s\n", indent, comment)
+		}
+
+		switch val := m[k].(type) {
+		case map[string]interface{}:
+			fmt.Fprintf(b, "%s%s {\n", indent, key)
+			writeHCLBody(b, val, path+k+".", comments, depth+1)
+			fmt.Fprintf(b, "%s}\n", indent)
+		case []interface{}:
+			if _, ok := val[0].(map[string]interface{}); ok {
+				for i, item := range val {
+					prefix := fmt.Sprintf("%s%s[%d].", path, k, i)
+					fmt.Fprintf(b, "%s%s {\n", indent, key)
+					writeHCLBody(b, item.(map[string]interface{}), prefix, comments, depth+1)
+					fmt.Fprintf(b, "%s}\n", indent)
+				}
+				continue
+			}
+			items := make([]string, 0, len(val))
+			for _, item := range val {
+				items = append(items, hclValue(item))
+			}
+			fmt.Fprintf(b, "%s%s = [%s]\n", indent, key, strings.Join(items, ", "))
+		default:
+			fmt.Fprintf(b, "%s%s = %s\n", indent, key, hclValue(val))
+		}
+	}
+}
+
+// hclValue returns the HCL literal for the given scalar.
+func hclValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprintf("%v", v)
+}
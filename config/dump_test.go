@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestConfig_Dump(t *testing.T) {
+	cases := []struct {
+		name   string
+		c      *Config
+		format string
+		e      string
+		err    bool
+	}{
+		{
+			"empty",
+			&Config{},
+			"hcl",
+			"",
+			false,
+		},
+		{
+			"hcl",
+			&Config{
+				Consul: &ConsulConfig{
+					Address: String("1.2.3.4"),
+					Headers: map[string]string{
+						"Authorization": "Bearer abcd1234",
+						"X-Foo":         "bar",
+					},
+					Token: String("abcd1234"),
+				},
+				KillSignal: Signal(syscall.SIGINT),
+				MaxStale:   TimeDuration(10 * time.Second),
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Destination: String("/tmp/a"),
+						Perms:       FileMode(0600),
+					},
+					&TemplateConfig{
+						Contents: String("{{ key \"foo\" }}\n"),
+						HTTP: &HTTPDestinationConfig{
+							Headers: []string{"x-vault-token: abcd1234", "X-Foo: bar"},
+						},
+					},
+				},
+				Vault: &VaultConfig{
+					Headers: map[string]string{"X-Vault-Token": "abcd1234"},
+					Token:   String(""),
+				},
+			},
+			"hcl",
+			`consul {
+  address = "1.2.3.4"
+  headers {
+    Authorization = "<redacted>"
+    X-Foo = "bar"
+  }
+  token = "<redacted>"
+}
+kill_signal = "SIGINT"
+max_stale = "10s"
+template {
+  destination = "/tmp/a"
+  perms = "0600"
+}
+template {
+  contents = "{{ key \"foo\" }}\n"
+  http {
+    headers = ["x-vault-token: <redacted>", "X-Foo: bar"]
+  }
+}
+vault {
+  headers {
+    X-Vault-Token = "<redacted>"
+  }
+  token = ""
+}
+`,
+			false,
+		},
+		{
+			"json",
+			&Config{
+				Consul: &ConsulConfig{
+					Auth: &AuthConfig{
+						Username: String("foo"),
+						Password: String("bar"),
+					},
+					FallbackTokens: []string{"abcd1234", "efgh5678"},
+				},
+				Dedup: &DedupConfig{
+					TTL: TimeDuration(15 * time.Second),
+				},
+				Sandbox: &SandboxConfig{
+					AllowedPorts: []int{8125},
+				},
+			},
+			"json",
+			`{
+  "consul": {
+    "auth": {
+      "password": "<redacted>",
+      "username": "foo"
+    },
+    "fallback_tokens": [
+      "<redacted>",
+      "<redacted>"
+    ]
+  },
+  "deduplicate": {
+    "ttl": "15s"
+  },
+  "sandbox": {
+    "allowed_ports": [
+      8125
+    ]
+  }
+}
+`,
+			false,
+		},
+		{
+			"unknown_format",
+			&Config{},
+			"yaml",
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			b, err := tc.c.Dump(tc.format)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if string(b) != tc.e {
+				t.Errorf("\nexp: %s\nact: %s", tc.e, b)
+			}
+		})
+	}
+}
+
+func TestConfig_Dump_roundtrip(t *testing.T) {
+	for _, format := range []string{"hcl", "json"} {
+		t.Run(format, func(t *testing.T) {
+			c := DefaultConfig()
+			c.Templates = &TemplateConfigs{
+				&TemplateConfig{
+					Contents:    String("{{ key \"foo\" }}"),
+					Destination: String("/tmp/a"),
+					Perms:       FileMode(0600),
+				},
+			}
+			c.RenderGroups = &RenderGroupConfigs{
+				&RenderGroupConfig{
+					Name:      String("frontend"),
+					Templates: []string{"/tmp/a"},
+				},
+			}
+			c.Finalize()
+
+			b, err := c.Dump(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := Parse(string(b))
+			if err != nil {
+				t.Fatalf("%s: %s", err, b)
+			}
+			parsed = DefaultConfig().Merge(parsed)
+			parsed.Finalize()
+
+			if !reflect.DeepEqual(c, parsed) {
+				t.Errorf("\nexp: %#v\nact: %#v", c, parsed)
+			}
+		})
+	}
+}
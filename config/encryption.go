@@ -0,0 +1,124 @@
+package config
+
+import "fmt"
+
+const (
+	// DefaultEncryptionVaultTransitMount is the default path where the Vault
+	// transit secrets engine is mounted.
+	DefaultEncryptionVaultTransitMount = "transit"
+)
+
+// EncryptionConfig is the configuration for encrypting files which may contain
+// secrets, such as backups and snapshots, before they are written to disk. The
+// key is either read from a local key file, or is a data key generated and
+// wrapped by a Vault transit key, but not both.
+type EncryptionConfig struct {
+	// Enabled controls whether files are encrypted.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// KeyFile is the path to a file holding a 32 byte AES-256 key, either raw
+	// or base64 encoded.
+	KeyFile *string `mapstructure:"key_file"`
+
+	// VaultTransitKey is the name of the Vault transit key which generates and
+	// wraps the data key of each file.
+	VaultTransitKey *string `mapstructure:"vault_transit_key"`
+
+	// VaultTransitMount is the path where the Vault transit secrets engine is
+	// mounted.
+	VaultTransitMount *string `mapstructure:"vault_transit_mount"`
+}
+
+// DefaultEncryptionConfig returns a configuration that is populated with the
+// default values.
+func DefaultEncryptionConfig() *EncryptionConfig {
+	return &EncryptionConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *EncryptionConfig) Copy() *EncryptionConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o EncryptionConfig
+	o.Enabled = c.Enabled
+	o.KeyFile = c.KeyFile
+	o.VaultTransitKey = c.VaultTransitKey
+	o.VaultTransitMount = c.VaultTransitMount
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *EncryptionConfig) Merge(o *EncryptionConfig) *EncryptionConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.KeyFile != nil {
+		r.KeyFile = o.KeyFile
+	}
+
+	if o.VaultTransitKey != nil {
+		r.VaultTransitKey = o.VaultTransitKey
+	}
+
+	if o.VaultTransitMount != nil {
+		r.VaultTransitMount = o.VaultTransitMount
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *EncryptionConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.KeyFile) || StringPresent(c.VaultTransitKey))
+	}
+
+	if c.KeyFile == nil {
+		c.KeyFile = String("")
+	}
+
+	if c.VaultTransitKey == nil {
+		c.VaultTransitKey = String("")
+	}
+
+	if c.VaultTransitMount == nil {
+		c.VaultTransitMount = String(DefaultEncryptionVaultTransitMount)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *EncryptionConfig) GoString() string {
+	if c == nil {
+		return "(*EncryptionConfig)(nil)"
+	}
+	return fmt.Sprintf("&EncryptionConfig{"+
+		"Enabled:%s, "+
+		"KeyFile:%s, "+
+		"VaultTransitKey:%s, "+
+		"VaultTransitMount:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		StringGoString(c.KeyFile),
+		StringGoString(c.VaultTransitKey),
+		StringGoString(c.VaultTransitMount),
+	)
+}
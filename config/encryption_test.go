@@ -0,0 +1,246 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEncryptionConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *EncryptionConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&EncryptionConfig{},
+		},
+		{
+			"copy",
+			&EncryptionConfig{
+				Enabled:           Bool(true),
+				KeyFile:           String(""),
+				VaultTransitKey:   String("consul-template"),
+				VaultTransitMount: String("kms"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestEncryptionConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *EncryptionConfig
+		b    *EncryptionConfig
+		r    *EncryptionConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&EncryptionConfig{},
+			&EncryptionConfig{},
+		},
+		{
+			"nil_b",
+			&EncryptionConfig{},
+			nil,
+			&EncryptionConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&EncryptionConfig{},
+			&EncryptionConfig{},
+			&EncryptionConfig{},
+		},
+		{
+			"enabled_overrides",
+			&EncryptionConfig{Enabled: Bool(true)},
+			&EncryptionConfig{Enabled: Bool(false)},
+			&EncryptionConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&EncryptionConfig{Enabled: Bool(true)},
+			&EncryptionConfig{},
+			&EncryptionConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&EncryptionConfig{},
+			&EncryptionConfig{Enabled: Bool(true)},
+			&EncryptionConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&EncryptionConfig{Enabled: Bool(true)},
+			&EncryptionConfig{Enabled: Bool(true)},
+			&EncryptionConfig{Enabled: Bool(true)},
+		},
+		{
+			"key_file_overrides",
+			&EncryptionConfig{KeyFile: String("/etc/ct.key")},
+			&EncryptionConfig{KeyFile: String("")},
+			&EncryptionConfig{KeyFile: String("")},
+		},
+		{
+			"key_file_empty_one",
+			&EncryptionConfig{KeyFile: String("/etc/ct.key")},
+			&EncryptionConfig{},
+			&EncryptionConfig{KeyFile: String("/etc/ct.key")},
+		},
+		{
+			"key_file_empty_two",
+			&EncryptionConfig{},
+			&EncryptionConfig{KeyFile: String("/etc/ct.key")},
+			&EncryptionConfig{KeyFile: String("/etc/ct.key")},
+		},
+		{
+			"key_file_same",
+			&EncryptionConfig{KeyFile: String("/etc/ct.key")},
+			&EncryptionConfig{KeyFile: String("/etc/ct.key")},
+			&EncryptionConfig{KeyFile: String("/etc/ct.key")},
+		},
+		{
+			"vault_transit_key_overrides",
+			&EncryptionConfig{VaultTransitKey: String("consul-template")},
+			&EncryptionConfig{VaultTransitKey: String("")},
+			&EncryptionConfig{VaultTransitKey: String("")},
+		},
+		{
+			"vault_transit_key_empty_one",
+			&EncryptionConfig{VaultTransitKey: String("consul-template")},
+			&EncryptionConfig{},
+			&EncryptionConfig{VaultTransitKey: String("consul-template")},
+		},
+		{
+			"vault_transit_key_empty_two",
+			&EncryptionConfig{},
+			&EncryptionConfig{VaultTransitKey: String("consul-template")},
+			&EncryptionConfig{VaultTransitKey: String("consul-template")},
+		},
+		{
+			"vault_transit_key_same",
+			&EncryptionConfig{VaultTransitKey: String("consul-template")},
+			&EncryptionConfig{VaultTransitKey: String("consul-template")},
+			&EncryptionConfig{VaultTransitKey: String("consul-template")},
+		},
+		{
+			"vault_transit_mount_overrides",
+			&EncryptionConfig{VaultTransitMount: String("kms")},
+			&EncryptionConfig{VaultTransitMount: String("transit")},
+			&EncryptionConfig{VaultTransitMount: String("transit")},
+		},
+		{
+			"vault_transit_mount_empty_one",
+			&EncryptionConfig{VaultTransitMount: String("kms")},
+			&EncryptionConfig{},
+			&EncryptionConfig{VaultTransitMount: String("kms")},
+		},
+		{
+			"vault_transit_mount_empty_two",
+			&EncryptionConfig{},
+			&EncryptionConfig{VaultTransitMount: String("kms")},
+			&EncryptionConfig{VaultTransitMount: String("kms")},
+		},
+		{
+			"vault_transit_mount_same",
+			&EncryptionConfig{VaultTransitMount: String("kms")},
+			&EncryptionConfig{VaultTransitMount: String("kms")},
+			&EncryptionConfig{VaultTransitMount: String("kms")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestEncryptionConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *EncryptionConfig
+		r    *EncryptionConfig
+	}{
+		{
+			"empty",
+			&EncryptionConfig{},
+			&EncryptionConfig{
+				Enabled:           Bool(false),
+				KeyFile:           String(""),
+				VaultTransitKey:   String(""),
+				VaultTransitMount: String(DefaultEncryptionVaultTransitMount),
+			},
+		},
+		{
+			"with_key_file",
+			&EncryptionConfig{
+				KeyFile: String("/etc/ct.key"),
+			},
+			&EncryptionConfig{
+				Enabled:           Bool(true),
+				KeyFile:           String("/etc/ct.key"),
+				VaultTransitKey:   String(""),
+				VaultTransitMount: String(DefaultEncryptionVaultTransitMount),
+			},
+		},
+		{
+			"with_vault_transit_key",
+			&EncryptionConfig{
+				VaultTransitKey:   String("consul-template"),
+				VaultTransitMount: String("kms"),
+			},
+			&EncryptionConfig{
+				Enabled:           Bool(true),
+				KeyFile:           String(""),
+				VaultTransitKey:   String("consul-template"),
+				VaultTransitMount: String("kms"),
+			},
+		},
+		{
+			"disabled",
+			&EncryptionConfig{
+				Enabled: Bool(false),
+				KeyFile: String("/etc/ct.key"),
+			},
+			&EncryptionConfig{
+				Enabled:           Bool(false),
+				KeyFile:           String("/etc/ct.key"),
+				VaultTransitKey:   String(""),
+				VaultTransitMount: String(DefaultEncryptionVaultTransitMount),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -125,17 +125,6 @@ func (c *EnvConfig) Env() []string {
 		env[list[0]] = list[1]
 	}
 
-	// anyGlobMatch is a helper function which checks if any of the given globs
-	// match the string.
-	anyGlobMatch := func(s string, patterns []string) bool {
-		for _, pattern := range patterns {
-			if matched, _ := filepath.Match(pattern, s); matched {
-				return true
-			}
-		}
-		return false
-	}
-
 	// Pull out any envvars that match the whitelist.
 	if len(c.Whitelist) > 0 {
 		newKeys := make([]string, 0, len(keys))
@@ -170,6 +159,32 @@ func (c *EnvConfig) Env() []string {
 	return finalEnv
 }
 
+// Allowed returns true if the environment variable with the given name passes
+// the whitelist and blacklist.
+func (c *EnvConfig) Allowed(k string) bool {
+	if len(c.Whitelist) > 0 && !anyGlobMatch(k, c.Whitelist) {
+		return false
+	}
+	return !anyGlobMatch(k, c.Blacklist)
+}
+
+// Restricted returns true if this configuration limits the environment in any
+// way, with pristine, a whitelist, or a blacklist.
+func (c *EnvConfig) Restricted() bool {
+	return BoolVal(c.Pristine) || len(c.Whitelist) > 0 || len(c.Blacklist) > 0
+}
+
+// anyGlobMatch is a helper function which checks if any of the given globs
+// match the string.
+func anyGlobMatch(s string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, s); matched {
+			return true
+		}
+	}
+	return false
+}
+
 // Finalize ensures there no nil pointers.
 func (c *EnvConfig) Finalize() {
 	if c.Blacklist == nil {
@@ -250,6 +250,57 @@ func TestExecConfig_Env(t *testing.T) {
 	}
 }
 
+func TestEnvConfig_Allowed(t *testing.T) {
+	cases := []struct {
+		name string
+		c    *EnvConfig
+		k    string
+		r    bool
+	}{
+		{
+			"no_args",
+			&EnvConfig{},
+			"VAULT_TOKEN",
+			true,
+		},
+		{
+			"whitelist",
+			&EnvConfig{Whitelist: []string{"APP_*"}},
+			"APP_PORT",
+			true,
+		},
+		{
+			"not_whitelisted",
+			&EnvConfig{Whitelist: []string{"APP_*"}},
+			"VAULT_TOKEN",
+			false,
+		},
+		{
+			"blacklist",
+			&EnvConfig{Blacklist: []string{"*TOKEN*"}},
+			"VAULT_TOKEN",
+			false,
+		},
+		{
+			"whitelist_blacklist",
+			&EnvConfig{
+				Whitelist: []string{"APP_*"},
+				Blacklist: []string{"*TOKEN*"},
+			},
+			"APP_TOKEN",
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if r := tc.c.Allowed(tc.k); r != tc.r {
+				t.Errorf("expected %t to be %t", r, tc.r)
+			}
+		})
+	}
+}
+
 func TestEnvConfig_Finalize(t *testing.T) {
 	cases := []struct {
 		name string
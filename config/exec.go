@@ -57,6 +57,16 @@ type ExecConfig struct {
 	// reduce the "thundering herd" problem where all tasks are restarted at once.
 	Splay *time.Duration `mapstructure:"splay"`
 
+	// SplayMin is the minimum amount of time to wait to signal or kill the
+	// process, so the delay is between SplayMin and Splay.
+	SplayMin *time.Duration `mapstructure:"splay_min"`
+
+	// SplaySeed makes the splay deterministic instead of random. The delay is
+	// derived from the seed, so the same seed always waits the same amount of
+	// time. The special value "hostname" uses the hostname of the machine,
+	// giving each host a stable offset.
+	SplaySeed *string `mapstructure:"splay_seed"`
+
 	// Timeout is the maximum amount of time to wait for a command to complete.
 	// By default, this is 0, which means "wait forever".
 	Timeout *time.Duration `mapstructure:"timeout"`
@@ -94,6 +104,10 @@ func (c *ExecConfig) Copy() *ExecConfig {
 
 	o.Splay = c.Splay
 
+	o.SplayMin = c.SplayMin
+
+	o.SplaySeed = c.SplaySeed
+
 	o.Timeout = c.Timeout
 
 	return &o
@@ -145,6 +159,14 @@ func (c *ExecConfig) Merge(o *ExecConfig) *ExecConfig {
 		r.Splay = o.Splay
 	}
 
+	if o.SplayMin != nil {
+		r.SplayMin = o.SplayMin
+	}
+
+	if o.SplaySeed != nil {
+		r.SplaySeed = o.SplaySeed
+	}
+
 	if o.Timeout != nil {
 		r.Timeout = o.Timeout
 	}
@@ -183,6 +205,14 @@ func (c *ExecConfig) Finalize() {
 		c.Splay = TimeDuration(0 * time.Second)
 	}
 
+	if c.SplayMin == nil {
+		c.SplayMin = TimeDuration(0 * time.Second)
+	}
+
+	if c.SplaySeed == nil {
+		c.SplaySeed = String("")
+	}
+
 	if c.Timeout == nil {
 		c.Timeout = TimeDuration(DefaultExecTimeout)
 	}
@@ -202,6 +232,8 @@ func (c *ExecConfig) GoString() string {
 		"KillTimeout:%s, "+
 		"ReloadSignal:%s, "+
 		"Splay:%s, "+
+		"SplayMin:%s, "+
+		"SplaySeed:%s, "+
 		"Timeout:%s"+
 		"}",
 		StringGoString(c.Command),
@@ -211,6 +243,8 @@ func (c *ExecConfig) GoString() string {
 		TimeDurationGoString(c.KillTimeout),
 		SignalGoString(c.ReloadSignal),
 		TimeDurationGoString(c.Splay),
+		TimeDurationGoString(c.SplayMin),
+		StringGoString(c.SplaySeed),
 		TimeDurationGoString(c.Timeout),
 	)
 }
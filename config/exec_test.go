@@ -31,6 +31,8 @@ func TestExecConfig_Copy(t *testing.T) {
 				KillTimeout:  TimeDuration(10 * time.Second),
 				ReloadSignal: Signal(syscall.SIGINT),
 				Splay:        TimeDuration(10 * time.Second),
+				SplayMin:     TimeDuration(5 * time.Second),
+				SplaySeed:    String("hostname"),
 				Timeout:      TimeDuration(10 * time.Second),
 			},
 		},
@@ -245,6 +247,54 @@ func TestExecConfig_Merge(t *testing.T) {
 			&ExecConfig{Splay: TimeDuration(10 * time.Second)},
 			&ExecConfig{Splay: TimeDuration(10 * time.Second)},
 		},
+		{
+			"splay_min_overrides",
+			&ExecConfig{SplayMin: TimeDuration(10 * time.Second)},
+			&ExecConfig{SplayMin: TimeDuration(0 * time.Second)},
+			&ExecConfig{SplayMin: TimeDuration(0 * time.Second)},
+		},
+		{
+			"splay_min_empty_one",
+			&ExecConfig{SplayMin: TimeDuration(10 * time.Second)},
+			&ExecConfig{},
+			&ExecConfig{SplayMin: TimeDuration(10 * time.Second)},
+		},
+		{
+			"splay_min_empty_two",
+			&ExecConfig{},
+			&ExecConfig{SplayMin: TimeDuration(10 * time.Second)},
+			&ExecConfig{SplayMin: TimeDuration(10 * time.Second)},
+		},
+		{
+			"splay_min_same",
+			&ExecConfig{SplayMin: TimeDuration(10 * time.Second)},
+			&ExecConfig{SplayMin: TimeDuration(10 * time.Second)},
+			&ExecConfig{SplayMin: TimeDuration(10 * time.Second)},
+		},
+		{
+			"splay_seed_overrides",
+			&ExecConfig{SplaySeed: String("hostname")},
+			&ExecConfig{SplaySeed: String("")},
+			&ExecConfig{SplaySeed: String("")},
+		},
+		{
+			"splay_seed_empty_one",
+			&ExecConfig{SplaySeed: String("hostname")},
+			&ExecConfig{},
+			&ExecConfig{SplaySeed: String("hostname")},
+		},
+		{
+			"splay_seed_empty_two",
+			&ExecConfig{},
+			&ExecConfig{SplaySeed: String("hostname")},
+			&ExecConfig{SplaySeed: String("hostname")},
+		},
+		{
+			"splay_seed_same",
+			&ExecConfig{SplaySeed: String("hostname")},
+			&ExecConfig{SplaySeed: String("hostname")},
+			&ExecConfig{SplaySeed: String("hostname")},
+		},
 		{
 			"timeout_overrides",
 			&ExecConfig{Timeout: TimeDuration(10 * time.Second)},
@@ -303,6 +353,8 @@ func TestExecConfig_Finalize(t *testing.T) {
 				KillTimeout:  TimeDuration(DefaultExecKillTimeout),
 				ReloadSignal: Signal(DefaultExecReloadSignal),
 				Splay:        TimeDuration(0 * time.Second),
+				SplayMin:     TimeDuration(0 * time.Second),
+				SplaySeed:    String(""),
 				Timeout:      TimeDuration(DefaultExecTimeout),
 			},
 		},
@@ -324,6 +376,8 @@ func TestExecConfig_Finalize(t *testing.T) {
 				KillTimeout:  TimeDuration(DefaultExecKillTimeout),
 				ReloadSignal: Signal(DefaultExecReloadSignal),
 				Splay:        TimeDuration(0 * time.Second),
+				SplayMin:     TimeDuration(0 * time.Second),
+				SplaySeed:    String(""),
 				Timeout:      TimeDuration(DefaultExecTimeout),
 			},
 		},
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultHTTPDestinationMethod is the default HTTP method used when
+	// rendering to an HTTP destination.
+	DefaultHTTPDestinationMethod = "POST"
+
+	// DefaultHTTPDestinationTimeout is the default amount of time to wait for
+	// an HTTP destination to respond.
+	DefaultHTTPDestinationTimeout = 30 * time.Second
+)
+
+// HTTPDestinationConfig is the configuration for rendering a template to an
+// http:// or https:// destination.
+type HTTPDestinationConfig struct {
+	// Headers is the list of headers to send with the request, in the form
+	// "Name: value".
+	Headers []string `mapstructure:"headers"`
+
+	// Method is the HTTP method to use for the request.
+	Method *string `mapstructure:"method"`
+
+	// SuccessCodes is the list of response status codes that are considered
+	// successful. If empty, any 2xx status code is considered successful.
+	SuccessCodes []int `mapstructure:"success_codes"`
+
+	// Timeout is the maximum amount of time to wait for the request to
+	// complete.
+	Timeout *time.Duration `mapstructure:"timeout"`
+}
+
+// DefaultHTTPDestinationConfig returns a configuration that is populated with
+// the default values.
+func DefaultHTTPDestinationConfig() *HTTPDestinationConfig {
+	return &HTTPDestinationConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *HTTPDestinationConfig) Copy() *HTTPDestinationConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o HTTPDestinationConfig
+
+	if c.Headers != nil {
+		o.Headers = append([]string{}, c.Headers...)
+	}
+
+	o.Method = c.Method
+
+	if c.SuccessCodes != nil {
+		o.SuccessCodes = append([]int{}, c.SuccessCodes...)
+	}
+
+	o.Timeout = c.Timeout
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *HTTPDestinationConfig) Merge(o *HTTPDestinationConfig) *HTTPDestinationConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Headers != nil {
+		r.Headers = append(r.Headers, o.Headers...)
+	}
+
+	if o.Method != nil {
+		r.Method = o.Method
+	}
+
+	if o.SuccessCodes != nil {
+		r.SuccessCodes = append(r.SuccessCodes, o.SuccessCodes...)
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *HTTPDestinationConfig) Finalize() {
+	if c.Headers == nil {
+		c.Headers = []string{}
+	}
+
+	if c.Method == nil {
+		c.Method = String(DefaultHTTPDestinationMethod)
+	}
+
+	if c.SuccessCodes == nil {
+		c.SuccessCodes = []int{}
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultHTTPDestinationTimeout)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *HTTPDestinationConfig) GoString() string {
+	if c == nil {
+		return "(*HTTPDestinationConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&HTTPDestinationConfig{"+
+		"Headers:%v, "+
+		"Method:%s, "+
+		"SuccessCodes:%v, "+
+		"Timeout:%s"+
+		"}",
+		c.Headers,
+		StringGoString(c.Method),
+		c.SuccessCodes,
+		TimeDurationGoString(c.Timeout),
+	)
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHTTPDestinationConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *HTTPDestinationConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&HTTPDestinationConfig{},
+		},
+		{
+			"copy",
+			&HTTPDestinationConfig{
+				Headers:      []string{"X-Foo: bar"},
+				Method:       String("PUT"),
+				SuccessCodes: []int{200, 202},
+				Timeout:      TimeDuration(10 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestHTTPDestinationConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *HTTPDestinationConfig
+		b    *HTTPDestinationConfig
+		r    *HTTPDestinationConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{},
+		},
+		{
+			"nil_b",
+			&HTTPDestinationConfig{},
+			nil,
+			&HTTPDestinationConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{},
+		},
+		{
+			"headers_merges",
+			&HTTPDestinationConfig{Headers: []string{"X-Foo: foo"}},
+			&HTTPDestinationConfig{Headers: []string{"X-Bar: bar"}},
+			&HTTPDestinationConfig{Headers: []string{"X-Foo: foo", "X-Bar: bar"}},
+		},
+		{
+			"headers_empty_one",
+			&HTTPDestinationConfig{Headers: []string{"X-Foo: foo"}},
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{Headers: []string{"X-Foo: foo"}},
+		},
+		{
+			"headers_empty_two",
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{Headers: []string{"X-Foo: foo"}},
+			&HTTPDestinationConfig{Headers: []string{"X-Foo: foo"}},
+		},
+		{
+			"method_overrides",
+			&HTTPDestinationConfig{Method: String("POST")},
+			&HTTPDestinationConfig{Method: String("PUT")},
+			&HTTPDestinationConfig{Method: String("PUT")},
+		},
+		{
+			"method_empty_one",
+			&HTTPDestinationConfig{Method: String("POST")},
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{Method: String("POST")},
+		},
+		{
+			"method_empty_two",
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{Method: String("POST")},
+			&HTTPDestinationConfig{Method: String("POST")},
+		},
+		{
+			"success_codes_merges",
+			&HTTPDestinationConfig{SuccessCodes: []int{200}},
+			&HTTPDestinationConfig{SuccessCodes: []int{202}},
+			&HTTPDestinationConfig{SuccessCodes: []int{200, 202}},
+		},
+		{
+			"success_codes_empty_one",
+			&HTTPDestinationConfig{SuccessCodes: []int{200}},
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{SuccessCodes: []int{200}},
+		},
+		{
+			"success_codes_empty_two",
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{SuccessCodes: []int{200}},
+			&HTTPDestinationConfig{SuccessCodes: []int{200}},
+		},
+		{
+			"timeout_overrides",
+			&HTTPDestinationConfig{Timeout: TimeDuration(10 * time.Second)},
+			&HTTPDestinationConfig{Timeout: TimeDuration(0)},
+			&HTTPDestinationConfig{Timeout: TimeDuration(0)},
+		},
+		{
+			"timeout_empty_one",
+			&HTTPDestinationConfig{Timeout: TimeDuration(10 * time.Second)},
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"timeout_empty_two",
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{Timeout: TimeDuration(10 * time.Second)},
+			&HTTPDestinationConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestHTTPDestinationConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *HTTPDestinationConfig
+		r    *HTTPDestinationConfig
+	}{
+		{
+			"empty",
+			&HTTPDestinationConfig{},
+			&HTTPDestinationConfig{
+				Headers:      []string{},
+				Method:       String(DefaultHTTPDestinationMethod),
+				SuccessCodes: []int{},
+				Timeout:      TimeDuration(DefaultHTTPDestinationTimeout),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
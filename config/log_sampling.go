@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultLogSamplingInitial is the default number of identical messages
+	// which are logged in each sampling period before sampling starts.
+	DefaultLogSamplingInitial = 5
+
+	// DefaultLogSamplingThereafter is the default sampling rate of identical
+	// messages after the initial ones: every Nth message is logged.
+	DefaultLogSamplingThereafter = 100
+
+	// DefaultLogSamplingPeriod is the default period after which the counts of
+	// identical messages are reset.
+	DefaultLogSamplingPeriod = 1 * time.Minute
+)
+
+// LogSamplingConfig is the configuration for sampling repeated identical log
+// messages, like retry errors during an outage, so they do not flood disks.
+type LogSamplingConfig struct {
+	// Enabled controls whether log messages are sampled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Initial is the number of identical messages which are logged in each
+	// period before sampling starts.
+	Initial *int `mapstructure:"initial"`
+
+	// Thereafter is the sampling rate after the initial messages: every Nth
+	// identical message is logged. Zero drops all of them.
+	Thereafter *int `mapstructure:"thereafter"`
+
+	// Period is the amount of time after which the counts of identical
+	// messages are reset.
+	Period *time.Duration `mapstructure:"period"`
+}
+
+// DefaultLogSamplingConfig returns a configuration that is populated with the
+// default values.
+func DefaultLogSamplingConfig() *LogSamplingConfig {
+	return &LogSamplingConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *LogSamplingConfig) Copy() *LogSamplingConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o LogSamplingConfig
+	o.Enabled = c.Enabled
+	o.Initial = c.Initial
+	o.Thereafter = c.Thereafter
+	o.Period = c.Period
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *LogSamplingConfig) Merge(o *LogSamplingConfig) *LogSamplingConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Initial != nil {
+		r.Initial = o.Initial
+	}
+
+	if o.Thereafter != nil {
+		r.Thereafter = o.Thereafter
+	}
+
+	if o.Period != nil {
+		r.Period = o.Period
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *LogSamplingConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(false ||
+			IntPresent(c.Initial) ||
+			IntPresent(c.Thereafter) ||
+			TimeDurationPresent(c.Period))
+	}
+
+	if c.Initial == nil {
+		c.Initial = Int(DefaultLogSamplingInitial)
+	}
+
+	if c.Thereafter == nil {
+		c.Thereafter = Int(DefaultLogSamplingThereafter)
+	}
+
+	if c.Period == nil {
+		c.Period = TimeDuration(DefaultLogSamplingPeriod)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *LogSamplingConfig) GoString() string {
+	if c == nil {
+		return "(*LogSamplingConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&LogSamplingConfig{"+
+		"Enabled:%s, "+
+		"Initial:%s, "+
+		"Thereafter:%s, "+
+		"Period:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		IntGoString(c.Initial),
+		IntGoString(c.Thereafter),
+		TimeDurationGoString(c.Period),
+	)
+}
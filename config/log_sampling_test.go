@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLogSamplingConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *LogSamplingConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&LogSamplingConfig{},
+		},
+		{
+			"same_enabled",
+			&LogSamplingConfig{
+				Enabled:    Bool(true),
+				Initial:    Int(5),
+				Thereafter: Int(100),
+				Period:     TimeDuration(10 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestLogSamplingConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *LogSamplingConfig
+		b    *LogSamplingConfig
+		r    *LogSamplingConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&LogSamplingConfig{},
+			&LogSamplingConfig{},
+		},
+		{
+			"nil_b",
+			&LogSamplingConfig{},
+			nil,
+			&LogSamplingConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&LogSamplingConfig{},
+			&LogSamplingConfig{},
+			&LogSamplingConfig{},
+		},
+		{
+			"enabled_overrides",
+			&LogSamplingConfig{Enabled: Bool(true)},
+			&LogSamplingConfig{Enabled: Bool(false)},
+			&LogSamplingConfig{Enabled: Bool(false)},
+		},
+		{
+			"initial_overrides",
+			&LogSamplingConfig{Initial: Int(5)},
+			&LogSamplingConfig{Initial: Int(10)},
+			&LogSamplingConfig{Initial: Int(10)},
+		},
+		{
+			"initial_empty_one",
+			&LogSamplingConfig{Initial: Int(5)},
+			&LogSamplingConfig{},
+			&LogSamplingConfig{Initial: Int(5)},
+		},
+		{
+			"thereafter_overrides",
+			&LogSamplingConfig{Thereafter: Int(100)},
+			&LogSamplingConfig{Thereafter: Int(0)},
+			&LogSamplingConfig{Thereafter: Int(0)},
+		},
+		{
+			"thereafter_empty_two",
+			&LogSamplingConfig{},
+			&LogSamplingConfig{Thereafter: Int(100)},
+			&LogSamplingConfig{Thereafter: Int(100)},
+		},
+		{
+			"period_overrides",
+			&LogSamplingConfig{Period: TimeDuration(10 * time.Second)},
+			&LogSamplingConfig{Period: TimeDuration(20 * time.Second)},
+			&LogSamplingConfig{Period: TimeDuration(20 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestLogSamplingConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *LogSamplingConfig
+		r    *LogSamplingConfig
+	}{
+		{
+			"empty",
+			&LogSamplingConfig{},
+			&LogSamplingConfig{
+				Enabled:    Bool(false),
+				Initial:    Int(DefaultLogSamplingInitial),
+				Thereafter: Int(DefaultLogSamplingThereafter),
+				Period:     TimeDuration(DefaultLogSamplingPeriod),
+			},
+		},
+		{
+			"with_initial",
+			&LogSamplingConfig{
+				Initial: Int(10),
+			},
+			&LogSamplingConfig{
+				Enabled:    Bool(true),
+				Initial:    Int(10),
+				Thereafter: Int(DefaultLogSamplingThereafter),
+				Period:     TimeDuration(DefaultLogSamplingPeriod),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
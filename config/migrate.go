@@ -0,0 +1,110 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// migration is a deprecated option which was rewritten into the current
+// schema.
+type migration struct {
+	// Path is the path of the option which replaces the deprecated one, such
+	// as "vault.renew_token" or "template[0].wait".
+	Path string
+
+	// Note describes the change.
+	Note string
+}
+
+// Migrate rewrites the configuration in the given HCL or JSON contents into
+// the current schema as HCL. Deprecated options are replaced by the options
+// which replace them, with a comment above each describing the change. Since
+// the configuration is parsed and written again, the comments and formatting
+// of the contents are not kept and the options are sorted, but every option
+// which is set is kept, including secrets. The changes are also returned.
+func Migrate(s string) ([]byte, []string, error) {
+	c, migrations, err := parse(s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	migrations = append(migrations, migrateTemplates(c, "")...)
+	for _, name := range names {
+		migrations = append(migrations,
+			migrateTemplates(c.Profiles[name], "profile."+name+".")...)
+	}
+
+	comments := make(map[string][]string, len(migrations))
+	changes := make([]string, 0, len(migrations))
+	for _, m := range migrations {
+		comments[m.Path] = append(comments[m.Path], "Migrated: "+m.Note+".")
+		changes = append(changes, fmt.Sprintf("%s: %s", m.Path, m.Note))
+	}
+	sort.Strings(changes)
+
+	var b bytes.Buffer
+	m, _ := dumpValue(reflect.ValueOf(c), true).(map[string]interface{})
+	writeHCLBody(&b, m, "", comments, 0)
+
+	// Profiles are written as labeled blocks, as they are declared.
+	for _, name := range names {
+		p, _ := dumpValue(reflect.ValueOf(c.Profiles[name]), true).(map[string]interface{})
+		fmt.Fprintf(&b, "profile %s {\n", strconv.Quote(name))
+		writeHCLBody(&b, p, "profile."+name+".", comments, 1)
+		b.WriteString("}\n")
+	}
+
+	return b.Bytes(), changes, nil
+}
+
+// migrateTemplates moves the deprecated command options of the templates of
+// the given configuration into their exec blocks. The prefix is the path of
+// the configuration.
+func migrateTemplates(c *Config, prefix string) []migration {
+	if c == nil || c.Templates == nil {
+		return nil
+	}
+
+	var migrations []migration
+	for i, t := range *c.Templates {
+		path := fmt.Sprintf("%stemplate[%d].exec.", prefix, i)
+
+		if t.Command != nil {
+			if t.Exec == nil {
+				t.Exec = &ExecConfig{}
+			}
+			note := `moved from the deprecated "command"`
+			if t.Exec.Command != nil {
+				note = `removed the deprecated "command", which this overrides`
+			} else {
+				t.Exec.Command = t.Command
+			}
+			t.Command = nil
+			migrations = append(migrations, migration{Path: path + "command", Note: note})
+		}
+
+		if t.CommandTimeout != nil {
+			if t.Exec == nil {
+				t.Exec = &ExecConfig{}
+			}
+			note := `moved from the deprecated "command_timeout"`
+			if t.Exec.Timeout != nil {
+				note = `removed the deprecated "command_timeout", which this overrides`
+			} else {
+				t.Exec.Timeout = t.CommandTimeout
+			}
+			t.CommandTimeout = nil
+			migrations = append(migrations, migration{Path: path + "timeout", Note: note})
+		}
+	}
+	return migrations
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		i       string
+		exp     string
+		changes []string
+	}{
+		{
+			"current",
+			`vault {
+				renew_token = true
+			}`,
+			"vault {\n" +
+				"  renew_token = true\n" +
+				"}\n",
+			[]string{},
+		},
+		{
+			"vault_renew",
+			`vault {
+				renew = true
+				token = "s.abcd"
+			}`,
+			"vault {\n" +
+				"  # Migrated: renamed from \"vault.renew\".\n" +
+				"  renew_token = true\n" +
+				"  token = \"s.abcd\"\n" +
+				"}\n",
+			[]string{`vault.renew_token: renamed from "vault.renew"`},
+		},
+		{
+			"consul_string",
+			`consul = "127.0.0.1:8500"
+			token = "abcd"`,
+			"consul {\n" +
+				"  # Migrated: converted from consul = \"127.0.0.1:8500\".\n" +
+				"  address = \"127.0.0.1:8500\"\n" +
+				"  # Migrated: moved from the top-level \"token\".\n" +
+				"  token = \"abcd\"\n" +
+				"}\n",
+			[]string{
+				`consul.address: converted from consul = "127.0.0.1:8500"`,
+				`consul.token: moved from the top-level "token"`,
+			},
+		},
+		{
+			"wait_string",
+			`wait = "5s:10s"`,
+			"# Migrated: converted from wait = \"5s:10s\".\n" +
+				"wait {\n" +
+				"  max = \"10s\"\n" +
+				"  min = \"5s\"\n" +
+				"}\n",
+			[]string{`wait: converted from wait = "5s:10s"`},
+		},
+		{
+			"template_command",
+			`template {
+				destination = "/tmp/a"
+				command = "restart foo"
+				command_timeout = "60s"
+				wait = "1s"
+			}`,
+			"template {\n" +
+				"  destination = \"/tmp/a\"\n" +
+				"  exec {\n" +
+				"    # Migrated: moved from the deprecated \"command\".\n" +
+				"    command = \"restart foo\"\n" +
+				"    # Migrated: moved from the deprecated \"command_timeout\".\n" +
+				"    timeout = \"1m0s\"\n" +
+				"  }\n" +
+				"  # Migrated: converted from wait = \"1s\".\n" +
+				"  wait {\n" +
+				"    max = \"4s\"\n" +
+				"    min = \"1s\"\n" +
+				"  }\n" +
+				"}\n",
+			[]string{
+				`template[0].exec.command: moved from the deprecated "command"`,
+				`template[0].exec.timeout: moved from the deprecated "command_timeout"`,
+				`template[0].wait: converted from wait = "1s"`,
+			},
+		},
+		{
+			"template_create_dest_dirs_perms",
+			`template {
+				destination = "/tmp/a"
+				create_dest_dirs_perms = 0700
+			}`,
+			"template {\n" +
+				"  # Migrated: renamed from \"create_dest_dirs_perms\".\n" +
+				"  dest_dir_perms = \"0700\"\n" +
+				"  destination = \"/tmp/a\"\n" +
+				"}\n",
+			[]string{`template[0].dest_dir_perms: renamed from "create_dest_dirs_perms"`},
+		},
+		{
+			"profile",
+			`profile "prod" {
+				template {
+					command = "a"
+					exec {
+						command = "b"
+					}
+				}
+			}`,
+			"profile \"prod\" {\n" +
+				"  template {\n" +
+				"    exec {\n" +
+				"      # Migrated: removed the deprecated \"command\", which this overrides.\n" +
+				"      command = \"b\"\n" +
+				"    }\n" +
+				"  }\n" +
+				"}\n",
+			[]string{
+				`profile.prod.template[0].exec.command: removed the deprecated "command", which this overrides`,
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			b, changes, err := Migrate(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.exp {
+				t.Errorf("\nexp:\n%s\nact:\n%s", tc.exp, b)
+			}
+			if !reflect.DeepEqual(tc.changes, changes) {
+				t.Errorf("\nexp: %q\nact: %q", tc.changes, changes)
+			}
+		})
+	}
+}
+
+func TestMigrate_lossless(t *testing.T) {
+	t.Parallel()
+
+	s := `
+		consul = "127.0.0.1:8500"
+		retry = "10s"
+		kill_signal = "SIGTERM"
+		max_stale = "5m"
+		wait = "5s:10s"
+		exec {
+			command = "/usr/bin/app"
+			env {
+				custom = ["A=1"]
+			}
+		}
+		vault {
+			address = "https://vault.service:8200"
+			renew = false
+			token = "s.abcd"
+		}
+		render_group "web" {
+			templates = ["/tmp/a"]
+		}
+		template {
+			contents = "{{ key \"foo\" }}"
+			destination = "/tmp/a"
+			command = "restart foo"
+			perms = 0600
+			wait = "1s:2s"
+		}
+		template {
+			source = "/tmp/b.tpl"
+			destination = "/tmp/b"
+			exec {
+				command = "reload bar"
+			}
+			command_timeout = "5s"
+		}
+		profile "prod" {
+			max_stale = "1m"
+		}
+	`
+
+	b, _, err := Migrate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act, err := Parse(string(b))
+	if err != nil {
+		t.Fatalf("%s:\n%s", err, b)
+	}
+
+	// The deprecated options are only equivalent once finalized.
+	for _, c := range []*Config{exp, act} {
+		c.Finalize()
+		for _, t := range *c.Templates {
+			t.Command, t.CommandTimeout = nil, nil
+		}
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// NotifyOnError and NotifyOnRendered are the events which notifications
+	// are sent on: a template failing to render, and a destination changing.
+	NotifyOnError    = "error"
+	NotifyOnRendered = "rendered"
+
+	// DefaultNotificationsTimeout is the default maximum amount of time to
+	// wait for the webhook to respond.
+	DefaultNotificationsTimeout = 5 * time.Second
+)
+
+// NotificationsConfig is used to POST structured events to a webhook, such as
+// a Slack incoming webhook, for visibility without a metrics stack.
+type NotificationsConfig struct {
+	// Enabled controls if notifications are sent.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// On is the list of events to notify, "error" and "rendered". Only errors
+	// are notified by default.
+	On []string `mapstructure:"on"`
+
+	// Timeout is the maximum amount of time to wait for the webhook to
+	// respond.
+	Timeout *time.Duration `mapstructure:"timeout"`
+
+	// Webhook is the URL the events are POSTed to as JSON.
+	Webhook *string `mapstructure:"webhook"`
+}
+
+// DefaultNotificationsConfig returns a configuration that is populated with
+// the default values.
+func DefaultNotificationsConfig() *NotificationsConfig {
+	return &NotificationsConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *NotificationsConfig) Copy() *NotificationsConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o NotificationsConfig
+	o.Enabled = c.Enabled
+	if c.On != nil {
+		o.On = append([]string{}, c.On...)
+	}
+	o.Timeout = c.Timeout
+	o.Webhook = c.Webhook
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *NotificationsConfig) Merge(o *NotificationsConfig) *NotificationsConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.On != nil {
+		r.On = append(r.On, o.On...)
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	if o.Webhook != nil {
+		r.Webhook = o.Webhook
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *NotificationsConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Webhook))
+	}
+
+	if len(c.On) == 0 {
+		c.On = []string{NotifyOnError}
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultNotificationsTimeout)
+	}
+
+	if c.Webhook == nil {
+		c.Webhook = String("")
+	}
+}
+
+// Notifies returns true if notifications are sent on the given event.
+func (c *NotificationsConfig) Notifies(event string) bool {
+	if c == nil || !BoolVal(c.Enabled) {
+		return false
+	}
+	for _, on := range c.On {
+		if on == event {
+			return true
+		}
+	}
+	return false
+}
+
+// GoString defines the printable version of this struct.
+func (c *NotificationsConfig) GoString() string {
+	if c == nil {
+		return "(*NotificationsConfig)(nil)"
+	}
+	return fmt.Sprintf("&NotificationsConfig{"+
+		"Enabled:%s, "+
+		"On:%q, "+
+		"Timeout:%s, "+
+		"Webhook:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		c.On,
+		TimeDurationGoString(c.Timeout),
+		StringGoString(c.Webhook),
+	)
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNotificationsConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *NotificationsConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&NotificationsConfig{},
+		},
+		{
+			"copy",
+			&NotificationsConfig{
+				Enabled: Bool(true),
+				On:      []string{"error", "rendered"},
+				Timeout: TimeDuration(10 * time.Second),
+				Webhook: String("https://hooks.example.com/ct"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestNotificationsConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *NotificationsConfig
+		b    *NotificationsConfig
+		r    *NotificationsConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&NotificationsConfig{},
+			&NotificationsConfig{},
+		},
+		{
+			"nil_b",
+			&NotificationsConfig{},
+			nil,
+			&NotificationsConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&NotificationsConfig{},
+			&NotificationsConfig{},
+			&NotificationsConfig{},
+		},
+		{
+			"enabled_overrides",
+			&NotificationsConfig{Enabled: Bool(true)},
+			&NotificationsConfig{Enabled: Bool(false)},
+			&NotificationsConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&NotificationsConfig{Enabled: Bool(true)},
+			&NotificationsConfig{},
+			&NotificationsConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&NotificationsConfig{},
+			&NotificationsConfig{Enabled: Bool(true)},
+			&NotificationsConfig{Enabled: Bool(true)},
+		},
+		{
+			"on_merges",
+			&NotificationsConfig{On: []string{"error"}},
+			&NotificationsConfig{On: []string{"rendered"}},
+			&NotificationsConfig{On: []string{"error", "rendered"}},
+		},
+		{
+			"on_empty_one",
+			&NotificationsConfig{On: []string{"error"}},
+			&NotificationsConfig{},
+			&NotificationsConfig{On: []string{"error"}},
+		},
+		{
+			"on_empty_two",
+			&NotificationsConfig{},
+			&NotificationsConfig{On: []string{"error"}},
+			&NotificationsConfig{On: []string{"error"}},
+		},
+		{
+			"timeout_overrides",
+			&NotificationsConfig{Timeout: TimeDuration(10 * time.Second)},
+			&NotificationsConfig{Timeout: TimeDuration(0)},
+			&NotificationsConfig{Timeout: TimeDuration(0)},
+		},
+		{
+			"timeout_empty_one",
+			&NotificationsConfig{Timeout: TimeDuration(10 * time.Second)},
+			&NotificationsConfig{},
+			&NotificationsConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"timeout_empty_two",
+			&NotificationsConfig{},
+			&NotificationsConfig{Timeout: TimeDuration(10 * time.Second)},
+			&NotificationsConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"webhook_overrides",
+			&NotificationsConfig{Webhook: String("https://a.example.com")},
+			&NotificationsConfig{Webhook: String("")},
+			&NotificationsConfig{Webhook: String("")},
+		},
+		{
+			"webhook_empty_one",
+			&NotificationsConfig{Webhook: String("https://a.example.com")},
+			&NotificationsConfig{},
+			&NotificationsConfig{Webhook: String("https://a.example.com")},
+		},
+		{
+			"webhook_empty_two",
+			&NotificationsConfig{},
+			&NotificationsConfig{Webhook: String("https://a.example.com")},
+			&NotificationsConfig{Webhook: String("https://a.example.com")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestNotificationsConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *NotificationsConfig
+		r    *NotificationsConfig
+	}{
+		{
+			"empty",
+			&NotificationsConfig{},
+			&NotificationsConfig{
+				Enabled: Bool(false),
+				On:      []string{"error"},
+				Timeout: TimeDuration(DefaultNotificationsTimeout),
+				Webhook: String(""),
+			},
+		},
+		{
+			"with_webhook",
+			&NotificationsConfig{
+				On:      []string{"rendered"},
+				Webhook: String("https://hooks.example.com/ct"),
+			},
+			&NotificationsConfig{
+				Enabled: Bool(true),
+				On:      []string{"rendered"},
+				Timeout: TimeDuration(DefaultNotificationsTimeout),
+				Webhook: String("https://hooks.example.com/ct"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import "fmt"
+
+// OAuth2Config is the configuration for obtaining bearer tokens with the
+// OAuth2 client credentials grant. It is used when Consul sits behind a
+// gateway which requires an OAuth2 or OIDC access token.
+type OAuth2Config struct {
+	// ClientID is the client identifier registered with the provider.
+	ClientID *string `mapstructure:"client_id"`
+
+	// ClientSecret is the client secret registered with the provider.
+	ClientSecret *string `mapstructure:"client_secret" json:"-"`
+
+	// Enabled controls whether requests carry an OAuth2 bearer token.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Scopes is the list of scopes to request.
+	Scopes []string `mapstructure:"scopes"`
+
+	// TokenURL is the token endpoint of the provider.
+	TokenURL *string `mapstructure:"token_url"`
+}
+
+// DefaultOAuth2Config returns a configuration that is populated with the
+// default values.
+func DefaultOAuth2Config() *OAuth2Config {
+	return &OAuth2Config{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *OAuth2Config) Copy() *OAuth2Config {
+	if c == nil {
+		return nil
+	}
+
+	var o OAuth2Config
+	o.ClientID = c.ClientID
+	o.ClientSecret = c.ClientSecret
+	o.Enabled = c.Enabled
+
+	if c.Scopes != nil {
+		o.Scopes = make([]string, len(c.Scopes))
+		copy(o.Scopes, c.Scopes)
+	}
+
+	o.TokenURL = c.TokenURL
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *OAuth2Config) Merge(o *OAuth2Config) *OAuth2Config {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.ClientID != nil {
+		r.ClientID = o.ClientID
+	}
+
+	if o.ClientSecret != nil {
+		r.ClientSecret = o.ClientSecret
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Scopes != nil {
+		r.Scopes = append(r.Scopes, o.Scopes...)
+	}
+
+	if o.TokenURL != nil {
+		r.TokenURL = o.TokenURL
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *OAuth2Config) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.TokenURL))
+	}
+
+	if c.ClientID == nil {
+		c.ClientID = String("")
+	}
+
+	if c.ClientSecret == nil {
+		c.ClientSecret = String("")
+	}
+
+	if c.Scopes == nil {
+		c.Scopes = []string{}
+	}
+
+	if c.TokenURL == nil {
+		c.TokenURL = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *OAuth2Config) GoString() string {
+	if c == nil {
+		return "(*OAuth2Config)(nil)"
+	}
+
+	return fmt.Sprintf("&OAuth2Config{"+
+		"ClientID:%s, "+
+		"ClientSecret:%t, "+
+		"Enabled:%s, "+
+		"Scopes:%q, "+
+		"TokenURL:%s"+
+		"}",
+		StringGoString(c.ClientID),
+		StringPresent(c.ClientSecret),
+		BoolGoString(c.Enabled),
+		c.Scopes,
+		StringGoString(c.TokenURL),
+	)
+}
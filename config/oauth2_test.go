@@ -0,0 +1,203 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOAuth2Config_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *OAuth2Config
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&OAuth2Config{},
+		},
+		{
+			"copy",
+			&OAuth2Config{
+				ClientID:     String("id"),
+				ClientSecret: String("secret"),
+				Enabled:      Bool(true),
+				Scopes:       []string{"consul"},
+				TokenURL:     String("https://idp.example.com/token"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestOAuth2Config_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *OAuth2Config
+		b    *OAuth2Config
+		r    *OAuth2Config
+	}{
+		{
+			"nil_a",
+			nil,
+			&OAuth2Config{},
+			&OAuth2Config{},
+		},
+		{
+			"nil_b",
+			&OAuth2Config{},
+			nil,
+			&OAuth2Config{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&OAuth2Config{},
+			&OAuth2Config{},
+			&OAuth2Config{},
+		},
+		{
+			"client_id_overrides",
+			&OAuth2Config{ClientID: String("a")},
+			&OAuth2Config{ClientID: String("b")},
+			&OAuth2Config{ClientID: String("b")},
+		},
+		{
+			"client_id_empty_one",
+			&OAuth2Config{ClientID: String("a")},
+			&OAuth2Config{},
+			&OAuth2Config{ClientID: String("a")},
+		},
+		{
+			"client_id_empty_two",
+			&OAuth2Config{},
+			&OAuth2Config{ClientID: String("a")},
+			&OAuth2Config{ClientID: String("a")},
+		},
+		{
+			"client_secret_overrides",
+			&OAuth2Config{ClientSecret: String("a")},
+			&OAuth2Config{ClientSecret: String("b")},
+			&OAuth2Config{ClientSecret: String("b")},
+		},
+		{
+			"client_secret_empty_one",
+			&OAuth2Config{ClientSecret: String("a")},
+			&OAuth2Config{},
+			&OAuth2Config{ClientSecret: String("a")},
+		},
+		{
+			"enabled_overrides",
+			&OAuth2Config{Enabled: Bool(true)},
+			&OAuth2Config{Enabled: Bool(false)},
+			&OAuth2Config{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&OAuth2Config{Enabled: Bool(true)},
+			&OAuth2Config{},
+			&OAuth2Config{Enabled: Bool(true)},
+		},
+		{
+			"scopes_merges",
+			&OAuth2Config{Scopes: []string{"a"}},
+			&OAuth2Config{Scopes: []string{"b"}},
+			&OAuth2Config{Scopes: []string{"a", "b"}},
+		},
+		{
+			"scopes_empty_one",
+			&OAuth2Config{Scopes: []string{"a"}},
+			&OAuth2Config{},
+			&OAuth2Config{Scopes: []string{"a"}},
+		},
+		{
+			"token_url_overrides",
+			&OAuth2Config{TokenURL: String("a")},
+			&OAuth2Config{TokenURL: String("b")},
+			&OAuth2Config{TokenURL: String("b")},
+		},
+		{
+			"token_url_empty_two",
+			&OAuth2Config{},
+			&OAuth2Config{TokenURL: String("a")},
+			&OAuth2Config{TokenURL: String("a")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestOAuth2Config_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *OAuth2Config
+		r    *OAuth2Config
+	}{
+		{
+			"empty",
+			&OAuth2Config{},
+			&OAuth2Config{
+				ClientID:     String(""),
+				ClientSecret: String(""),
+				Enabled:      Bool(false),
+				Scopes:       []string{},
+				TokenURL:     String(""),
+			},
+		},
+		{
+			"with_token_url",
+			&OAuth2Config{
+				TokenURL: String("https://idp.example.com/token"),
+			},
+			&OAuth2Config{
+				ClientID:     String(""),
+				ClientSecret: String(""),
+				Enabled:      Bool(true),
+				Scopes:       []string{},
+				TokenURL:     String("https://idp.example.com/token"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
+
+func TestOAuth2Config_GoString(t *testing.T) {
+	c := &OAuth2Config{
+		ClientSecret: String("s3cr3t"),
+	}
+	if s := c.GoString(); strings.Contains(s, "s3cr3t") {
+		t.Errorf("expected %q to not contain the client secret", s)
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultRegistrationService is the default name of the Consul service
+	// Consul Template registers itself as.
+	DefaultRegistrationService = "consul-template"
+
+	// DefaultRegistrationTTL is the default TTL of the health check of the
+	// registration.
+	DefaultRegistrationTTL = 30 * time.Second
+)
+
+// RegistrationConfig is used to register each instance of Consul Template as
+// an instance of a Consul service, so templates can discover the running
+// instances with the runners function.
+type RegistrationConfig struct {
+	// Enabled controls if Consul Template registers itself.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Service is the name of the service to register as.
+	Service *string `mapstructure:"service"`
+
+	// Tags are the tags of the registered service instance.
+	Tags []string `mapstructure:"tags"`
+
+	// TTL is the TTL of the health check of the registered service instance,
+	// which is kept passing while Consul Template runs.
+	TTL *time.Duration `mapstructure:"ttl"`
+}
+
+// DefaultRegistrationConfig returns a configuration that is populated with
+// the default values.
+func DefaultRegistrationConfig() *RegistrationConfig {
+	return &RegistrationConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *RegistrationConfig) Copy() *RegistrationConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o RegistrationConfig
+	o.Enabled = c.Enabled
+	o.Service = c.Service
+	if c.Tags != nil {
+		o.Tags = append([]string{}, c.Tags...)
+	}
+	o.TTL = c.TTL
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *RegistrationConfig) Merge(o *RegistrationConfig) *RegistrationConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Service != nil {
+		r.Service = o.Service
+	}
+
+	if o.Tags != nil {
+		r.Tags = append(r.Tags, o.Tags...)
+	}
+
+	if o.TTL != nil {
+		r.TTL = o.TTL
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *RegistrationConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Service))
+	}
+
+	if c.Service == nil {
+		c.Service = String(DefaultRegistrationService)
+	}
+
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+
+	if c.TTL == nil {
+		c.TTL = TimeDuration(DefaultRegistrationTTL)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *RegistrationConfig) GoString() string {
+	if c == nil {
+		return "(*RegistrationConfig)(nil)"
+	}
+	return fmt.Sprintf("&RegistrationConfig{"+
+		"Enabled:%s, "+
+		"Service:%s, "+
+		"Tags:%q, "+
+		"TTL:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		StringGoString(c.Service),
+		c.Tags,
+		TimeDurationGoString(c.TTL),
+	)
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistrationConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *RegistrationConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&RegistrationConfig{},
+		},
+		{
+			"copy",
+			&RegistrationConfig{
+				Enabled: Bool(true),
+				Service: String("edge-templates"),
+				Tags:    []string{"edge"},
+				TTL:     TimeDuration(10 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestRegistrationConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *RegistrationConfig
+		b    *RegistrationConfig
+		r    *RegistrationConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&RegistrationConfig{},
+			&RegistrationConfig{},
+		},
+		{
+			"nil_b",
+			&RegistrationConfig{},
+			nil,
+			&RegistrationConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&RegistrationConfig{},
+			&RegistrationConfig{},
+			&RegistrationConfig{},
+		},
+		{
+			"enabled_overrides",
+			&RegistrationConfig{Enabled: Bool(true)},
+			&RegistrationConfig{Enabled: Bool(false)},
+			&RegistrationConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&RegistrationConfig{Enabled: Bool(true)},
+			&RegistrationConfig{},
+			&RegistrationConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&RegistrationConfig{},
+			&RegistrationConfig{Enabled: Bool(true)},
+			&RegistrationConfig{Enabled: Bool(true)},
+		},
+		{
+			"service_overrides",
+			&RegistrationConfig{Service: String("a")},
+			&RegistrationConfig{Service: String("b")},
+			&RegistrationConfig{Service: String("b")},
+		},
+		{
+			"service_empty_one",
+			&RegistrationConfig{Service: String("a")},
+			&RegistrationConfig{},
+			&RegistrationConfig{Service: String("a")},
+		},
+		{
+			"service_empty_two",
+			&RegistrationConfig{},
+			&RegistrationConfig{Service: String("a")},
+			&RegistrationConfig{Service: String("a")},
+		},
+		{
+			"tags_merges",
+			&RegistrationConfig{Tags: []string{"a"}},
+			&RegistrationConfig{Tags: []string{"b"}},
+			&RegistrationConfig{Tags: []string{"a", "b"}},
+		},
+		{
+			"tags_empty_one",
+			&RegistrationConfig{Tags: []string{"a"}},
+			&RegistrationConfig{},
+			&RegistrationConfig{Tags: []string{"a"}},
+		},
+		{
+			"tags_empty_two",
+			&RegistrationConfig{},
+			&RegistrationConfig{Tags: []string{"a"}},
+			&RegistrationConfig{Tags: []string{"a"}},
+		},
+		{
+			"ttl_overrides",
+			&RegistrationConfig{TTL: TimeDuration(10 * time.Second)},
+			&RegistrationConfig{TTL: TimeDuration(20 * time.Second)},
+			&RegistrationConfig{TTL: TimeDuration(20 * time.Second)},
+		},
+		{
+			"ttl_empty_one",
+			&RegistrationConfig{TTL: TimeDuration(10 * time.Second)},
+			&RegistrationConfig{},
+			&RegistrationConfig{TTL: TimeDuration(10 * time.Second)},
+		},
+		{
+			"ttl_empty_two",
+			&RegistrationConfig{},
+			&RegistrationConfig{TTL: TimeDuration(10 * time.Second)},
+			&RegistrationConfig{TTL: TimeDuration(10 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestRegistrationConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *RegistrationConfig
+		r    *RegistrationConfig
+	}{
+		{
+			"empty",
+			&RegistrationConfig{},
+			&RegistrationConfig{
+				Enabled: Bool(false),
+				Service: String(DefaultRegistrationService),
+				Tags:    []string{},
+				TTL:     TimeDuration(DefaultRegistrationTTL),
+			},
+		},
+		{
+			"with_service",
+			&RegistrationConfig{
+				Service: String("edge-templates"),
+			},
+			&RegistrationConfig{
+				Enabled: Bool(true),
+				Service: String("edge-templates"),
+				Tags:    []string{},
+				TTL:     TimeDuration(DefaultRegistrationTTL),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
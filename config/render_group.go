@@ -0,0 +1,222 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenderGroupConfig is a named group of templates which are committed
+// together: either all of their destinations are updated and their commands
+// run, or none are.
+type RenderGroupConfig struct {
+	// Name is the name of the group, given as the label of the block.
+	Name *string `mapstructure:"name"`
+
+	// Templates are the destinations of the templates in the group.
+	Templates []string `mapstructure:"templates"`
+}
+
+// DefaultRenderGroupConfig returns a configuration that is populated with the
+// default values.
+func DefaultRenderGroupConfig() *RenderGroupConfig {
+	return &RenderGroupConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *RenderGroupConfig) Copy() *RenderGroupConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o RenderGroupConfig
+
+	o.Name = c.Name
+
+	if c.Templates != nil {
+		o.Templates = make([]string, len(c.Templates))
+		copy(o.Templates, c.Templates)
+	}
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *RenderGroupConfig) Merge(o *RenderGroupConfig) *RenderGroupConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Name != nil {
+		r.Name = o.Name
+	}
+
+	r.Templates = append(r.Templates, o.Templates...)
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *RenderGroupConfig) Finalize() {
+	if c.Name == nil {
+		c.Name = String("")
+	}
+
+	if c.Templates == nil {
+		c.Templates = []string{}
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *RenderGroupConfig) GoString() string {
+	if c == nil {
+		return "(*RenderGroupConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&RenderGroupConfig{"+
+		"Name:%s, "+
+		"Templates:%s"+
+		"}",
+		StringGoString(c.Name),
+		c.Templates,
+	)
+}
+
+// RenderGroupConfigs is a collection of RenderGroupConfigs.
+type RenderGroupConfigs []*RenderGroupConfig
+
+// DefaultRenderGroupConfigs returns a configuration that is populated with the
+// default values.
+func DefaultRenderGroupConfigs() *RenderGroupConfigs {
+	return &RenderGroupConfigs{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *RenderGroupConfigs) Copy() *RenderGroupConfigs {
+	if c == nil {
+		return nil
+	}
+
+	o := make(RenderGroupConfigs, len(*c))
+	for i, g := range *c {
+		o[i] = g.Copy()
+	}
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration. Groups with the same name are merged, so a group can be
+// extended with more templates, and other groups are appended.
+func (c *RenderGroupConfigs) Merge(o *RenderGroupConfigs) *RenderGroupConfigs {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+OUTER:
+	for _, og := range *o {
+		for i, g := range *r {
+			if g.Name != nil && og.Name != nil && *g.Name == *og.Name {
+				(*r)[i] = g.Merge(og)
+				continue OUTER
+			}
+		}
+		*r = append(*r, og.Copy())
+	}
+
+	return r
+}
+
+// Finalize ensures the configuration has no nil pointers and sets default
+// values.
+func (c *RenderGroupConfigs) Finalize() {
+	for _, g := range *c {
+		g.Finalize()
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *RenderGroupConfigs) GoString() string {
+	if c == nil {
+		return "(*RenderGroupConfigs)(nil)"
+	}
+
+	s := make([]string, len(*c))
+	for i, g := range *c {
+		s[i] = g.GoString()
+	}
+
+	return "{" + strings.Join(s, ", ") + "}"
+}
+
+// namedBlocks converts the named blocks under the given key of the parsed
+// configuration, such as `render_group "name" { ... }`, into a list of blocks
+// with the name as their "name" key, so they can be decoded like other lists
+// of blocks. Unlabeled blocks which already have a name, as the configuration
+// is dumped, are kept as they are.
+func namedBlocks(parsed map[string]interface{}, key string) error {
+	raw, ok := parsed[key]
+	if !ok {
+		return nil
+	}
+
+	var list []map[string]interface{}
+	switch typed := raw.(type) {
+	case []map[string]interface{}:
+		list = typed
+	case map[string]interface{}:
+		list = []map[string]interface{}{typed}
+	default:
+		return fmt.Errorf("%s: expected named blocks, got %T", key, raw)
+	}
+
+	var blocks []map[string]interface{}
+	for _, m := range list {
+		if _, ok := m["name"].(string); ok {
+			blocks = append(blocks, m)
+			continue
+		}
+
+		for name, body := range m {
+			var bodies []map[string]interface{}
+			switch typed := body.(type) {
+			case []map[string]interface{}:
+				bodies = typed
+			case map[string]interface{}:
+				bodies = []map[string]interface{}{typed}
+			default:
+				return fmt.Errorf("%s %q: expected a block, got %T", key, name, body)
+			}
+
+			for _, b := range bodies {
+				if _, ok := b["name"]; ok {
+					return fmt.Errorf("%s %q: the name is given as the label "+
+						"of the block", key, name)
+				}
+				b["name"] = name
+				blocks = append(blocks, b)
+			}
+		}
+	}
+	parsed[key] = blocks
+	return nil
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRenderGroupConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *RenderGroupConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&RenderGroupConfig{},
+		},
+		{
+			"same",
+			&RenderGroupConfig{
+				Name:      String("frontend"),
+				Templates: []string{"/a", "/b"},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestRenderGroupConfigs_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *RenderGroupConfigs
+		b    *RenderGroupConfigs
+		r    *RenderGroupConfigs
+	}{
+		{
+			"nil_a",
+			nil,
+			&RenderGroupConfigs{},
+			&RenderGroupConfigs{},
+		},
+		{
+			"nil_b",
+			&RenderGroupConfigs{},
+			nil,
+			&RenderGroupConfigs{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"appends",
+			&RenderGroupConfigs{
+				&RenderGroupConfig{Name: String("a"), Templates: []string{"/a"}},
+			},
+			&RenderGroupConfigs{
+				&RenderGroupConfig{Name: String("b"), Templates: []string{"/b"}},
+			},
+			&RenderGroupConfigs{
+				&RenderGroupConfig{Name: String("a"), Templates: []string{"/a"}},
+				&RenderGroupConfig{Name: String("b"), Templates: []string{"/b"}},
+			},
+		},
+		{
+			"merges_same_name",
+			&RenderGroupConfigs{
+				&RenderGroupConfig{Name: String("a"), Templates: []string{"/a"}},
+			},
+			&RenderGroupConfigs{
+				&RenderGroupConfig{Name: String("a"), Templates: []string{"/b"}},
+			},
+			&RenderGroupConfigs{
+				&RenderGroupConfig{Name: String("a"), Templates: []string{"/a", "/b"}},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestRenderGroupConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *RenderGroupConfig
+		r    *RenderGroupConfig
+	}{
+		{
+			"empty",
+			&RenderGroupConfig{},
+			&RenderGroupConfig{
+				Name:      String(""),
+				Templates: []string{},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
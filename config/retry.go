@@ -13,6 +13,10 @@ const (
 	// DefaultRetryBackoff is the default base for the exponential backoff
 	// algorithm.
 	DefaultRetryBackoff = 250 * time.Millisecond
+
+	// DefaultRetryMaxBackoff is the default maximum of the exponential backoff,
+	// and of the delays requested by throttling servers.
+	DefaultRetryMaxBackoff = 1 * time.Minute
 )
 
 // RetryFunc is the signature of a function that supports retries.
@@ -31,6 +35,11 @@ type RetryConfig struct {
 
 	// Enabled signals if this retry is enabled.
 	Enabled *bool
+
+	// MaxBackoff is the maximum sleep between retry attempts, including the
+	// delays requested by the Retry-After headers of throttled responses. A
+	// value of 0 means no maximum.
+	MaxBackoff *time.Duration `mapstructure:"max_backoff"`
 }
 
 // DefaultRetryConfig returns a configuration that is populated with the
@@ -53,6 +62,8 @@ func (c *RetryConfig) Copy() *RetryConfig {
 
 	o.Enabled = c.Enabled
 
+	o.MaxBackoff = c.MaxBackoff
+
 	return &o
 }
 
@@ -86,6 +97,10 @@ func (c *RetryConfig) Merge(o *RetryConfig) *RetryConfig {
 		r.Enabled = o.Enabled
 	}
 
+	if o.MaxBackoff != nil {
+		r.MaxBackoff = o.MaxBackoff
+	}
+
 	return r
 }
 
@@ -102,6 +117,9 @@ func (c *RetryConfig) RetryFunc() RetryFunc {
 
 		base := math.Pow(2, float64(retry))
 		sleep := time.Duration(base) * TimeDurationVal(c.Backoff)
+		if max := TimeDurationVal(c.MaxBackoff); max > 0 && (sleep > max || sleep <= 0) {
+			sleep = max
+		}
 
 		return true, sleep
 	}
@@ -120,6 +138,10 @@ func (c *RetryConfig) Finalize() {
 	if c.Enabled == nil {
 		c.Enabled = Bool(true)
 	}
+
+	if c.MaxBackoff == nil {
+		c.MaxBackoff = TimeDuration(DefaultRetryMaxBackoff)
+	}
 }
 
 // GoString defines the printable version of this struct.
@@ -131,10 +153,12 @@ func (c *RetryConfig) GoString() string {
 	return fmt.Sprintf("&RetryConfig{"+
 		"Attempts:%s, "+
 		"Backoff:%s, "+
-		"Enabled:%s"+
+		"Enabled:%s, "+
+		"MaxBackoff:%s"+
 		"}",
 		IntGoString(c.Attempts),
 		TimeDurationGoString(c.Backoff),
 		BoolGoString(c.Enabled),
+		TimeDurationGoString(c.MaxBackoff),
 	)
 }
@@ -23,9 +23,10 @@ func TestRetryConfig_Copy(t *testing.T) {
 		{
 			"same_enabled",
 			&RetryConfig{
-				Attempts: Int(25),
-				Backoff:  TimeDuration(20 * time.Second),
-				Enabled:  Bool(true),
+				Attempts:   Int(25),
+				Backoff:    TimeDuration(20 * time.Second),
+				Enabled:    Bool(true),
+				MaxBackoff: TimeDuration(1 * time.Minute),
 			},
 		},
 	}
@@ -144,6 +145,30 @@ func TestRetryConfig_Merge(t *testing.T) {
 			&RetryConfig{Enabled: Bool(true)},
 			&RetryConfig{Enabled: Bool(true)},
 		},
+		{
+			"max_backoff_overrides",
+			&RetryConfig{MaxBackoff: TimeDuration(10 * time.Second)},
+			&RetryConfig{MaxBackoff: TimeDuration(20 * time.Second)},
+			&RetryConfig{MaxBackoff: TimeDuration(20 * time.Second)},
+		},
+		{
+			"max_backoff_empty_one",
+			&RetryConfig{MaxBackoff: TimeDuration(10 * time.Second)},
+			&RetryConfig{},
+			&RetryConfig{MaxBackoff: TimeDuration(10 * time.Second)},
+		},
+		{
+			"max_backoff_empty_two",
+			&RetryConfig{},
+			&RetryConfig{MaxBackoff: TimeDuration(10 * time.Second)},
+			&RetryConfig{MaxBackoff: TimeDuration(10 * time.Second)},
+		},
+		{
+			"max_backoff_same",
+			&RetryConfig{MaxBackoff: TimeDuration(10 * time.Second)},
+			&RetryConfig{MaxBackoff: TimeDuration(10 * time.Second)},
+			&RetryConfig{MaxBackoff: TimeDuration(10 * time.Second)},
+		},
 	}
 
 	for i, tc := range cases {
@@ -166,9 +191,10 @@ func TestRetryConfig_Finalize(t *testing.T) {
 			"empty",
 			&RetryConfig{},
 			&RetryConfig{
-				Attempts: Int(DefaultRetryAttempts),
-				Backoff:  TimeDuration(DefaultRetryBackoff),
-				Enabled:  Bool(true),
+				Attempts:   Int(DefaultRetryAttempts),
+				Backoff:    TimeDuration(DefaultRetryBackoff),
+				Enabled:    Bool(true),
+				MaxBackoff: TimeDuration(DefaultRetryMaxBackoff),
 			},
 		},
 	}
@@ -182,3 +208,79 @@ func TestRetryConfig_Finalize(t *testing.T) {
 		})
 	}
 }
+
+func TestRetryConfig_RetryFunc(t *testing.T) {
+	cases := []struct {
+		name  string
+		c     *RetryConfig
+		retry int
+		ok    bool
+		sleep time.Duration
+	}{
+		{
+			"backoff",
+			&RetryConfig{
+				Attempts:   Int(5),
+				Backoff:    TimeDuration(1 * time.Second),
+				Enabled:    Bool(true),
+				MaxBackoff: TimeDuration(1 * time.Minute),
+			},
+			2,
+			true,
+			4 * time.Second,
+		},
+		{
+			"max_backoff",
+			&RetryConfig{
+				Attempts:   Int(10),
+				Backoff:    TimeDuration(1 * time.Second),
+				Enabled:    Bool(true),
+				MaxBackoff: TimeDuration(5 * time.Second),
+			},
+			4,
+			true,
+			5 * time.Second,
+		},
+		{
+			"no_max_backoff",
+			&RetryConfig{
+				Attempts:   Int(10),
+				Backoff:    TimeDuration(1 * time.Second),
+				Enabled:    Bool(true),
+				MaxBackoff: TimeDuration(0),
+			},
+			6,
+			true,
+			64 * time.Second,
+		},
+		{
+			"attempts",
+			&RetryConfig{
+				Attempts: Int(2),
+				Backoff:  TimeDuration(1 * time.Second),
+				Enabled:  Bool(true),
+			},
+			2,
+			false,
+			0,
+		},
+		{
+			"disabled",
+			&RetryConfig{
+				Enabled: Bool(false),
+			},
+			0,
+			false,
+			0,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			ok, sleep := tc.c.RetryFunc()(tc.retry)
+			if ok != tc.ok || sleep != tc.sleep {
+				t.Errorf("\nexp: %t %s\nact: %t %s", tc.ok, tc.sleep, ok, sleep)
+			}
+		})
+	}
+}
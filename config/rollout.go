@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultRolloutMaxParallel is the default number of instances which may
+	// apply a changed template at the same time.
+	DefaultRolloutMaxParallel = 1
+
+	// DefaultRolloutPrefix is the default prefix used for rollout coordination.
+	DefaultRolloutPrefix = "consul-template/rollout/"
+
+	// DefaultRolloutStagger is the default amount of time an instance keeps its
+	// place in the rollout after applying a changed template.
+	DefaultRolloutStagger = 0 * time.Second
+)
+
+// RolloutConfig is used to coordinate applying a changed template across many
+// instances of CT rendering the same template. Instances register their
+// intent to apply the change under a KV prefix in Consul, and only a limited
+// number of them apply it at the same time.
+type RolloutConfig struct {
+	// Enabled controls if rollout coordination is enabled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// MaxParallel is the maximum number of instances which may apply the change
+	// at the same time.
+	MaxParallel *int `mapstructure:"max_parallel"`
+
+	// Prefix is the KV prefix used for coordination. The template ID is
+	// appended to the prefix, so each template is rolled out independently.
+	Prefix *string `mapstructure:"prefix"`
+
+	// Stagger is the amount of time an instance keeps its place in the rollout
+	// after applying the change and running its command, which spaces out the
+	// batches of instances.
+	Stagger *time.Duration `mapstructure:"stagger"`
+}
+
+// DefaultRolloutConfig returns a configuration that is populated with the
+// default values.
+func DefaultRolloutConfig() *RolloutConfig {
+	return &RolloutConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *RolloutConfig) Copy() *RolloutConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o RolloutConfig
+	o.Enabled = c.Enabled
+	o.MaxParallel = c.MaxParallel
+	o.Prefix = c.Prefix
+	o.Stagger = c.Stagger
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *RolloutConfig) Merge(o *RolloutConfig) *RolloutConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.MaxParallel != nil {
+		r.MaxParallel = o.MaxParallel
+	}
+
+	if o.Prefix != nil {
+		r.Prefix = o.Prefix
+	}
+
+	if o.Stagger != nil {
+		r.Stagger = o.Stagger
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *RolloutConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(false ||
+			IntPresent(c.MaxParallel) ||
+			StringPresent(c.Prefix) ||
+			TimeDurationPresent(c.Stagger))
+	}
+
+	if c.MaxParallel == nil {
+		c.MaxParallel = Int(DefaultRolloutMaxParallel)
+	}
+
+	if c.Prefix == nil {
+		c.Prefix = String(DefaultRolloutPrefix)
+	}
+
+	if c.Stagger == nil {
+		c.Stagger = TimeDuration(DefaultRolloutStagger)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *RolloutConfig) GoString() string {
+	if c == nil {
+		return "(*RolloutConfig)(nil)"
+	}
+	return fmt.Sprintf("&RolloutConfig{"+
+		"Enabled:%s, "+
+		"MaxParallel:%s, "+
+		"Prefix:%s, "+
+		"Stagger:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		IntGoString(c.MaxParallel),
+		StringGoString(c.Prefix),
+		TimeDurationGoString(c.Stagger),
+	)
+}
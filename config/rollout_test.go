@@ -0,0 +1,233 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRolloutConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *RolloutConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&RolloutConfig{},
+		},
+		{
+			"copy",
+			&RolloutConfig{
+				Enabled:     Bool(true),
+				MaxParallel: Int(5),
+				Prefix:      String("prefix"),
+				Stagger:     TimeDuration(30 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestRolloutConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *RolloutConfig
+		b    *RolloutConfig
+		r    *RolloutConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&RolloutConfig{},
+			&RolloutConfig{},
+		},
+		{
+			"nil_b",
+			&RolloutConfig{},
+			nil,
+			&RolloutConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&RolloutConfig{},
+			&RolloutConfig{},
+			&RolloutConfig{},
+		},
+		{
+			"enabled_overrides",
+			&RolloutConfig{Enabled: Bool(true)},
+			&RolloutConfig{Enabled: Bool(false)},
+			&RolloutConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&RolloutConfig{Enabled: Bool(true)},
+			&RolloutConfig{},
+			&RolloutConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&RolloutConfig{},
+			&RolloutConfig{Enabled: Bool(true)},
+			&RolloutConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&RolloutConfig{Enabled: Bool(true)},
+			&RolloutConfig{Enabled: Bool(true)},
+			&RolloutConfig{Enabled: Bool(true)},
+		},
+		{
+			"max_parallel_overrides",
+			&RolloutConfig{MaxParallel: Int(5)},
+			&RolloutConfig{MaxParallel: Int(0)},
+			&RolloutConfig{MaxParallel: Int(0)},
+		},
+		{
+			"max_parallel_empty_one",
+			&RolloutConfig{MaxParallel: Int(5)},
+			&RolloutConfig{},
+			&RolloutConfig{MaxParallel: Int(5)},
+		},
+		{
+			"max_parallel_empty_two",
+			&RolloutConfig{},
+			&RolloutConfig{MaxParallel: Int(5)},
+			&RolloutConfig{MaxParallel: Int(5)},
+		},
+		{
+			"max_parallel_same",
+			&RolloutConfig{MaxParallel: Int(5)},
+			&RolloutConfig{MaxParallel: Int(5)},
+			&RolloutConfig{MaxParallel: Int(5)},
+		},
+		{
+			"prefix_overrides",
+			&RolloutConfig{Prefix: String("prefix")},
+			&RolloutConfig{Prefix: String("")},
+			&RolloutConfig{Prefix: String("")},
+		},
+		{
+			"prefix_empty_one",
+			&RolloutConfig{Prefix: String("prefix")},
+			&RolloutConfig{},
+			&RolloutConfig{Prefix: String("prefix")},
+		},
+		{
+			"prefix_empty_two",
+			&RolloutConfig{},
+			&RolloutConfig{Prefix: String("prefix")},
+			&RolloutConfig{Prefix: String("prefix")},
+		},
+		{
+			"prefix_same",
+			&RolloutConfig{Prefix: String("prefix")},
+			&RolloutConfig{Prefix: String("prefix")},
+			&RolloutConfig{Prefix: String("prefix")},
+		},
+		{
+			"stagger_overrides",
+			&RolloutConfig{Stagger: TimeDuration(10 * time.Second)},
+			&RolloutConfig{Stagger: TimeDuration(0 * time.Second)},
+			&RolloutConfig{Stagger: TimeDuration(0 * time.Second)},
+		},
+		{
+			"stagger_empty_one",
+			&RolloutConfig{Stagger: TimeDuration(10 * time.Second)},
+			&RolloutConfig{},
+			&RolloutConfig{Stagger: TimeDuration(10 * time.Second)},
+		},
+		{
+			"stagger_empty_two",
+			&RolloutConfig{},
+			&RolloutConfig{Stagger: TimeDuration(10 * time.Second)},
+			&RolloutConfig{Stagger: TimeDuration(10 * time.Second)},
+		},
+		{
+			"stagger_same",
+			&RolloutConfig{Stagger: TimeDuration(10 * time.Second)},
+			&RolloutConfig{Stagger: TimeDuration(10 * time.Second)},
+			&RolloutConfig{Stagger: TimeDuration(10 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestRolloutConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *RolloutConfig
+		r    *RolloutConfig
+	}{
+		{
+			"empty",
+			&RolloutConfig{},
+			&RolloutConfig{
+				Enabled:     Bool(false),
+				MaxParallel: Int(DefaultRolloutMaxParallel),
+				Prefix:      String(DefaultRolloutPrefix),
+				Stagger:     TimeDuration(DefaultRolloutStagger),
+			},
+		},
+		{
+			"with_max_parallel",
+			&RolloutConfig{
+				MaxParallel: Int(5),
+			},
+			&RolloutConfig{
+				Enabled:     Bool(true),
+				MaxParallel: Int(5),
+				Prefix:      String(DefaultRolloutPrefix),
+				Stagger:     TimeDuration(DefaultRolloutStagger),
+			},
+		},
+		{
+			"with_stagger",
+			&RolloutConfig{
+				Stagger: TimeDuration(30 * time.Second),
+			},
+			&RolloutConfig{
+				Enabled:     Bool(true),
+				MaxParallel: Int(DefaultRolloutMaxParallel),
+				Prefix:      String(DefaultRolloutPrefix),
+				Stagger:     TimeDuration(30 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import "fmt"
+
+// SandboxConfig is the configuration for confining the Consul Template
+// process, so that rendering untrusted templates cannot write outside of the
+// declared destinations or connect to anything but the declared backends.
+type SandboxConfig struct {
+	// AllowedPorts is the list of additional TCP ports the process may connect
+	// to. The ports of the Consul and Vault addresses and of HTTP destinations
+	// are always allowed.
+	AllowedPorts []int `mapstructure:"allowed_ports"`
+
+	// Enabled controls whether the sandbox is enabled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// WritablePaths is the list of additional paths the process may write
+	// beneath. The directories of template destinations, the PID file and the
+	// snapshot are always writable.
+	WritablePaths []string `mapstructure:"writable_paths"`
+}
+
+// DefaultSandboxConfig returns a configuration that is populated with the
+// default values.
+func DefaultSandboxConfig() *SandboxConfig {
+	return &SandboxConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *SandboxConfig) Copy() *SandboxConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o SandboxConfig
+
+	if c.AllowedPorts != nil {
+		o.AllowedPorts = append([]int{}, c.AllowedPorts...)
+	}
+
+	o.Enabled = c.Enabled
+
+	if c.WritablePaths != nil {
+		o.WritablePaths = append([]string{}, c.WritablePaths...)
+	}
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *SandboxConfig) Merge(o *SandboxConfig) *SandboxConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.AllowedPorts != nil {
+		r.AllowedPorts = append(r.AllowedPorts, o.AllowedPorts...)
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.WritablePaths != nil {
+		r.WritablePaths = append(r.WritablePaths, o.WritablePaths...)
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *SandboxConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(false ||
+			len(c.AllowedPorts) > 0 ||
+			len(c.WritablePaths) > 0)
+	}
+
+	if c.AllowedPorts == nil {
+		c.AllowedPorts = []int{}
+	}
+
+	if c.WritablePaths == nil {
+		c.WritablePaths = []string{}
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *SandboxConfig) GoString() string {
+	if c == nil {
+		return "(*SandboxConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&SandboxConfig{"+
+		"AllowedPorts:%v, "+
+		"Enabled:%s, "+
+		"WritablePaths:%v"+
+		"}",
+		c.AllowedPorts,
+		BoolGoString(c.Enabled),
+		c.WritablePaths,
+	)
+}
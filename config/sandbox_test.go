@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSandboxConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *SandboxConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&SandboxConfig{},
+		},
+		{
+			"copy",
+			&SandboxConfig{
+				AllowedPorts:  []int{8125},
+				Enabled:       Bool(true),
+				WritablePaths: []string{"/var/lib/app"},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestSandboxConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *SandboxConfig
+		b    *SandboxConfig
+		r    *SandboxConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&SandboxConfig{},
+			&SandboxConfig{},
+		},
+		{
+			"nil_b",
+			&SandboxConfig{},
+			nil,
+			&SandboxConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&SandboxConfig{},
+			&SandboxConfig{},
+			&SandboxConfig{},
+		},
+		{
+			"allowed_ports_merges",
+			&SandboxConfig{AllowedPorts: []int{8125}},
+			&SandboxConfig{AllowedPorts: []int{9000}},
+			&SandboxConfig{AllowedPorts: []int{8125, 9000}},
+		},
+		{
+			"allowed_ports_empty_one",
+			&SandboxConfig{AllowedPorts: []int{8125}},
+			&SandboxConfig{},
+			&SandboxConfig{AllowedPorts: []int{8125}},
+		},
+		{
+			"allowed_ports_empty_two",
+			&SandboxConfig{},
+			&SandboxConfig{AllowedPorts: []int{8125}},
+			&SandboxConfig{AllowedPorts: []int{8125}},
+		},
+		{
+			"enabled_overrides",
+			&SandboxConfig{Enabled: Bool(true)},
+			&SandboxConfig{Enabled: Bool(false)},
+			&SandboxConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&SandboxConfig{Enabled: Bool(true)},
+			&SandboxConfig{},
+			&SandboxConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&SandboxConfig{},
+			&SandboxConfig{Enabled: Bool(true)},
+			&SandboxConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&SandboxConfig{Enabled: Bool(true)},
+			&SandboxConfig{Enabled: Bool(true)},
+			&SandboxConfig{Enabled: Bool(true)},
+		},
+		{
+			"writable_paths_merges",
+			&SandboxConfig{WritablePaths: []string{"/a"}},
+			&SandboxConfig{WritablePaths: []string{"/b"}},
+			&SandboxConfig{WritablePaths: []string{"/a", "/b"}},
+		},
+		{
+			"writable_paths_empty_one",
+			&SandboxConfig{WritablePaths: []string{"/a"}},
+			&SandboxConfig{},
+			&SandboxConfig{WritablePaths: []string{"/a"}},
+		},
+		{
+			"writable_paths_empty_two",
+			&SandboxConfig{},
+			&SandboxConfig{WritablePaths: []string{"/a"}},
+			&SandboxConfig{WritablePaths: []string{"/a"}},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestSandboxConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *SandboxConfig
+		r    *SandboxConfig
+	}{
+		{
+			"empty",
+			&SandboxConfig{},
+			&SandboxConfig{
+				AllowedPorts:  []int{},
+				Enabled:       Bool(false),
+				WritablePaths: []string{},
+			},
+		},
+		{
+			"with_writable_paths",
+			&SandboxConfig{
+				WritablePaths: []string{"/var/lib/app"},
+			},
+			&SandboxConfig{
+				AllowedPorts:  []int{},
+				Enabled:       Bool(true),
+				WritablePaths: []string{"/var/lib/app"},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import "fmt"
+
+// SnapshotConfig is the configuration for persisting the last-known watch
+// indexes and data for dependencies to disk, so that a restarted process can
+// resume blocking queries where it left off instead of re-fetching everything.
+type SnapshotConfig struct {
+	// Enabled controls whether snapshots are written and restored.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Encryption configures encrypting the snapshot, since the data of Consul
+	// dependencies may contain secrets.
+	Encryption *EncryptionConfig `mapstructure:"encryption"`
+
+	// Path is the location on disk where the snapshot is stored.
+	Path *string `mapstructure:"path"`
+}
+
+// DefaultSnapshotConfig returns a configuration that is populated with the
+// default values.
+func DefaultSnapshotConfig() *SnapshotConfig {
+	return &SnapshotConfig{
+		Encryption: DefaultEncryptionConfig(),
+	}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *SnapshotConfig) Copy() *SnapshotConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o SnapshotConfig
+	o.Enabled = c.Enabled
+	if c.Encryption != nil {
+		o.Encryption = c.Encryption.Copy()
+	}
+	o.Path = c.Path
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *SnapshotConfig) Merge(o *SnapshotConfig) *SnapshotConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Encryption != nil {
+		r.Encryption = r.Encryption.Merge(o.Encryption)
+	}
+
+	if o.Path != nil {
+		r.Path = o.Path
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *SnapshotConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Path))
+	}
+
+	if c.Encryption == nil {
+		c.Encryption = DefaultEncryptionConfig()
+	}
+	c.Encryption.Finalize()
+
+	if c.Path == nil {
+		c.Path = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *SnapshotConfig) GoString() string {
+	if c == nil {
+		return "(*SnapshotConfig)(nil)"
+	}
+	return fmt.Sprintf("&SnapshotConfig{"+
+		"Enabled:%s, "+
+		"Encryption:%#v, "+
+		"Path:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		c.Encryption,
+		StringGoString(c.Path),
+	)
+}
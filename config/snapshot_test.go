@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *SnapshotConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&SnapshotConfig{},
+		},
+		{
+			"same_enabled",
+			&SnapshotConfig{
+				Enabled: Bool(true),
+				Encryption: &EncryptionConfig{
+					KeyFile: String("/snapshot.key"),
+				},
+				Path: String("path"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestSnapshotConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *SnapshotConfig
+		b    *SnapshotConfig
+		r    *SnapshotConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&SnapshotConfig{},
+			&SnapshotConfig{},
+		},
+		{
+			"nil_b",
+			&SnapshotConfig{},
+			nil,
+			&SnapshotConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&SnapshotConfig{},
+			&SnapshotConfig{},
+			&SnapshotConfig{},
+		},
+		{
+			"enabled_overrides",
+			&SnapshotConfig{Enabled: Bool(true)},
+			&SnapshotConfig{Enabled: Bool(false)},
+			&SnapshotConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&SnapshotConfig{Enabled: Bool(true)},
+			&SnapshotConfig{},
+			&SnapshotConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&SnapshotConfig{},
+			&SnapshotConfig{Enabled: Bool(true)},
+			&SnapshotConfig{Enabled: Bool(true)},
+		},
+		{
+			"path_overrides",
+			&SnapshotConfig{Path: String("path")},
+			&SnapshotConfig{Path: String("")},
+			&SnapshotConfig{Path: String("")},
+		},
+		{
+			"path_empty_one",
+			&SnapshotConfig{Path: String("path")},
+			&SnapshotConfig{},
+			&SnapshotConfig{Path: String("path")},
+		},
+		{
+			"path_empty_two",
+			&SnapshotConfig{},
+			&SnapshotConfig{Path: String("path")},
+			&SnapshotConfig{Path: String("path")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestSnapshotConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *SnapshotConfig
+		r    *SnapshotConfig
+	}{
+		{
+			"empty",
+			&SnapshotConfig{},
+			&SnapshotConfig{
+				Enabled: Bool(false),
+				Encryption: &EncryptionConfig{
+					Enabled:           Bool(false),
+					KeyFile:           String(""),
+					VaultTransitKey:   String(""),
+					VaultTransitMount: String(DefaultEncryptionVaultTransitMount),
+				},
+				Path: String(""),
+			},
+		},
+		{
+			"with_path",
+			&SnapshotConfig{
+				Path: String("path"),
+			},
+			&SnapshotConfig{
+				Enabled: Bool(true),
+				Encryption: &EncryptionConfig{
+					Enabled:           Bool(false),
+					KeyFile:           String(""),
+					VaultTransitKey:   String(""),
+					VaultTransitMount: String(DefaultEncryptionVaultTransitMount),
+				},
+				Path: String("path"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
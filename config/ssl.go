@@ -16,6 +16,14 @@ type SSLConfig struct {
 	Key        *string `mapstructure:"key"`
 	ServerName *string `mapstructure:"server_name"`
 	Verify     *bool   `mapstructure:"verify"`
+
+	// TLSCipherSuites is the list of cipher suites to offer, by their Go
+	// constant names. If empty, the Go defaults are used.
+	TLSCipherSuites []string `mapstructure:"tls_cipher_suites"`
+
+	// TLSMinVersion is the minimum TLS version to accept: "tls10", "tls11" or
+	// "tls12". If empty, the Go default is used.
+	TLSMinVersion *string `mapstructure:"tls_min_version"`
 }
 
 // DefaultSSLConfig returns a configuration that is populated with the
@@ -38,6 +46,13 @@ func (c *SSLConfig) Copy() *SSLConfig {
 	o.Key = c.Key
 	o.ServerName = c.ServerName
 	o.Verify = c.Verify
+
+	if c.TLSCipherSuites != nil {
+		o.TLSCipherSuites = make([]string, len(c.TLSCipherSuites))
+		copy(o.TLSCipherSuites, c.TLSCipherSuites)
+	}
+
+	o.TLSMinVersion = c.TLSMinVersion
 	return &o
 }
 
@@ -87,6 +102,14 @@ func (c *SSLConfig) Merge(o *SSLConfig) *SSLConfig {
 		r.Verify = o.Verify
 	}
 
+	if o.TLSCipherSuites != nil {
+		r.TLSCipherSuites = append(r.TLSCipherSuites, o.TLSCipherSuites...)
+	}
+
+	if o.TLSMinVersion != nil {
+		r.TLSMinVersion = o.TLSMinVersion
+	}
+
 	return r
 }
 
@@ -99,7 +122,9 @@ func (c *SSLConfig) Finalize() {
 			StringPresent(c.CaPath) ||
 			StringPresent(c.Key) ||
 			StringPresent(c.ServerName) ||
-			BoolPresent(c.Verify))
+			BoolPresent(c.Verify) ||
+			len(c.TLSCipherSuites) > 0 ||
+			StringPresent(c.TLSMinVersion))
 	}
 
 	if c.Cert == nil {
@@ -125,6 +150,14 @@ func (c *SSLConfig) Finalize() {
 	if c.Verify == nil {
 		c.Verify = Bool(DefaultSSLVerify)
 	}
+
+	if c.TLSCipherSuites == nil {
+		c.TLSCipherSuites = []string{}
+	}
+
+	if c.TLSMinVersion == nil {
+		c.TLSMinVersion = String("")
+	}
 }
 
 // GoString defines the printable version of this struct.
@@ -140,7 +173,9 @@ func (c *SSLConfig) GoString() string {
 		"Enabled:%s, "+
 		"Key:%s, "+
 		"ServerName:%s, "+
-		"Verify:%s"+
+		"Verify:%s, "+
+		"TLSCipherSuites:%q, "+
+		"TLSMinVersion:%s"+
 		"}",
 		StringGoString(c.CaCert),
 		StringGoString(c.CaPath),
@@ -149,5 +184,7 @@ func (c *SSLConfig) GoString() string {
 		StringGoString(c.Key),
 		StringGoString(c.ServerName),
 		BoolGoString(c.Verify),
+		c.TLSCipherSuites,
+		StringGoString(c.TLSMinVersion),
 	)
 }
@@ -29,6 +29,10 @@ func TestSSLConfig_Copy(t *testing.T) {
 				Cert:       String("cert"),
 				Key:        String("key"),
 				ServerName: String("server_name"),
+				TLSCipherSuites: []string{
+					"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+				},
+				TLSMinVersion: String("tls12"),
 			},
 		},
 	}
@@ -242,6 +246,48 @@ func TestSSLConfig_Merge(t *testing.T) {
 			&SSLConfig{ServerName: String("server_name")},
 			&SSLConfig{ServerName: String("server_name")},
 		},
+		{
+			"tls_cipher_suites_merges",
+			&SSLConfig{TLSCipherSuites: []string{"a"}},
+			&SSLConfig{TLSCipherSuites: []string{"b"}},
+			&SSLConfig{TLSCipherSuites: []string{"a", "b"}},
+		},
+		{
+			"tls_cipher_suites_empty_one",
+			&SSLConfig{TLSCipherSuites: []string{"a"}},
+			&SSLConfig{},
+			&SSLConfig{TLSCipherSuites: []string{"a"}},
+		},
+		{
+			"tls_cipher_suites_empty_two",
+			&SSLConfig{},
+			&SSLConfig{TLSCipherSuites: []string{"a"}},
+			&SSLConfig{TLSCipherSuites: []string{"a"}},
+		},
+		{
+			"tls_min_version_overrides",
+			&SSLConfig{TLSMinVersion: String("tls11")},
+			&SSLConfig{TLSMinVersion: String("tls12")},
+			&SSLConfig{TLSMinVersion: String("tls12")},
+		},
+		{
+			"tls_min_version_empty_one",
+			&SSLConfig{TLSMinVersion: String("tls12")},
+			&SSLConfig{},
+			&SSLConfig{TLSMinVersion: String("tls12")},
+		},
+		{
+			"tls_min_version_empty_two",
+			&SSLConfig{},
+			&SSLConfig{TLSMinVersion: String("tls12")},
+			&SSLConfig{TLSMinVersion: String("tls12")},
+		},
+		{
+			"tls_min_version_same",
+			&SSLConfig{TLSMinVersion: String("tls12")},
+			&SSLConfig{TLSMinVersion: String("tls12")},
+			&SSLConfig{TLSMinVersion: String("tls12")},
+		},
 	}
 
 	for i, tc := range cases {
@@ -264,13 +310,15 @@ func TestSSLConfig_Finalize(t *testing.T) {
 			"empty",
 			&SSLConfig{},
 			&SSLConfig{
-				Enabled:    Bool(false),
-				Cert:       String(""),
-				CaCert:     String(""),
-				CaPath:     String(""),
-				Key:        String(""),
-				ServerName: String(""),
-				Verify:     Bool(true),
+				Enabled:         Bool(false),
+				Cert:            String(""),
+				CaCert:          String(""),
+				CaPath:          String(""),
+				Key:             String(""),
+				ServerName:      String(""),
+				Verify:          Bool(true),
+				TLSCipherSuites: []string{},
+				TLSMinVersion:   String(""),
 			},
 		},
 		{
@@ -279,13 +327,15 @@ func TestSSLConfig_Finalize(t *testing.T) {
 				Cert: String("cert"),
 			},
 			&SSLConfig{
-				Enabled:    Bool(true),
-				Cert:       String("cert"),
-				CaCert:     String(""),
-				CaPath:     String(""),
-				Key:        String(""),
-				ServerName: String(""),
-				Verify:     Bool(true),
+				Enabled:         Bool(true),
+				Cert:            String("cert"),
+				CaCert:          String(""),
+				CaPath:          String(""),
+				Key:             String(""),
+				ServerName:      String(""),
+				Verify:          Bool(true),
+				TLSCipherSuites: []string{},
+				TLSMinVersion:   String(""),
 			},
 		},
 		{
@@ -294,13 +344,15 @@ func TestSSLConfig_Finalize(t *testing.T) {
 				CaCert: String("ca_cert"),
 			},
 			&SSLConfig{
-				Enabled:    Bool(true),
-				Cert:       String(""),
-				CaCert:     String("ca_cert"),
-				CaPath:     String(""),
-				Key:        String(""),
-				ServerName: String(""),
-				Verify:     Bool(true),
+				Enabled:         Bool(true),
+				Cert:            String(""),
+				CaCert:          String("ca_cert"),
+				CaPath:          String(""),
+				Key:             String(""),
+				ServerName:      String(""),
+				Verify:          Bool(true),
+				TLSCipherSuites: []string{},
+				TLSMinVersion:   String(""),
 			},
 		},
 		{
@@ -309,13 +361,15 @@ func TestSSLConfig_Finalize(t *testing.T) {
 				CaPath: String("ca_path"),
 			},
 			&SSLConfig{
-				Enabled:    Bool(true),
-				Cert:       String(""),
-				CaCert:     String(""),
-				CaPath:     String("ca_path"),
-				Key:        String(""),
-				ServerName: String(""),
-				Verify:     Bool(true),
+				Enabled:         Bool(true),
+				Cert:            String(""),
+				CaCert:          String(""),
+				CaPath:          String("ca_path"),
+				Key:             String(""),
+				ServerName:      String(""),
+				Verify:          Bool(true),
+				TLSCipherSuites: []string{},
+				TLSMinVersion:   String(""),
 			},
 		},
 		{
@@ -324,13 +378,15 @@ func TestSSLConfig_Finalize(t *testing.T) {
 				Key: String("key"),
 			},
 			&SSLConfig{
-				Enabled:    Bool(true),
-				Cert:       String(""),
-				CaCert:     String(""),
-				CaPath:     String(""),
-				Key:        String("key"),
-				ServerName: String(""),
-				Verify:     Bool(true),
+				Enabled:         Bool(true),
+				Cert:            String(""),
+				CaCert:          String(""),
+				CaPath:          String(""),
+				Key:             String("key"),
+				ServerName:      String(""),
+				Verify:          Bool(true),
+				TLSCipherSuites: []string{},
+				TLSMinVersion:   String(""),
 			},
 		},
 		{
@@ -339,13 +395,32 @@ func TestSSLConfig_Finalize(t *testing.T) {
 				ServerName: String("server_name"),
 			},
 			&SSLConfig{
-				Enabled:    Bool(true),
-				Cert:       String(""),
-				CaCert:     String(""),
-				CaPath:     String(""),
-				Key:        String(""),
-				ServerName: String("server_name"),
-				Verify:     Bool(true),
+				Enabled:         Bool(true),
+				Cert:            String(""),
+				CaCert:          String(""),
+				CaPath:          String(""),
+				Key:             String(""),
+				ServerName:      String("server_name"),
+				Verify:          Bool(true),
+				TLSCipherSuites: []string{},
+				TLSMinVersion:   String(""),
+			},
+		},
+		{
+			"with_tls_min_version",
+			&SSLConfig{
+				TLSMinVersion: String("tls12"),
+			},
+			&SSLConfig{
+				Enabled:         Bool(true),
+				Cert:            String(""),
+				CaCert:          String(""),
+				CaPath:          String(""),
+				Key:             String(""),
+				ServerName:      String(""),
+				Verify:          Bool(true),
+				TLSCipherSuites: []string{},
+				TLSMinVersion:   String("tls12"),
 			},
 		},
 	}
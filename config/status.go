@@ -0,0 +1,106 @@
+package config
+
+import "fmt"
+
+const (
+	// DefaultStatusHistory is the default number of render events kept in the
+	// status history.
+	DefaultStatusHistory = 100
+)
+
+// StatusConfig is the configuration for keeping a history of recent render
+// events and writing it to a status file, so external tooling can tell when
+// and why each destination last changed.
+type StatusConfig struct {
+	// Enabled controls whether the status file is written.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// History is the number of most recent render events which are kept.
+	History *int `mapstructure:"history"`
+
+	// Path is the location on disk where the status file is written.
+	Path *string `mapstructure:"path"`
+}
+
+// DefaultStatusConfig returns a configuration that is populated with the
+// default values.
+func DefaultStatusConfig() *StatusConfig {
+	return &StatusConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *StatusConfig) Copy() *StatusConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o StatusConfig
+	o.Enabled = c.Enabled
+	o.History = c.History
+	o.Path = c.Path
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *StatusConfig) Merge(o *StatusConfig) *StatusConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.History != nil {
+		r.History = o.History
+	}
+
+	if o.Path != nil {
+		r.Path = o.Path
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *StatusConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Path))
+	}
+
+	if c.History == nil {
+		c.History = Int(DefaultStatusHistory)
+	}
+
+	if c.Path == nil {
+		c.Path = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *StatusConfig) GoString() string {
+	if c == nil {
+		return "(*StatusConfig)(nil)"
+	}
+	return fmt.Sprintf("&StatusConfig{"+
+		"Enabled:%s, "+
+		"History:%s, "+
+		"Path:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		IntGoString(c.History),
+		StringGoString(c.Path),
+	)
+}
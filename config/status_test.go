@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestStatusConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *StatusConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&StatusConfig{},
+		},
+		{
+			"same_enabled",
+			&StatusConfig{
+				Enabled: Bool(true),
+				History: Int(10),
+				Path:    String("path"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestStatusConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *StatusConfig
+		b    *StatusConfig
+		r    *StatusConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&StatusConfig{},
+			&StatusConfig{},
+		},
+		{
+			"nil_b",
+			&StatusConfig{},
+			nil,
+			&StatusConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"enabled_overrides",
+			&StatusConfig{Enabled: Bool(true)},
+			&StatusConfig{Enabled: Bool(false)},
+			&StatusConfig{Enabled: Bool(false)},
+		},
+		{
+			"history_overrides",
+			&StatusConfig{History: Int(10)},
+			&StatusConfig{History: Int(20)},
+			&StatusConfig{History: Int(20)},
+		},
+		{
+			"history_empty_one",
+			&StatusConfig{History: Int(10)},
+			&StatusConfig{},
+			&StatusConfig{History: Int(10)},
+		},
+		{
+			"path_overrides",
+			&StatusConfig{Path: String("path")},
+			&StatusConfig{Path: String("")},
+			&StatusConfig{Path: String("")},
+		},
+		{
+			"path_empty_two",
+			&StatusConfig{},
+			&StatusConfig{Path: String("path")},
+			&StatusConfig{Path: String("path")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestStatusConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *StatusConfig
+		r    *StatusConfig
+	}{
+		{
+			"empty",
+			&StatusConfig{},
+			&StatusConfig{
+				Enabled: Bool(false),
+				History: Int(DefaultStatusHistory),
+				Path:    String(""),
+			},
+		},
+		{
+			"with_path",
+			&StatusConfig{
+				Path: String("path"),
+			},
+			&StatusConfig{
+				Enabled: Bool(true),
+				History: Int(DefaultStatusHistory),
+				Path:    String("path"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -5,12 +5,33 @@ import "fmt"
 const (
 	// DefaultSyslogFacility is the default facility to log to.
 	DefaultSyslogFacility = "LOCAL0"
+
+	// SyslogFormatRFC3164 is the traditional BSD syslog message format.
+	SyslogFormatRFC3164 = "rfc3164"
+
+	// SyslogFormatRFC5424 is the syslog message format with RFC3339 timestamps
+	// and structured data.
+	SyslogFormatRFC5424 = "rfc5424"
+
+	// DefaultSyslogFormat is the default syslog message format.
+	DefaultSyslogFormat = SyslogFormatRFC3164
 )
 
 // SyslogConfig is the configuration for syslog.
 type SyslogConfig struct {
+	// Address is the address of a remote syslog server, like
+	// "udp://10.0.0.1:514", "tcp://10.0.0.1:514", "tls://logs.example.com:6514"
+	// or "unix:///dev/log". If empty, the local syslog socket is used.
+	Address *string `mapstructure:"address"`
+
 	Enabled  *bool   `mapstructure:"enabled"`
 	Facility *string `mapstructure:"facility"`
+
+	// Format is the message format, "rfc3164" or "rfc5424".
+	Format *string `mapstructure:"format"`
+
+	// SSL is the TLS configuration of "tls://" addresses.
+	SSL *SSLConfig `mapstructure:"ssl"`
 }
 
 // DefaultSyslogConfig returns a configuration that is populated with the
@@ -26,8 +47,13 @@ func (c *SyslogConfig) Copy() *SyslogConfig {
 	}
 
 	var o SyslogConfig
+	o.Address = c.Address
 	o.Enabled = c.Enabled
 	o.Facility = c.Facility
+	o.Format = c.Format
+	if c.SSL != nil {
+		o.SSL = c.SSL.Copy()
+	}
 	return &o
 }
 
@@ -49,6 +75,10 @@ func (c *SyslogConfig) Merge(o *SyslogConfig) *SyslogConfig {
 
 	r := c.Copy()
 
+	if o.Address != nil {
+		r.Address = o.Address
+	}
+
 	if o.Enabled != nil {
 		r.Enabled = o.Enabled
 	}
@@ -57,18 +87,42 @@ func (c *SyslogConfig) Merge(o *SyslogConfig) *SyslogConfig {
 		r.Facility = o.Facility
 	}
 
+	if o.Format != nil {
+		r.Format = o.Format
+	}
+
+	if o.SSL != nil {
+		r.SSL = r.SSL.Merge(o.SSL)
+	}
+
 	return r
 }
 
 // Finalize ensures there no nil pointers.
 func (c *SyslogConfig) Finalize() {
+	if c.Address == nil {
+		c.Address = String("")
+	}
+
 	if c.Enabled == nil {
-		c.Enabled = Bool(StringPresent(c.Facility))
+		c.Enabled = Bool(false ||
+			StringPresent(c.Address) ||
+			StringPresent(c.Facility) ||
+			StringPresent(c.Format))
 	}
 
 	if c.Facility == nil {
 		c.Facility = String(DefaultSyslogFacility)
 	}
+
+	if c.Format == nil {
+		c.Format = String(DefaultSyslogFormat)
+	}
+
+	if c.SSL == nil {
+		c.SSL = DefaultSSLConfig()
+	}
+	c.SSL.Finalize()
 }
 
 // GoString defines the printable version of this struct.
@@ -78,10 +132,16 @@ func (c *SyslogConfig) GoString() string {
 	}
 
 	return fmt.Sprintf("&SyslogConfig{"+
+		"Address:%s, "+
 		"Enabled:%s, "+
-		"Facility:%s"+
+		"Facility:%s, "+
+		"Format:%s, "+
+		"SSL:%#v"+
 		"}",
+		StringGoString(c.Address),
 		BoolGoString(c.Enabled),
 		StringGoString(c.Facility),
+		StringGoString(c.Format),
+		c.SSL,
 	)
 }
@@ -22,8 +22,11 @@ func TestSyslogConfig_Copy(t *testing.T) {
 		{
 			"same_enabled",
 			&SyslogConfig{
+				Address:  String("tcp://127.0.0.1:514"),
 				Enabled:  Bool(true),
 				Facility: String("facility"),
+				Format:   String(SyslogFormatRFC5424),
+				SSL:      &SSLConfig{Enabled: Bool(true)},
 			},
 		},
 	}
@@ -69,6 +72,18 @@ func TestSyslogConfig_Merge(t *testing.T) {
 			&SyslogConfig{},
 			&SyslogConfig{},
 		},
+		{
+			"address_overrides",
+			&SyslogConfig{Address: String("udp://127.0.0.1:514")},
+			&SyslogConfig{Address: String("tls://127.0.0.1:6514")},
+			&SyslogConfig{Address: String("tls://127.0.0.1:6514")},
+		},
+		{
+			"address_empty_one",
+			&SyslogConfig{Address: String("udp://127.0.0.1:514")},
+			&SyslogConfig{},
+			&SyslogConfig{Address: String("udp://127.0.0.1:514")},
+		},
 		{
 			"enabled_overrides",
 			&SyslogConfig{Enabled: Bool(true)},
@@ -117,6 +132,24 @@ func TestSyslogConfig_Merge(t *testing.T) {
 			&SyslogConfig{Facility: String("facility")},
 			&SyslogConfig{Facility: String("facility")},
 		},
+		{
+			"format_overrides",
+			&SyslogConfig{Format: String(SyslogFormatRFC3164)},
+			&SyslogConfig{Format: String(SyslogFormatRFC5424)},
+			&SyslogConfig{Format: String(SyslogFormatRFC5424)},
+		},
+		{
+			"format_empty_two",
+			&SyslogConfig{},
+			&SyslogConfig{Format: String(SyslogFormatRFC5424)},
+			&SyslogConfig{Format: String(SyslogFormatRFC5424)},
+		},
+		{
+			"ssl_merges",
+			&SyslogConfig{SSL: &SSLConfig{CaCert: String("ca.pem")}},
+			&SyslogConfig{SSL: &SSLConfig{ServerName: String("logs")}},
+			&SyslogConfig{SSL: &SSLConfig{CaCert: String("ca.pem"), ServerName: String("logs")}},
+		},
 	}
 
 	for i, tc := range cases {
@@ -139,8 +172,21 @@ func TestSyslogConfig_Finalize(t *testing.T) {
 			"empty",
 			&SyslogConfig{},
 			&SyslogConfig{
+				Address:  String(""),
 				Enabled:  Bool(false),
 				Facility: String(DefaultSyslogFacility),
+				Format:   String(DefaultSyslogFormat),
+				SSL: &SSLConfig{
+					CaCert:          String(""),
+					CaPath:          String(""),
+					Cert:            String(""),
+					Enabled:         Bool(false),
+					Key:             String(""),
+					ServerName:      String(""),
+					Verify:          Bool(true),
+					TLSCipherSuites: []string{},
+					TLSMinVersion:   String(""),
+				},
 			},
 		},
 		{
@@ -149,8 +195,44 @@ func TestSyslogConfig_Finalize(t *testing.T) {
 				Facility: String("facility"),
 			},
 			&SyslogConfig{
+				Address:  String(""),
 				Enabled:  Bool(true),
 				Facility: String("facility"),
+				Format:   String(DefaultSyslogFormat),
+				SSL: &SSLConfig{
+					CaCert:          String(""),
+					CaPath:          String(""),
+					Cert:            String(""),
+					Enabled:         Bool(false),
+					Key:             String(""),
+					ServerName:      String(""),
+					Verify:          Bool(true),
+					TLSCipherSuites: []string{},
+					TLSMinVersion:   String(""),
+				},
+			},
+		},
+		{
+			"with_address",
+			&SyslogConfig{
+				Address: String("tls://127.0.0.1:6514"),
+			},
+			&SyslogConfig{
+				Address:  String("tls://127.0.0.1:6514"),
+				Enabled:  Bool(true),
+				Facility: String(DefaultSyslogFacility),
+				Format:   String(DefaultSyslogFormat),
+				SSL: &SSLConfig{
+					CaCert:          String(""),
+					CaPath:          String(""),
+					Cert:            String(""),
+					Enabled:         Bool(false),
+					Key:             String(""),
+					ServerName:      String(""),
+					Verify:          Bool(true),
+					TLSCipherSuites: []string{},
+					TLSMinVersion:   String(""),
+				},
 			},
 		},
 	}
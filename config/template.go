@@ -15,9 +15,19 @@ const (
 	// specified.
 	DefaultTemplateFilePerms = 0644
 
+	// DefaultTemplateDirPerms are the default permissions for any missing
+	// parent directories of the destination which are created on render.
+	DefaultTemplateDirPerms = 0755
+
 	// DefaultTemplateCommandTimeout is the amount of time to wait for a command
 	// to return.
 	DefaultTemplateCommandTimeout = 30 * time.Second
+
+	// TemplateApprovalAuto and TemplateApprovalManual are the valid values for
+	// the approval of a template. With manual approval, changed contents are
+	// staged next to the destination until they are approved by an operator.
+	TemplateApprovalAuto   = "auto"
+	TemplateApprovalManual = "manual"
 )
 
 var (
@@ -36,10 +46,26 @@ var (
 // TemplateConfig is a representation of a template on disk, as well as the
 // associated commands and reload instructions.
 type TemplateConfig struct {
+	// After is the list of names of templates which must render, and whose
+	// commands must complete, before this template renders and its command
+	// runs.
+	After []string `mapstructure:"after"`
+
+	// Approval determines if changed contents are written to the destination
+	// immediately ("auto"), or staged to "<destination>.pending" until they are
+	// approved by an operator ("manual"). The default value is "auto".
+	Approval *string `mapstructure:"approval"`
+
 	// Backup determines if this template should retain a backup. The default
 	// value is false.
 	Backup *bool `mapstructure:"backup"`
 
+	// ChangeReport determines if a JSON report of the dependency values which
+	// changed is written to a temporary file for the command, whose path is
+	// given in the CT_CHANGE_REPORT environment variable. The default value is
+	// false.
+	ChangeReport *bool `mapstructure:"change_report"`
+
 	// Command is the arbitrary command to execute after a template has
 	// successfully rendered. This is DEPRECATED. Use Exec instead.
 	Command *string `mapstructure:"command"`
@@ -48,30 +74,129 @@ type TemplateConfig struct {
 	// before force-killing it. This is DEPRECATED. Use Exec instead.
 	CommandTimeout *time.Duration `mapstructure:"command_timeout"`
 
+	// CommandSandbox configures confining the command of this template on
+	// Linux, limiting what it can do when the data which triggers it is not
+	// trusted.
+	CommandSandbox *CommandSandboxConfig `mapstructure:"command_sandbox"`
+
 	// Contents are the raw template contents to evaluate. Either this or Source
 	// must be specified, but not both.
 	Contents *string `mapstructure:"contents"`
 
+	// CreateDestDirs determines if missing parent directories of the
+	// destination are created. The default value is true.
+	CreateDestDirs *bool `mapstructure:"create_dest_dirs"`
+
+	// CreateDestDirsPerms are the file system permissions to use when creating
+	// missing parent directories of the destination. Like Perms, these are
+	// applied exactly, regardless of the process umask. They are set with the
+	// dest_dir_perms option, or the deprecated create_dest_dirs_perms.
+	CreateDestDirsPerms *os.FileMode `mapstructure:"dest_dir_perms"`
+
+	// DestDirGroup is the group name or ID to give ownership of any created
+	// parent directories of the destination.
+	DestDirGroup *string `mapstructure:"dest_dir_group"`
+
+	// DestDirUser is the user name or ID to give ownership of any created
+	// parent directories of the destination.
+	DestDirUser *string `mapstructure:"dest_dir_user"`
+
 	// Destination is the location on disk where the template should be rendered.
 	// This is required unless running in debug/dry mode.
 	Destination *string `mapstructure:"destination"`
 
+	// Diff configures writing an artifact describing each change to the
+	// destination into a directory, for use by external review tooling.
+	Diff *DiffConfig `mapstructure:"diff"`
+
+	// Encryption configures encrypting the backup of the destination, which
+	// may contain secrets, before it is written to disk.
+	Encryption *EncryptionConfig `mapstructure:"encryption"`
+
+	// Engine is the name of the template language used to evaluate this
+	// template, like "gotemplate" or "handlebars". The default is Go's
+	// text/template.
+	Engine *string `mapstructure:"engine"`
+
 	// Exec is the configuration for the command to run when the template renders
 	// successfully.
 	Exec *ExecConfig `mapstructure:"exec"`
 
+	// Guard is a Go template pipeline, such as `ge (len (service "web")) 2`,
+	// which must evaluate to a true value for the rendered contents to be
+	// written. While it does not hold, the destination keeps its previous
+	// contents.
+	Guard *string `mapstructure:"guard"`
+
+	// HoldDown configures the per-template hold-down timers. After a change
+	// is rendered, further changes within the minimum are held and rendered
+	// together, at most the maximum after the first held change.
+	HoldDown *WaitConfig `mapstructure:"hold_down"`
+
+	// HTTP configures the request made when Destination is an http:// or
+	// https:// URL.
+	HTTP *HTTPDestinationConfig `mapstructure:"http"`
+
+	// Name is an optional name for this template, by which other templates can
+	// refer to it.
+	Name *string `mapstructure:"name"`
+
 	// Perms are the file system permissions to use when creating the file on
 	// disk. This is useful for when files contain sensitive information, such as
-	// secrets from Vault.
+	// secrets from Vault. Zero keeps the permissions of an existing file, and
+	// creates new files with DefaultTemplateFilePerms.
 	Perms *os.FileMode `mapstructure:"perms"`
 
+	// PreserveXattrs copies the extended attributes of the existing
+	// destination, like its SELinux label, to the rendered file, which would
+	// otherwise get the default attributes of a new file when it replaces the
+	// destination. It only has an effect on Linux. The default value is true.
+	PreserveXattrs *bool `mapstructure:"preserve_xattrs"`
+
+	// RenderTimeout is the maximum amount of time the execution of this
+	// template may take, including the template functions it calls, before
+	// rendering fails. Zero uses the global render_timeout.
+	RenderTimeout *time.Duration `mapstructure:"render_timeout"`
+
+	// Rollout coordinates applying changes to this template with other
+	// instances rendering the same template, so they do not all apply the
+	// change at once.
+	Rollout *RolloutConfig `mapstructure:"rollout"`
+
+	// SELinuxLabel is the SELinux security context, like
+	// "system_u:object_r:httpd_config_t:s0", to apply to the rendered file. It
+	// takes precedence over the preserved label of the existing destination.
+	// It only has an effect on Linux.
+	SELinuxLabel *string `mapstructure:"selinux_label"`
+
+	// SkipFirstCommand determines if the command is skipped when the template
+	// is first rendered after starting, for example when the destination was
+	// stale at boot but the service reads it when it starts anyway. The
+	// default value is false.
+	SkipFirstCommand *bool `mapstructure:"skip_first_command"`
+
 	// Source is the path on disk to the template contents to evaluate. Either
 	// this or Contents should be specified, but not both.
 	Source *string `mapstructure:"source"`
 
+	// VarsFile is the path of a YAML or JSON file holding static values, such
+	// as per-environment settings, which the template reads with the var
+	// function. The file is watched for changes.
+	VarsFile *string `mapstructure:"vars_file"`
+
 	// Wait configures per-template quiescence timers.
 	Wait *WaitConfig `mapstructure:"wait"`
 
+	// WatchDestination watches the destination file and re-renders it as soon
+	// as it is modified or deleted by something other than Consul Template,
+	// which corrects drift from the desired contents.
+	WatchDestination *bool `mapstructure:"watch_destination"`
+
+	// WindowsACL is a security descriptor, in SDDL form, to apply to the
+	// destination file on Windows, where Perms has almost no effect. It is
+	// ignored on other platforms.
+	WindowsACL *string `mapstructure:"windows_acl"`
+
 	// LeftDelim and RightDelim are optional configurations to control what
 	// delimiter is utilized when parsing the template.
 	LeftDelim  *string `mapstructure:"left_delimiter"`
@@ -82,8 +207,14 @@ type TemplateConfig struct {
 // default values.
 func DefaultTemplateConfig() *TemplateConfig {
 	return &TemplateConfig{
-		Exec: DefaultExecConfig(),
-		Wait: DefaultWaitConfig(),
+		CommandSandbox: DefaultCommandSandboxConfig(),
+		Diff:           DefaultDiffConfig(),
+		Encryption:     DefaultEncryptionConfig(),
+		Exec:           DefaultExecConfig(),
+		HoldDown:       DefaultWaitConfig(),
+		HTTP:           DefaultHTTPDestinationConfig(),
+		Rollout:        DefaultRolloutConfig(),
+		Wait:           DefaultWaitConfig(),
 	}
 }
 
@@ -95,28 +226,89 @@ func (c *TemplateConfig) Copy() *TemplateConfig {
 
 	var o TemplateConfig
 
+	if c.After != nil {
+		o.After = make([]string, len(c.After))
+		copy(o.After, c.After)
+	}
+
+	o.Approval = c.Approval
+
 	o.Backup = c.Backup
 
+	o.ChangeReport = c.ChangeReport
+
 	o.Command = c.Command
 
 	o.CommandTimeout = c.CommandTimeout
 
+	if c.CommandSandbox != nil {
+		o.CommandSandbox = c.CommandSandbox.Copy()
+	}
+
 	o.Contents = c.Contents
 
+	o.CreateDestDirs = c.CreateDestDirs
+
+	o.CreateDestDirsPerms = c.CreateDestDirsPerms
+
+	o.DestDirGroup = c.DestDirGroup
+
+	o.DestDirUser = c.DestDirUser
+
 	o.Destination = c.Destination
 
+	if c.Diff != nil {
+		o.Diff = c.Diff.Copy()
+	}
+
+	if c.Encryption != nil {
+		o.Encryption = c.Encryption.Copy()
+	}
+
+	o.Engine = c.Engine
+
 	if c.Exec != nil {
 		o.Exec = c.Exec.Copy()
 	}
 
+	o.Guard = c.Guard
+
+	if c.HoldDown != nil {
+		o.HoldDown = c.HoldDown.Copy()
+	}
+
+	if c.HTTP != nil {
+		o.HTTP = c.HTTP.Copy()
+	}
+
+	o.Name = c.Name
+
 	o.Perms = c.Perms
 
+	o.PreserveXattrs = c.PreserveXattrs
+
+	o.RenderTimeout = c.RenderTimeout
+
+	if c.Rollout != nil {
+		o.Rollout = c.Rollout.Copy()
+	}
+
+	o.SELinuxLabel = c.SELinuxLabel
+
+	o.SkipFirstCommand = c.SkipFirstCommand
+
 	o.Source = c.Source
 
+	o.VarsFile = c.VarsFile
+
 	if c.Wait != nil {
 		o.Wait = c.Wait.Copy()
 	}
 
+	o.WatchDestination = c.WatchDestination
+
+	o.WindowsACL = c.WindowsACL
+
 	o.LeftDelim = c.LeftDelim
 	o.RightDelim = c.RightDelim
 
@@ -141,10 +333,20 @@ func (c *TemplateConfig) Merge(o *TemplateConfig) *TemplateConfig {
 
 	r := c.Copy()
 
+	r.After = append(r.After, o.After...)
+
+	if o.Approval != nil {
+		r.Approval = o.Approval
+	}
+
 	if o.Backup != nil {
 		r.Backup = o.Backup
 	}
 
+	if o.ChangeReport != nil {
+		r.ChangeReport = o.ChangeReport
+	}
+
 	if o.Command != nil {
 		r.Command = o.Command
 	}
@@ -153,30 +355,110 @@ func (c *TemplateConfig) Merge(o *TemplateConfig) *TemplateConfig {
 		r.CommandTimeout = o.CommandTimeout
 	}
 
+	if o.CommandSandbox != nil {
+		r.CommandSandbox = r.CommandSandbox.Merge(o.CommandSandbox)
+	}
+
 	if o.Contents != nil {
 		r.Contents = o.Contents
 	}
 
+	if o.CreateDestDirs != nil {
+		r.CreateDestDirs = o.CreateDestDirs
+	}
+
+	if o.CreateDestDirsPerms != nil {
+		r.CreateDestDirsPerms = o.CreateDestDirsPerms
+	}
+
+	if o.DestDirGroup != nil {
+		r.DestDirGroup = o.DestDirGroup
+	}
+
+	if o.DestDirUser != nil {
+		r.DestDirUser = o.DestDirUser
+	}
+
 	if o.Destination != nil {
 		r.Destination = o.Destination
 	}
 
+	if o.Diff != nil {
+		r.Diff = r.Diff.Merge(o.Diff)
+	}
+
+	if o.Encryption != nil {
+		r.Encryption = r.Encryption.Merge(o.Encryption)
+	}
+
+	if o.Engine != nil {
+		r.Engine = o.Engine
+	}
+
 	if o.Exec != nil {
 		r.Exec = r.Exec.Merge(o.Exec)
 	}
 
+	if o.Guard != nil {
+		r.Guard = o.Guard
+	}
+
+	if o.HoldDown != nil {
+		r.HoldDown = r.HoldDown.Merge(o.HoldDown)
+	}
+
+	if o.HTTP != nil {
+		r.HTTP = r.HTTP.Merge(o.HTTP)
+	}
+
+	if o.Name != nil {
+		r.Name = o.Name
+	}
+
 	if o.Perms != nil {
 		r.Perms = o.Perms
 	}
 
+	if o.PreserveXattrs != nil {
+		r.PreserveXattrs = o.PreserveXattrs
+	}
+
+	if o.RenderTimeout != nil {
+		r.RenderTimeout = o.RenderTimeout
+	}
+
+	if o.Rollout != nil {
+		r.Rollout = r.Rollout.Merge(o.Rollout)
+	}
+
+	if o.SELinuxLabel != nil {
+		r.SELinuxLabel = o.SELinuxLabel
+	}
+
+	if o.SkipFirstCommand != nil {
+		r.SkipFirstCommand = o.SkipFirstCommand
+	}
+
 	if o.Source != nil {
 		r.Source = o.Source
 	}
 
+	if o.VarsFile != nil {
+		r.VarsFile = o.VarsFile
+	}
+
 	if o.Wait != nil {
 		r.Wait = r.Wait.Merge(o.Wait)
 	}
 
+	if o.WatchDestination != nil {
+		r.WatchDestination = o.WatchDestination
+	}
+
+	if o.WindowsACL != nil {
+		r.WindowsACL = o.WindowsACL
+	}
+
 	if o.LeftDelim != nil {
 		r.LeftDelim = o.LeftDelim
 	}
@@ -191,10 +473,22 @@ func (c *TemplateConfig) Merge(o *TemplateConfig) *TemplateConfig {
 // Finalize ensures the configuration has no nil pointers and sets default
 // values.
 func (c *TemplateConfig) Finalize() {
+	if c.After == nil {
+		c.After = []string{}
+	}
+
+	if c.Approval == nil {
+		c.Approval = String(TemplateApprovalAuto)
+	}
+
 	if c.Backup == nil {
 		c.Backup = Bool(false)
 	}
 
+	if c.ChangeReport == nil {
+		c.ChangeReport = Bool(false)
+	}
+
 	if c.Command == nil {
 		c.Command = String("")
 	}
@@ -203,14 +497,49 @@ func (c *TemplateConfig) Finalize() {
 		c.CommandTimeout = TimeDuration(DefaultTemplateCommandTimeout)
 	}
 
+	if c.CommandSandbox == nil {
+		c.CommandSandbox = DefaultCommandSandboxConfig()
+	}
+	c.CommandSandbox.Finalize()
+
 	if c.Contents == nil {
 		c.Contents = String("")
 	}
 
+	if c.CreateDestDirs == nil {
+		c.CreateDestDirs = Bool(true)
+	}
+
+	if c.CreateDestDirsPerms == nil {
+		c.CreateDestDirsPerms = FileMode(DefaultTemplateDirPerms)
+	}
+
+	if c.DestDirGroup == nil {
+		c.DestDirGroup = String("")
+	}
+
+	if c.DestDirUser == nil {
+		c.DestDirUser = String("")
+	}
+
 	if c.Destination == nil {
 		c.Destination = String("")
 	}
 
+	if c.Diff == nil {
+		c.Diff = DefaultDiffConfig()
+	}
+	c.Diff.Finalize()
+
+	if c.Encryption == nil {
+		c.Encryption = DefaultEncryptionConfig()
+	}
+	c.Encryption.Finalize()
+
+	if c.Engine == nil {
+		c.Engine = String("")
+	}
+
 	if c.Exec == nil {
 		c.Exec = DefaultExecConfig()
 	}
@@ -224,19 +553,70 @@ func (c *TemplateConfig) Finalize() {
 	}
 	c.Exec.Finalize()
 
+	if c.Guard == nil {
+		c.Guard = String("")
+	}
+
+	if c.HoldDown == nil {
+		c.HoldDown = DefaultWaitConfig()
+	}
+	c.HoldDown.Finalize()
+
+	if c.HTTP == nil {
+		c.HTTP = DefaultHTTPDestinationConfig()
+	}
+	c.HTTP.Finalize()
+
+	if c.Name == nil {
+		c.Name = String("")
+	}
+
 	if c.Perms == nil {
-		c.Perms = FileMode(DefaultTemplateFilePerms)
+		c.Perms = FileMode(0)
+	}
+
+	if c.PreserveXattrs == nil {
+		c.PreserveXattrs = Bool(true)
+	}
+
+	if c.RenderTimeout == nil {
+		c.RenderTimeout = TimeDuration(0)
+	}
+
+	if c.Rollout == nil {
+		c.Rollout = DefaultRolloutConfig()
+	}
+	c.Rollout.Finalize()
+
+	if c.SELinuxLabel == nil {
+		c.SELinuxLabel = String("")
+	}
+
+	if c.SkipFirstCommand == nil {
+		c.SkipFirstCommand = Bool(false)
 	}
 
 	if c.Source == nil {
 		c.Source = String("")
 	}
 
+	if c.VarsFile == nil {
+		c.VarsFile = String("")
+	}
+
 	if c.Wait == nil {
 		c.Wait = DefaultWaitConfig()
 	}
 	c.Wait.Finalize()
 
+	if c.WatchDestination == nil {
+		c.WatchDestination = Bool(false)
+	}
+
+	if c.WindowsACL == nil {
+		c.WindowsACL = String("")
+	}
+
 	if c.LeftDelim == nil {
 		c.LeftDelim = String("")
 	}
@@ -253,27 +633,73 @@ func (c *TemplateConfig) GoString() string {
 	}
 
 	return fmt.Sprintf("&TemplateConfig{"+
+		"After:%s, "+
+		"Approval:%s, "+
 		"Backup:%s, "+
+		"ChangeReport:%s, "+
 		"Command:%s, "+
 		"CommandTimeout:%s, "+
+		"CommandSandbox:%#v, "+
 		"Contents:%s, "+
+		"CreateDestDirs:%s, "+
+		"CreateDestDirsPerms:%s, "+
+		"DestDirGroup:%s, "+
+		"DestDirUser:%s, "+
 		"Destination:%s, "+
+		"Diff:%#v, "+
+		"Encryption:%#v, "+
+		"Engine:%s, "+
 		"Exec:%#v, "+
+		"Guard:%s, "+
+		"HoldDown:%#v, "+
+		"HTTP:%#v, "+
+		"Name:%s, "+
 		"Perms:%s, "+
+		"PreserveXattrs:%s, "+
+		"RenderTimeout:%s, "+
+		"Rollout:%#v, "+
+		"SELinuxLabel:%s, "+
+		"SkipFirstCommand:%s, "+
 		"Source:%s, "+
+		"VarsFile:%s, "+
 		"Wait:%#v, "+
+		"WatchDestination:%s, "+
+		"WindowsACL:%s, "+
 		"LeftDelim:%s, "+
 		"RightDelim:%s"+
 		"}",
+		c.After,
+		StringGoString(c.Approval),
 		BoolGoString(c.Backup),
+		BoolGoString(c.ChangeReport),
 		StringGoString(c.Command),
 		TimeDurationGoString(c.CommandTimeout),
+		c.CommandSandbox,
 		StringGoString(c.Contents),
+		BoolGoString(c.CreateDestDirs),
+		FileModeGoString(c.CreateDestDirsPerms),
+		StringGoString(c.DestDirGroup),
+		StringGoString(c.DestDirUser),
 		StringGoString(c.Destination),
+		c.Diff,
+		c.Encryption,
+		StringGoString(c.Engine),
 		c.Exec,
+		StringGoString(c.Guard),
+		c.HoldDown,
+		c.HTTP,
+		StringGoString(c.Name),
 		FileModeGoString(c.Perms),
+		BoolGoString(c.PreserveXattrs),
+		TimeDurationGoString(c.RenderTimeout),
+		c.Rollout,
+		StringGoString(c.SELinuxLabel),
+		BoolGoString(c.SkipFirstCommand),
 		StringGoString(c.Source),
+		StringGoString(c.VarsFile),
 		c.Wait,
+		BoolGoString(c.WatchDestination),
+		StringGoString(c.WindowsACL),
 		StringGoString(c.LeftDelim),
 		StringGoString(c.RightDelim),
 	)
@@ -23,17 +23,36 @@ func TestTemplateConfig_Copy(t *testing.T) {
 		{
 			"same_enabled",
 			&TemplateConfig{
-				Backup:         Bool(true),
-				Command:        String("command"),
-				CommandTimeout: TimeDuration(10 * time.Second),
-				Contents:       String("contents"),
-				Destination:    String("destination"),
-				Exec:           &ExecConfig{Command: String("command")},
-				Perms:          FileMode(0600),
-				Source:         String("source"),
-				Wait:           &WaitConfig{Min: TimeDuration(10)},
-				LeftDelim:      String("left_delim"),
-				RightDelim:     String("right_delim"),
+				Approval:            String(TemplateApprovalManual),
+				Backup:              Bool(true),
+				ChangeReport:        Bool(true),
+				Command:             String("command"),
+				CommandTimeout:      TimeDuration(10 * time.Second),
+				CommandSandbox:      &CommandSandboxConfig{Network: Bool(false)},
+				Contents:            String("contents"),
+				CreateDestDirs:      Bool(false),
+				DestDirGroup:        String("group"),
+				CreateDestDirsPerms: FileMode(0700),
+				DestDirUser:         String("user"),
+				Destination:         String("destination"),
+				Diff:                &DiffConfig{Dir: String("/tmp/diffs")},
+				Engine:              String("engine"),
+				Exec:                &ExecConfig{Command: String("command")},
+				Guard:               String(`ge (len (service "web")) 2`),
+				HoldDown:            &WaitConfig{Min: TimeDuration(15)},
+				HTTP:                &HTTPDestinationConfig{Method: String("PUT")},
+				Perms:               FileMode(0600),
+				PreserveXattrs:      Bool(false),
+				Rollout:             &RolloutConfig{MaxParallel: Int(5)},
+				SELinuxLabel:        String("system_u:object_r:httpd_config_t:s0"),
+				SkipFirstCommand:    Bool(true),
+				Source:              String("source"),
+				VarsFile:            String("/etc/ct/values.yaml"),
+				Wait:                &WaitConfig{Min: TimeDuration(10)},
+				WatchDestination:    Bool(true),
+				WindowsACL:          String("D:P(A;;FA;;;SY)"),
+				LeftDelim:           String("left_delim"),
+				RightDelim:          String("right_delim"),
 			},
 		},
 	}
@@ -79,6 +98,30 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{},
 			&TemplateConfig{},
 		},
+		{
+			"approval_overrides",
+			&TemplateConfig{Approval: String("manual")},
+			&TemplateConfig{Approval: String("auto")},
+			&TemplateConfig{Approval: String("auto")},
+		},
+		{
+			"approval_empty_one",
+			&TemplateConfig{Approval: String("manual")},
+			&TemplateConfig{},
+			&TemplateConfig{Approval: String("manual")},
+		},
+		{
+			"approval_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{Approval: String("manual")},
+			&TemplateConfig{Approval: String("manual")},
+		},
+		{
+			"approval_same",
+			&TemplateConfig{Approval: String("manual")},
+			&TemplateConfig{Approval: String("manual")},
+			&TemplateConfig{Approval: String("manual")},
+		},
 		{
 			"backup_overrides",
 			&TemplateConfig{Backup: Bool(true)},
@@ -103,6 +146,30 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Backup: Bool(true)},
 			&TemplateConfig{Backup: Bool(true)},
 		},
+		{
+			"change_report_overrides",
+			&TemplateConfig{ChangeReport: Bool(true)},
+			&TemplateConfig{ChangeReport: Bool(false)},
+			&TemplateConfig{ChangeReport: Bool(false)},
+		},
+		{
+			"change_report_empty_one",
+			&TemplateConfig{ChangeReport: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{ChangeReport: Bool(true)},
+		},
+		{
+			"change_report_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{ChangeReport: Bool(true)},
+			&TemplateConfig{ChangeReport: Bool(true)},
+		},
+		{
+			"change_report_same",
+			&TemplateConfig{ChangeReport: Bool(true)},
+			&TemplateConfig{ChangeReport: Bool(true)},
+			&TemplateConfig{ChangeReport: Bool(true)},
+		},
 		{
 			"command_overrides",
 			&TemplateConfig{Command: String("command")},
@@ -151,6 +218,24 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{CommandTimeout: TimeDuration(10 * time.Second)},
 			&TemplateConfig{CommandTimeout: TimeDuration(10 * time.Second)},
 		},
+		{
+			"command_sandbox_merges",
+			&TemplateConfig{CommandSandbox: &CommandSandboxConfig{Network: Bool(false)}},
+			&TemplateConfig{CommandSandbox: &CommandSandboxConfig{ReadOnlyPaths: []string{"/etc"}}},
+			&TemplateConfig{CommandSandbox: &CommandSandboxConfig{Network: Bool(false), ReadOnlyPaths: []string{"/etc"}}},
+		},
+		{
+			"command_sandbox_empty_one",
+			&TemplateConfig{CommandSandbox: &CommandSandboxConfig{Network: Bool(false)}},
+			&TemplateConfig{},
+			&TemplateConfig{CommandSandbox: &CommandSandboxConfig{Network: Bool(false)}},
+		},
+		{
+			"command_sandbox_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{CommandSandbox: &CommandSandboxConfig{Network: Bool(false)}},
+			&TemplateConfig{CommandSandbox: &CommandSandboxConfig{Network: Bool(false)}},
+		},
 		{
 			"contents_overrides",
 			&TemplateConfig{Contents: String("contents")},
@@ -175,6 +260,102 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Contents: String("contents")},
 			&TemplateConfig{Contents: String("contents")},
 		},
+		{
+			"create_dest_dirs_overrides",
+			&TemplateConfig{CreateDestDirs: Bool(true)},
+			&TemplateConfig{CreateDestDirs: Bool(false)},
+			&TemplateConfig{CreateDestDirs: Bool(false)},
+		},
+		{
+			"create_dest_dirs_empty_one",
+			&TemplateConfig{CreateDestDirs: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{CreateDestDirs: Bool(true)},
+		},
+		{
+			"create_dest_dirs_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{CreateDestDirs: Bool(true)},
+			&TemplateConfig{CreateDestDirs: Bool(true)},
+		},
+		{
+			"create_dest_dirs_same",
+			&TemplateConfig{CreateDestDirs: Bool(true)},
+			&TemplateConfig{CreateDestDirs: Bool(true)},
+			&TemplateConfig{CreateDestDirs: Bool(true)},
+		},
+		{
+			"dest_dir_group_overrides",
+			&TemplateConfig{DestDirGroup: String("group")},
+			&TemplateConfig{DestDirGroup: String("")},
+			&TemplateConfig{DestDirGroup: String("")},
+		},
+		{
+			"dest_dir_group_empty_one",
+			&TemplateConfig{DestDirGroup: String("group")},
+			&TemplateConfig{},
+			&TemplateConfig{DestDirGroup: String("group")},
+		},
+		{
+			"dest_dir_group_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{DestDirGroup: String("group")},
+			&TemplateConfig{DestDirGroup: String("group")},
+		},
+		{
+			"dest_dir_group_same",
+			&TemplateConfig{DestDirGroup: String("group")},
+			&TemplateConfig{DestDirGroup: String("group")},
+			&TemplateConfig{DestDirGroup: String("group")},
+		},
+		{
+			"dest_dir_perms_overrides",
+			&TemplateConfig{CreateDestDirsPerms: FileMode(0700)},
+			&TemplateConfig{CreateDestDirsPerms: FileMode(0000)},
+			&TemplateConfig{CreateDestDirsPerms: FileMode(0000)},
+		},
+		{
+			"dest_dir_perms_empty_one",
+			&TemplateConfig{CreateDestDirsPerms: FileMode(0700)},
+			&TemplateConfig{},
+			&TemplateConfig{CreateDestDirsPerms: FileMode(0700)},
+		},
+		{
+			"dest_dir_perms_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{CreateDestDirsPerms: FileMode(0700)},
+			&TemplateConfig{CreateDestDirsPerms: FileMode(0700)},
+		},
+		{
+			"dest_dir_perms_same",
+			&TemplateConfig{CreateDestDirsPerms: FileMode(0700)},
+			&TemplateConfig{CreateDestDirsPerms: FileMode(0700)},
+			&TemplateConfig{CreateDestDirsPerms: FileMode(0700)},
+		},
+		{
+			"dest_dir_user_overrides",
+			&TemplateConfig{DestDirUser: String("user")},
+			&TemplateConfig{DestDirUser: String("")},
+			&TemplateConfig{DestDirUser: String("")},
+		},
+		{
+			"dest_dir_user_empty_one",
+			&TemplateConfig{DestDirUser: String("user")},
+			&TemplateConfig{},
+			&TemplateConfig{DestDirUser: String("user")},
+		},
+		{
+			"dest_dir_user_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{DestDirUser: String("user")},
+			&TemplateConfig{DestDirUser: String("user")},
+		},
+		{
+			"dest_dir_user_same",
+			&TemplateConfig{DestDirUser: String("user")},
+			&TemplateConfig{DestDirUser: String("user")},
+			&TemplateConfig{DestDirUser: String("user")},
+		},
 		{
 			"destination_overrides",
 			&TemplateConfig{Destination: String("destination")},
@@ -199,6 +380,66 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Destination: String("destination")},
 			&TemplateConfig{Destination: String("destination")},
 		},
+		{
+			"diff_overrides",
+			&TemplateConfig{Diff: &DiffConfig{Dir: String("/tmp/a")}},
+			&TemplateConfig{Diff: &DiffConfig{Dir: String("/tmp/b")}},
+			&TemplateConfig{Diff: &DiffConfig{Dir: String("/tmp/b")}},
+		},
+		{
+			"diff_empty_one",
+			&TemplateConfig{Diff: &DiffConfig{Dir: String("/tmp/a")}},
+			&TemplateConfig{Diff: &DiffConfig{}},
+			&TemplateConfig{Diff: &DiffConfig{Dir: String("/tmp/a")}},
+		},
+		{
+			"diff_empty_two",
+			&TemplateConfig{Diff: &DiffConfig{}},
+			&TemplateConfig{Diff: &DiffConfig{Dir: String("/tmp/a")}},
+			&TemplateConfig{Diff: &DiffConfig{Dir: String("/tmp/a")}},
+		},
+		{
+			"diff_same",
+			&TemplateConfig{Diff: &DiffConfig{Dir: String("/tmp/a")}},
+			&TemplateConfig{Diff: &DiffConfig{Dir: String("/tmp/a")}},
+			&TemplateConfig{Diff: &DiffConfig{Dir: String("/tmp/a")}},
+		},
+		{
+			"encryption_overrides",
+			&TemplateConfig{Encryption: &EncryptionConfig{KeyFile: String("/a.key")}},
+			&TemplateConfig{Encryption: &EncryptionConfig{KeyFile: String("/b.key")}},
+			&TemplateConfig{Encryption: &EncryptionConfig{KeyFile: String("/b.key")}},
+		},
+		{
+			"encryption_empty_one",
+			&TemplateConfig{Encryption: &EncryptionConfig{KeyFile: String("/a.key")}},
+			&TemplateConfig{Encryption: &EncryptionConfig{}},
+			&TemplateConfig{Encryption: &EncryptionConfig{KeyFile: String("/a.key")}},
+		},
+		{
+			"engine_overrides",
+			&TemplateConfig{Engine: String("engine")},
+			&TemplateConfig{Engine: String("")},
+			&TemplateConfig{Engine: String("")},
+		},
+		{
+			"engine_empty_one",
+			&TemplateConfig{Engine: String("engine")},
+			&TemplateConfig{},
+			&TemplateConfig{Engine: String("engine")},
+		},
+		{
+			"engine_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{Engine: String("engine")},
+			&TemplateConfig{Engine: String("engine")},
+		},
+		{
+			"engine_same",
+			&TemplateConfig{Engine: String("engine")},
+			&TemplateConfig{Engine: String("engine")},
+			&TemplateConfig{Engine: String("engine")},
+		},
 		{
 			"exec_overrides",
 			&TemplateConfig{Exec: &ExecConfig{Command: String("command")}},
@@ -223,6 +464,96 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Exec: &ExecConfig{Command: String("command")}},
 			&TemplateConfig{Exec: &ExecConfig{Command: String("command")}},
 		},
+		{
+			"guard_overrides",
+			&TemplateConfig{Guard: String("true")},
+			&TemplateConfig{Guard: String("")},
+			&TemplateConfig{Guard: String("")},
+		},
+		{
+			"guard_empty_one",
+			&TemplateConfig{Guard: String("true")},
+			&TemplateConfig{},
+			&TemplateConfig{Guard: String("true")},
+		},
+		{
+			"guard_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{Guard: String("true")},
+			&TemplateConfig{Guard: String("true")},
+		},
+		{
+			"guard_same",
+			&TemplateConfig{Guard: String("true")},
+			&TemplateConfig{Guard: String("true")},
+			&TemplateConfig{Guard: String("true")},
+		},
+		{
+			"hold_down_overrides",
+			&TemplateConfig{HoldDown: &WaitConfig{Min: TimeDuration(10)}},
+			&TemplateConfig{HoldDown: &WaitConfig{Min: TimeDuration(0)}},
+			&TemplateConfig{HoldDown: &WaitConfig{Min: TimeDuration(0)}},
+		},
+		{
+			"hold_down_empty_one",
+			&TemplateConfig{HoldDown: &WaitConfig{Min: TimeDuration(10)}},
+			&TemplateConfig{},
+			&TemplateConfig{HoldDown: &WaitConfig{Min: TimeDuration(10)}},
+		},
+		{
+			"hold_down_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{HoldDown: &WaitConfig{Min: TimeDuration(10)}},
+			&TemplateConfig{HoldDown: &WaitConfig{Min: TimeDuration(10)}},
+		},
+		{
+			"http_overrides",
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{Method: String("POST")}},
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{Method: String("PUT")}},
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{Method: String("PUT")}},
+		},
+		{
+			"http_empty_one",
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{Method: String("POST")}},
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{}},
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{Method: String("POST")}},
+		},
+		{
+			"http_empty_two",
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{}},
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{Method: String("POST")}},
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{Method: String("POST")}},
+		},
+		{
+			"http_same",
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{Method: String("POST")}},
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{Method: String("POST")}},
+			&TemplateConfig{HTTP: &HTTPDestinationConfig{Method: String("POST")}},
+		},
+		{
+			"rollout_overrides",
+			&TemplateConfig{Rollout: &RolloutConfig{MaxParallel: Int(5)}},
+			&TemplateConfig{Rollout: &RolloutConfig{MaxParallel: Int(1)}},
+			&TemplateConfig{Rollout: &RolloutConfig{MaxParallel: Int(1)}},
+		},
+		{
+			"rollout_empty_one",
+			&TemplateConfig{Rollout: &RolloutConfig{MaxParallel: Int(5)}},
+			&TemplateConfig{Rollout: &RolloutConfig{}},
+			&TemplateConfig{Rollout: &RolloutConfig{MaxParallel: Int(5)}},
+		},
+		{
+			"rollout_empty_two",
+			&TemplateConfig{Rollout: &RolloutConfig{}},
+			&TemplateConfig{Rollout: &RolloutConfig{MaxParallel: Int(5)}},
+			&TemplateConfig{Rollout: &RolloutConfig{MaxParallel: Int(5)}},
+		},
+		{
+			"rollout_same",
+			&TemplateConfig{Rollout: &RolloutConfig{MaxParallel: Int(5)}},
+			&TemplateConfig{Rollout: &RolloutConfig{MaxParallel: Int(5)}},
+			&TemplateConfig{Rollout: &RolloutConfig{MaxParallel: Int(5)}},
+		},
 		{
 			"perms_overrides",
 			&TemplateConfig{Perms: FileMode(0600)},
@@ -247,6 +578,72 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Perms: FileMode(0600)},
 			&TemplateConfig{Perms: FileMode(0600)},
 		},
+		{
+			"after_merges",
+			&TemplateConfig{After: []string{"a"}},
+			&TemplateConfig{After: []string{"b"}},
+			&TemplateConfig{After: []string{"a", "b"}},
+		},
+		{
+			"after_empty_one",
+			&TemplateConfig{After: []string{"a"}},
+			&TemplateConfig{},
+			&TemplateConfig{After: []string{"a"}},
+		},
+		{
+			"name_overrides",
+			&TemplateConfig{Name: String("a")},
+			&TemplateConfig{Name: String("b")},
+			&TemplateConfig{Name: String("b")},
+		},
+		{
+			"name_empty_one",
+			&TemplateConfig{Name: String("a")},
+			&TemplateConfig{},
+			&TemplateConfig{Name: String("a")},
+		},
+		{
+			"render_timeout_overrides",
+			&TemplateConfig{RenderTimeout: TimeDuration(10 * time.Second)},
+			&TemplateConfig{RenderTimeout: TimeDuration(0)},
+			&TemplateConfig{RenderTimeout: TimeDuration(0)},
+		},
+		{
+			"render_timeout_empty_one",
+			&TemplateConfig{RenderTimeout: TimeDuration(10 * time.Second)},
+			&TemplateConfig{},
+			&TemplateConfig{RenderTimeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"render_timeout_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{RenderTimeout: TimeDuration(10 * time.Second)},
+			&TemplateConfig{RenderTimeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"skip_first_command_overrides",
+			&TemplateConfig{SkipFirstCommand: Bool(true)},
+			&TemplateConfig{SkipFirstCommand: Bool(false)},
+			&TemplateConfig{SkipFirstCommand: Bool(false)},
+		},
+		{
+			"skip_first_command_empty_one",
+			&TemplateConfig{SkipFirstCommand: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{SkipFirstCommand: Bool(true)},
+		},
+		{
+			"skip_first_command_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{SkipFirstCommand: Bool(true)},
+			&TemplateConfig{SkipFirstCommand: Bool(true)},
+		},
+		{
+			"skip_first_command_same",
+			&TemplateConfig{SkipFirstCommand: Bool(true)},
+			&TemplateConfig{SkipFirstCommand: Bool(true)},
+			&TemplateConfig{SkipFirstCommand: Bool(true)},
+		},
 		{
 			"source_overrides",
 			&TemplateConfig{Source: String("source")},
@@ -271,6 +668,24 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Source: String("source")},
 			&TemplateConfig{Source: String("source")},
 		},
+		{
+			"vars_file_overrides",
+			&TemplateConfig{VarsFile: String("values.yaml")},
+			&TemplateConfig{VarsFile: String("")},
+			&TemplateConfig{VarsFile: String("")},
+		},
+		{
+			"vars_file_empty_one",
+			&TemplateConfig{VarsFile: String("values.yaml")},
+			&TemplateConfig{},
+			&TemplateConfig{VarsFile: String("values.yaml")},
+		},
+		{
+			"vars_file_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{VarsFile: String("values.yaml")},
+			&TemplateConfig{VarsFile: String("values.yaml")},
+		},
 		{
 			"wait_overrides",
 			&TemplateConfig{Wait: &WaitConfig{Min: TimeDuration(10)}},
@@ -295,6 +710,72 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Wait: &WaitConfig{Min: TimeDuration(10)}},
 			&TemplateConfig{Wait: &WaitConfig{Min: TimeDuration(10)}},
 		},
+		{
+			"preserve_xattrs_overrides",
+			&TemplateConfig{PreserveXattrs: Bool(true)},
+			&TemplateConfig{PreserveXattrs: Bool(false)},
+			&TemplateConfig{PreserveXattrs: Bool(false)},
+		},
+		{
+			"preserve_xattrs_empty_one",
+			&TemplateConfig{PreserveXattrs: Bool(false)},
+			&TemplateConfig{},
+			&TemplateConfig{PreserveXattrs: Bool(false)},
+		},
+		{
+			"selinux_label_overrides",
+			&TemplateConfig{SELinuxLabel: String("system_u:object_r:etc_t:s0")},
+			&TemplateConfig{SELinuxLabel: String("system_u:object_r:httpd_config_t:s0")},
+			&TemplateConfig{SELinuxLabel: String("system_u:object_r:httpd_config_t:s0")},
+		},
+		{
+			"selinux_label_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{SELinuxLabel: String("system_u:object_r:httpd_config_t:s0")},
+			&TemplateConfig{SELinuxLabel: String("system_u:object_r:httpd_config_t:s0")},
+		},
+		{
+			"watch_destination_overrides",
+			&TemplateConfig{WatchDestination: Bool(true)},
+			&TemplateConfig{WatchDestination: Bool(false)},
+			&TemplateConfig{WatchDestination: Bool(false)},
+		},
+		{
+			"watch_destination_empty_one",
+			&TemplateConfig{WatchDestination: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{WatchDestination: Bool(true)},
+		},
+		{
+			"watch_destination_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{WatchDestination: Bool(true)},
+			&TemplateConfig{WatchDestination: Bool(true)},
+		},
+		{
+			"windows_acl_overrides",
+			&TemplateConfig{WindowsACL: String("D:P(A;;FA;;;SY)")},
+			&TemplateConfig{WindowsACL: String("")},
+			&TemplateConfig{WindowsACL: String("")},
+		},
+		{
+			"windows_acl_empty_one",
+			&TemplateConfig{WindowsACL: String("D:P(A;;FA;;;SY)")},
+			&TemplateConfig{},
+			&TemplateConfig{WindowsACL: String("D:P(A;;FA;;;SY)")},
+		},
+		{
+			"windows_acl_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{WindowsACL: String("D:P(A;;FA;;;SY)")},
+			&TemplateConfig{WindowsACL: String("D:P(A;;FA;;;SY)")},
+		},
+		{
+			"windows_acl_same",
+			&TemplateConfig{WindowsACL: String("D:P(A;;FA;;;SY)")},
+			&TemplateConfig{WindowsACL: String("D:P(A;;FA;;;SY)")},
+			&TemplateConfig{WindowsACL: String("D:P(A;;FA;;;SY)")},
+		},
 		{
 			"left_delim_overrides",
 			&TemplateConfig{LeftDelim: String("left_delim")},
@@ -365,11 +846,36 @@ func TestTemplateConfig_Finalize(t *testing.T) {
 			"empty",
 			&TemplateConfig{},
 			&TemplateConfig{
+				After:          []string{},
+				Approval:       String(TemplateApprovalAuto),
 				Backup:         Bool(false),
+				ChangeReport:   Bool(false),
 				Command:        String(""),
 				CommandTimeout: TimeDuration(DefaultTemplateCommandTimeout),
-				Contents:       String(""),
-				Destination:    String(""),
+				CommandSandbox: &CommandSandboxConfig{
+					Enabled:       Bool(false),
+					Network:       Bool(true),
+					NoNewPrivs:    Bool(true),
+					ReadOnlyPaths: []string{},
+				},
+				Contents:            String(""),
+				CreateDestDirs:      Bool(true),
+				DestDirGroup:        String(""),
+				CreateDestDirsPerms: FileMode(DefaultTemplateDirPerms),
+				DestDirUser:         String(""),
+				Destination:         String(""),
+				Diff: &DiffConfig{
+					Dir:     String(""),
+					Enabled: Bool(false),
+					Format:  String(DefaultDiffFormat),
+				},
+				Encryption: &EncryptionConfig{
+					Enabled:           Bool(false),
+					KeyFile:           String(""),
+					VaultTransitKey:   String(""),
+					VaultTransitMount: String(DefaultEncryptionVaultTransitMount),
+				},
+				Engine: String(""),
 				Exec: &ExecConfig{
 					Command: String(""),
 					Enabled: Bool(false),
@@ -383,17 +889,45 @@ func TestTemplateConfig_Finalize(t *testing.T) {
 					KillTimeout:  TimeDuration(DefaultExecKillTimeout),
 					ReloadSignal: Signal(DefaultExecReloadSignal),
 					Splay:        TimeDuration(0 * time.Second),
+					SplayMin:     TimeDuration(0 * time.Second),
+					SplaySeed:    String(""),
 					Timeout:      TimeDuration(DefaultTemplateCommandTimeout),
 				},
-				Perms:  FileMode(DefaultTemplateFilePerms),
-				Source: String(""),
+				Guard: String(""),
+				HoldDown: &WaitConfig{
+					Enabled: Bool(false),
+					Max:     TimeDuration(0 * time.Second),
+					Min:     TimeDuration(0 * time.Second),
+				},
+				HTTP: &HTTPDestinationConfig{
+					Headers:      []string{},
+					Method:       String(DefaultHTTPDestinationMethod),
+					SuccessCodes: []int{},
+					Timeout:      TimeDuration(DefaultHTTPDestinationTimeout),
+				},
+				Name:           String(""),
+				Perms:          FileMode(0),
+				PreserveXattrs: Bool(true),
+				RenderTimeout:  TimeDuration(0),
+				Rollout: &RolloutConfig{
+					Enabled:     Bool(false),
+					MaxParallel: Int(DefaultRolloutMaxParallel),
+					Prefix:      String(DefaultRolloutPrefix),
+					Stagger:     TimeDuration(DefaultRolloutStagger),
+				},
+				SELinuxLabel:     String(""),
+				SkipFirstCommand: Bool(false),
+				Source:           String(""),
+				VarsFile:         String(""),
 				Wait: &WaitConfig{
 					Enabled: Bool(false),
 					Max:     TimeDuration(0 * time.Second),
 					Min:     TimeDuration(0 * time.Second),
 				},
-				LeftDelim:  String(""),
-				RightDelim: String(""),
+				WatchDestination: Bool(false),
+				WindowsACL:       String(""),
+				LeftDelim:        String(""),
+				RightDelim:       String(""),
 			},
 		},
 	}
@@ -16,6 +16,18 @@ const (
 	// be unwrapped.
 	DefaultVaultUnwrapToken = false
 
+	// DefaultVaultBatchReads is the default value for if the reads of secrets
+	// under the same path should be batched.
+	DefaultVaultBatchReads = false
+
+	// DefaultVaultSharedBackoff is the default value for if all requests
+	// should be held back when Vault throttles requests or is unavailable.
+	DefaultVaultSharedBackoff = false
+
+	// DefaultVaultRevokeOnShutdown is the default value for if the leases of
+	// secrets should be revoked when Consul Template stops.
+	DefaultVaultRevokeOnShutdown = false
+
 	// DefaultVaultRetryBase is the default value for the base time to use for
 	// exponential backoff.
 	DefaultVaultRetryBase = 250 * time.Millisecond
@@ -23,6 +35,18 @@ const (
 	// DefaultVaultRetryMaxAttempts is the default maximum number of attempts to
 	// retry before quitting.
 	DefaultVaultRetryMaxAttempts = 5
+
+	// DefaultVaultMaxConcurrentRequests is the default maximum number of
+	// requests to Vault in flight at once.
+	DefaultVaultMaxConcurrentRequests = 16
+
+	// DefaultVaultRenewJitter is the default fraction of the renewal interval
+	// by which token and lease renewals are randomly brought forward.
+	DefaultVaultRenewJitter = 0.1
+
+	// DefaultVaultKVMountCacheTTL is the default amount of time the detected
+	// KV version of a Vault mount is cached.
+	DefaultVaultKVMountCacheTTL = 5 * time.Minute
 )
 
 // VaultConfig is the configuration for connecting to a vault server.
@@ -30,15 +54,62 @@ type VaultConfig struct {
 	// Address is the URI to the Vault server.
 	Address *string `mapstructure:"address"`
 
+	// Addresses are the URIs of other nodes of the same Vault cluster. With
+	// the health check, the active node is preferred over standbys.
+	Addresses []string `mapstructure:"addresses"`
+
+	// BatchReads reads the secrets under the same path together on their
+	// first read: the path is listed once and the listed secrets are read in
+	// parallel, instead of each secret being read on its own.
+	BatchReads *bool `mapstructure:"batch_reads"`
+
 	// Enabled controls whether the Vault integration is active.
 	Enabled *bool `mapstructure:"enabled"`
 
+	// Headers are added to each request to Vault, for proxies and service
+	// meshes which route on them.
+	Headers map[string]string `mapstructure:"headers"`
+
+	// HealthCheck checks the sys/health endpoint of Vault before using it, to
+	// prefer active nodes and to fail with a clear error if Vault is sealed or
+	// not initialized. The check is repeated when requests to Vault fail. It
+	// defaults to true when Addresses are set.
+	HealthCheck *bool `mapstructure:"health_check"`
+
+	// HoldDown is the hold-down timers of Vault dependencies, which hold the
+	// changes of each dependency after one was rendered.
+	HoldDown *WaitConfig `mapstructure:"hold_down"`
+
+	// KVMountCacheTTL is how long the detected KV version of each mount is
+	// cached before it is detected again. Zero detects it on every read.
+	KVMountCacheTTL *time.Duration `mapstructure:"kv_mount_cache_ttl"`
+
+	// MaxConcurrentRequests is the maximum number of requests to Vault in
+	// flight at once, so that reading many secrets does not overwhelm it. Zero
+	// means no limit.
+	MaxConcurrentRequests *int `mapstructure:"max_concurrent_requests"`
+
+	// RenewJitter is the largest fraction of the renewal interval by which
+	// token and lease renewals are randomly brought forward, so that many
+	// processes with tokens issued at the same time do not all renew at once.
+	// It must be at least 0 and less than 1.
+	RenewJitter *float64 `mapstructure:"renew_jitter"`
+
 	// RenewToken renews the Vault token.
 	RenewToken *bool `mapstructure:"renew_token"`
 
 	// Retry is the configuration for specifying how to behave on failure.
 	Retry *RetryConfig `mapstructure:"retry"`
 
+	// RevokeOnShutdown revokes the leases of secrets read by this process when
+	// it stops cleanly.
+	RevokeOnShutdown *bool `mapstructure:"revoke_on_shutdown"`
+
+	// SharedBackoff holds back all requests to Vault when any of them finds
+	// it throttling requests or unavailable, backing off from the retry
+	// backoff until a request succeeds.
+	SharedBackoff *bool `mapstructure:"shared_backoff"`
+
 	// SSL indicates we should use a secure connection while talking to Vault.
 	SSL *SSLConfig `mapstructure:"ssl"`
 
@@ -58,6 +129,7 @@ type VaultConfig struct {
 // default values.
 func DefaultVaultConfig() *VaultConfig {
 	v := &VaultConfig{
+		HoldDown:  DefaultWaitConfig(),
 		Retry:     DefaultRetryConfig(),
 		SSL:       DefaultSSLConfig(),
 		Transport: DefaultTransportConfig(),
@@ -78,14 +150,38 @@ func (c *VaultConfig) Copy() *VaultConfig {
 	var o VaultConfig
 	o.Address = c.Address
 
+	if c.Addresses != nil {
+		o.Addresses = append([]string{}, c.Addresses...)
+	}
+
+	o.BatchReads = c.BatchReads
+
 	o.Enabled = c.Enabled
 
+	o.Headers = copyHeaders(c.Headers)
+
+	o.HealthCheck = c.HealthCheck
+
+	if c.HoldDown != nil {
+		o.HoldDown = c.HoldDown.Copy()
+	}
+
+	o.KVMountCacheTTL = c.KVMountCacheTTL
+
+	o.MaxConcurrentRequests = c.MaxConcurrentRequests
+
+	o.RenewJitter = c.RenewJitter
+
 	o.RenewToken = c.RenewToken
 
 	if c.Retry != nil {
 		o.Retry = c.Retry.Copy()
 	}
 
+	o.RevokeOnShutdown = c.RevokeOnShutdown
+
+	o.SharedBackoff = c.SharedBackoff
+
 	if c.SSL != nil {
 		o.SSL = c.SSL.Copy()
 	}
@@ -123,10 +219,42 @@ func (c *VaultConfig) Merge(o *VaultConfig) *VaultConfig {
 		r.Address = o.Address
 	}
 
+	if o.Addresses != nil {
+		r.Addresses = append(r.Addresses, o.Addresses...)
+	}
+
+	if o.BatchReads != nil {
+		r.BatchReads = o.BatchReads
+	}
+
 	if o.Enabled != nil {
 		r.Enabled = o.Enabled
 	}
 
+	if o.Headers != nil {
+		r.Headers = mergeHeaders(r.Headers, o.Headers)
+	}
+
+	if o.HealthCheck != nil {
+		r.HealthCheck = o.HealthCheck
+	}
+
+	if o.HoldDown != nil {
+		r.HoldDown = r.HoldDown.Merge(o.HoldDown)
+	}
+
+	if o.KVMountCacheTTL != nil {
+		r.KVMountCacheTTL = o.KVMountCacheTTL
+	}
+
+	if o.MaxConcurrentRequests != nil {
+		r.MaxConcurrentRequests = o.MaxConcurrentRequests
+	}
+
+	if o.RenewJitter != nil {
+		r.RenewJitter = o.RenewJitter
+	}
+
 	if o.RenewToken != nil {
 		r.RenewToken = o.RenewToken
 	}
@@ -135,6 +263,14 @@ func (c *VaultConfig) Merge(o *VaultConfig) *VaultConfig {
 		r.Retry = r.Retry.Merge(o.Retry)
 	}
 
+	if o.RevokeOnShutdown != nil {
+		r.RevokeOnShutdown = o.RevokeOnShutdown
+	}
+
+	if o.SharedBackoff != nil {
+		r.SharedBackoff = o.SharedBackoff
+	}
+
 	if o.SSL != nil {
 		r.SSL = r.SSL.Merge(o.SSL)
 	}
@@ -162,6 +298,39 @@ func (c *VaultConfig) Finalize() {
 		}, "")
 	}
 
+	if c.Addresses == nil {
+		c.Addresses = []string{}
+	}
+
+	if c.BatchReads == nil {
+		c.BatchReads = Bool(DefaultVaultBatchReads)
+	}
+
+	if c.Headers == nil {
+		c.Headers = map[string]string{}
+	}
+
+	if c.HealthCheck == nil {
+		c.HealthCheck = Bool(len(c.Addresses) > 0)
+	}
+
+	if c.HoldDown == nil {
+		c.HoldDown = DefaultWaitConfig()
+	}
+	c.HoldDown.Finalize()
+
+	if c.KVMountCacheTTL == nil {
+		c.KVMountCacheTTL = TimeDuration(DefaultVaultKVMountCacheTTL)
+	}
+
+	if c.MaxConcurrentRequests == nil {
+		c.MaxConcurrentRequests = Int(DefaultVaultMaxConcurrentRequests)
+	}
+
+	if c.RenewJitter == nil {
+		c.RenewJitter = Float64(DefaultVaultRenewJitter)
+	}
+
 	if c.RenewToken == nil {
 		c.RenewToken = boolFromEnv([]string{
 			"VAULT_RENEW_TOKEN",
@@ -173,6 +342,14 @@ func (c *VaultConfig) Finalize() {
 	}
 	c.Retry.Finalize()
 
+	if c.RevokeOnShutdown == nil {
+		c.RevokeOnShutdown = Bool(DefaultVaultRevokeOnShutdown)
+	}
+
+	if c.SharedBackoff == nil {
+		c.SharedBackoff = Bool(DefaultVaultSharedBackoff)
+	}
+
 	if c.SSL == nil {
 		c.SSL = DefaultSSLConfig()
 		c.SSL.Enabled = Bool(true)
@@ -211,7 +388,7 @@ func (c *VaultConfig) Finalize() {
 	}
 
 	if c.Enabled == nil {
-		c.Enabled = Bool(StringPresent(c.Address))
+		c.Enabled = Bool(StringPresent(c.Address) || len(c.Addresses) > 0)
 	}
 }
 
@@ -223,18 +400,38 @@ func (c *VaultConfig) GoString() string {
 
 	return fmt.Sprintf("&VaultConfig{"+
 		"Address:%s, "+
+		"Addresses:%v, "+
+		"BatchReads:%s, "+
 		"Enabled:%s, "+
+		"Headers:%s, "+
+		"HealthCheck:%s, "+
+		"HoldDown:%#v, "+
+		"KVMountCacheTTL:%s, "+
+		"MaxConcurrentRequests:%s, "+
+		"RenewJitter:%s, "+
 		"RenewToken:%s, "+
 		"Retry:%#v, "+
+		"RevokeOnShutdown:%s, "+
+		"SharedBackoff:%s, "+
 		"SSL:%#v, "+
 		"Token:%t, "+
 		"Transport:%#v, "+
 		"UnwrapToken:%s"+
 		"}",
 		StringGoString(c.Address),
+		c.Addresses,
+		BoolGoString(c.BatchReads),
 		BoolGoString(c.Enabled),
+		headersGoString(c.Headers),
+		BoolGoString(c.HealthCheck),
+		c.HoldDown,
+		TimeDurationGoString(c.KVMountCacheTTL),
+		IntGoString(c.MaxConcurrentRequests),
+		Float64GoString(c.RenewJitter),
 		BoolGoString(c.RenewToken),
 		c.Retry,
+		BoolGoString(c.RevokeOnShutdown),
+		BoolGoString(c.SharedBackoff),
 		c.SSL,
 		StringPresent(c.Token),
 		c.Transport,
@@ -25,12 +25,24 @@ func TestVaultConfig_Copy(t *testing.T) {
 		{
 			"same_enabled",
 			&VaultConfig{
-				Address:    String("address"),
-				Enabled:    Bool(true),
-				RenewToken: Bool(true),
-				Retry:      &RetryConfig{Enabled: Bool(true)},
-				SSL:        &SSLConfig{Enabled: Bool(true)},
-				Token:      String("token"),
+				Address:     String("address"),
+				Addresses:   []string{"other"},
+				BatchReads:  Bool(true),
+				Enabled:     Bool(true),
+				HealthCheck: Bool(true),
+				HoldDown: &WaitConfig{
+					Enabled: Bool(false),
+					Min:     TimeDuration(0),
+					Max:     TimeDuration(0),
+				},
+				KVMountCacheTTL:  TimeDuration(time.Minute),
+				RenewJitter:      Float64(0.2),
+				RenewToken:       Bool(true),
+				Retry:            &RetryConfig{Enabled: Bool(true)},
+				RevokeOnShutdown: Bool(true),
+				SharedBackoff:    Bool(true),
+				SSL:              &SSLConfig{Enabled: Bool(true)},
+				Token:            String("token"),
 				Transport: &TransportConfig{
 					DialKeepAlive: TimeDuration(20 * time.Second),
 				},
@@ -272,6 +284,132 @@ func TestVaultConfig_Merge(t *testing.T) {
 			&VaultConfig{Transport: &TransportConfig{DialKeepAlive: TimeDuration(10 * time.Second)}},
 			&VaultConfig{Transport: &TransportConfig{DialKeepAlive: TimeDuration(10 * time.Second)}},
 		},
+		{
+			"revoke_on_shutdown_overrides",
+			&VaultConfig{RevokeOnShutdown: Bool(true)},
+			&VaultConfig{RevokeOnShutdown: Bool(false)},
+			&VaultConfig{RevokeOnShutdown: Bool(false)},
+		},
+		{
+			"revoke_on_shutdown_empty_one",
+			&VaultConfig{RevokeOnShutdown: Bool(true)},
+			&VaultConfig{},
+			&VaultConfig{RevokeOnShutdown: Bool(true)},
+		},
+		{
+			"revoke_on_shutdown_empty_two",
+			&VaultConfig{},
+			&VaultConfig{RevokeOnShutdown: Bool(true)},
+			&VaultConfig{RevokeOnShutdown: Bool(true)},
+		},
+		{
+			"revoke_on_shutdown_same",
+			&VaultConfig{RevokeOnShutdown: Bool(true)},
+			&VaultConfig{RevokeOnShutdown: Bool(true)},
+			&VaultConfig{RevokeOnShutdown: Bool(true)},
+		},
+		{
+			"headers_merges",
+			&VaultConfig{Headers: map[string]string{"X-Org": "infra", "X-Env": "dev"}},
+			&VaultConfig{Headers: map[string]string{"X-Env": "prod"}},
+			&VaultConfig{Headers: map[string]string{"X-Org": "infra", "X-Env": "prod"}},
+		},
+		{
+			"headers_empty_one",
+			&VaultConfig{Headers: map[string]string{"X-Org": "infra"}},
+			&VaultConfig{},
+			&VaultConfig{Headers: map[string]string{"X-Org": "infra"}},
+		},
+		{
+			"headers_empty_two",
+			&VaultConfig{},
+			&VaultConfig{Headers: map[string]string{"X-Org": "infra"}},
+			&VaultConfig{Headers: map[string]string{"X-Org": "infra"}},
+		},
+		{
+			"max_concurrent_requests_overrides",
+			&VaultConfig{MaxConcurrentRequests: Int(16)},
+			&VaultConfig{MaxConcurrentRequests: Int(0)},
+			&VaultConfig{MaxConcurrentRequests: Int(0)},
+		},
+		{
+			"max_concurrent_requests_empty_one",
+			&VaultConfig{MaxConcurrentRequests: Int(4)},
+			&VaultConfig{},
+			&VaultConfig{MaxConcurrentRequests: Int(4)},
+		},
+		{
+			"kv_mount_cache_ttl_overrides",
+			&VaultConfig{KVMountCacheTTL: TimeDuration(time.Minute)},
+			&VaultConfig{KVMountCacheTTL: TimeDuration(0)},
+			&VaultConfig{KVMountCacheTTL: TimeDuration(0)},
+		},
+		{
+			"kv_mount_cache_ttl_empty_one",
+			&VaultConfig{KVMountCacheTTL: TimeDuration(time.Minute)},
+			&VaultConfig{},
+			&VaultConfig{KVMountCacheTTL: TimeDuration(time.Minute)},
+		},
+		{
+			"addresses_merges",
+			&VaultConfig{Addresses: []string{"a"}},
+			&VaultConfig{Addresses: []string{"b"}},
+			&VaultConfig{Addresses: []string{"a", "b"}},
+		},
+		{
+			"addresses_empty_one",
+			&VaultConfig{Addresses: []string{"a"}},
+			&VaultConfig{},
+			&VaultConfig{Addresses: []string{"a"}},
+		},
+		{
+			"health_check_overrides",
+			&VaultConfig{HealthCheck: Bool(true)},
+			&VaultConfig{HealthCheck: Bool(false)},
+			&VaultConfig{HealthCheck: Bool(false)},
+		},
+		{
+			"health_check_empty_one",
+			&VaultConfig{HealthCheck: Bool(true)},
+			&VaultConfig{},
+			&VaultConfig{HealthCheck: Bool(true)},
+		},
+		{
+			"renew_jitter_overrides",
+			&VaultConfig{RenewJitter: Float64(0.1)},
+			&VaultConfig{RenewJitter: Float64(0)},
+			&VaultConfig{RenewJitter: Float64(0)},
+		},
+		{
+			"renew_jitter_empty_one",
+			&VaultConfig{RenewJitter: Float64(0.25)},
+			&VaultConfig{},
+			&VaultConfig{RenewJitter: Float64(0.25)},
+		},
+		{
+			"batch_reads_overrides",
+			&VaultConfig{BatchReads: Bool(true)},
+			&VaultConfig{BatchReads: Bool(false)},
+			&VaultConfig{BatchReads: Bool(false)},
+		},
+		{
+			"batch_reads_empty_one",
+			&VaultConfig{BatchReads: Bool(true)},
+			&VaultConfig{},
+			&VaultConfig{BatchReads: Bool(true)},
+		},
+		{
+			"shared_backoff_overrides",
+			&VaultConfig{SharedBackoff: Bool(true)},
+			&VaultConfig{SharedBackoff: Bool(false)},
+			&VaultConfig{SharedBackoff: Bool(false)},
+		},
+		{
+			"shared_backoff_empty_one",
+			&VaultConfig{SharedBackoff: Bool(true)},
+			&VaultConfig{},
+			&VaultConfig{SharedBackoff: Bool(true)},
+		},
 	}
 
 	for i, tc := range cases {
@@ -294,22 +432,39 @@ func TestVaultConfig_Finalize(t *testing.T) {
 			"empty",
 			&VaultConfig{},
 			&VaultConfig{
-				Address:    String(""),
-				Enabled:    Bool(false),
-				RenewToken: Bool(DefaultVaultRenewToken),
+				Address:     String(""),
+				Addresses:   []string{},
+				BatchReads:  Bool(DefaultVaultBatchReads),
+				Enabled:     Bool(false),
+				Headers:     map[string]string{},
+				HealthCheck: Bool(false),
+				HoldDown: &WaitConfig{
+					Enabled: Bool(false),
+					Min:     TimeDuration(0),
+					Max:     TimeDuration(0),
+				},
+				KVMountCacheTTL:       TimeDuration(DefaultVaultKVMountCacheTTL),
+				MaxConcurrentRequests: Int(DefaultVaultMaxConcurrentRequests),
+				RenewJitter:           Float64(DefaultVaultRenewJitter),
+				RenewToken:            Bool(DefaultVaultRenewToken),
 				Retry: &RetryConfig{
-					Backoff:  TimeDuration(DefaultRetryBackoff),
-					Enabled:  Bool(true),
-					Attempts: Int(DefaultRetryAttempts),
+					Backoff:    TimeDuration(DefaultRetryBackoff),
+					Enabled:    Bool(true),
+					Attempts:   Int(DefaultRetryAttempts),
+					MaxBackoff: TimeDuration(DefaultRetryMaxBackoff),
 				},
+				RevokeOnShutdown: Bool(DefaultVaultRevokeOnShutdown),
+				SharedBackoff:    Bool(DefaultVaultSharedBackoff),
 				SSL: &SSLConfig{
-					CaCert:     String(""),
-					CaPath:     String(""),
-					Cert:       String(""),
-					Enabled:    Bool(true),
-					Key:        String(""),
-					ServerName: String(""),
-					Verify:     Bool(true),
+					CaCert:          String(""),
+					CaPath:          String(""),
+					Cert:            String(""),
+					Enabled:         Bool(true),
+					Key:             String(""),
+					ServerName:      String(""),
+					Verify:          Bool(true),
+					TLSCipherSuites: []string{},
+					TLSMinVersion:   String(""),
 				},
 				Token: String(""),
 				Transport: &TransportConfig{
@@ -330,22 +485,92 @@ func TestVaultConfig_Finalize(t *testing.T) {
 				Address: String("address"),
 			},
 			&VaultConfig{
-				Address:    String("address"),
-				Enabled:    Bool(true),
-				RenewToken: Bool(DefaultVaultRenewToken),
+				Address:     String("address"),
+				Addresses:   []string{},
+				BatchReads:  Bool(DefaultVaultBatchReads),
+				Enabled:     Bool(true),
+				Headers:     map[string]string{},
+				HealthCheck: Bool(false),
+				HoldDown: &WaitConfig{
+					Enabled: Bool(false),
+					Min:     TimeDuration(0),
+					Max:     TimeDuration(0),
+				},
+				KVMountCacheTTL:       TimeDuration(DefaultVaultKVMountCacheTTL),
+				MaxConcurrentRequests: Int(DefaultVaultMaxConcurrentRequests),
+				RenewJitter:           Float64(DefaultVaultRenewJitter),
+				RenewToken:            Bool(DefaultVaultRenewToken),
 				Retry: &RetryConfig{
-					Backoff:  TimeDuration(DefaultRetryBackoff),
-					Enabled:  Bool(true),
-					Attempts: Int(DefaultRetryAttempts),
+					Backoff:    TimeDuration(DefaultRetryBackoff),
+					Enabled:    Bool(true),
+					Attempts:   Int(DefaultRetryAttempts),
+					MaxBackoff: TimeDuration(DefaultRetryMaxBackoff),
 				},
+				RevokeOnShutdown: Bool(DefaultVaultRevokeOnShutdown),
+				SharedBackoff:    Bool(DefaultVaultSharedBackoff),
 				SSL: &SSLConfig{
-					CaCert:     String(""),
-					CaPath:     String(""),
-					Cert:       String(""),
+					CaCert:          String(""),
+					CaPath:          String(""),
+					Cert:            String(""),
+					Enabled:         Bool(true),
+					Key:             String(""),
+					ServerName:      String(""),
+					Verify:          Bool(true),
+					TLSCipherSuites: []string{},
+					TLSMinVersion:   String(""),
+				},
+				Token: String(""),
+				Transport: &TransportConfig{
+					DialKeepAlive:       TimeDuration(DefaultDialKeepAlive),
+					DialTimeout:         TimeDuration(DefaultDialTimeout),
+					DisableKeepAlives:   Bool(false),
+					IdleConnTimeout:     TimeDuration(DefaultIdleConnTimeout),
+					MaxIdleConns:        Int(DefaultMaxIdleConns),
+					MaxIdleConnsPerHost: Int(DefaultMaxIdleConnsPerHost),
+					TLSHandshakeTimeout: TimeDuration(DefaultTLSHandshakeTimeout),
+				},
+				UnwrapToken: Bool(DefaultVaultUnwrapToken),
+			},
+		},
+		{
+			"with_addresses",
+			&VaultConfig{
+				Addresses: []string{"other"},
+			},
+			&VaultConfig{
+				Address:     String(""),
+				Addresses:   []string{"other"},
+				BatchReads:  Bool(DefaultVaultBatchReads),
+				Enabled:     Bool(true),
+				Headers:     map[string]string{},
+				HealthCheck: Bool(true),
+				HoldDown: &WaitConfig{
+					Enabled: Bool(false),
+					Min:     TimeDuration(0),
+					Max:     TimeDuration(0),
+				},
+				KVMountCacheTTL:       TimeDuration(DefaultVaultKVMountCacheTTL),
+				MaxConcurrentRequests: Int(DefaultVaultMaxConcurrentRequests),
+				RenewJitter:           Float64(DefaultVaultRenewJitter),
+				RenewToken:            Bool(DefaultVaultRenewToken),
+				Retry: &RetryConfig{
+					Backoff:    TimeDuration(DefaultRetryBackoff),
 					Enabled:    Bool(true),
-					Key:        String(""),
-					ServerName: String(""),
-					Verify:     Bool(true),
+					Attempts:   Int(DefaultRetryAttempts),
+					MaxBackoff: TimeDuration(DefaultRetryMaxBackoff),
+				},
+				RevokeOnShutdown: Bool(DefaultVaultRevokeOnShutdown),
+				SharedBackoff:    Bool(DefaultVaultSharedBackoff),
+				SSL: &SSLConfig{
+					CaCert:          String(""),
+					CaPath:          String(""),
+					Cert:            String(""),
+					Enabled:         Bool(true),
+					Key:             String(""),
+					ServerName:      String(""),
+					Verify:          Bool(true),
+					TLSCipherSuites: []string{},
+					TLSMinVersion:   String(""),
 				},
 				Token: String(""),
 				Transport: &TransportConfig{
@@ -0,0 +1,117 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*ACLTokenSelfQuery)(nil)
+
+	// ACLTokenSelfQuerySleepTime is the amount of time to sleep between
+	// queries, since the endpoint does not support blocking queries.
+	ACLTokenSelfQuerySleepTime = 1 * time.Minute
+)
+
+func init() {
+	gob.Register(&ACLToken{})
+}
+
+// ACLToken is the Consul ACL token Consul Template is using. The secret of
+// the token is deliberately not included.
+type ACLToken struct {
+	AccessorID     string
+	CreateTime     time.Time
+	Description    string
+	ExpirationTime *time.Time
+	Local          bool
+	Policies       []*ACLLink
+	Roles          []*ACLLink
+}
+
+// ACLLink is a reference to an ACL policy or role.
+type ACLLink struct {
+	ID   string
+	Name string
+}
+
+// HasPolicy returns true if the token is linked directly to the policy with
+// the given name. Policies granted through its roles are not included.
+func (t *ACLToken) HasPolicy(name string) bool {
+	for _, p := range t.Policies {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ACLTokenSelfQuery is the dependency to query the ACL token of the Consul
+// client.
+type ACLTokenSelfQuery struct {
+	stopCh chan struct{}
+}
+
+// NewACLTokenSelfQuery creates a new dependency for the ACL token of the
+// Consul client.
+func NewACLTokenSelfQuery() (*ACLTokenSelfQuery, error) {
+	return &ACLTokenSelfQuery{
+		stopCh: make(chan struct{}, 1),
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// ACL token it is using.
+func (d *ACLTokenSelfQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	opts = opts.Merge(&QueryOptions{})
+
+	// The endpoint does not support blocking queries, so poll instead.
+	if opts.WaitIndex != 0 {
+		log.Printf("[TRACE] %s: long polling for %s", d, ACLTokenSelfQuerySleepTime)
+
+		select {
+		case <-d.stopCh:
+			return nil, nil, ErrStopped
+		case <-time.After(ACLTokenSelfQuerySleepTime):
+		}
+	}
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path: "/v1/acl/token/self",
+	})
+
+	var result ACLToken
+	if _, err := clients.Consul().Raw().Query("/v1/acl/token/self", &result, nil); err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	log.Printf("[TRACE] %s: returned token %s", d, result.AccessorID)
+
+	return respWithMetadata(&result)
+}
+
+// CanShare returns if this dependency is shareable. The token differs per
+// process, so it is not.
+func (d *ACLTokenSelfQuery) CanShare() bool {
+	return false
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *ACLTokenSelfQuery) String() string {
+	return "acl.token.self"
+}
+
+// Stop terminates this dependency's fetch.
+func (d *ACLTokenSelfQuery) Stop() {
+	close(d.stopCh)
+}
+
+// Type returns the type of this dependency.
+func (d *ACLTokenSelfQuery) Type() Type {
+	return TypeConsul
+}
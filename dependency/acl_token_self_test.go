@@ -0,0 +1,70 @@
+package dependency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestACLTokenSelfQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	var token string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/acl/token/self" {
+			http.NotFound(w, r)
+			return
+		}
+		token = r.Header.Get("X-Consul-Token")
+		w.Write([]byte(`{
+			"AccessorID": "6a1253d2-1785-24fd-91c2-f8e78c745511",
+			"SecretID": "45a3bd52-07c7-47a4-52fd-0745e0cfe967",
+			"Description": "consul-template",
+			"Policies": [{"ID": "165d4317", "Name": "kv-read"}],
+			"Local": false,
+			"ExpirationTime": "2030-01-01T00:00:00Z",
+			"CreateTime": "2020-01-01T00:00:00Z"
+		}`))
+	}))
+	defer ts.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateConsulClient(&CreateConsulClientInput{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+		Token:   "45a3bd52-07c7-47a4-52fd-0745e0cfe967",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewACLTokenSelfQuery()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, _, err := d.Fetch(clients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok := act.(*ACLToken)
+	assert.Equal(t, "45a3bd52-07c7-47a4-52fd-0745e0cfe967", token)
+	assert.Equal(t, "6a1253d2-1785-24fd-91c2-f8e78c745511", tok.AccessorID)
+	assert.Equal(t, "consul-template", tok.Description)
+	assert.Equal(t, []*ACLLink{{ID: "165d4317", Name: "kv-read"}}, tok.Policies)
+	assert.Equal(t, 2030, tok.ExpirationTime.Year())
+	assert.True(t, tok.HasPolicy("kv-read"))
+	assert.False(t, tok.HasPolicy("kv-write"))
+}
+
+func TestACLTokenSelfQuery_String(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewACLTokenSelfQuery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "acl.token.self", d.String())
+}
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"time"
 
+	"github.com/hashicorp/consul/api"
 	"github.com/pkg/errors"
 )
 
@@ -21,6 +22,9 @@ var (
 // CatalogDatacentersQuery is the dependency to query all datacenters
 type CatalogDatacentersQuery struct {
 	stopCh chan struct{}
+
+	// healthy excludes datacenters which are unreachable or have no leader.
+	healthy bool
 }
 
 // NewCatalogDatacentersQuery creates a new datacenter dependency.
@@ -30,6 +34,16 @@ func NewCatalogDatacentersQuery() (*CatalogDatacentersQuery, error) {
 	}, nil
 }
 
+// NewHealthyCatalogDatacentersQuery creates a new datacenter dependency which
+// probes each datacenter and excludes those which are unreachable or have no
+// leader.
+func NewHealthyCatalogDatacentersQuery() (*CatalogDatacentersQuery, error) {
+	return &CatalogDatacentersQuery{
+		stopCh:  make(chan struct{}, 1),
+		healthy: true,
+	}, nil
+}
+
 // Fetch queries the Consul API defined by the given client and returns a slice
 // of strings representing the datacenters
 func (d *CatalogDatacentersQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
@@ -66,11 +80,34 @@ func (d *CatalogDatacentersQuery) Fetch(clients *ClientSet, opts *QueryOptions)
 
 	log.Printf("[TRACE] %s: returned %d results", d, len(result))
 
+	if d.healthy {
+		result = d.healthyDatacenters(clients, result)
+		log.Printf("[TRACE] %s: returned %d results after probing", d, len(result))
+	}
+
 	sort.Strings(result)
 
 	return respWithMetadata(result)
 }
 
+// healthyDatacenters returns the given datacenters which answer a consistent
+// query, which requires them to be reachable and to have a leader.
+func (d *CatalogDatacentersQuery) healthyDatacenters(clients *ClientSet, dcs []string) []string {
+	healthy := make([]string, 0, len(dcs))
+	for _, dc := range dcs {
+		_, _, err := clients.Consul().Catalog().Services(&api.QueryOptions{
+			Datacenter:        dc,
+			RequireConsistent: true,
+		})
+		if err != nil {
+			log.Printf("[WARN] %s: excluding %s: %s", d, dc, err)
+			continue
+		}
+		healthy = append(healthy, dc)
+	}
+	return healthy
+}
+
 // CanShare returns if this dependency is shareable.
 func (d *CatalogDatacentersQuery) CanShare() bool {
 	return true
@@ -78,6 +115,9 @@ func (d *CatalogDatacentersQuery) CanShare() bool {
 
 // String returns the human-friendly version of this dependency.
 func (d *CatalogDatacentersQuery) String() string {
+	if d.healthy {
+		return "catalog.datacenters(healthy)"
+	}
 	return "catalog.datacenters"
 }
 
@@ -17,19 +17,27 @@ func TestNewCatalogDatacentersQuery(t *testing.T) {
 
 	cases := []struct {
 		name string
+		f    func() (*CatalogDatacentersQuery, error)
 		exp  *CatalogDatacentersQuery
 		err  bool
 	}{
 		{
 			"empty",
+			NewCatalogDatacentersQuery,
 			&CatalogDatacentersQuery{},
 			false,
 		},
+		{
+			"healthy",
+			NewHealthyCatalogDatacentersQuery,
+			&CatalogDatacentersQuery{healthy: true},
+			false,
+		},
 	}
 
 	for i, tc := range cases {
 		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
-			act, err := NewCatalogDatacentersQuery()
+			act, err := tc.f()
 			if (err != nil) != tc.err {
 				t.Fatal(err)
 			}
@@ -51,17 +59,24 @@ func TestCatalogDatacentersQuery_Fetch(t *testing.T) {
 
 	cases := []struct {
 		name string
+		f    func() (*CatalogDatacentersQuery, error)
 		exp  []string
 	}{
 		{
 			"default",
+			NewCatalogDatacentersQuery,
+			[]string{"dc1"},
+		},
+		{
+			"healthy",
+			NewHealthyCatalogDatacentersQuery,
 			[]string{"dc1"},
 		},
 	}
 
 	for i, tc := range cases {
 		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
-			d, err := NewCatalogDatacentersQuery()
+			d, err := tc.f()
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -150,17 +165,24 @@ func TestCatalogDatacentersQuery_String(t *testing.T) {
 
 	cases := []struct {
 		name string
+		f    func() (*CatalogDatacentersQuery, error)
 		exp  string
 	}{
 		{
 			"empty",
+			NewCatalogDatacentersQuery,
 			"catalog.datacenters",
 		},
+		{
+			"healthy",
+			NewHealthyCatalogDatacentersQuery,
+			"catalog.datacenters(healthy)",
+		},
 	}
 
 	for i, tc := range cases {
 		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
-			d, err := NewCatalogDatacentersQuery()
+			d, err := tc.f()
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -20,6 +20,7 @@ var (
 )
 
 func init() {
+	gob.Register(&CatalogNode{})
 	gob.Register([]*CatalogNode{})
 	gob.Register([]*CatalogNodeService{})
 }
@@ -114,8 +115,8 @@ func (d *CatalogNodeQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interf
 	for _, v := range node.Services {
 		services = append(services, &CatalogNodeService{
 			ID:                v.ID,
-			Service:           v.Service,
-			Tags:              ServiceTags(deepCopyAndSortTags(v.Tags)),
+			Service:           intern.String(v.Service),
+			Tags:              intern.Tags(v.Tags),
 			Port:              v.Port,
 			Address:           v.Address,
 			EnableTagOverride: v.EnableTagOverride,
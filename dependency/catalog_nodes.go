@@ -84,10 +84,10 @@ func (d *CatalogNodesQuery) Fetch(clients *ClientSet, opts *QueryOptions) (inter
 	for _, node := range n {
 		nodes = append(nodes, &Node{
 			ID:              node.ID,
-			Node:            node.Node,
-			Address:         node.Address,
-			TaggedAddresses: node.TaggedAddresses,
-			Meta:            node.Meta,
+			Node:            intern.String(node.Node),
+			Address:         intern.String(node.Address),
+			TaggedAddresses: intern.Map(node.TaggedAddresses),
+			Meta:            intern.Map(node.Meta),
 		})
 	}
 	sort.Stable(ByNode(nodes))
@@ -19,7 +19,7 @@ var (
 )
 
 func init() {
-	gob.Register([]*CatalogSnippet{})
+	gob.Register([]*CatalogService{})
 }
 
 // CatalogService is a catalog entry in Consul.
@@ -99,14 +99,14 @@ func (d *CatalogServiceQuery) Fetch(clients *ClientSet, opts *QueryOptions) (int
 	for _, s := range entries {
 		list = append(list, &CatalogService{
 			ID:              s.ID,
-			Node:            s.Node,
-			Address:         s.Address,
-			TaggedAddresses: s.TaggedAddresses,
-			NodeMeta:        s.NodeMeta,
+			Node:            intern.String(s.Node),
+			Address:         intern.String(s.Address),
+			TaggedAddresses: intern.Map(s.TaggedAddresses),
+			NodeMeta:        intern.Map(s.NodeMeta),
 			ServiceID:       s.ServiceID,
-			ServiceName:     s.ServiceName,
-			ServiceAddress:  s.ServiceAddress,
-			ServiceTags:     ServiceTags(deepCopyAndSortTags(s.ServiceTags)),
+			ServiceName:     intern.String(s.ServiceName),
+			ServiceAddress:  intern.String(s.ServiceAddress),
+			ServiceTags:     intern.Tags(s.ServiceTags),
 			ServicePort:     s.ServicePort,
 		})
 	}
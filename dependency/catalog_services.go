@@ -78,8 +78,8 @@ func (d *CatalogServicesQuery) Fetch(clients *ClientSet, opts *QueryOptions) (in
 	var catalogServices []*CatalogSnippet
 	for name, tags := range entries {
 		catalogServices = append(catalogServices, &CatalogSnippet{
-			Name: name,
-			Tags: ServiceTags(deepCopyAndSortTags(tags)),
+			Name: intern.String(name),
+			Tags: intern.Tags(tags),
 		})
 	}
 
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"log"
+	"math/rand"
 	"net"
 	"net/http"
 	"sync"
@@ -12,6 +13,8 @@ import (
 	consulapi "github.com/hashicorp/consul/api"
 	rootcerts "github.com/hashicorp/go-rootcerts"
 	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
 )
 
 // ClientSet is a collection of clients that dependencies use to communicate
@@ -27,12 +30,61 @@ type ClientSet struct {
 type consulClient struct {
 	client     *consulapi.Client
 	httpClient *http.Client
+	transport  *http.Transport
+	retryAfter *retryAfterTransport
 }
 
 // vaultClient is a wrapper around a real Vault API client.
 type vaultClient struct {
 	client     *vaultapi.Client
 	httpClient *http.Client
+	transport  *http.Transport
+	retryAfter *retryAfterTransport
+
+	// renewJitter is the largest fraction by which renewals are brought
+	// forward.
+	renewJitter float64
+
+	// kvMounts caches the detected KV versions of mounts.
+	kvMounts *kvMountCache
+
+	// batcher coalesces the first reads of secrets under the same path, or
+	// is nil if reads are not batched.
+	batcher *vaultBatcher
+}
+
+// headerTransport is an http.RoundTripper which adds headers to each request
+// before passing it to the underlying transport. Headers which are already
+// set on the request by the API client are left alone.
+type headerTransport struct {
+	headers map[string]string
+	base    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request, so add the headers to a copy.
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+len(t.headers))
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	for k, v := range t.headers {
+		if r.Header.Get(k) == "" {
+			r.Header.Set(k, v)
+		}
+	}
+	return t.base.RoundTrip(r)
+}
+
+// withHeaders returns the transport wrapped to add the given headers, or the
+// transport itself if there are none.
+func withHeaders(transport *http.Transport, headers map[string]string) http.RoundTripper {
+	if len(headers) == 0 {
+		return transport
+	}
+	return &headerTransport{headers: headers, base: transport}
 }
 
 // CreateConsulClientInput is used as input to the CreateConsulClient function.
@@ -50,6 +102,30 @@ type CreateConsulClientInput struct {
 	SSLCAPath    string
 	ServerName   string
 
+	// AgentToken and ReplicationToken are the tokens of requests to the agent
+	// and ACL endpoints, or empty to use Token. FallbackTokens are tried in
+	// order when a request is denied.
+	AgentToken       string
+	ReplicationToken string
+	FallbackTokens   []string
+
+	// TLSMinVersion and TLSCipherSuites constrain the TLS parameters by name,
+	// for example "tls12". Empty values leave the Go defaults.
+	TLSMinVersion   string
+	TLSCipherSuites []string
+
+	// Headers are added to each request, for proxies which route on them.
+	Headers map[string]string
+
+	// TokenSource supplies bearer tokens which are sent in the Authorization
+	// header of each request, for Consul behind an OAuth2 or OIDC protected
+	// gateway. It cannot be combined with basic authentication.
+	TokenSource oauth2.TokenSource
+
+	// MaxRetryAfter is the longest delay honored from the Retry-After headers
+	// of throttled responses, or 0 for no maximum.
+	MaxRetryAfter time.Duration
+
 	TransportDialKeepAlive       time.Duration
 	TransportDialTimeout         time.Duration
 	TransportDisableKeepAlives   bool
@@ -72,6 +148,44 @@ type CreateVaultClientInput struct {
 	SSLCAPath   string
 	ServerName  string
 
+	// TLSMinVersion and TLSCipherSuites constrain the TLS parameters by name,
+	// for example "tls12". Empty values leave the Go defaults.
+	TLSMinVersion   string
+	TLSCipherSuites []string
+
+	// Headers are added to each request, for proxies which route on them.
+	Headers map[string]string
+
+	// Addresses are the other nodes of the Vault cluster. With HealthCheck,
+	// the sys/health endpoint of each node is checked before it is used, the
+	// active node is preferred, and sealed or uninitialized clusters are an
+	// error. The nodes are checked again when requests fail.
+	Addresses   []string
+	HealthCheck bool
+
+	// MaxConcurrentRequests bounds the number of requests in flight, or is 0
+	// for no bound. SharedBackoff is the base of the backoff shared by all
+	// requests when Vault throttles requests or is unavailable, or is 0 for no
+	// shared backoff.
+	MaxConcurrentRequests int
+	SharedBackoff         time.Duration
+
+	// MaxRetryAfter is the longest delay honored from the Retry-After and rate
+	// limit headers of throttled responses, or 0 for no maximum.
+	MaxRetryAfter time.Duration
+
+	// BatchReads coalesces the first reads of the secrets under the same path
+	// into a list of the path and parallel reads of the listed secrets.
+	BatchReads bool
+
+	// RenewJitter is the largest fraction of the interval by which token and
+	// lease renewals are randomly brought forward, at least 0 and less than 1.
+	RenewJitter float64
+
+	// KVMountCacheTTL is how long the detected KV version of a mount is
+	// cached, or 0 to detect it on every read.
+	KVMountCacheTTL time.Duration
+
 	TransportDialKeepAlive       time.Duration
 	TransportDialTimeout         time.Duration
 	TransportDisableKeepAlives   bool
@@ -98,6 +212,11 @@ func (c *ClientSet) CreateConsulClient(i *CreateConsulClientInput) error {
 		consulConfig.Token = i.Token
 	}
 
+	if i.AuthEnabled && i.TokenSource != nil {
+		return fmt.Errorf("client set: consul: basic auth cannot be combined " +
+			"with oauth2")
+	}
+
 	if i.AuthEnabled {
 		consulConfig.HttpAuth = &consulapi.HttpBasicAuth{
 			Username: i.AuthUsername,
@@ -164,12 +283,37 @@ func (c *ClientSet) CreateConsulClient(i *CreateConsulClientInput) error {
 			tlsConfig.InsecureSkipVerify = true
 		}
 
+		// TLS parameters
+		if err := configureTLSParams(&tlsConfig, i.TLSMinVersion, i.TLSCipherSuites); err != nil {
+			return fmt.Errorf("client set: consul: %s", err)
+		}
+
 		// Save the TLS config on our transport
 		transport.TLSClientConfig = &tlsConfig
 	}
 
 	// Setup the new transport
-	consulConfig.HttpClient.Transport = transport
+	consulConfig.HttpClient.Transport = withHeaders(transport, i.Headers)
+	if i.TokenSource != nil {
+		consulConfig.HttpClient.Transport = &oauth2.Transport{
+			Source: i.TokenSource,
+			Base:   consulConfig.HttpClient.Transport,
+		}
+	}
+	if i.AgentToken != "" || i.ReplicationToken != "" || len(i.FallbackTokens) > 0 {
+		consulConfig.HttpClient.Transport = &tokenTransport{
+			base:        consulConfig.HttpClient.Transport,
+			token:       i.Token,
+			agent:       i.AgentToken,
+			replication: i.ReplicationToken,
+			fallbacks:   i.FallbackTokens,
+		}
+	}
+	retryAfter := &retryAfterTransport{
+		base: consulConfig.HttpClient.Transport,
+		max:  i.MaxRetryAfter,
+	}
+	consulConfig.HttpClient.Transport = retryAfter
 
 	// Create the API client
 	client, err := consulapi.NewClient(consulConfig)
@@ -182,6 +326,8 @@ func (c *ClientSet) CreateConsulClient(i *CreateConsulClientInput) error {
 	c.consul = &consulClient{
 		client:     client,
 		httpClient: consulConfig.HttpClient,
+		transport:  transport,
+		retryAfter: retryAfter,
 	}
 	c.Unlock()
 
@@ -189,10 +335,17 @@ func (c *ClientSet) CreateConsulClient(i *CreateConsulClientInput) error {
 }
 
 func (c *ClientSet) CreateVaultClient(i *CreateVaultClientInput) error {
+	if i.RenewJitter < 0 || i.RenewJitter >= 1 {
+		return fmt.Errorf("client set: vault renew jitter must be at least 0 "+
+			"and less than 1, got %g", i.RenewJitter)
+	}
+
 	vaultConfig := vaultapi.DefaultConfig()
 
 	if i.Address != "" {
 		vaultConfig.Address = i.Address
+	} else if len(i.Addresses) > 0 {
+		vaultConfig.Address = i.Addresses[0]
 	}
 
 	// This transport will attempt to keep connections open to the Vault server.
@@ -252,12 +405,36 @@ func (c *ClientSet) CreateVaultClient(i *CreateVaultClientInput) error {
 			tlsConfig.InsecureSkipVerify = true
 		}
 
+		// TLS parameters
+		if err := configureTLSParams(&tlsConfig, i.TLSMinVersion, i.TLSCipherSuites); err != nil {
+			return fmt.Errorf("client set: vault: %s", err)
+		}
+
 		// Save the TLS config on our transport
 		transport.TLSClientConfig = &tlsConfig
 	}
 
 	// Setup the new transport
-	vaultConfig.HttpClient.Transport = transport
+	var base http.RoundTripper = withHeaders(transport, i.Headers)
+	if i.HealthCheck {
+		health, err := newVaultHealthTransport(base, base,
+			append([]string{vaultConfig.Address}, i.Addresses...))
+		if err != nil {
+			return fmt.Errorf("client set: vault: %s", err)
+		}
+		if err := health.check(); err != nil {
+			// Unreachable nodes may come back, and are checked again when
+			// requests fail, but sealed or uninitialized ones need an operator.
+			if cause := errors.Cause(err); cause == ErrVaultSealed || cause == ErrVaultUninitialized {
+				return fmt.Errorf("client set: vault: %s", err)
+			}
+			log.Printf("[WARN] (clients) vault: %s", err)
+		}
+		base = health
+	}
+	retryAfter := &retryAfterTransport{base: base, max: i.MaxRetryAfter}
+	vaultConfig.HttpClient.Transport = newLimitTransport(retryAfter,
+		i.MaxConcurrentRequests, i.SharedBackoff)
 
 	// Create the client
 	client, err := vaultapi.NewClient(vaultConfig)
@@ -295,14 +472,38 @@ func (c *ClientSet) CreateVaultClient(i *CreateVaultClientInput) error {
 	// Save the data on ourselves
 	c.Lock()
 	c.vault = &vaultClient{
-		client:     client,
-		httpClient: vaultConfig.HttpClient,
+		client:      client,
+		httpClient:  vaultConfig.HttpClient,
+		transport:   transport,
+		retryAfter:  retryAfter,
+		renewJitter: i.RenewJitter,
+		kvMounts:    newKVMountCache(i.KVMountCacheTTL),
+	}
+	if i.BatchReads {
+		c.vault.batcher = newVaultBatcher(i.MaxConcurrentRequests)
 	}
 	c.Unlock()
 
 	return nil
 }
 
+// SetConsulClient sets a Consul API client which was built elsewhere, like one
+// which is instrumented or talks to a mock server, instead of creating one.
+// Retry-After headers are only honored if its transport handles them.
+func (c *ClientSet) SetConsulClient(client *consulapi.Client) {
+	c.Lock()
+	c.consul = &consulClient{client: client}
+	c.Unlock()
+}
+
+// SetVaultClient sets a Vault API client which was built elsewhere, like one
+// which is instrumented or talks to a mock server, instead of creating one.
+func (c *ClientSet) SetVaultClient(client *vaultapi.Client) {
+	c.Lock()
+	c.vault = &vaultClient{client: client}
+	c.Unlock()
+}
+
 // Consul returns the Consul client for this set.
 func (c *ClientSet) Consul() *consulapi.Client {
 	c.RLock()
@@ -317,16 +518,53 @@ func (c *ClientSet) Vault() *vaultapi.Client {
 	return c.vault.client
 }
 
+// RetryAfter returns how much longer the server of the given type asked
+// clients to back off in the Retry-After or rate limit headers of a throttled
+// response, or 0 if it is not throttling requests.
+func (c *ClientSet) RetryAfter(t Type) time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	switch t {
+	case TypeConsul:
+		if c.consul != nil && c.consul.retryAfter != nil {
+			return c.consul.retryAfter.wait()
+		}
+	case TypeVault:
+		if c.vault != nil && c.vault.retryAfter != nil {
+			return c.vault.retryAfter.wait()
+		}
+	}
+	return 0
+}
+
+// vaultRenewWait returns how long to wait before renewing a Vault token or
+// lease, which is the given interval brought forward by a random fraction of
+// up to the renew jitter. Renewals are only ever made earlier, never later
+// than the interval.
+func (c *ClientSet) vaultRenewWait(d time.Duration) time.Duration {
+	if c == nil {
+		return d
+	}
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.vault == nil || c.vault.renewJitter <= 0 || d <= 0 {
+		return d
+	}
+	return d - time.Duration(rand.Float64()*c.vault.renewJitter*float64(d))
+}
+
 // Stop closes all idle connections for any attached clients.
 func (c *ClientSet) Stop() {
 	c.Lock()
 	defer c.Unlock()
 
-	if c.consul != nil {
-		c.consul.httpClient.Transport.(*http.Transport).CloseIdleConnections()
+	if c.consul != nil && c.consul.transport != nil {
+		c.consul.transport.CloseIdleConnections()
 	}
 
-	if c.vault != nil {
-		c.vault.httpClient.Transport.(*http.Transport).CloseIdleConnections()
+	if c.vault != nil && c.vault.transport != nil {
+		c.vault.transport.CloseIdleConnections()
 	}
 }
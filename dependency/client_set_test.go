@@ -1,8 +1,13 @@
 package dependency
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/vault/api"
 )
 
@@ -53,3 +58,144 @@ func TestClientSet_unwrapVaultToken(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestHeaderTransport(t *testing.T) {
+	t.Parallel()
+
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: withHeaders(&http.Transport{}, map[string]string{
+			"X-Org":           "infra",
+			"X-Consul-Token":  "ignored",
+			"x-lowercase-key": "value",
+		}),
+	}
+
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Consul-Token", "token")
+
+	if _, err := client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := header.Get("X-Org"); v != "infra" {
+		t.Errorf("expected X-Org to be %q, got %q", "infra", v)
+	}
+	if v := header.Get("X-Lowercase-Key"); v != "value" {
+		t.Errorf("expected X-Lowercase-Key to be %q, got %q", "value", v)
+	}
+	if v := header.Get("X-Consul-Token"); v != "token" {
+		t.Errorf("expected X-Consul-Token to be %q, got %q", "token", v)
+	}
+	if _, ok := req.Header["X-Org"]; ok {
+		t.Errorf("expected the original request to be unchanged")
+	}
+}
+
+func TestClientSet_vaultRenewWait(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		jitter float64
+		d      time.Duration
+		min    time.Duration
+		err    bool
+	}{
+		{
+			"no_jitter",
+			0,
+			time.Minute,
+			time.Minute,
+			false,
+		},
+		{
+			"jitter",
+			0.1,
+			time.Minute,
+			54 * time.Second,
+			false,
+		},
+		{
+			"large_jitter",
+			0.9,
+			time.Minute,
+			6 * time.Second,
+			false,
+		},
+		{
+			"negative_jitter",
+			-0.1,
+			time.Minute,
+			0,
+			true,
+		},
+		{
+			"whole_jitter",
+			1,
+			time.Minute,
+			0,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			clients := NewClientSet()
+			err := clients.CreateVaultClient(&CreateVaultClientInput{
+				RenewJitter: tc.jitter,
+			})
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if tc.err {
+				return
+			}
+
+			for j := 0; j < 100; j++ {
+				act := clients.vaultRenewWait(tc.d)
+				if act < tc.min || act > tc.d {
+					t.Fatalf("expected between %s and %s, got %s", tc.min, tc.d, act)
+				}
+			}
+		})
+	}
+}
+
+func TestClientSet_setClients(t *testing.T) {
+	t.Parallel()
+
+	consul, err := consulapi.NewClient(consulapi.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	vault, err := api.NewClient(api.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clients := NewClientSet()
+	clients.SetConsulClient(consul)
+	clients.SetVaultClient(vault)
+
+	if clients.Consul() != consul {
+		t.Errorf("expected the given consul client")
+	}
+	if clients.Vault() != vault {
+		t.Errorf("expected the given vault client")
+	}
+	if d := clients.RetryAfter(TypeConsul); d != 0 {
+		t.Errorf("expected no retry after, got %s", d)
+	}
+
+	// The clients have no transports of their own to close.
+	clients.Stop()
+}
@@ -0,0 +1,188 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ConfigEntryServiceDefaults is the kind of the config entry with the
+	// defaults of a service, like its protocol.
+	ConfigEntryServiceDefaults = "service-defaults"
+
+	// ConfigEntryServiceRouter is the kind of the config entry with the L7
+	// routes of a service.
+	ConfigEntryServiceRouter = "service-router"
+
+	// ConfigEntryProxyDefaults is the kind of the config entry with the
+	// defaults of all proxies. Its only entry is named "global".
+	ConfigEntryProxyDefaults = "proxy-defaults"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*ConfigEntryQuery)(nil)
+
+	// ConfigEntryQueryRe is the regular expression to use.
+	ConfigEntryQueryRe = regexp.MustCompile(`\A` + nameRe + dcRe + `\z`)
+
+	// ConfigEntryQuerySleepTime is the amount of time to sleep between queries
+	// while the config entry does not exist, since Consul does not block on
+	// missing entries.
+	ConfigEntryQuerySleepTime = 1 * time.Minute
+)
+
+func init() {
+	gob.Register(&ConfigEntry{})
+}
+
+// ConfigEntry is a Consul central configuration entry. Only the fields of the
+// supported kinds are decoded, and the fields of other kinds are empty.
+type ConfigEntry struct {
+	Kind        string
+	Name        string
+	Protocol    string
+	MeshGateway MeshGatewayConfig
+	Config      map[string]interface{}
+	Routes      []map[string]interface{}
+	Meta        map[string]string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// MeshGatewayConfig is the mesh gateway configuration of a config entry.
+type MeshGatewayConfig struct {
+	Mode string
+}
+
+// ConfigEntryKinds returns the kinds of config entries which can be queried.
+func ConfigEntryKinds() []string {
+	return []string{
+		ConfigEntryProxyDefaults,
+		ConfigEntryServiceDefaults,
+		ConfigEntryServiceRouter,
+	}
+}
+
+// ConfigEntryQuery is the dependency to query a Consul central configuration
+// entry.
+type ConfigEntryQuery struct {
+	stopCh chan struct{}
+
+	dc   string
+	kind string
+	name string
+}
+
+// NewConfigEntryQuery parses the given string into a dependency on the config
+// entry of the given kind, like "service-defaults".
+func NewConfigEntryQuery(kind, s string) (*ConfigEntryQuery, error) {
+	var ok bool
+	for _, k := range ConfigEntryKinds() {
+		if k == kind {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("config.entry: invalid kind: %q (expected one of %s)",
+			kind, strings.Join(ConfigEntryKinds(), ", "))
+	}
+
+	if !ConfigEntryQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("config.entry: invalid format: %q", s)
+	}
+
+	m := regexpMatch(ConfigEntryQueryRe, s)
+	return &ConfigEntryQuery{
+		dc:     m["dc"],
+		kind:   kind,
+		name:   m["name"],
+		stopCh: make(chan struct{}, 1),
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// config entry, or nil if it does not exist.
+func (d *ConfigEntryQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{
+		Datacenter: d.dc,
+	})
+
+	endpoint := "/v1/config/" + d.kind + "/" + d.name
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     endpoint,
+		RawQuery: opts.String(),
+	})
+
+	var entry ConfigEntry
+	qm, err := clients.Consul().Raw().Query(endpoint, &entry, opts.ToConsulOpts())
+	if err != nil {
+		if !strings.Contains(err.Error(), "404") {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+
+		// Consul returns missing entries right away, so poll until it exists.
+		if opts.WaitIndex != 0 {
+			log.Printf("[TRACE] %s: long polling for %s", d, ConfigEntryQuerySleepTime)
+
+			select {
+			case <-d.stopCh:
+				return nil, nil, ErrStopped
+			case <-time.After(ConfigEntryQuerySleepTime):
+			}
+		}
+
+		log.Printf("[TRACE] %s: returned nil", d)
+		return nil, &ResponseMetadata{
+			LastIndex: opts.WaitIndex,
+		}, nil
+	}
+
+	log.Printf("[TRACE] %s: returned response", d)
+
+	rm := &ResponseMetadata{
+		LastIndex:   qm.LastIndex,
+		LastContact: qm.LastContact,
+	}
+
+	return &entry, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *ConfigEntryQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *ConfigEntryQuery) String() string {
+	name := d.kind + "/" + d.name
+	if d.dc != "" {
+		name = name + "@" + d.dc
+	}
+	return fmt.Sprintf("config.entry(%s)", name)
+}
+
+// Stop halts the dependency's fetch function.
+func (d *ConfigEntryQuery) Stop() {
+	close(d.stopCh)
+}
+
+// Type returns the type of this dependency.
+func (d *ConfigEntryQuery) Type() Type {
+	return TypeConsul
+}
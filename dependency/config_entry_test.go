@@ -0,0 +1,196 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConfigEntryQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		kind string
+		i    string
+		exp  *ConfigEntryQuery
+		err  bool
+	}{
+		{
+			"empty",
+			ConfigEntryServiceDefaults,
+			"",
+			nil,
+			true,
+		},
+		{
+			"invalid_kind",
+			"ingress-gateway",
+			"web",
+			nil,
+			true,
+		},
+		{
+			"name",
+			ConfigEntryServiceDefaults,
+			"web",
+			&ConfigEntryQuery{
+				kind: "service-defaults",
+				name: "web",
+			},
+			false,
+		},
+		{
+			"name_dc",
+			ConfigEntryProxyDefaults,
+			"global@dc1",
+			&ConfigEntryQuery{
+				dc:   "dc1",
+				kind: "proxy-defaults",
+				name: "global",
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewConfigEntryQuery(tc.kind, tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestConfigEntryQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/config/service-defaults/web":
+			w.Header().Set("X-Consul-Index", "12")
+			w.Write([]byte(`{
+				"Kind": "service-defaults",
+				"Name": "web",
+				"Protocol": "http",
+				"MeshGateway": {"Mode": "local"},
+				"CreateIndex": 10,
+				"ModifyIndex": 12
+			}`))
+		case "/v1/config/service-router/web":
+			w.Header().Set("X-Consul-Index", "14")
+			w.Write([]byte(`{
+				"Kind": "service-router",
+				"Name": "web",
+				"Routes": [{
+					"Match": {"HTTP": {"PathPrefix": "/admin"}},
+					"Destination": {"Service": "admin"}
+				}]
+			}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateConsulClient(&CreateConsulClientInput{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("service_defaults", func(t *testing.T) {
+		d, err := NewConfigEntryQuery(ConfigEntryServiceDefaults, "web")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act, rm, err := d.Fetch(clients, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entry := act.(*ConfigEntry)
+		assert.Equal(t, uint64(12), rm.LastIndex)
+		assert.Equal(t, "http", entry.Protocol)
+		assert.Equal(t, "local", entry.MeshGateway.Mode)
+	})
+
+	t.Run("service_router", func(t *testing.T) {
+		d, err := NewConfigEntryQuery(ConfigEntryServiceRouter, "web")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act, _, err := d.Fetch(clients, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entry := act.(*ConfigEntry)
+		if assert.Len(t, entry.Routes, 1) {
+			assert.Equal(t, map[string]interface{}{"Service": "admin"}, entry.Routes[0]["Destination"])
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		d, err := NewConfigEntryQuery(ConfigEntryServiceDefaults, "db")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act, rm, err := d.Fetch(clients, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Nil(t, act)
+		assert.Equal(t, uint64(0), rm.LastIndex)
+	})
+}
+
+func TestConfigEntryQuery_String(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		kind string
+		i    string
+		exp  string
+	}{
+		{
+			"name",
+			ConfigEntryServiceDefaults,
+			"web",
+			"config.entry(service-defaults/web)",
+		},
+		{
+			"name_dc",
+			ConfigEntryServiceRouter,
+			"web@dc1",
+			"config.entry(service-router/web@dc1)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewConfigEntryQuery(tc.kind, tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*ConnectCAQuery)(nil)
+)
+
+func init() {
+	gob.Register([]*CARoot{})
+}
+
+// CARoot is a Connect CA root certificate.
+type CARoot struct {
+	ID                string
+	Name              string
+	SerialNumber      uint64
+	SigningKeyID      string
+	NotBefore         time.Time
+	NotAfter          time.Time
+	RootCert          string
+	IntermediateCerts []string
+	Active            bool
+	CreateIndex       uint64
+	ModifyIndex       uint64
+}
+
+// caRootList is the response of the CA roots endpoint.
+type caRootList struct {
+	ActiveRootID string
+	TrustDomain  string
+	Roots        []*CARoot
+}
+
+// ConnectCAQuery is the dependency to query the Connect CA root certificates
+// from the local agent.
+type ConnectCAQuery struct {
+	stopCh chan struct{}
+}
+
+// NewConnectCAQuery creates a new dependency on the Connect CA roots.
+func NewConnectCAQuery() (*ConnectCAQuery, error) {
+	return &ConnectCAQuery{
+		stopCh: make(chan struct{}, 1),
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// CA roots, with the active root first.
+func (d *ConnectCAQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{})
+
+	endpoint := "/v1/agent/connect/ca/roots"
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     endpoint,
+		RawQuery: opts.String(),
+	})
+
+	var list caRootList
+	qm, err := clients.Consul().Raw().Query(endpoint, &list, opts.ToConsulOpts())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	log.Printf("[TRACE] %s: returned %d results", d, len(list.Roots))
+
+	roots := make([]*CARoot, 0, len(list.Roots))
+	for _, r := range list.Roots {
+		if r.Active {
+			roots = append([]*CARoot{r}, roots...)
+		} else {
+			roots = append(roots, r)
+		}
+	}
+
+	rm := &ResponseMetadata{
+		LastIndex:   qm.LastIndex,
+		LastContact: qm.LastContact,
+	}
+
+	return roots, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *ConnectCAQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *ConnectCAQuery) String() string {
+	return "connect.caroots"
+}
+
+// Stop halts the dependency's fetch function.
+func (d *ConnectCAQuery) Stop() {
+	close(d.stopCh)
+}
+
+// Type returns the type of this dependency.
+func (d *ConnectCAQuery) Type() Type {
+	return TypeConsul
+}
@@ -0,0 +1,75 @@
+package dependency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectCAQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/agent/connect/ca/roots" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("X-Consul-Index", "8")
+		w.Write([]byte(`{
+			"ActiveRootID": "b",
+			"TrustDomain": "11111111.consul",
+			"Roots": [
+				{"ID": "a", "Name": "Old Root", "RootCert": "old", "Active": false},
+				{
+					"ID": "b",
+					"Name": "Consul CA Root Cert",
+					"SerialNumber": 7,
+					"RootCert": "new",
+					"IntermediateCerts": ["intermediate"],
+					"Active": true
+				}
+			]
+		}`))
+	}))
+	defer ts.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateConsulClient(&CreateConsulClientInput{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewConnectCAQuery()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, rm, err := d.Fetch(clients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := act.([]*CARoot)
+	assert.Equal(t, uint64(8), rm.LastIndex)
+	if assert.Len(t, roots, 2) {
+		assert.Equal(t, "b", roots[0].ID)
+		assert.Equal(t, uint64(7), roots[0].SerialNumber)
+		assert.Equal(t, []string{"intermediate"}, roots[0].IntermediateCerts)
+		assert.Equal(t, "a", roots[1].ID)
+	}
+}
+
+func TestConnectCAQuery_String(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewConnectCAQuery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "connect.caroots", d.String())
+}
@@ -0,0 +1,148 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*ConnectLeafQuery)(nil)
+
+	// ConnectLeafQueryRe is the regular expression to use.
+	ConnectLeafQueryRe = regexp.MustCompile(`\A(?P<name>[[:word:]\-\.]+)\z`)
+
+	// ConnectLeafRenewBefore is how long before a leaf certificate expires
+	// that it is fetched again without blocking, in case the agent has not
+	// rotated it by then.
+	ConnectLeafRenewBefore = 1 * time.Minute
+
+	// ConnectLeafQuerySleepTime is the amount of time to sleep between
+	// queries for a leaf certificate which is about to expire.
+	ConnectLeafQuerySleepTime = 5 * time.Second
+)
+
+func init() {
+	gob.Register(&LeafCert{})
+}
+
+// LeafCert is a Connect leaf certificate of a service, signed by the active
+// CA root.
+type LeafCert struct {
+	Service       string
+	ServiceURI    string
+	SerialNumber  string
+	CertPEM       string
+	PrivateKeyPEM string
+	ValidAfter    time.Time
+	ValidBefore   time.Time
+	CreateIndex   uint64
+	ModifyIndex   uint64
+}
+
+// ConnectLeafQuery is the dependency to query the Connect leaf certificate of
+// a service from the local agent.
+type ConnectLeafQuery struct {
+	stopCh chan struct{}
+
+	service string
+
+	// leaf is the last leaf certificate which was fetched.
+	leaf *LeafCert
+}
+
+// NewConnectLeafQuery creates a new dependency on the leaf certificate of the
+// given service.
+func NewConnectLeafQuery(s string) (*ConnectLeafQuery, error) {
+	if !ConnectLeafQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("connect.leaf: invalid format: %q", s)
+	}
+
+	m := regexpMatch(ConnectLeafQueryRe, s)
+	return &ConnectLeafQuery{
+		service: m["name"],
+		stopCh:  make(chan struct{}, 1),
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// leaf certificate. The agent rotates the certificate before it expires,
+// which returns the blocking query, but the certificate is also fetched again
+// without blocking once it is about to expire.
+func (d *ConnectLeafQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{})
+
+	if opts.WaitIndex != 0 && d.leaf != nil {
+		renew := time.Until(d.leaf.ValidBefore.Add(-ConnectLeafRenewBefore))
+		switch {
+		case renew <= 0:
+			log.Printf("[TRACE] %s: certificate is about to expire, sleeping for %s",
+				d, ConnectLeafQuerySleepTime)
+
+			select {
+			case <-d.stopCh:
+				return nil, nil, ErrStopped
+			case <-time.After(ConnectLeafQuerySleepTime):
+			}
+			opts.WaitIndex, opts.WaitTime = 0, 0
+		case opts.WaitTime == 0 || renew < opts.WaitTime:
+			opts.WaitTime = renew
+		}
+	}
+
+	endpoint := "/v1/agent/connect/ca/leaf/" + d.service
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     endpoint,
+		RawQuery: opts.String(),
+	})
+
+	var leaf LeafCert
+	qm, err := clients.Consul().Raw().Query(endpoint, &leaf, opts.ToConsulOpts())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	log.Printf("[TRACE] %s: returned response", d)
+
+	d.leaf = &leaf
+
+	rm := &ResponseMetadata{
+		LastIndex:   qm.LastIndex,
+		LastContact: qm.LastContact,
+	}
+
+	return &leaf, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *ConnectLeafQuery) CanShare() bool {
+	return false
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *ConnectLeafQuery) String() string {
+	return fmt.Sprintf("connect.leaf(%s)", d.service)
+}
+
+// Stop halts the dependency's fetch function.
+func (d *ConnectLeafQuery) Stop() {
+	close(d.stopCh)
+}
+
+// Type returns the type of this dependency.
+func (d *ConnectLeafQuery) Type() Type {
+	return TypeConsul
+}
@@ -1,3 +1,7 @@
+// Package dependency defines the data which templates depend on, like Consul
+// keys and services and Vault secrets, and the clients which fetch it. The
+// Dependency interface, the ClientSet, and the New*Query constructors are the
+// entry points for embedding Consul Template.
 package dependency
 
 import (
@@ -14,7 +18,7 @@ import (
 const (
 	dcRe     = `(@(?P<dc>[[:word:]\.\-\_]+))?`
 	keyRe    = `/?(?P<key>[^@]+)`
-	filterRe = `(\|(?P<filter>[[:word:]\,]+))?`
+	filterRe = `(\|(?P<filter>[[:word:]\,\! ]+))?`
 	nameRe   = `(?P<name>[[:word:]\-\_\/\.]+)`
 	nearRe   = `(~(?P<near>[[:word:]\.\-\_]+))?`
 	prefixRe = `/?(?P<prefix>[^@]+)`
@@ -27,8 +31,18 @@ const (
 	TypeConsul Type = iota
 	TypeVault
 	TypeLocal
+
+	// TypeInternal dependencies are supplied by Consul Template itself rather
+	// than watched.
+	TypeInternal
 )
 
+// Backends returns the sorted names of the backends templates can read data
+// from.
+func Backends() []string {
+	return []string{"consul", "file", "vault"}
+}
+
 // Dependency is an interface for a dependency that Consul Template is capable
 // of watching.
 type Dependency interface {
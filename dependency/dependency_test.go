@@ -23,8 +23,10 @@ func TestCanShare(t *testing.T) {
 	t.Parallel()
 
 	deps := []Dependency{
+		&ACLTokenSelfQuery{},
 		&CatalogNodeQuery{},
 		&FileQuery{},
+		&FileStatQuery{},
 		&VaultListQuery{},
 		&VaultReadQuery{},
 		&VaultTokenQuery{},
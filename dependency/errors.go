@@ -9,3 +9,12 @@ var ErrStopped = errors.New("dependency stopped")
 
 // ErrContinue is a special error which says to continue (retry) on error.
 var ErrContinue = errors.New("dependency continue")
+
+// ErrVaultSealed is returned when the health check of Vault finds that it is
+// sealed, so no secrets can be read until an operator unseals it.
+var ErrVaultSealed = errors.New("vault is sealed; unseal it with \"vault operator unseal\"")
+
+// ErrVaultUninitialized is returned when the health check of Vault finds that
+// it was never initialized.
+var ErrVaultUninitialized = errors.New("vault is not initialized; initialize " +
+	"it with \"vault operator init\" and unseal it")
@@ -0,0 +1,116 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/ghodss/yaml"
+)
+
+// FakeData is user-provided data for dependencies, which is returned instead
+// of querying the backends, so templates can be rendered without them. It is
+// loaded from a YAML or JSON document which maps the string of each
+// dependency, like "kv.block(foo)", to its data in the form it is given to
+// templates.
+type FakeData struct {
+	path string
+	data map[string]json.RawMessage
+}
+
+// LoadFakeData reads the fake data document at the given path.
+func LoadFakeData(path string) (*FakeData, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fake data: %s", err)
+	}
+
+	var data map[string]json.RawMessage
+	if err := yaml.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("fake data: %s: %s", path, err)
+	}
+
+	return &FakeData{path: path, data: data}, nil
+}
+
+// Fakes returns true if the fake data is used for the dependency. Local
+// dependencies, like files, need no backend, so they are read as usual unless
+// the document has data for them.
+func (f *FakeData) Fakes(d Dependency) bool {
+	if d.Type() != TypeLocal {
+		return true
+	}
+	_, ok := f.data[d.String()]
+	return ok
+}
+
+// Fetch returns the fake data of the dependency, decoded into the type its
+// Fetch returns. It is an error if the document has no data for the
+// dependency.
+func (f *FakeData) Fetch(d Dependency) (interface{}, error) {
+	raw, ok := f.data[d.String()]
+	if !ok {
+		return nil, fmt.Errorf("fake data: %s has no data for %s", f.path, d)
+	}
+
+	// A null value is data which does not exist, like a missing key.
+	if string(raw) == "null" {
+		return nil, nil
+	}
+
+	var v interface{}
+	switch d.(type) {
+	case *KVGetQuery, *FileQuery, *VaultGenerateQuery:
+		v = new(string)
+	case *KVKeysQuery, *CatalogDatacentersQuery, *VaultListQuery:
+		v = new([]string)
+	case *KVListQuery:
+		v = new([]*KeyPair)
+	case *KVLockQuery:
+		v = new(KVLock)
+	case *CatalogNodeQuery:
+		v = new(CatalogNode)
+	case *CatalogNodesQuery:
+		v = new([]*Node)
+	case *CatalogServiceQuery:
+		v = new([]*CatalogService)
+	case *CatalogServicesQuery:
+		v = new([]*CatalogSnippet)
+	case *HealthServiceQuery:
+		v = new([]*HealthService)
+	case *HealthChecksQuery:
+		v = new([]*HealthCheck)
+	case *HealthServiceSummaryQuery:
+		v = new(HealthServiceSummary)
+	case *RunnersQuery:
+		v = new([]*TemplateRunner)
+	case *ACLTokenSelfQuery:
+		v = new(ACLToken)
+	case *ConfigEntryQuery:
+		v = new(ConfigEntry)
+	case *ConnectCAQuery:
+		v = new([]*CARoot)
+	case *ConnectLeafQuery:
+		v = new(LeafCert)
+	case *FileStatQuery:
+		v = new(FileStat)
+	case *VaultReadQuery, *VaultWriteQuery, *VaultTokenQuery:
+		v = new(Secret)
+	case *VaultVersionsQuery:
+		v = new([]int)
+	default:
+		return nil, fmt.Errorf("fake data: %s is not supported", d)
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return nil, fmt.Errorf("fake data: %s: invalid data for %s: %s", f.path, d, err)
+	}
+
+	// Structs are returned as pointers, like the backends return them, and
+	// everything else as values.
+	if e := reflect.ValueOf(v).Elem(); e.Kind() != reflect.Struct {
+		return e.Interface(), nil
+	}
+	return v, nil
+}
@@ -0,0 +1,132 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeData_Fetch(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`
+kv.block(app/name): web
+kv.block(app/missing): null
+kv.list(app): [{Key: name, Value: web}]
+catalog.node(node1): {Node: {Node: node1, Address: 10.0.0.1}}
+vault.read(secret/app): {Data: {password: hunter2}}
+vault.versions(secret/data/app, 2): [1, 2]
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	fd, err := LoadFakeData(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newDep := func(d Dependency, err error) Dependency {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	cases := []struct {
+		name string
+		d    Dependency
+		exp  interface{}
+		err  bool
+	}{
+		{
+			"string",
+			newDep(NewKVGetQuery("app/name")),
+			"web",
+			false,
+		},
+		{
+			"null",
+			newDep(NewKVGetQuery("app/missing")),
+			nil,
+			false,
+		},
+		{
+			"list",
+			newDep(NewKVListQuery("app")),
+			[]*KeyPair{{Key: "name", Value: "web"}},
+			false,
+		},
+		{
+			"struct",
+			newDep(NewCatalogNodeQuery("node1")),
+			&CatalogNode{Node: &Node{Node: "node1", Address: "10.0.0.1"}},
+			false,
+		},
+		{
+			"secret",
+			newDep(NewVaultReadQuery("secret/app")),
+			&Secret{Data: map[string]interface{}{"password": "hunter2"}},
+			false,
+		},
+		{
+			"ints",
+			newDep(NewVaultVersionsQuery("secret/data/app", 2)),
+			[]int{1, 2},
+			false,
+		},
+		{
+			"missing",
+			newDep(NewKVGetQuery("app/port")),
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if d, ok := tc.d.(*KVGetQuery); ok {
+				d.EnableBlocking()
+			}
+
+			act, err := fd.Fetch(tc.d)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestFakeData_Fakes(t *testing.T) {
+	t.Parallel()
+
+	fd := &FakeData{data: map[string]json.RawMessage{
+		"file(/etc/faked)": json.RawMessage(`"faked"`),
+	}}
+
+	faked, err := NewFileQuery("/etc/faked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := NewFileQuery("/etc/local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := NewKVGetQuery("app/port")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, fd.Fakes(faked))
+	assert.False(t, fd.Fakes(local))
+	assert.True(t, fd.Fakes(kv))
+}
@@ -0,0 +1,130 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*FileStatQuery)(nil)
+)
+
+func init() {
+	gob.Register(&FileStat{})
+}
+
+// FileStat is the information about a local file, which may not exist.
+type FileStat struct {
+	Exists  bool
+	IsDir   bool
+	Mode    os.FileMode
+	ModTime time.Time
+	Name    string
+	Size    int64
+}
+
+// equal returns true if the stats describe the same version of the file.
+func (s *FileStat) equal(o *FileStat) bool {
+	return s.Exists == o.Exists &&
+		s.IsDir == o.IsDir &&
+		s.Mode == o.Mode &&
+		s.ModTime.Equal(o.ModTime) &&
+		s.Size == o.Size
+}
+
+// FileStatQuery represents a dependency on the presence and metadata of a
+// local file, without reading its contents.
+type FileStatQuery struct {
+	stopCh chan struct{}
+
+	path string
+	stat *FileStat
+}
+
+// NewFileStatQuery creates a file stat dependency from the given path.
+func NewFileStatQuery(s string) (*FileStatQuery, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("file.stat: invalid format: %q", s)
+	}
+
+	return &FileStatQuery{
+		stopCh: make(chan struct{}, 1),
+		path:   s,
+	}, nil
+}
+
+// Fetch returns the stat of the file, waiting until it changes if it was
+// returned before. A file which does not exist is not an error, since watching
+// for files to appear and disappear is the purpose of this dependency.
+func (d *FileStatQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	log.Printf("[TRACE] %s: STAT %s", d, d.path)
+
+	for {
+		stat, err := statFile(d.path)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+
+		if d.stat == nil || !d.stat.equal(stat) {
+			log.Printf("[TRACE] %s: reported change", d)
+			d.stat = stat
+			return respWithMetadata(stat)
+		}
+
+		select {
+		case <-d.stopCh:
+			log.Printf("[TRACE] %s: stopped", d)
+			return nil, nil, ErrStopped
+		case <-time.After(FileQuerySleepTime):
+		}
+	}
+}
+
+// statFile returns the stat of the file at the given path.
+func statFile(path string) (*FileStat, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &FileStat{Name: filepath.Base(path)}, nil
+		}
+		return nil, err
+	}
+
+	return &FileStat{
+		Exists:  true,
+		IsDir:   info.IsDir(),
+		Mode:    info.Mode(),
+		ModTime: info.ModTime(),
+		Name:    info.Name(),
+		Size:    info.Size(),
+	}, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *FileStatQuery) CanShare() bool {
+	return false
+}
+
+// Stop halts the dependency's fetch function.
+func (d *FileStatQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *FileStatQuery) String() string {
+	return fmt.Sprintf("file.stat(%s)", d.path)
+}
+
+// Type returns the type of this dependency.
+func (d *FileStatQuery) Type() Type {
+	return TypeLocal
+}
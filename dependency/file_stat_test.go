@@ -0,0 +1,181 @@
+package dependency
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFileStatQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *FileStatQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"path",
+			"path",
+			&FileStatQuery{
+				path: "path",
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewFileStatQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestFileStatQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "marker")
+	if err := ioutil.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("exists", func(t *testing.T) {
+		d, err := NewFileStatQuery(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act, _, err := d.Fetch(nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stat := act.(*FileStat)
+		assert.True(t, stat.Exists)
+		assert.False(t, stat.IsDir)
+		assert.Equal(t, "marker", stat.Name)
+		assert.Equal(t, int64(5), stat.Size)
+	})
+
+	t.Run("non_existent", func(t *testing.T) {
+		d, err := NewFileStatQuery(filepath.Join(dir, "missing"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act, _, err := d.Fetch(nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, &FileStat{Name: "missing"}, act)
+	})
+
+	t.Run("fires_changes", func(t *testing.T) {
+		created := filepath.Join(dir, "created")
+
+		d, err := NewFileStatQuery(created)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dataCh := make(chan interface{}, 1)
+		errCh := make(chan error, 1)
+		go func() {
+			for {
+				data, _, err := d.Fetch(nil, nil)
+				if err != nil {
+					errCh <- err
+					return
+				}
+				dataCh <- data
+			}
+		}()
+		defer d.Stop()
+
+		select {
+		case err := <-errCh:
+			t.Fatal(err)
+		case data := <-dataCh:
+			assert.False(t, data.(*FileStat).Exists)
+		}
+
+		if err := ioutil.WriteFile(created, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err := <-errCh:
+			t.Fatal(err)
+		case data := <-dataCh:
+			assert.True(t, data.(*FileStat).Exists)
+		case <-time.After(time.Second):
+			t.Errorf("did not fire")
+		}
+	})
+
+	t.Run("stops", func(t *testing.T) {
+		d, err := NewFileStatQuery(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			for {
+				_, _, err := d.Fetch(nil, nil)
+				if err != nil {
+					errCh <- err
+					return
+				}
+			}
+		}()
+
+		d.Stop()
+
+		select {
+		case err := <-errCh:
+			if err != ErrStopped {
+				t.Fatal(err)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Errorf("did not stop")
+		}
+	})
+}
+
+func TestFileStatQuery_String(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewFileStatQuery("path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "file.stat(path)", d.String())
+}
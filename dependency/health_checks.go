@@ -0,0 +1,183 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*HealthChecksQuery)(nil)
+
+	// HealthChecksQueryRe is the regular expression to use.
+	HealthChecksQueryRe = regexp.MustCompile(`\A` + nameRe + dcRe + nearRe + filterRe + `\z`)
+)
+
+func init() {
+	gob.Register([]*HealthCheck{})
+}
+
+// HealthCheck is a health check of a service in Consul. Checks which put a
+// node or service in maintenance have the "maintenance" status.
+type HealthCheck struct {
+	Node        string
+	CheckID     string
+	Name        string
+	Status      string
+	Notes       string
+	Output      string
+	ServiceID   string
+	ServiceName string
+}
+
+// HealthChecksQuery is the representation of a query for the health checks of
+// a service in Consul.
+type HealthChecksQuery struct {
+	stopCh chan struct{}
+
+	dc      string
+	filters []string
+	name    string
+	near    string
+}
+
+// NewHealthChecksQuery processes the strings to build a health checks
+// dependency. The filter is the same as for a HealthServiceQuery, but applies
+// to the status of each check, and accepts every status if it is omitted.
+func NewHealthChecksQuery(s string) (*HealthChecksQuery, error) {
+	if !HealthChecksQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("health.checks: invalid format: %q", s)
+	}
+
+	m := regexpMatch(HealthChecksQueryRe, s)
+
+	filters, err := parseHealthFilter(m["filter"])
+	if err != nil {
+		return nil, fmt.Errorf("health.checks: %s", err)
+	}
+	if filters == nil {
+		filters = []string{HealthAny}
+	}
+
+	return &HealthChecksQuery{
+		stopCh:  make(chan struct{}, 1),
+		dc:      m["dc"],
+		filters: filters,
+		name:    m["name"],
+		near:    m["near"],
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns a slice
+// of HealthCheck objects.
+func (d *HealthChecksQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{
+		Datacenter: d.dc,
+		Near:       d.near,
+	})
+
+	u := &url.URL{
+		Path:     "/v1/health/checks/" + d.name,
+		RawQuery: opts.String(),
+	}
+	log.Printf("[TRACE] %s: GET %s", d, u)
+
+	checks, qm, err := clients.Consul().Health().Checks(d.name, opts.ToConsulOpts())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	log.Printf("[TRACE] %s: returned %d results", d, len(checks))
+
+	list := make([]*HealthCheck, 0, len(checks))
+	for _, check := range checks {
+		// Checks which put a node or service in maintenance are critical, so
+		// their status is aggregated to tell them apart.
+		status := api.HealthChecks{check}.AggregatedStatus()
+		if !acceptStatus(d.filters, status) {
+			continue
+		}
+
+		list = append(list, &HealthCheck{
+			Node:        intern.String(check.Node),
+			CheckID:     check.CheckID,
+			Name:        check.Name,
+			Status:      intern.String(status),
+			Notes:       check.Notes,
+			Output:      check.Output,
+			ServiceID:   check.ServiceID,
+			ServiceName: intern.String(check.ServiceName),
+		})
+	}
+
+	log.Printf("[TRACE] %s: returned %d results after filtering", d, len(list))
+
+	// Keep the order of checks by node when sorted by distance.
+	if d.near == "" {
+		sort.Stable(ByNodeThenCheckID(list))
+	}
+
+	rm := &ResponseMetadata{
+		LastIndex:   qm.LastIndex,
+		LastContact: qm.LastContact,
+	}
+
+	return list, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *HealthChecksQuery) CanShare() bool {
+	return true
+}
+
+// Stop halts the dependency's fetch function.
+func (d *HealthChecksQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *HealthChecksQuery) String() string {
+	name := d.name
+	if d.dc != "" {
+		name = name + "@" + d.dc
+	}
+	if d.near != "" {
+		name = name + "~" + d.near
+	}
+	if len(d.filters) > 0 {
+		name = name + "|" + strings.Join(d.filters, ",")
+	}
+	return fmt.Sprintf("health.checks(%s)", name)
+}
+
+// Type returns the type of this dependency.
+func (d *HealthChecksQuery) Type() Type {
+	return TypeConsul
+}
+
+// ByNodeThenCheckID is a sortable slice of HealthCheck
+type ByNodeThenCheckID []*HealthCheck
+
+// Len, Swap, and Less are used to implement the sort.Sort interface.
+func (s ByNodeThenCheckID) Len() int      { return len(s) }
+func (s ByNodeThenCheckID) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByNodeThenCheckID) Less(i, j int) bool {
+	if s[i].Node != s[j].Node {
+		return s[i].Node < s[j].Node
+	}
+	return s[i].CheckID < s[j].CheckID
+}
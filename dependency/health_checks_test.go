@@ -0,0 +1,198 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHealthChecksQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *HealthChecksQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"dc_only",
+			"@dc1",
+			nil,
+			true,
+		},
+		{
+			"name",
+			"name",
+			&HealthChecksQuery{
+				filters: []string{"any"},
+				name:    "name",
+			},
+			false,
+		},
+		{
+			"name_dc_near",
+			"name@dc1~_agent",
+			&HealthChecksQuery{
+				dc:      "dc1",
+				filters: []string{"any"},
+				name:    "name",
+				near:    "_agent",
+			},
+			false,
+		},
+		{
+			"negated_filter",
+			"name|not passing",
+			&HealthChecksQuery{
+				filters: []string{"critical", "maintenance", "warning"},
+				name:    "name",
+			},
+			false,
+		},
+		{
+			"invalid_filter",
+			"name|healthy",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewHealthChecksQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestHealthChecksQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/checks/web" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"Node": "node2", "CheckID": "service:web", "Status": "warning", "ServiceName": "web"},
+			{"Node": "node1", "CheckID": "service:web", "Status": "passing", "ServiceName": "web"},
+			{"Node": "node3", "CheckID": "_service_maintenance:web", "Status": "critical", "ServiceName": "web"},
+			{"Node": "node3", "CheckID": "service:web", "Status": "critical", "ServiceName": "web"}
+		]`))
+	}))
+	defer ts.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateConsulClient(&CreateConsulClientInput{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		i    string
+		exp  []string
+	}{
+		{
+			"any",
+			"web",
+			[]string{
+				"node1/service:web",
+				"node2/service:web",
+				"node3/_service_maintenance:web",
+				"node3/service:web",
+			},
+		},
+		{
+			"single",
+			"web|critical",
+			[]string{"node3/service:web"},
+		},
+		{
+			"combination",
+			"web|passing,warning",
+			[]string{"node1/service:web", "node2/service:web"},
+		},
+		{
+			"negation",
+			"web|!critical,!passing",
+			[]string{"node2/service:web", "node3/_service_maintenance:web"},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewHealthChecksQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, _, err := d.Fetch(clients, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := []string{}
+			for _, c := range act.([]*HealthCheck) {
+				ids = append(ids, c.Node+"/"+c.CheckID)
+			}
+			assert.Equal(t, tc.exp, ids)
+		})
+	}
+}
+
+func TestHealthChecksQuery_String(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"name",
+			"name",
+			"health.checks(name|any)",
+		},
+		{
+			"name_dc_near_filter",
+			"name@dc~near|warning,critical",
+			"health.checks(name@dc~near|critical,warning)",
+		},
+		{
+			"name_negated_filter",
+			"name|not critical,not maintenance",
+			"health.checks(name|passing,warning)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewHealthChecksQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
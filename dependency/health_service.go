@@ -30,6 +30,9 @@ var (
 
 	// HealthServiceQueryRe is the regular expression to use.
 	HealthServiceQueryRe = regexp.MustCompile(`\A` + tagRe + nameRe + dcRe + nearRe + filterRe + `\z`)
+
+	// healthStates are the sorted health states filters select from.
+	healthStates = []string{HealthCritical, HealthMaint, HealthPassing, HealthWarning}
 )
 
 func init() {
@@ -71,25 +74,11 @@ func NewHealthServiceQuery(s string) (*HealthServiceQuery, error) {
 
 	m := regexpMatch(HealthServiceQueryRe, s)
 
-	var filters []string
-	if filter := m["filter"]; filter != "" {
-		split := strings.Split(filter, ",")
-		for _, f := range split {
-			f = strings.TrimSpace(f)
-			switch f {
-			case HealthAny,
-				HealthPassing,
-				HealthWarning,
-				HealthCritical,
-				HealthMaint:
-				filters = append(filters, f)
-			case "":
-			default:
-				return nil, fmt.Errorf("health.service: invalid filter: %q in %q", f, s)
-			}
-		}
-		sort.Strings(filters)
-	} else {
+	filters, err := parseHealthFilter(m["filter"])
+	if err != nil {
+		return nil, fmt.Errorf("health.service: %s", err)
+	}
+	if filters == nil {
 		filters = []string{HealthPassing}
 	}
 
@@ -130,6 +119,9 @@ func (d *HealthServiceQuery) Fetch(clients *ClientSet, opts *QueryOptions) (inte
 
 	// Check if a user-supplied filter was given. If so, we may be querying for
 	// more than healthy services, so we need to implement client-side filtering.
+	// Filters are normalized, so any filter which only accepts passing
+	// services, such as "not critical,not warning,not maintenance", is
+	// filtered by Consul instead.
 	passingOnly := len(d.filters) == 1 && d.filters[0] == HealthPassing
 
 	entries, qm, err := clients.Consul().Health().Service(d.name, d.tag, passingOnly, opts.ToConsulOpts())
@@ -157,16 +149,16 @@ func (d *HealthServiceQuery) Fetch(clients *ClientSet, opts *QueryOptions) (inte
 		}
 
 		list = append(list, &HealthService{
-			Node:                entry.Node.Node,
-			NodeID:              entry.Node.ID,
-			NodeAddress:         entry.Node.Address,
-			NodeTaggedAddresses: entry.Node.TaggedAddresses,
-			NodeMeta:            entry.Node.Meta,
-			Address:             address,
+			Node:                intern.String(entry.Node.Node),
+			NodeID:              intern.String(entry.Node.ID),
+			NodeAddress:         intern.String(entry.Node.Address),
+			NodeTaggedAddresses: intern.Map(entry.Node.TaggedAddresses),
+			NodeMeta:            intern.Map(entry.Node.Meta),
+			Address:             intern.String(address),
 			ID:                  entry.Service.ID,
-			Name:                entry.Service.Service,
-			Tags:                ServiceTags(deepCopyAndSortTags(entry.Service.Tags)),
-			Status:              status,
+			Name:                intern.String(entry.Service.Service),
+			Tags:                intern.Tags(entry.Service.Tags),
+			Status:              intern.String(status),
 			Checks:              entry.Checks,
 			Port:                entry.Service.Port,
 		})
@@ -217,6 +209,69 @@ func (d *HealthServiceQuery) Type() Type {
 	return TypeConsul
 }
 
+// parseHealthFilter parses a comma-separated health filter into the sorted
+// health states it accepts, or just HealthAny if it accepts every state. Each
+// term is a health state or "any", optionally negated with "not " or "!", such
+// as "passing,warning" or "not critical". Negated states are removed from the
+// states of the other terms, or from every state if there are none. Since
+// equivalent filters parse the same, they share the same dependency. It
+// returns nil if the filter has no terms.
+func parseHealthFilter(filter string) ([]string, error) {
+	include := make(map[string]bool)
+	exclude := make(map[string]bool)
+	var terms int
+	for _, term := range strings.Split(filter, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		terms++
+
+		states := include
+		if strings.HasPrefix(term, "!") {
+			states, term = exclude, strings.TrimSpace(term[1:])
+		} else if f := strings.Fields(term); len(f) == 2 && f[0] == "not" {
+			states, term = exclude, f[1]
+		}
+
+		switch term {
+		case HealthAny:
+			for _, s := range healthStates {
+				states[s] = true
+			}
+		case HealthPassing, HealthWarning, HealthCritical, HealthMaint:
+			states[term] = true
+		default:
+			return nil, fmt.Errorf("invalid filter: %q in %q", term, filter)
+		}
+	}
+	if terms == 0 {
+		return nil, nil
+	}
+
+	if len(include) == 0 {
+		for _, s := range healthStates {
+			include[s] = true
+		}
+	}
+
+	var accepted []string
+	for _, s := range healthStates {
+		if include[s] && !exclude[s] {
+			accepted = append(accepted, s)
+		}
+	}
+
+	switch len(accepted) {
+	case 0:
+		return nil, fmt.Errorf("filter %q accepts no health state", filter)
+	case len(healthStates):
+		return []string{HealthAny}, nil
+	default:
+		return accepted, nil
+	}
+}
+
 // acceptStatus allows us to check if a slice of health checks pass this filter.
 func acceptStatus(list []string, s string) bool {
 	for _, status := range list {
@@ -0,0 +1,170 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*HealthServiceSummaryQuery)(nil)
+
+	// HealthServiceSummaryQueryRe is the regular expression to use.
+	HealthServiceSummaryQueryRe = regexp.MustCompile(`\A` + tagRe + nameRe + dcRe + `\z`)
+)
+
+func init() {
+	gob.Register(&HealthServiceSummary{})
+}
+
+// HealthServiceSummary is the number of instances of a service in Consul by
+// health state.
+type HealthServiceSummary struct {
+	Passing     int
+	Warning     int
+	Critical    int
+	Maintenance int
+	Total       int
+}
+
+// Count returns the number of instances in the health states accepted by the
+// filter, such as "passing,warning" or "not critical", or the passing
+// instances if no filter is given.
+func (s *HealthServiceSummary) Count(filter string) (int, error) {
+	states, err := parseHealthFilter(filter)
+	if err != nil {
+		return 0, fmt.Errorf("health.service.summary: %s", err)
+	}
+	if states == nil {
+		return s.Passing, nil
+	}
+
+	var count int
+	for _, state := range states {
+		switch state {
+		case HealthAny:
+			return s.Total, nil
+		case HealthPassing:
+			count += s.Passing
+		case HealthWarning:
+			count += s.Warning
+		case HealthCritical:
+			count += s.Critical
+		case HealthMaint:
+			count += s.Maintenance
+		}
+	}
+	return count, nil
+}
+
+// HealthServiceSummaryQuery is the representation of a query for the number
+// of instances of a service in Consul by health state. Only the counts are
+// kept, so it is much smaller than a HealthServiceQuery for large services.
+type HealthServiceSummaryQuery struct {
+	stopCh chan struct{}
+
+	dc   string
+	name string
+	tag  string
+}
+
+// NewHealthServiceSummaryQuery processes the strings to build a service
+// summary dependency.
+func NewHealthServiceSummaryQuery(s string) (*HealthServiceSummaryQuery, error) {
+	if !HealthServiceSummaryQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("health.service.summary: invalid format: %q", s)
+	}
+
+	m := regexpMatch(HealthServiceSummaryQueryRe, s)
+	return &HealthServiceSummaryQuery{
+		stopCh: make(chan struct{}, 1),
+		dc:     m["dc"],
+		name:   m["name"],
+		tag:    m["tag"],
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns a
+// HealthServiceSummary object.
+func (d *HealthServiceSummaryQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{
+		Datacenter: d.dc,
+	})
+
+	u := &url.URL{
+		Path:     "/v1/health/service/" + d.name,
+		RawQuery: opts.String(),
+	}
+	if d.tag != "" {
+		q := u.Query()
+		q.Set("tag", d.tag)
+		u.RawQuery = q.Encode()
+	}
+	log.Printf("[TRACE] %s: GET %s", d, u)
+
+	entries, qm, err := clients.Consul().Health().Service(d.name, d.tag, false, opts.ToConsulOpts())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	log.Printf("[TRACE] %s: returned %d results", d, len(entries))
+
+	summary := &HealthServiceSummary{Total: len(entries)}
+	for _, entry := range entries {
+		switch entry.Checks.AggregatedStatus() {
+		case HealthPassing:
+			summary.Passing++
+		case HealthWarning:
+			summary.Warning++
+		case HealthCritical:
+			summary.Critical++
+		case HealthMaint:
+			summary.Maintenance++
+		}
+	}
+
+	rm := &ResponseMetadata{
+		LastIndex:   qm.LastIndex,
+		LastContact: qm.LastContact,
+	}
+
+	return summary, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *HealthServiceSummaryQuery) CanShare() bool {
+	return true
+}
+
+// Stop halts the dependency's fetch function.
+func (d *HealthServiceSummaryQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *HealthServiceSummaryQuery) String() string {
+	name := d.name
+	if d.tag != "" {
+		name = d.tag + "." + name
+	}
+	if d.dc != "" {
+		name = name + "@" + d.dc
+	}
+	return fmt.Sprintf("health.service.summary(%s)", name)
+}
+
+// Type returns the type of this dependency.
+func (d *HealthServiceSummaryQuery) Type() Type {
+	return TypeConsul
+}
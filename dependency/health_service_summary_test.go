@@ -0,0 +1,159 @@
+package dependency
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHealthServiceSummaryQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *HealthServiceSummaryQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"dc_only",
+			"@dc1",
+			nil,
+			true,
+		},
+		{
+			"filter",
+			"name|passing",
+			nil,
+			true,
+		},
+		{
+			"name",
+			"name",
+			&HealthServiceSummaryQuery{
+				name: "name",
+			},
+			false,
+		},
+		{
+			"tag_name_dc",
+			"tag.name@dc1",
+			&HealthServiceSummaryQuery{
+				dc:   "dc1",
+				name: "name",
+				tag:  "tag",
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewHealthServiceSummaryQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestHealthServiceSummaryQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	clients, consul := testConsulServer(t)
+	defer consul.Stop()
+
+	d, err := NewHealthServiceSummaryQuery("consul")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, _, err := d.Fetch(clients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, &HealthServiceSummary{Passing: 1, Total: 1}, act)
+}
+
+func TestHealthServiceSummaryQuery_String(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"name",
+			"name",
+			"health.service.summary(name)",
+		},
+		{
+			"tag_name_dc",
+			"tag.name@dc",
+			"health.service.summary(tag.name@dc)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewHealthServiceSummaryQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
+
+func TestHealthServiceSummary_Count(t *testing.T) {
+	t.Parallel()
+
+	s := &HealthServiceSummary{
+		Passing:     4,
+		Warning:     3,
+		Critical:    2,
+		Maintenance: 1,
+		Total:       10,
+	}
+
+	cases := []struct {
+		name   string
+		filter string
+		exp    int
+		err    bool
+	}{
+		{"default", "", 4, false},
+		{"single", "critical", 2, false},
+		{"multiple", "passing,warning", 7, false},
+		{"maintenance", "maintenance", 1, false},
+		{"any", "passing,any", 10, false},
+		{"negation", "not critical", 8, false},
+		{"negation_from_combination", "passing,warning,!warning", 4, false},
+		{"invalid", "healthy", 0, true},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := s.Count(tc.filter)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
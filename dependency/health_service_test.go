@@ -124,6 +124,30 @@ func TestNewHealthServiceQuery(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"name_filter_combination",
+			"name|warning,passing",
+			&HealthServiceQuery{
+				filters: []string{"passing", "warning"},
+				name:    "name",
+			},
+			false,
+		},
+		{
+			"name_filter_negation",
+			"name|not critical",
+			&HealthServiceQuery{
+				filters: []string{"maintenance", "passing", "warning"},
+				name:    "name",
+			},
+			false,
+		},
+		{
+			"name_filter_invalid",
+			"name|healthy",
+			nil,
+			true,
+		},
 	}
 
 	for i, tc := range cases {
@@ -142,6 +166,41 @@ func TestNewHealthServiceQuery(t *testing.T) {
 	}
 }
 
+func TestParseHealthFilter(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  []string
+		err  bool
+	}{
+		{"empty", "", nil, false},
+		{"only_commas", " , ", nil, false},
+		{"single", "warning", []string{"warning"}, false},
+		{"combination", "warning, passing", []string{"passing", "warning"}, false},
+		{"any", "any", []string{"any"}, false},
+		{"every_state", "passing,warning,critical,maintenance", []string{"any"}, false},
+		{"not", "not critical", []string{"maintenance", "passing", "warning"}, false},
+		{"bang", "!critical,!maintenance", []string{"passing", "warning"}, false},
+		{"not_from_combination", "passing,warning,not warning", []string{"passing"}, false},
+		{"not_from_any", "any,!passing", []string{"critical", "maintenance", "warning"}, false},
+		{"nothing", "not any", nil, true},
+		{"invalid", "healthy", nil, true},
+		{"invalid_negation", "not healthy", nil, true},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := parseHealthFilter(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
 func TestHealthServiceQuery_Fetch(t *testing.T) {
 	t.Parallel()
 
@@ -254,6 +313,11 @@ func TestHealthServiceQuery_String(t *testing.T) {
 			"name|warning,passing",
 			"health.service(name|passing,warning)",
 		},
+		{
+			"name_negated_filter",
+			"name|!critical,!maintenance",
+			"health.service(name|passing,warning)",
+		},
 		{
 			"name_near",
 			"name~near",
@@ -0,0 +1,124 @@
+package dependency
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// internLimit is the number of distinct values the interner holds before it
+// starts over.
+const internLimit = 1 << 16
+
+// intern is the interner shared by all dependencies.
+var intern = newInterner(internLimit)
+
+// interner deduplicates the strings, tag lists and string maps which repeat
+// across the results of dependencies, such as the names, addresses, tags and
+// metadata of nodes. Each distinct value is then held in memory once, no
+// matter how many services on how many watched queries refer to it. The
+// values are shared, so they must never be modified. To bound its own memory,
+// the interner forgets everything it holds once it holds more than its limit
+// of values, and shares values again as they are seen after that.
+type interner struct {
+	sync.Mutex
+
+	limit   int
+	strings map[string]string
+	tags    map[string]ServiceTags
+	maps    map[string]map[string]string
+}
+
+// newInterner returns an interner which holds at most the given number of
+// distinct values.
+func newInterner(limit int) *interner {
+	i := &interner{limit: limit}
+	i.reset()
+	return i
+}
+
+// reset forgets all of the values held by the interner.
+func (i *interner) reset() {
+	i.strings = make(map[string]string)
+	i.tags = make(map[string]ServiceTags)
+	i.maps = make(map[string]map[string]string)
+}
+
+// full resets the interner if it holds more than its limit of values. The
+// caller must hold the lock.
+func (i *interner) full() {
+	if len(i.strings)+len(i.tags)+len(i.maps) >= i.limit {
+		i.reset()
+	}
+}
+
+// String returns the shared copy of the given string.
+func (i *interner) String(s string) string {
+	i.Lock()
+	defer i.Unlock()
+
+	return i.string(s)
+}
+
+// string returns the shared copy of the given string. The caller must hold the
+// lock.
+func (i *interner) string(s string) string {
+	if v, ok := i.strings[s]; ok {
+		return v
+	}
+	i.full()
+	i.strings[s] = s
+	return s
+}
+
+// Tags returns the shared, sorted copy of the given tags.
+func (i *interner) Tags(tags []string) ServiceTags {
+	sorted := deepCopyAndSortTags(tags)
+	key := strings.Join(sorted, "\x00")
+
+	i.Lock()
+	defer i.Unlock()
+
+	if v, ok := i.tags[key]; ok && len(v) == len(sorted) {
+		return v
+	}
+	for n, tag := range sorted {
+		sorted[n] = i.string(tag)
+	}
+	i.full()
+	i.tags[key] = ServiceTags(sorted)
+	return i.tags[key]
+}
+
+// Map returns the shared copy of the given map. A nil map stays nil.
+func (i *interner) Map(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k, m[k])
+	}
+	key := strings.Join(pairs, "\x00")
+
+	i.Lock()
+	defer i.Unlock()
+
+	if v, ok := i.maps[key]; ok && len(v) == len(m) {
+		return v
+	}
+	shared := make(map[string]string, len(m))
+	for k, v := range m {
+		shared[i.string(k)] = i.string(v)
+	}
+	i.full()
+	i.maps[key] = shared
+	return shared
+}
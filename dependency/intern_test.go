@@ -0,0 +1,69 @@
+package dependency
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInterner_Tags(t *testing.T) {
+	t.Parallel()
+
+	i := newInterner(internLimit)
+
+	a := i.Tags([]string{"b", "a"})
+	b := i.Tags([]string{"a", "b"})
+
+	if exp := ServiceTags([]string{"a", "b"}); !reflect.DeepEqual(exp, a) {
+		t.Fatalf("expected %v, got %v", exp, a)
+	}
+	if &a[0] != &b[0] {
+		t.Error("expected the tags to be shared")
+	}
+
+	if c := i.Tags([]string{"a"}); reflect.DeepEqual(a, c) {
+		t.Errorf("expected different tags to not be shared, got %v", c)
+	}
+
+	if empty := i.Tags(nil); empty == nil || len(empty) != 0 {
+		t.Errorf("expected empty tags, got %#v", empty)
+	}
+}
+
+func TestInterner_Map(t *testing.T) {
+	t.Parallel()
+
+	i := newInterner(internLimit)
+
+	a := i.Map(map[string]string{"rack": "r1", "zone": "a"})
+	b := i.Map(map[string]string{"zone": "a", "rack": "r1"})
+	c := i.Map(map[string]string{"zone": "b", "rack": "r1"})
+
+	if reflect.ValueOf(a).Pointer() != reflect.ValueOf(b).Pointer() {
+		t.Error("expected equal maps to be shared")
+	}
+	if reflect.ValueOf(a).Pointer() == reflect.ValueOf(c).Pointer() {
+		t.Error("expected different maps to not be shared")
+	}
+	if c["zone"] != "b" {
+		t.Errorf("expected zone b, got %q", c["zone"])
+	}
+
+	if m := i.Map(nil); m != nil {
+		t.Errorf("expected nil, got %#v", m)
+	}
+}
+
+func TestInterner_limit(t *testing.T) {
+	t.Parallel()
+
+	i := newInterner(3)
+	for _, s := range []string{"a", "b", "c", "d"} {
+		if act := i.String(s); act != s {
+			t.Fatalf("expected %q, got %q", s, act)
+		}
+	}
+
+	if n := len(i.strings); n != 1 {
+		t.Errorf("expected the interner to start over at its limit, holds %d", n)
+	}
+}
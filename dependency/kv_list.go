@@ -1,9 +1,11 @@
 package dependency
 
 import (
+	"encoding/gob"
 	"fmt"
 	"log"
 	"net/url"
+	"path"
 	"regexp"
 	"strings"
 
@@ -18,6 +20,10 @@ var (
 	KVListQueryRe = regexp.MustCompile(`\A` + prefixRe + dcRe + `\z`)
 )
 
+func init() {
+	gob.Register([]*KeyPair{})
+}
+
 // KeyPair is a simple Key-Value pair
 type KeyPair struct {
 	Path  string
@@ -36,22 +42,35 @@ type KeyPair struct {
 type KVListQuery struct {
 	stopCh chan struct{}
 
-	dc     string
-	prefix string
+	dc      string
+	exclude []string
+	prefix  string
 }
 
-// NewKVListQuery parses a string into a dependency.
-func NewKVListQuery(s string) (*KVListQuery, error) {
+// NewKVListQuery parses a string into a dependency. Keys matching any of the
+// exclude patterns are dropped before the result is returned, so changes to
+// them do not trigger renders. See excludeKey for how patterns are matched.
+func NewKVListQuery(s string, exclude ...string) (*KVListQuery, error) {
 	if s != "" && !KVListQueryRe.MatchString(s) {
 		return nil, fmt.Errorf("kv.list: invalid format: %q", s)
 	}
 
+	for _, p := range exclude {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("kv.list: invalid exclude pattern: %q", p)
+		}
+	}
+
 	m := regexpMatch(KVListQueryRe, s)
-	return &KVListQuery{
+	d := &KVListQuery{
 		stopCh: make(chan struct{}, 1),
 		dc:     m["dc"],
 		prefix: m["prefix"],
-	}, nil
+	}
+	if len(exclude) > 0 {
+		d.exclude = exclude
+	}
+	return d, nil
 }
 
 // Fetch queries the Consul API defined by the given client.
@@ -83,6 +102,10 @@ func (d *KVListQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}
 		key := strings.TrimPrefix(pair.Key, d.prefix)
 		key = strings.TrimLeft(key, "/")
 
+		if excludeKey(d.exclude, key) {
+			continue
+		}
+
 		pairs = append(pairs, &KeyPair{
 			Path:        pair.Key,
 			Key:         key,
@@ -95,6 +118,10 @@ func (d *KVListQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}
 		})
 	}
 
+	if len(d.exclude) > 0 {
+		log.Printf("[TRACE] %s: returned %d pairs after excluding", d, len(pairs))
+	}
+
 	rm := &ResponseMetadata{
 		LastIndex:   qm.LastIndex,
 		LastContact: qm.LastContact,
@@ -114,6 +141,9 @@ func (d *KVListQuery) String() string {
 	if d.dc != "" {
 		prefix = prefix + "@" + d.dc
 	}
+	if len(d.exclude) > 0 {
+		prefix = prefix + "|exclude=" + strings.Join(d.exclude, ",")
+	}
 	return fmt.Sprintf("kv.list(%s)", prefix)
 }
 
@@ -126,3 +156,29 @@ func (d *KVListQuery) Stop() {
 func (d *KVListQuery) Type() Type {
 	return TypeConsul
 }
+
+// excludeKey returns true if the key, relative to the listed prefix, matches
+// any of the patterns. A pattern without a "/" is matched against each
+// segment of the key, so "*.tmp" excludes "a/b.tmp". Other patterns are
+// matched against the key and each of its parent directories, so "private/*"
+// excludes everything under "private/".
+func excludeKey(patterns []string, key string) bool {
+	segments := strings.Split(key, "/")
+	for _, p := range patterns {
+		if !strings.Contains(p, "/") {
+			for _, s := range segments {
+				if ok, _ := path.Match(p, s); ok {
+					return true
+				}
+			}
+			continue
+		}
+
+		for i := range segments {
+			if ok, _ := path.Match(p, strings.Join(segments[:i+1], "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -347,3 +347,45 @@ func TestKVListQuery_String(t *testing.T) {
 		})
 	}
 }
+
+func TestNewKVListQuery_exclude(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewKVListQuery("prefix@dc1", "*.tmp", "private/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"*.tmp", "private/*"}, d.exclude)
+	assert.Equal(t, "kv.list(prefix@dc1|exclude=*.tmp,private/*)", d.String())
+
+	if _, err := NewKVListQuery("prefix", "[a-"); err == nil {
+		t.Errorf("expected error for invalid pattern")
+	}
+}
+
+func TestExcludeKey(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		patterns []string
+		key      string
+		exp      bool
+	}{
+		{"none", nil, "a", false},
+		{"base", []string{"*.tmp"}, "a.tmp", true},
+		{"nested_base", []string{"*.tmp"}, "a/b.tmp", true},
+		{"no_match", []string{"*.tmp"}, "a/b.conf", false},
+		{"segment", []string{"private"}, "private/a", true},
+		{"path", []string{"private/*"}, "private/a", true},
+		{"path_nested", []string{"private/*"}, "private/a/b", true},
+		{"path_other", []string{"private/*"}, "public/private", false},
+		{"multiple", []string{"x", "*.tmp"}, "a.tmp", true},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			assert.Equal(t, tc.exp, excludeKey(tc.patterns, tc.key))
+		})
+	}
+}
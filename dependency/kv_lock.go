@@ -0,0 +1,153 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*KVLockQuery)(nil)
+
+	// KVLockQueryRe is the regular expression to use.
+	KVLockQueryRe = regexp.MustCompile(`\A` + keyRe + dcRe + `\z`)
+)
+
+func init() {
+	gob.Register(&KVLock{})
+}
+
+// KVLock is the holder of a lock on a Consul key, which is the session that
+// acquired it.
+type KVLock struct {
+	// Key is the key which is locked.
+	Key string
+
+	// Session is the ID of the session holding the lock, and Name the name it
+	// was created with.
+	Session string
+	Name    string
+
+	// Node is the name of the node the session belongs to.
+	Node string
+
+	// LockIndex is the number of times the lock was acquired.
+	LockIndex uint64
+
+	// Value is the value of the key, which holders often set to identify
+	// themselves.
+	Value string
+}
+
+// KVLockQuery queries the holder of a lock on a key.
+type KVLockQuery struct {
+	stopCh chan struct{}
+
+	dc  string
+	key string
+}
+
+// NewKVLockQuery parses a string into a dependency.
+func NewKVLockQuery(s string) (*KVLockQuery, error) {
+	if !KVLockQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("kv.lock: invalid format: %q", s)
+	}
+
+	m := regexpMatch(KVLockQueryRe, s)
+	return &KVLockQuery{
+		stopCh: make(chan struct{}, 1),
+		dc:     m["dc"],
+		key:    m["key"],
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// holder of the lock, or nil if the key is not locked.
+func (d *KVLockQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{
+		Datacenter: d.dc,
+	})
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     "/v1/kv/" + d.key,
+		RawQuery: opts.String(),
+	})
+
+	pair, qm, err := clients.Consul().KV().Get(d.key, opts.ToConsulOpts())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	rm := &ResponseMetadata{
+		LastIndex:   qm.LastIndex,
+		LastContact: qm.LastContact,
+		Block:       true,
+	}
+
+	if pair == nil || pair.Session == "" {
+		log.Printf("[TRACE] %s: not locked", d)
+		return nil, rm, nil
+	}
+
+	lock := &KVLock{
+		Key:       pair.Key,
+		Session:   pair.Session,
+		LockIndex: pair.LockIndex,
+		Value:     string(pair.Value),
+	}
+
+	// The key changes when its lock is released, so only the key is watched
+	// and the session is looked up without blocking.
+	sessionOpts := &QueryOptions{Datacenter: d.dc, AllowStale: opts.AllowStale}
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     "/v1/session/info/" + pair.Session,
+		RawQuery: sessionOpts.String(),
+	})
+
+	session, _, err := clients.Consul().Session().Info(pair.Session, sessionOpts.ToConsulOpts())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+	if session != nil {
+		lock.Name = session.Name
+		lock.Node = session.Node
+	}
+
+	log.Printf("[TRACE] %s: locked by session %s on node %q", d, lock.Session, lock.Node)
+	return lock, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *KVLockQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *KVLockQuery) String() string {
+	key := d.key
+	if d.dc != "" {
+		key = key + "@" + d.dc
+	}
+	return fmt.Sprintf("kv.lock(%s)", key)
+}
+
+// Stop halts the dependency's fetch function.
+func (d *KVLockQuery) Stop() {
+	close(d.stopCh)
+}
+
+// Type returns the type of this dependency.
+func (d *KVLockQuery) Type() Type {
+	return TypeConsul
+}
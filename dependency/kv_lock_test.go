@@ -0,0 +1,173 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKVLockQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *KVLockQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"dc_only",
+			"@dc1",
+			nil,
+			true,
+		},
+		{
+			"key",
+			"service/app/leader",
+			&KVLockQuery{
+				key: "service/app/leader",
+			},
+			false,
+		},
+		{
+			"dc",
+			"service/app/leader@dc1",
+			&KVLockQuery{
+				key: "service/app/leader",
+				dc:  "dc1",
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewKVLockQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestKVLockQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Consul-Index", "7")
+		switch r.URL.Path {
+		case "/v1/kv/locked":
+			fmt.Fprint(w, `[{"Key":"locked","Value":"YXBwMQ==","LockIndex":2,"Session":"abcd"}]`)
+		case "/v1/kv/unlocked":
+			fmt.Fprint(w, `[{"Key":"unlocked","Value":"","LockIndex":2}]`)
+		case "/v1/session/info/abcd":
+			fmt.Fprint(w, `[{"ID":"abcd","Name":"app leader","Node":"node1"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	consul, err := consulapi.NewClient(&consulapi.Config{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := NewClientSet()
+	clients.SetConsulClient(consul)
+
+	cases := []struct {
+		name string
+		i    string
+		exp  interface{}
+	}{
+		{
+			"locked",
+			"locked",
+			&KVLock{
+				Key:       "locked",
+				Session:   "abcd",
+				Name:      "app leader",
+				Node:      "node1",
+				LockIndex: 2,
+				Value:     "app1",
+			},
+		},
+		{
+			"unlocked",
+			"unlocked",
+			nil,
+		},
+		{
+			"no_exist",
+			"not/a/real/key",
+			nil,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewKVLockQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, rm, err := d.Fetch(clients, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tc.exp, act)
+			assert.Equal(t, uint64(7), rm.LastIndex)
+		})
+	}
+}
+
+func TestKVLockQuery_String(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"key",
+			"key",
+			"kv.lock(key)",
+		},
+		{
+			"dc",
+			"key@dc1",
+			"kv.lock(key@dc1)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewKVLockQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package dependency
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxSharedBackoff is the longest time requests are held back after a server
+// throttled requests or was unavailable.
+const maxSharedBackoff = 30 * time.Second
+
+// limitTransport is an http.RoundTripper which bounds the number of requests
+// in flight to a server, and holds back all requests when any of them finds
+// the server throttling requests or unavailable, backing off exponentially
+// until a request succeeds. Without it, templates reading many secrets send
+// all of their reads at once on the first render, and each retries on its own
+// when the server pushes back.
+type limitTransport struct {
+	base http.RoundTripper
+
+	// sem holds a slot for each request in flight. It is nil if the number of
+	// requests is not bounded.
+	sem chan struct{}
+
+	// backoff is the base of the exponential backoff.
+	backoff time.Duration
+
+	lock     sync.Mutex
+	failures uint
+	until    time.Time
+}
+
+// newLimitTransport returns a transport which allows at most max requests in
+// flight, or any number if max is 0, and backs off from the given base, or
+// does not back off if it is 0.
+func newLimitTransport(base http.RoundTripper, max int, backoff time.Duration) *limitTransport {
+	t := &limitTransport{base: base, backoff: backoff}
+	if max > 0 {
+		t.sem = make(chan struct{}, max)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.sem != nil {
+		select {
+		case t.sem <- struct{}{}:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		defer func() { <-t.sem }()
+	}
+
+	if d := t.wait(); d > 0 {
+		select {
+		case <-time.After(d):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		// Requests canceled by their caller, like blocking queries which are
+		// stopped, say nothing about the server.
+		if req.Context().Err() != context.Canceled {
+			t.fail(req)
+		}
+		return resp, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		t.fail(req)
+	default:
+		t.succeed()
+	}
+
+	return resp, err
+}
+
+// wait returns how much longer requests are held back.
+func (t *limitTransport) wait() time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if d := t.until.Sub(time.Now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// fail extends the backoff after the given request failed.
+func (t *limitTransport) fail(req *http.Request) {
+	if t.backoff <= 0 {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	d := t.backoff << t.failures
+	if d <= 0 || d > maxSharedBackoff {
+		d = maxSharedBackoff
+	} else {
+		t.failures++
+	}
+
+	if until := time.Now().Add(d); until.After(t.until) {
+		log.Printf("[WARN] (clients) %s %s failed, holding back requests to %s for %s",
+			req.Method, req.URL.Path, req.URL.Host, d)
+		t.until = until
+	}
+}
+
+// succeed resets the backoff after a request succeeded.
+func (t *limitTransport) succeed() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.failures = 0
+}
@@ -0,0 +1,121 @@
+package dependency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimitTransport_concurrency(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, max int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{
+		Transport: newLimitTransport(http.DefaultTransport, 2, 0),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if max != 2 {
+		t.Errorf("expected at most 2 requests in flight, got %d", max)
+	}
+}
+
+func TestLimitTransport_backoff(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	transport := newLimitTransport(http.DefaultTransport, 0, 50*time.Millisecond)
+	client := &http.Client{Transport: transport}
+
+	get := func() (int, time.Duration) {
+		start := time.Now()
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode, time.Since(start)
+	}
+
+	// The first failure holds back the next request for the base, and the
+	// second for twice as long.
+	if code, _ := get(); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", code)
+	}
+	if code, d := get(); code != http.StatusServiceUnavailable || d < 50*time.Millisecond {
+		t.Fatalf("expected 503 after 50ms, got %d after %s", code, d)
+	}
+	if code, d := get(); code != http.StatusOK || d < 100*time.Millisecond {
+		t.Fatalf("expected 200 after 100ms, got %d after %s", code, d)
+	}
+
+	// A success resets the backoff.
+	if transport.failures != 0 {
+		t.Errorf("expected the backoff to be reset, got %d failures", transport.failures)
+	}
+}
+
+func TestLimitTransport_canceled(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	transport := newLimitTransport(http.DefaultTransport, 0, 50*time.Millisecond)
+	client := &http.Client{Transport: transport}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	// A request canceled by its caller does not hold back other requests.
+	if _, err := client.Do(req.WithContext(ctx)); err == nil {
+		t.Fatal("expected the request to be canceled")
+	}
+	if d := transport.wait(); d != 0 {
+		t.Errorf("expected requests not to be held back, got %s", d)
+	}
+}
@@ -0,0 +1,121 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ClientCredentialsInput is used as input to the NewClientCredentialsTokenSource
+// function.
+type ClientCredentialsInput struct {
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	TokenURL     string
+
+	// HTTPClient is the client used to request tokens. If nil, a client with
+	// a 30s timeout is used.
+	HTTPClient *http.Client
+}
+
+// clientCredentialsTokenSource is an oauth2.TokenSource which obtains tokens
+// with the OAuth2 client credentials grant (RFC 6749 section 4.4).
+type clientCredentialsTokenSource struct {
+	clientID     string
+	clientSecret string
+	scopes       []string
+	tokenURL     string
+	httpClient   *http.Client
+}
+
+// NewClientCredentialsTokenSource returns a token source which obtains bearer
+// tokens from the token endpoint of an OAuth2 or OIDC provider with the client
+// credentials grant. Tokens are cached and a new one is requested shortly
+// before the cached token expires.
+func NewClientCredentialsTokenSource(i *ClientCredentialsInput) (oauth2.TokenSource, error) {
+	if i.TokenURL == "" {
+		return nil, fmt.Errorf("oauth2: missing token_url")
+	}
+	if _, err := url.Parse(i.TokenURL); err != nil {
+		return nil, fmt.Errorf("oauth2: invalid token_url: %s", err)
+	}
+	if i.ClientID == "" {
+		return nil, fmt.Errorf("oauth2: missing client_id")
+	}
+
+	httpClient := i.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return oauth2.ReuseTokenSource(nil, &clientCredentialsTokenSource{
+		clientID:     i.ClientID,
+		clientSecret: i.ClientSecret,
+		scopes:       i.Scopes,
+		tokenURL:     i.TokenURL,
+		httpClient:   httpClient,
+	}), nil
+}
+
+// Token implements oauth2.TokenSource by requesting a new token.
+func (s *clientCredentialsTokenSource) Token() (*oauth2.Token, error) {
+	v := url.Values{}
+	v.Set("grant_type", "client_credentials")
+	if len(s.scopes) > 0 {
+		v.Set("scope", strings.Join(s.scopes, " "))
+	}
+
+	req, err := http.NewRequest("POST", s.tokenURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(s.clientID), url.QueryEscape(s.clientSecret))
+
+	log.Printf("[TRACE] (clients) requesting oauth2 token from %s", s.tokenURL)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: requesting token: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: reading token response: %s", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("oauth2: requesting token: %s: %s",
+			resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var tr struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, fmt.Errorf("oauth2: decoding token response: %s", err)
+	}
+	if tr.AccessToken == "" {
+		return nil, fmt.Errorf("oauth2: token response has no access_token")
+	}
+
+	token := &oauth2.Token{
+		AccessToken: tr.AccessToken,
+		TokenType:   tr.TokenType,
+	}
+	if tr.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
@@ -0,0 +1,98 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewClientCredentialsTokenSource(t *testing.T) {
+	t.Parallel()
+
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "id" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if v := r.FormValue("grant_type"); v != "client_credentials" {
+			t.Errorf("expected grant_type to be %q, got %q", "client_credentials", v)
+		}
+		if v := r.FormValue("scope"); v != "consul read" {
+			t.Errorf("expected scope to be %q, got %q", "consul read", v)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, requests)
+	}))
+	defer ts.Close()
+
+	src, err := NewClientCredentialsTokenSource(&ClientCredentialsInput{
+		ClientID:     "id",
+		ClientSecret: "secret",
+		Scopes:       []string{"consul", "read"},
+		TokenURL:     ts.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := src.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.AccessToken != "token-1" {
+			t.Errorf("expected %q to be %q", token.AccessToken, "token-1")
+		}
+		if token.Type() != "Bearer" {
+			t.Errorf("expected %q to be %q", token.Type(), "Bearer")
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("expected the token to be reused, got %d requests", requests)
+	}
+
+	bad, err := NewClientCredentialsTokenSource(&ClientCredentialsInput{
+		ClientID:     "id",
+		ClientSecret: "wrong",
+		TokenURL:     ts.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bad.Token(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestNewClientCredentialsTokenSource_invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    *ClientCredentialsInput
+	}{
+		{
+			"missing_token_url",
+			&ClientCredentialsInput{ClientID: "id"},
+		},
+		{
+			"missing_client_id",
+			&ClientCredentialsInput{TokenURL: "https://idp.example.com/token"},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if _, err := NewClientCredentialsTokenSource(tc.i); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package dependency
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Preflight checks that the given dependency can be read with the credentials
+// of the clients, without watching it. Consul and local dependencies are
+// fetched once, which does not block the first time. Vault dependencies are
+// checked against the capabilities of the token instead of being read,
+// because reading dynamic secrets issues new credentials and writing has side
+// effects.
+func Preflight(d Dependency, clients *ClientSet) error {
+	switch d := d.(type) {
+	case *VaultReadQuery:
+		return checkVaultCapabilities(clients, d.path, "read")
+	case *VaultVersionsQuery:
+		if err := checkVaultCapabilities(clients, d.metadataPath, "read"); err != nil {
+			return err
+		}
+		return checkVaultCapabilities(clients, d.path, "read")
+	case *VaultListQuery:
+		return checkVaultCapabilities(clients, d.path, "list")
+	case *VaultWriteQuery:
+		return checkVaultCapabilities(clients, d.path, "create", "update")
+	case *VaultGenerateQuery:
+		if err := checkVaultCapabilities(clients, d.path, "read"); err != nil {
+			return err
+		}
+		return checkVaultCapabilities(clients, d.path, "create")
+	case *VaultTokenQuery:
+		// A token may always renew itself.
+		return nil
+	case *TemplateOutputQuery:
+		// The output of templates is not read from anywhere.
+		return nil
+	}
+
+	log.Printf("[TRACE] %s: preflight fetch", d)
+	_, _, err := d.Fetch(clients, nil)
+	return err
+}
+
+// checkVaultCapabilities returns an error unless the Vault token has one of
+// the given capabilities on the path.
+func checkVaultCapabilities(clients *ClientSet, path string, any ...string) error {
+	log.Printf("[TRACE] preflight: checking capabilities of %s", path)
+
+	caps, err := clients.Vault().Sys().CapabilitiesSelf(path)
+	if err != nil {
+		return fmt.Errorf("failed checking capabilities on %s: %s", path, err)
+	}
+
+	for _, c := range caps {
+		if c == "root" {
+			return nil
+		}
+		for _, want := range any {
+			if c == want {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("permission denied: token needs %s capability on %s, has %s",
+		strings.Join(any, " or "), path, strings.Join(caps, ", "))
+}
@@ -0,0 +1,132 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	t.Parallel()
+
+	capabilities := map[string][]string{
+		"secret/foo":           {"read"},
+		"secret/root":          {"root"},
+		"secret/data/foo":      {"read"},
+		"secret/metadata/foo":  {"deny"},
+		"secret/list":          {"list", "read"},
+		"secret/generated":     {"create", "read"},
+		"pki/issue/example":    {"update"},
+		"database/creds/admin": {"deny"},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/sys/capabilities-self" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct{ Path string }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		caps, ok := capabilities[body.Path]
+		if !ok {
+			caps = []string{"deny"}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"capabilities": caps,
+		})
+	}))
+	defer ts.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateVaultClient(&CreateVaultClientInput{
+		Address: ts.URL,
+		Token:   "s.token",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	mustDep := func(d Dependency, err error) Dependency {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	cases := []struct {
+		name string
+		d    Dependency
+		err  string
+	}{
+		{
+			"read",
+			mustDep(NewVaultReadQuery("secret/foo")),
+			"",
+		},
+		{
+			"read_root",
+			mustDep(NewVaultReadQuery("secret/root")),
+			"",
+		},
+		{
+			"read_denied",
+			mustDep(NewVaultReadQuery("database/creds/admin")),
+			"permission denied: token needs read capability on database/creds/admin, has deny",
+		},
+		{
+			"versions_metadata_denied",
+			mustDep(NewVaultVersionsQuery("secret/data/foo", 2)),
+			"token needs read capability on secret/metadata/foo",
+		},
+		{
+			"list",
+			mustDep(NewVaultListQuery("secret/list")),
+			"",
+		},
+		{
+			"write",
+			mustDep(NewVaultWriteQuery("pki/issue/example", nil)),
+			"",
+		},
+		{
+			"write_denied",
+			mustDep(NewVaultWriteQuery("secret/foo", nil)),
+			"token needs create or update capability on secret/foo, has read",
+		},
+		{
+			"generate",
+			mustDep(NewVaultGenerateQuery("secret/generated")),
+			"",
+		},
+		{
+			"generate_denied",
+			mustDep(NewVaultGenerateQuery("secret/foo")),
+			"token needs create capability on secret/foo, has read",
+		},
+		{
+			"token",
+			mustDep(NewVaultTokenQuery()),
+			"",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			err := Preflight(tc.d, clients)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package dependency
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// retryAfterTransport is an http.RoundTripper which records how long the
+// server asked clients to back off when it throttles a request, so retries
+// can wait at least that long instead of aggravating the throttling. Delays
+// longer than max, if it is not 0, are shortened to max, so a misbehaving
+// server cannot stall the retries indefinitely.
+type retryAfterTransport struct {
+	base http.RoundTripper
+	max  time.Duration
+
+	lock  sync.Mutex
+	until time.Time
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		now := time.Now()
+		if d, ok := parseRetryAfter(resp.Header, now); ok {
+			if t.max > 0 && d > t.max {
+				d = t.max
+			}
+			t.lock.Lock()
+			if until := now.Add(d); until.After(t.until) {
+				t.until = until
+			}
+			t.lock.Unlock()
+		}
+	}
+
+	return resp, err
+}
+
+// wait returns how much longer the server asked clients to back off, or 0 if
+// it has not throttled any request or the time has passed.
+func (t *retryAfterTransport) wait() time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if d := t.until.Sub(time.Now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// parseRetryAfter returns the delay requested by the headers of a throttled
+// response. The Retry-After header may be a number of seconds or an HTTP date.
+// If it is missing, the X-RateLimit-Reset header sent by Vault rate limit
+// quotas, which is the number of seconds until the quota resets, is used.
+func parseRetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := strings.TrimSpace(h.Get("Retry-After")); v != "" {
+		if secs, err := strconv.ParseUint(v, 10, 32); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			if d := t.Sub(now); d > 0 {
+				return d, true
+			}
+			return 0, true
+		}
+	}
+
+	if v := strings.TrimSpace(h.Get("X-RateLimit-Reset")); v != "" {
+		if secs, err := strconv.ParseUint(v, 10, 32); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,121 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		exp     time.Duration
+		ok      bool
+	}{
+		{
+			"empty",
+			nil,
+			0,
+			false,
+		},
+		{
+			"seconds",
+			map[string]string{"Retry-After": "30"},
+			30 * time.Second,
+			true,
+		},
+		{
+			"date",
+			map[string]string{"Retry-After": "Tue, 02 Jan 2018 03:05:05 GMT"},
+			1 * time.Minute,
+			true,
+		},
+		{
+			"date_past",
+			map[string]string{"Retry-After": "Tue, 02 Jan 2018 03:00:00 GMT"},
+			0,
+			true,
+		},
+		{
+			"invalid",
+			map[string]string{"Retry-After": "soon"},
+			0,
+			false,
+		},
+		{
+			"rate_limit_reset",
+			map[string]string{"X-RateLimit-Reset": "5"},
+			5 * time.Second,
+			true,
+		},
+		{
+			"retry_after_precedence",
+			map[string]string{"Retry-After": "2", "X-RateLimit-Reset": "5"},
+			2 * time.Second,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			h := make(http.Header)
+			for k, v := range tc.headers {
+				h.Set(k, v)
+			}
+
+			d, ok := parseRetryAfter(h, now)
+			if ok != tc.ok {
+				t.Fatalf("expected ok to be %t, got %t", tc.ok, ok)
+			}
+			if d != tc.exp {
+				t.Errorf("expected %s, got %s", tc.exp, d)
+			}
+		})
+	}
+}
+
+func TestRetryAfterTransport(t *testing.T) {
+	t.Parallel()
+
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	transport := &retryAfterTransport{base: &http.Transport{}}
+	client := &http.Client{Transport: transport}
+
+	if _, err := client.Get(ts.URL); err != nil {
+		t.Fatal(err)
+	}
+	if d := transport.wait(); d != 0 {
+		t.Errorf("expected successful responses to be ignored, got %s", d)
+	}
+
+	status = http.StatusTooManyRequests
+	if _, err := client.Get(ts.URL); err != nil {
+		t.Fatal(err)
+	}
+	if d := transport.wait(); d <= 9*time.Second || d > 10*time.Second {
+		t.Errorf("expected a wait of about 10s, got %s", d)
+	}
+
+	// Longer delays than the maximum are shortened to the maximum.
+	transport = &retryAfterTransport{base: &http.Transport{}, max: time.Second}
+	client = &http.Client{Transport: transport}
+	if _, err := client.Get(ts.URL); err != nil {
+		t.Fatal(err)
+	}
+	if d := transport.wait(); d <= 0 || d > time.Second {
+		t.Errorf("expected a wait of at most 1s, got %s", d)
+	}
+}
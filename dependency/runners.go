@@ -0,0 +1,169 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultRunnersService is the default name of the Consul service instances
+// of Consul Template register themselves as.
+const DefaultRunnersService = "consul-template"
+
+var (
+	// Ensure implements
+	_ Dependency = (*RunnersQuery)(nil)
+
+	// RunnersQueryRe is the regular expression to use.
+	RunnersQueryRe = regexp.MustCompile(`\A(` + nameRe + `)?` + dcRe + `\z`)
+)
+
+func init() {
+	gob.Register([]*TemplateRunner{})
+}
+
+// TemplateRunner is a running instance of Consul Template which registered
+// itself in Consul.
+type TemplateRunner struct {
+	// ID is the ID of the service instance, and Node and Address the node it
+	// runs on and its address.
+	ID      string
+	Node    string
+	Address string
+	Tags    ServiceTags
+
+	// Index is the position of the instance in the list of instances sorted
+	// by ID, which is stable while the set of instances does not change.
+	Index int
+
+	// Self is true for the instance of the process rendering the template.
+	Self bool
+}
+
+// RunnerID returns the service ID this process registers itself with in the
+// given service. It is unique per process, so several instances may run on a
+// node.
+func RunnerID(service string) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%s-%d", service, host, os.Getpid())
+}
+
+// RunnersQuery queries the running instances of Consul Template which
+// registered themselves in a service.
+type RunnersQuery struct {
+	stopCh chan struct{}
+
+	dc      string
+	service string
+}
+
+// NewRunnersQuery parses a string into a dependency. An empty name is the
+// default service.
+func NewRunnersQuery(s string) (*RunnersQuery, error) {
+	if !RunnersQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("runners: invalid format: %q", s)
+	}
+
+	m := regexpMatch(RunnersQueryRe, s)
+	service := m["name"]
+	if service == "" {
+		service = DefaultRunnersService
+	}
+
+	return &RunnersQuery{
+		stopCh:  make(chan struct{}, 1),
+		dc:      m["dc"],
+		service: service,
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// passing instances of the service, sorted by ID.
+func (d *RunnersQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{
+		Datacenter: d.dc,
+	})
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     "/v1/health/service/" + d.service,
+		RawQuery: opts.String(),
+	})
+
+	entries, qm, err := clients.Consul().Health().Service(d.service, "", true, opts.ToConsulOpts())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	self := RunnerID(d.service)
+	list := make([]*TemplateRunner, 0, len(entries))
+	for _, entry := range entries {
+		address := entry.Service.Address
+		if address == "" {
+			address = entry.Node.Address
+		}
+
+		list = append(list, &TemplateRunner{
+			ID:      entry.Service.ID,
+			Node:    entry.Node.Node,
+			Address: address,
+			Tags:    ServiceTags(deepCopyAndSortTags(entry.Service.Tags)),
+			Self:    entry.Service.ID == self,
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	for i, r := range list {
+		r.Index = i
+	}
+
+	log.Printf("[TRACE] %s: returned %d results", d, len(list))
+
+	rm := &ResponseMetadata{
+		LastIndex:   qm.LastIndex,
+		LastContact: qm.LastContact,
+	}
+
+	return list, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable. Which instance
+// is this one differs per process, so it is not.
+func (d *RunnersQuery) CanShare() bool {
+	return false
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *RunnersQuery) String() string {
+	service := d.service
+	if d.dc != "" {
+		service = service + "@" + d.dc
+	}
+	return fmt.Sprintf("runners(%s)", service)
+}
+
+// Stop halts the dependency's fetch function.
+func (d *RunnersQuery) Stop() {
+	close(d.stopCh)
+}
+
+// Type returns the type of this dependency.
+func (d *RunnersQuery) Type() Type {
+	return TypeConsul
+}
@@ -0,0 +1,175 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunnersQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *RunnersQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			&RunnersQuery{
+				service: DefaultRunnersService,
+			},
+			false,
+		},
+		{
+			"dc_only",
+			"@dc1",
+			&RunnersQuery{
+				dc:      "dc1",
+				service: DefaultRunnersService,
+			},
+			false,
+		},
+		{
+			"service",
+			"edge-templates",
+			&RunnersQuery{
+				service: "edge-templates",
+			},
+			false,
+		},
+		{
+			"service_dc",
+			"edge-templates@dc1",
+			&RunnersQuery{
+				dc:      "dc1",
+				service: "edge-templates",
+			},
+			false,
+		},
+		{
+			"invalid",
+			"edge templates",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewRunnersQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestRunnersQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	self := RunnerID(DefaultRunnersService)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/consul-template" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if _, ok := r.URL.Query()["passing"]; !ok {
+			t.Errorf("expected only passing instances to be queried")
+		}
+
+		w.Header().Set("X-Consul-Index", "7")
+		fmt.Fprintf(w, `[
+			{"Node":{"Node":"node2","Address":"10.0.0.2"},"Service":{"ID":"consul-template-node2-1","Tags":["b","a"]}},
+			{"Node":{"Node":"node1","Address":"10.0.0.1"},"Service":{"ID":%q,"Address":"10.1.0.1"}}
+		]`, self)
+	}))
+	defer ts.Close()
+
+	consul, err := consulapi.NewClient(&consulapi.Config{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := NewClientSet()
+	clients.SetConsulClient(consul)
+
+	d, err := NewRunnersQuery("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, rm, err := d.Fetch(clients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := &TemplateRunner{
+		ID:      "consul-template-node2-1",
+		Node:    "node2",
+		Address: "10.0.0.2",
+		Tags:    ServiceTags([]string{"a", "b"}),
+	}
+	mine := &TemplateRunner{
+		ID:      self,
+		Node:    "node1",
+		Address: "10.1.0.1",
+		Tags:    ServiceTags([]string{}),
+		Self:    true,
+	}
+	exp := []*TemplateRunner{mine, other}
+	if self > other.ID {
+		exp = []*TemplateRunner{other, mine}
+	}
+	for i, r := range exp {
+		r.Index = i
+	}
+
+	assert.Equal(t, exp, act)
+	assert.Equal(t, uint64(7), rm.LastIndex)
+}
+
+func TestRunnersQuery_String(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"default",
+			"",
+			"runners(consul-template)",
+		},
+		{
+			"service_dc",
+			"edge@dc1",
+			"runners(edge@dc1)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewRunnersQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
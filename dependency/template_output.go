@@ -0,0 +1,58 @@
+package dependency
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*TemplateOutputQuery)(nil)
+)
+
+// TemplateOutputQuery is the dependency on the rendered output of another
+// template, identified by its name. Its data is not fetched, but supplied by
+// the runner whenever the named template renders.
+type TemplateOutputQuery struct {
+	name string
+}
+
+// NewTemplateOutputQuery creates a dependency on the output of the template
+// with the given name.
+func NewTemplateOutputQuery(s string) (*TemplateOutputQuery, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("template.output: invalid format: %q", s)
+	}
+
+	return &TemplateOutputQuery{name: s}, nil
+}
+
+// Fetch always returns an error, since the output of templates is supplied by
+// the runner instead of being watched.
+func (d *TemplateOutputQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	return nil, nil, fmt.Errorf("%s: template output cannot be fetched", d)
+}
+
+// Name returns the name of the template whose output this is.
+func (d *TemplateOutputQuery) Name() string {
+	return d.name
+}
+
+// CanShare returns if this dependency is shareable.
+func (d *TemplateOutputQuery) CanShare() bool {
+	return false
+}
+
+// Stop is a no-op, since the output of templates is never fetched.
+func (d *TemplateOutputQuery) Stop() {}
+
+// String returns the human-friendly version of this dependency.
+func (d *TemplateOutputQuery) String() string {
+	return fmt.Sprintf("template.output(%s)", d.name)
+}
+
+// Type returns the type of this dependency.
+func (d *TemplateOutputQuery) Type() Type {
+	return TypeInternal
+}
@@ -0,0 +1,77 @@
+package dependency
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTemplateOutputQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *TemplateOutputQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"name",
+			"certs",
+			&TemplateOutputQuery{
+				name: "certs",
+			},
+			false,
+		},
+		{
+			"spaces",
+			" certs ",
+			&TemplateOutputQuery{
+				name: "certs",
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewTemplateOutputQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestTemplateOutputQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewTemplateOutputQuery("certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := d.Fetch(nil, nil); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestTemplateOutputQuery_String(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewTemplateOutputQuery("certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "template.output(certs)", d.String())
+}
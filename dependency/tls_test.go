@@ -0,0 +1,77 @@
+package dependency
+
+import (
+	"crypto/tls"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigureTLSParams(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		minVersion   string
+		cipherSuites []string
+		exp          *tls.Config
+		err          bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			&tls.Config{},
+			false,
+		},
+		{
+			"min_version",
+			"TLS12",
+			nil,
+			&tls.Config{MinVersion: tls.VersionTLS12},
+			false,
+		},
+		{
+			"cipher_suites",
+			"",
+			[]string{
+				"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+				"tls_ecdhe_ecdsa_with_aes_256_gcm_sha384",
+			},
+			&tls.Config{CipherSuites: []uint16{
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			}},
+			false,
+		},
+		{
+			"invalid_min_version",
+			"ssl3",
+			nil,
+			nil,
+			true,
+		},
+		{
+			"invalid_cipher_suite",
+			"",
+			[]string{"TLS_RSA_WITH_NOPE"},
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			var act tls.Config
+			err := configureTLSParams(&act, tc.minVersion, tc.cipherSuites)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if tc.err {
+				return
+			}
+			assert.Equal(t, tc.exp, &act)
+		})
+	}
+}
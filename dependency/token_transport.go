@@ -0,0 +1,100 @@
+package dependency
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// consulTokenHeader is the header which carries the ACL token of a request to
+// Consul.
+const consulTokenHeader = "X-Consul-Token"
+
+// tokenTransport is an http.RoundTripper which sends the token of the class of
+// each request to Consul, and retries a request which is denied with each of
+// the fallback tokens in order until one is allowed.
+type tokenTransport struct {
+	base http.RoundTripper
+
+	// token is the default token, which the API client sends with each request
+	// that does not set its own. Only requests with the default token are
+	// given the token of their class.
+	token string
+
+	// agent and replication are the tokens of requests to the agent and ACL
+	// endpoints, or empty to use the default token.
+	agent       string
+	replication string
+
+	// fallbacks are the tokens to retry denied requests with.
+	fallbacks []string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := req.Header.Get(consulTokenHeader)
+	if token == t.token {
+		token = t.classToken(req.URL.Path)
+	}
+
+	resp, err := t.base.RoundTrip(withConsulToken(req, token, req.Body))
+	for i, fallback := range t.fallbacks {
+		if err != nil || resp.StatusCode != http.StatusForbidden {
+			break
+		}
+		if fallback == token {
+			continue
+		}
+
+		// A request whose body cannot be read again cannot be retried.
+		body := req.Body
+		if body != nil && body != http.NoBody {
+			if req.GetBody == nil {
+				break
+			}
+			if body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		log.Printf("[DEBUG] (clients) consul: %s %s was denied, retrying with "+
+			"fallback token %d", req.Method, req.URL.Path, i+1)
+		resp, err = t.base.RoundTrip(withConsulToken(req, fallback, body))
+	}
+	return resp, err
+}
+
+// classToken returns the token for requests to the given path.
+func (t *tokenTransport) classToken(path string) string {
+	switch {
+	case t.agent != "" && strings.HasPrefix(path, "/v1/agent/"):
+		return t.agent
+	case t.replication != "" && strings.HasPrefix(path, "/v1/acl/"):
+		return t.replication
+	default:
+		return t.token
+	}
+}
+
+// withConsulToken returns a copy of the request with the given token and body,
+// since a RoundTripper must not modify the request.
+func withConsulToken(req *http.Request, token string, body io.ReadCloser) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.Body = body
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	if token != "" {
+		r.Header.Set(consulTokenHeader, token)
+	} else {
+		r.Header.Del(consulTokenHeader)
+	}
+	return r
+}
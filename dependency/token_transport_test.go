@@ -0,0 +1,144 @@
+package dependency
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenTransport_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	// The server allows each path with the listed tokens only, and records the
+	// tokens of the requests in order.
+	allowed := map[string][]string{
+		"/v1/kv/foo":     {"default"},
+		"/v1/kv/bar":     {"fallback2"},
+		"/v1/kv/denied":  {},
+		"/v1/agent/self": {"agent"},
+		"/v1/acl/info":   {"replication"},
+		"/v1/session":    {"fallback1"},
+	}
+	var seen []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(consulTokenHeader)
+		body, _ := ioutil.ReadAll(r.Body)
+		seen = append(seen, token+string(body))
+		for _, a := range allowed[r.URL.Path] {
+			if a == token {
+				return
+			}
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		token  string
+		code   int
+		seen   []string
+	}{
+		{
+			"default",
+			"GET",
+			"/v1/kv/foo",
+			"",
+			"default",
+			http.StatusOK,
+			[]string{"default"},
+		},
+		{
+			"agent",
+			"GET",
+			"/v1/agent/self",
+			"",
+			"default",
+			http.StatusOK,
+			[]string{"agent"},
+		},
+		{
+			"replication",
+			"GET",
+			"/v1/acl/info",
+			"",
+			"default",
+			http.StatusOK,
+			[]string{"replication"},
+		},
+		{
+			"request_token",
+			"GET",
+			"/v1/kv/foo",
+			"",
+			"other",
+			http.StatusForbidden,
+			[]string{"other", "fallback1", "fallback2"},
+		},
+		{
+			"fallback_in_order",
+			"GET",
+			"/v1/kv/bar",
+			"",
+			"default",
+			http.StatusOK,
+			[]string{"default", "fallback1", "fallback2"},
+		},
+		{
+			"fallback_body",
+			"PUT",
+			"/v1/session",
+			"{}",
+			"default",
+			http.StatusOK,
+			[]string{"default{}", "fallback1{}"},
+		},
+		{
+			"denied",
+			"GET",
+			"/v1/kv/denied",
+			"",
+			"default",
+			http.StatusForbidden,
+			[]string{"default", "fallback1", "fallback2"},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			seen = nil
+
+			tr := &tokenTransport{
+				base:        http.DefaultTransport,
+				token:       "default",
+				agent:       "agent",
+				replication: "replication",
+				fallbacks:   []string{"fallback1", "fallback2"},
+			}
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set(consulTokenHeader, tc.token)
+
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			assert.Equal(t, tc.code, resp.StatusCode)
+			assert.Equal(t, tc.seen, seen)
+			assert.Equal(t, tc.token, req.Header.Get(consulTokenHeader))
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package dependency
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+const (
+	// vaultBatchTTL is how long the secrets read by a batch are kept for the
+	// reads which have not taken theirs yet, and how long a failed batch keeps
+	// the reads under its path from being batched again.
+	vaultBatchTTL = 30 * time.Second
+
+	// vaultBatchMaxSecrets is the largest number of listed secrets which are
+	// read by a batch. Larger paths are not batched, so a template reading a
+	// few secrets of a large path does not read all of them.
+	vaultBatchMaxSecrets = 128
+
+	// vaultBatchWorkers is the number of reads of a batch in flight when the
+	// number of requests to Vault is not bounded.
+	vaultBatchWorkers = 16
+)
+
+// vaultBatch is the result of listing a path and reading its secrets.
+type vaultBatch struct {
+	// done is closed when the secrets were read.
+	done chan struct{}
+
+	// secrets are the secrets which were read and not taken yet, by their
+	// read path. It is nil if the path could not be listed, in which case the
+	// secrets are read on their own.
+	secrets map[string]*vaultapi.Secret
+
+	expires time.Time
+}
+
+// vaultBatcher coalesces the first reads of the secrets under the same path:
+// the first read lists the path and reads all of the listed secrets in
+// parallel, and the reads of the other secrets take theirs from the batch
+// instead of each being sent on its own as the template discovers it.
+type vaultBatcher struct {
+	sync.Mutex
+
+	// workers is the number of reads in flight.
+	workers int
+
+	// batches are the batches by list path.
+	batches map[string]*vaultBatch
+}
+
+// newVaultBatcher creates a batcher which sends the given number of reads in
+// parallel, or a default number if it is 0.
+func newVaultBatcher(workers int) *vaultBatcher {
+	if workers <= 0 {
+		workers = vaultBatchWorkers
+	}
+	return &vaultBatcher{
+		workers: workers,
+		batches: make(map[string]*vaultBatch),
+	}
+}
+
+// vaultBatchRead returns the secret at the given read path from the batch of
+// its parent path, listing the parent path and reading its secrets if it has
+// no batch yet. It returns false if reads are not batched, if the parent path
+// cannot be listed, or if the secret was not listed, in which case the
+// secret must be read on its own.
+func (c *ClientSet) vaultBatchRead(path string) (*vaultapi.Secret, bool) {
+	c.RLock()
+	var batcher *vaultBatcher
+	if c.vault != nil {
+		batcher = c.vault.batcher
+	}
+	c.RUnlock()
+	if batcher == nil {
+		return nil, false
+	}
+
+	idx := strings.LastIndex(path, "/")
+	if idx == -1 {
+		return nil, false
+	}
+	dir := path[:idx]
+
+	// Secrets on KV v2 mounts are listed under their metadata path.
+	list := dir
+	if m := c.vaultKVMount(path); m.detected {
+		if rest := strings.TrimPrefix(dir+"/", m.path+"data/"); rest != dir+"/" {
+			list = strings.TrimSuffix(m.path+"metadata/"+rest, "/")
+		}
+	}
+
+	now := time.Now()
+	batcher.Lock()
+	b, ok := batcher.batches[list]
+	if ok && !b.expires.IsZero() && !now.Before(b.expires) {
+		delete(batcher.batches, list)
+		ok = false
+	}
+	if !ok {
+		b = &vaultBatch{done: make(chan struct{})}
+		batcher.batches[list] = b
+	}
+	batcher.Unlock()
+
+	if !ok {
+		secrets, err := batcher.read(c, list, dir)
+		if err != nil {
+			log.Printf("[DEBUG] (clients) vault: not batching the reads under %q: %s",
+				dir, err)
+		}
+
+		batcher.Lock()
+		b.secrets = secrets
+		b.expires = time.Now().Add(vaultBatchTTL)
+		batcher.Unlock()
+		close(b.done)
+	}
+
+	<-b.done
+
+	batcher.Lock()
+	defer batcher.Unlock()
+
+	secret, ok := b.secrets[path]
+	if ok {
+		delete(b.secrets, path)
+	}
+	return secret, ok
+}
+
+// read lists the given path and reads the listed secrets under the given
+// directory in parallel. Secrets which cannot be read are left out.
+func (b *vaultBatcher) read(clients *ClientSet, list, dir string) (map[string]*vaultapi.Secret, error) {
+	log.Printf("[TRACE] (clients) vault: LIST %s", list)
+
+	secret, err := clients.Vault().Logical().List(list)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("no secrets listed")
+	}
+
+	raw, _ := secret.Data["keys"].([]interface{})
+	var keys []string
+	for _, k := range raw {
+		if s, ok := k.(string); ok && !strings.HasSuffix(s, "/") {
+			keys = append(keys, s)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no secrets listed")
+	}
+	if len(keys) > vaultBatchMaxSecrets {
+		return nil, fmt.Errorf("%d secrets listed, more than %d",
+			len(keys), vaultBatchMaxSecrets)
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	secrets := make(map[string]*vaultapi.Secret, len(keys))
+	sem := make(chan struct{}, b.workers)
+	for _, k := range keys {
+		path := dir + "/" + k
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			s, err := clients.Vault().Logical().Read(path)
+			if err != nil {
+				log.Printf("[DEBUG] (clients) vault: batch read of %q failed: %s", path, err)
+				return
+			}
+			if s == nil {
+				return
+			}
+
+			lock.Lock()
+			secrets[path] = s
+			lock.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	log.Printf("[TRACE] (clients) vault: batch read %d of %d secrets under %q",
+		len(secrets), len(keys), dir)
+
+	return secrets, nil
+}
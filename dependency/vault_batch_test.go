@@ -0,0 +1,106 @@
+package dependency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaultBatchRead(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	requests := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		if r.URL.Query().Get("list") == "true" {
+			path = "LIST " + path
+		}
+		lock.Lock()
+		requests[path]++
+		lock.Unlock()
+
+		switch {
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/secret/"):
+			w.Write([]byte(`{"data": {"path": "secret/", "type": "kv", "options": {"version": "2"}}}`))
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/kv/"):
+			w.Write([]byte(`{"data": {"path": "kv/", "type": "kv", "options": null}}`))
+		case path == "LIST secret/metadata/app":
+			w.Write([]byte(`{"data": {"keys": ["a", "b", "nested/"]}}`))
+		case path == "secret/data/app/a", path == "secret/data/app/b":
+			w.Write([]byte(`{"data": {"data": {"name": "` + path + `"}}}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+		}
+	}))
+	defer ts.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateVaultClient(&CreateVaultClientInput{
+		Address:         ts.URL,
+		Token:           "s.token",
+		KVMountCacheTTL: time.Minute,
+		BatchReads:      true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first read lists the path and reads all of its secrets, and the
+	// other reads take theirs from the batch.
+	for _, p := range []string{"secret/data/app/a", "secret/data/app/b"} {
+		secret, ok := clients.vaultBatchRead(p)
+		if !ok {
+			t.Fatalf("expected %q to be batched", p)
+		}
+		data := secret.Data["data"].(map[string]interface{})
+		assert.Equal(t, p, data["name"])
+	}
+
+	// Secrets are only taken once, and secrets which are not listed are read
+	// on their own.
+	for _, p := range []string{"secret/data/app/a", "secret/data/app/c"} {
+		if _, ok := clients.vaultBatchRead(p); ok {
+			t.Errorf("expected %q not to be batched", p)
+		}
+	}
+
+	// Paths which cannot be listed are read on their own, and are not listed
+	// again.
+	for _, p := range []string{"kv/app/a", "kv/app/b"} {
+		if _, ok := clients.vaultBatchRead(p); ok {
+			t.Errorf("expected %q not to be batched", p)
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, map[string]int{
+		"sys/internal/ui/mounts/secret/data/app/a": 1,
+		"sys/internal/ui/mounts/kv/app/a":          1,
+		"LIST secret/metadata/app":                 1,
+		"LIST kv/app":                              1,
+		"secret/data/app/a":                        1,
+		"secret/data/app/b":                        1,
+	}, requests)
+}
+
+func TestVaultBatchRead_disabled(t *testing.T) {
+	t.Parallel()
+
+	clients := NewClientSet()
+	if err := clients.CreateVaultClient(&CreateVaultClientInput{
+		Address: "http://127.0.0.1:0",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := clients.vaultBatchRead("secret/foo"); ok {
+		t.Errorf("expected reads not to be batched")
+	}
+}
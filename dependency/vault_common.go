@@ -1,6 +1,12 @@
 package dependency
 
-import "time"
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
 
 var (
 	// VaultDefaultLeaseDuration is the default lease duration in seconds.
@@ -17,6 +23,49 @@ type Secret struct {
 	// Data is the actual contents of the secret. The format of the data
 	// is arbitrary and up to the secret backend.
 	Data map[string]interface{}
+
+	// Previous is the secret this secret replaced while its lease is still
+	// valid, for reads with an overlap.
+	Previous *Secret
+}
+
+// vaultSecretVersion returns the version of a KV v2 secret from the metadata
+// in the response data, or 0 if the secret is not versioned.
+func vaultSecretVersion(data map[string]interface{}) int {
+	metadata, ok := data["metadata"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	switch v := metadata["version"].(type) {
+	case json.Number:
+		i, _ := v.Int64()
+		return int(i)
+	case float64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+// readVaultVersion reads the given version of a KV v2 secret. It returns nil
+// if the version does not exist or was deleted.
+func readVaultVersion(clients *ClientSet, path string, version int) (*vaultapi.Secret, error) {
+	r := clients.Vault().NewRequest("GET", "/v1/"+path)
+	r.Params.Set("version", strconv.Itoa(version))
+	resp, err := clients.Vault().RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == 404 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return vaultapi.ParseSecret(resp.Body)
 }
 
 // leaseDurationOrDefault returns a value or the default lease duration.
@@ -1,5 +1,40 @@
 package dependency
 
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
 func init() {
 	VaultDefaultLeaseDuration = 0
 }
+
+func TestVaultSecretVersion(t *testing.T) {
+	cases := []struct {
+		name string
+		data map[string]interface{}
+		exp  int
+	}{
+		{
+			"unversioned",
+			map[string]interface{}{"foo": "bar"},
+			0,
+		},
+		{
+			"json_number",
+			map[string]interface{}{
+				"metadata": map[string]interface{}{"version": json.Number("4")},
+			},
+			4,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := vaultSecretVersion(tc.data); act != tc.exp {
+				t.Errorf("expected %d to be %d", act, tc.exp)
+			}
+		})
+	}
+}
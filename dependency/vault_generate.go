@@ -0,0 +1,245 @@
+package dependency
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*VaultGenerateQuery)(nil)
+
+	// VaultGenerateCharsets are the characters generated secrets are made of,
+	// by the name of the charset option.
+	VaultGenerateCharsets = map[string]string{
+		"alpha":    "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+		"alphanum": "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+		"ascii":    "!\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~",
+		"hex":      "0123456789abcdef",
+		"numeric":  "0123456789",
+	}
+)
+
+const (
+	// vaultGenerateLength is the default and vaultGenerateMaxLength the
+	// largest length of generated secrets.
+	vaultGenerateLength    = 32
+	vaultGenerateMaxLength = 4096
+)
+
+// VaultGenerateQuery is the dependency to Vault for a secret which is
+// generated and written if it does not exist yet. Its data is the value of
+// the secret.
+type VaultGenerateQuery struct {
+	stopCh chan struct{}
+
+	path    string
+	key     string
+	length  int
+	charset string
+}
+
+// NewVaultGenerateQuery creates a new dependency for the secret at the given
+// path. The spec are "key=value" options: "key" is the field of the secret
+// holding the value, "value" by default; "length" is the number of characters
+// generated, 32 by default; and "charset" is the name of the characters used,
+// one of VaultGenerateCharsets, "alphanum" by default.
+func NewVaultGenerateQuery(s string, spec ...string) (*VaultGenerateQuery, error) {
+	s = strings.TrimSpace(s)
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return nil, fmt.Errorf("vault.generate: invalid format: %q", s)
+	}
+
+	d := &VaultGenerateQuery{
+		stopCh:  make(chan struct{}, 1),
+		path:    s,
+		key:     "value",
+		length:  vaultGenerateLength,
+		charset: "alphanum",
+	}
+
+	for _, opt := range spec {
+		parts := strings.SplitN(opt, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("vault.generate: not k=v pair %q", opt)
+		}
+
+		k, v := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch k {
+		case "key":
+			if v == "" {
+				return nil, fmt.Errorf("vault.generate: key must not be empty")
+			}
+			d.key = v
+		case "length":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > vaultGenerateMaxLength {
+				return nil, fmt.Errorf("vault.generate: length must be between 1 and %d, got %q",
+					vaultGenerateMaxLength, v)
+			}
+			d.length = n
+		case "charset":
+			if _, ok := VaultGenerateCharsets[v]; !ok {
+				return nil, fmt.Errorf("vault.generate: unknown charset %q", v)
+			}
+			d.charset = v
+		default:
+			return nil, fmt.Errorf("vault.generate: unknown option %q", k)
+		}
+	}
+
+	return d, nil
+}
+
+// Fetch reads the secret, generating and writing it first if it does not
+// exist. Since the secret does not change once written, it is read again
+// only every VaultDefaultLeaseDuration.
+func (d *VaultGenerateQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{})
+
+	if opts.WaitIndex != 0 {
+		log.Printf("[TRACE] %s: long polling for %s", d, VaultDefaultLeaseDuration)
+
+		select {
+		case <-d.stopCh:
+			return nil, nil, ErrStopped
+		case <-time.After(VaultDefaultLeaseDuration):
+		}
+	}
+
+	path, err := d.dataPath(clients)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	value, ok, err := d.read(clients, path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+	if ok {
+		return respWithMetadata(value)
+	}
+
+	generated, err := d.generate()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	log.Printf("[TRACE] %s: PUT %s", d, &url.URL{Path: "/v1/" + path})
+
+	data := map[string]interface{}{
+		"data":    map[string]interface{}{d.key: generated},
+		"options": map[string]interface{}{"cas": 0},
+	}
+
+	if _, writeErr := clients.Vault().Logical().Write(path, data); writeErr != nil {
+		// Another writer may have created the secret first, in which case the
+		// check-and-set fails and its secret is used instead.
+		value, ok, err := d.read(clients, path)
+		if err != nil || !ok {
+			return nil, nil, errors.Wrap(writeErr, d.String())
+		}
+		log.Printf("[DEBUG] %s: secret was created by another writer", d)
+		return respWithMetadata(value)
+	}
+
+	log.Printf("[INFO] %s: generated secret at %s", d, path)
+
+	return respWithMetadata(generated)
+}
+
+// dataPath returns the KV v2 data path of the secret. Secrets are only
+// generated on KV v2 mounts, since they are written with check-and-set so
+// that only one writer can create them, and KV v1 has no check-and-set. If
+// the mount cannot be looked up, paths containing "/data/" are taken to be
+// KV v2 data paths.
+func (d *VaultGenerateQuery) dataPath(clients *ClientSet) (string, error) {
+	m := clients.vaultKVMount(d.path)
+	switch {
+	case m.detected && m.version == 2:
+		return kvDataPath(m.path, d.path), nil
+	case !m.detected && strings.Contains(d.path, "/data/"):
+		return d.path, nil
+	}
+	return "", fmt.Errorf("%s is not a KV v2 secret, which is required for "+
+		"check-and-set", d.path)
+}
+
+// read returns the value of the secret at the given data path, and false if
+// the secret does not exist. A secret which exists without the key is an
+// error, since writing the key would replace the other data of the secret.
+func (d *VaultGenerateQuery) read(clients *ClientSet, path string) (string, bool, error) {
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{Path: "/v1/" + path})
+
+	secret, err := clients.Vault().Logical().Read(path)
+	if err != nil {
+		return "", false, err
+	}
+	if secret == nil || secret.Data == nil {
+		return "", false, nil
+	}
+
+	// Deleted versions of KV v2 secrets are returned without data.
+	data, _ := secret.Data["data"].(map[string]interface{})
+	if data == nil {
+		return "", false, nil
+	}
+
+	value, ok := data[d.key]
+	if !ok {
+		return "", false, fmt.Errorf("secret at %s exists without key %q", path, d.key)
+	}
+	return fmt.Sprintf("%v", value), true, nil
+}
+
+// generate returns a new random secret.
+func (d *VaultGenerateQuery) generate() (string, error) {
+	chars := VaultGenerateCharsets[d.charset]
+	max := big.NewInt(int64(len(chars)))
+
+	b := make([]byte, d.length)
+	for i := range b {
+		c, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = chars[c.Int64()]
+	}
+	return string(b), nil
+}
+
+// CanShare returns if this dependency is shareable.
+func (d *VaultGenerateQuery) CanShare() bool {
+	return false
+}
+
+// Stop halts the given dependency's fetch.
+func (d *VaultGenerateQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *VaultGenerateQuery) String() string {
+	return fmt.Sprintf("vault.generate(%s?charset=%s&key=%s&length=%d)",
+		d.path, d.charset, url.QueryEscape(d.key), d.length)
+}
+
+// Type returns the type of this dependency.
+func (d *VaultGenerateQuery) Type() Type {
+	return TypeVault
+}
@@ -0,0 +1,308 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVaultGenerateQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		spec []string
+		exp  *VaultGenerateQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			nil,
+			true,
+		},
+		{
+			"defaults",
+			"/secret/db/",
+			nil,
+			&VaultGenerateQuery{
+				path:    "secret/db",
+				key:     "value",
+				length:  32,
+				charset: "alphanum",
+			},
+			false,
+		},
+		{
+			"spec",
+			"secret/data/db",
+			[]string{"key=password", "length=16", "charset=hex"},
+			&VaultGenerateQuery{
+				path:    "secret/data/db",
+				key:     "password",
+				length:  16,
+				charset: "hex",
+			},
+			false,
+		},
+		{
+			"bad_length",
+			"secret/db",
+			[]string{"length=0"},
+			nil,
+			true,
+		},
+		{
+			"bad_charset",
+			"secret/db",
+			[]string{"charset=emoji"},
+			nil,
+			true,
+		},
+		{
+			"unknown_option",
+			"secret/db",
+			[]string{"ttl=1h"},
+			nil,
+			true,
+		},
+		{
+			"not_pair",
+			"secret/db",
+			[]string{"length"},
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewVaultGenerateQuery(tc.i, tc.spec...)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+// testVaultKV is a fake Vault KV store. Secrets under "secret/data/" are KV
+// v2 secrets, written with check-and-set. The "kv/" mount is a KV v1 mount,
+// and the other mounts cannot be looked up.
+type testVaultKV struct {
+	sync.Mutex
+	secrets map[string]map[string]interface{}
+
+	// beforeWrite is called before each write, with the lock held.
+	beforeWrite func()
+}
+
+func (kv *testVaultKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	kv.Lock()
+	defer kv.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	kv2 := strings.HasPrefix(path, "secret/data/")
+
+	if strings.HasPrefix(path, "sys/internal/ui/mounts/kv/") {
+		fmt.Fprintf(w, `{"data": {"path": "kv/", "type": "kv", "options": {"version": "1"}}}`)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		data, ok := kv.secrets[path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if kv2 {
+			data = map[string]interface{}{"data": data}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	case "PUT", "POST":
+		if kv.beforeWrite != nil {
+			kv.beforeWrite()
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if kv2 {
+			if _, ok := kv.secrets[path]; ok {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"errors":["check-and-set parameter did not match the current version"]}`)
+				return
+			}
+			body, _ = body["data"].(map[string]interface{})
+		}
+		kv.secrets[path] = body
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestVaultGenerateQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		path     string
+		spec     []string
+		existing map[string]map[string]interface{}
+		race     map[string]interface{}
+		exp      string
+		err      bool
+	}{
+		{
+			"generates",
+			"secret/data/db",
+			[]string{"length=24", "charset=numeric"},
+			nil,
+			nil,
+			"",
+			false,
+		},
+		{
+			"generates_key",
+			"secret/data/db",
+			[]string{"key=password"},
+			nil,
+			nil,
+			"",
+			false,
+		},
+		{
+			"kv1",
+			"kv/db",
+			nil,
+			nil,
+			nil,
+			"",
+			true,
+		},
+		{
+			"not_data_path",
+			"secret/db",
+			nil,
+			nil,
+			nil,
+			"",
+			true,
+		},
+		{
+			"existing",
+			"secret/data/db",
+			nil,
+			map[string]map[string]interface{}{
+				"secret/data/db": {"value": "stored"},
+			},
+			nil,
+			"stored",
+			false,
+		},
+		{
+			"lost_race",
+			"secret/data/db",
+			nil,
+			nil,
+			map[string]interface{}{"value": "winner"},
+			"winner",
+			false,
+		},
+		{
+			"existing_without_key",
+			"secret/data/db",
+			[]string{"key=password"},
+			map[string]map[string]interface{}{
+				"secret/data/db": {"username": "admin"},
+			},
+			nil,
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			kv := &testVaultKV{secrets: make(map[string]map[string]interface{})}
+			for k, v := range tc.existing {
+				kv.secrets[k] = v
+			}
+			if tc.race != nil {
+				// Another writer creates the secret between the read and the
+				// write.
+				kv.beforeWrite = func() {
+					kv.secrets[tc.path] = tc.race
+				}
+			}
+
+			ts := httptest.NewServer(kv)
+			defer ts.Close()
+
+			clients := NewClientSet()
+			if err := clients.CreateVaultClient(&CreateVaultClientInput{
+				Address: ts.URL,
+				Token:   "s.token",
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := NewVaultGenerateQuery(tc.path, tc.spec...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, _, err := d.Fetch(clients, nil)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if tc.err {
+				return
+			}
+
+			value := act.(string)
+			if tc.exp != "" {
+				assert.Equal(t, tc.exp, value)
+				return
+			}
+
+			if len(value) != d.length {
+				t.Errorf("expected %d characters, got %q", d.length, value)
+			}
+			chars := VaultGenerateCharsets[d.charset]
+			for _, c := range value {
+				if !strings.ContainsRune(chars, c) {
+					t.Errorf("expected only %s characters, got %q", d.charset, value)
+					break
+				}
+			}
+
+			// The generated secret is stored, and read back the next time.
+			kv.Lock()
+			assert.Equal(t, value, kv.secrets[tc.path][d.key])
+			kv.Unlock()
+			again, _, err := d.Fetch(clients, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, value, again)
+		})
+	}
+}
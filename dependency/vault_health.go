@@ -0,0 +1,229 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// vaultHealthTimeout is the maximum amount of time to wait for the
+	// sys/health endpoint of a Vault node.
+	vaultHealthTimeout = 5 * time.Second
+
+	// vaultHealthRecheckInterval is the minimum amount of time between health
+	// checks after failed requests, so an outage does not turn every retry
+	// into a round of health checks.
+	vaultHealthRecheckInterval = 10 * time.Second
+)
+
+// vaultNodeState is the state of a Vault node as reported by sys/health,
+// ordered from least to most usable.
+type vaultNodeState int
+
+const (
+	vaultNodeUnreachable vaultNodeState = iota
+	vaultNodeUninitialized
+	vaultNodeSealed
+	vaultNodeStandby
+	vaultNodeActive
+)
+
+// vaultHealthResponse is the response of the sys/health endpoint.
+type vaultHealthResponse struct {
+	Initialized bool `json:"initialized"`
+	Sealed      bool `json:"sealed"`
+	Standby     bool `json:"standby"`
+}
+
+// vaultHealthTransport is an http.RoundTripper which sends the requests for
+// the configured Vault address to the healthiest node of the cluster,
+// preferring the active node over standbys, which forward requests to it.
+// Failed requests trigger a new health check, so a failover is followed.
+// Requests to other hosts, like the redirects of standbys, are left alone.
+type vaultHealthTransport struct {
+	base   http.RoundTripper
+	health *http.Client
+	addrs  []*url.URL
+
+	mu          sync.Mutex
+	current     *url.URL
+	lastChecked time.Time
+	checking    bool
+}
+
+// newVaultHealthTransport creates a transport for the given Vault addresses,
+// the first of which is the one the API client is configured with. The health
+// checks are sent with the given transport.
+func newVaultHealthTransport(base, healthBase http.RoundTripper, addrs []string) (*vaultHealthTransport, error) {
+	t := &vaultHealthTransport{
+		base: base,
+		health: &http.Client{
+			Transport: healthBase,
+			Timeout:   vaultHealthTimeout,
+		},
+	}
+
+	seen := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		u, err := url.Parse(addr)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid vault address %q", addr)
+		}
+		if seen[u.Host] {
+			continue
+		}
+		seen[u.Host] = true
+		t.addrs = append(t.addrs, u)
+	}
+	if len(t.addrs) == 0 {
+		return nil, fmt.Errorf("no vault address")
+	}
+
+	t.current = t.addrs[0]
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *vaultHealthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	current := t.current
+	t.mu.Unlock()
+
+	if req.URL.Host == t.addrs[0].Host && current.Host != req.URL.Host {
+		// A RoundTripper must not modify the request, so send a copy.
+		r := new(http.Request)
+		*r = *req
+		u := *req.URL
+		u.Scheme, u.Host = current.Scheme, current.Host
+		r.URL, r.Host = &u, current.Host
+		req = r
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+		t.recheck()
+	}
+	return resp, err
+}
+
+// recheck checks the health of the nodes again in the background, unless
+// they were checked recently.
+func (t *vaultHealthTransport) recheck() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.checking || time.Since(t.lastChecked) < vaultHealthRecheckInterval {
+		return
+	}
+	t.checking = true
+
+	go func() {
+		if err := t.check(); err != nil {
+			log.Printf("[ERR] (clients) vault: %s", err)
+		}
+	}()
+}
+
+// check checks the health of each node and sends the following requests to
+// the active node, or the first standby if there is no active node. If no
+// node is usable, the requests are still sent to the current one, and the
+// error explains why the nodes cannot be used.
+func (t *vaultHealthTransport) check() error {
+	var best *url.URL
+	bestState := vaultNodeUnreachable
+	var errs []error
+
+	for _, addr := range t.addrs {
+		state, err := t.nodeState(addr)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if state > bestState && state >= vaultNodeStandby {
+			best, bestState = addr, state
+		}
+		if state == vaultNodeActive {
+			break
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastChecked = time.Now()
+	t.checking = false
+
+	if best == nil {
+		return vaultHealthError(errs)
+	}
+	if best.Host != t.current.Host {
+		log.Printf("[INFO] (clients) vault: using %s node %s",
+			vaultNodeStateName(bestState), best)
+	}
+	t.current = best
+	return nil
+}
+
+// nodeState returns the state of the Vault node at the given address, and an
+// error if the node is not usable.
+func (t *vaultHealthTransport) nodeState(addr *url.URL) (vaultNodeState, error) {
+	u := *addr
+	u.Path = strings.TrimRight(u.Path, "/") + "/v1/sys/health"
+	u.RawQuery = ""
+
+	resp, err := t.health.Get(u.String())
+	if err != nil {
+		return vaultNodeUnreachable, fmt.Errorf("vault at %s is unreachable: %s", addr, err)
+	}
+	defer resp.Body.Close()
+
+	var health vaultHealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return vaultNodeUnreachable, fmt.Errorf("vault at %s returned an invalid "+
+			"health response (status %d): %s", addr, resp.StatusCode, err)
+	}
+
+	switch {
+	case !health.Initialized:
+		return vaultNodeUninitialized, errors.Wrapf(ErrVaultUninitialized, "%s", addr)
+	case health.Sealed:
+		return vaultNodeSealed, errors.Wrapf(ErrVaultSealed, "%s", addr)
+	case health.Standby:
+		return vaultNodeStandby, nil
+	default:
+		return vaultNodeActive, nil
+	}
+}
+
+// vaultHealthError returns the error for a cluster none of whose nodes is
+// usable. Sealed and uninitialized nodes take precedence over unreachable
+// ones, since they need an operator.
+func vaultHealthError(errs []error) error {
+	for _, target := range []error{ErrVaultSealed, ErrVaultUninitialized} {
+		for _, err := range errs {
+			if errors.Cause(err) == target {
+				return err
+			}
+		}
+	}
+
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Errorf("no healthy vault node: %s", strings.Join(msgs, "; "))
+}
+
+// vaultNodeStateName returns the name of a usable node state for logging.
+func vaultNodeStateName(s vaultNodeState) string {
+	if s == vaultNodeActive {
+		return "active"
+	}
+	return "standby"
+}
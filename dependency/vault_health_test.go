@@ -0,0 +1,178 @@
+package dependency
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// testVaultNode starts a server which answers sys/health with the given
+// response and other requests with its name.
+func testVaultNode(t *testing.T, name, health string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/sys/health" {
+			fmt.Fprint(w, health)
+			return
+		}
+		fmt.Fprint(w, name)
+	}))
+}
+
+const (
+	testVaultActive        = `{"initialized":true,"sealed":false,"standby":false}`
+	testVaultStandby       = `{"initialized":true,"sealed":false,"standby":true}`
+	testVaultSealed        = `{"initialized":true,"sealed":true,"standby":true}`
+	testVaultUninitialized = `{"initialized":false,"sealed":true,"standby":true}`
+)
+
+func TestVaultHealthTransport_check(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		nodes []string
+		exp   int
+		err   error
+	}{
+		{
+			"active_first",
+			[]string{testVaultActive, testVaultStandby},
+			0,
+			nil,
+		},
+		{
+			"active_preferred",
+			[]string{testVaultStandby, testVaultSealed, testVaultActive},
+			2,
+			nil,
+		},
+		{
+			"standby",
+			[]string{testVaultSealed, testVaultStandby},
+			1,
+			nil,
+		},
+		{
+			"sealed",
+			[]string{testVaultSealed, "nope"},
+			-1,
+			ErrVaultSealed,
+		},
+		{
+			"uninitialized",
+			[]string{testVaultUninitialized},
+			-1,
+			ErrVaultUninitialized,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			addrs := make([]string, 0, len(tc.nodes))
+			for j, health := range tc.nodes {
+				ts := testVaultNode(t, fmt.Sprint(j), health)
+				defer ts.Close()
+				addrs = append(addrs, ts.URL)
+			}
+
+			tr, err := newVaultHealthTransport(http.DefaultTransport,
+				http.DefaultTransport, addrs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tr.check()
+			if tc.err != nil {
+				if errors.Cause(err) != tc.err {
+					t.Fatalf("expected %q, got %v", tc.err, err)
+				}
+				if tr.current != tr.addrs[0] {
+					t.Errorf("expected the first address to still be used")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tr.current.String() != addrs[tc.exp] {
+				t.Errorf("expected %s, got %s", addrs[tc.exp], tr.current)
+			}
+		})
+	}
+}
+
+func TestVaultHealthTransport_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	standby := testVaultNode(t, "standby", testVaultStandby)
+	defer standby.Close()
+	active := testVaultNode(t, "active", testVaultActive)
+	defer active.Close()
+	redirect := testVaultNode(t, "redirect", testVaultActive)
+	defer redirect.Close()
+
+	tr, err := newVaultHealthTransport(http.DefaultTransport,
+		http.DefaultTransport, []string{standby.URL, active.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.check(); err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: tr}
+
+	cases := []struct {
+		name string
+		url  string
+		exp  string
+	}{
+		{
+			"configured_address",
+			standby.URL + "/v1/secret/foo",
+			"active",
+		},
+		{
+			"other_host",
+			redirect.URL + "/v1/secret/foo",
+			"redirect",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			resp, err := client.Get(tc.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			b, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.exp {
+				t.Errorf("expected %q, got %q", tc.exp, b)
+			}
+		})
+	}
+}
+
+func TestCreateVaultClient_healthCheck(t *testing.T) {
+	t.Parallel()
+
+	ts := testVaultNode(t, "", testVaultSealed)
+	defer ts.Close()
+
+	clients := NewClientSet()
+	err := clients.CreateVaultClient(&CreateVaultClientInput{
+		Address:     ts.URL,
+		HealthCheck: true,
+	})
+	if err == nil {
+		t.Fatal("expected an error for a sealed vault")
+	}
+}
@@ -0,0 +1,166 @@
+package dependency
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// kvMount is the detected KV version of a Vault mount.
+type kvMount struct {
+	// path is the path of the mount with a trailing slash, like "secret/".
+	path string
+
+	// version is 1 or 2 for KV mounts, and 0 for other mounts or if the
+	// version could not be detected.
+	version int
+
+	// detected is false if the mount could not be looked up, in which case
+	// path is only the first segment of the secret path.
+	detected bool
+
+	expires time.Time
+}
+
+// kvMountCache caches the detected KV versions of Vault mounts, so the
+// sys/internal/ui/mounts endpoint is queried once per mount and TTL rather
+// than on every read. Failed detections are cached as well, so tokens without
+// permission to read the endpoint do not query it on every read.
+type kvMountCache struct {
+	sync.Mutex
+
+	ttl    time.Duration
+	mounts map[string]*kvMount
+}
+
+// newKVMountCache creates a cache whose entries expire after the given TTL.
+func newKVMountCache(ttl time.Duration) *kvMountCache {
+	return &kvMountCache{
+		ttl:    ttl,
+		mounts: make(map[string]*kvMount),
+	}
+}
+
+// get returns the cached mount of the given secret path, which is the
+// unexpired mount with the longest path that is a prefix of it.
+func (c *kvMountCache) get(path string, now time.Time) (*kvMount, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	var found *kvMount
+	for p, m := range c.mounts {
+		if !now.Before(m.expires) {
+			delete(c.mounts, p)
+			continue
+		}
+		if strings.HasPrefix(path+"/", p) && (found == nil || len(p) > len(found.path)) {
+			found = m
+		}
+	}
+	return found, found != nil
+}
+
+// put caches the given mount.
+func (c *kvMountCache) put(m *kvMount, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	m.expires = now.Add(c.ttl)
+	c.mounts[m.path] = m
+}
+
+// vaultKVMount returns the mount of the given secret path and its KV version,
+// from the cache if it was detected within the TTL.
+func (c *ClientSet) vaultKVMount(path string) *kvMount {
+	c.RLock()
+	cache := c.vault.kvMounts
+	c.RUnlock()
+
+	now := time.Now()
+	if cache != nil {
+		if m, ok := cache.get(path, now); ok {
+			return m
+		}
+	}
+
+	m, err := detectKVMount(c, path)
+	if err != nil {
+		// Without permission to read the endpoint, the path is used as given.
+		// The failure is cached for the first path segment, so it is not
+		// retried on every read.
+		log.Printf("[DEBUG] (clients) vault: failed to detect the KV version "+
+			"of %q, reading it as given: %s", path, err)
+		m = &kvMount{path: strings.SplitN(path, "/", 2)[0] + "/"}
+	}
+
+	if cache != nil {
+		cache.put(m, now)
+	}
+	return m
+}
+
+// detectKVMount queries Vault for the mount of the given secret path and its
+// KV version.
+func detectKVMount(clients *ClientSet, path string) (*kvMount, error) {
+	secret, err := clients.Vault().Logical().Read("sys/internal/ui/mounts/" + path)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("no mount data returned")
+	}
+
+	mountPath, _ := secret.Data["path"].(string)
+	if mountPath == "" {
+		return nil, fmt.Errorf("no mount path returned")
+	}
+
+	m := &kvMount{
+		path:     strings.TrimSuffix(mountPath, "/") + "/",
+		detected: true,
+	}
+
+	typ, _ := secret.Data["type"].(string)
+	if typ != "kv" && typ != "generic" {
+		return m, nil
+	}
+
+	m.version = 1
+	if options, ok := secret.Data["options"].(map[string]interface{}); ok {
+		if v, _ := options["version"].(string); v == "2" {
+			m.version = 2
+		}
+	}
+	return m, nil
+}
+
+// parseKVVersion splits an explicit "kv1:" or "kv2:" prefix from the given
+// path, which overrides the detection of the KV version of its mount. The
+// version is 0 if there is no prefix.
+func parseKVVersion(s string) (string, int) {
+	switch {
+	case strings.HasPrefix(s, "kv1:"):
+		return strings.TrimPrefix(s, "kv1:"), 1
+	case strings.HasPrefix(s, "kv2:"):
+		return strings.TrimPrefix(s, "kv2:"), 2
+	}
+	return s, 0
+}
+
+// kvDataPath returns the path to read a secret from a KV v2 mount at the
+// given mount path. Paths which already address the data or metadata of a
+// secret are returned as they are.
+func kvDataPath(mount, path string) string {
+	rest := strings.TrimPrefix(path, mount)
+	if rest == path || strings.HasPrefix(rest, "data/") ||
+		strings.HasPrefix(rest, "metadata/") {
+		return path
+	}
+	return mount + "data/" + rest
+}
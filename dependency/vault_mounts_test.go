@@ -0,0 +1,177 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKVDataPath(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		mount string
+		path  string
+		exp   string
+	}{
+		{
+			"logical",
+			"secret/",
+			"secret/foo",
+			"secret/data/foo",
+		},
+		{
+			"nested_mount",
+			"team/kv/",
+			"team/kv/foo/bar",
+			"team/kv/data/foo/bar",
+		},
+		{
+			"data",
+			"secret/",
+			"secret/data/foo",
+			"secret/data/foo",
+		},
+		{
+			"metadata",
+			"secret/",
+			"secret/metadata/foo",
+			"secret/metadata/foo",
+		},
+		{
+			"other_mount",
+			"secret/",
+			"database/creds/app",
+			"database/creds/app",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			assert.Equal(t, tc.exp, kvDataPath(tc.mount, tc.path))
+		})
+	}
+}
+
+func TestKVMountCache(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	c := newKVMountCache(time.Minute)
+	c.put(&kvMount{path: "secret/", version: 2}, now)
+	c.put(&kvMount{path: "secret/team/", version: 1}, now)
+
+	m, ok := c.get("secret/foo", now)
+	if assert.True(t, ok) {
+		assert.Equal(t, 2, m.version)
+	}
+
+	m, ok = c.get("secret/team/foo", now)
+	if assert.True(t, ok) {
+		assert.Equal(t, 1, m.version)
+	}
+
+	_, ok = c.get("secretive/foo", now)
+	assert.False(t, ok)
+
+	_, ok = c.get("secret/foo", now.Add(time.Minute))
+	assert.False(t, ok)
+	assert.Empty(t, c.mounts)
+}
+
+func TestVaultReadQuery_readPath(t *testing.T) {
+	t.Parallel()
+
+	var lookups int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/")
+		atomic.AddInt32(&lookups, 1)
+		switch {
+		case strings.HasPrefix(path, "secret/"):
+			w.Write([]byte(`{"data": {"path": "secret/", "type": "kv", "options": {"version": "2"}}}`))
+		case strings.HasPrefix(path, "kv/"):
+			w.Write([]byte(`{"data": {"path": "kv/", "type": "kv", "options": null}}`))
+		case strings.HasPrefix(path, "team/app/"):
+			w.Write([]byte(`{"data": {"path": "team/app/", "type": "kv", "options": null}}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+		}
+	}))
+	defer ts.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateVaultClient(&CreateVaultClientInput{
+		Address:         ts.URL,
+		Token:           "s.token",
+		KVMountCacheTTL: time.Minute,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"kv2",
+			"secret/foo",
+			"secret/data/foo",
+		},
+		{
+			"kv2_cached",
+			"secret/bar",
+			"secret/data/bar",
+		},
+		{
+			"kv1",
+			"kv/foo",
+			"kv/foo",
+		},
+		{
+			"denied",
+			"database/creds/app",
+			"database/creds/app",
+		},
+		{
+			"denied_cached",
+			"database/creds/web",
+			"database/creds/web",
+		},
+		{
+			"override_kv2",
+			"kv2:team/app/foo",
+			"team/app/data/foo",
+		},
+		{
+			"override_kv2_denied",
+			"kv2:app/foo",
+			"app/foo",
+		},
+		{
+			"override_kv1",
+			"kv1:secret/foo",
+			"secret/foo",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewVaultReadQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.readPath(clients))
+		})
+	}
+
+	assert.Equal(t, int32(5), atomic.LoadInt32(&lookups))
+}
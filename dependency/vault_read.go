@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/pkg/errors"
 )
 
@@ -21,20 +23,92 @@ type VaultReadQuery struct {
 
 	path   string
 	secret *Secret
+
+	// kv is the KV version of the mount of the secret if it was given with a
+	// "kv1:" or "kv2:" prefix, or 0 to detect it.
+	kv int
+
+	// version is the KV v2 secret version to read. Zero reads the latest
+	// version.
+	version int
+
+	// latestOnly is false if the version should be pinned to the version that
+	// is read first, instead of following new versions.
+	latestOnly bool
+
+	// freeze prevents new versions of the secret from being used. A change in
+	// version is logged and the previous secret is returned instead.
+	freeze bool
+
+	// seenVersion is the first version of the secret that was read, and
+	// pinnedVersion is the version read on each fetch when latestOnly is false.
+	seenVersion   int
+	pinnedVersion int
+
+	// overlap is how long before a non-renewable secret expires a new secret is
+	// read. The old secret is returned as the Previous secret until it expires,
+	// so both can be rendered during the overlap.
+	overlap time.Duration
+
+	// expires and previousExpires are when the lease of the current and the
+	// previous secret end.
+	expires         time.Time
+	previousExpires time.Time
 }
 
-// NewVaultReadQuery creates a new datacenter dependency.
+// NewVaultReadQuery creates a new datacenter dependency. The path may include
+// the query parameters "version" to read a specific version of a KV v2
+// secret, "latestVersionOnly=false" to keep reading the first version which
+// was read, "freeze=true" to refuse to use new versions of the secret, and
+// "overlap" to read a new non-renewable secret that long before the old one
+// expires. A "kv1:" or "kv2:" prefix sets the KV version of the mount instead
+// of detecting it.
 func NewVaultReadQuery(s string) (*VaultReadQuery, error) {
-	s = strings.TrimSpace(s)
+	s, kv := parseKVVersion(strings.TrimSpace(s))
+
+	var query string
+	if idx := strings.Index(s, "?"); idx != -1 {
+		s, query = s[:idx], s[idx+1:]
+	}
+
 	s = strings.Trim(s, "/")
 	if s == "" {
 		return nil, fmt.Errorf("vault.read: invalid format: %q", s)
 	}
 
-	return &VaultReadQuery{
-		stopCh: make(chan struct{}, 1),
-		path:   s,
-	}, nil
+	d := &VaultReadQuery{
+		stopCh:     make(chan struct{}, 1),
+		path:       s,
+		kv:         kv,
+		latestOnly: true,
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "vault.read: invalid query")
+	}
+	for k, v := range params {
+		switch k {
+		case "version":
+			d.version, err = strconv.Atoi(v[0])
+		case "latestVersionOnly":
+			d.latestOnly, err = strconv.ParseBool(v[0])
+		case "freeze":
+			d.freeze, err = strconv.ParseBool(v[0])
+		case "overlap":
+			d.overlap, err = time.ParseDuration(v[0])
+			if err == nil && d.overlap <= 0 {
+				err = fmt.Errorf("overlap must be positive")
+			}
+		default:
+			err = fmt.Errorf("unknown option %q", k)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("vault.read: invalid query %q: %s", query, err)
+		}
+	}
+
+	return d, nil
 }
 
 // Fetch queries the Vault API
@@ -54,6 +128,10 @@ func (d *VaultReadQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfac
 		if dur == 0 {
 			dur = VaultDefaultLeaseDuration
 		}
+		dur = clients.vaultRenewWait(dur)
+		if d.overlapping() {
+			dur = d.overlapWait(time.Now())
+		}
 
 		log.Printf("[TRACE] %s: long polling for %s", d, dur)
 
@@ -64,6 +142,21 @@ func (d *VaultReadQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfac
 		}
 	}
 
+	// If the previous secret expired before it is time to read a new one, stop
+	// returning it.
+	if d.overlapping() && d.secret.Previous != nil {
+		now := time.Now()
+		if !now.Before(d.previousExpires) && now.Before(d.expires.Add(-d.overlap)) {
+			log.Printf("[TRACE] %s: previous secret expired", d)
+
+			secret := *d.secret
+			secret.Previous = nil
+			d.secret = &secret
+
+			return respWithMetadata(d.secret)
+		}
+	}
+
 	// Attempt to renew the secret. If we do not have a secret or if that secret
 	// is not renewable, we will attempt a (re-)read later.
 	if d.secret != nil && d.secret.LeaseID != "" && d.secret.Renewable {
@@ -94,11 +187,12 @@ func (d *VaultReadQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfac
 
 	// If we got this far, we either didn't have a secret to renew, the secret was
 	// not renewable, or the renewal failed, so attempt a fresh read.
+	path := d.readPath(clients)
 	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
-		Path:     "/v1/" + d.path,
+		Path:     "/v1/" + path,
 		RawQuery: opts.String(),
 	})
-	vaultSecret, err := clients.Vault().Logical().Read(d.path)
+	vaultSecret, err := d.read(clients, path)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, d.String())
 	}
@@ -108,6 +202,21 @@ func (d *VaultReadQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfac
 		return nil, nil, fmt.Errorf("%s: no secret exists at %s", d, d.path)
 	}
 
+	if version := vaultSecretVersion(vaultSecret.Data); version != 0 {
+		if d.seenVersion == 0 {
+			d.seenVersion = version
+			if !d.latestOnly && d.version == 0 {
+				log.Printf("[DEBUG] %s: pinning to version %d", d, version)
+				d.pinnedVersion = version
+			}
+		} else if d.freeze && version != d.seenVersion && d.secret != nil {
+			log.Printf("[ERR] %s: version changed from %d to %d, but the secret "+
+				"is frozen; continuing to use version %d", d, d.seenVersion, version,
+				d.seenVersion)
+			return respWithMetadata(d.secret)
+		}
+	}
+
 	// Print any warnings.
 	for _, w := range vaultSecret.Warnings {
 		log.Printf("[WARN] %s: %s", d, w)
@@ -120,11 +229,88 @@ func (d *VaultReadQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfac
 		Renewable:     vaultSecret.Renewable,
 		Data:          vaultSecret.Data,
 	}
+
+	// Keep returning the previous secret until it expires, so applications can
+	// move over to the new one.
+	now := time.Now()
+	if d.overlapping() && now.Before(d.expires) {
+		previous := *d.secret
+		previous.Previous = nil
+		secret.Previous = &previous
+		d.previousExpires = d.expires
+	}
+	d.expires = now.Add(time.Duration(secret.LeaseDuration) * time.Second)
 	d.secret = secret
 
+	if d.overlap > 0 && !secret.Renewable && !d.overlapping() {
+		log.Printf("[WARN] %s: lease of %ds is not longer than the overlap, "+
+			"ignoring the overlap", d, secret.LeaseDuration)
+	}
+
 	return respWithMetadata(secret)
 }
 
+// overlapping returns true if the current secret is read again with an
+// overlap before it expires. The overlap is ignored for secrets which are
+// renewable or whose lease is not longer than the overlap.
+func (d *VaultReadQuery) overlapping() bool {
+	return d.overlap > 0 && d.secret != nil && !d.secret.Renewable &&
+		time.Duration(d.secret.LeaseDuration)*time.Second > d.overlap
+}
+
+// overlapWait returns how long to wait before the next fetch of a secret with
+// an overlap. That is the overlap before the current secret expires, or when
+// the previous secret expires if that is sooner.
+func (d *VaultReadQuery) overlapWait(now time.Time) time.Duration {
+	next := d.expires.Add(-d.overlap)
+	if d.secret.Previous != nil && d.previousExpires.Before(next) {
+		next = d.previousExpires
+	}
+	if next.Before(now) {
+		return 0
+	}
+	return next.Sub(now)
+}
+
+// readPath returns the path to read the secret from. Secrets on KV v2 mounts
+// are read from their data path, so they can be given by the same path as on
+// KV v1 mounts. A "kv2:" prefix reads the secret as KV v2 at the mount path
+// which Vault reports, whatever version the mount reports. Paths are read as
+// given if their mount cannot be looked up.
+func (d *VaultReadQuery) readPath(clients *ClientSet) string {
+	if d.kv == 1 {
+		return d.path
+	}
+
+	m := clients.vaultKVMount(d.path)
+	if !m.detected {
+		return d.path
+	}
+	if m.version == 2 || d.kv == 2 {
+		return kvDataPath(m.path, d.path)
+	}
+	return d.path
+}
+
+// read reads the secret at the given path, requesting a specific version if
+// one is set. The first read of the latest version may be taken from a batch
+// of the secrets under the same path.
+func (d *VaultReadQuery) read(clients *ClientSet, path string) (*vaultapi.Secret, error) {
+	version := d.version
+	if version == 0 {
+		version = d.pinnedVersion
+	}
+	if version == 0 {
+		if d.secret == nil {
+			if secret, ok := clients.vaultBatchRead(path); ok {
+				return secret, nil
+			}
+		}
+		return clients.Vault().Logical().Read(path)
+	}
+	return readVaultVersion(clients, path, version)
+}
+
 // CanShare returns if this dependency is shareable.
 func (d *VaultReadQuery) CanShare() bool {
 	return false
@@ -137,7 +323,29 @@ func (d *VaultReadQuery) Stop() {
 
 // String returns the human-friendly version of this dependency.
 func (d *VaultReadQuery) String() string {
-	return fmt.Sprintf("vault.read(%s)", d.path)
+	params := url.Values{}
+	if d.version != 0 {
+		params.Set("version", strconv.Itoa(d.version))
+	}
+	if !d.latestOnly {
+		params.Set("latestVersionOnly", "false")
+	}
+	if d.freeze {
+		params.Set("freeze", "true")
+	}
+	if d.overlap != 0 {
+		params.Set("overlap", d.overlap.String())
+	}
+
+	path := d.path
+	if d.kv != 0 {
+		path = fmt.Sprintf("kv%d:%s", d.kv, path)
+	}
+
+	if len(params) == 0 {
+		return fmt.Sprintf("vault.read(%s)", path)
+	}
+	return fmt.Sprintf("vault.read(%s?%s)", path, params.Encode())
 }
 
 // Type returns the type of this dependency.
@@ -27,7 +27,8 @@ func TestNewVaultReadQuery(t *testing.T) {
 			"path",
 			"path",
 			&VaultReadQuery{
-				path: "path",
+				path:       "path",
+				latestOnly: true,
 			},
 			false,
 		},
@@ -35,7 +36,8 @@ func TestNewVaultReadQuery(t *testing.T) {
 			"leading_slash",
 			"/leading/slash",
 			&VaultReadQuery{
-				path: "leading/slash",
+				path:       "leading/slash",
+				latestOnly: true,
 			},
 			false,
 		},
@@ -43,10 +45,77 @@ func TestNewVaultReadQuery(t *testing.T) {
 			"trailing_slash",
 			"trailing/slash/",
 			&VaultReadQuery{
-				path: "trailing/slash",
+				path:       "trailing/slash",
+				latestOnly: true,
 			},
 			false,
 		},
+		{
+			"kv2",
+			"kv2:secret/foo",
+			&VaultReadQuery{
+				path:       "secret/foo",
+				kv:         2,
+				latestOnly: true,
+			},
+			false,
+		},
+		{
+			"version",
+			"secret/data/foo?version=3",
+			&VaultReadQuery{
+				path:       "secret/data/foo",
+				version:    3,
+				latestOnly: true,
+			},
+			false,
+		},
+		{
+			"latest_version_only",
+			"secret/data/foo?latestVersionOnly=false",
+			&VaultReadQuery{
+				path: "secret/data/foo",
+			},
+			false,
+		},
+		{
+			"freeze",
+			"secret/data/foo?freeze=true",
+			&VaultReadQuery{
+				path:       "secret/data/foo",
+				latestOnly: true,
+				freeze:     true,
+			},
+			false,
+		},
+		{
+			"overlap",
+			"database/creds/app?overlap=2m",
+			&VaultReadQuery{
+				path:       "database/creds/app",
+				latestOnly: true,
+				overlap:    2 * time.Minute,
+			},
+			false,
+		},
+		{
+			"bad_overlap",
+			"database/creds/app?overlap=-2m",
+			nil,
+			true,
+		},
+		{
+			"bad_version",
+			"secret/data/foo?version=abc",
+			nil,
+			true,
+		},
+		{
+			"unknown_option",
+			"secret/data/foo?nope=true",
+			nil,
+			true,
+		},
 	}
 
 	for i, tc := range cases {
@@ -206,6 +275,21 @@ func TestVaultReadQuery_String(t *testing.T) {
 			"path",
 			"vault.read(path)",
 		},
+		{
+			"options",
+			"secret/data/foo?freeze=true&version=3",
+			"vault.read(secret/data/foo?freeze=true&version=3)",
+		},
+		{
+			"overlap",
+			"database/creds/app?overlap=2m",
+			"vault.read(database/creds/app?overlap=2m0s)",
+		},
+		{
+			"kv1",
+			"kv1:secret/foo",
+			"vault.read(kv1:secret/foo)",
+		},
 	}
 
 	for i, tc := range cases {
@@ -218,3 +302,56 @@ func TestVaultReadQuery_String(t *testing.T) {
 		})
 	}
 }
+
+func TestVaultReadQuery_overlapWait(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	cases := []struct {
+		name     string
+		secret   *Secret
+		expires  time.Time
+		previous time.Time
+		exp      time.Duration
+	}{
+		{
+			"before_overlap",
+			&Secret{LeaseDuration: 600},
+			now.Add(10 * time.Minute),
+			time.Time{},
+			8 * time.Minute,
+		},
+		{
+			"previous_expires_first",
+			&Secret{LeaseDuration: 600, Previous: &Secret{}},
+			now.Add(10 * time.Minute),
+			now.Add(1 * time.Minute),
+			1 * time.Minute,
+		},
+		{
+			"past",
+			&Secret{LeaseDuration: 600},
+			now.Add(1 * time.Minute),
+			time.Time{},
+			0,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewVaultReadQuery("database/creds/app?overlap=2m")
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.secret = tc.secret
+			d.expires = tc.expires
+			d.previousExpires = tc.previous
+
+			if !d.overlapping() {
+				t.Fatal("expected overlap")
+			}
+			assert.Equal(t, tc.exp, d.overlapWait(now))
+		})
+	}
+}
@@ -50,6 +50,7 @@ func (d *VaultTokenQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfa
 		if dur == 0 {
 			dur = VaultDefaultLeaseDuration
 		}
+		dur = clients.vaultRenewWait(dur)
 
 		log.Printf("[TRACE] %s: long polling for %s", d, dur)
 
@@ -0,0 +1,191 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*VaultVersionsQuery)(nil)
+)
+
+func init() {
+	gob.Register([]*Secret{})
+}
+
+// VaultVersionsQuery is the dependency to Vault for the latest versions of a
+// KV v2 secret.
+type VaultVersionsQuery struct {
+	stopCh chan struct{}
+
+	// path is the data path of the secret, and metadataPath the path of its
+	// metadata.
+	path         string
+	metadataPath string
+
+	// n is the maximum number of versions to return.
+	n int
+}
+
+// NewVaultVersionsQuery creates a new dependency for the latest n versions of
+// the KV v2 secret at the given data path, such as "secret/data/foo".
+func NewVaultVersionsQuery(s string, n int) (*VaultVersionsQuery, error) {
+	s = strings.TrimSpace(s)
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return nil, fmt.Errorf("vault.versions: invalid format: %q", s)
+	}
+
+	parts := strings.SplitN(s, "/data/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("vault.versions: %q is not a KV v2 data path "+
+			"like \"secret/data/foo\"", s)
+	}
+
+	if n < 1 {
+		return nil, fmt.Errorf("vault.versions: number of versions must be at "+
+			"least 1, got %d", n)
+	}
+
+	return &VaultVersionsQuery{
+		stopCh:       make(chan struct{}, 1),
+		path:         s,
+		metadataPath: parts[0] + "/metadata/" + parts[1],
+		n:            n,
+	}, nil
+}
+
+// Fetch queries the Vault API
+func (d *VaultVersionsQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{})
+
+	// If this is not the first query, poll to simulate blocking-queries.
+	if opts.WaitIndex != 0 {
+		dur := VaultDefaultLeaseDuration
+		log.Printf("[TRACE] %s: long polling for %s", d, dur)
+
+		select {
+		case <-d.stopCh:
+			return nil, nil, ErrStopped
+		case <-time.After(dur):
+		}
+	}
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     "/v1/" + d.metadataPath,
+		RawQuery: opts.String(),
+	})
+	metadata, err := clients.Vault().Logical().Read(d.metadataPath)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	// The metadata could be nil if the secret does not exist.
+	if metadata == nil {
+		return nil, nil, fmt.Errorf("%s: no secret exists at %s", d, d.path)
+	}
+
+	var result []*Secret
+	for _, version := range liveVersions(metadata.Data) {
+		log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+			Path:     "/v1/" + d.path,
+			RawQuery: "version=" + strconv.Itoa(version),
+		})
+		secret, err := readVaultVersion(clients, d.path, version)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+
+		// The version could have been deleted since the metadata was read.
+		if secret == nil {
+			continue
+		}
+
+		for _, w := range secret.Warnings {
+			log.Printf("[WARN] %s: %s", d, w)
+		}
+
+		result = append(result, &Secret{
+			RequestID:     secret.RequestID,
+			LeaseID:       secret.LeaseID,
+			LeaseDuration: secret.LeaseDuration,
+			Renewable:     secret.Renewable,
+			Data:          secret.Data,
+		})
+		if len(result) == d.n {
+			break
+		}
+	}
+
+	log.Printf("[TRACE] %s: returned %d results", d, len(result))
+
+	return respWithMetadata(result)
+}
+
+// liveVersions returns the versions in the given KV v2 metadata which are
+// neither deleted nor destroyed, latest first.
+func liveVersions(metadata map[string]interface{}) []int {
+	versions, ok := metadata["versions"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	result := make([]int, 0, len(versions))
+	for k, v := range versions {
+		version, err := strconv.Atoi(k)
+		if err != nil {
+			continue
+		}
+
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if destroyed, _ := m["destroyed"].(bool); destroyed {
+			continue
+		}
+		if deleted, _ := m["deletion_time"].(string); deleted != "" {
+			continue
+		}
+
+		result = append(result, version)
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(result)))
+	return result
+}
+
+// CanShare returns if this dependency is shareable.
+func (d *VaultVersionsQuery) CanShare() bool {
+	return false
+}
+
+// Stop halts the given dependency's fetch.
+func (d *VaultVersionsQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *VaultVersionsQuery) String() string {
+	return fmt.Sprintf("vault.versions(%s, %d)", d.path, d.n)
+}
+
+// Type returns the type of this dependency.
+func (d *VaultVersionsQuery) Type() Type {
+	return TypeVault
+}
@@ -0,0 +1,127 @@
+package dependency
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVaultVersionsQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		n    int
+		exp  *VaultVersionsQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			2,
+			nil,
+			true,
+		},
+		{
+			"not_kv_v2",
+			"secret/foo",
+			2,
+			nil,
+			true,
+		},
+		{
+			"zero",
+			"secret/data/foo",
+			0,
+			nil,
+			true,
+		},
+		{
+			"path",
+			"/secret/data/foo/bar/",
+			2,
+			&VaultVersionsQuery{
+				path:         "secret/data/foo/bar",
+				metadataPath: "secret/metadata/foo/bar",
+				n:            2,
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewVaultVersionsQuery(tc.i, tc.n)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestVaultVersionsQuery_String(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewVaultVersionsQuery("secret/data/foo", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "vault.versions(secret/data/foo, 2)", d.String())
+}
+
+func TestLiveVersions(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		metadata map[string]interface{}
+		exp      []int
+	}{
+		{
+			"empty",
+			map[string]interface{}{},
+			nil,
+		},
+		{
+			"latest_first",
+			map[string]interface{}{
+				"versions": map[string]interface{}{
+					"1":  map[string]interface{}{"deletion_time": "", "destroyed": false},
+					"2":  map[string]interface{}{"deletion_time": "", "destroyed": false},
+					"10": map[string]interface{}{"deletion_time": "", "destroyed": false},
+				},
+			},
+			[]int{10, 2, 1},
+		},
+		{
+			"deleted_destroyed",
+			map[string]interface{}{
+				"versions": map[string]interface{}{
+					"1": map[string]interface{}{"deletion_time": "", "destroyed": false},
+					"2": map[string]interface{}{"deletion_time": "", "destroyed": true},
+					"3": map[string]interface{}{"deletion_time": "2018-03-22T02:24:06.945319214Z", "destroyed": false},
+					"4": map[string]interface{}{"deletion_time": "", "destroyed": false},
+				},
+			},
+			[]int{4, 1},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act := liveVersions(tc.metadata)
+			if len(act) == 0 && len(tc.exp) == 0 {
+				return
+			}
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
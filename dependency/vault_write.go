@@ -61,6 +61,7 @@ func (d *VaultWriteQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfa
 		if dur == 0 {
 			dur = VaultDefaultLeaseDuration
 		}
+		dur = clients.vaultRenewWait(dur)
 
 		log.Printf("[TRACE] %s: long polling for %s", d, dur)
 
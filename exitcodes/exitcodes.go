@@ -0,0 +1,39 @@
+// Package exitcodes defines the exit codes of Consul Template, so wrappers and
+// service managers can tell the class of a failure apart without parsing its
+// output. The values are a stable contract and are never reused.
+package exitcodes
+
+const (
+	// OK is the exit code when Consul Template finished without errors.
+	OK = 0
+
+	// Error is the exit code of errors which have no more specific class.
+	Error = 10
+
+	// Interrupt is the exit code when Consul Template was interrupted by a
+	// signal.
+	Interrupt = 11
+
+	// ParseFlagsError is the exit code when the command line flags or the
+	// command are invalid.
+	ParseFlagsError = 12
+
+	// RunnerError is the exit code of errors while running which have no more
+	// specific class, like a watch which failed after its retries.
+	RunnerError = 13
+
+	// ConfigError is the exit code when the configuration or the templates are
+	// invalid.
+	ConfigError = 14
+
+	// RenderError is the exit code when a template failed to execute, or its
+	// contents could not be written to the destination.
+	RenderError = 15
+
+	// CommandError is the exit code when the command of a template failed.
+	CommandError = 16
+
+	// BackendUnreachable is the exit code when Consul or Vault could not be
+	// reached in once mode, after the retries.
+	BackendUnreachable = 17
+)
@@ -13,6 +13,14 @@ func (f funcVar) Set(s string) error { return f(s) }
 func (f funcVar) String() string     { return "" }
 func (f funcVar) IsBoolFlag() bool   { return false }
 
+// funcOptionalVar is a type of flag that accepts a function that is the string
+// given by the user, or "true" if the flag is given without a value.
+type funcOptionalVar func(s string) error
+
+func (f funcOptionalVar) Set(s string) error { return f(s) }
+func (f funcOptionalVar) String() string     { return "" }
+func (f funcOptionalVar) IsBoolFlag() bool   { return true }
+
 // funcBoolVar is a type of flag that accepts a function, converts the user's
 // value to a bool, and then calls the given function.
 type funcBoolVar func(b bool) error
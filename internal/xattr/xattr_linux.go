@@ -0,0 +1,89 @@
+// +build linux
+
+// Package xattr reads and writes the extended attributes of files, like their
+// SELinux labels. It is internal to Consul Template and not part of its API.
+package xattr
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"syscall"
+)
+
+// selinuxXattr is the extended attribute holding the SELinux label of a file.
+const selinuxXattr = "security.selinux"
+
+// Copy copies the extended attributes of the file at src to the file at
+// dst, so a file which replaces another keeps its attributes, like its SELinux
+// label. Attributes which cannot be copied, like those in namespaces the
+// process has no permission for, are skipped.
+func Copy(src, dst string) error {
+	names, err := List(src)
+	if err != nil {
+		if err == syscall.ENOTSUP {
+			return nil
+		}
+		return fmt.Errorf("failed to list extended attributes of %q: %s", src, err)
+	}
+
+	for _, name := range names {
+		value, err := Get(src, name)
+		if err != nil {
+			log.Printf("[DEBUG] (runner) skipping extended attribute %q of %q: %s",
+				name, src, err)
+			continue
+		}
+		if err := syscall.Setxattr(dst, name, value, 0); err != nil {
+			log.Printf("[DEBUG] (runner) skipping extended attribute %q of %q: %s",
+				name, src, err)
+		}
+	}
+	return nil
+}
+
+// SetSELinuxLabel sets the SELinux label of the file at path.
+func SetSELinuxLabel(path, label string) error {
+	// Like libselinux, store the label with a terminating NUL byte.
+	if err := syscall.Setxattr(path, selinuxXattr, append([]byte(label), 0), 0); err != nil {
+		return fmt.Errorf("failed to set selinux_label %q on %q: %s", label, path, err)
+	}
+	return nil
+}
+
+// List returns the names of the extended attributes of the file at path.
+func List(path string) ([]string, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names, nil
+}
+
+// Get returns the value of the extended attribute of the file at path.
+func Get(path, name string) ([]byte, error) {
+	size, err := syscall.Getxattr(path, name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	size, err = syscall.Getxattr(path, name, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:size], nil
+}
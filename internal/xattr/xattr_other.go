@@ -0,0 +1,15 @@
+// +build !linux
+
+package xattr
+
+// Copy is a no-op on platforms other than Linux, where extended
+// attributes are not preserved.
+func Copy(src, dst string) error {
+	return nil
+}
+
+// SetSELinuxLabel is a no-op on platforms other than Linux, which do not have
+// SELinux.
+func SetSELinuxLabel(path, label string) error {
+	return nil
+}
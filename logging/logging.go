@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-syslog"
 	"github.com/hashicorp/logutils"
@@ -14,6 +16,17 @@ import (
 // Levels are the log levels we respond to=o.
 var Levels = []logutils.LogLevel{"TRACE", "DEBUG", "INFO", "WARN", "ERR"}
 
+var (
+	// currentFilter is the filter from the last call to Setup. It is used to
+	// check if a level is enabled. currentSyslog is the syslog output and
+	// currentSampling the sampling options, if enabled, which are kept when the
+	// level is changed.
+	currentFilter     *logutils.LevelFilter
+	currentSyslog     gsyslog.Syslogger
+	currentSampling   *samplingOptions
+	currentFilterLock sync.RWMutex
+)
+
 // Config is the configuration for this log setup.
 type Config struct {
 	// Name is the progname as it will appear in syslog output (if enabled).
@@ -22,49 +35,192 @@ type Config struct {
 	// Level is the log level to use.
 	Level string `json:"level"`
 
+	// Pretty is the pretty mode, which writes human-friendly logs to the writer
+	// if they are enabled.
+	Pretty string `json:"pretty"`
+
 	// Syslog and SyslogFacility are the syslog configuration options.
 	Syslog         bool   `json:"syslog"`
 	SyslogFacility string `json:"syslog_facility"`
 
+	// SyslogAddress is the address of a remote syslog server, like
+	// "tcp://10.0.0.1:514" or "tls://logs.example.com:6514", and SyslogFormat
+	// is the message format, "rfc3164" or "rfc5424". Without an address,
+	// messages go to the local syslog socket.
+	SyslogAddress string `json:"syslog_address"`
+	SyslogFormat  string `json:"syslog_format"`
+
+	// SyslogSSL* are the TLS options of "tls://" syslog addresses.
+	SyslogSSLCACert     string `json:"syslog_ssl_ca_cert"`
+	SyslogSSLCAPath     string `json:"syslog_ssl_ca_path"`
+	SyslogSSLCert       string `json:"syslog_ssl_cert"`
+	SyslogSSLKey        string `json:"syslog_ssl_key"`
+	SyslogSSLServerName string `json:"syslog_ssl_server_name"`
+	SyslogSSLVerify     bool   `json:"syslog_ssl_verify"`
+
+	// Sampling enables sampling of repeated identical messages: in each
+	// SamplingPeriod, the first SamplingInitial of them are written, and after
+	// that every SamplingThereafter-th one.
+	Sampling           bool          `json:"sampling"`
+	SamplingInitial    int           `json:"sampling_initial"`
+	SamplingThereafter int           `json:"sampling_thereafter"`
+	SamplingPeriod     time.Duration `json:"sampling_period"`
+
 	// Writer is the output where logs should go. If syslog is enabled, data will
 	// be written to writer in addition to syslog.
 	Writer io.Writer `json:"-"`
 }
 
 func Setup(config *Config) error {
-	var logOutput io.Writer
+	pretty, err := ParsePretty(config.Pretty)
+	if err != nil {
+		return err
+	}
+
+	// Human-friendly logs have their own prefix.
+	w := config.Writer
+	flags := log.Ldate | log.Ltime | log.Lmicroseconds | log.LUTC
+	if p := newPrettyWriter(pretty, w); p != nil {
+		w = p
+		flags = 0
+	}
 
 	// Setup the default logging
-	logFilter := NewLogFilter()
-	logFilter.MinLevel = logutils.LogLevel(strings.ToUpper(config.Level))
-	logFilter.Writer = config.Writer
-	if !ValidateLevelFilter(logFilter.MinLevel, logFilter) {
-		levels := make([]string, 0, len(logFilter.Levels))
-		for _, level := range logFilter.Levels {
-			levels = append(levels, string(level))
-		}
-		return fmt.Errorf("invalid log level %q, valid log levels are %s",
-			config.Level, strings.Join(levels, ", "))
+	logFilter, err := newLevelFilter(config.Level, w)
+	if err != nil {
+		return err
 	}
 
 	// Check if syslog is enabled
+	var l gsyslog.Syslogger
 	if config.Syslog {
 		log.Printf("[DEBUG] (logging) enabling syslog on %s", config.SyslogFacility)
 
-		l, err := gsyslog.NewLogger(gsyslog.LOG_NOTICE, config.SyslogFacility, config.Name)
+		// The local syslog logger only supports the RFC3164 format, so remote
+		// servers and the RFC5424 format use the pure Go syslog writer.
+		if config.SyslogAddress == "" && !strings.EqualFold(config.SyslogFormat, syslogFormatRFC5424) {
+			l, err = gsyslog.NewLogger(gsyslog.LOG_NOTICE, config.SyslogFacility, config.Name)
+		} else {
+			l, err = newSyslogWriter(config)
+		}
 		if err != nil {
 			return fmt.Errorf("error setting up syslog logger: %s", err)
 		}
+	}
+
+	var sampling *samplingOptions
+	if config.Sampling && config.SamplingPeriod > 0 {
+		sampling = &samplingOptions{
+			initial:    config.SamplingInitial,
+			thereafter: config.SamplingThereafter,
+			period:     config.SamplingPeriod,
+		}
+	}
+
+	log.SetFlags(flags)
+
+	currentFilterLock.Lock()
+	defer currentFilterLock.Unlock()
+	currentSampling = sampling
+	setOutput(logFilter, l)
+
+	return nil
+}
+
+// SetLevel changes the level of the logging from the last call to Setup,
+// keeping its outputs. Messages being written while the level changes are
+// filtered by either the old or the new level.
+func SetLevel(level string) error {
+	currentFilterLock.Lock()
+	defer currentFilterLock.Unlock()
+
+	if currentFilter == nil {
+		return fmt.Errorf("logging has not been setup")
+	}
+
+	logFilter, err := newLevelFilter(level, currentFilter.Writer)
+	if err != nil {
+		return err
+	}
+	setOutput(logFilter, currentSyslog)
+
+	return nil
+}
+
+// CycleLevel changes the level of the logging to the next more verbose level,
+// wrapping around to the least verbose level after TRACE, and returns the new
+// level.
+func CycleLevel() (string, error) {
+	currentFilterLock.RLock()
+	if currentFilter == nil {
+		currentFilterLock.RUnlock()
+		return "", fmt.Errorf("logging has not been setup")
+	}
+	current := currentFilter.MinLevel
+	currentFilterLock.RUnlock()
+
+	next := Levels[len(Levels)-1]
+	for i, level := range Levels {
+		if level == current && i > 0 {
+			next = Levels[i-1]
+		}
+	}
+
+	if err := SetLevel(string(next)); err != nil {
+		return "", err
+	}
+	return string(next), nil
+}
+
+// newLevelFilter returns a filter for the given level writing to the given
+// writer, or an error if the level is not valid.
+func newLevelFilter(level string, w io.Writer) (*logutils.LevelFilter, error) {
+	logFilter := NewLogFilter()
+	logFilter.MinLevel = logutils.LogLevel(strings.ToUpper(level))
+	logFilter.Writer = w
+	if !ValidateLevelFilter(logFilter.MinLevel, logFilter) {
+		levels := make([]string, 0, len(logFilter.Levels))
+		for _, level := range logFilter.Levels {
+			levels = append(levels, string(level))
+		}
+		return nil, fmt.Errorf("invalid log level %q, valid log levels are %s",
+			level, strings.Join(levels, ", "))
+	}
+	return logFilter, nil
+}
+
+// setOutput sends the log to the given filter, and to syslog if it is not nil,
+// sampling the messages if sampling is enabled. The filter is replaced instead
+// of changed, because the log only serializes writes to its output, not
+// changes to the filter. currentFilterLock must be held.
+func setOutput(logFilter *logutils.LevelFilter, l gsyslog.Syslogger) {
+	var logOutput io.Writer
+	if l != nil {
 		syslog := &SyslogWrapper{l, logFilter}
 		logOutput = io.MultiWriter(logFilter, syslog)
 	} else {
 		logOutput = io.MultiWriter(logFilter)
 	}
-
-	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds | log.LUTC)
+	if currentSampling != nil {
+		logOutput = newSampler(currentSampling, logFilter, logOutput)
+	}
 	log.SetOutput(logOutput)
 
-	return nil
+	currentFilter = logFilter
+	currentSyslog = l
+}
+
+// Enabled returns true if messages at the given level are written to the log.
+// This can be used to skip building expensive log messages. If logging has not
+// been setup, all levels are enabled.
+func Enabled(level string) bool {
+	currentFilterLock.RLock()
+	defer currentFilterLock.RUnlock()
+
+	if currentFilter == nil {
+		return true
+	}
+	return currentFilter.Check([]byte("[" + strings.ToUpper(level) + "]"))
 }
 
 // NewLogFilter returns a LevelFilter that is configured with the log levels that
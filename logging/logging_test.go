@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnabled(t *testing.T) {
+	if err := Setup(&Config{
+		Level:  "info",
+		Writer: ioutil.Discard,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer log.SetOutput(os.Stderr)
+
+	if Enabled("DEBUG") {
+		t.Errorf("expected DEBUG to be disabled")
+	}
+	if !Enabled("INFO") {
+		t.Errorf("expected INFO to be enabled")
+	}
+	if !Enabled("err") {
+		t.Errorf("expected ERR to be enabled")
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup(&Config{
+		Level:  "warn",
+		Writer: &buf,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer log.SetOutput(os.Stderr)
+
+	log.Printf("[DEBUG] before")
+	if err := SetLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("[DEBUG] after")
+
+	if s := buf.String(); strings.Contains(s, "before") || !strings.Contains(s, "after") {
+		t.Errorf("expected only the message after the change, got %q", s)
+	}
+	if !Enabled("DEBUG") {
+		t.Errorf("expected DEBUG to be enabled")
+	}
+
+	if err := SetLevel("nope"); err == nil {
+		t.Errorf("expected error for invalid level")
+	}
+}
+
+func TestCycleLevel(t *testing.T) {
+	if err := Setup(&Config{
+		Level:  "warn",
+		Writer: ioutil.Discard,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer log.SetOutput(os.Stderr)
+
+	var act []string
+	for i := 0; i < len(Levels); i++ {
+		level, err := CycleLevel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		act = append(act, level)
+	}
+
+	exp := []string{"INFO", "DEBUG", "TRACE", "ERR", "WARN"}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %q\nact: %q", exp, act)
+	}
+}
@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
+)
+
+const (
+	// PrettyAuto writes human-friendly logs if the output is a terminal, and
+	// plain logs otherwise, such as when it is redirected to a file.
+	PrettyAuto = "auto"
+
+	// PrettyAlways writes human-friendly logs to any output.
+	PrettyAlways = "always"
+
+	// PrettyNever writes plain logs. This is the default.
+	PrettyNever = "never"
+)
+
+// prettyDependencyLength is the length over which the arguments of dependency
+// names are condensed in human-friendly logs.
+const prettyDependencyLength = 32
+
+// prettyColors are the ANSI colors of the levels in human-friendly logs.
+var prettyColors = map[string]string{
+	"TRACE": "\x1b[90m",
+	"DEBUG": "\x1b[36m",
+	"INFO":  "\x1b[32m",
+	"WARN":  "\x1b[33m",
+	"ERR":   "\x1b[31m",
+}
+
+// prettyDependencyRe matches dependency names like "kv.block(foo/bar)".
+var prettyDependencyRe = regexp.MustCompile(`\b([a-z]+(?:\.[a-z]+)*)\(([^()\s]+)\)`)
+
+// ParsePretty returns the pretty mode for the given value, which is one of the
+// modes, or a boolean for PrettyAuto or PrettyNever.
+func ParsePretty(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case PrettyAuto, "true":
+		return PrettyAuto, nil
+	case PrettyAlways:
+		return PrettyAlways, nil
+	case PrettyNever, "false", "":
+		return PrettyNever, nil
+	default:
+		return "", fmt.Errorf("invalid log pretty mode %q, valid modes are %s, %s, "+
+			"and %s", s, PrettyAuto, PrettyAlways, PrettyNever)
+	}
+}
+
+// prettyWriter writes each log message with a colored level, the time since
+// logging was setup, and condensed dependency names. The log must not add its
+// own prefix.
+type prettyWriter struct {
+	sync.Mutex
+
+	w     io.Writer
+	start time.Time
+}
+
+// newPrettyWriter returns a writer of human-friendly logs to w for the pretty
+// mode, or nil if the logs must be plain.
+func newPrettyWriter(mode string, w io.Writer) *prettyWriter {
+	f, ok := w.(*os.File)
+	switch mode {
+	case PrettyAlways:
+	case PrettyAuto:
+		if !ok || !isatty.IsTerminal(f.Fd()) {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	// Windows consoles need the ANSI colors converted.
+	if ok {
+		w = colorable.NewColorable(f)
+	}
+	return &prettyWriter{w: w, start: time.Now()}
+}
+
+// Write is used to implement io.Writer.
+func (p *prettyWriter) Write(b []byte) (int, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, err := p.w.Write(p.format(b, time.Since(p.start))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// format returns the human-friendly form of the message written after the
+// given time.
+func (p *prettyWriter) format(b []byte, since time.Duration) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%9s ", "+"+prettyDuration(since))
+
+	msg := string(b)
+	if strings.HasPrefix(msg, "[") {
+		if i := strings.Index(msg, "] "); i > 0 {
+			level := msg[1:i]
+			color, ok := prettyColors[level]
+			if !ok {
+				color = "\x1b[0m"
+			}
+			fmt.Fprintf(&buf, "%s%-5s\x1b[0m ", color, level)
+			msg = msg[i+2:]
+		}
+	}
+
+	buf.WriteString(prettyDependencyRe.ReplaceAllStringFunc(msg, condenseDependency))
+	return buf.Bytes()
+}
+
+// prettyDuration returns the duration rounded for human-friendly logs.
+func prettyDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond).String()
+	default:
+		return d.Round(time.Second).String()
+	}
+}
+
+// condenseDependency shortens the arguments of a dependency name over
+// prettyDependencyLength, keeping their start and end.
+func condenseDependency(s string) string {
+	m := prettyDependencyRe.FindStringSubmatch(s)
+	args := []rune(m[2])
+	if len(args) <= prettyDependencyLength {
+		return s
+	}
+
+	half := prettyDependencyLength / 2
+	return m[1] + "(" + string(args[:half]) + "…" + string(args[len(args)-half:]) + ")"
+}
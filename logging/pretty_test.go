@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePretty(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			PrettyNever,
+			false,
+		},
+		{
+			"true",
+			"true",
+			PrettyAuto,
+			false,
+		},
+		{
+			"false",
+			"false",
+			PrettyNever,
+			false,
+		},
+		{
+			"always",
+			"Always",
+			PrettyAlways,
+			false,
+		},
+		{
+			"invalid",
+			"sometimes",
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := ParsePretty(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if act != tc.exp {
+				t.Errorf("expected %q to be %q", act, tc.exp)
+			}
+		})
+	}
+}
+
+func TestPrettyWriter_format(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		i     string
+		since time.Duration
+		exp   string
+	}{
+		{
+			"level",
+			"[INFO] (runner) creating watcher\n",
+			1234 * time.Microsecond,
+			"     +1ms \x1b[32mINFO \x1b[0m (runner) creating watcher\n",
+		},
+		{
+			"no_level",
+			"starting\n",
+			1500 * time.Millisecond,
+			"    +1.5s starting\n",
+		},
+		{
+			"dependency",
+			"[DEBUG] (view) kv.block(foo) marked for retry\n",
+			0,
+			"      +0s \x1b[36mDEBUG\x1b[0m (view) kv.block(foo) marked for retry\n",
+		},
+		{
+			"condensed_dependency",
+			"[WARN] (view) vault.read(secret/data/applications/production/database/credentials) failed\n",
+			90 * time.Second,
+			"   +1m30s \x1b[33mWARN \x1b[0m (view) vault.read(secret/data/appl…base/credentials) failed\n",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			p := &prettyWriter{}
+			act := string(p.format([]byte(tc.i), tc.since))
+			if act != tc.exp {
+				t.Errorf("\nexp: %q\nact: %q", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestNewPrettyWriter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if p := newPrettyWriter(PrettyAuto, &buf); p != nil {
+		t.Errorf("expected plain logs when the output is not a terminal")
+	}
+	if p := newPrettyWriter(PrettyNever, &buf); p != nil {
+		t.Errorf("expected plain logs when disabled")
+	}
+	if p := newPrettyWriter(PrettyAlways, &buf); p == nil {
+		t.Errorf("expected human-friendly logs when forced")
+	}
+}
+
+func TestSetup_pretty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup(&Config{
+		Level:  "info",
+		Pretty: PrettyAlways,
+		Writer: &buf,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.LstdFlags)
+
+	log.Printf("[INFO] (runner) starting")
+	log.Printf("[DEBUG] (runner) hidden")
+
+	s := buf.String()
+	if !strings.Contains(s, "\x1b[32mINFO \x1b[0m (runner) starting\n") {
+		t.Errorf("expected a human-friendly message, got %q", s)
+	}
+	if strings.Contains(s, "hidden") {
+		t.Errorf("expected the level to be filtered, got %q", s)
+	}
+}
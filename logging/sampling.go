@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/logutils"
+)
+
+// maxSampleKeys is the largest number of distinct messages counted in a
+// period. Once reached, the other messages of the period are counted
+// together, so messages which are all different cannot grow the counts
+// without bound.
+const maxSampleKeys = 1024
+
+// samplingOptions are the options of log sampling.
+type samplingOptions struct {
+	initial    int
+	thereafter int
+	period     time.Duration
+}
+
+// sampler samples repeated identical log messages. In each period, the first
+// messages up to the initial count are written, and after that every Nth one,
+// so a message repeated during an outage does not flood the disk. Messages
+// are identical if they have the same level and text, regardless of their
+// timestamps and of the numbers in them, such as indexes, durations and
+// attempts.
+type sampler struct {
+	sync.Mutex
+
+	w      io.Writer
+	filter *logutils.LevelFilter
+	opts   *samplingOptions
+
+	start   time.Time
+	counts  map[string]int
+	dropped int
+}
+
+// newSampler creates a sampler writing to the given writer. Only messages
+// which pass the filter are counted.
+func newSampler(opts *samplingOptions, filter *logutils.LevelFilter, w io.Writer) *sampler {
+	return &sampler{
+		w:      w,
+		filter: filter,
+		opts:   opts,
+		start:  time.Now(),
+		counts: make(map[string]int),
+	}
+}
+
+// Write writes the message unless it is sampled out.
+func (s *sampler) Write(p []byte) (int, error) {
+	if !s.filter.Check(p) {
+		return s.w.Write(p)
+	}
+
+	if !s.sample(p, time.Now()) {
+		return len(p), nil
+	}
+	return s.w.Write(p)
+}
+
+// sample returns true if the message should be written, counting it.
+func (s *sampler) sample(p []byte, now time.Time) bool {
+	key := string(sampleKey(p))
+
+	s.Lock()
+	defer s.Unlock()
+
+	if now.Sub(s.start) >= s.opts.period {
+		if s.dropped > 0 {
+			// The log is locked while it writes, so report asynchronously.
+			dropped, period := s.dropped, s.opts.period
+			go log.Printf("[WARN] (logging) sampled out %d repeated log messages "+
+				"in the last %s", dropped, period)
+		}
+		s.start = now
+		s.counts = make(map[string]int)
+		s.dropped = 0
+	}
+
+	if _, ok := s.counts[key]; !ok && len(s.counts) >= maxSampleKeys {
+		key = ""
+	}
+	s.counts[key]++
+	n := s.counts[key]
+
+	if n <= s.opts.initial {
+		return true
+	}
+	if s.opts.thereafter > 0 && (n-s.opts.initial)%s.opts.thereafter == 0 {
+		return true
+	}
+	s.dropped++
+	return false
+}
+
+// sampleKey returns the part of the log message which identifies it, which
+// starts at its level, after the timestamp. Each run of digits is replaced by
+// a single zero, so messages which only differ by their numbers are identical.
+func sampleKey(p []byte) []byte {
+	if i := bytes.IndexByte(p, '['); i >= 0 {
+		p = p[i:]
+	}
+
+	key := make([]byte, 0, len(p))
+	for i, c := range p {
+		if c >= '0' && c <= '9' {
+			if i > 0 && p[i-1] >= '0' && p[i-1] <= '9' {
+				continue
+			}
+			c = '0'
+		}
+		key = append(key, c)
+	}
+	return key
+}
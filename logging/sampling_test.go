@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/logutils"
+)
+
+func TestSampler(t *testing.T) {
+	var buf bytes.Buffer
+
+	filter := NewLogFilter()
+	filter.MinLevel = logutils.LogLevel("INFO")
+	filter.Writer = &buf
+
+	s := newSampler(&samplingOptions{
+		initial:    2,
+		thereafter: 3,
+		period:     time.Hour,
+	}, filter, filter)
+
+	var written int
+	for i := 0; i < 10; i++ {
+		before := buf.Len()
+		if _, err := s.Write([]byte("2017/03/04 05:06:07 [WARN] (view) retrying\n")); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() > before {
+			written++
+		}
+	}
+
+	// The first 2, and then the 5th and 8th.
+	if written != 4 {
+		t.Errorf("expected 4 messages to be written, got %d", written)
+	}
+
+	// Other messages and filtered messages are counted separately.
+	buf.Reset()
+	s.Write([]byte("2017/03/04 05:06:07 [WARN] (view) other\n"))
+	if buf.Len() == 0 {
+		t.Errorf("expected other message to be written")
+	}
+	for i := 0; i < 10; i++ {
+		s.Write([]byte("2017/03/04 05:06:07 [DEBUG] (view) ignored\n"))
+	}
+	if l := len(s.counts); l != 2 {
+		t.Errorf("expected 2 counted messages, got %d", l)
+	}
+}
+
+func TestSampler_maxKeys(t *testing.T) {
+	filter := NewLogFilter()
+	s := newSampler(&samplingOptions{
+		initial:    1,
+		thereafter: 0,
+		period:     time.Hour,
+	}, filter, filter)
+
+	now := time.Now()
+	for i := 0; i < maxSampleKeys+10; i++ {
+		s.sample([]byte(fmt.Sprintf("[WARN] (view) %c%c", 'a'+i%26, 'a'+i/26)), now)
+	}
+	if l := len(s.counts); l != maxSampleKeys+1 {
+		t.Errorf("expected %d counted messages, got %d", maxSampleKeys+1, l)
+	}
+	if s.dropped != 9 {
+		t.Errorf("expected 9 dropped messages, got %d", s.dropped)
+	}
+}
+
+func TestSampleKey(t *testing.T) {
+	cases := []struct {
+		p string
+		e string
+	}{
+		{
+			"2017/03/04 05:06:07 [WARN] (view) retrying",
+			"[WARN] (view) retrying",
+		},
+		{
+			"2017/03/04 05:06:07 [WARN] (view) retry attempt 12 after 250ms (index 1234)",
+			"[WARN] (view) retry attempt 0 after 0ms (index 0)",
+		},
+		{
+			"no level 42",
+			"no level 0",
+		},
+	}
+
+	for _, tc := range cases {
+		if a := string(sampleKey([]byte(tc.p))); a != tc.e {
+			t.Errorf("\nexp: %q\nact: %q", tc.e, a)
+		}
+	}
+}
+
+func TestSampler_period(t *testing.T) {
+	filter := NewLogFilter()
+	s := newSampler(&samplingOptions{
+		initial:    1,
+		thereafter: 0,
+		period:     time.Minute,
+	}, filter, filter)
+
+	msg := []byte("[WARN] (view) retrying")
+	start := time.Now()
+
+	if !s.sample(msg, start) {
+		t.Errorf("expected the first message to be written")
+	}
+	if s.sample(msg, start.Add(time.Second)) {
+		t.Errorf("expected the repeated message to be sampled out")
+	}
+	if !s.sample(msg, start.Add(2*time.Minute)) {
+		t.Errorf("expected the message to be written in the next period")
+	}
+}
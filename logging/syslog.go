@@ -47,7 +47,13 @@ func (s *SyslogWrapper) Write(p []byte) (int, error) {
 		priority = gsyslog.LOG_NOTICE
 	}
 
-	// Attempt the write
-	err := s.l.WriteLevel(priority, afterLevel)
+	// Attempt the write, recording the level in the structured data if the
+	// syslog writer supports it.
+	var err error
+	if w, ok := s.l.(*syslogWriter); ok {
+		err = w.writeStructured(priority, level, afterLevel)
+	} else {
+		err = s.l.WriteLevel(priority, afterLevel)
+	}
 	return len(p), err
 }
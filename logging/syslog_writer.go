@@ -0,0 +1,354 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-rootcerts"
+	"github.com/hashicorp/go-syslog"
+)
+
+const (
+	// syslogFormatRFC3164 and syslogFormatRFC5424 are the supported message
+	// formats.
+	syslogFormatRFC3164 = "rfc3164"
+	syslogFormatRFC5424 = "rfc5424"
+
+	// syslogSDID is the ID of the structured data element of RFC5424 messages.
+	// 32473 is the private enterprise number reserved for documentation, since
+	// the element is not registered.
+	syslogSDID = "consul-template@32473"
+
+	// syslogTimeout is the maximum amount of time to connect to the syslog
+	// server or to write a message, so an unresponsive server does not block
+	// logging for long.
+	syslogTimeout = 1 * time.Second
+
+	// syslogRedialInterval is the minimum amount of time between attempts to
+	// reconnect to the syslog server. Messages are dropped in between.
+	syslogRedialInterval = 5 * time.Second
+)
+
+// syslogFacilities maps the names of syslog facilities to their codes.
+var syslogFacilities = map[string]int{
+	"KERN":     0,
+	"USER":     1,
+	"MAIL":     2,
+	"DAEMON":   3,
+	"AUTH":     4,
+	"SYSLOG":   5,
+	"LPR":      6,
+	"NEWS":     7,
+	"UUCP":     8,
+	"CRON":     9,
+	"AUTHPRIV": 10,
+	"FTP":      11,
+	"LOCAL0":   16,
+	"LOCAL1":   17,
+	"LOCAL2":   18,
+	"LOCAL3":   19,
+	"LOCAL4":   20,
+	"LOCAL5":   21,
+	"LOCAL6":   22,
+	"LOCAL7":   23,
+}
+
+// syslogLocalSockets are the paths of the local syslog socket on the various
+// platforms.
+var syslogLocalSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogWriter writes log messages to a syslog server over UDP, TCP, TLS or a
+// Unix socket, in the RFC3164 or RFC5424 format. It is implemented in pure Go
+// on all platforms, so it also works in minimal containers and on platforms
+// without a local syslog.
+type syslogWriter struct {
+	network   string
+	addr      string
+	tlsConfig *tls.Config
+
+	format   string
+	facility int
+	tag      string
+	hostname string
+	pid      int
+
+	mu         sync.Mutex
+	conn       net.Conn
+	lastDialed time.Time
+}
+
+// newSyslogWriter creates a syslog writer from the syslog options of the
+// given configuration, and connects to the server.
+func newSyslogWriter(config *Config) (*syslogWriter, error) {
+	facility, ok := syslogFacilities[strings.ToUpper(config.SyslogFacility)]
+	if !ok {
+		return nil, fmt.Errorf("invalid syslog facility: %s", config.SyslogFacility)
+	}
+
+	format := strings.ToLower(config.SyslogFormat)
+	switch format {
+	case "":
+		format = syslogFormatRFC3164
+	case syslogFormatRFC3164, syslogFormatRFC5424:
+	default:
+		return nil, fmt.Errorf("invalid syslog format %q, valid formats are %s, %s",
+			config.SyslogFormat, syslogFormatRFC3164, syslogFormatRFC5424)
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	w := &syslogWriter{
+		format:   format,
+		facility: facility,
+		tag:      config.Name,
+		hostname: hostname,
+		pid:      os.Getpid(),
+	}
+
+	if config.SyslogAddress == "" {
+		if err := w.dialLocal(); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+
+	u, err := url.Parse(config.SyslogAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid syslog address %q: %s", config.SyslogAddress, err)
+	}
+
+	switch u.Scheme {
+	case "udp", "tcp":
+		w.network, w.addr = u.Scheme, u.Host
+	case "tls":
+		w.network, w.addr = "tcp", u.Host
+		w.tlsConfig, err = syslogTLSConfig(config, u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+	case "unix", "unixgram":
+		w.network, w.addr = u.Scheme, u.Path
+	default:
+		return nil, fmt.Errorf("invalid syslog address %q: the scheme must be "+
+			"udp, tcp, tls, unix or unixgram", config.SyslogAddress)
+	}
+
+	if w.addr == "" {
+		return nil, fmt.Errorf("invalid syslog address %q: missing host or path",
+			config.SyslogAddress)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.dial(); err != nil {
+		return nil, fmt.Errorf("error connecting to syslog at %s: %s", config.SyslogAddress, err)
+	}
+	return w, nil
+}
+
+// syslogTLSConfig returns the TLS configuration for connecting to the syslog
+// server with the given host name.
+func syslogTLSConfig(config *Config, host string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: host}
+
+	if config.SyslogSSLCert != "" {
+		key := config.SyslogSSLKey
+		if key == "" {
+			key = config.SyslogSSLCert
+		}
+		cert, err := tls.LoadX509KeyPair(config.SyslogSSLCert, key)
+		if err != nil {
+			return nil, fmt.Errorf("syslog: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if config.SyslogSSLCACert != "" || config.SyslogSSLCAPath != "" {
+		if err := rootcerts.ConfigureTLS(tlsConfig, &rootcerts.Config{
+			CAFile: config.SyslogSSLCACert,
+			CAPath: config.SyslogSSLCAPath,
+		}); err != nil {
+			return nil, fmt.Errorf("syslog: configuring TLS failed: %s", err)
+		}
+	}
+
+	if config.SyslogSSLServerName != "" {
+		tlsConfig.ServerName = config.SyslogSSLServerName
+	}
+	tlsConfig.InsecureSkipVerify = !config.SyslogSSLVerify
+
+	return tlsConfig, nil
+}
+
+// dialLocal connects to the first local syslog socket which accepts the
+// connection.
+func (w *syslogWriter) dialLocal() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogLocalSockets {
+			w.network, w.addr = network, path
+			if err := w.dial(); err == nil {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no local syslog socket found, set a syslog address instead")
+}
+
+// dial connects to the syslog server. w.mu must be held.
+func (w *syslogWriter) dial() error {
+	w.lastDialed = time.Now()
+
+	dialer := &net.Dialer{Timeout: syslogTimeout}
+
+	var conn net.Conn
+	var err error
+	if w.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, w.network, w.addr, w.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(w.network, w.addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.conn = conn
+	return nil
+}
+
+// stream returns true if the connection is a stream, whose messages must be
+// framed.
+func (w *syslogWriter) stream() bool {
+	return w.network == "tcp" || w.network == "unix"
+}
+
+// Write writes the message at the notice level.
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	if err := w.WriteLevel(gsyslog.LOG_NOTICE, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteLevel writes the message at the given priority.
+func (w *syslogWriter) WriteLevel(p gsyslog.Priority, msg []byte) error {
+	return w.writeStructured(p, "", msg)
+}
+
+// writeStructured writes the message at the given priority, recording the
+// given log level in the structured data of RFC5424 messages. If writing
+// fails, it reconnects and tries again once.
+func (w *syslogWriter) writeStructured(p gsyslog.Priority, level string, msg []byte) error {
+	b := w.formatMessage(p, level, msg, time.Now())
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		if err := w.write(b); err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	if time.Since(w.lastDialed) < syslogRedialInterval {
+		return fmt.Errorf("syslog: not connected to %s", w.addr)
+	}
+	if err := w.dial(); err != nil {
+		return fmt.Errorf("syslog: %s", err)
+	}
+	return w.write(b)
+}
+
+// write writes the formatted message to the connection. w.mu must be held.
+func (w *syslogWriter) write(b []byte) error {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(syslogTimeout)); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(b)
+	return err
+}
+
+// formatMessage formats the message in the format of the writer, framed
+// for the connection.
+func (w *syslogWriter) formatMessage(p gsyslog.Priority, level string, msg []byte, now time.Time) []byte {
+	msg = bytes.TrimRight(msg, "\r\n")
+	pri := w.facility*8 + int(p)
+
+	var b bytes.Buffer
+	if w.format == syslogFormatRFC5424 {
+		component, _ := splitComponent(msg)
+		if component == "" {
+			component = "-"
+		}
+
+		sd := "-"
+		if level != "" {
+			sd = fmt.Sprintf(`[%s level="%s"]`, syslogSDID, escapeSDParam(level))
+		}
+
+		fmt.Fprintf(&b, "<%d>1 %s %s %s %d %s %s %s", pri,
+			now.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+			w.hostname, w.tag, w.pid, component, sd, msg)
+	} else {
+		fmt.Fprintf(&b, "<%d>%s %s %s[%d]: %s", pri,
+			now.Format(time.Stamp), w.hostname, w.tag, w.pid, msg)
+	}
+
+	if !w.stream() {
+		return b.Bytes()
+	}
+
+	// Stream transports need framing. RFC5424 messages are framed by octet
+	// counting (RFC6587), which allows newlines in messages, and RFC3164
+	// messages by a trailing newline, which is what older servers expect.
+	if w.format == syslogFormatRFC5424 {
+		return append([]byte(fmt.Sprintf("%d ", b.Len())), b.Bytes()...)
+	}
+	return append(b.Bytes(), '\n')
+}
+
+// Close closes the connection to the syslog server.
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// splitComponent splits the component prefix of a log message, like
+// "(runner)", from the rest of the message.
+func splitComponent(msg []byte) (string, []byte) {
+	if len(msg) == 0 || msg[0] != '(' {
+		return "", msg
+	}
+	end := bytes.IndexByte(msg, ')')
+	if end < 0 || bytes.ContainsAny(msg[1:end], " \t") {
+		return "", msg
+	}
+	return string(msg[1:end]), bytes.TrimLeft(msg[end+1:], " ")
+}
+
+// escapeSDParam escapes the characters which must be escaped in the value of
+// an RFC5424 structured data parameter.
+func escapeSDParam(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
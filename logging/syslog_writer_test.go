@@ -0,0 +1,167 @@
+package logging
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-syslog"
+	"github.com/hashicorp/logutils"
+)
+
+func TestSyslogWriter_formatMessage(t *testing.T) {
+	now := time.Date(2017, 3, 4, 5, 6, 7, 8000, time.UTC)
+
+	cases := []struct {
+		name    string
+		network string
+		format  string
+		level   string
+		msg     string
+		exp     string
+	}{
+		{
+			"rfc3164",
+			"udp",
+			syslogFormatRFC3164,
+			"INFO",
+			"(runner) rendered\n",
+			"<134>Mar  4 05:06:07 host consul-template[42]: (runner) rendered",
+		},
+		{
+			"rfc3164_stream",
+			"tcp",
+			syslogFormatRFC3164,
+			"INFO",
+			"(runner) rendered\n",
+			"<134>Mar  4 05:06:07 host consul-template[42]: (runner) rendered\n",
+		},
+		{
+			"rfc5424",
+			"udp",
+			syslogFormatRFC5424,
+			"INFO",
+			"(runner) rendered\n",
+			`<134>1 2017-03-04T05:06:07.000008Z host consul-template 42 runner ` +
+				`[consul-template@32473 level="INFO"] (runner) rendered`,
+		},
+		{
+			"rfc5424_no_level",
+			"udp",
+			syslogFormatRFC5424,
+			"",
+			"rendered",
+			`<134>1 2017-03-04T05:06:07.000008Z host consul-template 42 - - rendered`,
+		},
+		{
+			"rfc5424_stream",
+			"tcp",
+			syslogFormatRFC5424,
+			"",
+			"rendered",
+			`71 <134>1 2017-03-04T05:06:07.000008Z host consul-template 42 - - rendered`,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			w := &syslogWriter{
+				network:  tc.network,
+				format:   tc.format,
+				facility: syslogFacilities["LOCAL0"],
+				tag:      "consul-template",
+				hostname: "host",
+				pid:      42,
+			}
+
+			act := string(w.formatMessage(gsyslog.LOG_INFO, tc.level, []byte(tc.msg), now))
+			if act != tc.exp {
+				t.Errorf("\nexp: %q\nact: %q", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestNewSyslogWriter_invalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			"facility",
+			&Config{SyslogFacility: "nope", SyslogAddress: "udp://127.0.0.1:514"},
+		},
+		{
+			"format",
+			&Config{SyslogFacility: "LOCAL0", SyslogFormat: "json", SyslogAddress: "udp://127.0.0.1:514"},
+		},
+		{
+			"scheme",
+			&Config{SyslogFacility: "LOCAL0", SyslogAddress: "http://127.0.0.1:514"},
+		},
+		{
+			"host",
+			&Config{SyslogFacility: "LOCAL0", SyslogAddress: "tcp://"},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if _, err := newSyslogWriter(tc.config); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestSyslogWriter_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		line, _ := r.ReadString('\n')
+		lines <- line
+	}()
+
+	w, err := newSyslogWriter(&Config{
+		Name:           "consul-template",
+		SyslogFacility: "LOCAL0",
+		SyslogAddress:  "tcp://" + ln.Addr().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	filt := NewLogFilter()
+	filt.MinLevel = logutils.LogLevel("INFO")
+
+	s := &SyslogWrapper{w, filt}
+	if _, err := s.Write([]byte("2017/03/04 05:06:07 [WARN] (runner) test\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case line := <-lines:
+		if !strings.HasPrefix(line, "<132>") || !strings.HasSuffix(line, "consul-template["+
+			fmt.Sprint(w.pid)+"]: (runner) test\n") {
+			t.Errorf("bad message: %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not received")
+	}
+}
@@ -1,8 +1,16 @@
 package main // import "github.com/hashicorp/consul-template"
 
-import "os"
+import (
+	"os"
+
+	"github.com/hashicorp/consul-template/child"
+)
 
 func main() {
+	// The sandbox of a template command runs this binary to confine itself
+	// before it runs the command.
+	child.InitSandbox()
+
 	cli := NewCLI(os.Stdout, os.Stderr)
 	os.Exit(cli.Run(os.Args))
 }
@@ -0,0 +1,38 @@
+package manager
+
+import "strings"
+
+// Security information flags used when applying a Windows security
+// descriptor. They are defined here, rather than in the Windows-specific
+// file, so the SDDL handling can be tested on all platforms.
+const (
+	ownerSecurityInformation         = 0x00000001
+	groupSecurityInformation         = 0x00000002
+	daclSecurityInformation          = 0x00000004
+	saclSecurityInformation          = 0x00000008
+	protectedDaclSecurityInformation = 0x80000000
+)
+
+// sddlSecurityInformation returns the security information flags for the
+// parts of the given SDDL string which are present. Only the owner, group,
+// DACL, and SACL which are specified are applied to the file; the rest are
+// left as they are.
+func sddlSecurityInformation(sddl string) uint32 {
+	var info uint32
+	if strings.Contains(sddl, "O:") {
+		info |= ownerSecurityInformation
+	}
+	if strings.Contains(sddl, "G:") {
+		info |= groupSecurityInformation
+	}
+	if idx := strings.Index(sddl, "D:"); idx != -1 {
+		info |= daclSecurityInformation
+		if strings.HasPrefix(sddl[idx+2:], "P") {
+			info |= protectedDaclSecurityInformation
+		}
+	}
+	if strings.Contains(sddl, "S:") {
+		info |= saclSecurityInformation
+	}
+	return info
+}
@@ -0,0 +1,9 @@
+// +build !windows
+
+package manager
+
+// setWindowsACL is a no-op on platforms other than Windows, where the file
+// mode is used instead.
+func setWindowsACL(path, sddl string) error {
+	return nil
+}
@@ -0,0 +1,57 @@
+// +build windows
+
+package manager
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// sddlRevision1 is the only supported SDDL revision.
+const sddlRevision1 = 1
+
+var (
+	modadvapi32 = syscall.NewLazyDLL("advapi32.dll")
+
+	procConvertStringSecurityDescriptorToSecurityDescriptorW = modadvapi32.NewProc("ConvertStringSecurityDescriptorToSecurityDescriptorW")
+	procSetFileSecurityW                                     = modadvapi32.NewProc("SetFileSecurityW")
+)
+
+// setWindowsACL applies the security descriptor in the given SDDL string to
+// the file at path. This is used instead of file modes, which are mostly
+// ignored on Windows.
+func setWindowsACL(path, sddl string) error {
+	s, err := syscall.UTF16PtrFromString(sddl)
+	if err != nil {
+		return err
+	}
+
+	var sd uintptr
+	r, _, e := procConvertStringSecurityDescriptorToSecurityDescriptorW.Call(
+		uintptr(unsafe.Pointer(s)),
+		sddlRevision1,
+		uintptr(unsafe.Pointer(&sd)),
+		0,
+	)
+	if r == 0 {
+		return fmt.Errorf("invalid windows_acl %q: %s", sddl, e)
+	}
+	defer syscall.LocalFree(syscall.Handle(sd))
+
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	r, _, e = procSetFileSecurityW.Call(
+		uintptr(unsafe.Pointer(p)),
+		uintptr(sddlSecurityInformation(sddl)),
+		sd,
+	)
+	if r == 0 {
+		return fmt.Errorf("failed to apply windows_acl to %q: %s", path, e)
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+const (
+	// alarmCheckInterval is how often the alarm conditions are checked.
+	alarmCheckInterval = 5 * time.Second
+
+	// alarmEnv, alarmMessageEnv and alarmTemplateEnv are the environment
+	// variables which describe the alarm to the alarm command.
+	alarmEnv         = "CONSUL_TEMPLATE_ALARM"
+	alarmMessageEnv  = "CONSUL_TEMPLATE_ALARM_MESSAGE"
+	alarmTemplateEnv = "CONSUL_TEMPLATE_ALARM_TEMPLATE"
+
+	// alarmRenderStaleness and alarmErrorRate are the names of the alarms.
+	alarmRenderStaleness = "render_staleness"
+	alarmErrorRate       = "error_rate"
+)
+
+// alarm is an alarm which started firing.
+type alarm struct {
+	// name is the name of the alarm condition.
+	name string
+
+	// template is the destination of the template the alarm is about, if any.
+	template string
+
+	// message is the human-readable description of the alarm.
+	message string
+}
+
+// errorSample is the number of watcher errors seen at a point in time.
+type errorSample struct {
+	at    time.Time
+	count uint64
+}
+
+// alarmer tracks the alarm conditions of a runner. An alarm fires once when
+// its condition starts to hold, and fires again only after the condition has
+// cleared.
+type alarmer struct {
+	staleness time.Duration
+	threshold int
+	window    time.Duration
+
+	// started is when the runner started, which is used as the last render
+	// time for templates which never rendered.
+	started time.Time
+
+	// samples are the watcher error counts seen over the error window, oldest
+	// first. The first sample is the baseline errors are counted from.
+	samples []*errorSample
+
+	// firing is the set of alarms whose condition held at the last check.
+	firing map[string]struct{}
+}
+
+// newAlarmer creates a new alarmer for the given configuration.
+func newAlarmer(c *config.AlarmConfig, now time.Time) *alarmer {
+	return &alarmer{
+		staleness: config.TimeDurationVal(c.RenderStaleness),
+		threshold: config.IntVal(c.ErrorThreshold),
+		window:    config.TimeDurationVal(c.ErrorWindow),
+		started:   now,
+		samples:   []*errorSample{{at: now}},
+		firing:    make(map[string]struct{}),
+	}
+}
+
+// check evaluates the alarm conditions against the given render events and
+// total number of watcher errors, and returns the alarms which started firing
+// since the last check.
+func (a *alarmer) check(now time.Time, events map[string]*RenderEvent, fetchErrors uint64) []*alarm {
+	active := make(map[string]*alarm)
+
+	if a.staleness > 0 {
+		for id, event := range events {
+			if event.WouldRender {
+				continue
+			}
+
+			since := a.started
+			if event.LastWouldRender.After(since) {
+				since = event.LastWouldRender
+			}
+			if now.Sub(since) < a.staleness {
+				continue
+			}
+
+			destinations := make([]string, 0, len(event.TemplateConfigs))
+			for _, tc := range event.TemplateConfigs {
+				destinations = append(destinations, config.StringVal(tc.Destination))
+			}
+			dest := strings.Join(destinations, ",")
+
+			active[alarmRenderStaleness+"/"+id] = &alarm{
+				name:     alarmRenderStaleness,
+				template: dest,
+				message: fmt.Sprintf("template %s has not rendered in over %s",
+					dest, a.staleness),
+			}
+		}
+	}
+
+	if a.threshold > 0 {
+		a.samples = append(a.samples, &errorSample{at: now, count: fetchErrors})
+
+		// Keep the newest sample from before the window as the baseline.
+		cutoff := now.Add(-a.window)
+		i := 0
+		for i < len(a.samples)-1 && !a.samples[i+1].at.After(cutoff) {
+			i++
+		}
+		a.samples = a.samples[i:]
+
+		if errs := fetchErrors - a.samples[0].count; errs >= uint64(a.threshold) {
+			active[alarmErrorRate] = &alarm{
+				name: alarmErrorRate,
+				message: fmt.Sprintf("watcher returned %d errors in the last %s",
+					errs, a.window),
+			}
+		}
+	}
+
+	var fired []*alarm
+	for k, v := range active {
+		if _, ok := a.firing[k]; !ok {
+			fired = append(fired, v)
+		}
+	}
+	for k := range a.firing {
+		if _, ok := active[k]; !ok {
+			log.Printf("[INFO] (runner) alarm %s resolved", k)
+		}
+	}
+
+	a.firing = make(map[string]struct{}, len(active))
+	for k := range active {
+		a.firing[k] = struct{}{}
+	}
+
+	sort.Slice(fired, func(i, j int) bool {
+		if fired[i].name != fired[j].name {
+			return fired[i].name < fired[j].name
+		}
+		return fired[i].template < fired[j].template
+	})
+	return fired
+}
+
+// watchAlarms periodically checks the alarm conditions of the runner and runs
+// the alarm command for each alarm which starts firing, until the runner is
+// stopped.
+func (r *Runner) watchAlarms() {
+	a := newAlarmer(r.config.Alarm, time.Now())
+
+	ticker := time.NewTicker(alarmCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, al := range a.check(now, r.RenderEvents(), r.watcher.FetchErrors()) {
+				r.fireAlarm(al)
+			}
+		case <-r.DoneCh:
+			return
+		}
+	}
+}
+
+// fireAlarm runs the alarm command for the given alarm. Failures are logged,
+// since alarms are best-effort and should never stop the runner.
+func (r *Runner) fireAlarm(al *alarm) {
+	command := config.StringVal(r.config.Alarm.Command)
+	log.Printf("[WARN] (runner) alarm %s: %s, executing %q", al.name, al.message, command)
+
+	env := &config.EnvConfig{
+		Custom: append(r.childEnv(),
+			alarmEnv+"="+al.name,
+			alarmMessageEnv+"="+al.message,
+			alarmTemplateEnv+"="+al.template,
+		),
+	}
+	if _, err := spawnChild(&spawnChildInput{
+		Stdin:       r.inStream,
+		Stdout:      r.outStream,
+		Stderr:      r.errStream,
+		Command:     command,
+		Env:         env.Env(),
+		Timeout:     config.TimeDurationVal(r.config.Alarm.Timeout),
+		KillSignal:  config.DefaultExecKillSignal,
+		KillTimeout: config.DefaultExecKillTimeout,
+	}); err != nil {
+		log.Printf("[ERR] (runner) failed to execute alarm command %q: %s", command, err)
+	}
+}
@@ -0,0 +1,142 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestAlarmer_check(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	c := &config.AlarmConfig{
+		ErrorThreshold:  config.Int(3),
+		ErrorWindow:     config.TimeDuration(1 * time.Minute),
+		RenderStaleness: config.TimeDuration(10 * time.Minute),
+	}
+
+	stale := &RenderEvent{
+		TemplateConfigs: []*config.TemplateConfig{
+			{Destination: config.String("/tmp/out")},
+		},
+		LastWouldRender: start.Add(5 * time.Minute),
+	}
+	rendered := &RenderEvent{
+		TemplateConfigs: []*config.TemplateConfig{
+			{Destination: config.String("/tmp/out")},
+		},
+		WouldRender:     true,
+		LastWouldRender: start.Add(20 * time.Minute),
+	}
+
+	type step struct {
+		at     time.Duration
+		events map[string]*RenderEvent
+		errors uint64
+		exp    []string
+	}
+
+	cases := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			"quiet",
+			[]step{
+				{1 * time.Minute, nil, 0, nil},
+				{20 * time.Minute, map[string]*RenderEvent{"a": rendered}, 2, nil},
+			},
+		},
+		{
+			"never_rendered",
+			[]step{
+				{9 * time.Minute, map[string]*RenderEvent{"a": {}}, 0, nil},
+				{10 * time.Minute, map[string]*RenderEvent{"a": {}}, 0, []string{"render_staleness"}},
+			},
+		},
+		{
+			"stale_since_last_render",
+			[]step{
+				{14 * time.Minute, map[string]*RenderEvent{"a": stale}, 0, nil},
+				{15 * time.Minute, map[string]*RenderEvent{"a": stale}, 0, []string{"render_staleness"}},
+				{16 * time.Minute, map[string]*RenderEvent{"a": stale}, 0, nil},
+			},
+		},
+		{
+			"stale_fires_again_after_render",
+			[]step{
+				{15 * time.Minute, map[string]*RenderEvent{"a": stale}, 0, []string{"render_staleness"}},
+				{20 * time.Minute, map[string]*RenderEvent{"a": rendered}, 0, nil},
+				{20 * time.Minute, map[string]*RenderEvent{"a": stale}, 0, []string{"render_staleness"}},
+			},
+		},
+		{
+			"error_rate",
+			[]step{
+				{10 * time.Second, nil, 2, nil},
+				{20 * time.Second, nil, 3, []string{"error_rate"}},
+				{30 * time.Second, nil, 10, nil},
+			},
+		},
+		{
+			"error_rate_window",
+			[]step{
+				{10 * time.Second, nil, 2, nil},
+				{90 * time.Second, nil, 2, nil},
+				{160 * time.Second, nil, 4, nil},
+			},
+		},
+		{
+			"error_rate_resolves",
+			[]step{
+				{10 * time.Second, nil, 5, []string{"error_rate"}},
+				{2 * time.Minute, nil, 5, nil},
+				{2*time.Minute + 10*time.Second, nil, 9, []string{"error_rate"}},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			a := newAlarmer(c, start)
+			for j, s := range tc.steps {
+				var names []string
+				for _, al := range a.check(start.Add(s.at), s.events, s.errors) {
+					names = append(names, al.name)
+				}
+				if !reflect.DeepEqual(s.exp, names) {
+					t.Errorf("step %d: expected %q, got %q", j, s.exp, names)
+				}
+			}
+		})
+	}
+}
+
+func TestAlarmer_check_template(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	a := newAlarmer(&config.AlarmConfig{
+		RenderStaleness: config.TimeDuration(1 * time.Minute),
+	}, start)
+
+	alarms := a.check(start.Add(time.Minute), map[string]*RenderEvent{
+		"a": {
+			TemplateConfigs: []*config.TemplateConfig{
+				{Destination: config.String("/tmp/a")},
+				{Destination: config.String("/tmp/b")},
+			},
+		},
+	}, 0)
+	if len(alarms) != 1 {
+		t.Fatalf("expected 1 alarm, got %d", len(alarms))
+	}
+	if exp := "/tmp/a,/tmp/b"; alarms[0].template != exp {
+		t.Errorf("expected template %q, got %q", exp, alarms[0].template)
+	}
+}
@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// changeReportEnv is the name of the environment variable which holds the path
+// to the change report for a command.
+const changeReportEnv = "CT_CHANGE_REPORT"
+
+// changeReport describes what changed in the templates rendered during a run.
+// It is written as JSON for commands of templates with change_report enabled.
+type changeReport struct {
+	Templates []*templateChanges `json:"templates"`
+}
+
+// templateChanges describes the dependency values which changed since a
+// template was last rendered.
+type templateChanges struct {
+	Source      string              `json:"source"`
+	Destination string              `json:"destination"`
+	Changes     []*dependencyChange `json:"changes"`
+}
+
+// dependencyChange is the value of a dependency before and after a change. A
+// nil value means the dependency was not used by the template.
+type dependencyChange struct {
+	Dependency string      `json:"dependency"`
+	Before     interface{} `json:"before"`
+	After      interface{} `json:"after"`
+}
+
+// dependencyValues returns the current value in the brain of each of the given
+// dependencies, keyed by the dependency string.
+func dependencyValues(brain *template.Brain, deps []dep.Dependency) map[string]interface{} {
+	values := make(map[string]interface{}, len(deps))
+	for _, d := range deps {
+		if data, ok := brain.Recall(d); ok {
+			values[d.String()] = data
+		}
+	}
+	return values
+}
+
+// diffDependencyValues returns the changes between two sets of dependency
+// values, sorted by dependency.
+func diffDependencyValues(before, after map[string]interface{}) []*dependencyChange {
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range after {
+		keys = append(keys, k)
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]*dependencyChange, 0, len(keys))
+	for _, k := range keys {
+		if reflect.DeepEqual(before[k], after[k]) {
+			continue
+		}
+		changes = append(changes, &dependencyChange{
+			Dependency: k,
+			Before:     before[k],
+			After:      after[k],
+		})
+	}
+	return changes
+}
+
+// writeChangeReport writes the report to a new temporary file, returning its
+// path. The caller is responsible for removing the file.
+func writeChangeReport(report *changeReport) (string, error) {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	f, err := ioutil.TempFile("", "consul-template-change-report-")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
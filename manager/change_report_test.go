@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDiffDependencyValues(t *testing.T) {
+	cases := []struct {
+		name   string
+		before map[string]interface{}
+		after  map[string]interface{}
+		exp    []*dependencyChange
+	}{
+		{
+			"first_render",
+			nil,
+			map[string]interface{}{"key(foo)": "bar"},
+			[]*dependencyChange{
+				{Dependency: "key(foo)", Before: nil, After: "bar"},
+			},
+		},
+		{
+			"unchanged",
+			map[string]interface{}{"key(foo)": "bar"},
+			map[string]interface{}{"key(foo)": "bar"},
+			[]*dependencyChange{},
+		},
+		{
+			"changed_added_removed",
+			map[string]interface{}{
+				"key(a)": "1",
+				"key(b)": "2",
+				"key(c)": "3",
+			},
+			map[string]interface{}{
+				"key(b)": "20",
+				"key(c)": "3",
+				"key(d)": "4",
+			},
+			[]*dependencyChange{
+				{Dependency: "key(a)", Before: "1", After: nil},
+				{Dependency: "key(b)", Before: "2", After: "20"},
+				{Dependency: "key(d)", Before: nil, After: "4"},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act := diffDependencyValues(tc.before, tc.after)
+			if !reflect.DeepEqual(tc.exp, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestWriteChangeReport(t *testing.T) {
+	report := &changeReport{
+		Templates: []*templateChanges{
+			{
+				Destination: "/tmp/out",
+				Changes: []*dependencyChange{
+					{Dependency: "key(foo)", Before: "a", After: "b"},
+				},
+			},
+		},
+	}
+
+	path, err := writeChangeReport(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var act changeReport
+	if err := json.Unmarshal(b, &act); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report, &act) {
+		t.Errorf("\nexp: %#v\nact: %#v", report, &act)
+	}
+}
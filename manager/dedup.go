@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log"
 	"path"
+	"strings"
 	"sync"
 	"time"
 
@@ -31,13 +32,35 @@ const (
 	// templateDataFlag is added as a flag to the shared data values
 	// so that we can use it as a sanity check
 	templateDataFlag = 0x22b9a127a2c03520
+
+	// templateDataFormat is the version of the format of the shared data. It
+	// must be incremented whenever the encoding of the data changes, so that
+	// instances do not consume data they cannot read.
+	templateDataFormat = 1
 )
 
 // templateData is GOB encoded share the depdency values
 type templateData struct {
+	// Format is the templateDataFormat, and Fingerprint the fingerprint of
+	// the instance which wrote the data. Data written before these were added
+	// has neither.
+	Format      int
+	Fingerprint string
+
 	Data map[string]interface{}
 }
 
+// dedupFingerprint returns the fingerprint of the functions and engines of
+// templates and the format of the shared data, which differs between
+// instances that may render the same template differently.
+func dedupFingerprint() string {
+	h := md5.New()
+	fmt.Fprintf(h, "format=%d\n", templateDataFormat)
+	fmt.Fprintf(h, "functions=%s\n", strings.Join(template.Functions(), ","))
+	fmt.Fprintf(h, "engines=%s\n", strings.Join(template.Engines(), ","))
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
 // DedupManager is used to de-duplicate which instance of Consul-Template
 // is handling each template. For each template, a lock path is determined
 // using the MD5 of the template. This path is used to elect a "leader"
@@ -69,6 +92,15 @@ type DedupManager struct {
 	// templates is the set of templates we are trying to dedup
 	templates []*template.Template
 
+	// fingerprint is the fingerprint of this instance, which is included in
+	// the hash of each template unless disabled.
+	fingerprint string
+
+	// local is the set of templates whose shared data could not be used, so
+	// their dependencies are fetched locally until compatible data is shared.
+	local     map[*template.Template]struct{}
+	localLock sync.RWMutex
+
 	// leader tracks if we are currently the leader
 	leader     map[*template.Template]<-chan struct{}
 	leaderLock sync.RWMutex
@@ -91,14 +123,16 @@ type DedupManager struct {
 // NewDedupManager creates a new Dedup manager
 func NewDedupManager(config *config.DedupConfig, clients *dep.ClientSet, brain *template.Brain, templates []*template.Template) (*DedupManager, error) {
 	d := &DedupManager{
-		config:    config,
-		clients:   clients,
-		brain:     brain,
-		templates: templates,
-		leader:    make(map[*template.Template]<-chan struct{}),
-		lastWrite: make(map[*template.Template][]byte),
-		updateCh:  make(chan struct{}, 1),
-		stopCh:    make(chan struct{}),
+		config:      config,
+		clients:     clients,
+		brain:       brain,
+		templates:   templates,
+		fingerprint: dedupFingerprint(),
+		local:       make(map[*template.Template]struct{}),
+		leader:      make(map[*template.Template]<-chan struct{}),
+		lastWrite:   make(map[*template.Template][]byte),
+		updateCh:    make(chan struct{}, 1),
+		stopCh:      make(chan struct{}),
 	}
 	return d, nil
 }
@@ -190,14 +224,48 @@ func (d *DedupManager) IsLeader(tmpl *template.Template) bool {
 	}
 }
 
+// IsLocal checks if the dependencies of a template must be fetched locally,
+// because the data shared for it could not be used by this instance.
+func (d *DedupManager) IsLocal(tmpl *template.Template) bool {
+	d.localLock.RLock()
+	defer d.localLock.RUnlock()
+
+	_, ok := d.local[tmpl]
+	return ok
+}
+
+// setLocal sets if the dependencies of a template must be fetched locally.
+func (d *DedupManager) setLocal(tmpl *template.Template, local bool) {
+	d.localLock.Lock()
+	defer d.localLock.Unlock()
+
+	if local {
+		d.local[tmpl] = struct{}{}
+	} else {
+		delete(d.local, tmpl)
+	}
+}
+
+// hash returns the hash which keys the lock and the data of a template. It
+// includes the fingerprint of this instance unless that is disabled, so that
+// instances with different fingerprints elect separate leaders.
+func (d *DedupManager) hash(t *template.Template) string {
+	if !config.BoolVal(d.config.Fingerprint) {
+		return t.ID()
+	}
+	return fmt.Sprintf("%x", md5.Sum([]byte(t.ID()+d.fingerprint)))
+}
+
 // UpdateDeps is used to update the values of the dependencies for a template
 func (d *DedupManager) UpdateDeps(t *template.Template, deps []dep.Dependency) error {
 	// Calculate the path to write updates to
-	dataPath := path.Join(*d.config.Prefix, t.ID(), "data")
+	dataPath := path.Join(*d.config.Prefix, d.hash(t), "data")
 
 	// Package up the dependency data
 	td := templateData{
-		Data: make(map[string]interface{}),
+		Format:      templateDataFormat,
+		Fingerprint: d.fingerprint,
+		Data:        make(map[string]interface{}),
 	}
 	for _, dp := range deps {
 		// Skip any dependencies that can't be shared
@@ -213,16 +281,13 @@ func (d *DedupManager) UpdateDeps(t *template.Template, deps []dep.Dependency) e
 	}
 
 	// Encode via GOB and LZW compress
-	var buf bytes.Buffer
-	compress := lzw.NewWriter(&buf, lzw.LSB, 8)
-	enc := gob.NewEncoder(compress)
-	if err := enc.Encode(&td); err != nil {
-		return fmt.Errorf("encode failed: %v", err)
+	raw, err := encodeTemplateData(&td)
+	if err != nil {
+		return err
 	}
-	compress.Close()
 
 	// Compute MD5 of the buffer
-	hash := md5.Sum(buf.Bytes())
+	hash := md5.Sum(raw)
 	d.lastWriteLock.RLock()
 	existing, ok := d.lastWrite[t]
 	d.lastWriteLock.RUnlock()
@@ -235,7 +300,7 @@ func (d *DedupManager) UpdateDeps(t *template.Template, deps []dep.Dependency) e
 	// Write the KV update
 	kvPair := consulapi.KVPair{
 		Key:   dataPath,
-		Value: buf.Bytes(),
+		Value: raw,
 		Flags: templateDataFlag,
 	}
 	client := d.clients.Consul()
@@ -249,6 +314,18 @@ func (d *DedupManager) UpdateDeps(t *template.Template, deps []dep.Dependency) e
 	return nil
 }
 
+// encodeTemplateData encodes the shared data via GOB and compresses it.
+func encodeTemplateData(td *templateData) ([]byte, error) {
+	var buf bytes.Buffer
+	compress := lzw.NewWriter(&buf, lzw.LSB, 8)
+	enc := gob.NewEncoder(compress)
+	if err := enc.Encode(td); err != nil {
+		return nil, fmt.Errorf("encode failed: %v", err)
+	}
+	compress.Close()
+	return buf.Bytes(), nil
+}
+
 // UpdateCh returns a channel to watch for depedency updates
 func (d *DedupManager) UpdateCh() <-chan struct{} {
 	return d.updateCh
@@ -280,8 +357,8 @@ func (d *DedupManager) setLeader(tmpl *template.Template, lockCh <-chan struct{}
 }
 
 func (d *DedupManager) watchTemplate(client *consulapi.Client, t *template.Template) {
-	log.Printf("[INFO] (dedup) starting watch for template hash %s", t.ID())
-	path := path.Join(*d.config.Prefix, t.ID(), "data")
+	log.Printf("[INFO] (dedup) starting watch for template hash %s", d.hash(t))
+	path := path.Join(*d.config.Prefix, d.hash(t), "data")
 
 	// Determine if stale queries are allowed
 	var allowStale bool
@@ -317,7 +394,7 @@ START:
 	}
 
 	// Block for updates on the data key
-	log.Printf("[INFO] (dedup) listing data for template hash %s", t.ID())
+	log.Printf("[INFO] (dedup) listing data for template hash %s", d.hash(t))
 	pair, meta, err := client.KV().Get(path, opts)
 	if err != nil {
 		log.Printf("[ERR] (dedup) failed to get '%s': %v", path, err)
@@ -364,13 +441,16 @@ START:
 
 	// Parse the data file
 	if pair != nil && pair.Flags == templateDataFlag {
-		d.parseData(pair.Key, pair.Value)
+		d.parseData(t, pair.Key, pair.Value)
 	}
 	goto START
 }
 
-// parseData is used to update brain from a KV data pair
-func (d *DedupManager) parseData(path string, raw []byte) {
+// parseData is used to update brain from a KV data pair. Data which cannot be
+// decoded, or which was written by an instance with a different format or
+// fingerprint, is not used, and the dependencies of the template are fetched
+// locally instead until compatible data is shared.
+func (d *DedupManager) parseData(t *template.Template, path string, raw []byte) {
 	// Setup the decompression and decoders
 	r := bytes.NewReader(raw)
 	decompress := lzw.NewReader(r, lzw.LSB, 8)
@@ -380,12 +460,28 @@ func (d *DedupManager) parseData(path string, raw []byte) {
 	// Decode the data
 	var td templateData
 	if err := dec.Decode(&td); err != nil {
-		log.Printf("[ERR] (dedup) failed to decode '%s': %v",
+		log.Printf("[ERR] (dedup) failed to decode '%s', fetching locally: %v",
 			path, err)
+		d.fetchLocally(t)
+		return
+	}
+
+	if td.Format != templateDataFormat {
+		log.Printf("[WARN] (dedup) data at '%s' has format %d, expected %d, "+
+			"fetching locally", path, td.Format, templateDataFormat)
+		d.fetchLocally(t)
 		return
 	}
+	if config.BoolVal(d.config.Fingerprint) && td.Fingerprint != d.fingerprint {
+		log.Printf("[WARN] (dedup) data at '%s' was written by an instance "+
+			"with a different fingerprint, fetching locally", path)
+		d.fetchLocally(t)
+		return
+	}
+
 	log.Printf("[INFO] (dedup) loading %d dependencies from '%s'",
 		len(td.Data), path)
+	d.setLocal(t, false)
 
 	// Update the data in the brain
 	for hashCode, value := range td.Data {
@@ -399,11 +495,22 @@ func (d *DedupManager) parseData(path string, raw []byte) {
 	}
 }
 
+// fetchLocally marks the dependencies of the template to be fetched locally,
+// and triggers a run so they are.
+func (d *DedupManager) fetchLocally(t *template.Template) {
+	d.setLocal(t, true)
+
+	select {
+	case d.updateCh <- struct{}{}:
+	default:
+	}
+}
+
 func (d *DedupManager) attemptLock(client *consulapi.Client, session string, sessionCh chan struct{}, t *template.Template) {
 	defer d.wg.Done()
 	for {
-		log.Printf("[INFO] (dedup) attempting lock for template hash %s", t.ID())
-		basePath := path.Join(*d.config.Prefix, t.ID())
+		log.Printf("[INFO] (dedup) attempting lock for template hash %s", d.hash(t))
+		basePath := path.Join(*d.config.Prefix, d.hash(t))
 		lopts := &consulapi.LockOptions{
 			Key:              path.Join(basePath, "lock"),
 			Session:          session,
@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"fmt"
 	"io/ioutil"
 	"testing"
 	"time"
@@ -210,3 +211,136 @@ func TestDedup_FollowerUpdate(t *testing.T) {
 		t.Fatalf("bad: %v", data)
 	}
 }
+
+func TestDedup_hash(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := template.NewTemplate(&template.NewTemplateInput{
+		Contents: `{{ key "foo" }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := config.DefaultDedupConfig()
+	c.Finalize()
+	dedup, err := NewDedupManager(c, nil, template.NewBrain(), []*template.Template{tmpl})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprinted := dedup.hash(tmpl)
+	if fingerprinted == tmpl.ID() {
+		t.Errorf("expected hash to include the fingerprint, got the template ID")
+	}
+
+	dedup.fingerprint = "other"
+	if dedup.hash(tmpl) == fingerprinted {
+		t.Errorf("expected a different fingerprint to change the hash")
+	}
+
+	c.Fingerprint = config.Bool(false)
+	if h := dedup.hash(tmpl); h != tmpl.ID() {
+		t.Errorf("expected the template ID %q without fingerprint, got %q", tmpl.ID(), h)
+	}
+}
+
+func TestDedup_parseData(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := template.NewTemplate(&template.NewTemplateInput{
+		Contents: `{{ key "foo" }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := dependency.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name        string
+		fingerprint bool
+		td          *templateData
+		local       bool
+	}{
+		{
+			"compatible",
+			true,
+			&templateData{
+				Format:      templateDataFormat,
+				Fingerprint: dedupFingerprint(),
+			},
+			false,
+		},
+		{
+			"old_format",
+			false,
+			&templateData{},
+			true,
+		},
+		{
+			"other_fingerprint",
+			true,
+			&templateData{
+				Format:      templateDataFormat,
+				Fingerprint: "other",
+			},
+			true,
+		},
+		{
+			"other_fingerprint_disabled",
+			false,
+			&templateData{
+				Format:      templateDataFormat,
+				Fingerprint: "other",
+			},
+			false,
+		},
+		{
+			"corrupt",
+			true,
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultDedupConfig()
+			c.Fingerprint = config.Bool(tc.fingerprint)
+			c.Finalize()
+
+			brain := template.NewBrain()
+			dedup, err := NewDedupManager(c, nil, brain, []*template.Template{tmpl})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			raw := []byte("corrupt")
+			if tc.td != nil {
+				tc.td.Data = map[string]interface{}{d.String(): "bar"}
+				if raw, err = encodeTemplateData(tc.td); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			dedup.parseData(tmpl, "data", raw)
+
+			if local := dedup.IsLocal(tmpl); local != tc.local {
+				t.Errorf("expected local %t, got %t", tc.local, local)
+			}
+			_, ok := brain.Recall(d)
+			if ok == tc.local {
+				t.Errorf("expected data to be used %t, got %t", !tc.local, ok)
+			}
+			select {
+			case <-dedup.UpdateCh():
+			default:
+				t.Errorf("expected an update")
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package manager
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// orphan is a dependency which is no longer used by any template, but is
+// still watched until the dependency_gc grace period has passed.
+type orphan struct {
+	d     dep.Dependency
+	since time.Time
+}
+
+// DependencyGCStats describes the stopping of watches for dependencies which
+// are no longer used by any template.
+type DependencyGCStats struct {
+	// Watched is the number of dependencies used by templates.
+	Watched int
+
+	// Orphaned is the number of unused dependencies which are still watched
+	// because they are within the grace period.
+	Orphaned int
+
+	// Reaped is the total number of watches stopped because their dependency
+	// was unused.
+	Reaped uint64
+
+	// Sweeps is the total number of sweeps for unused dependencies, and
+	// LastSweep is the time of the most recent one.
+	Sweeps    uint64
+	LastSweep time.Time
+}
+
+// DependencyGCStats returns statistics about the stopping of watches for
+// dependencies which are no longer used by any template.
+func (r *Runner) DependencyGCStats() DependencyGCStats {
+	r.dependenciesLock.Lock()
+	defer r.dependenciesLock.Unlock()
+
+	stats := r.gcStats
+	stats.Watched = len(r.dependencies)
+	stats.Orphaned = len(r.orphans)
+	return stats
+}
+
+// sweepDependencies stops watching the unused dependencies whose grace period
+// has passed at the given time, and logs a report of them. The caller must
+// hold the dependencies lock.
+func (r *Runner) sweepDependencies(now time.Time) {
+	grace := config.TimeDurationVal(r.config.DependencyGC.GracePeriod)
+
+	var reaped []string
+	for key, o := range r.orphans {
+		if now.Sub(o.since) < grace {
+			continue
+		}
+
+		r.watcher.Remove(o.d)
+		r.brain.Forget(o.d)
+		if r.snapshot != nil {
+			r.snapshot.Forget(o.d)
+		}
+		delete(r.orphans, key)
+		reaped = append(reaped, key)
+	}
+
+	r.gcStats.Reaped += uint64(len(reaped))
+	r.gcStats.Sweeps++
+	r.gcStats.LastSweep = now
+
+	if len(reaped) > 0 {
+		sort.Strings(reaped)
+		log.Printf("[INFO] (runner) stopped watching %d unused dependencies: %s",
+			len(reaped), strings.Join(reaped, ", "))
+	}
+	if l := len(r.orphans); l > 0 {
+		log.Printf("[DEBUG] (runner) %d unused dependencies are within the "+
+			"grace period", l)
+	}
+}
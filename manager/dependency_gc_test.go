@@ -0,0 +1,153 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+func TestRunner_diffAndUpdateDeps(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		gc       *config.DependencyGCConfig
+		orphaned int
+		reaped   uint64
+	}{
+		{
+			"immediate",
+			&config.DependencyGCConfig{},
+			0,
+			1,
+		},
+		{
+			"grace_period",
+			&config.DependencyGCConfig{
+				GracePeriod: config.TimeDuration(time.Minute),
+			},
+			1,
+			0,
+		},
+		{
+			"interval",
+			&config.DependencyGCConfig{
+				Interval: config.TimeDuration(time.Minute),
+			},
+			1,
+			0,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				DependencyGC: tc.gc,
+			})
+			c.Finalize()
+
+			r, err := NewRunner(c, true, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Stop()
+
+			foo, err := dep.NewFileQuery("/tmp/foo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			bar, err := dep.NewFileQuery("/tmp/bar")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r.watcher.Add(foo)
+			r.watcher.Add(bar)
+			r.diffAndUpdateDeps(map[string]dep.Dependency{
+				foo.String(): foo,
+				bar.String(): bar,
+			})
+
+			// bar is no longer used
+			r.diffAndUpdateDeps(map[string]dep.Dependency{
+				foo.String(): foo,
+			})
+
+			stats := r.DependencyGCStats()
+			if stats.Watched != 1 {
+				t.Errorf("expected 1 watched, got %d", stats.Watched)
+			}
+			if stats.Orphaned != tc.orphaned {
+				t.Errorf("expected %d orphaned, got %d", tc.orphaned, stats.Orphaned)
+			}
+			if stats.Reaped != tc.reaped {
+				t.Errorf("expected %d reaped, got %d", tc.reaped, stats.Reaped)
+			}
+			if exp := tc.reaped == 0; r.watcher.Watching(bar) != exp {
+				t.Errorf("expected watching bar to be %t", exp)
+			}
+
+			// A sweep after the grace period reaps it
+			r.dependenciesLock.Lock()
+			r.sweepDependencies(time.Now().Add(time.Minute))
+			r.dependenciesLock.Unlock()
+
+			stats = r.DependencyGCStats()
+			if stats.Orphaned != 0 || stats.Reaped != 1 {
+				t.Errorf("expected bar to be reaped, got %#v", stats)
+			}
+			if r.watcher.Watching(bar) {
+				t.Errorf("expected bar to not be watched")
+			}
+			if !r.watcher.Watching(foo) {
+				t.Errorf("expected foo to be watched")
+			}
+		})
+	}
+
+	t.Run("used_again", func(t *testing.T) {
+		c := config.DefaultConfig().Merge(&config.Config{
+			DependencyGC: &config.DependencyGCConfig{
+				GracePeriod: config.TimeDuration(time.Minute),
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, true, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Stop()
+
+		foo, err := dep.NewFileQuery("/tmp/foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.watcher.Add(foo)
+		r.diffAndUpdateDeps(map[string]dep.Dependency{foo.String(): foo})
+		r.diffAndUpdateDeps(map[string]dep.Dependency{})
+
+		// Data for the orphan is still received
+		r.Receive(foo, "contents")
+		if _, ok := r.brain.Recall(foo); !ok {
+			t.Errorf("expected data for the orphan to be received")
+		}
+
+		r.diffAndUpdateDeps(map[string]dep.Dependency{foo.String(): foo})
+
+		r.dependenciesLock.Lock()
+		r.sweepDependencies(time.Now().Add(time.Hour))
+		r.dependenciesLock.Unlock()
+
+		if stats := r.DependencyGCStats(); stats.Orphaned != 0 || stats.Reaped != 0 {
+			t.Errorf("expected foo to be used again, got %#v", stats)
+		}
+		if !r.watcher.Watching(foo) {
+			t.Errorf("expected foo to be watched")
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"sort"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+	"github.com/pkg/errors"
+)
+
+// TemplateDependencies is the list of dependencies of a template.
+type TemplateDependencies struct {
+	// Template is the display name of the template configuration.
+	Template string `json:"template"`
+
+	// Dependencies are the names of the dependencies, sorted.
+	Dependencies []string `json:"dependencies"`
+}
+
+// Dependencies returns the dependencies of each template configuration, in
+// the order of the configuration. Only the dependencies of the first pass of
+// each template are known, since dependencies which depend on the data of
+// others are only found once that data is available. Nothing is fetched.
+func (r *Runner) Dependencies() ([]*TemplateDependencies, error) {
+	var list []*TemplateDependencies
+	for _, tmpl := range r.templates {
+		deps, err := r.firstPassDependencies(tmpl)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(deps))
+		for _, d := range deps {
+			names = append(names, d.String())
+			d.Stop()
+		}
+
+		for _, c := range r.templateConfigsFor(tmpl) {
+			list = append(list, &TemplateDependencies{
+				Template:     c.Display(),
+				Dependencies: names,
+			})
+		}
+	}
+	return list, nil
+}
+
+// firstPassDependencies returns the dependencies of the first pass of the
+// template, sorted by name.
+func (r *Runner) firstPassDependencies(tmpl *template.Template) ([]dep.Dependency, error) {
+	env, restrict := r.templateEnv()
+	result, err := tmpl.Execute(&template.ExecuteInput{
+		Brain:       template.NewBrain(),
+		Env:         env,
+		RestrictEnv: restrict,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, tmpl.Source())
+	}
+
+	deps := result.Used.List()
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].String() < deps[j].String()
+	})
+	return deps, nil
+}
@@ -0,0 +1,48 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_Dependencies(t *testing.T) {
+	t.Parallel()
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "b" }}{{ key "a" }}{{ range ls "c" }}{{ key .Key }}{{ end }}`),
+				Destination: config.String("/tmp/one"),
+			},
+			&config.TemplateConfig{
+				Contents:    config.String("hello"),
+				Destination: config.String("/tmp/two"),
+			},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, err := r.Dependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []*TemplateDependencies{
+		{
+			Template:     `"(dynamic)" => "/tmp/one"`,
+			Dependencies: []string{"kv.block(a)", "kv.block(b)", "kv.list(c)"},
+		},
+		{
+			Template:     `"(dynamic)" => "/tmp/two"`,
+			Dependencies: []string{},
+		},
+	}
+	assert.Equal(t, exp, act)
+}
@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+)
+
+// commandDrain tracks the template commands which are running, so a shutdown
+// can wait for them to finish instead of orphaning them or killing them
+// mid-run. Once it is draining, no new commands are started.
+type commandDrain struct {
+	sync.Mutex
+
+	draining bool
+
+	// idleCh is closed once no commands are running while draining.
+	idleCh chan struct{}
+
+	// running maps the running commands to whether Start returned for them,
+	// which is when a command without a timeout runs in the background.
+	running map[*child.Child]bool
+}
+
+// newCommandDrain creates a new command drain.
+func newCommandDrain() *commandDrain {
+	return &commandDrain{
+		running: make(map[*child.Child]bool),
+	}
+}
+
+// add tracks the command before it is started. It returns false if the runner
+// is shutting down, in which case the command must not be started.
+func (d *commandDrain) add(c *child.Child) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.draining {
+		return false
+	}
+	d.running[c] = false
+	return true
+}
+
+// started records that Start returned for the command, which is still
+// running in the background until its exit code is sent on exitCh.
+func (d *commandDrain) started(c *child.Child, exitCh <-chan int) {
+	d.Lock()
+	if _, ok := d.running[c]; ok {
+		d.running[c] = true
+	}
+	d.Unlock()
+
+	go func() {
+		<-exitCh
+		d.done(c)
+	}()
+}
+
+// done stops tracking the command, which exited.
+func (d *commandDrain) done(c *child.Child) {
+	d.Lock()
+	defer d.Unlock()
+
+	if _, ok := d.running[c]; ok {
+		delete(d.running, c)
+		if len(d.running) == 0 && d.idleCh != nil {
+			close(d.idleCh)
+			d.idleCh = nil
+		}
+	}
+}
+
+// isDraining returns true if the runner is shutting down.
+func (d *commandDrain) isDraining() bool {
+	d.Lock()
+	defer d.Unlock()
+	return d.draining
+}
+
+// drain stops new commands from being started, and waits up to the grace
+// period for the running ones to exit. Commands which are still running then
+// are stopped with their kill signal.
+func (d *commandDrain) drain(grace time.Duration) {
+	d.Lock()
+	d.draining = true
+	n := len(d.running)
+	if n == 0 {
+		d.Unlock()
+		return
+	}
+	idleCh := make(chan struct{})
+	d.idleCh = idleCh
+	d.Unlock()
+
+	log.Printf("[INFO] (runner) waiting up to %s for %d running commands to finish", grace, n)
+
+	select {
+	case <-idleCh:
+		log.Printf("[INFO] (runner) running commands finished")
+		return
+	case <-time.After(grace):
+	}
+
+	// Stopping a command waits for its kill timeout, so the commands are
+	// stopped without holding the lock.
+	d.Lock()
+	running := make(map[*child.Child]bool, len(d.running))
+	for c, background := range d.running {
+		running[c] = background
+	}
+	d.Unlock()
+
+	log.Printf("[WARN] (runner) stopping %d commands which did not finish within %s",
+		len(running), grace)
+	for c, background := range running {
+		if background {
+			c.Stop()
+		} else {
+			c.Cancel()
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandDrain(t *testing.T) {
+	t.Parallel()
+
+	testRunner := func() *Runner {
+		return &Runner{commands: newCommandDrain()}
+	}
+	input := func(command string, timeout time.Duration) *spawnChildInput {
+		return &spawnChildInput{
+			Stdout:      ioutil.Discard,
+			Stderr:      ioutil.Discard,
+			Command:     command,
+			Timeout:     timeout,
+			KillSignal:  os.Interrupt,
+			KillTimeout: 100 * time.Millisecond,
+		}
+	}
+
+	t.Run("waits", func(t *testing.T) {
+		r := testRunner()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- r.runCommand(input("sleep 0.5", 10*time.Second))
+		}()
+		time.Sleep(100 * time.Millisecond)
+
+		start := time.Now()
+		r.commands.drain(5 * time.Second)
+		if d := time.Since(start); d > 2*time.Second {
+			t.Errorf("expected the drain to end when the command exited, took %s", d)
+		}
+
+		select {
+		case err := <-errCh:
+			if err != nil {
+				t.Fatal(err)
+			}
+		default:
+			t.Fatal("expected the command to have finished")
+		}
+	})
+
+	t.Run("cancels", func(t *testing.T) {
+		r := testRunner()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- r.runCommand(input("sleep 30", 30*time.Second))
+		}()
+		time.Sleep(100 * time.Millisecond)
+
+		r.commands.drain(200 * time.Millisecond)
+
+		select {
+		case err := <-errCh:
+			if err == nil || !strings.Contains(err.Error(), "cancelled") {
+				t.Errorf("expected a cancellation error, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the command to be cancelled")
+		}
+	})
+
+	t.Run("stops_background", func(t *testing.T) {
+		r := testRunner()
+
+		if err := r.runCommand(input("sleep 30", 0)); err != nil {
+			t.Fatal(err)
+		}
+
+		doneCh := make(chan struct{})
+		go func() {
+			r.commands.drain(200 * time.Millisecond)
+			close(doneCh)
+		}()
+
+		select {
+		case <-doneCh:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the command to be stopped")
+		}
+	})
+
+	t.Run("refuses_new", func(t *testing.T) {
+		r := testRunner()
+		r.commands.drain(time.Second)
+
+		if err := r.runCommand(input("true", time.Second)); err == nil {
+			t.Fatal("expected an error")
+		}
+		if !r.commands.isDraining() {
+			t.Errorf("expected to be draining")
+		}
+	})
+}
@@ -0,0 +1,253 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// pathWatcher reports changes to files. Implementations may report changes
+// which did not alter the file, or report a change more than once.
+type pathWatcher interface {
+	// add starts watching the file at the given absolute path, which need not
+	// exist.
+	add(path string) error
+
+	// changes returns the channel of the paths of watched files which were
+	// written, replaced, or deleted.
+	changes() <-chan string
+
+	// close stops watching.
+	close() error
+}
+
+// driftEvent is a destination whose contents no longer match what was last
+// rendered to it.
+type driftEvent struct {
+	tmpl *template.Template
+	dest string
+
+	// reason describes the change for logging.
+	reason string
+}
+
+// driftWatcher watches the destinations of templates with watch_destination,
+// and reports those which are modified or deleted by something other than
+// Consul Template, so they can be rendered again. Its own writes leave the
+// contents as expected, so they are not reported.
+type driftWatcher struct {
+	sync.Mutex
+
+	watcher pathWatcher
+
+	// templates maps the watched destinations to their template, and paths
+	// maps their absolute paths to the destinations. expected is the hash of
+	// the contents last rendered to each destination.
+	templates map[string]*template.Template
+	paths     map[string]string
+	expected  map[string][sha256.Size]byte
+
+	eventCh chan *driftEvent
+	stopCh  chan struct{}
+}
+
+// newDriftWatcher creates a watcher of the given destinations, keyed by
+// destination. A destination is only watched once it was rendered.
+func newDriftWatcher(templates map[string]*template.Template) (*driftWatcher, error) {
+	w, err := newPathWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("watching destinations: %s", err)
+	}
+
+	d := &driftWatcher{
+		watcher:   w,
+		templates: templates,
+		paths:     make(map[string]string, len(templates)),
+		expected:  make(map[string][sha256.Size]byte, len(templates)),
+		eventCh:   make(chan *driftEvent, len(templates)),
+		stopCh:    make(chan struct{}),
+	}
+	go d.run()
+	return d, nil
+}
+
+// EventCh returns the channel of destinations which drifted. It is nil for a
+// nil watcher, so it can be selected on unconditionally.
+func (d *driftWatcher) EventCh() <-chan *driftEvent {
+	if d == nil {
+		return nil
+	}
+	return d.eventCh
+}
+
+// expect records the contents which are about to be rendered to the
+// destination, before they are written, so the write is not reported as a
+// change.
+func (d *driftWatcher) expect(dest string, contents []byte) {
+	if d == nil {
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	if _, ok := d.templates[dest]; ok {
+		d.expected[dest] = sha256.Sum256(contents)
+	}
+}
+
+// watch starts watching the destination, once it was rendered, if it is one
+// of the watched destinations.
+func (d *driftWatcher) watch(dest string) {
+	if d == nil {
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	if _, ok := d.templates[dest]; !ok {
+		return
+	}
+
+	abs, err := filepath.Abs(dest)
+	if err != nil {
+		log.Printf("[WARN] (runner) cannot watch %q: %s", dest, err)
+		return
+	}
+	if _, ok := d.paths[abs]; ok {
+		return
+	}
+	if err := d.watcher.add(abs); err != nil {
+		log.Printf("[WARN] (runner) cannot watch %q: %s", dest, err)
+		return
+	}
+	d.paths[abs] = dest
+	log.Printf("[DEBUG] (runner) watching %q for changes", dest)
+}
+
+// run checks the destinations which changed until the watcher is stopped.
+func (d *driftWatcher) run() {
+	for {
+		select {
+		case path, ok := <-d.watcher.changes():
+			if !ok {
+				return
+			}
+
+			d.Lock()
+			dest, ok := d.paths[path]
+			d.Unlock()
+			if !ok {
+				continue
+			}
+
+			if ev := d.check(dest); ev != nil {
+				select {
+				case d.eventCh <- ev:
+				case <-d.stopCh:
+					return
+				}
+			}
+		case <-d.stopCh:
+			return
+		}
+	}
+}
+
+// check returns an event if the contents of the destination differ from what
+// was last rendered to it.
+func (d *driftWatcher) check(dest string) *driftEvent {
+	d.Lock()
+	expected, ok := d.expected[dest]
+	tmpl := d.templates[dest]
+	d.Unlock()
+	if !ok {
+		return nil
+	}
+
+	var reason string
+	contents, err := ioutil.ReadFile(dest)
+	switch {
+	case os.IsNotExist(err):
+		reason = "deleted"
+	case err != nil:
+		log.Printf("[WARN] (runner) cannot check %q for changes: %s", dest, err)
+		return nil
+	case sha256.Sum256(contents) == expected:
+		return nil
+	default:
+		reason = "modified"
+		if fi, err := os.Stat(dest); err == nil {
+			reason = fmt.Sprintf("modified at %s%s",
+				fi.ModTime().Format("2006-01-02T15:04:05Z07:00"), fileOwner(fi))
+		}
+	}
+
+	return &driftEvent{tmpl: tmpl, dest: dest, reason: reason}
+}
+
+// stop stops watching the destinations.
+func (d *driftWatcher) stop() {
+	if d == nil {
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	select {
+	case <-d.stopCh:
+		return
+	default:
+	}
+	close(d.stopCh)
+	if err := d.watcher.close(); err != nil {
+		log.Printf("[WARN] (runner) failed to stop watching destinations: %s", err)
+	}
+}
+
+// initDrift starts watching the destinations of the templates which enable
+// watch_destination. Nothing is written in dry mode, and nothing is watched in
+// once mode.
+func (r *Runner) initDrift() error {
+	if r.dry || r.once {
+		return nil
+	}
+
+	templates := make(map[string]*template.Template)
+	for _, tmpl := range r.templates {
+		for _, c := range r.templateConfigsFor(tmpl) {
+			if !config.BoolVal(c.WatchDestination) {
+				continue
+			}
+
+			dest := config.StringVal(c.Destination)
+			switch {
+			case !isFileDestination(dest):
+				log.Printf("[WARN] (runner) %s: watch_destination only applies to "+
+					"files", c.Display())
+			case config.StringVal(c.Approval) == config.TemplateApprovalManual ||
+				r.rolloutEnabled(c) || r.renderGroupFor(c) != nil:
+				log.Printf("[WARN] (runner) %s: watch_destination does not apply to "+
+					"staged templates", c.Display())
+			default:
+				templates[dest] = tmpl
+			}
+		}
+	}
+	if len(templates) == 0 {
+		return nil
+	}
+
+	var err error
+	r.drift, err = newDriftWatcher(templates)
+	return err
+}
@@ -0,0 +1,167 @@
+// +build linux
+
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask are the events of a directory which may change one of the
+// files in it: a write, a file being renamed over it or away, or a deletion.
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO |
+	syscall.IN_MOVED_FROM | syscall.IN_DELETE
+
+// inotifyWatcher watches files with inotify. Consul Template replaces
+// destinations by renaming a file over them, so the directories of the files
+// are watched rather than the files themselves.
+type inotifyWatcher struct {
+	sync.Mutex
+
+	fd int
+	f  *os.File
+
+	// dirs maps watch descriptors to the directories they watch, and watched
+	// is the set of watched directories. paths is the set of watched files.
+	dirs    map[int32]string
+	watched map[string]struct{}
+	paths   map[string]struct{}
+
+	ch     chan string
+	stopCh chan struct{}
+}
+
+// newPathWatcher creates a watcher of files.
+func newPathWatcher() (pathWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %s", err)
+	}
+
+	// The file is non-blocking, so reads wait in the runtime poller and are
+	// interrupted by closing it.
+	w := &inotifyWatcher{
+		fd:      fd,
+		f:       os.NewFile(uintptr(fd), "inotify"),
+		dirs:    make(map[int32]string),
+		watched: make(map[string]struct{}),
+		paths:   make(map[string]struct{}),
+		ch:      make(chan string, 16),
+		stopCh:  make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// add implements pathWatcher.
+func (w *inotifyWatcher) add(path string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	dir := filepath.Dir(path)
+	if _, ok := w.watched[dir]; !ok {
+		wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+		if err != nil {
+			return fmt.Errorf("inotify: %s: %s", dir, err)
+		}
+		w.dirs[int32(wd)] = dir
+		w.watched[dir] = struct{}{}
+	}
+	w.paths[path] = struct{}{}
+	return nil
+}
+
+// changes implements pathWatcher.
+func (w *inotifyWatcher) changes() <-chan string {
+	return w.ch
+}
+
+// close implements pathWatcher.
+func (w *inotifyWatcher) close() error {
+	close(w.stopCh)
+	return w.f.Close()
+}
+
+// run reads the events until the watcher is closed.
+func (w *inotifyWatcher) run() {
+	defer close(w.ch)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			start := off + syscall.SizeofInotifyEvent
+			off = start + int(ev.Len)
+
+			// Events were lost, so any of the files may have changed.
+			if ev.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				for _, path := range w.list() {
+					if !w.send(path) {
+						return
+					}
+				}
+				continue
+			}
+
+			name := string(bytes.TrimRight(buf[start:off], "\x00"))
+			w.Lock()
+			path := filepath.Join(w.dirs[ev.Wd], name)
+			_, ok := w.paths[path]
+			w.Unlock()
+
+			if ok && !w.send(path) {
+				return
+			}
+		}
+	}
+}
+
+// list returns the watched files.
+func (w *inotifyWatcher) list() []string {
+	w.Lock()
+	defer w.Unlock()
+
+	paths := make([]string, 0, len(w.paths))
+	for path := range w.paths {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// send reports the change of the file, and returns false if the watcher was
+// closed.
+func (w *inotifyWatcher) send(path string) bool {
+	select {
+	case w.ch <- path:
+		return true
+	case <-w.stopCh:
+		return false
+	}
+}
+
+// fileOwner describes the owner of a changed file, since inotify does not
+// report which process changed it.
+func fileOwner(fi os.FileInfo) string {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+
+	uid := strconv.FormatUint(uint64(st.Uid), 10)
+	if u, err := user.LookupId(uid); err == nil {
+		return fmt.Sprintf(", owned by %s (uid %s)", u.Username, uid)
+	}
+	return fmt.Sprintf(", owned by uid %s", uid)
+}
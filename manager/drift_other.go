@@ -0,0 +1,92 @@
+// +build !linux
+
+package manager
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// driftPollInterval is how often watched files are checked on platforms
+// without inotify.
+const driftPollInterval = 2 * time.Second
+
+// pollWatcher watches files by reporting each of them on an interval, which
+// the caller compares with the expected contents.
+type pollWatcher struct {
+	sync.Mutex
+
+	paths map[string]struct{}
+
+	ch     chan string
+	stopCh chan struct{}
+}
+
+// newPathWatcher creates a watcher of files.
+func newPathWatcher() (pathWatcher, error) {
+	w := &pollWatcher{
+		paths:  make(map[string]struct{}),
+		ch:     make(chan string),
+		stopCh: make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// add implements pathWatcher.
+func (w *pollWatcher) add(path string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	w.paths[path] = struct{}{}
+	return nil
+}
+
+// changes implements pathWatcher.
+func (w *pollWatcher) changes() <-chan string {
+	return w.ch
+}
+
+// close implements pathWatcher.
+func (w *pollWatcher) close() error {
+	close(w.stopCh)
+	return nil
+}
+
+// run reports the files on an interval until the watcher is closed.
+func (w *pollWatcher) run() {
+	defer close(w.ch)
+
+	ticker := time.NewTicker(driftPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.stopCh:
+			return
+		}
+
+		w.Lock()
+		paths := make([]string, 0, len(w.paths))
+		for path := range w.paths {
+			paths = append(paths, path)
+		}
+		w.Unlock()
+
+		for _, path := range paths {
+			select {
+			case w.ch <- path:
+			case <-w.stopCh:
+				return
+			}
+		}
+	}
+}
+
+// fileOwner describes the owner of a changed file. It is not available on
+// this platform.
+func fileOwner(fi os.FileInfo) string {
+	return ""
+}
@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/template"
+)
+
+func TestDriftWatcher(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmpl, err := template.NewTemplate(&template.NewTemplateInput{
+		Contents: "desired",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(dir, "out.conf")
+	d, err := newDriftWatcher(map[string]*template.Template{dest: tmpl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.stop()
+
+	render := func() {
+		d.expect(dest, []byte("desired"))
+		if err := AtomicWrite(dest, []byte("desired"), 0644, false); err != nil {
+			t.Fatal(err)
+		}
+		d.watch(dest)
+	}
+	expectEvent := func(reason string) {
+		select {
+		case ev := <-d.EventCh():
+			if ev.dest != dest || ev.tmpl != tmpl {
+				t.Errorf("unexpected event for %q", ev.dest)
+			}
+			if !strings.HasPrefix(ev.reason, reason) {
+				t.Errorf("expected %q, got %q", reason, ev.reason)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %s event", reason)
+		}
+	}
+	expectNoEvent := func() {
+		select {
+		case ev := <-d.EventCh():
+			t.Fatalf("unexpected event: %q was %s", ev.dest, ev.reason)
+		case <-time.After(3 * time.Second):
+		}
+	}
+
+	render()
+
+	// Rendering the expected contents again is not a change.
+	render()
+	expectNoEvent()
+
+	if err := ioutil.WriteFile(dest, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent("modified")
+
+	render()
+	if err := os.Remove(dest); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent("deleted")
+}
+
+func TestDriftWatcher_unwatched(t *testing.T) {
+	t.Parallel()
+
+	var d *driftWatcher
+	d.expect("/tmp/foo", []byte("foo"))
+	d.watch("/tmp/foo")
+	d.stop()
+
+	if d.EventCh() != nil {
+		t.Errorf("expected no events")
+	}
+}
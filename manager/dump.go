@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+// Dump writes the stacks of all goroutines and the internal state of the
+// runner to a new file in the given directory, or the system's temporary
+// directory if it is empty, and returns the path of the file. The runner keeps
+// running. The stacks are written first, so they are available even if the
+// runner is wedged while holding one of its locks.
+func (r *Runner) Dump(dir string) (string, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	now := time.Now().UTC()
+	path := filepath.Join(dir, fmt.Sprintf("consul-template-dump-%d-%s.txt",
+		os.Getpid(), now.Format("20060102T150405Z")))
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("runner: failed to create dump: %s", err)
+	}
+	defer f.Close()
+
+	log.Printf("[INFO] (runner) writing dump to %q", path)
+
+	fmt.Fprintf(f, "Consul Template dump of process %d at %s\n",
+		os.Getpid(), now.Format(time.RFC3339))
+
+	fmt.Fprintf(f, "\n== Goroutines ==\n\n")
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		return "", fmt.Errorf("runner: failed to write dump: %s", err)
+	}
+
+	r.dumpTemplates(f)
+	r.dumpDependencies(f)
+
+	return path, f.Sync()
+}
+
+// dumpTemplates writes the last render of each template and whether its
+// contents are staged, waiting to be promoted.
+func (r *Runner) dumpTemplates(w io.Writer) {
+	events := r.RenderEvents()
+
+	fmt.Fprintf(w, "\n== Templates ==\n")
+	for _, tmpl := range r.templates {
+		fmt.Fprintf(w, "\n%s\n", tmpl.ID())
+
+		event, ok := events[tmpl.ID()]
+		if !ok {
+			fmt.Fprintf(w, "  never ran\n")
+		} else {
+			fmt.Fprintf(w, "  last would render: %s\n", dumpTime(event.LastWouldRender))
+			fmt.Fprintf(w, "  last did render:   %s\n", dumpTime(event.LastDidRender))
+		}
+
+		for _, c := range r.templateConfigsFor(tmpl) {
+			fmt.Fprintf(w, "  %s\n", c.Display())
+			pending := pendingPath(config.StringVal(c.Destination))
+			if _, err := os.Stat(pending); err == nil {
+				fmt.Fprintf(w, "    staged at %q\n", pending)
+			}
+		}
+	}
+}
+
+// dumpDependencies writes each dependency with the last index the watcher
+// saw for it, and whether data was received for it yet.
+func (r *Runner) dumpDependencies(w io.Writer) {
+	r.dependenciesLock.Lock()
+	defer r.dependenciesLock.Unlock()
+
+	keys := make([]string, 0, len(r.dependencies)+len(r.orphans))
+	for k := range r.dependencies {
+		keys = append(keys, k)
+	}
+	for k := range r.orphans {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "\n== Dependencies ==\n\n")
+	for _, k := range keys {
+		d, ok := r.dependencies[k]
+		if !ok {
+			d = r.orphans[k].d
+		}
+
+		data := "received"
+		if _, ok := r.brain.Recall(d); !ok {
+			data = "waiting"
+		}
+		fmt.Fprintf(w, "%s\n  index: %d, data: %s, watching: %t",
+			k, r.watcher.LastIndex(d), data, r.watcher.Watching(d))
+		if o, ok := r.orphans[k]; ok {
+			fmt.Fprintf(w, ", unused since: %s", dumpTime(o.since))
+		}
+		fmt.Fprintf(w, "\n")
+	}
+}
+
+// dumpTime formats the given time for a dump.
+func dumpTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,75 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+func TestRunner_Dump(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source")
+	out := filepath.Join(dir, "out")
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ file "` + source + `" }}`),
+				Destination: config.String(out),
+			},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := r.Dump(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("expected dump in %q, got %q", dir, path)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := dep.NewFileQuery(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{
+		"== Goroutines ==",
+		"TestRunner_Dump",
+		"== Templates ==",
+		"never ran",
+		"== Dependencies ==",
+		d.String() + "\n  index: 0, data: waiting, watching: true",
+	} {
+		if !strings.Contains(string(b), exp) {
+			t.Errorf("expected dump to contain %q, got:\n%s", exp, b)
+		}
+	}
+}
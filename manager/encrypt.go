@@ -0,0 +1,294 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+const (
+	// encryptedVersion is the version of the format of encrypted files.
+	// Files with a different version cannot be decrypted.
+	encryptedVersion = 1
+
+	// encryptionKeySize is the size of the AES-256 keys files are encrypted
+	// with.
+	encryptionKeySize = 32
+)
+
+// encryptedFile is the JSON encoded representation of an encrypted file. It
+// records where the key came from, so the file can be decrypted with nothing
+// more than access to the same key.
+type encryptedFile struct {
+	Version int `json:"version"`
+
+	// KeyFile is the path of the key file the contents were encrypted with.
+	KeyFile string `json:"key_file,omitempty"`
+
+	// TransitMount and TransitKey are the Vault transit key which wrapped the
+	// data key the contents were encrypted with, and WrappedKey is the
+	// ciphertext of that data key.
+	TransitMount string `json:"transit_mount,omitempty"`
+	TransitKey   string `json:"transit_key,omitempty"`
+	WrappedKey   string `json:"wrapped_key,omitempty"`
+
+	// Nonce and Ciphertext are the AES-256-GCM nonce and sealed contents.
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// encrypter encrypts the contents of files before they are written to disk.
+type encrypter struct {
+	sync.Mutex
+
+	config  *config.EncryptionConfig
+	clients *dep.ClientSet
+
+	// key and wrappedKey are the Vault transit data key, which is generated
+	// on first use and reused for each file after that.
+	key        []byte
+	wrappedKey string
+}
+
+// newEncrypter returns an encrypter for the given configuration, or nil if
+// encryption is not enabled. The clients are used to reach Vault transit.
+func newEncrypter(c *config.EncryptionConfig, clients *dep.ClientSet) *encrypter {
+	if c == nil || !config.BoolVal(c.Enabled) {
+		return nil
+	}
+	return &encrypter{config: c, clients: clients}
+}
+
+// validateEncryption returns an error if encryption is enabled without exactly
+// one of a key file or a Vault transit key, or if the key file is unusable.
+func validateEncryption(c *config.EncryptionConfig) error {
+	if c == nil || !config.BoolVal(c.Enabled) {
+		return nil
+	}
+
+	keyFile := config.StringVal(c.KeyFile)
+	transitKey := config.StringVal(c.VaultTransitKey)
+	switch {
+	case keyFile != "" && transitKey != "":
+		return fmt.Errorf("encryption: key_file and vault_transit_key cannot " +
+			"both be set")
+	case keyFile == "" && transitKey == "":
+		return fmt.Errorf("encryption: one of key_file or vault_transit_key " +
+			"is required")
+	case keyFile != "":
+		if _, err := readEncryptionKey(keyFile); err != nil {
+			return fmt.Errorf("encryption: %s", err)
+		}
+	}
+	return nil
+}
+
+// encrypt returns the encrypted form of the contents.
+func (e *encrypter) encrypt(contents []byte) ([]byte, error) {
+	f := encryptedFile{Version: encryptedVersion}
+
+	var key []byte
+	if keyFile := config.StringVal(e.config.KeyFile); keyFile != "" {
+		k, err := readEncryptionKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, f.KeyFile = k, keyFile
+	} else {
+		k, wrapped, err := e.dataKey()
+		if err != nil {
+			return nil, err
+		}
+		key, f.WrappedKey = k, wrapped
+		f.TransitMount = config.StringVal(e.config.VaultTransitMount)
+		f.TransitKey = config.StringVal(e.config.VaultTransitKey)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, f.Nonce); err != nil {
+		return nil, err
+	}
+	f.Ciphertext = gcm.Seal(nil, f.Nonce, contents, nil)
+
+	return json.Marshal(&f)
+}
+
+// decrypt returns the contents of the encrypted file.
+func (e *encrypter) decrypt(raw []byte) ([]byte, error) {
+	return decrypt(e.clients, raw)
+}
+
+// dataKey returns the plaintext and wrapped Vault transit data key, generating
+// it if needed.
+func (e *encrypter) dataKey() ([]byte, string, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.key != nil {
+		return e.key, e.wrappedKey, nil
+	}
+
+	mount := config.StringVal(e.config.VaultTransitMount)
+	name := config.StringVal(e.config.VaultTransitKey)
+	secret, err := e.clients.Vault().Logical().Write(
+		transitPath(mount, "datakey/plaintext", name), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("encryption: failed generating data key: %s", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, "", fmt.Errorf("encryption: no data key returned by %s", mount)
+	}
+
+	key, err := transitPlaintext(secret.Data)
+	if err != nil {
+		return nil, "", err
+	}
+	wrapped, ok := secret.Data["ciphertext"].(string)
+	if !ok || wrapped == "" {
+		return nil, "", fmt.Errorf("encryption: no wrapped data key returned by %s", mount)
+	}
+
+	log.Printf("[DEBUG] (encryption) generated data key with %s/keys/%s", mount, name)
+
+	e.key, e.wrappedKey = key, wrapped
+	return key, wrapped, nil
+}
+
+// decrypt returns the contents of the encrypted file, unwrapping its data key
+// with Vault transit if needed.
+func decrypt(clients *dep.ClientSet, raw []byte) ([]byte, error) {
+	var f encryptedFile
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil || len(f.Ciphertext) == 0 {
+		return nil, fmt.Errorf("encryption: not an encrypted file")
+	}
+	if f.Version != encryptedVersion {
+		return nil, fmt.Errorf("encryption: unsupported version %d (expected %d)",
+			f.Version, encryptedVersion)
+	}
+
+	var key []byte
+	switch {
+	case f.KeyFile != "":
+		k, err := readEncryptionKey(f.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	case f.TransitKey != "":
+		if clients == nil {
+			return nil, fmt.Errorf("encryption: vault is required to unwrap the data key")
+		}
+		secret, err := clients.Vault().Logical().Write(
+			transitPath(f.TransitMount, "decrypt", f.TransitKey),
+			map[string]interface{}{"ciphertext": f.WrappedKey})
+		if err != nil {
+			return nil, fmt.Errorf("encryption: failed unwrapping data key: %s", err)
+		}
+		if secret == nil || secret.Data == nil {
+			return nil, fmt.Errorf("encryption: no data key returned by %s", f.TransitMount)
+		}
+		k, err := transitPlaintext(secret.Data)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	default:
+		return nil, fmt.Errorf("encryption: file does not name its key")
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("encryption: invalid nonce")
+	}
+	contents, err := gcm.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: failed decrypting: %s", err)
+	}
+	return contents, nil
+}
+
+// DecryptFile returns the contents of a backup or snapshot which was
+// encrypted at rest. The Vault configuration is used to unwrap the data key
+// of files encrypted with Vault transit.
+func DecryptFile(c *config.Config, path string) ([]byte, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := newClientSet(c)
+	if err != nil {
+		return nil, err
+	}
+	defer clients.Stop()
+
+	return decrypt(clients, raw)
+}
+
+// readEncryptionKey reads a 32 byte key, raw or base64 encoded, from the file
+// at the given path.
+func readEncryptionKey(path string) ([]byte, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading key file: %s", err)
+	}
+	if len(raw) == encryptionKeySize {
+		return raw, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(raw)))
+	if err != nil || len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("key file %q must hold a %d byte key, raw or "+
+			"base64 encoded", path, encryptionKeySize)
+	}
+	return key, nil
+}
+
+// transitPath returns the path of the Vault transit endpoint for the key.
+func transitPath(mount, endpoint, name string) string {
+	return strings.Trim(mount, "/") + "/" + endpoint + "/" + name
+}
+
+// transitPlaintext returns the decoded plaintext key of a Vault transit
+// response.
+func transitPlaintext(data map[string]interface{}) ([]byte, error) {
+	s, ok := data["plaintext"].(string)
+	if !ok {
+		return nil, fmt.Errorf("encryption: no plaintext data key returned")
+	}
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption: data key must be %d bytes", encryptionKeySize)
+	}
+	return key, nil
+}
+
+// newGCM returns the AES-256-GCM cipher for the key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,318 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// testEncryptionKeyFile writes a base64 encoded key to a file in the directory
+// and returns its path.
+func testEncryptionKeyFile(t *testing.T, dir string) string {
+	key := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "key")
+	if err := ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testTransit is a fake Vault transit secrets engine. Data keys are "wrapped"
+// by prefixing them, and each request is counted.
+type testTransit struct {
+	sync.Mutex
+	datakeys int
+	decrypts int
+}
+
+func (tt *testTransit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tt.Lock()
+	defer tt.Unlock()
+
+	var data map[string]interface{}
+	switch r.URL.Path {
+	case "/v1/transit/datakey/plaintext/ct":
+		tt.datakeys++
+		key := make([]byte, encryptionKeySize)
+		rand.Read(key)
+		plaintext := base64.StdEncoding.EncodeToString(key)
+		data = map[string]interface{}{
+			"plaintext":  plaintext,
+			"ciphertext": "vault:v1:" + plaintext,
+		}
+	case "/v1/transit/decrypt/ct":
+		tt.decrypts++
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data = map[string]interface{}{
+			"plaintext": strings.TrimPrefix(body["ciphertext"], "vault:v1:"),
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func testTransitClients(t *testing.T, tt *testTransit) (*dep.ClientSet, func()) {
+	ts := httptest.NewServer(tt)
+
+	clients := dep.NewClientSet()
+	if err := clients.CreateVaultClient(&dep.CreateVaultClientInput{
+		Address: ts.URL,
+		Token:   "s.token",
+	}); err != nil {
+		ts.Close()
+		t.Fatal(err)
+	}
+	return clients, ts.Close
+}
+
+func TestEncrypter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("key_file", func(t *testing.T) {
+		keyFile := testEncryptionKeyFile(t, dir)
+		e := newEncrypter(&config.EncryptionConfig{
+			Enabled: config.Bool(true),
+			KeyFile: config.String(keyFile),
+		}, nil)
+
+		sealed, err := e.encrypt([]byte("password"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(sealed, []byte("password")) {
+			t.Fatalf("expected contents to be encrypted, got %q", sealed)
+		}
+
+		contents, err := e.decrypt(sealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(contents) != "password" {
+			t.Errorf("expected %q, got %q", "password", contents)
+		}
+
+		// A file encrypted with another key cannot be decrypted.
+		testEncryptionKeyFile(t, dir)
+		if _, err := e.decrypt(sealed); err == nil {
+			t.Error("expected error decrypting with another key")
+		}
+	})
+
+	t.Run("raw_key_file", func(t *testing.T) {
+		keyFile := filepath.Join(dir, "raw")
+		if err := ioutil.WriteFile(keyFile, bytes.Repeat([]byte{7}, encryptionKeySize), 0600); err != nil {
+			t.Fatal(err)
+		}
+		e := newEncrypter(&config.EncryptionConfig{
+			Enabled: config.Bool(true),
+			KeyFile: config.String(keyFile),
+		}, nil)
+
+		sealed, err := e.encrypt([]byte("password"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := decrypt(nil, sealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(contents) != "password" {
+			t.Errorf("expected %q, got %q", "password", contents)
+		}
+	})
+
+	t.Run("vault_transit", func(t *testing.T) {
+		tt := &testTransit{}
+		clients, stop := testTransitClients(t, tt)
+		defer stop()
+
+		e := newEncrypter(&config.EncryptionConfig{
+			Enabled:           config.Bool(true),
+			VaultTransitKey:   config.String("ct"),
+			VaultTransitMount: config.String("transit/"),
+		}, clients)
+
+		for i := 0; i < 2; i++ {
+			sealed, err := e.encrypt([]byte(fmt.Sprintf("password%d", i)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents, err := decrypt(clients, sealed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := fmt.Sprintf("password%d", i); string(contents) != exp {
+				t.Errorf("expected %q, got %q", exp, contents)
+			}
+		}
+
+		tt.Lock()
+		defer tt.Unlock()
+		if tt.datakeys != 1 || tt.decrypts != 2 {
+			t.Errorf("expected 1 data key and 2 decrypts, got %d and %d",
+				tt.datakeys, tt.decrypts)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		if e := newEncrypter(&config.EncryptionConfig{
+			Enabled: config.Bool(false),
+			KeyFile: config.String("key"),
+		}, nil); e != nil {
+			t.Errorf("expected nil encrypter, got %#v", e)
+		}
+	})
+
+	t.Run("not_encrypted", func(t *testing.T) {
+		if _, err := decrypt(nil, []byte("plaintext")); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestValidateEncryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyFile := testEncryptionKeyFile(t, dir)
+	shortKeyFile := filepath.Join(dir, "short")
+	if err := ioutil.WriteFile(shortKeyFile, []byte("c2hvcnQ="), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		c    *config.EncryptionConfig
+		err  bool
+	}{
+		{
+			"disabled",
+			&config.EncryptionConfig{},
+			false,
+		},
+		{
+			"key_file",
+			&config.EncryptionConfig{KeyFile: config.String(keyFile)},
+			false,
+		},
+		{
+			"vault_transit_key",
+			&config.EncryptionConfig{VaultTransitKey: config.String("ct")},
+			false,
+		},
+		{
+			"both",
+			&config.EncryptionConfig{
+				KeyFile:         config.String(keyFile),
+				VaultTransitKey: config.String("ct"),
+			},
+			true,
+		},
+		{
+			"neither",
+			&config.EncryptionConfig{Enabled: config.Bool(true)},
+			true,
+		},
+		{
+			"missing_key_file",
+			&config.EncryptionConfig{KeyFile: config.String(filepath.Join(dir, "nope"))},
+			true,
+		},
+		{
+			"short_key",
+			&config.EncryptionConfig{KeyFile: config.String(shortKeyFile)},
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.c.Finalize()
+			if err := validateEncryption(tc.c); (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSnapshotter_encrypted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d, err := dep.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := newEncrypter(&config.EncryptionConfig{
+		Enabled: config.Bool(true),
+		KeyFile: config.String(testEncryptionKeyFile(t, dir)),
+	}, nil)
+	path := filepath.Join(dir, "snapshot")
+
+	sd := &snapshotData{
+		Version: snapshotVersion,
+		Indexes: map[string]uint64{d.String(): 10},
+		Data:    map[string][]byte{d.String(): testSnapshotValue(t, "secret-value")},
+	}
+	plain := testSnapshotFile(t, sd)
+	defer os.Remove(plain)
+	raw, err := ioutil.ReadFile(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := enc.encrypt(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, sealed, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	brain := template.NewBrain()
+	if indexes := newSnapshotter(path, enc).Restore(brain); indexes[d.String()] != 10 {
+		t.Errorf("expected index 10, got %#v", indexes)
+	}
+	if data, ok := brain.Recall(d); !ok || data != "secret-value" {
+		t.Errorf("expected brain to have %q, got %#v", "secret-value", data)
+	}
+
+	// A plaintext snapshot is ignored when encryption is enabled.
+	if indexes := newSnapshotter(plain, enc).Restore(template.NewBrain()); indexes != nil {
+		t.Errorf("expected nil indexes, got %#v", indexes)
+	}
+}
@@ -1,6 +1,12 @@
 package manager
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+
+	"github.com/hashicorp/consul-template/exitcodes"
+	"github.com/pkg/errors"
+)
 
 // ErrExitable is an interface that defines an integer ExitStatus() function.
 type ErrExitable interface {
@@ -29,3 +35,74 @@ func (e *ErrChildDied) Error() string {
 func (e *ErrChildDied) ExitStatus() int {
 	return e.code
 }
+
+var _ ErrExitable = new(ErrRender)
+var _ ErrExitable = new(ErrCommand)
+var _ ErrExitable = new(ErrBackendUnreachable)
+
+// ErrRender is the error returned when a template fails to execute, or its
+// contents cannot be written to the destination.
+type ErrRender struct {
+	err error
+}
+
+// NewErrRender creates a new render error caused by the given error.
+func NewErrRender(err error) *ErrRender {
+	return &ErrRender{err: err}
+}
+
+// Error implements the error interface.
+func (e *ErrRender) Error() string { return e.err.Error() }
+
+// Cause returns the error which caused the render to fail.
+func (e *ErrRender) Cause() error { return e.err }
+
+// ExitStatus implements the ErrExitable interface.
+func (e *ErrRender) ExitStatus() int { return exitcodes.RenderError }
+
+// ErrCommand is the error returned when the commands of templates fail.
+type ErrCommand struct {
+	err error
+}
+
+// NewErrCommand creates a new command error caused by the given error.
+func NewErrCommand(err error) *ErrCommand {
+	return &ErrCommand{err: err}
+}
+
+// Error implements the error interface.
+func (e *ErrCommand) Error() string { return e.err.Error() }
+
+// Cause returns the error which caused the commands to fail.
+func (e *ErrCommand) Cause() error { return e.err }
+
+// ExitStatus implements the ErrExitable interface.
+func (e *ErrCommand) ExitStatus() int { return exitcodes.CommandError }
+
+// ErrBackendUnreachable is the error returned in once mode when Consul or
+// Vault could not be reached after the retries.
+type ErrBackendUnreachable struct {
+	err error
+}
+
+// NewErrBackendUnreachable creates a new error for the given error of the
+// backend.
+func NewErrBackendUnreachable(err error) *ErrBackendUnreachable {
+	return &ErrBackendUnreachable{err: err}
+}
+
+// Error implements the error interface.
+func (e *ErrBackendUnreachable) Error() string { return e.err.Error() }
+
+// Cause returns the error returned by the backend.
+func (e *ErrBackendUnreachable) Cause() error { return e.err }
+
+// ExitStatus implements the ErrExitable interface.
+func (e *ErrBackendUnreachable) ExitStatus() int { return exitcodes.BackendUnreachable }
+
+// isUnreachable returns true if the error was caused by a failure to connect
+// to a backend, rather than an error response from it.
+func isUnreachable(err error) bool {
+	_, ok := errors.Cause(err).(net.Error)
+	return ok
+}
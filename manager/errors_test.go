@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/exitcodes"
+	"github.com/pkg/errors"
+)
+
+func TestRunner_exitStatus(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name     string
+		contents string
+		command  string
+		consul   string
+		exp      int
+	}{
+		{
+			"render",
+			`{{ "nope" | parseInt }}`,
+			"",
+			"",
+			exitcodes.RenderError,
+		},
+		{
+			"command",
+			"hello",
+			"exit 1",
+			"",
+			exitcodes.CommandError,
+		},
+		{
+			"backend_unreachable",
+			`{{ key "foo" }}`,
+			"",
+			"127.0.0.1:1",
+			exitcodes.BackendUnreachable,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				Consul: &config.ConsulConfig{
+					Address: config.String(tc.consul),
+					Retry: &config.RetryConfig{
+						Enabled: config.Bool(false),
+					},
+				},
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String(tc.contents),
+						Command:     config.String(tc.command),
+						Destination: config.String(filepath.Join(dir, tc.name)),
+					},
+				},
+			})
+			c.Finalize()
+
+			r, err := NewRunner(c, false, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			go r.Start()
+			defer r.Stop()
+
+			select {
+			case err := <-r.ErrCh:
+				typed, ok := err.(ErrExitable)
+				if !ok {
+					t.Fatalf("expected an exit status for %q", err)
+				}
+				if act := typed.ExitStatus(); act != tc.exp {
+					t.Errorf("expected exit status %d to be %d for %q", act, tc.exp, err)
+				}
+			case <-r.DoneCh:
+				t.Fatal("expected an error")
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout")
+			}
+		})
+	}
+}
+
+func TestIsUnreachable(t *testing.T) {
+	t.Parallel()
+
+	if isUnreachable(errors.New("Unexpected response code: 500")) {
+		t.Errorf("expected an error response not to be unreachable")
+	}
+
+	_, err := (&net.Dialer{}).Dial("tcp", "127.0.0.1:1")
+	if err == nil {
+		t.Skip("expected nothing to listen on port 1")
+	}
+	if !isUnreachable(errors.Wrap(err, "kv.block(foo)")) {
+		t.Errorf("expected %q to be unreachable", err)
+	}
+}
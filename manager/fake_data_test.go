@@ -0,0 +1,131 @@
+package manager
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRunner_fakeData(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`
+kv.block(app/name): web
+health.service(web|passing):
+  - Address: 10.0.0.1
+    Port: 8080
+  - Address: 10.0.0.2
+    Port: 8080
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	newConfig := func(contents string) *config.Config {
+		c := config.DefaultConfig().Merge(&config.Config{
+			FakeData: config.String(f.Name()),
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(contents),
+					Destination: config.String("/tmp/fake"),
+				},
+			},
+		})
+		c.Finalize()
+		return c
+	}
+
+	t.Run("render", func(t *testing.T) {
+		r, err := NewRunner(newConfig(`{{ key "app/name" }}:{{ range service "web" }} {{ .Address }}:{{ .Port }}{{ end }}`), true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(bytes.Buffer)
+		r.outStream = out
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			t.Fatal(err)
+		case <-r.DoneCh:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout")
+		}
+
+		if exp := "web: 10.0.0.1:8080 10.0.0.2:8080"; !strings.Contains(out.String(), exp) {
+			t.Errorf("expected %q to contain %q", out.String(), exp)
+		}
+	})
+
+	t.Run("local_file", func(t *testing.T) {
+		local, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(local.Name())
+		if _, err := local.WriteString("from disk"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Files without fake data are read from disk.
+		r, err := NewRunner(newConfig(`{{ key "app/name" }}: {{ file "`+local.Name()+`" }}`), true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(bytes.Buffer)
+		r.outStream = out
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			t.Fatal(err)
+		case <-r.DoneCh:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout")
+		}
+
+		if exp := "web: from disk"; !strings.Contains(out.String(), exp) {
+			t.Errorf("expected %q to contain %q", out.String(), exp)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		r, err := NewRunner(newConfig(`{{ key "app/port" }}`), true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			if !strings.Contains(err.Error(), "no data for kv.block(app/port)") {
+				t.Errorf("expected a missing data error, got %q", err)
+			}
+		case <-r.DoneCh:
+			t.Fatal("expected an error")
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+
+	t.Run("not_dry", func(t *testing.T) {
+		if _, err := NewRunner(newConfig("hello"), false, false); err == nil {
+			t.Fatal("expected an error outside of dry mode")
+		}
+	})
+}
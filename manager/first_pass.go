@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"log"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// resolveFirstPass resolves the dependencies of the templates before their
+// first render, in waves. Each wave executes the templates without rendering
+// them, starts watching every dependency they are missing at once, and waits
+// for the data of all of them. Dependencies which are only discovered from the
+// data of others are therefore fetched together, instead of every arrival of
+// data causing a run which discovers a few more. It gives up after the first
+// pass timeout, and the runs continue from wherever it left off.
+func (r *Runner) resolveFirstPass() error {
+	timeout := config.TimeDurationVal(r.config.FirstPassTimeout)
+	if timeout <= 0 {
+		return nil
+	}
+
+	// Dependencies of templates which this instance is not the leader for
+	// are delivered by the de-duplication manager instead.
+	if r.dedup != nil {
+		return nil
+	}
+
+	start := time.Now()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	env, restrict := r.templateEnv()
+	for wave := 1; ; wave++ {
+		pending := make(map[string]dep.Dependency)
+		for _, tmpl := range r.templates {
+			result, err := tmpl.Execute(&template.ExecuteInput{
+				Brain:       r.brain,
+				Env:         env,
+				RestrictEnv: restrict,
+				Timeout:     r.renderTimeout(tmpl),
+			})
+			if err != nil {
+				// The first run reports the error.
+				return nil
+			}
+			for _, d := range result.Missing.List() {
+				if d.Type() != dep.TypeInternal {
+					pending[d.String()] = d
+				}
+			}
+		}
+
+		if len(pending) == 0 {
+			log.Printf("[DEBUG] (runner) resolved dependencies in %d waves in %s",
+				wave-1, time.Since(start))
+			return nil
+		}
+
+		log.Printf("[DEBUG] (runner) resolving %d dependencies in wave %d",
+			len(pending), wave)
+
+		r.dependenciesLock.Lock()
+		for key, d := range pending {
+			r.dependencies[key] = d
+			if !r.watcher.Watching(d) {
+				r.watcher.Add(d)
+			}
+		}
+		r.dependenciesLock.Unlock()
+
+		for len(pending) > 0 {
+			select {
+			case view := <-r.watcher.DataCh():
+				r.Receive(view.Dependency(), view.Data())
+				delete(pending, view.Dependency().String())
+			case err := <-r.watcher.ErrCh():
+				return err
+			case <-deadline.C:
+				log.Printf("[DEBUG] (runner) stopped resolving dependencies after %s, "+
+					"%d are still missing in wave %d", timeout, len(pending), wave)
+				return nil
+			case <-r.DoneCh:
+				return nil
+			}
+		}
+	}
+}
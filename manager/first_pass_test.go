@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+)
+
+func TestRunner_resolveFirstPass(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The first file names the second, which is only discovered once the
+	// data of the first is available.
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	if err := ioutil.WriteFile(first, []byte(second), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(second, []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		contents string
+		timeout  time.Duration
+		resolved []string
+		err      bool
+	}{
+		{
+			"nested",
+			fmt.Sprintf(`{{ file (file %q) }}`, first),
+			5 * time.Second,
+			[]string{first, second},
+			false,
+		},
+		{
+			"timeout",
+			// Consul is not running, so the key is retried until the timeout.
+			fmt.Sprintf(`{{ file %q }}{{ key "foo" }}`, first),
+			50 * time.Millisecond,
+			[]string{first},
+			false,
+		},
+		{
+			"error",
+			fmt.Sprintf(`{{ file %q }}`, filepath.Join(dir, "missing")),
+			5 * time.Second,
+			nil,
+			true,
+		},
+		{
+			"disabled",
+			fmt.Sprintf(`{{ file %q }}`, first),
+			0,
+			nil,
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				FirstPassTimeout: config.TimeDuration(tc.timeout),
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String(tc.contents),
+						Destination: config.String(filepath.Join(dir, tc.name)),
+					},
+				},
+			})
+			c.Finalize()
+
+			r, err := NewRunner(c, true, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Stop()
+
+			err = r.resolveFirstPass()
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+
+			for _, path := range tc.resolved {
+				d, err := dep.NewFileQuery(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := r.brain.Recall(d); !ok {
+					t.Errorf("expected %s to be resolved", d)
+				}
+			}
+
+			if tc.resolved == nil && !tc.err {
+				tmpl := r.templates[0]
+				result, err := tmpl.Execute(&template.ExecuteInput{Brain: r.brain})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Missing.Len() == 0 {
+					t.Error("expected dependencies to not be resolved")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// holdDown is an internal representation of a single template's hold-down
+// state. After a change to the template is rendered, further changes within
+// the period are held instead of rendered. The held changes are rendered
+// together once the template has not changed for the period, or at most the
+// maximum wait after the first held change, so a flapping dependency renders
+// at most once per period.
+type holdDown struct {
+	template *template.Template
+	period   time.Duration
+	max      time.Duration
+	ch       chan *template.Template
+
+	// until is the end of the hold-down period started by the last render,
+	// and sum is the checksum of the contents rendered then.
+	until time.Time
+	sum   [sha256.Size]byte
+
+	// pending is the timer of the held changes, if any, and released is true
+	// once it fired until the changes are rendered.
+	pending  *quiescence
+	released bool
+}
+
+// newHoldDown creates a new hold-down for the given template, which reports
+// the release of held changes on the channel.
+func newHoldDown(ch chan *template.Template, period, max time.Duration, t *template.Template) *holdDown {
+	return &holdDown{
+		template: t,
+		period:   period,
+		max:      max,
+		ch:       ch,
+	}
+}
+
+// hold returns true if the rendering of the contents must be held, starting
+// or snoozing the timer of the held changes.
+func (h *holdDown) hold(contents []byte) bool {
+	if h.released {
+		h.released = false
+		return false
+	}
+
+	// Contents which were already rendered are not a change.
+	if sha256.Sum256(contents) == h.sum {
+		return false
+	}
+
+	if h.pending == nil {
+		if !time.Now().Before(h.until) {
+			return false
+		}
+		h.pending = newQuiescence(h.ch, h.period, h.max, h.template)
+	}
+	h.pending.tick()
+	return true
+}
+
+// release marks the held changes to be rendered by the next run.
+func (h *holdDown) release() {
+	h.pending = nil
+	h.released = true
+}
+
+// rendered starts the hold-down period after the contents were rendered.
+func (h *holdDown) rendered(contents []byte) {
+	h.sum = sha256.Sum256(contents)
+	h.until = time.Now().Add(h.period)
+}
+
+// holdDownFor returns the hold-down of the template, or nil if its changes
+// are not held down. A template-specific hold_down takes precedence over the
+// global one. Changes are never held in once mode.
+func (r *Runner) holdDownFor(tmpl *template.Template) *holdDown {
+	if r.once {
+		return nil
+	}
+
+	if h, ok := r.holdDownMap[tmpl.ID()]; ok {
+		return h
+	}
+
+	c := r.config.HoldDown
+	for _, templateConfig := range r.templateConfigsFor(tmpl) {
+		if config.BoolVal(templateConfig.HoldDown.Enabled) {
+			c = templateConfig.HoldDown
+			break
+		}
+	}
+
+	var h *holdDown
+	if config.BoolVal(c.Enabled) {
+		h = newHoldDown(r.holdDownCh, config.TimeDurationVal(c.Min),
+			config.TimeDurationVal(c.Max), tmpl)
+	}
+	r.holdDownMap[tmpl.ID()] = h
+	return h
+}
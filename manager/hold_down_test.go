@@ -0,0 +1,169 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/template"
+)
+
+func TestHoldDown_hold(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := template.NewTemplate(&template.NewTemplateInput{
+		Contents: "hello",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan *template.Template, 1)
+	h := newHoldDown(ch, 50*time.Millisecond, 200*time.Millisecond, tmpl)
+
+	// The first change is not held.
+	if h.hold([]byte("a")) {
+		t.Fatal("expected the first change not to be held")
+	}
+	h.rendered([]byte("a"))
+
+	// Contents which were already rendered are not held.
+	if h.hold([]byte("a")) {
+		t.Fatal("expected the rendered contents not to be held")
+	}
+
+	// Changes within the period are held until the timer fires.
+	if !h.hold([]byte("b")) {
+		t.Fatal("expected the change to be held")
+	}
+	if !h.hold([]byte("c")) {
+		t.Fatal("expected the change to be held")
+	}
+
+	select {
+	case act := <-ch:
+		if act != tmpl {
+			t.Fatalf("expected %q to be released, got %q", tmpl.ID(), act.ID())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the held changes to be released")
+	}
+	h.release()
+
+	// The released changes are rendered.
+	if h.hold([]byte("c")) {
+		t.Fatal("expected the released change not to be held")
+	}
+	h.rendered([]byte("c"))
+
+	// A change after the period is not held.
+	time.Sleep(60 * time.Millisecond)
+	if h.hold([]byte("d")) {
+		t.Fatal("expected the change after the period not to be held")
+	}
+}
+
+func TestHoldDown_max(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := template.NewTemplate(&template.NewTemplateInput{
+		Contents: "hello",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan *template.Template, 1)
+	h := newHoldDown(ch, 50*time.Millisecond, 150*time.Millisecond, tmpl)
+	h.rendered([]byte("a"))
+
+	// A dependency which keeps flapping is still released at the maximum.
+	start := time.Now()
+	deadline := time.After(time.Second)
+	for i := 0; ; i++ {
+		h.hold([]byte(fmt.Sprintf("%d", i)))
+
+		select {
+		case <-ch:
+			if d := time.Since(start); d > 500*time.Millisecond {
+				t.Fatalf("expected release at the maximum, took %s", d)
+			}
+			return
+		case <-deadline:
+			t.Fatal("expected the held changes to be released")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestRunner_holdDownFor(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		global   *config.WaitConfig
+		template *config.WaitConfig
+		once     bool
+		exp      time.Duration
+	}{
+		{
+			"disabled",
+			nil,
+			nil,
+			false,
+			0,
+		},
+		{
+			"global",
+			&config.WaitConfig{Min: config.TimeDuration(10 * time.Second)},
+			nil,
+			false,
+			10 * time.Second,
+		},
+		{
+			"template_overrides_global",
+			&config.WaitConfig{Min: config.TimeDuration(10 * time.Second)},
+			&config.WaitConfig{Min: config.TimeDuration(15 * time.Second)},
+			false,
+			15 * time.Second,
+		},
+		{
+			"once",
+			nil,
+			&config.WaitConfig{Min: config.TimeDuration(15 * time.Second)},
+			true,
+			0,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				HoldDown: tc.global,
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents: config.String("hello"),
+						HoldDown: tc.template,
+					},
+				},
+			})
+			c.Finalize()
+
+			r, err := NewRunner(c, true, tc.once)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var act time.Duration
+			for _, tmpl := range r.templates {
+				if h := r.holdDownFor(tmpl); h != nil {
+					act = h.period
+				}
+			}
+			if act != tc.exp {
+				t.Errorf("expected %s to be %s", act, tc.exp)
+			}
+		})
+	}
+}
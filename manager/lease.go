@@ -0,0 +1,67 @@
+package manager
+
+import (
+	"log"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// trackLeases records the leases of a Vault secret received by this runner,
+// so they can be revoked when the runner stops. Leases which have expired are
+// forgotten. The caller must hold the dependencies lock.
+func (r *Runner) trackLeases(data interface{}) {
+	if !config.BoolVal(r.config.Vault.RevokeOnShutdown) {
+		return
+	}
+
+	secret, ok := data.(*dep.Secret)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	for id, expires := range r.leases {
+		if !now.Before(expires) {
+			delete(r.leases, id)
+		}
+	}
+
+	for s := secret; s != nil; s = s.Previous {
+		if s.LeaseID == "" {
+			continue
+		}
+
+		// A previous secret keeps the expiry from when it was received.
+		if _, ok := r.leases[s.LeaseID]; ok && s != secret {
+			continue
+		}
+		r.leases[s.LeaseID] = now.Add(time.Duration(s.LeaseDuration) * time.Second)
+	}
+}
+
+// revokeLeases revokes the leases of the Vault secrets received by this runner
+// which have not expired yet.
+func (r *Runner) revokeLeases() {
+	r.dependenciesLock.Lock()
+	defer r.dependenciesLock.Unlock()
+
+	if len(r.leases) == 0 {
+		return
+	}
+
+	now := time.Now()
+	for id, expires := range r.leases {
+		delete(r.leases, id)
+		if !now.Before(expires) {
+			continue
+		}
+
+		if err := r.clients.Vault().Sys().Revoke(id); err != nil {
+			log.Printf("[WARN] (runner) failed to revoke lease %s: %s", id, err)
+			continue
+		}
+		log.Printf("[DEBUG] (runner) revoked lease %s", id)
+	}
+}
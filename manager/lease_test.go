@@ -0,0 +1,79 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+func TestRunner_revokeLeases(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	var revoked []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if req.Method == "PUT" && strings.HasPrefix(req.URL.Path, "/v1/sys/revoke/") {
+			revoked = append(revoked, strings.TrimPrefix(req.URL.Path, "/v1/sys/revoke/"))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Vault: &config.VaultConfig{
+			Address:          config.String(ts.URL),
+			RenewToken:       config.Bool(false),
+			RevokeOnShutdown: config.Bool(true),
+			SSL:              &config.SSLConfig{Enabled: config.Bool(false)},
+			Token:            config.String("token"),
+		},
+	})
+	c.Finalize()
+
+	newRunner := func() *Runner {
+		r, err := NewRunner(c, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.trackLeases(&dep.Secret{
+			LeaseID:       "database/creds/app/2",
+			LeaseDuration: 60,
+			Previous: &dep.Secret{
+				LeaseID:       "database/creds/app/1",
+				LeaseDuration: 60,
+			},
+		})
+		r.trackLeases(&dep.Secret{LeaseID: "pki/issue/expired", LeaseDuration: -1})
+		r.trackLeases(&dep.Secret{Data: map[string]interface{}{"a": "b"}})
+		r.trackLeases("not a secret")
+		return r
+	}
+
+	// A reload stops the runner without revoking the leases.
+	newRunner().Stop()
+	lock.Lock()
+	if len(revoked) != 0 {
+		t.Errorf("expected no leases to be revoked on stop, got %q", revoked)
+	}
+	lock.Unlock()
+
+	newRunner().Shutdown()
+
+	lock.Lock()
+	defer lock.Unlock()
+	sort.Strings(revoked)
+	exp := []string{"database/creds/app/1", "database/creds/app/2"}
+	if !reflect.DeepEqual(exp, revoked) {
+		t.Errorf("expected %q to be %q", revoked, exp)
+	}
+}
@@ -0,0 +1,198 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+// notifyQueueSize is the number of notifications which may wait to be sent
+// before new ones are dropped.
+const notifyQueueSize = 64
+
+// notification is the JSON document POSTed to the notifications webhook. Text
+// is a human-readable summary, which is what chat webhooks like Slack's
+// display; the other fields are those of the render record.
+type notification struct {
+	Event string `json:"event"`
+	Text  string `json:"text"`
+	Host  string `json:"host"`
+
+	*RenderRecord
+}
+
+// notifier POSTs render records to the notifications webhook. Notifications
+// are sent in the background and in order, so a slow webhook never delays
+// rendering. A nil notifier sends nothing.
+type notifier struct {
+	client *http.Client
+	url    string
+	host   string
+	on     map[string]bool
+
+	queue   chan *notification
+	pending sync.WaitGroup
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// newNotifier creates a notifier for the given configuration and starts
+// sending notifications.
+func newNotifier(c *config.NotificationsConfig) (*notifier, error) {
+	webhook := config.StringVal(c.Webhook)
+	if u, err := url.Parse(webhook); err != nil || u.Host == "" ||
+		(u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("runner: invalid notifications webhook %q", webhook)
+	}
+
+	on := make(map[string]bool, len(c.On))
+	for _, event := range c.On {
+		switch event {
+		case config.NotifyOnError, config.NotifyOnRendered:
+			on[event] = true
+		default:
+			return nil, fmt.Errorf("runner: unknown notifications event %q, "+
+				"must be %q or %q", event, config.NotifyOnError, config.NotifyOnRendered)
+		}
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	n := &notifier{
+		client: &http.Client{Timeout: config.TimeDurationVal(c.Timeout)},
+		url:    webhook,
+		host:   host,
+		on:     on,
+		queue:  make(chan *notification, notifyQueueSize),
+		stopCh: make(chan struct{}),
+	}
+	go n.run()
+	return n, nil
+}
+
+// notify queues a notification of the given record, if its event is
+// notified. It never waits for the notification to be sent; errors stop the
+// runner, which sends the queued notifications when it stops.
+func (n *notifier) notify(r *RenderRecord) {
+	if n == nil {
+		return
+	}
+
+	event := config.NotifyOnRendered
+	if r.Error != "" {
+		event = config.NotifyOnError
+	}
+	if !n.on[event] {
+		return
+	}
+
+	n.pending.Add(1)
+	select {
+	case n.queue <- &notification{
+		Event:        event,
+		Text:         n.text(event, r),
+		Host:         n.host,
+		RenderRecord: r,
+	}:
+	default:
+		n.pending.Done()
+		log.Printf("[WARN] (runner) notifications queue is full, dropping %s "+
+			"notification for %s", event, r.Template)
+	}
+}
+
+// text returns the human-readable summary of the record.
+func (n *notifier) text(event string, r *RenderRecord) string {
+	target := r.Destination
+	if target == "" {
+		target = r.Source
+	}
+	if target == "" {
+		target = r.Template
+	}
+
+	if event == config.NotifyOnError {
+		return fmt.Sprintf("consul-template on %s failed to render %s: %s",
+			n.host, target, r.Error)
+	}
+	return fmt.Sprintf("consul-template on %s rendered %s", n.host, target)
+}
+
+// run sends the queued notifications until the notifier is stopped.
+func (n *notifier) run() {
+	for {
+		select {
+		case msg := <-n.queue:
+			if err := n.send(msg); err != nil {
+				log.Printf("[WARN] (runner) failed to send %s notification: %s",
+					msg.Event, err)
+			}
+			n.pending.Done()
+		case <-n.stopCh:
+			return
+		}
+	}
+}
+
+// send POSTs the notification to the webhook.
+func (n *notifier) send(msg *notification) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// flush waits for the queued notifications to be sent, for at most the time
+// it takes to send each of them.
+func (n *notifier) flush() {
+	doneCh := make(chan struct{})
+	go func() {
+		n.pending.Wait()
+		close(doneCh)
+	}()
+
+	timeout := n.client.Timeout * time.Duration(len(n.queue)+1)
+	if timeout <= 0 {
+		timeout = config.DefaultNotificationsTimeout
+	}
+	select {
+	case <-doneCh:
+	case <-time.After(timeout):
+		log.Printf("[WARN] (runner) timed out sending notifications")
+	}
+}
+
+// stop sends the queued notifications and stops the notifier.
+func (n *notifier) stop() {
+	if n == nil {
+		return
+	}
+
+	n.stopOnce.Do(func() {
+		n.flush()
+		close(n.stopCh)
+	})
+}
@@ -0,0 +1,217 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+// testWebhook starts a server which records the notifications POSTed to it.
+func testWebhook(t *testing.T) (*httptest.Server, func() []map[string]interface{}) {
+	var mu sync.Mutex
+	var received []map[string]interface{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected application/json, got %q", ct)
+		}
+
+		var msg map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		received = append(received, msg)
+		mu.Unlock()
+	}))
+
+	return ts, func() []map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]map[string]interface{}{}, received...)
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		on   []string
+		exp  []string
+	}{
+		{
+			"errors",
+			[]string{"error"},
+			[]string{"error"},
+		},
+		{
+			"rendered",
+			[]string{"rendered"},
+			[]string{"rendered"},
+		},
+		{
+			"both",
+			[]string{"error", "rendered"},
+			[]string{"rendered", "error"},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			ts, received := testWebhook(t)
+			defer ts.Close()
+
+			c := &config.NotificationsConfig{
+				On:      tc.on,
+				Webhook: config.String(ts.URL),
+			}
+			c.Finalize()
+
+			n, err := newNotifier(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			n.notify(&RenderRecord{
+				Template:    "abc",
+				Destination: "/tmp/foo",
+				Rendered:    true,
+				ContentHash: "123",
+			})
+			n.notify(&RenderRecord{
+				Template:    "abc",
+				Destination: "/tmp/foo",
+				Error:       "permission denied",
+			})
+			n.stop()
+
+			msgs := received()
+			if len(msgs) != len(tc.exp) {
+				t.Fatalf("expected %d notifications, got %d: %v", len(tc.exp), len(msgs), msgs)
+			}
+			for j, msg := range msgs {
+				if msg["event"] != tc.exp[j] {
+					t.Errorf("expected %q, got %q", tc.exp[j], msg["event"])
+				}
+				if msg["destination"] != "/tmp/foo" {
+					t.Errorf("expected the destination, got %v", msg["destination"])
+				}
+				text, _ := msg["text"].(string)
+				if !strings.Contains(text, "/tmp/foo") {
+					t.Errorf("expected the text to name the destination, got %q", text)
+				}
+				if msg["event"] == "error" && msg["error"] != "permission denied" {
+					t.Errorf("expected the error, got %v", msg["error"])
+				}
+			}
+		})
+	}
+}
+
+func TestNotifier_errorStop(t *testing.T) {
+	t.Parallel()
+
+	ts, received := testWebhook(t)
+	defer ts.Close()
+
+	c := &config.NotificationsConfig{Webhook: config.String(ts.URL)}
+	c.Finalize()
+
+	n, err := newNotifier(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.stop()
+
+	// The runner stops after an error, which sends it before stop returns.
+	n.notify(&RenderRecord{Template: "abc", Source: "in.tpl", Error: "bad"})
+	n.stop()
+	if l := len(received()); l != 1 {
+		t.Errorf("expected the error to be sent, got %d notifications", l)
+	}
+}
+
+func TestNotifier_slowWebhook(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	defer ts.Close()
+
+	c := &config.NotificationsConfig{
+		On:      []string{"error"},
+		Timeout: config.TimeDuration(100 * time.Millisecond),
+		Webhook: config.String(ts.URL),
+	}
+	c.Finalize()
+
+	n, err := newNotifier(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.stop()
+
+	// Errors are sent in the background, and stopping waits for at most the
+	// webhook timeout.
+	start := time.Now()
+	n.notify(&RenderRecord{Template: "abc", Error: "bad"})
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Errorf("expected the error to be sent in the background, took %s", d)
+	}
+	n.stop()
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("expected the webhook timeout to apply, took %s", d)
+	}
+}
+
+func TestNewNotifier_invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		webhook string
+		on      []string
+	}{
+		{
+			"no_scheme",
+			"hooks.example.com/ct",
+			nil,
+		},
+		{
+			"bad_scheme",
+			"ftp://hooks.example.com/ct",
+			nil,
+		},
+		{
+			"unknown_event",
+			"https://hooks.example.com/ct",
+			[]string{"error", "nope"},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := &config.NotificationsConfig{
+				On:      tc.on,
+				Webhook: config.String(tc.webhook),
+			}
+			c.Finalize()
+
+			if _, err := newNotifier(c); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// orderTemplates validates the names of the template configurations and the
+// templates they must render after, and sorts the templates so that each one
+// comes after the templates it must follow. Otherwise the order of the
+// configuration is kept.
+func (r *Runner) orderTemplates() error {
+	// Map each name to the template it belongs to.
+	named := make(map[string]*template.Template)
+	for _, tmpl := range r.templates {
+		for _, c := range r.templateConfigsFor(tmpl) {
+			name := config.StringVal(c.Name)
+			if name == "" {
+				continue
+			}
+			if _, ok := named[name]; ok {
+				return fmt.Errorf("runner: %s: template name %q is not unique",
+					c.Display(), name)
+			}
+			named[name] = tmpl
+		}
+	}
+
+	// Find the templates each template must render after. Configurations
+	// which share the contents of a template may refer to each other, which
+	// needs no ordering.
+	after := make(map[string][]string)
+	prereqs := make(map[string]map[string]struct{})
+	for _, tmpl := range r.templates {
+		for _, c := range r.templateConfigsFor(tmpl) {
+			for _, name := range c.After {
+				other, ok := named[name]
+				if !ok {
+					return fmt.Errorf("runner: %s: no template is named %q",
+						c.Display(), name)
+				}
+				if other == tmpl {
+					continue
+				}
+				after[tmpl.ID()] = append(after[tmpl.ID()], name)
+				if prereqs[tmpl.ID()] == nil {
+					prereqs[tmpl.ID()] = make(map[string]struct{})
+				}
+				prereqs[tmpl.ID()][other.ID()] = struct{}{}
+			}
+		}
+	}
+
+	// Repeatedly take the first template whose prerequisites are all placed.
+	ordered := make([]*template.Template, 0, len(r.templates))
+	placed := make(map[string]struct{}, len(r.templates))
+	remaining := append([]*template.Template{}, r.templates...)
+	for len(remaining) > 0 {
+		next := -1
+		for i, tmpl := range remaining {
+			ready := true
+			for id := range prereqs[tmpl.ID()] {
+				if _, ok := placed[id]; !ok {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+
+		if next < 0 {
+			var names []string
+			for _, tmpl := range remaining {
+				names = append(names, after[tmpl.ID()]...)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("runner: templates cannot be ordered, their after "+
+				"references form a cycle through %s", strings.Join(names, ", "))
+		}
+
+		tmpl := remaining[next]
+		ordered = append(ordered, tmpl)
+		placed[tmpl.ID()] = struct{}{}
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+
+	r.templates = ordered
+	r.namedTemplates = named
+	r.after = after
+
+	r.commandOrder = make(map[*config.TemplateConfig]int)
+	for i, tmpl := range r.templates {
+		for _, c := range r.templateConfigsFor(tmpl) {
+			r.commandOrder[c] = i
+		}
+	}
+
+	return nil
+}
+
+// waitingFor returns the names of the templates the given template must
+// render after, which have not rendered yet.
+func (r *Runner) waitingFor(tmpl *template.Template) []string {
+	r.renderEventsLock.RLock()
+	defer r.renderEventsLock.RUnlock()
+
+	var waiting []string
+	for _, name := range r.after[tmpl.ID()] {
+		event, ok := r.renderEvents[r.namedTemplates[name].ID()]
+		if !ok || event.LastWouldRender.IsZero() {
+			waiting = append(waiting, name)
+		}
+	}
+	return waiting
+}
+
+// sortCommands sorts the given commands in the order of their templates, so
+// the commands of templates run after the commands of the templates they must
+// follow. Commands of the same template keep their relative order.
+func (r *Runner) sortCommands(commands []*config.TemplateConfig) {
+	sort.SliceStable(commands, func(i, j int) bool {
+		return r.commandOrder[commands[i]] < r.commandOrder[commands[j]]
+	})
+}
+
+// failedAfter returns the name of a template the given template must follow,
+// whose command failed, if any.
+func failedAfter(c *config.TemplateConfig, failed map[string]struct{}) string {
+	for _, name := range c.After {
+		if _, ok := failed[name]; ok {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,273 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+func TestRunner_orderTemplates(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		templates *config.TemplateConfigs
+		exp       []string
+		err       string
+	}{
+		{
+			"config_order",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents: config.String("a"),
+					Name:     config.String("a"),
+				},
+				&config.TemplateConfig{
+					Contents: config.String("b"),
+				},
+			},
+			[]string{"a", "b"},
+			"",
+		},
+		{
+			"after",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents: config.String("a"),
+					After:    []string{"c"},
+				},
+				&config.TemplateConfig{
+					Contents: config.String("b"),
+					Name:     config.String("b"),
+				},
+				&config.TemplateConfig{
+					Contents: config.String("c"),
+					Name:     config.String("c"),
+					After:    []string{"b"},
+				},
+			},
+			[]string{"b", "c", "a"},
+			"",
+		},
+		{
+			"same_contents",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String("a"),
+					Destination: config.String("/tmp/a1"),
+					Name:        config.String("a1"),
+				},
+				&config.TemplateConfig{
+					Contents:    config.String("a"),
+					Destination: config.String("/tmp/a2"),
+					After:       []string{"a1"},
+				},
+			},
+			[]string{"a"},
+			"",
+		},
+		{
+			"duplicate_name",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents: config.String("a"),
+					Name:     config.String("a"),
+				},
+				&config.TemplateConfig{
+					Contents: config.String("b"),
+					Name:     config.String("a"),
+				},
+			},
+			nil,
+			`template name "a" is not unique`,
+		},
+		{
+			"unknown_name",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents: config.String("a"),
+					After:    []string{"nope"},
+				},
+			},
+			nil,
+			`no template is named "nope"`,
+		},
+		{
+			"cycle",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents: config.String("a"),
+					Name:     config.String("a"),
+					After:    []string{"b"},
+				},
+				&config.TemplateConfig{
+					Contents: config.String("b"),
+					Name:     config.String("b"),
+					After:    []string{"a"},
+				},
+				&config.TemplateConfig{
+					Contents: config.String("c"),
+				},
+			},
+			nil,
+			"form a cycle through a, b",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				Templates: tc.templates,
+			})
+			c.Finalize()
+
+			r, err := NewRunner(c, true, true)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var act []string
+			for _, tmpl := range r.templates {
+				act = append(act, tmpl.Contents())
+			}
+			if !reflect.DeepEqual(tc.exp, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestRunner_after(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certs := filepath.Join(dir, "certs.pem")
+	proxy := filepath.Join(dir, "proxy.cfg")
+	source := filepath.Join(dir, "certs.src")
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				After:       []string{"certs"},
+				Contents:    config.String("crt " + certs),
+				Command:     config.String("echo proxy"),
+				Destination: config.String(proxy),
+			},
+			&config.TemplateConfig{
+				Name:        config.String("certs"),
+				Contents:    config.String(fmt.Sprintf(`{{ file %q }}`, source)),
+				Command:     config.String("echo certs"),
+				Destination: config.String(certs),
+			},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	out := new(bytes.Buffer)
+	r.outStream, r.errStream = out, out
+
+	// The certificates are missing data, so the proxy waits for them.
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(proxy); !os.IsNotExist(err) {
+		t.Fatalf("expected %q to not be rendered, got %v", proxy, err)
+	}
+
+	d, err := dep.NewFileQuery(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Receive(d, "certificate")
+
+	// Both render, and the command of the certificates runs first.
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(proxy); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "certs\nproxy\n"; out.String() != exp {
+		t.Errorf("expected commands output %q, got %q", exp, out)
+	}
+}
+
+func TestRunner_runCommands_after(t *testing.T) {
+	t.Parallel()
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Name:     config.String("certs"),
+				Contents: config.String("a"),
+				Command:  config.String("exit 1"),
+			},
+			&config.TemplateConfig{
+				Name:     config.String("proxy"),
+				After:    []string{"certs"},
+				Contents: config.String("b"),
+				Command:  config.String("echo proxy"),
+			},
+			&config.TemplateConfig{
+				After:    []string{"proxy"},
+				Contents: config.String("c"),
+				Command:  config.String("echo health"),
+			},
+			&config.TemplateConfig{
+				Contents: config.String("d"),
+				Command:  config.String("echo other"),
+			},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(bytes.Buffer)
+	r.outStream, r.errStream = out, out
+
+	err = r.runCommands(*c.Templates, false, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, exp := range []string{
+		`failed to execute command "exit 1"`,
+		`skipped command "echo proxy"`,
+		`skipped command "echo health"`,
+	} {
+		if !strings.Contains(err.Error(), exp) {
+			t.Errorf("expected error containing %q, got %q", exp, err)
+		}
+	}
+	if exp := "other\n"; out.String() != exp {
+		t.Errorf("expected commands output %q, got %q", exp, out)
+	}
+}
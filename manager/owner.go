@@ -0,0 +1,44 @@
+package manager
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+// lookupOwner resolves the given user and group, each of which may be a name
+// or a numeric ID, into a uid and gid. An empty user or group resolves to -1,
+// which leaves the existing owner or group unchanged.
+func lookupOwner(username, group string) (int, int, error) {
+	uid, gid := -1, -1
+
+	if username != "" {
+		id, err := strconv.Atoi(username)
+		if err != nil {
+			u, err := user.Lookup(username)
+			if err != nil {
+				return -1, -1, fmt.Errorf("failed to lookup user %q: %s", username, err)
+			}
+			if id, err = strconv.Atoi(u.Uid); err != nil {
+				return -1, -1, fmt.Errorf("user %q has non-numeric uid %q", username, u.Uid)
+			}
+		}
+		uid = id
+	}
+
+	if group != "" {
+		id, err := strconv.Atoi(group)
+		if err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return -1, -1, fmt.Errorf("failed to lookup group %q: %s", group, err)
+			}
+			if id, err = strconv.Atoi(g.Gid); err != nil {
+				return -1, -1, fmt.Errorf("group %q has non-numeric gid %q", group, g.Gid)
+			}
+		}
+		gid = id
+	}
+
+	return uid, gid, nil
+}
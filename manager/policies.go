@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// aclRole is a Consul ACL role, whose policies are granted to the tokens
+// linked to it.
+type aclRole struct {
+	Policies []*dep.ACLLink
+}
+
+// checkRequiredPolicies returns an error if the Consul token is not linked to
+// all of the consul.required_policies, directly or through its roles, so a
+// token without the permissions templates need fails on start instead of
+// leaving them unrendered.
+func (r *Runner) checkRequiredPolicies() error {
+	required := r.config.Consul.RequiredPolicies
+	if len(required) == 0 {
+		return nil
+	}
+
+	d, err := dep.NewACLTokenSelfQuery()
+	if err != nil {
+		return err
+	}
+
+	data, _, err := d.Fetch(r.clients, nil)
+	if err != nil {
+		return fmt.Errorf("runner: failed reading the consul token to check "+
+			"consul.required_policies: %s", err)
+	}
+	token := data.(*dep.ACLToken)
+
+	var missing []string
+	for _, name := range required {
+		if !token.HasPolicy(name) {
+			missing = append(missing, name)
+		}
+	}
+
+	// The roles are only read when needed, since reading them requires
+	// acl:read. If that is denied, the error is reported with the policies.
+	var roleErrs []string
+	if len(missing) > 0 && len(token.Roles) > 0 {
+		granted := make(map[string]bool)
+		for _, link := range token.Roles {
+			var role aclRole
+			if _, err := r.clients.Consul().Raw().Query("/v1/acl/role/"+link.ID, &role, nil); err != nil {
+				roleErrs = append(roleErrs, fmt.Sprintf("%s: %s", link.Name, err))
+				continue
+			}
+			for _, p := range role.Policies {
+				granted[p.Name] = true
+			}
+		}
+
+		var stillMissing []string
+		for _, name := range missing {
+			if !granted[name] {
+				stillMissing = append(stillMissing, name)
+			}
+		}
+		missing = stillMissing
+	}
+
+	if len(missing) > 0 {
+		err := fmt.Errorf("runner: consul token %s is missing required policies: %s",
+			token.AccessorID, strings.Join(missing, ", "))
+		if len(roleErrs) > 0 {
+			err = fmt.Errorf("%s (failed reading the roles of the token: %s)",
+				err, strings.Join(roleErrs, "; "))
+		}
+		return err
+	}
+
+	log.Printf("[DEBUG] (runner) consul token %s has the required policies",
+		token.AccessorID)
+	return nil
+}
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRunner_checkRequiredPolicies(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "valid" {
+			http.Error(w, "ACL not found", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/acl/role/10":
+			fmt.Fprint(w, `{"ID": "10", "Policies": [{"ID": "3", "Name": "node-read"}]}`)
+		case "/v1/acl/role/11":
+			http.Error(w, "Permission denied", http.StatusForbidden)
+		default:
+			fmt.Fprint(w, `{
+				"AccessorID": "6a1253d2",
+				"Policies": [{"ID": "1", "Name": "kv-read"}, {"ID": "2", "Name": "service-read"}],
+				"Roles": [{"ID": "10", "Name": "nodes"}, {"ID": "11", "Name": "secret"}]
+			}`)
+		}
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		name     string
+		token    string
+		required []string
+		err      string
+	}{
+		{
+			"none_required",
+			"invalid",
+			nil,
+			"",
+		},
+		{
+			"has_policies",
+			"valid",
+			[]string{"kv-read", "service-read"},
+			"",
+		},
+		{
+			"role_policies",
+			"valid",
+			[]string{"kv-read", "node-read"},
+			"",
+		},
+		{
+			"missing_policies",
+			"valid",
+			[]string{"kv-read", "kv-write", "node-read"},
+			"consul token 6a1253d2 is missing required policies: kv-write " +
+				"(failed reading the roles of the token: secret: ",
+		},
+		{
+			"invalid_token",
+			"invalid",
+			[]string{"kv-read"},
+			"ACL not found",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				Consul: &config.ConsulConfig{
+					Address:          config.String(strings.TrimPrefix(ts.URL, "http://")),
+					RequiredPolicies: tc.required,
+					Token:            config.String(tc.token),
+				},
+			})
+			c.Finalize()
+
+			r, err := NewRunner(c, true, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.checkRequiredPolicies()
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
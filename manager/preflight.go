@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// preflight checks that each dependency of the templates can be read with the
+// configured credentials, writing a report to the error stream, and returns
+// an error if any cannot. Only the dependencies of the first pass of each
+// template are known, since dependencies which depend on the data of others
+// are only found once that data is available.
+func (r *Runner) preflight() error {
+	log.Printf("[INFO] (runner) running preflight checks")
+
+	var all dep.Set
+	for _, tmpl := range r.templates {
+		deps, err := r.firstPassDependencies(tmpl)
+		if err != nil {
+			return err
+		}
+		for _, d := range deps {
+			all.Add(d)
+		}
+	}
+
+	deps := all.List()
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].String() < deps[j].String()
+	})
+
+	var failed int
+	for _, d := range deps {
+		err := dep.Preflight(d, r.clients)
+		d.Stop()
+
+		if err != nil {
+			failed++
+			fmt.Fprintf(r.errStream, "Preflight: FAILED %s: %s\n", d, err)
+			continue
+		}
+		fmt.Fprintf(r.errStream, "Preflight: ok     %s\n", d)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("runner: preflight failed for %d of %d dependencies",
+			failed, len(deps))
+	}
+
+	fmt.Fprintf(r.errStream, "Preflight: all %d dependencies can be read\n", len(deps))
+	return nil
+}
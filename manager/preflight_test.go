@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRunner_preflight(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/kv/allowed":
+			fmt.Fprint(w, `[{"Key": "allowed", "Value": "Zm9v"}]`)
+		default:
+			http.Error(w, "Permission denied", http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	cases := []struct {
+		name     string
+		contents string
+		out      []string
+		err      string
+	}{
+		{
+			"all_ok",
+			fmt.Sprintf(`{{ key "allowed" }}{{ file %q }}`, f.Name()),
+			[]string{
+				fmt.Sprintf("Preflight: ok     file(%s)", f.Name()),
+				"Preflight: ok     kv.block(allowed)",
+				"Preflight: all 2 dependencies can be read",
+			},
+			"",
+		},
+		{
+			"failed",
+			`{{ key "allowed" }}{{ key "denied" }}{{ file "/does/not/exist" }}`,
+			[]string{
+				"Preflight: FAILED file(/does/not/exist)",
+				"Preflight: ok     kv.block(allowed)",
+				"Preflight: FAILED kv.block(denied): kv.block(denied): Unexpected response code: 403",
+			},
+			"preflight failed for 2 of 3 dependencies",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				Consul: &config.ConsulConfig{
+					Address: config.String(strings.TrimPrefix(ts.URL, "http://")),
+				},
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents: config.String(tc.contents),
+					},
+				},
+			})
+			c.Finalize()
+
+			r, err := NewRunner(c, true, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var errStream bytes.Buffer
+			r.errStream = &errStream
+
+			err = r.preflight()
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+
+			for _, line := range tc.out {
+				if !strings.Contains(errStream.String(), line) {
+					t.Errorf("expected %q to contain %q", errStream.String(), line)
+				}
+			}
+		})
+	}
+}
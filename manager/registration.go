@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// registrationMinDeregisterAfter is the minimum amount of time after which
+// Consul removes the registration of an instance whose check is critical,
+// such as one which crashed. Consul does not reap faster than this.
+const registrationMinDeregisterAfter = 1 * time.Minute
+
+// registration keeps this instance registered as an instance of a Consul
+// service while it runs, with a TTL check which it keeps passing.
+type registration struct {
+	sync.Mutex
+
+	agent   *consulapi.Agent
+	service *consulapi.AgentServiceRegistration
+	checkID string
+	ttl     time.Duration
+
+	started bool
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+// newRegistration creates the registration of this instance for the given
+// configuration with the given Consul agent.
+func newRegistration(c *config.RegistrationConfig, agent *consulapi.Agent) (*registration, error) {
+	ttl := config.TimeDurationVal(c.TTL)
+	if ttl <= 0 {
+		return nil, fmt.Errorf("runner: registration ttl must be positive")
+	}
+
+	deregisterAfter := 2 * ttl
+	if deregisterAfter < registrationMinDeregisterAfter {
+		deregisterAfter = registrationMinDeregisterAfter
+	}
+
+	name := config.StringVal(c.Service)
+	id := dep.RunnerID(name)
+	return &registration{
+		agent: agent,
+		service: &consulapi.AgentServiceRegistration{
+			ID:   id,
+			Name: name,
+			Tags: c.Tags,
+			Check: &consulapi.AgentServiceCheck{
+				TTL:                            ttl.String(),
+				Status:                         consulapi.HealthPassing,
+				DeregisterCriticalServiceAfter: deregisterAfter.String(),
+			},
+		},
+		// This is the ID Consul gives the only check of a service.
+		checkID: "service:" + id,
+		ttl:     ttl,
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}, nil
+}
+
+// start registers this instance and keeps its check passing until the
+// registration is stopped.
+func (g *registration) start() error {
+	if g == nil {
+		return nil
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	if err := g.agent.ServiceRegister(g.service); err != nil {
+		return fmt.Errorf("registration: %s", err)
+	}
+	log.Printf("[INFO] (runner) registered as %q in service %q",
+		g.service.ID, g.service.Name)
+
+	g.started = true
+	go g.heartbeat()
+	return nil
+}
+
+// heartbeat keeps the check passing, registering this instance again if the
+// agent lost the registration, like when it restarted.
+func (g *registration) heartbeat() {
+	defer close(g.doneCh)
+
+	ticker := time.NewTicker(g.ttl / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := g.agent.UpdateTTL(g.checkID, "", consulapi.HealthPassing)
+			if err == nil {
+				continue
+			}
+			log.Printf("[WARN] (runner) registration: updating check: %s", err)
+
+			if err := g.agent.ServiceRegister(g.service); err != nil {
+				log.Printf("[WARN] (runner) registration: %s", err)
+			}
+		case <-g.stopCh:
+			return
+		}
+	}
+}
+
+// stop stops keeping the check passing. When deregister is true, it also
+// deregisters this instance, so the other instances see it is gone without
+// waiting for its check to expire. A reload keeps the registration, which the
+// next runner registers again with the same ID, so the set of runners the
+// other instances see does not change.
+func (g *registration) stop(deregister bool) {
+	if g == nil {
+		return
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	if !g.started {
+		return
+	}
+	g.started = false
+
+	close(g.stopCh)
+	<-g.doneCh
+
+	if !deregister {
+		return
+	}
+
+	if err := g.agent.ServiceDeregister(g.service.ID); err != nil {
+		log.Printf("[WARN] (runner) registration: deregistering %q: %s",
+			g.service.ID, err)
+		return
+	}
+	log.Printf("[DEBUG] (runner) deregistered %q", g.service.ID)
+}
@@ -0,0 +1,146 @@
+package manager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestRegistration(t *testing.T) {
+	t.Parallel()
+
+	id := dep.RunnerID("edge-templates")
+
+	var mu sync.Mutex
+	var calls []string
+	var registered *consulapi.AgentServiceRegistration
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls = append(calls, r.URL.Path)
+		if r.URL.Path == "/v1/agent/service/register" {
+			registered = new(consulapi.AgentServiceRegistration)
+			if err := json.NewDecoder(r.Body).Decode(registered); err != nil {
+				t.Error(err)
+			}
+		}
+	}))
+	defer ts.Close()
+
+	consul, err := consulapi.NewClient(&consulapi.Config{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &config.RegistrationConfig{
+		Service: config.String("edge-templates"),
+		Tags:    []string{"edge"},
+		TTL:     config.TimeDuration(200 * time.Millisecond),
+	}
+	c.Finalize()
+
+	g, err := newRegistration(c, consul.Agent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	g.stop(true)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if registered == nil {
+		t.Fatal("expected the service to be registered")
+	}
+	if registered.ID != id || registered.Name != "edge-templates" {
+		t.Errorf("expected %q in edge-templates, got %q in %q",
+			id, registered.ID, registered.Name)
+	}
+	if registered.Check == nil || registered.Check.TTL != "200ms" ||
+		registered.Check.DeregisterCriticalServiceAfter != "1m0s" {
+		t.Errorf("unexpected check %#v", registered.Check)
+	}
+
+	exp := []string{
+		"/v1/agent/service/register",
+		"/v1/agent/check/update/service:" + id,
+		"/v1/agent/service/deregister/" + id,
+	}
+	for _, path := range exp {
+		found := false
+		for _, call := range calls {
+			found = found || call == path
+		}
+		if !found {
+			t.Errorf("expected a call to %s, got %v", path, calls)
+		}
+	}
+	if last := calls[len(calls)-1]; last != exp[2] {
+		t.Errorf("expected to deregister last, got %s", last)
+	}
+}
+
+func TestNewRegistration_invalidTTL(t *testing.T) {
+	t.Parallel()
+
+	c := &config.RegistrationConfig{TTL: config.TimeDuration(0)}
+	c.Finalize()
+
+	if _, err := newRegistration(c, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestRegistration_reload(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var calls []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	consul, err := consulapi.NewClient(&consulapi.Config{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &config.RegistrationConfig{Service: config.String("edge-templates")}
+	c.Finalize()
+
+	g, err := newRegistration(c, consul.Agent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.stop(false)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, call := range calls {
+		if strings.HasPrefix(call, "/v1/agent/service/deregister/") {
+			t.Errorf("expected a reload not to deregister, got %v", calls)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+	"github.com/pkg/errors"
+)
+
+// groupMember is a template configuration in a render group, with the
+// template it belongs to.
+type groupMember struct {
+	tmpl   *template.Template
+	config *config.TemplateConfig
+}
+
+// initRenderGroups validates the render groups and maps the destinations of
+// their templates to them.
+func (r *Runner) initRenderGroups() error {
+	destinations := make(map[string]*config.TemplateConfig)
+	for _, c := range *r.config.Templates {
+		destinations[config.StringVal(c.Destination)] = c
+	}
+
+	r.renderGroups = make(map[string]*config.RenderGroupConfig)
+	r.groupReady = make(map[string]bool)
+
+	for _, g := range *r.config.RenderGroups {
+		name := config.StringVal(g.Name)
+		if name == "" {
+			return fmt.Errorf("runner: render_group must have a name")
+		}
+		if len(g.Templates) == 0 {
+			return fmt.Errorf("runner: render_group %q has no templates", name)
+		}
+
+		for _, dest := range g.Templates {
+			c, ok := destinations[dest]
+			if !ok {
+				return fmt.Errorf("runner: render_group %q: no template has the "+
+					"destination %q", name, dest)
+			}
+			if !isFileDestination(dest) {
+				return fmt.Errorf("runner: render_group %q: %s: render groups are "+
+					"only supported for file destinations", name, c.Display())
+			}
+			if config.StringVal(c.Approval) == config.TemplateApprovalManual ||
+				config.BoolVal(c.Rollout.Enabled) {
+				return fmt.Errorf("runner: render_group %q: %s: render groups "+
+					"cannot be combined with manual approval or rollout", name, c.Display())
+			}
+			if other, ok := r.renderGroups[dest]; ok {
+				return fmt.Errorf("runner: render_group %q: %s is already in "+
+					"render_group %q", name, c.Display(), config.StringVal(other.Name))
+			}
+			r.renderGroups[dest] = g
+		}
+	}
+
+	return nil
+}
+
+// renderGroupFor returns the render group of the given template configuration,
+// or nil if it is not in one.
+func (r *Runner) renderGroupFor(c *config.TemplateConfig) *config.RenderGroupConfig {
+	return r.renderGroups[config.StringVal(c.Destination)]
+}
+
+// markGroupsUnready records that the grouped configurations of the given
+// template cannot be committed until it is rendered again, for example because
+// it is missing data.
+func (r *Runner) markGroupsUnready(tmpl *template.Template) {
+	for _, c := range r.templateConfigsFor(tmpl) {
+		if r.renderGroupFor(c) != nil {
+			r.groupReady[config.StringVal(c.Destination)] = false
+		}
+	}
+}
+
+// commitRenderGroups promotes the staged contents of the render groups whose
+// templates have all rendered, returning the commands to run for the
+// promoted templates and whether any were promoted.
+func (r *Runner) commitRenderGroups() ([]*config.TemplateConfig, bool, error) {
+	var commands []*config.TemplateConfig
+	var committedAny bool
+
+	for _, g := range *r.config.RenderGroups {
+		name := config.StringVal(g.Name)
+
+		var members []*groupMember
+		var waiting int
+		for _, tmpl := range r.templates {
+			for _, c := range r.templateConfigsFor(tmpl) {
+				if r.renderGroupFor(c) != g {
+					continue
+				}
+				members = append(members, &groupMember{tmpl: tmpl, config: c})
+				if !r.groupReady[config.StringVal(c.Destination)] {
+					waiting++
+				}
+			}
+		}
+
+		if waiting > 0 {
+			log.Printf("[DEBUG] (runner) render_group %q is waiting for %d of %d "+
+				"templates", name, waiting, len(members))
+			continue
+		}
+
+		promoted, err := commitRenderGroup(members, r.clients)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "error committing render_group %q", name)
+		}
+		if len(promoted) == 0 {
+			continue
+		}
+
+		log.Printf("[INFO] (runner) committed render_group %q (%d of %d templates "+
+			"changed)", name, len(promoted), len(members))
+		committedAny = true
+
+		for _, m := range promoted {
+			r.markPromoted(m.tmpl)
+			if config.StringPresent(m.config.Exec.Command) &&
+				findCommand(m.config, commands) == nil {
+				commands = append(commands, m.config)
+			}
+		}
+	}
+
+	return commands, committedAny, nil
+}
+
+// commitRenderGroup promotes the staged contents of the given members to their
+// destinations. If any cannot be promoted, the destinations which were already
+// promoted are restored, so either all staged contents are promoted or none
+// are. The promoted members are returned. The clients are used to encrypt
+// backups with Vault transit.
+func commitRenderGroup(members []*groupMember, clients *dep.ClientSet) ([]*groupMember, error) {
+	// Keep the current contents of each destination with staged contents, so
+	// they can be restored.
+	type previous struct {
+		contents []byte
+		perms    os.FileMode
+		exists   bool
+	}
+	prev := make(map[string]*previous)
+	for _, m := range members {
+		dest := config.StringVal(m.config.Destination)
+		if _, err := os.Stat(pendingPath(dest)); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		p := &previous{}
+		if info, err := os.Stat(dest); err == nil {
+			contents, err := ioutil.ReadFile(dest)
+			if err != nil {
+				return nil, err
+			}
+			p.contents, p.perms, p.exists = contents, info.Mode().Perm(), true
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		prev[dest] = p
+	}
+
+	var promoted []*groupMember
+	for _, m := range members {
+		dest := config.StringVal(m.config.Destination)
+		if _, ok := prev[dest]; !ok {
+			continue
+		}
+
+		ok, err := promote(dest,
+			config.FileModeVal(m.config.Perms),
+			config.BoolVal(m.config.Backup),
+			newEncrypter(m.config.Encryption, clients))
+		if err == nil && ok {
+			promoted = append(promoted, m)
+			continue
+		}
+		if err == nil {
+			err = fmt.Errorf("staged contents of %q disappeared", dest)
+		}
+
+		// Roll back the destinations which were already promoted.
+		for _, done := range promoted {
+			dest := config.StringVal(done.config.Destination)
+			p := prev[dest]
+			var rerr error
+			if p.exists {
+				rerr = AtomicWrite(dest, p.contents, p.perms, false)
+			} else {
+				rerr = os.Remove(dest)
+			}
+			if rerr != nil {
+				log.Printf("[ERR] (runner) failed to restore %q: %s", dest, rerr)
+			}
+		}
+		return nil, errors.Wrap(err, m.config.Display())
+	}
+
+	return promoted, nil
+}
@@ -0,0 +1,262 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+func TestRunner_initRenderGroups(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		groups *config.RenderGroupConfigs
+		err    string
+	}{
+		{
+			"valid",
+			&config.RenderGroupConfigs{
+				&config.RenderGroupConfig{
+					Name:      config.String("frontend"),
+					Templates: []string{"/tmp/a", "/tmp/b"},
+				},
+			},
+			"",
+		},
+		{
+			"no_templates",
+			&config.RenderGroupConfigs{
+				&config.RenderGroupConfig{
+					Name: config.String("frontend"),
+				},
+			},
+			"has no templates",
+		},
+		{
+			"unknown_destination",
+			&config.RenderGroupConfigs{
+				&config.RenderGroupConfig{
+					Name:      config.String("frontend"),
+					Templates: []string{"/tmp/nope"},
+				},
+			},
+			`no template has the destination "/tmp/nope"`,
+		},
+		{
+			"manual_approval",
+			&config.RenderGroupConfigs{
+				&config.RenderGroupConfig{
+					Name:      config.String("frontend"),
+					Templates: []string{"/tmp/manual"},
+				},
+			},
+			"cannot be combined with manual approval",
+		},
+		{
+			"not_file",
+			&config.RenderGroupConfigs{
+				&config.RenderGroupConfig{
+					Name:      config.String("frontend"),
+					Templates: []string{"consul://kv/foo"},
+				},
+			},
+			"only supported for file destinations",
+		},
+		{
+			"in_two_groups",
+			&config.RenderGroupConfigs{
+				&config.RenderGroupConfig{
+					Name:      config.String("frontend"),
+					Templates: []string{"/tmp/a"},
+				},
+				&config.RenderGroupConfig{
+					Name:      config.String("backend"),
+					Templates: []string{"/tmp/a"},
+				},
+			},
+			`is already in render_group "frontend"`,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				RenderGroups: tc.groups,
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("a"),
+						Destination: config.String("/tmp/a"),
+					},
+					&config.TemplateConfig{
+						Contents:    config.String("b"),
+						Destination: config.String("/tmp/b"),
+					},
+					&config.TemplateConfig{
+						Approval:    config.String(config.TemplateApprovalManual),
+						Contents:    config.String("c"),
+						Destination: config.String("/tmp/manual"),
+					},
+					&config.TemplateConfig{
+						Contents:    config.String("d"),
+						Destination: config.String("consul://kv/foo"),
+					},
+				},
+			})
+			c.Finalize()
+
+			_, err := NewRunner(c, true, true)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestRunner_renderGroup(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := filepath.Join(dir, "haproxy.cfg")
+	cert := filepath.Join(dir, "cert.pem")
+	source := filepath.Join(dir, "cert.src")
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		RenderGroups: &config.RenderGroupConfigs{
+			&config.RenderGroupConfig{
+				Name:      config.String("frontend"),
+				Templates: []string{cfg, cert},
+			},
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("crt " + cert),
+				Command:     config.String("echo reload"),
+				Destination: config.String(cfg),
+			},
+			&config.TemplateConfig{
+				Contents:    config.String(fmt.Sprintf(`{{ file %q }}`, source)),
+				Destination: config.String(cert),
+			},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	out := new(bytes.Buffer)
+	r.outStream, r.errStream = out, out
+
+	// The certificate is missing data, so nothing is committed.
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cfg); !os.IsNotExist(err) {
+		t.Fatalf("expected %q to not be rendered, got %v", cfg, err)
+	}
+	if _, err := os.Stat(pendingPath(cfg)); err != nil {
+		t.Fatalf("expected %q to be staged: %s", cfg, err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no commands to run, got %q", out)
+	}
+
+	d, err := dep.NewFileQuery(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Receive(d, "certificate")
+
+	// Once the certificate renders, the whole group is committed.
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for path, exp := range map[string]string{
+		cfg:  "crt " + cert,
+		cert: "certificate",
+	} {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != exp {
+			t.Errorf("expected %q to be %q, got %q", path, exp, b)
+		}
+		if _, err := os.Stat(pendingPath(path)); !os.IsNotExist(err) {
+			t.Errorf("expected %q to be promoted", pendingPath(path))
+		}
+	}
+	if exp := "reload\n"; out.String() != exp {
+		t.Errorf("expected command output %q, got %q", exp, out)
+	}
+}
+
+func TestCommitRenderGroup_rollback(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+
+	// a is promoted first, then b cannot be promoted because its destination
+	// is a non-empty directory.
+	if err := ioutil.WriteFile(a, []byte("old"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(pendingPath(a), []byte("new"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(b, "child"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(pendingPath(b), []byte("new"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	members := []*groupMember{
+		{config: &config.TemplateConfig{Destination: config.String(a)}},
+		{config: &config.TemplateConfig{Destination: config.String(b)}},
+	}
+	for _, m := range members {
+		m.config.Finalize()
+	}
+
+	if _, err := commitRenderGroup(members, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	contents, err := ioutil.ReadFile(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "old" {
+		t.Errorf("expected %q to be restored, got %q", a, contents)
+	}
+}
@@ -0,0 +1,140 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/consul-template/template"
+)
+
+// TemplateTiming is the time spent executing and rendering a template while
+// profile_render is enabled.
+type TemplateTiming struct {
+	// Template is the display name of the configurations of the template.
+	Template string
+
+	// Executions is the number of times the template was executed, including
+	// executions which were missing data, and ExecuteTotal and ExecuteMax are
+	// the total and longest time those took.
+	Executions   int
+	ExecuteTotal time.Duration
+	ExecuteMax   time.Duration
+
+	// Renders is the number of times the contents were rendered to a
+	// destination, whether or not they changed, and RenderTotal is the total
+	// time those took.
+	Renders     int
+	RenderTotal time.Duration
+}
+
+// renderProfile records the timing of each template. All of its methods may
+// be called on a nil profile, which records nothing.
+type renderProfile struct {
+	sync.Mutex
+	timings map[*template.Template]*TemplateTiming
+}
+
+func newRenderProfile() *renderProfile {
+	return &renderProfile{timings: make(map[*template.Template]*TemplateTiming)}
+}
+
+// timing returns the timing of the template, creating it if needed. The lock
+// must be held.
+func (p *renderProfile) timing(tmpl *template.Template) *TemplateTiming {
+	t, ok := p.timings[tmpl]
+	if !ok {
+		t = new(TemplateTiming)
+		p.timings[tmpl] = t
+	}
+	return t
+}
+
+// executed records an execution of the template which took d.
+func (p *renderProfile) executed(tmpl *template.Template, d time.Duration) {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+
+	t := p.timing(tmpl)
+	t.Executions++
+	t.ExecuteTotal += d
+	if d > t.ExecuteMax {
+		t.ExecuteMax = d
+	}
+}
+
+// rendered records a render of the template which took d.
+func (p *renderProfile) rendered(tmpl *template.Template, d time.Duration) {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+
+	t := p.timing(tmpl)
+	t.Renders++
+	t.RenderTotal += d
+}
+
+// list returns a copy of the timings, named with the given function, slowest
+// total execution first.
+func (p *renderProfile) list(name func(*template.Template) string) []TemplateTiming {
+	if p == nil {
+		return nil
+	}
+	p.Lock()
+	defer p.Unlock()
+
+	l := make([]TemplateTiming, 0, len(p.timings))
+	for tmpl, t := range p.timings {
+		timing := *t
+		timing.Template = name(tmpl)
+		l = append(l, timing)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].ExecuteTotal != l[j].ExecuteTotal {
+			return l[i].ExecuteTotal > l[j].ExecuteTotal
+		}
+		return l[i].Template < l[j].Template
+	})
+	return l
+}
+
+// RenderProfile returns the time spent executing and rendering each template,
+// slowest first, or nil unless profile_render is enabled.
+func (r *Runner) RenderProfile() []TemplateTiming {
+	return r.profile.list(r.profileName)
+}
+
+// profileName returns the name the timing of the template is reported with.
+func (r *Runner) profileName(tmpl *template.Template) string {
+	configs := r.templateConfigsFor(tmpl)
+	names := make([]string, 0, len(configs))
+	for _, c := range configs {
+		names = append(names, c.Display())
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeRenderProfile writes the timings as a table.
+func writeRenderProfile(w io.Writer, timings []TemplateTiming) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "TEMPLATE\tEXECUTIONS\tTOTAL\tAVERAGE\tMAX\tRENDERS\tRENDER TOTAL\n")
+	for _, t := range timings {
+		var avg time.Duration
+		if t.Executions > 0 {
+			avg = t.ExecuteTotal / time.Duration(t.Executions)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%d\t%s\n",
+			t.Template, t.Executions, t.ExecuteTotal, avg, t.ExecuteMax,
+			t.Renders, t.RenderTotal)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,101 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRunner_RenderProfile(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name    string
+		enabled bool
+	}{
+		{
+			"enabled",
+			true,
+		},
+		{
+			"disabled",
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			dest := filepath.Join(dir, tc.name)
+			c := config.DefaultConfig().Merge(&config.Config{
+				ProfileRender: config.Bool(tc.enabled),
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("hello"),
+						Destination: config.String(dest),
+					},
+				},
+			})
+			c.Finalize()
+
+			r, err := NewRunner(c, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var errStream bytes.Buffer
+			r.errStream = &errStream
+
+			for j := 0; j < 2; j++ {
+				if err := r.Run(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r.Stop()
+
+			timings := r.RenderProfile()
+			if !tc.enabled {
+				if timings != nil {
+					t.Errorf("expected no timings, got %#v", timings)
+				}
+				if errStream.Len() != 0 {
+					t.Errorf("expected no report, got %q", errStream.String())
+				}
+				return
+			}
+
+			if len(timings) != 1 {
+				t.Fatalf("expected 1 timing, got %#v", timings)
+			}
+			timing := timings[0]
+			if !strings.Contains(timing.Template, dest) {
+				t.Errorf("expected template to name %q, got %q", dest, timing.Template)
+			}
+			if timing.Executions != 2 || timing.Renders != 2 {
+				t.Errorf("expected 2 executions and renders, got %d and %d",
+					timing.Executions, timing.Renders)
+			}
+			if timing.ExecuteMax > timing.ExecuteTotal {
+				t.Errorf("expected max %s to be at most total %s",
+					timing.ExecuteMax, timing.ExecuteTotal)
+			}
+
+			report := errStream.String()
+			for _, s := range []string{"Render profile:", "EXECUTIONS", dest} {
+				if !strings.Contains(report, s) {
+					t.Errorf("expected report to contain %q, got %q", s, report)
+				}
+			}
+		})
+	}
+}
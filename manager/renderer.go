@@ -5,47 +5,158 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"time"
 
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/internal/xattr"
 	"github.com/pkg/errors"
 )
 
 type RenderInput struct {
 	Backup    bool
+	Clients   *dep.ClientSet
 	Contents  []byte
 	Dry       bool
 	DryStream io.Writer
+	HTTP      *config.HTTPDestinationConfig
 	Path      string
 	Perms     os.FileMode
+
+	// ExecTimeout is the maximum amount of time to wait for the command of an
+	// exec:// destination to exit.
+	ExecTimeout time.Duration
+
+	// CreateDestDirs, DirPerms, DirUID, and DirGID control how missing parent
+	// directories of the destination are created. A DirUID or DirGID of -1
+	// leaves the owner or group unchanged.
+	CreateDestDirs bool
+	DirPerms       os.FileMode
+	DirUID         int
+	DirGID         int
+
+	// WindowsACL is an SDDL string applied to the rendered file on Windows.
+	WindowsACL string
+
+	// PreserveXattrs copies the extended attributes of the existing
+	// destination to the rendered file, and SELinuxLabel is the SELinux label
+	// applied to it. Both only have an effect on Linux.
+	PreserveXattrs bool
+	SELinuxLabel   string
+
+	// xattrsFrom is the file whose extended attributes are preserved, if it is
+	// not the destination itself, like when staging a change.
+	xattrsFrom string
+
+	// Pending stages changed contents to the pending path of the destination
+	// instead of writing the destination, so they can be approved later.
+	Pending bool
+
+	// Diff configures writing an artifact describing each change to the
+	// destination.
+	Diff *config.DiffConfig
+
+	// Encryption configures encrypting the backup of the destination.
+	Encryption *config.EncryptionConfig
 }
 
 type RenderResult struct {
 	DidRender   bool
 	WouldRender bool
+
+	// DidStage is true if changed contents were staged for approval instead of
+	// being rendered.
+	DidStage bool
+}
+
+// pendingSuffix is appended to the destination of templates which require
+// manual approval to get the path where changed contents are staged.
+const pendingSuffix = ".pending"
+
+// pendingPath returns the path where changed contents for the given
+// destination are staged until they are approved.
+func pendingPath(path string) string {
+	return path + pendingSuffix
+}
+
+// Destinations returns the sorted names of the kinds of destinations templates
+// can be rendered to.
+func Destinations() []string {
+	return []string{"consul", "exec", "file", "http", "vault"}
+}
+
+// isFileDestination returns true if the given destination is a path on disk
+// rather than a Consul KV, Vault, HTTP or exec destination.
+func isFileDestination(s string) bool {
+	return !isConsulKVDestination(s) &&
+		!isVaultKVDestination(s) &&
+		!isHTTPDestination(s) &&
+		!isExecDestination(s)
 }
 
 // Render atomically renders a file contents to disk, returning a result of
-// whether it would have rendered and actually did render.
+// whether it would have rendered and actually did render. Destinations that
+// begin with "consul://kv/" or "vault://" are written to the Consul KV store
+// or a Vault KV v2 secret instead, http:// or https:// destinations are sent
+// as a request to the URL, and exec:// destinations are piped to the stdin of
+// the command.
 func Render(i *RenderInput) (*RenderResult, error) {
+	if isConsulKVDestination(i.Path) {
+		return renderConsulKV(i)
+	}
+	if isVaultKVDestination(i.Path) {
+		return renderVaultKV(i)
+	}
+	if isHTTPDestination(i.Path) {
+		return renderHTTP(i)
+	}
+	if isExecDestination(i.Path) {
+		return renderExec(i)
+	}
+
 	existing, err := ioutil.ReadFile(i.Path)
 	if err != nil && !os.IsNotExist(err) {
 		return nil, errors.Wrap(err, "failed reading file")
 	}
 
 	if bytes.Equal(existing, i.Contents) {
+		if !i.Dry && i.Perms != 0 {
+			if err := enforcePerms(i.Path, i.Perms); err != nil {
+				return nil, errors.Wrap(err, "failed enforcing permissions")
+			}
+		}
+
+		// The contents changed back before they were approved, so the staged
+		// contents are stale.
+		if !i.Dry && i.Pending {
+			if err := os.Remove(pendingPath(i.Path)); err == nil {
+				log.Printf("[INFO] (runner) removed stale %q", pendingPath(i.Path))
+			} else if !os.IsNotExist(err) {
+				return nil, errors.Wrap(err, "failed removing pending file")
+			}
+		}
+
 		return &RenderResult{
 			DidRender:   false,
 			WouldRender: true,
 		}, nil
 	}
 
+	if i.Pending && !i.Dry {
+		return stage(i, existing)
+	}
+
 	if i.Dry {
 		fmt.Fprintf(i.DryStream, "> %s\n%s", i.Path, i.Contents)
 	} else {
-		if err := AtomicWrite(i.Path, i.Contents, i.Perms, i.Backup); err != nil {
+		if err := atomicWrite(i); err != nil {
 			return nil, errors.Wrap(err, "failed writing file")
 		}
+		writeDiff(i.Diff, i.Path, existing, i.Contents)
 	}
 
 	return &RenderResult{
@@ -54,6 +165,79 @@ func Render(i *RenderInput) (*RenderResult, error) {
 	}, nil
 }
 
+// stage writes changed contents to the pending path of the destination,
+// leaving the destination itself untouched. The current contents of the
+// destination are given to describe the staged change.
+func stage(i *RenderInput, current []byte) (*RenderResult, error) {
+	path := pendingPath(i.Path)
+
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed reading pending file")
+	}
+
+	if err == nil && bytes.Equal(existing, i.Contents) {
+		return &RenderResult{
+			DidRender:   false,
+			WouldRender: true,
+		}, nil
+	}
+
+	p := *i
+	p.Backup = false
+	p.Path = path
+	p.xattrsFrom = i.Path
+	if err := atomicWrite(&p); err != nil {
+		return nil, errors.Wrap(err, "failed writing pending file")
+	}
+	writeDiff(i.Diff, i.Path, current, i.Contents)
+
+	return &RenderResult{
+		DidRender:   false,
+		WouldRender: true,
+		DidStage:    true,
+	}, nil
+}
+
+// promote moves the approved contents staged at the pending path of the
+// destination into place, keeping a backup of the destination if requested,
+// encrypted with the encrypter if it is not nil. It returns false if there
+// were no staged contents.
+func promote(path string, perms os.FileMode, backup bool, enc *encrypter) (bool, error) {
+	pending := pendingPath(path)
+	if _, err := os.Stat(pending); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if backup {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			if err := backupFile(path, enc); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	perms, err := filePerms(pending, perms)
+	if err != nil {
+		return false, err
+	}
+
+	if err := os.Rename(pending, path); err != nil {
+		return false, err
+	}
+
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(path, perms); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
 // AtomicWrite accepts a destination path and the template contents. It writes
 // the template contents to a TempFile on disk, returning if any errors occur.
 //
@@ -61,26 +245,58 @@ func Render(i *RenderInput) (*RenderResult, error) {
 // automatically with permissions 0755. To use a different permission, create
 // the directory first or use `chmod` in a Command.
 //
-// If the destination path exists, all attempts will be made to preserve the
-// existing file permissions. If those permissions cannot be read, an error is
-// returned. If the file does not exist, it will be created automatically with
-// permissions 0644. To use a different permission, create the destination file
-// first or use `chmod` in a Command.
+// If perms is zero and the destination path exists, all attempts will be made
+// to preserve the existing file permissions. If those permissions cannot be
+// read, an error is returned. If the file does not exist, it will be created
+// automatically with permissions 0644. To use a different permission, create
+// the destination file first or use `chmod` in a Command. Otherwise, the
+// destination file is given exactly the requested permissions, regardless of
+// the process umask.
 //
 // If no errors occur, the Tempfile is "renamed" (moved) to the destination
 // path.
 func AtomicWrite(path string, contents []byte, perms os.FileMode, backup bool) error {
+	return atomicWrite(&RenderInput{
+		Backup:         backup,
+		Contents:       contents,
+		CreateDestDirs: true,
+		DirPerms:       0755,
+		DirUID:         -1,
+		DirGID:         -1,
+		Path:           path,
+		Perms:          perms,
+	})
+}
+
+// atomicWrite is the implementation of AtomicWrite, which also applies the
+// platform-specific options in the render input.
+func atomicWrite(i *RenderInput) error {
+	path, contents, perms, backup := i.Path, i.Contents, i.Perms, i.Backup
 	if path == "" {
 		return fmt.Errorf("missing destination")
 	}
 
 	parent := filepath.Dir(path)
 	if _, err := os.Stat(parent); os.IsNotExist(err) {
-		if err := os.MkdirAll(parent, 0755); err != nil {
+		if !i.CreateDestDirs {
+			return fmt.Errorf("parent directory %q does not exist and "+
+				"create_dest_dirs is disabled", parent)
+		}
+		if err := mkdirAll(parent, i.DirPerms, i.DirUID, i.DirGID); err != nil {
 			return err
 		}
 	}
 
+	// Staged contents take the permissions of the destination they replace.
+	from := i.xattrsFrom
+	if from == "" {
+		from = path
+	}
+	perms, err := filePerms(from, perms)
+	if err != nil {
+		return err
+	}
+
 	f, err := ioutil.TempFile(parent, "")
 	if err != nil {
 		return err
@@ -103,11 +319,35 @@ func AtomicWrite(path string, contents []byte, perms os.FileMode, backup bool) e
 		return err
 	}
 
+	// Apply the ACL before the rename so the destination is never visible
+	// with the inherited ACL.
+	if i.WindowsACL != "" {
+		if err := setWindowsACL(f.Name(), i.WindowsACL); err != nil {
+			return err
+		}
+	}
+
+	// Likewise, copy the extended attributes of the destination and apply the
+	// SELinux label before the rename, so the destination never has the
+	// default label of a new file, which services may be denied to read.
+	if i.PreserveXattrs {
+		if _, err := os.Stat(from); err == nil {
+			if err := xattr.Copy(from, f.Name()); err != nil {
+				return err
+			}
+		}
+	}
+	if i.SELinuxLabel != "" {
+		if err := xattr.SetSELinuxLabel(f.Name(), i.SELinuxLabel); err != nil {
+			return err
+		}
+	}
+
 	// If we got this far, it means we are about to save the file. Copy the
 	// current contents of the file onto disk (if it exists) so we have a backup.
 	if backup {
 		if _, err := os.Stat(path); !os.IsNotExist(err) {
-			if err := copyFile(path, path+".bak"); err != nil {
+			if err := backupFile(path, newEncrypter(i.Encryption, i.Clients)); err != nil {
 				return err
 			}
 		}
@@ -117,9 +357,120 @@ func AtomicWrite(path string, contents []byte, perms os.FileMode, backup bool) e
 		return err
 	}
 
+	// Set the mode again after the rename, in case the destination is on a
+	// filesystem which does not preserve it.
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(path, perms); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// filePerms returns the given permissions, or if they are zero, the
+// permissions of the file at path, or 0644 if it does not exist.
+func filePerms(path string, perms os.FileMode) (os.FileMode, error) {
+	if perms != 0 {
+		return perms, nil
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config.DefaultTemplateFilePerms, nil
+		}
+		return 0, err
+	}
+	return stat.Mode().Perm(), nil
+}
+
+// enforcePerms sets the mode of the file at path to the given permissions if
+// it has drifted, for example because it was changed by another process. It is
+// a no-op on Windows, where file modes are not meaningful.
+func enforcePerms(path string, perms os.FileMode) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if stat.Mode().Perm() == perms.Perm() {
+		return nil
+	}
+
+	log.Printf("[INFO] (runner) resetting permissions of %q from %s to %s",
+		path, stat.Mode().Perm(), perms.Perm())
+	return os.Chmod(path, perms)
+}
+
+// mkdirAll is like os.MkdirAll, except that each directory it creates is
+// given exactly the requested permissions, regardless of the process umask,
+// and the given owner and group. Existing directories are not modified.
+func mkdirAll(path string, perms os.FileMode, uid, gid int) error {
+	stat, err := os.Stat(path)
+	if err == nil {
+		if !stat.IsDir() {
+			return fmt.Errorf("%q is not a directory", path)
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	if parent := filepath.Dir(path); parent != path {
+		if err := mkdirAll(parent, perms, uid, gid); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Mkdir(path, perms); err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := os.Chmod(path, perms); err != nil {
+		return err
+	}
+
+	if (uid != -1 || gid != -1) && runtime.GOOS != "windows" {
+		if err := os.Chown(path, uid, gid); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// backupFile copies the file at path to its backup, encrypting the copy if the
+// encrypter is not nil. The backup has the same mode as the file.
+func backupFile(path string, enc *encrypter) error {
+	if enc == nil {
+		return copyFile(path, path+".bak")
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sealed, err := enc.encrypt(contents)
+	if err != nil {
+		return errors.Wrap(err, "failed encrypting backup")
+	}
+	return AtomicWrite(path+".bak", sealed, stat.Mode().Perm(), false)
+}
+
 // copyFile copies the file at src to the path at dst. Any errors that occur
 // are returned.
 func copyFile(src, dst string) error {
@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/pkg/errors"
+)
+
+const (
+	// consulKVScheme is the prefix for destinations which render into the
+	// Consul KV store instead of onto disk.
+	consulKVScheme = "consul://kv/"
+
+	// consulKVMaxCASAttempts is the number of times a check-and-set write is
+	// attempted before giving up.
+	consulKVMaxCASAttempts = 3
+)
+
+// isConsulKVDestination returns true if the given destination is a Consul KV
+// destination.
+func isConsulKVDestination(s string) bool {
+	return strings.HasPrefix(s, consulKVScheme)
+}
+
+// parseConsulKVDestination parses a destination of the form
+// consul://kv/path/to/key into the key path.
+func parseConsulKVDestination(s string) (string, error) {
+	if !isConsulKVDestination(s) {
+		return "", fmt.Errorf("consul kv destination must begin with %q: %q",
+			consulKVScheme, s)
+	}
+
+	key := strings.Trim(strings.TrimPrefix(s, consulKVScheme), "/")
+	if key == "" {
+		return "", fmt.Errorf("consul kv destination is missing a key: %q", s)
+	}
+	return key, nil
+}
+
+// renderConsulKV renders the contents into the Consul KV store. Writes use
+// check-and-set against the index of the value that was read, so concurrent
+// writers never silently clobber each other.
+func renderConsulKV(i *RenderInput) (*RenderResult, error) {
+	key, err := parseConsulKVDestination(i.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.Clients == nil {
+		return nil, fmt.Errorf("consul kv destination %q: missing clients", i.Path)
+	}
+	kv := i.Clients.Consul().KV()
+
+	for attempt := 1; attempt <= consulKVMaxCASAttempts; attempt++ {
+		existing, _, err := kv.Get(key, &consulapi.QueryOptions{
+			RequireConsistent: true,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed reading key")
+		}
+
+		var index uint64
+		if existing != nil {
+			if bytes.Equal(existing.Value, i.Contents) {
+				return &RenderResult{
+					DidRender:   false,
+					WouldRender: true,
+				}, nil
+			}
+			index = existing.ModifyIndex
+		}
+
+		if i.Dry {
+			fmt.Fprintf(i.DryStream, "> %s\n%s", i.Path, i.Contents)
+			return &RenderResult{
+				DidRender:   true,
+				WouldRender: true,
+			}, nil
+		}
+
+		ok, _, err := kv.CAS(&consulapi.KVPair{
+			Key:         key,
+			Value:       i.Contents,
+			ModifyIndex: index,
+		}, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed writing key")
+		}
+		if ok {
+			return &RenderResult{
+				DidRender:   true,
+				WouldRender: true,
+			}, nil
+		}
+
+		log.Printf("[DEBUG] (runner) check-and-set failed for %q (attempt %d)",
+			key, attempt)
+	}
+
+	return nil, fmt.Errorf("failed writing key %q: check-and-set failed after %d attempts",
+		key, consulKVMaxCASAttempts)
+}
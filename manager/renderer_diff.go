@@ -0,0 +1,179 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// diffTimeFormat is the format of the timestamp in the name of diff artifacts.
+// It sorts lexically in the order the artifacts were written.
+const diffTimeFormat = "20060102T150405.000000000Z"
+
+// writeDiff writes an artifact describing the change of the contents of the
+// given destination from old to new, if enabled. Failures are logged rather
+// than returned so they never prevent a render.
+func writeDiff(c *config.DiffConfig, path string, old, new []byte) {
+	if c == nil || !config.BoolVal(c.Enabled) {
+		return
+	}
+
+	if _, err := writeDiffFile(c, path, old, new, time.Now()); err != nil {
+		log.Printf("[WARN] (runner) failed writing diff for %q: %s", path, err)
+	}
+}
+
+// writeDiffFile writes the artifact describing the change of the contents of
+// the given destination into the configured directory and returns its path.
+// Artifacts may contain secrets, so they are only readable by the owner.
+func writeDiffFile(c *config.DiffConfig, path string, old, new []byte, now time.Time) (string, error) {
+	var contents []byte
+	var ext string
+
+	switch format := config.StringVal(c.Format); format {
+	case config.DiffFormatUnified:
+		diff, err := unifiedDiff(path, old, new)
+		if err != nil {
+			return "", err
+		}
+		contents, ext = []byte(diff), ".diff"
+	case config.DiffFormatJSONPatch:
+		patch, err := jsonPatch(old, new)
+		if err != nil {
+			return "", err
+		}
+		contents, ext = patch, ".json"
+	default:
+		return "", fmt.Errorf("unknown diff format %q", format)
+	}
+
+	dir := config.StringVal(c.Dir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", errors.Wrap(err, "failed creating diff directory")
+	}
+
+	name := filepath.Base(path) + "-" + now.UTC().Format(diffTimeFormat) + ext
+	out := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(out, contents, 0600); err != nil {
+		return "", errors.Wrap(err, "failed writing diff")
+	}
+	return out, nil
+}
+
+// unifiedDiff returns a unified diff of the contents of the given destination.
+func unifiedDiff(path string, old, new []byte) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(old)),
+		B:        difflib.SplitLines(string(new)),
+		FromFile: path,
+		ToFile:   path,
+		Context:  3,
+	})
+}
+
+// jsonPatch returns an RFC 6902 JSON Patch which transforms the old contents
+// into the new contents. Both must be JSON, except that empty old contents
+// are treated as a missing document. Objects are compared key by key and any
+// other differing value is replaced as a whole.
+func jsonPatch(old, new []byte) ([]byte, error) {
+	after, err := decodeJSON(new)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed decoding new contents")
+	}
+
+	ops := []map[string]interface{}{}
+	if len(bytes.TrimSpace(old)) == 0 {
+		ops = append(ops, map[string]interface{}{
+			"op":    "add",
+			"path":  "",
+			"value": after,
+		})
+	} else {
+		before, err := decodeJSON(old)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed decoding old contents")
+		}
+		ops = diffJSON(ops, "", before, after)
+	}
+
+	return json.MarshalIndent(ops, "", "  ")
+}
+
+// decodeJSON decodes the given JSON, keeping numbers as they were written.
+func decodeJSON(b []byte) (interface{}, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// diffJSON appends the operations which transform before into after at the
+// given JSON pointer to ops.
+func diffJSON(ops []map[string]interface{}, ptr string, before, after interface{}) []map[string]interface{} {
+	b, bok := before.(map[string]interface{})
+	a, aok := after.(map[string]interface{})
+	if !bok || !aok {
+		if !reflect.DeepEqual(before, after) {
+			ops = append(ops, map[string]interface{}{
+				"op":    "replace",
+				"path":  ptr,
+				"value": after,
+			})
+		}
+		return ops
+	}
+
+	keys := make([]string, 0, len(b)+len(a))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := ptr + "/" + escapeJSONPointer(k)
+		bv, inBefore := b[k]
+		av, inAfter := a[k]
+		switch {
+		case !inAfter:
+			ops = append(ops, map[string]interface{}{
+				"op":   "remove",
+				"path": p,
+			})
+		case !inBefore:
+			ops = append(ops, map[string]interface{}{
+				"op":    "add",
+				"path":  p,
+				"value": av,
+			})
+		default:
+			ops = diffJSON(ops, p, bv, av)
+		}
+	}
+	return ops
+}
+
+// escapeJSONPointer escapes a reference token of a JSON pointer as described
+// in RFC 6901.
+func escapeJSONPointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
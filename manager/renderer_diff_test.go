@@ -0,0 +1,177 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestJSONPatch(t *testing.T) {
+	cases := []struct {
+		name string
+		old  string
+		new  string
+		exp  string
+		err  bool
+	}{
+		{
+			"empty_old",
+			"",
+			`{"a":1}`,
+			`[{"op":"add","path":"","value":{"a":1}}]`,
+			false,
+		},
+		{
+			"unchanged",
+			`{"a":1}`,
+			`{"a":1}`,
+			`[]`,
+			false,
+		},
+		{
+			"add_remove_replace",
+			`{"a":1,"b":{"c":"x","d":true}}`,
+			`{"b":{"c":"y","e":null},"f":[1,2]}`,
+			`[
+				{"op":"remove","path":"/a"},
+				{"op":"replace","path":"/b/c","value":"y"},
+				{"op":"remove","path":"/b/d"},
+				{"op":"add","path":"/b/e","value":null},
+				{"op":"add","path":"/f","value":[1,2]}
+			]`,
+			false,
+		},
+		{
+			"arrays_replaced",
+			`{"a":[1,2]}`,
+			`{"a":[1,3]}`,
+			`[{"op":"replace","path":"/a","value":[1,3]}]`,
+			false,
+		},
+		{
+			"escaped",
+			`{"a/b":1,"c~d":1}`,
+			`{"a/b":2,"c~d":2}`,
+			`[
+				{"op":"replace","path":"/a~1b","value":2},
+				{"op":"replace","path":"/c~0d","value":2}
+			]`,
+			false,
+		},
+		{
+			"not_json",
+			`{"a":1}`,
+			`a = 1`,
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			b, err := jsonPatch([]byte(tc.old), []byte(tc.new))
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if tc.err {
+				return
+			}
+
+			var act, exp interface{}
+			if err := json.Unmarshal(b, &act); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tc.exp), &exp); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(exp, act) {
+				t.Errorf("\nexp: %s\nact: %s", tc.exp, b)
+			}
+		})
+	}
+}
+
+func TestWriteDiffFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2018, 1, 2, 3, 4, 5, 6, time.UTC)
+	c := &config.DiffConfig{
+		Dir:    config.String(filepath.Join(dir, "diffs")),
+		Format: config.String(config.DiffFormatUnified),
+	}
+	c.Finalize()
+
+	path, err := writeDiffFile(c, "/etc/app.conf", []byte("a\nb\n"), []byte("a\nc\n"), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := filepath.Join(dir, "diffs", "app.conf-20180102T030405.000000006Z.diff")
+	if path != exp {
+		t.Errorf("expected %q to be %q", path, exp)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"--- /etc/app.conf", "+++ /etc/app.conf", "-b\n", "+c\n"} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("expected %q to contain %q", b, s)
+		}
+	}
+}
+
+func TestRender_diff(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	diffDir := filepath.Join(outDir, "diffs")
+	c := &config.DiffConfig{
+		Dir:    config.String(diffDir),
+		Format: config.String(config.DiffFormatJSONPatch),
+	}
+	c.Finalize()
+
+	in := &RenderInput{
+		Contents: []byte(`{"a":1}`),
+		Diff:     c,
+		Path:     filepath.Join(outDir, "out.json"),
+		Perms:    0644,
+	}
+	if _, err := Render(in); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged contents do not write another artifact.
+	if _, err := Render(in); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(diffDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 diff, got %d", len(files))
+	}
+	if name := files[0].Name(); !strings.HasPrefix(name, "out.json-") ||
+		!strings.HasSuffix(name, ".json") {
+		t.Errorf("unexpected diff name %q", name)
+	}
+}
@@ -0,0 +1,134 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/mattn/go-shellwords"
+	"github.com/pkg/errors"
+)
+
+const (
+	// execDestinationPrefix is the prefix of destinations which pipe the
+	// rendered contents to the stdin of a command.
+	execDestinationPrefix = "exec://"
+
+	// execOutputLimit is the maximum amount of command output included in an
+	// error.
+	execOutputLimit = 1024
+)
+
+var (
+	// execRenderedLock protects execRendered.
+	execRenderedLock sync.Mutex
+
+	// execRendered is the hash of the contents last successfully piped to
+	// each exec destination. Commands cannot be read back, so this is used to
+	// avoid running the command again for unchanged contents.
+	execRendered = make(map[string][md5.Size]byte)
+)
+
+// isExecDestination returns true if the given destination is an exec://
+// command.
+func isExecDestination(s string) bool {
+	return strings.HasPrefix(s, execDestinationPrefix)
+}
+
+// parseExecDestination returns the command and arguments of the given exec
+// destination.
+func parseExecDestination(s string) ([]string, error) {
+	p := shellwords.NewParser()
+	p.ParseEnv = true
+	args, err := p.Parse(strings.TrimPrefix(s, execDestinationPrefix))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing command")
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing command in %q", s)
+	}
+	return args, nil
+}
+
+// renderExec pipes the contents to the stdin of the command of an exec
+// destination, and waits for the command to exit. If the command fails, an
+// error is returned and the contents are not recorded, so the command runs
+// again on the next render.
+func renderExec(i *RenderInput) (*RenderResult, error) {
+	hash := md5.Sum(i.Contents)
+
+	execRenderedLock.Lock()
+	last, ok := execRendered[i.Path]
+	execRenderedLock.Unlock()
+	if ok && last == hash {
+		return &RenderResult{
+			DidRender:   false,
+			WouldRender: true,
+		}, nil
+	}
+
+	if i.Dry {
+		fmt.Fprintf(i.DryStream, "> %s\n%s", i.Path, i.Contents)
+		return &RenderResult{
+			DidRender:   true,
+			WouldRender: true,
+		}, nil
+	}
+
+	args, err := parseExecDestination(i.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := runExec(args, i.Contents, i.ExecTimeout); err != nil {
+		return nil, err
+	}
+
+	execRenderedLock.Lock()
+	execRendered[i.Path] = hash
+	execRenderedLock.Unlock()
+
+	return &RenderResult{
+		DidRender:   true,
+		WouldRender: true,
+	}, nil
+}
+
+// runExec runs the given command with the contents as its stdin, returning an
+// error with the output of the command if it fails or does not exit within
+// the timeout.
+func runExec(args []string, contents []byte, timeout time.Duration) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(contents)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := child.RunCommand(cmd); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		out := bytes.TrimSpace(output.Bytes())
+		if len(out) == 0 {
+			return fmt.Errorf("command %q failed: %s", args[0], err)
+		}
+		if len(out) > execOutputLimit {
+			out = out[len(out)-execOutputLimit:]
+		}
+		return fmt.Errorf("command %q failed: %s: %s", args[0], err, out)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// +build linux darwin freebsd openbsd solaris netbsd
+
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExecDestination(t *testing.T) {
+	cases := []struct {
+		name string
+		s    string
+		e    []string
+		err  bool
+	}{
+		{
+			"command",
+			"exec://haproxy -f -",
+			[]string{"haproxy", "-f", "-"},
+			false,
+		},
+		{
+			"quoted",
+			`exec://sh -c "cat > /tmp/out"`,
+			[]string{"sh", "-c", "cat > /tmp/out"},
+			false,
+		},
+		{
+			"empty",
+			"exec://",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			a, err := parseExecDestination(tc.s)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if strings.Join(a, "|") != strings.Join(tc.e, "|") {
+				t.Errorf("expected %q to be %q", a, tc.e)
+			}
+		})
+	}
+}
+
+func TestRender_exec(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	outFile := filepath.Join(outDir, "out")
+	countFile := filepath.Join(outDir, "count")
+	dest := fmt.Sprintf(`exec://sh -c "cat > %s && echo x >> %s"`, outFile, countFile)
+
+	for i := 0; i < 2; i++ {
+		result, err := Render(&RenderInput{
+			Contents:    []byte("hello"),
+			ExecTimeout: 5 * time.Second,
+			Path:        dest,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := i == 0; result.DidRender != exp {
+			t.Errorf("%d: expected DidRender to be %t", i, exp)
+		}
+	}
+
+	b, err := ioutil.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Errorf("expected %q to be %q", b, "hello")
+	}
+
+	// Unchanged contents do not run the command again.
+	count, err := ioutil.ReadFile(countFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(count) != "x\n" {
+		t.Errorf("expected the command to run once, got %q", count)
+	}
+}
+
+func TestRender_execFailure(t *testing.T) {
+	countDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(countDir)
+
+	countFile := filepath.Join(countDir, "count")
+	dest := fmt.Sprintf(`exec://sh -c "echo x >> %s; exit 1"`, countFile)
+
+	// A failed command returns an error, and is run again even if the
+	// contents did not change.
+	for _, contents := range []string{"a", "a"} {
+		_, err := Render(&RenderInput{
+			Contents:    []byte(contents),
+			ExecTimeout: 5 * time.Second,
+			Path:        dest,
+		})
+		if err == nil {
+			t.Fatalf("%s: expected an error", contents)
+		}
+	}
+
+	count, err := ioutil.ReadFile(countFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(count) != "x\nx\n" {
+		t.Errorf("expected the command to run twice, got %q", count)
+	}
+}
+
+func TestRunExec(t *testing.T) {
+	cases := []struct {
+		name    string
+		args    []string
+		timeout time.Duration
+		err     string
+	}{
+		{
+			"success",
+			[]string{"cat"},
+			5 * time.Second,
+			"",
+		},
+		{
+			"output",
+			[]string{"sh", "-c", "echo bad config >&2; exit 1"},
+			5 * time.Second,
+			"bad config",
+		},
+		{
+			"timeout",
+			[]string{"sleep", "5"},
+			100 * time.Millisecond,
+			"timed out",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			err := runExec(tc.args, []byte("hello"), tc.timeout)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected %v to contain %q", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/pkg/errors"
+)
+
+var (
+	// httpRenderedLock protects httpRendered.
+	httpRenderedLock sync.Mutex
+
+	// httpRendered is the hash of the contents last successfully sent to each
+	// HTTP destination. Unlike files and KV, remote APIs cannot generally be
+	// read back, so this is used to avoid resending unchanged payloads.
+	httpRendered = make(map[string][md5.Size]byte)
+)
+
+// isHTTPDestination returns true if the given destination is an http:// or
+// https:// URL.
+func isHTTPDestination(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// parseHTTPHeaders parses a list of headers in the form "Name: value".
+func parseHTTPHeaders(list []string) (http.Header, error) {
+	h := make(http.Header, len(list))
+	for _, raw := range list {
+		parts := strings.SplitN(raw, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid header %q: expected \"Name: value\"", raw)
+		}
+		h.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+	return h, nil
+}
+
+// httpSuccess returns true if the given status code is a success. If no codes
+// are given, any 2xx status code is a success.
+func httpSuccess(code int, codes []int) bool {
+	if len(codes) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// renderHTTP sends the contents to an HTTP destination.
+func renderHTTP(i *RenderInput) (*RenderResult, error) {
+	c := i.HTTP
+	if c == nil {
+		c = config.DefaultHTTPDestinationConfig()
+		c.Finalize()
+	}
+
+	method := config.StringVal(c.Method)
+	key := method + " " + i.Path
+	hash := md5.Sum(i.Contents)
+
+	httpRenderedLock.Lock()
+	last, ok := httpRendered[key]
+	httpRenderedLock.Unlock()
+	if ok && last == hash {
+		return &RenderResult{
+			DidRender:   false,
+			WouldRender: true,
+		}, nil
+	}
+
+	if i.Dry {
+		fmt.Fprintf(i.DryStream, "> %s %s\n%s", method, i.Path, i.Contents)
+		return &RenderResult{
+			DidRender:   true,
+			WouldRender: true,
+		}, nil
+	}
+
+	headers, err := parseHTTPHeaders(c.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, i.Path, bytes.NewReader(i.Contents))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating request")
+	}
+	req.Header = headers
+
+	client := &http.Client{Timeout: config.TimeDurationVal(c.Timeout)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed sending request")
+	}
+	defer resp.Body.Close()
+
+	if !httpSuccess(resp.StatusCode, c.SuccessCodes) {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected response code %d from %s: %s",
+			resp.StatusCode, i.Path, bytes.TrimSpace(body))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+
+	httpRenderedLock.Lock()
+	httpRendered[key] = hash
+	httpRenderedLock.Unlock()
+
+	return &RenderResult{
+		DidRender:   true,
+		WouldRender: true,
+	}, nil
+}
@@ -2,10 +2,19 @@ package manager
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	vaultapi "github.com/hashicorp/vault/api"
 )
 
 func TestAtomicWrite(t *testing.T) {
@@ -79,6 +88,27 @@ func TestAtomicWrite(t *testing.T) {
 		}
 	})
 
+	t.Run("create_dest_dirs_disabled", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		file := filepath.Join(outDir, "missing", "nope")
+		err = atomicWrite(&RenderInput{
+			CreateDestDirs: false,
+			Path:           file,
+			Perms:          0644,
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "create_dest_dirs is disabled") {
+			t.Errorf("unexpected error %q", err)
+		}
+	})
+
 	t.Run("backup", func(t *testing.T) {
 		outDir, err := ioutil.TempDir("", "")
 		if err != nil {
@@ -117,6 +147,49 @@ func TestAtomicWrite(t *testing.T) {
 		}
 	})
 
+	t.Run("backup_encrypted", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		keyFile := testEncryptionKeyFile(t, outDir)
+		file := filepath.Join(outDir, "secret")
+		if err := ioutil.WriteFile(file, []byte("before"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		err = atomicWrite(&RenderInput{
+			Backup:   true,
+			Contents: []byte("after"),
+			Encryption: &config.EncryptionConfig{
+				Enabled: config.Bool(true),
+				KeyFile: config.String(keyFile),
+			},
+			Path:  file,
+			Perms: 0600,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := ioutil.ReadFile(file + ".bak")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(raw, []byte("before")) {
+			t.Fatalf("expected backup to be encrypted, got %q", raw)
+		}
+		contents, err := decrypt(nil, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(contents, []byte("before")) {
+			t.Fatalf("expected %q to be %q", contents, []byte("before"))
+		}
+	})
+
 	t.Run("backup_not_exists", func(t *testing.T) {
 		outDir, err := ioutil.TempDir("", "")
 		if err != nil {
@@ -145,3 +218,325 @@ func TestAtomicWrite(t *testing.T) {
 		}
 	})
 }
+
+func TestParseConsulKVDestination(t *testing.T) {
+	cases := []struct {
+		name string
+		s    string
+		key  string
+		err  bool
+	}{
+		{
+			"key",
+			"consul://kv/foo/bar",
+			"foo/bar",
+			false,
+		},
+		{
+			"trailing_slash",
+			"consul://kv/foo/bar/",
+			"foo/bar",
+			false,
+		},
+		{
+			"missing_key",
+			"consul://kv/",
+			"",
+			true,
+		},
+		{
+			"not_kv",
+			"consul://catalog/foo",
+			"",
+			true,
+		},
+		{
+			"file",
+			"/tmp/foo",
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			key, err := parseConsulKVDestination(tc.s)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if key != tc.key {
+				t.Errorf("expected %q to be %q", key, tc.key)
+			}
+		})
+	}
+}
+
+func TestParseVaultKVDestination(t *testing.T) {
+	cases := []struct {
+		name  string
+		s     string
+		path  string
+		field string
+		err   bool
+	}{
+		{
+			"path_field",
+			"vault://secret/data/foo#bar",
+			"secret/data/foo",
+			"bar",
+			false,
+		},
+		{
+			"missing_field",
+			"vault://secret/data/foo",
+			"",
+			"",
+			true,
+		},
+		{
+			"empty_field",
+			"vault://secret/data/foo#",
+			"",
+			"",
+			true,
+		},
+		{
+			"not_v2",
+			"vault://secret/foo#bar",
+			"",
+			"",
+			true,
+		},
+		{
+			"file",
+			"/tmp/foo",
+			"",
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			path, field, err := parseVaultKVDestination(tc.s)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if path != tc.path {
+				t.Errorf("expected %q to be %q", path, tc.path)
+			}
+			if field != tc.field {
+				t.Errorf("expected %q to be %q", field, tc.field)
+			}
+		})
+	}
+}
+
+func TestVaultKVData(t *testing.T) {
+	data, version := vaultKVData(nil)
+	if len(data) != 0 || version != 0 {
+		t.Errorf("expected empty data and version 0, got %#v %d", data, version)
+	}
+
+	data, version = vaultKVData(&vaultapi.Secret{
+		Data: map[string]interface{}{
+			"data":     map[string]interface{}{"foo": "bar"},
+			"metadata": map[string]interface{}{"version": json.Number("3")},
+		},
+	})
+	if !reflect.DeepEqual(data, map[string]interface{}{"foo": "bar"}) {
+		t.Errorf("unexpected data %#v", data)
+	}
+	if version != 3 {
+		t.Errorf("expected version 3, got %d", version)
+	}
+}
+
+func TestRender_HTTP(t *testing.T) {
+	var requests int
+	var method, header, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		method = r.Method
+		header = r.Header.Get("X-Foo")
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(202)
+	}))
+	defer ts.Close()
+
+	c := &config.HTTPDestinationConfig{
+		Headers:      []string{"X-Foo: bar"},
+		Method:       config.String("PUT"),
+		SuccessCodes: []int{202},
+	}
+	c.Finalize()
+
+	in := &RenderInput{
+		Contents: []byte("hello"),
+		HTTP:     c,
+		Path:     ts.URL + "/render",
+	}
+
+	r, err := Render(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.DidRender {
+		t.Errorf("expected render")
+	}
+	if method != "PUT" || header != "bar" || body != "hello" {
+		t.Errorf("unexpected request %q %q %q", method, header, body)
+	}
+
+	r, err = Render(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.DidRender {
+		t.Errorf("expected unchanged contents to not render")
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+
+	c.SuccessCodes = []int{200}
+	in.Contents = []byte("world")
+	if _, err := Render(in); err == nil {
+		t.Fatal("expected error for unexpected status code")
+	}
+}
+
+func TestSddlSecurityInformation(t *testing.T) {
+	cases := []struct {
+		name string
+		sddl string
+		exp  uint32
+	}{
+		{
+			"dacl",
+			"D:(A;;FA;;;SY)",
+			daclSecurityInformation,
+		},
+		{
+			"protected_dacl",
+			"D:P(A;;FA;;;SY)",
+			daclSecurityInformation | protectedDaclSecurityInformation,
+		},
+		{
+			"owner_group_dacl",
+			"O:BAG:SYD:(A;;FA;;;BA)",
+			ownerSecurityInformation | groupSecurityInformation | daclSecurityInformation,
+		},
+		{
+			"empty",
+			"",
+			0,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := sddlSecurityInformation(tc.sddl); act != tc.exp {
+				t.Errorf("expected %#x to be %#x", act, tc.exp)
+			}
+		})
+	}
+}
+
+func TestRender_pending(t *testing.T) {
+	// The pending file is managed the same with and without enforced perms.
+	for _, perms := range []os.FileMode{0644, 0} {
+		perms := perms
+		t.Run(fmt.Sprintf("perms_%o", perms), func(t *testing.T) {
+			outDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(outDir)
+			path := filepath.Join(outDir, "out")
+			if err := ioutil.WriteFile(path, []byte("before"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			in := &RenderInput{
+				Contents:       []byte("after"),
+				CreateDestDirs: true,
+				Path:           path,
+				Pending:        true,
+				Perms:          perms,
+			}
+
+			r, err := Render(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.DidRender || !r.DidStage {
+				t.Errorf("expected stage, got %#v", r)
+			}
+
+			if b, _ := ioutil.ReadFile(path); string(b) != "before" {
+				t.Errorf("expected destination to be unchanged, got %q", b)
+			}
+			if b, _ := ioutil.ReadFile(path + ".pending"); string(b) != "after" {
+				t.Errorf("expected pending contents %q, got %q", "after", b)
+			}
+
+			// Staging the same contents again is a no-op.
+			r, err = Render(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.DidStage {
+				t.Errorf("expected unchanged contents to not be staged again")
+			}
+
+			// Changing back to the current contents removes the stale pending file.
+			in.Contents = []byte("before")
+			if _, err := Render(in); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(path + ".pending"); !os.IsNotExist(err) {
+				t.Errorf("expected pending file to be removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestPromote(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+	path := filepath.Join(outDir, "out")
+
+	ok, err := promote(path, 0644, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Errorf("expected nothing to promote")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path+".pending", []byte("after"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err = promote(path, 0644, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Errorf("expected promotion")
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "after" {
+		t.Errorf("expected %q, got %q", "after", b)
+	}
+	if b, _ := ioutil.ReadFile(path + ".bak"); string(b) != "before" {
+		t.Errorf("expected backup %q, got %q", "before", b)
+	}
+}
@@ -0,0 +1,180 @@
+// +build linux darwin freebsd openbsd solaris netbsd
+
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestAtomicWrite_ignoresUmask(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+
+	file := filepath.Join(outDir, "a", "b", "c")
+	if err := atomicWrite(&RenderInput{
+		CreateDestDirs: true,
+		DirPerms:       0750,
+		DirUID:         -1,
+		DirGID:         -1,
+		Path:           file,
+		Perms:          0640,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{"a", "a/b"} {
+		stat, err := os.Stat(filepath.Join(outDir, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Mode().Perm() != 0750 {
+			t.Errorf("expected %q to have mode 0750, got %s", dir, stat.Mode())
+		}
+	}
+
+	stat, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode().Perm() != 0640 {
+		t.Errorf("expected file to have mode 0640, got %s", stat.Mode())
+	}
+
+}
+
+func TestRender_enforcesPerms(t *testing.T) {
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Render(&RenderInput{
+		Contents: []byte("hello"),
+		Path:     f.Name(),
+		Perms:    0600,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.DidRender {
+		t.Errorf("expected unchanged contents to not render")
+	}
+
+	stat, err := os.Stat(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %s", stat.Mode())
+	}
+}
+
+func TestRender_keepsPermsWhenUnset(t *testing.T) {
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// Like a command which runs chmod on the destination.
+	if err := os.Chmod(f.Name(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, contents := range []string{"hello", "world"} {
+		if _, err := Render(&RenderInput{
+			Contents: []byte(contents),
+			Path:     f.Name(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		stat, err := os.Stat(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Mode().Perm() != 0600 {
+			t.Errorf("%s: expected mode 0600, got %s", contents, stat.Mode())
+		}
+	}
+}
+
+func TestLookupOwner(t *testing.T) {
+	cases := []struct {
+		name  string
+		user  string
+		group string
+		uid   int
+		gid   int
+		err   bool
+	}{
+		{
+			"empty",
+			"",
+			"",
+			-1,
+			-1,
+			false,
+		},
+		{
+			"numeric",
+			"1000",
+			"1001",
+			1000,
+			1001,
+			false,
+		},
+		{
+			"root",
+			"root",
+			"",
+			0,
+			-1,
+			false,
+		},
+		{
+			"unknown_user",
+			"not-a-real-user",
+			"",
+			-1,
+			-1,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			uid, gid, err := lookupOwner(tc.user, tc.group)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if uid != tc.uid || gid != tc.gid {
+				t.Errorf("expected %d:%d to be %d:%d", uid, gid, tc.uid, tc.gid)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+const (
+	// vaultKVScheme is the prefix for destinations which render into a Vault
+	// KV v2 secret instead of onto disk.
+	vaultKVScheme = "vault://"
+
+	// vaultKVMaxCASAttempts is the number of times a check-and-set write is
+	// attempted before giving up.
+	vaultKVMaxCASAttempts = 3
+)
+
+// isVaultKVDestination returns true if the given destination is a Vault KV
+// destination.
+func isVaultKVDestination(s string) bool {
+	return strings.HasPrefix(s, vaultKVScheme)
+}
+
+// parseVaultKVDestination parses a destination of the form
+// vault://secret/data/path#field into the secret path and field. The path must
+// be the KV v2 "data" path of the secret.
+func parseVaultKVDestination(s string) (string, string, error) {
+	if !isVaultKVDestination(s) {
+		return "", "", fmt.Errorf("vault kv destination must begin with %q: %q",
+			vaultKVScheme, s)
+	}
+
+	rest := strings.TrimPrefix(s, vaultKVScheme)
+	idx := strings.LastIndex(rest, "#")
+	if idx == -1 {
+		return "", "", fmt.Errorf("vault kv destination is missing a field: %q", s)
+	}
+
+	path, field := strings.Trim(rest[:idx], "/"), rest[idx+1:]
+	if field == "" {
+		return "", "", fmt.Errorf("vault kv destination is missing a field: %q", s)
+	}
+	if !strings.Contains(path, "/data/") {
+		return "", "", fmt.Errorf("vault kv destination must be a kv v2 data path: %q", s)
+	}
+	return path, field, nil
+}
+
+// renderVaultKV renders the contents into a field of a Vault KV v2 secret.
+// Other fields on the secret are preserved. Writes use check-and-set against
+// the version of the secret that was read.
+func renderVaultKV(i *RenderInput) (*RenderResult, error) {
+	path, field, err := parseVaultKVDestination(i.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.Clients == nil {
+		return nil, fmt.Errorf("vault kv destination %q: missing clients", i.Path)
+	}
+	logical := i.Clients.Vault().Logical()
+
+	for attempt := 1; attempt <= vaultKVMaxCASAttempts; attempt++ {
+		existing, err := logical.Read(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed reading secret")
+		}
+
+		data, version := vaultKVData(existing)
+		if v, ok := data[field].(string); ok && v == string(i.Contents) {
+			return &RenderResult{
+				DidRender:   false,
+				WouldRender: true,
+			}, nil
+		}
+
+		if i.Dry {
+			fmt.Fprintf(i.DryStream, "> %s\n%s", i.Path, i.Contents)
+			return &RenderResult{
+				DidRender:   true,
+				WouldRender: true,
+			}, nil
+		}
+
+		data[field] = string(i.Contents)
+		_, err = logical.Write(path, map[string]interface{}{
+			"data":    data,
+			"options": map[string]interface{}{"cas": version},
+		})
+		if err == nil {
+			return &RenderResult{
+				DidRender:   true,
+				WouldRender: true,
+			}, nil
+		}
+		if !strings.Contains(err.Error(), "check-and-set") {
+			return nil, errors.Wrap(err, "failed writing secret")
+		}
+
+		log.Printf("[DEBUG] (runner) check-and-set failed for %q (attempt %d)",
+			path, attempt)
+	}
+
+	return nil, fmt.Errorf("failed writing secret %q: check-and-set failed after %d attempts",
+		path, vaultKVMaxCASAttempts)
+}
+
+// vaultKVData returns a copy of the data and the current version of the given
+// KV v2 secret. A missing secret has version 0, which tells Vault to only
+// write the secret if it does not exist.
+func vaultKVData(s *vaultapi.Secret) (map[string]interface{}, int64) {
+	data := make(map[string]interface{})
+	if s == nil || s.Data == nil {
+		return data, 0
+	}
+	raw := s.Data
+
+	if d, ok := raw["data"].(map[string]interface{}); ok {
+		for k, v := range d {
+			data[k] = v
+		}
+	}
+
+	var version int64
+	if m, ok := raw["metadata"].(map[string]interface{}); ok {
+		switch v := m["version"].(type) {
+		case json.Number:
+			version, _ = v.Int64()
+		case float64:
+			version = int64(v)
+		case int:
+			version = int64(v)
+		case int64:
+			version = v
+		}
+	}
+	return data, version
+}
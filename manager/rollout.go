@@ -0,0 +1,146 @@
+package manager
+
+import (
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/template"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+const (
+	// rolloutSessionName is the name of the Consul session used to hold a place
+	// in a rollout.
+	rolloutSessionName = "Consul-Template rollout"
+
+	// rolloutRetryInterval is the amount of time to wait before retrying to
+	// join a rollout after an error.
+	rolloutRetryInterval = 5 * time.Second
+)
+
+// rolloutGrant is sent to the runner when this instance has a place in the
+// rollout of a template, and may apply its staged contents. The runner closes
+// doneCh once the contents have been applied.
+type rolloutGrant struct {
+	config *config.TemplateConfig
+	doneCh chan struct{}
+}
+
+// rolloutPrefix returns the KV prefix used to coordinate the rollout of the
+// given template. Each template is rolled out independently.
+func rolloutPrefix(c *config.RolloutConfig, tmpl *template.Template) string {
+	return path.Join(config.StringVal(c.Prefix), tmpl.ID())
+}
+
+// startRollout joins the rollout of the staged contents of the given template,
+// unless this instance is already waiting for a place in it. Any later changes
+// are staged over the same file, so the latest contents are applied.
+func (r *Runner) startRollout(tmpl *template.Template, c *config.TemplateConfig) {
+	dest := config.StringVal(c.Destination)
+	if _, ok := r.rollouts[dest]; ok {
+		return
+	}
+	r.rollouts[dest] = struct{}{}
+
+	go r.rollout(rolloutPrefix(c.Rollout, tmpl), c)
+}
+
+// rollout waits for a place in the rollout, retrying on errors until the
+// runner is stopped.
+func (r *Runner) rollout(prefix string, c *config.TemplateConfig) {
+	for {
+		err := r.rolloutOnce(prefix, c)
+		if err == nil {
+			return
+		}
+
+		log.Printf("[ERR] (runner) rollout of %s failed, retrying in %s: %s",
+			c.Display(), rolloutRetryInterval, err)
+
+		select {
+		case <-time.After(rolloutRetryInterval):
+		case <-r.DoneCh:
+			return
+		}
+	}
+}
+
+// rolloutOnce registers the intent of this instance to apply the change under
+// the prefix, and blocks until at most max_parallel-1 other instances are
+// applying it. It then asks the runner to apply the change, and holds its
+// place for the stagger before letting the next instance proceed.
+func (r *Runner) rolloutOnce(prefix string, c *config.TemplateConfig) error {
+	host, _ := os.Hostname()
+	sem, err := r.clients.Consul().SemaphoreOpts(&consulapi.SemaphoreOptions{
+		Prefix:      prefix,
+		Limit:       config.IntVal(c.Rollout.MaxParallel),
+		Value:       []byte(host),
+		SessionName: rolloutSessionName,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] (runner) waiting for a place in the rollout of %s", c.Display())
+	lockCh, err := sem.Acquire(r.DoneCh)
+	if err != nil {
+		return err
+	}
+	if lockCh == nil {
+		// The runner was stopped.
+		return nil
+	}
+	defer func() {
+		if err := sem.Release(); err != nil {
+			log.Printf("[WARN] (runner) failed to leave the rollout of %s: %s",
+				c.Display(), err)
+		}
+	}()
+
+	doneCh := make(chan struct{})
+	select {
+	case r.rolloutCh <- &rolloutGrant{config: c, doneCh: doneCh}:
+	case <-r.DoneCh:
+		return nil
+	}
+
+	select {
+	case <-doneCh:
+	case <-r.DoneCh:
+		return nil
+	}
+
+	if stagger := config.TimeDurationVal(c.Rollout.Stagger); stagger > 0 {
+		log.Printf("[DEBUG] (runner) holding place in the rollout of %s for %s",
+			c.Display(), stagger)
+		select {
+		case <-time.After(stagger):
+		case <-lockCh:
+		case <-r.DoneCh:
+		}
+	}
+
+	return nil
+}
+
+// applyRollout applies the staged contents of the template granted a place in
+// its rollout.
+func (r *Runner) applyRollout(g *rolloutGrant) error {
+	defer close(g.doneCh)
+
+	delete(r.rollouts, config.StringVal(g.config.Destination))
+
+	log.Printf("[INFO] (runner) applying rollout of %s", g.config.Display())
+	return r.promoteStaged(func(c *config.TemplateConfig) bool {
+		return c == g.config
+	})
+}
+
+// rolloutEnabled returns true if changes to the given template are coordinated
+// with other instances. Rollouts are disabled in once and dry mode.
+func (r *Runner) rolloutEnabled(c *config.TemplateConfig) bool {
+	return !r.once && !r.dry && config.BoolVal(c.Rollout.Enabled)
+}
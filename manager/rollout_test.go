@@ -0,0 +1,22 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/template"
+)
+
+func TestRolloutPrefix(t *testing.T) {
+	tmpl, err := template.NewTemplate(&template.NewTemplateInput{
+		Contents: "hello",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &config.RolloutConfig{Prefix: config.String("consul-template/rollout/")}
+	if act, exp := rolloutPrefix(c, tmpl), "consul-template/rollout/"+tmpl.ID(); act != exp {
+		t.Errorf("expected %q to be %q", act, exp)
+	}
+}
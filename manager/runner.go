@@ -1,3 +1,8 @@
+// Package manager runs Consul Template: the Runner watches the dependencies of
+// the templates, renders them, and runs their commands. NewRunner, the Runner
+// methods, and RenderInput are the entry points for embedding Consul
+// Template. Helpers which are not meant for embedding live in packages under
+// internal/.
 package manager
 
 import (
@@ -6,18 +11,22 @@ import (
 	"io"
 	"log"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/hashicorp/consul-template/child"
 	"github.com/hashicorp/consul-template/config"
 	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/signals"
 	"github.com/hashicorp/consul-template/template"
 	"github.com/hashicorp/consul-template/watch"
 	"github.com/hashicorp/go-multierror"
 	"github.com/mattn/go-shellwords"
 	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
 )
 
 const (
@@ -62,18 +71,96 @@ type Runner struct {
 	// renderEventLock protects access into the renderEvents map
 	renderEventsLock sync.RWMutex
 
+	// history is the history of recent render events, which is written to
+	// the status file if one is configured.
+	history *renderHistory
+
+	// notifier POSTs render events to the notifications webhook. It is nil
+	// unless notifications are enabled.
+	notifier *notifier
+
+	// drift watches the destinations of templates with watch_destination for
+	// external changes. It is nil unless a template enables it.
+	drift *driftWatcher
+
+	// registration keeps this instance registered in Consul for the runners
+	// function. It is nil unless registration is enabled.
+	registration *registration
+
 	// renderedCh is used to signal that a template has been rendered
 	renderedCh chan struct{}
 
+	// approveCh is used to request that any templates staged for manual
+	// approval are promoted.
+	approveCh chan struct{}
+
+	// outputCh is used to request another run after the output of a template
+	// changed, which is used by a template that already ran.
+	outputCh chan struct{}
+
+	// rolloutCh receives templates which were granted a place in their
+	// rollout. rollouts is the set of destinations which are waiting for a
+	// place.
+	rolloutCh chan *rolloutGrant
+	rollouts  map[string]struct{}
+
+	// renderedValues is the value of each dependency of a template when it was
+	// last rendered, keyed by template ID. It is only kept for templates with
+	// change reports enabled. changeReportPath is the path of the last change
+	// report, which is kept until the next one is written, since commands
+	// without a timeout run in the background.
+	renderedValues   map[string]map[string]interface{}
+	changeReportPath string
+
+	// profile records the time spent executing and rendering each template.
+	// It is nil unless profile_render is enabled.
+	profile *renderProfile
+
+	// leases maps the IDs of the leases of Vault secrets received by this
+	// runner to when they expire, so they can be revoked on shutdown. It is
+	// only kept if revoke_on_shutdown is enabled, and is protected by the
+	// dependencies lock.
+	leases map[string]time.Time
+
+	// namedTemplates maps the names of template configurations to their
+	// templates, after maps the IDs of templates to the names of the templates
+	// they must render after, and commandOrder is the position of the
+	// template of each configuration in that order.
+	namedTemplates map[string]*template.Template
+	after          map[string][]string
+	commandOrder   map[*config.TemplateConfig]int
+
+	// renderGroups maps the destinations of templates in render groups to
+	// their group, and groupReady tracks which of those destinations have
+	// rendered contents which may be committed with the rest of the group.
+	renderGroups map[string]*config.RenderGroupConfig
+	groupReady   map[string]bool
+
 	// dependencies is the list of dependencies this runner is watching.
 	dependencies map[string]dep.Dependency
 
+	// orphans are the dependencies which are no longer used by any template,
+	// but are still watched until the dependency_gc grace period has passed.
+	// gcStats are the statistics about reaping them. Both are protected by the
+	// dependencies lock.
+	orphans map[string]*orphan
+	gcStats DependencyGCStats
+
 	// dependenciesLock is a lock around touching the dependencies map.
 	dependenciesLock sync.Mutex
 
 	// watcher is the watcher this runner is using.
 	watcher *watch.Watcher
 
+	// clients is the set of API clients, used by destinations which write to
+	// Consul or Vault instead of to disk.
+	clients *dep.ClientSet
+
+	// reloaded is true if this runner replaced another one after a reload, in
+	// which case the templates are not rendered for the first time since
+	// Consul Template started, and skip_first_command does not apply.
+	reloaded bool
+
 	// brain is the internal storage database of returned dependency data.
 	brain *template.Brain
 
@@ -84,15 +171,31 @@ type Runner struct {
 	// childLock is the internal lock around the child process.
 	childLock sync.RWMutex
 
+	// commands tracks the running template commands, so a shutdown can wait
+	// for them to finish.
+	commands *commandDrain
+
 	// quiescenceMap is the map of templates to their quiescence timers.
 	// quiescenceCh is the channel where templates report returns from quiescence
 	// fires.
 	quiescenceMap map[string]*quiescence
 	quiescenceCh  chan *template.Template
 
+	// holdDownMap is the map of templates to their hold-down state, which is
+	// nil for templates whose changes are not held down. holdDownCh is the
+	// channel where templates report the release of their held changes.
+	holdDownMap map[string]*holdDown
+	holdDownCh  chan *template.Template
+
 	// dedup is the deduplication manager if enabled
 	dedup *DedupManager
 
+	// snapshot persists watch state across restarts if enabled
+	snapshot *snapshotter
+
+	// fakeData is rendered instead of querying the backends if set
+	fakeData *dep.FakeData
+
 	// Env represents a custom set of environment variables to populate the
 	// template and command runtime with. These environment variables will be
 	// available in both the command's environment as well as the template's
@@ -154,9 +257,31 @@ type RenderEvent struct {
 	LastDidRender time.Time
 }
 
+// RunnerOption customizes a Runner created by NewRunner.
+type RunnerOption func(*Runner)
+
+// WithClientSet makes the runner use the given clients instead of creating
+// them from the Consul and Vault configuration, so programs embedding Consul
+// Template can supply instrumented or mocked clients, or custom transports.
+// The clients must be ready before the runner is started.
+func WithClientSet(clients *dep.ClientSet) RunnerOption {
+	return func(r *Runner) {
+		r.clients = clients
+	}
+}
+
+// WithReload marks the runner as replacing a previous runner after the
+// configuration was reloaded, so the commands of templates which only skip
+// their first command after starting are run on the next change.
+func WithReload() RunnerOption {
+	return func(r *Runner) {
+		r.reloaded = true
+	}
+}
+
 // NewRunner accepts a slice of TemplateConfigs and returns a pointer to the new
 // Runner and any error that occurred during creation.
-func NewRunner(config *config.Config, dry, once bool) (*Runner, error) {
+func NewRunner(config *config.Config, dry, once bool, opts ...RunnerOption) (*Runner, error) {
 	log.Printf("[INFO] (runner) creating new runner (dry: %v, once: %v)", dry, once)
 
 	runner := &Runner{
@@ -164,6 +289,9 @@ func NewRunner(config *config.Config, dry, once bool) (*Runner, error) {
 		dry:    dry,
 		once:   once,
 	}
+	for _, opt := range opts {
+		opt(runner)
+	}
 
 	if err := runner.init(); err != nil {
 		return nil, err
@@ -184,6 +312,29 @@ func (r *Runner) Start() {
 		return
 	}
 
+	// Confine the process before anything is rendered
+	if config.BoolVal(r.config.Sandbox.Enabled) {
+		if err := r.sandbox(); err != nil {
+			r.ErrCh <- err
+			return
+		}
+	}
+
+	// Fail fast if the Consul token lacks the permissions templates need. The
+	// backends are not checked when fake data is rendered instead.
+	if r.fakeData == nil {
+		if err := r.checkRequiredPolicies(); err != nil {
+			r.ErrCh <- err
+			return
+		}
+	}
+	if config.BoolVal(r.config.Preflight) && r.fakeData == nil {
+		if err := r.preflight(); err != nil {
+			r.ErrCh <- err
+			return
+		}
+	}
+
 	// Start the de-duplication manager
 	var dedupCh <-chan struct{}
 	if r.dedup != nil {
@@ -194,9 +345,39 @@ func (r *Runner) Start() {
 		dedupCh = r.dedup.UpdateCh()
 	}
 
+	// Register this instance, so other instances can discover it
+	if err := r.registration.start(); err != nil {
+		r.ErrCh <- err
+		return
+	}
+
+	// Start checking the alarm conditions
+	if config.BoolVal(r.config.Alarm.Enabled) {
+		go r.watchAlarms()
+	}
+
+	// Destinations which are changed externally are rendered again
+	driftCh := r.drift.EventCh()
+
 	// Setup the child process exit channel
 	var childExitCh <-chan int
 
+	// Sweep for unused dependencies on an interval, if configured, instead of
+	// after each run
+	var gcCh <-chan time.Time
+	if interval := config.TimeDurationVal(r.config.DependencyGC.Interval); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		gcCh = ticker.C
+	}
+
+	// Resolve as many dependencies as possible before the first render
+	if err := r.resolveFirstPass(); err != nil {
+		log.Printf("[ERR] (runner) watcher reported error: %s", err)
+		r.ErrCh <- r.watcherError(err)
+		return
+	}
+
 	// Fire an initial run to parse all the templates and setup the first-pass
 	// dependencies. This also forces any templates that have no dependencies to
 	// be rendered immediately (since they are already renderable).
@@ -260,6 +441,8 @@ func (r *Runner) Start() {
 						KillSignal:   config.SignalVal(r.config.Exec.KillSignal),
 						KillTimeout:  config.TimeDurationVal(r.config.Exec.KillTimeout),
 						Splay:        config.TimeDurationVal(r.config.Exec.Splay),
+						SplayMin:     config.TimeDurationVal(r.config.Exec.SplayMin),
+						SplaySeed:    splaySeed(config.StringVal(r.config.Exec.SplaySeed)),
 					})
 					if err != nil {
 						r.ErrCh <- err
@@ -302,7 +485,7 @@ func (r *Runner) Start() {
 					}
 				}
 
-				r.Stop()
+				r.Shutdown()
 				return
 			}
 		}
@@ -341,7 +524,7 @@ func (r *Runner) Start() {
 		case err := <-r.watcher.ErrCh():
 			// Push the error back up the stack
 			log.Printf("[ERR] (runner) watcher reported error: %s", err)
-			r.ErrCh <- err
+			r.ErrCh <- r.watcherError(err)
 			return
 
 		case tmpl := <-r.quiescenceCh:
@@ -350,6 +533,45 @@ func (r *Runner) Start() {
 			log.Printf("[DEBUG] (runner) received template %q from quiescence", tmpl.ID())
 			delete(r.quiescenceMap, tmpl.ID())
 
+		case tmpl := <-r.holdDownCh:
+			// Release the held changes of this template, so the upcoming Run
+			// call renders them.
+			log.Printf("[DEBUG] (runner) received template %q from hold-down", tmpl.ID())
+			if h := r.holdDownMap[tmpl.ID()]; h != nil {
+				h.release()
+			}
+
+		case <-r.outputCh:
+			// The output of a template changed, which is used by a template
+			// which was ordered before it.
+			log.Printf("[DEBUG] (runner) template output changed")
+			break OUTER
+
+		case ev := <-driftCh:
+			// Restore the destination right away, bypassing quiescence since
+			// the data did not change.
+			log.Printf("[WARN] (runner) %q was %s externally, rendering it again",
+				ev.dest, ev.reason)
+			delete(r.quiescenceMap, ev.tmpl.ID())
+
+		case <-r.approveCh:
+			if err := r.approve(); err != nil {
+				r.ErrCh <- err
+				return
+			}
+
+		case g := <-r.rolloutCh:
+			if err := r.applyRollout(g); err != nil {
+				r.ErrCh <- err
+				return
+			}
+
+		case now := <-gcCh:
+			r.dependenciesLock.Lock()
+			r.sweepDependencies(now)
+			r.dependenciesLock.Unlock()
+			continue
+
 		case c := <-childExitCh:
 			log.Printf("[INFO] (runner) child process died")
 			r.ErrCh <- NewErrChildDied(c)
@@ -369,8 +591,23 @@ func (r *Runner) Start() {
 	}
 }
 
-// Stop halts the execution of this runner and its subprocesses.
+// Stop halts the execution of this runner and its subprocesses. It is used
+// when the configuration is reloaded, so what outlives a runner, like the
+// registration of this instance or the leases of the rendered Vault secrets,
+// is kept. Use Shutdown when Consul Template exits.
 func (r *Runner) Stop() {
+	r.stop(false)
+}
+
+// Shutdown halts the execution of this runner like Stop, but also deregisters
+// this instance, waits up to the shutdown grace period for the running
+// template commands to finish, and revokes the leases of the Vault secrets it
+// received when revoke_on_shutdown is set.
+func (r *Runner) Shutdown() {
+	r.stop(true)
+}
+
+func (r *Runner) stop(shutdown bool) {
 	r.stopLock.Lock()
 	defer r.stopLock.Unlock()
 
@@ -379,20 +616,47 @@ func (r *Runner) Stop() {
 	}
 
 	log.Printf("[INFO] (runner) stopping")
+	r.registration.stop(shutdown)
 	r.stopDedup()
 	r.stopWatcher()
+	if shutdown {
+		r.drainCommands()
+	}
 	r.stopChild()
+	if shutdown {
+		r.revokeLeases()
+	}
+	r.drift.stop()
+	r.notifier.stop()
 
 	if err := r.deletePid(); err != nil {
 		log.Printf("[WARN] (runner) could not remove pid at %q: %s",
 			r.config.PidFile, err)
 	}
 
+	if r.changeReportPath != "" {
+		os.Remove(r.changeReportPath)
+	}
+
+	if r.profile != nil {
+		fmt.Fprintf(r.errStream, "Render profile:\n")
+		writeRenderProfile(r.errStream, r.RenderProfile())
+	}
+
 	r.stopped = true
 
 	close(r.DoneCh)
 }
 
+// FlushNotifications waits for the queued notifications, such as that of the
+// error the runner reported, to be sent. It is called before exiting on an
+// error, since the runner is not stopped then.
+func (r *Runner) FlushNotifications() {
+	if r.notifier != nil {
+		r.notifier.flush()
+	}
+}
+
 // TemplateRenderedCh returns a channel that will return the path of the
 // template when it is rendered.
 func (r *Runner) TemplateRenderedCh() <-chan struct{} {
@@ -426,6 +690,18 @@ func (r *Runner) stopWatcher() {
 	}
 }
 
+// drainCommands stops new template commands from being started, and waits up
+// to the shutdown grace period for the running ones to finish.
+func (r *Runner) drainCommands() {
+	if r.commands == nil {
+		return
+	}
+
+	if grace := config.TimeDurationVal(r.config.ShutdownGrace); grace > 0 {
+		r.commands.drain(grace)
+	}
+}
+
 func (r *Runner) stopChild() {
 	r.childLock.RLock()
 	defer r.childLock.RUnlock()
@@ -457,9 +733,33 @@ func (r *Runner) Receive(d dep.Dependency, data interface{}) {
 	//     https://github.com/hashicorp/consul-template/issues/198
 	//
 	// and by "little" bug, I mean really big bug.
-	if _, ok := r.dependencies[d.String()]; ok {
+	//
+	// Unused dependencies within their grace period are still received, so
+	// their data is current if they are used again.
+	_, ok := r.dependencies[d.String()]
+	if _, orphaned := r.orphans[d.String()]; ok || orphaned {
 		log.Printf("[DEBUG] (runner) receiving dependency %s", d)
 		r.brain.Remember(d, data)
+		r.trackLeases(data)
+
+		// Record why the templates which use the dependency render again.
+		r.renderEventsLock.RLock()
+		for id, event := range r.renderEvents {
+			if event.UsedDeps != nil && event.UsedDeps.Get(d.String()) != nil {
+				r.history.dependencyChanged(id, d)
+			}
+		}
+		r.renderEventsLock.RUnlock()
+	}
+}
+
+// Approve promotes the contents of any templates which are staged for manual
+// approval, running their commands as if they were just rendered. It does not
+// block; the approval is handled by the running runner.
+func (r *Runner) Approve() {
+	select {
+	case r.approveCh <- struct{}{}:
+	default:
 	}
 }
 
@@ -482,10 +782,17 @@ func (r *Runner) Signal(s os.Signal) error {
 // Please note that all templates are rendered **and then** any commands are
 // executed.
 func (r *Runner) Run() error {
+	// No new renders are started while shutting down.
+	if r.commands != nil && r.commands.isDraining() {
+		log.Printf("[DEBUG] (runner) shutting down, skipping run")
+		return nil
+	}
+
 	log.Printf("[INFO] (runner) initiating run")
 
 	var wouldRenderAny, renderedAny bool
 	var commands []*config.TemplateConfig
+	report := new(changeReport)
 	depsMap := make(map[string]dep.Dependency)
 
 	for _, tmpl := range r.templates {
@@ -505,10 +812,12 @@ func (r *Runner) Run() error {
 			event.LastDidRender = lastEvent.LastDidRender
 		}
 
-		// Check if we are currently the leader instance
-		isLeader := true
+		// Check if we are currently the leader instance. The leader watches the
+		// dependencies, as does a follower which cannot use the shared data.
+		isLeader, watchLocal := true, true
 		if r.dedup != nil {
 			isLeader = r.dedup.IsLeader(tmpl)
+			watchLocal = isLeader || r.dedup.IsLocal(tmpl)
 		}
 
 		// If we are in once mode and this template was already rendered, move
@@ -524,26 +833,57 @@ func (r *Runner) Run() error {
 			}
 		}
 
+		// Templates which must render after others wait until those have
+		// rendered at least once.
+		if waiting := r.waitingFor(tmpl); len(waiting) > 0 {
+			log.Printf("[DEBUG] (runner) %s is waiting for %s to render",
+				tmpl.ID(), strings.Join(waiting, ", "))
+			continue
+		}
+
 		// Attempt to render the template, returning any missing dependencies and
 		// the rendered contents. If there are any missing dependencies, the
 		// contents cannot be rendered or trusted!
+		env, restrict := r.templateEnv()
+		executeStart := time.Now()
 		result, err := tmpl.Execute(&template.ExecuteInput{
-			Brain: r.brain,
-			Env:   r.childEnv(),
+			Brain:       r.brain,
+			Env:         env,
+			RestrictEnv: restrict,
+			Timeout:     r.renderTimeout(tmpl),
 		})
+		r.profile.executed(tmpl, time.Since(executeStart))
 		if err != nil {
-			return errors.Wrap(err, tmpl.Source())
+			r.notifier.notify(r.history.failed(tmpl.ID(), tmpl.Source(), "", err))
+			return NewErrRender(errors.Wrap(err, tmpl.Source()))
 		}
 
 		// Grab the list of used and missing dependencies.
 		missing, used := result.Missing, result.Used
+		if err := r.checkTemplateOutputs(tmpl, used); err != nil {
+			return err
+		}
 
 		// Add the dependency to the list of dependencies for this runner.
 		for _, d := range used.List() {
+			// The output of other templates is supplied by the runner instead
+			// of being watched.
+			if d.Type() == dep.TypeInternal {
+				depsMap[d.String()] = d
+				continue
+			}
+
+			// If the data was restored from a snapshot, start the watcher from
+			// the restored index, but trust the data. The watcher will deliver
+			// new data as soon as it has changed since the snapshot was taken.
+			if r.snapshot != nil && r.snapshot.Restored(d) && !r.watcher.Watching(d) {
+				r.watcher.Add(d)
+			}
+
 			// If we've taken over leadership for a template, we may have data
 			// that is cached, but not have the watcher. We must treat this as
 			// missing so that we create the watcher and re-run the template.
-			if isLeader && !r.watcher.Watching(d) {
+			if watchLocal && !r.watcher.Watching(d) {
 				missing.Add(d)
 			}
 			if _, ok := depsMap[d.String()]; !ok {
@@ -555,7 +895,7 @@ func (r *Runner) Run() error {
 		// the watcher is watching.
 		unwatched := new(dep.Set)
 		for _, d := range missing.List() {
-			if !r.watcher.Watching(d) {
+			if d.Type() != dep.TypeInternal && !r.watcher.Watching(d) {
 				unwatched.Add(d)
 			}
 		}
@@ -564,10 +904,11 @@ func (r *Runner) Run() error {
 		// next one.
 		if l := unwatched.Len(); l > 0 {
 			log.Printf("[DEBUG] (runner) was not watching %d dependencies", l)
+			r.markGroupsUnready(tmpl)
 			for _, d := range unwatched.List() {
 				// If we are deduplicating, we must still handle non-sharable
 				// dependencies, since those will be ignored.
-				if isLeader || !d.CanShare() {
+				if watchLocal || !d.CanShare() {
 					r.watcher.Add(d)
 				}
 			}
@@ -578,6 +919,7 @@ func (r *Runner) Run() error {
 		// ready to render and need to move on to the next one.
 		if l := missing.Len(); l > 0 {
 			log.Printf("[DEBUG] (runner) missing data for %d dependencies", l)
+			r.markGroupsUnready(tmpl)
 			continue
 		}
 
@@ -593,6 +935,22 @@ func (r *Runner) Run() error {
 		event.UnwatchedDeps = unwatched
 		event.UsedDeps = used
 
+		// If the guard of the template does not hold, keep the previous
+		// contents of its destinations until the data changes. In once mode the
+		// data will not change, so that is an error.
+		if result.GuardFailed {
+			for _, templateConfig := range r.templateConfigsFor(tmpl) {
+				log.Printf("[WARN] (runner) guard %q does not hold for %s, keeping "+
+					"the previous contents", config.StringVal(templateConfig.Guard),
+					templateConfig.Display())
+			}
+			if r.once {
+				return NewErrRender(fmt.Errorf("runner: %s: guard does not hold", tmpl.Source()))
+			}
+			r.markGroupsUnready(tmpl)
+			continue
+		}
+
 		// If quiescence is activated, start/update the timers and loop back around.
 		// We do not want to render the templates yet.
 		if q, ok := r.quiescenceMap[tmpl.ID()]; ok {
@@ -600,22 +958,104 @@ func (r *Runner) Run() error {
 			continue
 		}
 
+		// If a change of this template was rendered recently, hold this one
+		// until the hold-down timer fires.
+		holdDown := r.holdDownFor(tmpl)
+		if holdDown != nil && holdDown.hold(result.Output) {
+			log.Printf("[DEBUG] (runner) holding down changes of %q", tmpl.ID())
+			continue
+		}
+
+		// Supply the output to any templates which use it.
+		r.publishOutput(tmpl, result.Output, depsMap)
+
+		// Grab the current dependency values if any configuration wants a report
+		// of what changed.
+		var values map[string]interface{}
+		for _, templateConfig := range r.templateConfigsFor(tmpl) {
+			if config.BoolVal(templateConfig.ChangeReport) {
+				values = dependencyValues(r.brain, used.List())
+				break
+			}
+		}
+
 		// For each template configuration that is tied to this template, attempt to
 		// render it to disk and accumulate commands for later use.
+		output := result.Output
 		for _, templateConfig := range r.templateConfigsFor(tmpl) {
 			log.Printf("[DEBUG] (runner) rendering %s", templateConfig.Display())
 
+			uid, gid, err := lookupOwner(
+				config.StringVal(templateConfig.DestDirUser),
+				config.StringVal(templateConfig.DestDirGroup))
+			if err != nil {
+				return NewErrRender(errors.Wrap(err, "error rendering "+templateConfig.Display()))
+			}
+
+			manual := config.StringVal(templateConfig.Approval) == config.TemplateApprovalManual
+			rollout := r.rolloutEnabled(templateConfig)
+			group := r.renderGroupFor(templateConfig)
+
+			// Destinations which are watched for external changes expect the
+			// contents, unless they are staged.
+			staged := manual || rollout || group != nil
+			if !staged {
+				r.drift.expect(config.StringVal(templateConfig.Destination), output)
+			}
+
 			// Render the template, taking dry mode into account
+			renderStart := time.Now()
 			result, err := Render(&RenderInput{
-				Backup:    config.BoolVal(templateConfig.Backup),
-				Contents:  result.Output,
-				Dry:       r.dry,
-				DryStream: r.outStream,
-				Path:      config.StringVal(templateConfig.Destination),
-				Perms:     config.FileModeVal(templateConfig.Perms),
+				Backup:         config.BoolVal(templateConfig.Backup),
+				Clients:        r.clients,
+				Contents:       result.Output,
+				Diff:           templateConfig.Diff,
+				Encryption:     templateConfig.Encryption,
+				CreateDestDirs: config.BoolVal(templateConfig.CreateDestDirs),
+				DirPerms:       config.FileModeVal(templateConfig.CreateDestDirsPerms),
+				DirUID:         uid,
+				DirGID:         gid,
+				Dry:            r.dry,
+				DryStream:      r.outStream,
+				ExecTimeout:    config.TimeDurationVal(templateConfig.Exec.Timeout),
+				HTTP:           templateConfig.HTTP,
+				Path:           config.StringVal(templateConfig.Destination),
+				Pending:        staged,
+				Perms:          config.FileModeVal(templateConfig.Perms),
+				PreserveXattrs: config.BoolVal(templateConfig.PreserveXattrs),
+				SELinuxLabel:   config.StringVal(templateConfig.SELinuxLabel),
+				WindowsACL:     config.StringVal(templateConfig.WindowsACL),
 			})
+			r.profile.rendered(tmpl, time.Since(renderStart))
+			dest := config.StringVal(templateConfig.Destination)
 			if err != nil {
-				return errors.Wrap(err, "error rendering "+templateConfig.Display())
+				r.notifier.notify(r.history.failed(tmpl.ID(),
+					config.StringVal(templateConfig.Source), dest, err))
+				return NewErrRender(errors.Wrap(err, "error rendering "+templateConfig.Display()))
+			}
+
+			if result.DidStage && manual {
+				log.Printf("[INFO] (runner) staged %s at %q, waiting for approval",
+					templateConfig.Display(), pendingPath(dest))
+			}
+
+			// Grouped templates are committed with the rest of their group.
+			if group != nil {
+				if result.DidStage {
+					log.Printf("[DEBUG] (runner) staged %s at %q for render_group %q",
+						templateConfig.Display(), pendingPath(dest), config.StringVal(group.Name))
+				}
+				r.groupReady[dest] = true
+			}
+
+			// Join the rollout of any staged contents, including those staged
+			// before a restart.
+			if rollout {
+				if _, err := os.Stat(pendingPath(dest)); err == nil {
+					log.Printf("[INFO] (runner) staged %s at %q, waiting for rollout",
+						templateConfig.Display(), pendingPath(dest))
+					r.startRollout(tmpl, templateConfig)
+				}
 			}
 
 			renderTime := time.Now().UTC()
@@ -630,6 +1070,11 @@ func (r *Runner) Run() error {
 				event.WouldRender = true
 				event.LastWouldRender = renderTime
 
+				// The destination now has the rendered contents.
+				if !staged {
+					r.drift.watch(dest)
+				}
+
 				// Record that at least one template would have been rendered.
 				wouldRenderAny = true
 			}
@@ -646,6 +1091,17 @@ func (r *Runner) Run() error {
 				// Record that at least one template was rendered.
 				renderedAny = true
 
+				r.notifier.notify(r.history.rendered(tmpl.ID(),
+					config.StringVal(templateConfig.Source), dest, output))
+
+				if config.BoolVal(templateConfig.ChangeReport) {
+					report.Templates = append(report.Templates, &templateChanges{
+						Source:      config.StringVal(templateConfig.Source),
+						Destination: config.StringVal(templateConfig.Destination),
+						Changes:     diffDependencyValues(r.renderedValues[tmpl.ID()], values),
+					})
+				}
+
 				if !r.dry {
 					// If the template was rendered (changed) and we are not in dry-run mode,
 					// aggregate commands, ignoring previously known commands
@@ -659,7 +1115,10 @@ func (r *Runner) Run() error {
 					// if config.StringPresent(ctemplate.Command)
 					if c := config.StringVal(templateConfig.Exec.Command); c != "" {
 						existing := findCommand(templateConfig, commands)
-						if existing != nil {
+						if lastEvent == nil && !r.reloaded && config.BoolVal(templateConfig.SkipFirstCommand) {
+							log.Printf("[DEBUG] (runner) skipping command %q from %s (first render)",
+								c, templateConfig.Display())
+						} else if existing != nil {
 							log.Printf("[DEBUG] (runner) skipping command %q from %s (already appended from %s)",
 								c, templateConfig.Display(), existing.Display())
 						} else {
@@ -672,6 +1131,18 @@ func (r *Runner) Run() error {
 			}
 		}
 
+		if values != nil && event.DidRender {
+			r.renderedValues[tmpl.ID()] = values
+		}
+
+		if holdDown != nil && event.DidRender {
+			holdDown.rendered(result.Output)
+		}
+
+		if event.DidRender {
+			r.history.renderedAll(tmpl.ID())
+		}
+
 		// Send updated render event
 		r.renderEventsLock.Lock()
 		event.UpdatedAt = time.Now().UTC()
@@ -679,6 +1150,21 @@ func (r *Runner) Run() error {
 		r.renderEventsLock.Unlock()
 	}
 
+	// Commit the render groups whose templates have all rendered.
+	if !r.dry {
+		groupCommands, committed, err := r.commitRenderGroups()
+		if err != nil {
+			return err
+		}
+		for _, c := range groupCommands {
+			if findCommand(c, commands) == nil {
+				commands = append(commands, c)
+			}
+		}
+		renderedAny = renderedAny || committed
+	}
+	r.sortCommands(commands)
+
 	// Check if we need to deliver any rendered signals
 	if wouldRenderAny || renderedAny {
 		// Send the signal that a template got rendered
@@ -691,15 +1177,143 @@ func (r *Runner) Run() error {
 	// Perform the diff and update the known dependencies.
 	r.diffAndUpdateDeps(depsMap)
 
-	// Execute each command in sequence, collecting any errors that occur - this
-	// ensures all commands execute at least once.
+	// Persist the watch state so a restart can resume from here.
+	if r.snapshot != nil {
+		if err := r.snapshot.Write(depsMap, r.brain, r.watcher); err != nil {
+			log.Printf("[WARN] (runner) failed to write snapshot: %s", err)
+		}
+	}
+
+	return r.runCommands(commands, renderedAny, report)
+}
+
+// watcherError returns the error of the watcher to report. In once mode, a
+// backend which cannot be reached is reported distinctly, since the render
+// cannot succeed until it is back.
+func (r *Runner) watcherError(err error) error {
+	if r.once && isUnreachable(err) {
+		return NewErrBackendUnreachable(err)
+	}
+	return err
+}
+
+// approve promotes the contents of any templates staged for manual approval.
+func (r *Runner) approve() error {
+	log.Printf("[INFO] (runner) approving staged templates")
+
+	return r.promoteStaged(func(c *config.TemplateConfig) bool {
+		return config.StringVal(c.Approval) == config.TemplateApprovalManual
+	})
+}
+
+// promoteStaged promotes the staged contents of the templates matching the
+// filter to their destinations, and then runs their commands and reloads the
+// child process, just as Run does for templates which are rendered directly.
+func (r *Runner) promoteStaged(filter func(*config.TemplateConfig) bool) error {
+	var promotedAny bool
+	var commands []*config.TemplateConfig
+
+	for _, tmpl := range r.templates {
+		for _, templateConfig := range r.templateConfigsFor(tmpl) {
+			if !filter(templateConfig) {
+				continue
+			}
+
+			promoted, err := promote(
+				config.StringVal(templateConfig.Destination),
+				config.FileModeVal(templateConfig.Perms),
+				config.BoolVal(templateConfig.Backup),
+				newEncrypter(templateConfig.Encryption, r.clients))
+			if err != nil {
+				return errors.Wrap(err, "error promoting "+templateConfig.Display())
+			}
+			if !promoted {
+				continue
+			}
+
+			log.Printf("[INFO] (runner) promoted %s", templateConfig.Display())
+			promotedAny = true
+			r.markPromoted(tmpl)
+
+			if config.StringPresent(templateConfig.Exec.Command) &&
+				findCommand(templateConfig, commands) == nil {
+				commands = append(commands, templateConfig)
+			}
+		}
+	}
+
+	if !promotedAny {
+		log.Printf("[INFO] (runner) no staged templates to promote")
+		return nil
+	}
+
+	return r.runCommands(commands, true, nil)
+}
+
+// markPromoted updates the last render event of the given template after its
+// staged contents were promoted, since that is when it actually rendered.
+func (r *Runner) markPromoted(tmpl *template.Template) {
+	r.renderEventsLock.Lock()
+	defer r.renderEventsLock.Unlock()
+
+	if last, ok := r.renderEvents[tmpl.ID()]; ok {
+		event := *last
+		event.DidRender = true
+		event.LastDidRender = time.Now().UTC()
+		event.UpdatedAt = event.LastDidRender
+		r.renderEvents[tmpl.ID()] = &event
+	}
+}
+
+// runCommands executes the commands of the given templates in sequence, and
+// then sends the reload signal to the child process, if requested. The change
+// report, if given, is written for the commands of templates which want it.
+// Any errors that occur are collected and returned together - this ensures all
+// commands execute at least once.
+func (r *Runner) runCommands(commands []*config.TemplateConfig, reload bool, report *changeReport) error {
 	var errs []error
+
+	var reportPath string
+	for _, t := range commands {
+		if report == nil || !config.BoolVal(t.ChangeReport) {
+			continue
+		}
+		if r.changeReportPath != "" {
+			os.Remove(r.changeReportPath)
+			r.changeReportPath = ""
+		}
+		path, err := writeChangeReport(report)
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "failed to write change report"))
+			break
+		}
+		reportPath, r.changeReportPath = path, path
+		break
+	}
+
+	// failed holds the names of templates whose commands failed or were
+	// skipped, so the commands of templates which must run after them are
+	// skipped too.
+	failed := make(map[string]struct{})
+
 	for _, t := range commands {
 		command := config.StringVal(t.Exec.Command)
+		if name := failedAfter(t, failed); name != "" {
+			errs = append(errs, fmt.Errorf("skipped command %q from %s because "+
+				"the command of template %q failed", command, t.Display(), name))
+			if n := config.StringVal(t.Name); n != "" {
+				failed[n] = struct{}{}
+			}
+			continue
+		}
+
 		log.Printf("[INFO] (runner) executing command %q from %s", command, t.Display())
 		env := t.Exec.Env.Copy()
 		env.Custom = append(r.childEnv(), env.Custom...)
-		if _, err := spawnChild(&spawnChildInput{
+		if reportPath != "" && config.BoolVal(t.ChangeReport) {
+			env.Custom = append(env.Custom, changeReportEnv+"="+reportPath)
+		}
+		if err := r.runCommand(&spawnChildInput{
 			Stdin:        r.inStream,
 			Stdout:       r.outStream,
 			Stderr:       r.errStream,
@@ -710,15 +1324,21 @@ func (r *Runner) Run() error {
 			KillSignal:   config.SignalVal(t.Exec.KillSignal),
 			KillTimeout:  config.TimeDurationVal(t.Exec.KillTimeout),
 			Splay:        config.TimeDurationVal(t.Exec.Splay),
+			SplayMin:     config.TimeDurationVal(t.Exec.SplayMin),
+			SplaySeed:    splaySeed(config.StringVal(t.Exec.SplaySeed)),
+			Sandbox:      commandSandbox(t.CommandSandbox),
 		}); err != nil {
 			s := fmt.Sprintf("failed to execute command %q from %s", command, t.Display())
 			errs = append(errs, errors.Wrap(err, s))
+			if n := config.StringVal(t.Name); n != "" {
+				failed[n] = struct{}{}
+			}
 		}
 	}
 
 	// If we got this far and have a child process, we need to send the reload
 	// signal to the child process.
-	if renderedAny && r.child != nil {
+	if reload && r.child != nil {
 		r.childLock.RLock()
 		if err := r.child.Reload(); err != nil {
 			errs = append(errs, err)
@@ -733,12 +1353,76 @@ func (r *Runner) Run() error {
 		for _, err := range errs {
 			result = multierror.Append(result, err)
 		}
-		return result.ErrorOrNil()
+		return NewErrCommand(result)
 	}
 
 	return nil
 }
 
+// runCommand runs the command of a template, tracking it so a shutdown can
+// wait for it. A command with a timeout is waited for, and one without keeps
+// running in the background. It is an error if the runner is shutting down.
+func (r *Runner) runCommand(i *spawnChildInput) error {
+	c, err := newChild(i)
+	if err != nil {
+		return err
+	}
+
+	if !r.commands.add(c) {
+		return fmt.Errorf("not started because consul-template is shutting down")
+	}
+
+	if err := c.Start(); err != nil {
+		r.commands.done(c)
+		return errors.Wrap(err, "child")
+	}
+
+	if i.Timeout == 0 {
+		r.commands.started(c, c.ExitCh())
+	} else {
+		r.commands.done(c)
+	}
+	return nil
+}
+
+// validateSignals returns an error if two of the signals handled by Consul
+// Template are the same, since only one of their actions would be taken.
+func validateSignals(c *config.Config) error {
+	handled := []struct {
+		name   string
+		signal *os.Signal
+	}{
+		{"reload_signal", c.ReloadSignal},
+		{"kill_signal", c.KillSignal},
+		{"approve_signal", c.ApproveSignal},
+		{"dump_signal", c.DumpSignal},
+		{"log_level_signal", c.LogLevelSignal},
+	}
+
+	seen := make(map[os.Signal]string, len(handled))
+	for _, h := range handled {
+		if h.signal == nil || *h.signal == nil || *h.signal == signals.SIGNIL {
+			continue
+		}
+		if other, ok := seen[*h.signal]; ok {
+			return fmt.Errorf("runner: %s and %s are both %s", other, h.name, *h.signal)
+		}
+		seen[*h.signal] = h.name
+	}
+	return nil
+}
+
+// validateSplay returns an error if the minimum splay of the exec
+// configuration is greater than the splay. A splay of 0 with a minimum always
+// waits the minimum.
+func validateSplay(c *config.ExecConfig) error {
+	splay, min := config.TimeDurationVal(c.Splay), config.TimeDurationVal(c.SplayMin)
+	if splay != 0 && min > splay {
+		return fmt.Errorf("splay_min (%s) is greater than splay (%s)", min, splay)
+	}
+	return nil
+}
+
 // init() creates the Runner's underlying data structures and returns an error
 // if any problems occur.
 func (r *Runner) init() error {
@@ -753,19 +1437,63 @@ func (r *Runner) init() error {
 	}
 	log.Printf("[DEBUG] (runner) final config: %s", result)
 
-	// Create the clientset
-	clients, err := newClientSet(r.config)
-	if err != nil {
-		return fmt.Errorf("runner: %s", err)
+	if err := validateSignals(r.config); err != nil {
+		return err
+	}
+
+	if err := validateSplay(r.config.Exec); err != nil {
+		return fmt.Errorf("runner: exec: %s", err)
+	}
+
+	// Fake data is only rendered once to stdout, since it never changes and is
+	// not meant to be committed to disk.
+	if path := config.StringVal(r.config.FakeData); path != "" {
+		if !r.dry {
+			return fmt.Errorf("runner: fake data is only allowed in dry mode")
+		}
+		fakeData, err := dep.LoadFakeData(path)
+		if err != nil {
+			return fmt.Errorf("runner: %s", err)
+		}
+		r.fakeData = fakeData
+		r.once = true
+	}
+
+	// Create the clientset, unless one was given
+	if r.clients == nil {
+		clients, err := newClientSet(r.config)
+		if err != nil {
+			return fmt.Errorf("runner: %s", err)
+		}
+		r.clients = clients
+	}
+	clients := r.clients
+
+	r.brain = template.NewBrain()
+
+	// Restore the last known watch state, if a snapshot is configured
+	var lastIndexes map[string]uint64
+	if *r.config.Snapshot.Enabled {
+		if r.once {
+			log.Printf("[INFO] (runner) disabling snapshots in once mode")
+		} else {
+			if err := validateEncryption(r.config.Snapshot.Encryption); err != nil {
+				return fmt.Errorf("runner: snapshot: %s", err)
+			}
+			r.snapshot = newSnapshotter(*r.config.Snapshot.Path,
+				newEncrypter(r.config.Snapshot.Encryption, clients))
+			lastIndexes = r.snapshot.Restore(r.brain)
+		}
 	}
 
 	// Create the watcher
-	watcher, err := newWatcher(r.config, clients, r.once)
+	watcher, err := newWatcher(r.config, clients, r.once, lastIndexes, r.fakeData)
 	if err != nil {
 		return fmt.Errorf("runner: %s", err)
 	}
 	r.watcher = watcher
 
+	var manualApproval bool
 	numTemplates := len(*r.config.Templates)
 	templates := make([]*template.Template, 0, numTemplates)
 	ctemplatesMap := make(map[string]config.TemplateConfigs)
@@ -780,11 +1508,113 @@ func (r *Runner) init() error {
 			Contents:   config.StringVal(ctmpl.Contents),
 			LeftDelim:  config.StringVal(ctmpl.LeftDelim),
 			RightDelim: config.StringVal(ctmpl.RightDelim),
+			Engine:     config.StringVal(ctmpl.Engine),
+			Guard:      config.StringVal(ctmpl.Guard),
+			VarsFile:   config.StringVal(ctmpl.VarsFile),
 		})
 		if err != nil {
 			return err
 		}
 
+		if _, _, err := lookupOwner(config.StringVal(ctmpl.DestDirUser),
+			config.StringVal(ctmpl.DestDirGroup)); err != nil {
+			return fmt.Errorf("runner: %s: %s", ctmpl.Display(), err)
+		}
+
+		switch approval := config.StringVal(ctmpl.Approval); approval {
+		case config.TemplateApprovalAuto:
+		case config.TemplateApprovalManual:
+			dest := config.StringVal(ctmpl.Destination)
+			if !isFileDestination(dest) {
+				return fmt.Errorf("runner: %s: manual approval is only supported "+
+					"for file destinations", ctmpl.Display())
+			}
+			if config.BoolVal(ctmpl.Rollout.Enabled) {
+				return fmt.Errorf("runner: %s: manual approval cannot be "+
+					"combined with rollout", ctmpl.Display())
+			}
+			manualApproval = true
+		default:
+			return fmt.Errorf("runner: %s: invalid approval %q - valid values "+
+				"are %q and %q", ctmpl.Display(), approval,
+				config.TemplateApprovalAuto, config.TemplateApprovalManual)
+		}
+
+		if config.BoolVal(ctmpl.Rollout.Enabled) {
+			dest := config.StringVal(ctmpl.Destination)
+			if !isFileDestination(dest) {
+				return fmt.Errorf("runner: %s: rollout is only supported for file "+
+					"destinations", ctmpl.Display())
+			}
+			if config.IntVal(ctmpl.Rollout.MaxParallel) < 1 {
+				return fmt.Errorf("runner: %s: rollout max_parallel must be at "+
+					"least 1", ctmpl.Display())
+			}
+			if r.once {
+				log.Printf("[INFO] (runner) disabling rollout in once mode for %s",
+					ctmpl.Display())
+			}
+		}
+
+		if config.BoolVal(ctmpl.Diff.Enabled) {
+			dest := config.StringVal(ctmpl.Destination)
+			if !isFileDestination(dest) {
+				return fmt.Errorf("runner: %s: diff is only supported for file "+
+					"destinations", ctmpl.Display())
+			}
+			if config.StringVal(ctmpl.Diff.Dir) == "" {
+				return fmt.Errorf("runner: %s: diff requires a dir", ctmpl.Display())
+			}
+			switch format := config.StringVal(ctmpl.Diff.Format); format {
+			case config.DiffFormatUnified, config.DiffFormatJSONPatch:
+			default:
+				return fmt.Errorf("runner: %s: invalid diff format %q - valid "+
+					"values are %q and %q", ctmpl.Display(), format,
+					config.DiffFormatUnified, config.DiffFormatJSONPatch)
+			}
+		}
+
+		if config.BoolVal(ctmpl.CommandSandbox.Enabled) {
+			if runtime.GOOS != "linux" {
+				return fmt.Errorf("runner: %s: command_sandbox is only supported "+
+					"on Linux", ctmpl.Display())
+			}
+			if len(ctmpl.CommandSandbox.ReadOnlyPaths) > 0 &&
+				config.BoolVal(r.config.Sandbox.Enabled) {
+				return fmt.Errorf("runner: %s: command_sandbox read_only_paths "+
+					"cannot be combined with sandbox, which forbids mounting",
+					ctmpl.Display())
+			}
+		}
+
+		if dest := config.StringVal(ctmpl.Destination); isExecDestination(dest) {
+			if _, err := parseExecDestination(dest); err != nil {
+				return fmt.Errorf("runner: %s: %s", ctmpl.Display(), err)
+			}
+		}
+
+		if err := validateEncryption(ctmpl.Encryption); err != nil {
+			return fmt.Errorf("runner: %s: %s", ctmpl.Display(), err)
+		}
+
+		if err := validateSplay(ctmpl.Exec); err != nil {
+			return fmt.Errorf("runner: %s: %s", ctmpl.Display(), err)
+		}
+		if config.BoolVal(ctmpl.Encryption.Enabled) && !config.BoolVal(ctmpl.Backup) {
+			log.Printf("[WARN] (runner) encryption has no effect without backup "+
+				"for %s", ctmpl.Display())
+		}
+
+		if config.StringVal(ctmpl.WindowsACL) != "" && runtime.GOOS != "windows" {
+			log.Printf("[WARN] (runner) windows_acl is ignored on %s for %s",
+				runtime.GOOS, ctmpl.Display())
+		}
+
+		if config.StringVal(ctmpl.SELinuxLabel) != "" && runtime.GOOS != "linux" {
+			log.Printf("[WARN] (runner) selinux_label is ignored on %s for %s",
+				runtime.GOOS, ctmpl.Display())
+		}
+
 		if _, ok := ctemplatesMap[tmpl.ID()]; !ok {
 			templates = append(templates, tmpl)
 		}
@@ -800,15 +1630,57 @@ func (r *Runner) init() error {
 	r.templates = templates
 
 	r.renderEvents = make(map[string]*RenderEvent, numTemplates)
+
+	// The status file is not written in dry mode, which changes nothing.
+	var statusPath string
+	if config.BoolVal(r.config.Status.Enabled) && !r.dry {
+		statusPath = config.StringVal(r.config.Status.Path)
+	}
+	r.history = newRenderHistory(statusPath, config.IntVal(r.config.Status.History))
+	r.commands = newCommandDrain()
 	r.dependencies = make(map[string]dep.Dependency)
+	r.orphans = make(map[string]*orphan)
 
 	r.renderedCh = make(chan struct{}, 1)
+	r.approveCh = make(chan struct{}, 1)
+	r.outputCh = make(chan struct{}, 1)
+
+	if config.BoolVal(r.config.ProfileRender) {
+		r.profile = newRenderProfile()
+	}
+	r.rolloutCh = make(chan *rolloutGrant)
+	r.rollouts = make(map[string]struct{})
+	r.renderedValues = make(map[string]map[string]interface{})
+	r.leases = make(map[string]time.Time)
+
+	if manualApproval {
+		if config.SignalPresent(r.config.ApproveSignal) {
+			log.Printf("[INFO] (runner) changed templates which require manual "+
+				"approval are staged until %s is received",
+				config.SignalVal(r.config.ApproveSignal))
+		} else {
+			log.Printf("[WARN] (runner) templates require manual approval, but " +
+				"no approve_signal is configured")
+		}
+	}
 
 	r.ctemplatesMap = ctemplatesMap
+
+	if err := r.orderTemplates(); err != nil {
+		return err
+	}
+
+	if err := r.checkTemplateOutputCycles(); err != nil {
+		return err
+	}
+
+	if err := r.initRenderGroups(); err != nil {
+		return err
+	}
+
 	r.inStream = os.Stdin
 	r.outStream = os.Stdout
 	r.errStream = os.Stderr
-	r.brain = template.NewBrain()
 
 	r.ErrCh = make(chan error)
 	r.DoneCh = make(chan struct{})
@@ -816,6 +1688,9 @@ func (r *Runner) init() error {
 	r.quiescenceMap = make(map[string]*quiescence)
 	r.quiescenceCh = make(chan *template.Template)
 
+	r.holdDownMap = make(map[string]*holdDown)
+	r.holdDownCh = make(chan *template.Template)
+
 	if *r.config.Dedup.Enabled {
 		if r.once {
 			log.Printf("[INFO] (runner) disabling de-duplication in once mode")
@@ -827,32 +1702,77 @@ func (r *Runner) init() error {
 		}
 	}
 
+	if config.BoolVal(r.config.Alarm.Enabled) {
+		if config.TimeDurationVal(r.config.Alarm.RenderStaleness) <= 0 &&
+			config.IntVal(r.config.Alarm.ErrorThreshold) <= 0 {
+			log.Printf("[WARN] (runner) alarm is enabled, but neither " +
+				"render_staleness nor error_threshold is configured")
+		}
+		if config.IntVal(r.config.Alarm.ErrorThreshold) > 0 &&
+			config.TimeDurationVal(r.config.Alarm.ErrorWindow) <= 0 {
+			return fmt.Errorf("runner: alarm error_window must be positive")
+		}
+	}
+
+	// Only long-running instances register themselves, since instances in dry
+	// or once mode do not keep templates up to date.
+	if config.BoolVal(r.config.Registration.Enabled) && !r.dry && !r.once {
+		r.registration, err = newRegistration(r.config.Registration, clients.Consul().Agent())
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := r.initDrift(); err != nil {
+		return err
+	}
+
+	// The notifier starts sending in the background, so it is created last.
+	// Nothing changes in dry mode, so there is nothing to notify.
+	if config.BoolVal(r.config.Notifications.Enabled) && !r.dry {
+		if r.notifier, err = newNotifier(r.config.Notifications); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // diffAndUpdateDeps iterates through the current map of dependencies on this
-// runner and stops the watcher for any deps that are no longer required.
+// runner and marks any deps that are no longer required as orphans, whose
+// watchers are stopped by sweepDependencies. Unless sweeps are scheduled by
+// the dependency_gc interval, a sweep is done immediately.
 //
-// At the end of this function, the given depsMap is converted to a slice and
-// stored on the runner.
+// At the end of this function, the given depsMap is stored on the runner.
 func (r *Runner) diffAndUpdateDeps(depsMap map[string]dep.Dependency) {
 	r.dependenciesLock.Lock()
 	defer r.dependenciesLock.Unlock()
 
-	// Diff and up the list of dependencies, stopping any unneeded watchers.
+	// Diff and up the list of dependencies, marking any unneeded watchers.
 	log.Printf("[DEBUG] (runner) diffing and updating dependencies")
 
+	now := time.Now()
 	for key, d := range r.dependencies {
 		if _, ok := depsMap[key]; !ok {
 			log.Printf("[DEBUG] (runner) %s is no longer needed", d)
-			r.watcher.Remove(d)
-			r.brain.Forget(d)
+			r.orphans[key] = &orphan{d: d, since: now}
 		} else {
 			log.Printf("[DEBUG] (runner) %s is still needed", d)
 		}
 	}
 
+	for key := range depsMap {
+		if _, ok := r.orphans[key]; ok {
+			log.Printf("[DEBUG] (runner) %s is needed again", key)
+			delete(r.orphans, key)
+		}
+	}
+
 	r.dependencies = depsMap
+
+	if config.TimeDurationVal(r.config.DependencyGC.Interval) <= 0 {
+		r.sweepDependencies(now)
+	}
 }
 
 // TemplateConfigFor returns the TemplateConfig for the given Template
@@ -860,6 +1780,23 @@ func (r *Runner) templateConfigsFor(tmpl *template.Template) []*config.TemplateC
 	return r.ctemplatesMap[tmpl.ID()]
 }
 
+// renderTimeout returns the maximum amount of time the execution of the given
+// template may take. When several template configurations share the template,
+// the shortest of their timeouts applies. Templates which do not set a timeout
+// use the global one.
+func (r *Runner) renderTimeout(tmpl *template.Template) time.Duration {
+	var timeout time.Duration
+	for _, c := range r.templateConfigsFor(tmpl) {
+		if t := config.TimeDurationVal(c.RenderTimeout); t > 0 && (timeout == 0 || t < timeout) {
+			timeout = t
+		}
+	}
+	if timeout == 0 {
+		timeout = config.TimeDurationVal(r.config.RenderTimeout)
+	}
+	return timeout
+}
+
 // TemplateConfigMapping returns a mapping between the template ID and the set
 // of TemplateConfig represented by the template ID
 func (r *Runner) TemplateConfigMapping() map[string][]config.TemplateConfig {
@@ -947,6 +1884,30 @@ func (r *Runner) childEnv() []string {
 	return e
 }
 
+// templateEnv returns the environment for the env template function, and
+// whether the function is restricted to it by the template_env configuration.
+// Custom variables take precedence over the variables for Consul and Vault,
+// which take precedence over the environment of the process.
+func (r *Runner) templateEnv() ([]string, bool) {
+	c := r.config.TemplateEnv
+
+	env := append([]string{}, c.Custom...)
+	if !c.Restricted() {
+		return append(env, r.childEnv()...), false
+	}
+
+	list := r.childEnv()
+	if !config.BoolVal(c.Pristine) {
+		list = append(list, os.Environ()...)
+	}
+	for _, kv := range list {
+		if c.Allowed(strings.SplitN(kv, "=", 2)[0]) {
+			env = append(env, kv)
+		}
+	}
+	return env, true
+}
+
 // storePid is used to write out a PID file to disk.
 func (r *Runner) storePid() error {
 	path := config.StringVal(r.config.PidFile)
@@ -1006,11 +1967,43 @@ type spawnChildInput struct {
 	KillSignal   os.Signal
 	KillTimeout  time.Duration
 	Splay        time.Duration
+	SplayMin     time.Duration
+	SplaySeed    string
+	Sandbox      *child.Sandbox
+}
+
+// splaySeed returns the seed to use for a deterministic splay from the
+// configured value, resolving the special value "hostname".
+func splaySeed(s string) string {
+	if s != "hostname" {
+		return s
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		log.Printf("[WARN] (runner) failed to get hostname for splay seed, "+
+			"using a random splay: %s", err)
+		return ""
+	}
+	return host
 }
 
 // spawnChild spawns a child process with the given inputs and returns the
 // resulting child.
 func spawnChild(i *spawnChildInput) (*child.Child, error) {
+	child, err := newChild(i)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := child.Start(); err != nil {
+		return nil, errors.Wrap(err, "child")
+	}
+	return child, nil
+}
+
+// newChild creates a child process with the given inputs without starting it.
+func newChild(i *spawnChildInput) (*child.Child, error) {
 	p := shellwords.NewParser()
 	p.ParseEnv = true
 	p.ParseBacktick = true
@@ -1031,14 +2024,13 @@ func spawnChild(i *spawnChildInput) (*child.Child, error) {
 		KillSignal:   i.KillSignal,
 		KillTimeout:  i.KillTimeout,
 		Splay:        i.Splay,
+		SplayMin:     i.SplayMin,
+		SplaySeed:    i.SplaySeed,
+		Sandbox:      i.Sandbox,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating child")
 	}
-
-	if err := child.Start(); err != nil {
-		return nil, errors.Wrap(err, "child")
-	}
 	return child, nil
 }
 
@@ -1110,9 +2102,32 @@ func findCommand(c *config.TemplateConfig, templates []*config.TemplateConfig) *
 func newClientSet(c *config.Config) (*dep.ClientSet, error) {
 	clients := dep.NewClientSet()
 
+	var tokenSource oauth2.TokenSource
+	if config.BoolVal(c.Consul.OAuth2.Enabled) {
+		ts, err := dep.NewClientCredentialsTokenSource(&dep.ClientCredentialsInput{
+			ClientID:     config.StringVal(c.Consul.OAuth2.ClientID),
+			ClientSecret: config.StringVal(c.Consul.OAuth2.ClientSecret),
+			Scopes:       c.Consul.OAuth2.Scopes,
+			TokenURL:     config.StringVal(c.Consul.OAuth2.TokenURL),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("runner: %s", err)
+		}
+		tokenSource = ts
+	}
+
+	// The default token of the tokens takes precedence over the token.
+	token := config.StringVal(c.Consul.Token)
+	if config.StringPresent(c.Consul.Tokens.Default) {
+		token = config.StringVal(c.Consul.Tokens.Default)
+	}
+
 	if err := clients.CreateConsulClient(&dep.CreateConsulClientInput{
 		Address:                      config.StringVal(c.Consul.Address),
-		Token:                        config.StringVal(c.Consul.Token),
+		Token:                        token,
+		AgentToken:                   config.StringVal(c.Consul.Tokens.Agent),
+		ReplicationToken:             config.StringVal(c.Consul.Tokens.Replication),
+		FallbackTokens:               c.Consul.FallbackTokens,
 		AuthEnabled:                  config.BoolVal(c.Consul.Auth.Enabled),
 		AuthUsername:                 config.StringVal(c.Consul.Auth.Username),
 		AuthPassword:                 config.StringVal(c.Consul.Auth.Password),
@@ -1123,6 +2138,11 @@ func newClientSet(c *config.Config) (*dep.ClientSet, error) {
 		SSLCACert:                    config.StringVal(c.Consul.SSL.CaCert),
 		SSLCAPath:                    config.StringVal(c.Consul.SSL.CaPath),
 		ServerName:                   config.StringVal(c.Consul.SSL.ServerName),
+		TLSMinVersion:                config.StringVal(c.Consul.SSL.TLSMinVersion),
+		TLSCipherSuites:              c.Consul.SSL.TLSCipherSuites,
+		Headers:                      c.Consul.Headers,
+		TokenSource:                  tokenSource,
+		MaxRetryAfter:                config.TimeDurationVal(c.Consul.Retry.MaxBackoff),
 		TransportDialKeepAlive:       config.TimeDurationVal(c.Consul.Transport.DialKeepAlive),
 		TransportDialTimeout:         config.TimeDurationVal(c.Consul.Transport.DialTimeout),
 		TransportDisableKeepAlives:   config.BoolVal(c.Consul.Transport.DisableKeepAlives),
@@ -1134,8 +2154,15 @@ func newClientSet(c *config.Config) (*dep.ClientSet, error) {
 		return nil, fmt.Errorf("runner: %s", err)
 	}
 
+	var sharedBackoff time.Duration
+	if config.BoolVal(c.Vault.SharedBackoff) {
+		sharedBackoff = config.TimeDurationVal(c.Vault.Retry.Backoff)
+	}
+
 	if err := clients.CreateVaultClient(&dep.CreateVaultClientInput{
 		Address:                      config.StringVal(c.Vault.Address),
+		Addresses:                    c.Vault.Addresses,
+		HealthCheck:                  config.BoolVal(c.Vault.Enabled) && config.BoolVal(c.Vault.HealthCheck),
 		Token:                        config.StringVal(c.Vault.Token),
 		UnwrapToken:                  config.BoolVal(c.Vault.UnwrapToken),
 		SSLEnabled:                   config.BoolVal(c.Vault.SSL.Enabled),
@@ -1145,6 +2172,15 @@ func newClientSet(c *config.Config) (*dep.ClientSet, error) {
 		SSLCACert:                    config.StringVal(c.Vault.SSL.CaCert),
 		SSLCAPath:                    config.StringVal(c.Vault.SSL.CaPath),
 		ServerName:                   config.StringVal(c.Vault.SSL.ServerName),
+		TLSMinVersion:                config.StringVal(c.Vault.SSL.TLSMinVersion),
+		TLSCipherSuites:              c.Vault.SSL.TLSCipherSuites,
+		Headers:                      c.Vault.Headers,
+		MaxConcurrentRequests:        config.IntVal(c.Vault.MaxConcurrentRequests),
+		SharedBackoff:                sharedBackoff,
+		MaxRetryAfter:                config.TimeDurationVal(c.Vault.Retry.MaxBackoff),
+		BatchReads:                   config.BoolVal(c.Vault.BatchReads),
+		RenewJitter:                  config.Float64Val(c.Vault.RenewJitter),
+		KVMountCacheTTL:              config.TimeDurationVal(c.Vault.KVMountCacheTTL),
 		TransportDialKeepAlive:       config.TimeDurationVal(c.Vault.Transport.DialKeepAlive),
 		TransportDialTimeout:         config.TimeDurationVal(c.Vault.Transport.DialTimeout),
 		TransportDisableKeepAlives:   config.BoolVal(c.Vault.Transport.DisableKeepAlives),
@@ -1160,22 +2196,38 @@ func newClientSet(c *config.Config) (*dep.ClientSet, error) {
 }
 
 // newWatcher creates a new watcher.
-func newWatcher(c *config.Config, clients *dep.ClientSet, once bool) (*watch.Watcher, error) {
+func newWatcher(c *config.Config, clients *dep.ClientSet, once bool, lastIndexes map[string]uint64, fakeData *dep.FakeData) (*watch.Watcher, error) {
 	log.Printf("[INFO] (runner) creating watcher")
 
 	w, err := watch.NewWatcher(&watch.NewWatcherInput{
 		Clients:         clients,
 		MaxStale:        config.TimeDurationVal(c.MaxStale),
 		Once:            once,
-		RenewVault:      config.StringPresent(c.Vault.Token) && config.BoolVal(c.Vault.RenewToken),
+		LastIndexes:     lastIndexes,
+		RenewVault:      fakeData == nil && config.StringPresent(c.Vault.Token) && config.BoolVal(c.Vault.RenewToken),
+		FakeData:        fakeData,
 		RetryFuncConsul: watch.RetryFunc(c.Consul.Retry.RetryFunc()),
 		// TODO: Add a sane default retry - right now this only affects "local"
 		// dependencies like reading a file from disk.
 		RetryFuncDefault: nil,
 		RetryFuncVault:   watch.RetryFunc(c.Vault.Retry.RetryFunc()),
+		HoldDownConsul:   watchHoldDown(c.Consul.HoldDown),
+		HoldDownVault:    watchHoldDown(c.Vault.HoldDown),
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "runner")
 	}
 	return w, nil
 }
+
+// watchHoldDown returns the hold-down of views for the given configuration,
+// or nil if it is disabled.
+func watchHoldDown(c *config.WaitConfig) *watch.HoldDown {
+	if c == nil || !config.BoolVal(c.Enabled) {
+		return nil
+	}
+	return &watch.HoldDown{
+		Min: config.TimeDurationVal(c.Min),
+		Max: config.TimeDurationVal(c.Max),
+	}
+}
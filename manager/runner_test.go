@@ -4,14 +4,21 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/consul-template/config"
 	dep "github.com/hashicorp/consul-template/dependency"
 	"github.com/hashicorp/consul-template/template"
+	consulapi "github.com/hashicorp/consul/api"
 )
 
 func TestRunner_Receive(t *testing.T) {
@@ -323,6 +330,99 @@ func TestRunner_Run(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"change_report",
+			func(t *testing.T, r *Runner) {
+				r.dry = false
+			},
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						ChangeReport: config.Bool(true),
+						Contents:     config.String("hello"),
+						Command:      config.String("env"),
+						Destination:  config.String("/tmp/ct-change_report_a"),
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				defer os.Remove("/tmp/ct-change_report_a")
+
+				var path string
+				for _, l := range strings.Split(out, "\n") {
+					if strings.HasPrefix(l, "CT_CHANGE_REPORT=") {
+						path = strings.TrimPrefix(l, "CT_CHANGE_REPORT=")
+					}
+				}
+				if path == "" {
+					t.Fatalf("expected CT_CHANGE_REPORT in %q", out)
+				}
+
+				b, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				exp := `"destination": "/tmp/ct-change_report_a"`
+				if !strings.Contains(string(b), exp) {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, string(b))
+				}
+			},
+			false,
+		},
+		{
+			"skip_first_command",
+			func(t *testing.T, r *Runner) {
+				r.dry = false
+			},
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:         config.String("hello"),
+						Command:          config.String("echo ran-command"),
+						Destination:      config.String("/tmp/ct-skip_first_command_a"),
+						SkipFirstCommand: config.Bool(true),
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				if strings.Contains(out, "ran-command") {
+					t.Errorf("expected command to be skipped: %q", out)
+				}
+				b, err := ioutil.ReadFile("/tmp/ct-skip_first_command_a")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != "hello" {
+					t.Errorf("expected %q to be %q", b, "hello")
+				}
+				os.Remove("/tmp/ct-skip_first_command_a")
+			},
+			false,
+		},
+		{
+			"skip_first_command_reload",
+			func(t *testing.T, r *Runner) {
+				r.dry = false
+				WithReload()(r)
+			},
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:         config.String("hello"),
+						Command:          config.String("echo ran-command"),
+						Destination:      config.String("/tmp/ct-skip_first_command_reload_a"),
+						SkipFirstCommand: config.Bool(true),
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				if !strings.Contains(out, "ran-command") {
+					t.Errorf("expected command to run after a reload: %q", out)
+				}
+				os.Remove("/tmp/ct-skip_first_command_reload_a")
+			},
+			false,
+		},
 	}
 
 	for i, tc := range cases {
@@ -656,6 +756,118 @@ func TestRunner_Start(t *testing.T) {
 	})
 }
 
+func TestRunner_signals(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		c    *config.Config
+		err  string
+	}{
+		{
+			"defaults",
+			&config.Config{},
+			"",
+		},
+		{
+			"kill_reload",
+			&config.Config{
+				KillSignal: config.Signal(syscall.SIGHUP),
+			},
+			"reload_signal and kill_signal are both hangup",
+		},
+		{
+			"dump_kill",
+			&config.Config{
+				DumpSignal: config.Signal(syscall.SIGINT),
+			},
+			"kill_signal and dump_signal are both interrupt",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(tc.c)
+			c.Finalize()
+
+			_, err := NewRunner(c, true, true)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestRunner_splay(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		c    *config.Config
+		err  string
+	}{
+		{
+			"min_only",
+			&config.Config{
+				Exec: &config.ExecConfig{
+					SplayMin: config.TimeDuration(5 * time.Second),
+				},
+			},
+			"",
+		},
+		{
+			"min_above_splay",
+			&config.Config{
+				Exec: &config.ExecConfig{
+					Splay:    config.TimeDuration(1 * time.Second),
+					SplayMin: config.TimeDuration(5 * time.Second),
+				},
+			},
+			"runner: exec: splay_min (5s) is greater than splay (1s)",
+		},
+		{
+			"template_min_above_splay",
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents: config.String("hello"),
+						Exec: &config.ExecConfig{
+							Command:  config.String("echo"),
+							Splay:    config.TimeDuration(1 * time.Second),
+							SplayMin: config.TimeDuration(5 * time.Second),
+						},
+					},
+				},
+			},
+			"splay_min (5s) is greater than splay (1s)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(tc.c)
+			c.Finalize()
+
+			_, err := NewRunner(c, true, true)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
 func TestRunner_quiescence(t *testing.T) {
 	t.Parallel()
 
@@ -746,3 +958,493 @@ func TestRunner_quiescence(t *testing.T) {
 		}
 	})
 }
+
+func TestRunner_approve(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "out")
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Approval:    config.String(config.TemplateApprovalManual),
+				Contents:    config.String("hello"),
+				Destination: config.String(dest),
+			},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("expected %q to not be rendered before approval: %v", dest, err)
+	}
+	b, err := ioutil.ReadFile(dest + ".pending")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Errorf("expected %q to be %q", b, "hello")
+	}
+
+	if err := r.approve(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Errorf("expected %q to be %q", b, "hello")
+	}
+	if _, err := os.Stat(dest + ".pending"); !os.IsNotExist(err) {
+		t.Errorf("expected pending file to be removed: %v", err)
+	}
+	if ev := r.RenderEvents()[r.templates[0].ID()]; ev == nil || !ev.DidRender {
+		t.Errorf("expected render event to be marked rendered: %#v", ev)
+	}
+}
+
+func TestRunner_guard(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name  string
+		guard string
+		once  bool
+		exp   string
+		err   bool
+	}{
+		{
+			"holds",
+			`eq (len "hello") 5`,
+			false,
+			"hello",
+			false,
+		},
+		{
+			"fails",
+			`gt (len "") 0`,
+			false,
+			"before",
+			false,
+		},
+		{
+			"fails_once",
+			"false",
+			true,
+			"before",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			dest := filepath.Join(dir, tc.name)
+			if err := ioutil.WriteFile(dest, []byte("before"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			c := config.DefaultConfig().Merge(&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("hello"),
+						Destination: config.String(dest),
+						Guard:       config.String(tc.guard),
+					},
+				},
+			})
+			c.Finalize()
+
+			r, err := NewRunner(c, false, tc.once)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := r.Run(); (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			b, err := ioutil.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.exp {
+				t.Errorf("expected %q to be %q", b, tc.exp)
+			}
+		})
+	}
+}
+
+func TestRunner_stagingInvalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		approval string
+		dest     string
+		rollout  *config.RolloutConfig
+		diff     *config.DiffConfig
+	}{
+		{
+			"unknown",
+			"sometimes",
+			"/tmp/out",
+			nil,
+			nil,
+		},
+		{
+			"consul_kv",
+			config.TemplateApprovalManual,
+			"consul://kv/foo",
+			nil,
+			nil,
+		},
+		{
+			"http",
+			config.TemplateApprovalManual,
+			"https://example.com/foo",
+			nil,
+			nil,
+		},
+		{
+			"exec",
+			config.TemplateApprovalManual,
+			"exec://haproxy -f -",
+			nil,
+			nil,
+		},
+		{
+			"exec_empty",
+			config.TemplateApprovalAuto,
+			"exec://",
+			nil,
+			nil,
+		},
+		{
+			"manual_rollout",
+			config.TemplateApprovalManual,
+			"/tmp/out",
+			&config.RolloutConfig{Enabled: config.Bool(true)},
+			nil,
+		},
+		{
+			"rollout_consul_kv",
+			config.TemplateApprovalAuto,
+			"consul://kv/foo",
+			&config.RolloutConfig{Enabled: config.Bool(true)},
+			nil,
+		},
+		{
+			"rollout_max_parallel",
+			config.TemplateApprovalAuto,
+			"/tmp/out",
+			&config.RolloutConfig{Enabled: config.Bool(true), MaxParallel: config.Int(-1)},
+			nil,
+		},
+		{
+			"diff_consul_kv",
+			config.TemplateApprovalAuto,
+			"consul://kv/foo",
+			nil,
+			&config.DiffConfig{Dir: config.String("/tmp/diffs")},
+		},
+		{
+			"diff_format",
+			config.TemplateApprovalAuto,
+			"/tmp/out",
+			nil,
+			&config.DiffConfig{Dir: config.String("/tmp/diffs"), Format: config.String("xml")},
+		},
+		{
+			"diff_dir",
+			config.TemplateApprovalAuto,
+			"/tmp/out",
+			nil,
+			&config.DiffConfig{Enabled: config.Bool(true)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Approval:    config.String(tc.approval),
+						Contents:    config.String("hello"),
+						Destination: config.String(tc.dest),
+						Diff:        tc.diff,
+						Rollout:     tc.rollout,
+					},
+				},
+			})
+			c.Finalize()
+
+			if _, err := NewRunner(c, false, false); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestRunner_applyRollout(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "out")
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("hello"),
+				Destination: config.String(dest),
+				Rollout: &config.RolloutConfig{
+					MaxParallel: config.Int(5),
+				},
+			},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("expected %q to not be rendered before rollout: %v", dest, err)
+	}
+	if _, ok := r.rollouts[dest]; !ok {
+		t.Fatalf("expected %q to be waiting for rollout", dest)
+	}
+
+	g := &rolloutGrant{
+		config: (*c.Templates)[0],
+		doneCh: make(chan struct{}),
+	}
+	if err := r.applyRollout(g); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-g.doneCh:
+	default:
+		t.Errorf("expected grant to be done")
+	}
+	if _, ok := r.rollouts[dest]; ok {
+		t.Errorf("expected %q to no longer be waiting for rollout", dest)
+	}
+	b, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Errorf("expected %q to be %q", b, "hello")
+	}
+}
+
+func TestRunner_templateEnv(t *testing.T) {
+	if err := os.Setenv("CT_TEST_PORT", "8080"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("CT_TEST_PORT")
+	if err := os.Setenv("CT_TEST_TOKEN", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("CT_TEST_TOKEN")
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		TemplateEnv: &config.EnvConfig{
+			Blacklist: []string{"*TOKEN*"},
+			Custom:    []string{"CT_TEST_CUSTOM=1"},
+			Whitelist: []string{"CT_TEST_*"},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	env, restrict := r.templateEnv()
+	if !restrict {
+		t.Errorf("expected the env to be restricted")
+	}
+	exp := []string{"CT_TEST_CUSTOM=1", "CT_TEST_PORT=8080"}
+	if !reflect.DeepEqual(exp, env) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, env)
+	}
+}
+
+func TestRunner_renderTimeout(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		global   time.Duration
+		template []time.Duration
+		exp      time.Duration
+	}{
+		{
+			"none",
+			0,
+			[]time.Duration{0},
+			0,
+		},
+		{
+			"global",
+			30 * time.Second,
+			[]time.Duration{0},
+			30 * time.Second,
+		},
+		{
+			"template_overrides_global",
+			30 * time.Second,
+			[]time.Duration{time.Minute},
+			time.Minute,
+		},
+		{
+			"shortest_of_shared",
+			0,
+			[]time.Duration{time.Minute, 0, 10 * time.Second},
+			10 * time.Second,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			var templates config.TemplateConfigs
+			for _, timeout := range tc.template {
+				templates = append(templates, &config.TemplateConfig{
+					Contents:      config.String("foo"),
+					RenderTimeout: config.TimeDuration(timeout),
+				})
+			}
+
+			c := config.DefaultConfig().Merge(&config.Config{
+				RenderTimeout: config.TimeDuration(tc.global),
+				Templates:     &templates,
+			})
+			c.Finalize()
+
+			r, err := NewRunner(c, true, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Stop()
+
+			if act := r.renderTimeout(r.templates[0]); act != tc.exp {
+				t.Errorf("expected %s, got %s", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestRunner_withClientSet(t *testing.T) {
+	t.Parallel()
+
+	var requested []string
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+
+		if r.URL.Path != "/v1/kv/foo" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Consul-Index", "1")
+		fmt.Fprint(w, `[{"Key":"foo","Value":"YmFy"}]`)
+	}))
+	defer ts.Close()
+
+	consul, err := consulapi.NewClient(&consulapi.Config{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := dep.NewClientSet()
+	clients.SetConsulClient(consul)
+
+	out, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(out.Name())
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}`),
+				Destination: config.String(out.Name()),
+			},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, false, true, WithClientSet(clients))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.clients != clients {
+		t.Fatalf("expected the given client set to be used")
+	}
+
+	go r.Start()
+	defer r.Stop()
+
+	select {
+	case err := <-r.ErrCh:
+		t.Fatal(err)
+	case <-r.DoneCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	b, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Errorf("expected %q, got %q", "bar", b)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requested) == 0 {
+		t.Errorf("expected the mock server to be queried")
+	}
+}
@@ -0,0 +1,265 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+)
+
+const (
+	// sandboxDNSPort is always allowed, so backend addresses can be resolved
+	// over TCP.
+	sandboxDNSPort = 53
+
+	// sandboxDefaultConsulPort and sandboxDefaultVaultPort are the ports of the
+	// default Consul and Vault addresses.
+	sandboxDefaultConsulPort = 8500
+	sandboxDefaultVaultPort  = 8200
+)
+
+var (
+	// sandboxLock protects sandboxApplied.
+	sandboxLock sync.Mutex
+
+	// sandboxApplied is true once the process is confined. Confinement cannot
+	// be lifted, so it is applied once per process and not on reload.
+	sandboxApplied bool
+)
+
+// sandboxRules are the paths the sandboxed process may write beneath and the
+// TCP ports it may connect to.
+type sandboxRules struct {
+	writable []string
+	ports    []int
+}
+
+// newSandboxRules returns the sandbox rules for the given configuration: the
+// declared writable paths and ports, plus the directories of the file
+// destinations, PID file, snapshot and status file, and the ports of the
+// Consul and Vault addresses, of the notifications webhook and of HTTP
+// destinations.
+func newSandboxRules(c *config.Config) (*sandboxRules, error) {
+	writable := map[string]struct{}{
+		os.TempDir(): {},
+	}
+	ports := map[int]struct{}{
+		sandboxDNSPort: {},
+	}
+
+	addPath := func(path string) error {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		writable[existingAncestor(abs)] = struct{}{}
+		return nil
+	}
+
+	addURL := func(s string, def int) error {
+		port, ok, err := addressPort(s, def)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ports[port] = struct{}{}
+		}
+		return nil
+	}
+
+	if err := addURL(config.StringVal(c.Consul.Address), sandboxDefaultConsulPort); err != nil {
+		return nil, fmt.Errorf("consul address: %s", err)
+	}
+	if config.BoolVal(c.Consul.OAuth2.Enabled) {
+		if err := addURL(config.StringVal(c.Consul.OAuth2.TokenURL), 0); err != nil {
+			return nil, fmt.Errorf("consul oauth2 token_url: %s", err)
+		}
+	}
+	if config.BoolVal(c.Vault.Enabled) {
+		if err := addURL(config.StringVal(c.Vault.Address), sandboxDefaultVaultPort); err != nil {
+			return nil, fmt.Errorf("vault address: %s", err)
+		}
+		for _, a := range c.Vault.Addresses {
+			if err := addURL(a, sandboxDefaultVaultPort); err != nil {
+				return nil, fmt.Errorf("vault addresses: %s", err)
+			}
+		}
+	}
+	if config.BoolVal(c.Notifications.Enabled) {
+		if err := addURL(config.StringVal(c.Notifications.Webhook), 0); err != nil {
+			return nil, fmt.Errorf("notifications webhook: %s", err)
+		}
+	}
+	// The syslog writer dials the remote server again after errors.
+	if config.BoolVal(c.Syslog.Enabled) {
+		if err := addURL(config.StringVal(c.Syslog.Address), 0); err != nil {
+			return nil, fmt.Errorf("syslog address: %s", err)
+		}
+	}
+
+	if pid := config.StringVal(c.PidFile); pid != "" {
+		if err := addPath(filepath.Dir(pid)); err != nil {
+			return nil, err
+		}
+	}
+	if config.BoolVal(c.Snapshot.Enabled) {
+		if err := addPath(filepath.Dir(config.StringVal(c.Snapshot.Path))); err != nil {
+			return nil, err
+		}
+	}
+	if config.BoolVal(c.Status.Enabled) {
+		if err := addPath(filepath.Dir(config.StringVal(c.Status.Path))); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, t := range *c.Templates {
+		dest := config.StringVal(t.Destination)
+		switch {
+		case isHTTPDestination(dest):
+			if err := addURL(dest, 0); err != nil {
+				return nil, fmt.Errorf("%s: %s", t.Display(), err)
+			}
+		case isFileDestination(dest):
+			if err := addPath(filepath.Dir(dest)); err != nil {
+				return nil, err
+			}
+		}
+
+		if config.BoolVal(t.Diff.Enabled) {
+			if err := addPath(config.StringVal(t.Diff.Dir)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, p := range c.Sandbox.WritablePaths {
+		if err := addPath(p); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range c.Sandbox.AllowedPorts {
+		if p < 1 || p > 65535 {
+			return nil, fmt.Errorf("invalid port %d", p)
+		}
+		ports[p] = struct{}{}
+	}
+
+	rules := &sandboxRules{
+		writable: make([]string, 0, len(writable)),
+		ports:    make([]int, 0, len(ports)),
+	}
+	for p := range writable {
+		rules.writable = append(rules.writable, p)
+	}
+	for p := range ports {
+		rules.ports = append(rules.ports, p)
+	}
+	sort.Strings(rules.writable)
+	sort.Ints(rules.ports)
+	return rules, nil
+}
+
+// existingAncestor returns the path itself if it exists, or its nearest
+// ancestor which does, since missing destination directories are created
+// when rendering.
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// addressPort returns the TCP port of the given address, which may be a URL
+// or a host and port, falling back to the default for the scheme or the
+// given default. Unix socket addresses have no port.
+func addressPort(s string, def int) (int, bool, error) {
+	if s == "" {
+		return def, def != 0, nil
+	}
+	if strings.HasPrefix(s, "unix://") {
+		return 0, false, nil
+	}
+
+	host := s
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return 0, false, err
+		}
+		host = u.Host
+		switch u.Scheme {
+		case "http":
+			def = 80
+		case "https":
+			def = 443
+		}
+	}
+
+	_, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return def, def != 0, nil
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid port %q", port)
+	}
+	return p, true, nil
+}
+
+// sandbox confines the process to the sandbox rules of the configuration. It
+// is applied once, and later runners created on reload keep the confinement
+// of the first.
+func (r *Runner) sandbox() error {
+	sandboxLock.Lock()
+	defer sandboxLock.Unlock()
+
+	if sandboxApplied {
+		log.Printf("[INFO] (runner) sandbox was applied on startup, changes to " +
+			"the sandbox require a restart")
+		return nil
+	}
+
+	rules, err := newSandboxRules(r.config)
+	if err != nil {
+		return fmt.Errorf("runner: sandbox: %s", err)
+	}
+
+	if err := applySandbox(rules); err != nil {
+		return fmt.Errorf("runner: sandbox: %s", err)
+	}
+	sandboxApplied = true
+
+	log.Printf("[INFO] (runner) sandbox applied (writable: %q, ports: %v)",
+		rules.writable, rules.ports)
+	return nil
+}
+
+// commandSandbox returns the sandbox of the command of a template for the
+// given configuration, or nil if the command is not confined.
+func commandSandbox(c *config.CommandSandboxConfig) *child.Sandbox {
+	if !config.BoolVal(c.Enabled) {
+		return nil
+	}
+
+	return &child.Sandbox{
+		NoNetwork:     !config.BoolVal(c.Network),
+		NoNewPrivs:    config.BoolVal(c.NoNewPrivs),
+		ReadOnlyPaths: c.ReadOnlyPaths,
+	}
+}
@@ -0,0 +1,168 @@
+// +build linux,go1.16
+
+package manager
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Landlock system calls and constants, which are the same on all
+// architectures. See https://docs.kernel.org/userspace-api/landlock.html.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1 << 0
+
+	landlockRulePathBeneath = 1
+	landlockRuleNetPort     = 2
+
+	landlockAccessFSWriteFile  = 1 << 1
+	landlockAccessFSRemoveDir  = 1 << 4
+	landlockAccessFSRemoveFile = 1 << 5
+	landlockAccessFSMakeChar   = 1 << 6
+	landlockAccessFSMakeDir    = 1 << 7
+	landlockAccessFSMakeReg    = 1 << 8
+	landlockAccessFSMakeSock   = 1 << 9
+	landlockAccessFSMakeFifo   = 1 << 10
+	landlockAccessFSMakeBlock  = 1 << 11
+	landlockAccessFSMakeSym    = 1 << 12
+	landlockAccessFSRefer      = 1 << 13
+	landlockAccessFSTruncate   = 1 << 14
+
+	landlockAccessNetConnectTCP = 1 << 1
+
+	prSetNoNewPrivs = 38
+)
+
+// landlockRulesetAttr is struct landlock_ruleset_attr.
+type landlockRulesetAttr struct {
+	handledAccessFS  uint64
+	handledAccessNet uint64
+}
+
+// landlockPathBeneathAttr is the packed struct landlock_path_beneath_attr.
+// Only the first 12 bytes are read by the kernel.
+type landlockPathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+}
+
+// landlockNetPortAttr is struct landlock_net_port_attr.
+type landlockNetPortAttr struct {
+	allowedAccess uint64
+	port          uint64
+}
+
+// applySandbox confines all threads of the process with Landlock, so that
+// files can only be written beneath the writable paths of the rules. On
+// kernels which support it (6.7 and later), TCP connections are limited to
+// the ports of the rules. Child processes inherit the confinement.
+func applySandbox(rules *sandboxRules) error {
+	abi, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return fmt.Errorf("landlock is not supported by this kernel: %s", errno)
+	}
+
+	// The access rights which are known to the kernel depend on the ABI.
+	fileAccess := uint64(landlockAccessFSWriteFile)
+	dirAccess := uint64(landlockAccessFSWriteFile |
+		landlockAccessFSRemoveDir |
+		landlockAccessFSRemoveFile |
+		landlockAccessFSMakeChar |
+		landlockAccessFSMakeDir |
+		landlockAccessFSMakeReg |
+		landlockAccessFSMakeSock |
+		landlockAccessFSMakeFifo |
+		landlockAccessFSMakeBlock |
+		landlockAccessFSMakeSym)
+	if abi >= 2 {
+		dirAccess |= landlockAccessFSRefer
+	}
+	if abi >= 3 {
+		fileAccess |= landlockAccessFSTruncate
+		dirAccess |= landlockAccessFSTruncate
+	}
+
+	attr := landlockRulesetAttr{handledAccessFS: dirAccess}
+	attrSize := unsafe.Sizeof(attr.handledAccessFS)
+	if abi >= 4 {
+		attr.handledAccessNet = landlockAccessNetConnectTCP
+		attrSize = unsafe.Sizeof(attr)
+	}
+
+	fd, _, errno := syscall.Syscall(sysLandlockCreateRuleset,
+		uintptr(unsafe.Pointer(&attr)), attrSize, 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to create landlock ruleset: %s", errno)
+	}
+	defer syscall.Close(int(fd))
+
+	for _, path := range rules.writable {
+		if err := landlockAllowPath(int(fd), path, fileAccess, dirAccess); err != nil {
+			return err
+		}
+	}
+
+	if abi >= 4 {
+		for _, port := range rules.ports {
+			rule := landlockNetPortAttr{
+				allowedAccess: landlockAccessNetConnectTCP,
+				port:          uint64(port),
+			}
+			if _, _, errno := syscall.Syscall6(sysLandlockAddRule, fd,
+				landlockRuleNetPort, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+				return fmt.Errorf("failed to allow port %d: %s", port, errno)
+			}
+		}
+	}
+
+	// Landlock only applies to the calling thread, so both calls are made on
+	// every thread of the runtime. This is not supported in binaries which use
+	// cgo.
+	if _, _, errno := syscall.AllThreadsSyscall6(syscall.SYS_PRCTL,
+		prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		if errno == syscall.ENOTSUP {
+			return fmt.Errorf("the sandbox requires a binary built with CGO_ENABLED=0")
+		}
+		return fmt.Errorf("failed to set no_new_privs: %s", errno)
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(sysLandlockRestrictSelf, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to restrict process: %s", errno)
+	}
+
+	return nil
+}
+
+// landlockAllowPath adds a rule which allows writing beneath the given path to
+// the ruleset. Directory access rights cannot be granted on regular files.
+func landlockAllowPath(ruleset int, path string, fileAccess, dirAccess uint64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	rule := landlockPathBeneathAttr{
+		allowedAccess: fileAccess,
+		parentFd:      int32(f.Fd()),
+	}
+	if fi.IsDir() {
+		rule.allowedAccess = dirAccess
+	}
+
+	if _, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(ruleset),
+		landlockRulePathBeneath, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to allow writing beneath %q: %s", path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// +build linux,go1.16
+
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sandboxHelperEnv is set when the test binary is re-executed to apply the
+// sandbox, since the sandbox cannot be lifted from the test process.
+const sandboxHelperEnv = "CT_SANDBOX_TEST_DIRS"
+
+func TestApplySandbox(t *testing.T) {
+	if dirs := os.Getenv(sandboxHelperEnv); dirs != "" {
+		sandboxHelper(strings.Split(dirs, ":"))
+		return
+	}
+
+	allowed, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(allowed)
+
+	denied, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(denied)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestApplySandbox$")
+	cmd.Env = append(os.Environ(), sandboxHelperEnv+"="+allowed+":"+denied)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+
+	switch {
+	case strings.Contains(string(out), "sandbox: unsupported:"):
+		t.Skip(string(out))
+	case !strings.Contains(string(out), "sandbox: ok"):
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+// sandboxHelper confines the process to the first directory, and checks that
+// it can write to it but not to the second.
+func sandboxHelper(dirs []string) {
+	allowed, denied := dirs[0], dirs[1]
+
+	if err := applySandbox(&sandboxRules{writable: []string{allowed}}); err != nil {
+		fmt.Printf("sandbox: unsupported: %s\n", err)
+		os.Exit(0)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(allowed, "a"), []byte("a"), 0644); err != nil {
+		fmt.Printf("sandbox: failed to write to allowed directory: %s\n", err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(filepath.Join(denied, "a"), []byte("a"), 0644); err == nil {
+		fmt.Printf("sandbox: wrote to denied directory\n")
+		os.Exit(1)
+	}
+
+	fmt.Println("sandbox: ok")
+	os.Exit(0)
+}
@@ -0,0 +1,12 @@
+// +build !linux !go1.16
+
+package manager
+
+import "fmt"
+
+// applySandbox is not supported on platforms other than Linux, or on Go
+// versions without syscall.AllThreadsSyscall.
+func applySandbox(rules *sandboxRules) error {
+	return fmt.Errorf("the sandbox is only supported on Linux builds with " +
+		"Go 1.16 or later")
+}
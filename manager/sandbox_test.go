@@ -0,0 +1,282 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestAddressPort(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		s    string
+		def  int
+		port int
+		ok   bool
+		err  bool
+	}{
+		{"empty", "", 8500, 8500, true, false},
+		{"empty_no_default", "", 0, 0, false, false},
+		{"host_port", "consul.service:8501", 8500, 8501, true, false},
+		{"host", "consul.service", 8500, 8500, true, false},
+		{"http_url", "http://vault.service", 8200, 80, true, false},
+		{"https_url", "https://vault.service", 8200, 443, true, false},
+		{"url_port", "https://vault.service:8200/v1", 0, 8200, true, false},
+		{"unix", "unix:///var/run/consul.sock", 8500, 0, false, false},
+		{"invalid_port", "consul.service:abc", 8500, 0, false, true},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			port, ok, err := addressPort(tc.s, tc.def)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if port != tc.port || ok != tc.ok {
+				t.Errorf("expected (%d, %t), got (%d, %t)", tc.port, tc.ok, port, ok)
+			}
+		})
+	}
+}
+
+func TestNewSandboxRules(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Consul: &config.ConsulConfig{
+			Address: config.String("consul.service:8501"),
+		},
+		Notifications: &config.NotificationsConfig{
+			Webhook: config.String("https://hooks.example.com:9443/ct"),
+		},
+		PidFile: config.String(filepath.Join(dir, "run", "ct.pid")),
+		Sandbox: &config.SandboxConfig{
+			AllowedPorts:  []int{8125},
+			WritablePaths: []string{"/var/lib/app"},
+		},
+		Status: &config.StatusConfig{
+			Path: config.String(filepath.Join(dir, "state", "status.json")),
+		},
+		Syslog: &config.SyslogConfig{
+			Address: config.String("tls://logs.example.com:6514"),
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String(filepath.Join(dir, "out", "a.conf")),
+			},
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String("https://example.com:8443/hook"),
+			},
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String("consul://kv/foo"),
+			},
+		},
+		Vault: &config.VaultConfig{
+			Address:   config.String("https://vault.service"),
+			Addresses: []string{"https://vault-2.service:8210", "vault-3.service"},
+		},
+	})
+	c.Finalize()
+
+	for _, d := range []string{"out", "state"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules, err := newSandboxRules(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expWritable := map[string]bool{
+		dir:                              true,
+		filepath.Join(dir, "out"):        true,
+		filepath.Join(dir, "state"):      true,
+		existingAncestor("/var/lib/app"): true,
+		os.TempDir():                     true,
+	}
+	for _, p := range rules.writable {
+		if !expWritable[p] {
+			t.Errorf("unexpected writable path %q", p)
+		}
+		delete(expWritable, p)
+	}
+	for p := range expWritable {
+		t.Errorf("missing writable path %q", p)
+	}
+
+	if exp := []int{53, 443, 6514, 8125, 8200, 8210, 8443, 8501, 9443}; !reflect.DeepEqual(exp, rules.ports) {
+		t.Errorf("expected ports %v, got %v", exp, rules.ports)
+	}
+}
+
+func TestNewSandboxRules_syslog(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		address string
+		ports   []int
+	}{
+		{"tcp", "tcp://10.0.0.1:1514", []int{53, 1514, 8500}},
+		{"udp", "udp://10.0.0.1:514", []int{53, 514, 8500}},
+		{"unix", "unix:///dev/log", []int{53, 8500}},
+		{"local", "", []int{53, 8500}},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				Syslog: &config.SyslogConfig{
+					Enabled: config.Bool(true),
+					Address: config.String(tc.address),
+				},
+			})
+			c.Finalize()
+
+			rules, err := newSandboxRules(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.ports, rules.ports) {
+				t.Errorf("expected ports %v, got %v", tc.ports, rules.ports)
+			}
+		})
+	}
+}
+
+func TestNewSandboxRules_invalidPort(t *testing.T) {
+	t.Parallel()
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Sandbox: &config.SandboxConfig{
+			AllowedPorts: []int{70000},
+		},
+	})
+	c.Finalize()
+
+	if _, err := newSandboxRules(c); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCommandSandbox(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		c    *config.CommandSandboxConfig
+		exp  *child.Sandbox
+	}{
+		{
+			"disabled",
+			&config.CommandSandboxConfig{},
+			nil,
+		},
+		{
+			"defaults",
+			&config.CommandSandboxConfig{
+				Enabled: config.Bool(true),
+			},
+			&child.Sandbox{
+				NoNewPrivs:    true,
+				ReadOnlyPaths: []string{},
+			},
+		},
+		{
+			"all",
+			&config.CommandSandboxConfig{
+				Network:       config.Bool(false),
+				NoNewPrivs:    config.Bool(false),
+				ReadOnlyPaths: []string{"/etc"},
+			},
+			&child.Sandbox{
+				NoNetwork:     true,
+				ReadOnlyPaths: []string{"/etc"},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.c.Finalize()
+			if act := commandSandbox(tc.c); !reflect.DeepEqual(tc.exp, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestRunner_initCommandSandbox(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("the command sandbox is only supported on Linux")
+	}
+
+	cases := []struct {
+		name    string
+		sandbox *config.SandboxConfig
+		err     string
+	}{
+		{
+			"valid",
+			&config.SandboxConfig{},
+			"",
+		},
+		{
+			"with_sandbox",
+			&config.SandboxConfig{Enabled: config.Bool(true)},
+			"cannot be combined with sandbox",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				Sandbox: tc.sandbox,
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("a"),
+						Destination: config.String("/tmp/a"),
+						CommandSandbox: &config.CommandSandboxConfig{
+							ReadOnlyPaths: []string{"/etc"},
+						},
+					},
+				},
+			})
+			c.Finalize()
+
+			_, err := NewRunner(c, true, true)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package manager
+
+import (
+	"bytes"
+	"compress/lzw"
+	"crypto/md5"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+	"github.com/hashicorp/consul-template/watch"
+)
+
+const (
+	// snapshotVersion is the version of the on-disk snapshot format. Snapshots
+	// with a different version are ignored.
+	snapshotVersion = 2
+
+	// snapshotPerms are the file permissions for the snapshot on disk.
+	snapshotPerms = 0600
+)
+
+// snapshotData is the GOB encoded representation of the watch state that is
+// persisted to disk between restarts.
+type snapshotData struct {
+	Version int
+
+	// Indexes is the map of dependency strings to the last index that was
+	// observed for that dependency.
+	Indexes map[string]uint64
+
+	// Data is the map of dependency strings to the last data that was
+	// received for that dependency. The data of each dependency is encoded on
+	// its own, so that data which cannot be encoded or decoded only leaves out
+	// its dependency.
+	Data map[string][]byte
+}
+
+// snapshotValue wraps the data of a dependency, since gob only encodes the
+// concrete type of interface values which are fields.
+type snapshotValue struct {
+	Value interface{}
+}
+
+// encodeSnapshotValue returns the GOB encoding of the given dependency data.
+func encodeSnapshotValue(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&snapshotValue{Value: v}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeSnapshotValue returns the dependency data of the given GOB encoding.
+func decodeSnapshotValue(b []byte) (interface{}, error) {
+	var sv snapshotValue
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&sv); err != nil {
+		return nil, err
+	}
+	return sv.Value, nil
+}
+
+// snapshotter reads and writes the watch state for a runner. Only Consul
+// dependencies are persisted, since they are the only dependencies with a
+// meaningful blocking index, and because Vault secrets should never be
+// written to disk. For the same reason, Connect leaf certificates are not
+// persisted either, as they carry their private key. The data of other Consul
+// dependencies may still contain secrets, so the snapshot is encrypted if
+// encryption is configured.
+type snapshotter struct {
+	// path is the location of the snapshot on disk.
+	path string
+
+	// enc encrypts the snapshot, or is nil if it is written in plaintext.
+	enc *encrypter
+
+	// restored is the set of dependency strings which were loaded from the
+	// snapshot and have not been replaced by fresh data yet.
+	restored map[string]struct{}
+
+	// lastWrite is the hash of the last snapshot written to disk. It is used
+	// to avoid rewriting an unchanged snapshot on every run.
+	lastWrite []byte
+}
+
+// newSnapshotter creates a new snapshotter for the given path, which encrypts
+// the snapshot with the encrypter if it is not nil.
+func newSnapshotter(path string, enc *encrypter) *snapshotter {
+	return &snapshotter{
+		path:     path,
+		enc:      enc,
+		restored: make(map[string]struct{}),
+	}
+}
+
+// Restore loads the snapshot from disk into the given brain and returns the
+// map of last indexes to seed the watcher with. A missing snapshot is not an
+// error. A corrupt or incompatible snapshot is logged and ignored, since the
+// worst case is a full re-fetch of all dependencies.
+func (s *snapshotter) Restore(brain *template.Brain) map[string]uint64 {
+	raw, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[WARN] (snapshot) failed to read %q: %s", s.path, err)
+		}
+		return nil
+	}
+
+	if s.enc != nil {
+		raw, err = s.enc.decrypt(raw)
+		if err != nil {
+			log.Printf("[WARN] (snapshot) failed to decrypt %q: %s", s.path, err)
+			return nil
+		}
+	}
+
+	decompress := lzw.NewReader(bytes.NewReader(raw), lzw.LSB, 8)
+	defer decompress.Close()
+
+	var sd snapshotData
+	if err := gob.NewDecoder(decompress).Decode(&sd); err != nil {
+		log.Printf("[WARN] (snapshot) failed to decode %q: %s", s.path, err)
+		return nil
+	}
+
+	if sd.Version != snapshotVersion {
+		log.Printf("[WARN] (snapshot) ignoring %q with version %d (expected %d)",
+			s.path, sd.Version, snapshotVersion)
+		return nil
+	}
+
+	indexes := make(map[string]uint64, len(sd.Indexes))
+	for k, index := range sd.Indexes {
+		raw, ok := sd.Data[k]
+		if !ok {
+			continue
+		}
+		data, err := decodeSnapshotValue(raw)
+		if err != nil {
+			log.Printf("[WARN] (snapshot) failed to decode %s from %q: %s",
+				k, s.path, err)
+			continue
+		}
+		brain.ForceSet(k, data)
+		indexes[k] = index
+		s.restored[k] = struct{}{}
+	}
+
+	log.Printf("[INFO] (snapshot) restored %d dependencies from %q",
+		len(indexes), s.path)
+
+	return indexes
+}
+
+// Restored returns true if the data for the given dependency was loaded from
+// the snapshot.
+func (s *snapshotter) Restored(d dep.Dependency) bool {
+	_, ok := s.restored[d.String()]
+	return ok
+}
+
+// Forget marks the given dependency as no longer restored, usually because
+// it is no longer in use.
+func (s *snapshotter) Forget(d dep.Dependency) {
+	delete(s.restored, d.String())
+}
+
+// Write persists the current index and data for each of the given
+// dependencies. The snapshot is written atomically and only when it has
+// changed since the last write. Dependencies whose data cannot be encoded are
+// logged and left out.
+func (s *snapshotter) Write(deps map[string]dep.Dependency, brain *template.Brain, watcher *watch.Watcher) error {
+	sd := snapshotData{
+		Version: snapshotVersion,
+		Indexes: make(map[string]uint64),
+		Data:    make(map[string][]byte),
+	}
+
+	for k, d := range deps {
+		if !snapshotDep(d) {
+			continue
+		}
+
+		index := watcher.LastIndex(d)
+		if index == 0 {
+			continue
+		}
+
+		data, ok := brain.Recall(d)
+		if !ok {
+			continue
+		}
+
+		raw, err := encodeSnapshotValue(data)
+		if err != nil {
+			log.Printf("[WARN] (snapshot) not persisting %s: %s", k, err)
+			continue
+		}
+
+		sd.Indexes[k] = index
+		sd.Data[k] = raw
+	}
+
+	var buf bytes.Buffer
+	compress := lzw.NewWriter(&buf, lzw.LSB, 8)
+	if err := gob.NewEncoder(compress).Encode(&sd); err != nil {
+		return fmt.Errorf("snapshot: encode failed: %s", err)
+	}
+	compress.Close()
+
+	hash := md5.Sum(buf.Bytes())
+	if bytes.Equal(s.lastWrite, hash[:]) {
+		return nil
+	}
+
+	contents := buf.Bytes()
+	if s.enc != nil {
+		sealed, err := s.enc.encrypt(contents)
+		if err != nil {
+			return fmt.Errorf("snapshot: %s", err)
+		}
+		contents = sealed
+	}
+
+	if err := AtomicWrite(s.path, contents, snapshotPerms, false); err != nil {
+		return fmt.Errorf("snapshot: %s", err)
+	}
+	s.lastWrite = hash[:]
+
+	log.Printf("[DEBUG] (snapshot) wrote %d dependencies to %q",
+		len(sd.Indexes), s.path)
+
+	return nil
+}
+
+// snapshotDep returns true if the data of the given dependency may be written
+// to the snapshot.
+func snapshotDep(d dep.Dependency) bool {
+	if d.Type() != dep.TypeConsul {
+		return false
+	}
+	if _, ok := d.(*dep.ConnectLeafQuery); ok {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package manager
+
+import (
+	"bytes"
+	"compress/lzw"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+)
+
+func testSnapshotFile(t *testing.T, sd *snapshotData) string {
+	var buf bytes.Buffer
+	compress := lzw.NewWriter(&buf, lzw.LSB, 8)
+	if err := gob.NewEncoder(compress).Encode(sd); err != nil {
+		t.Fatal(err)
+	}
+	compress.Close()
+
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func testSnapshotValue(t *testing.T, v interface{}) []byte {
+	b, err := encodeSnapshotValue(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSnapshotter_Restore(t *testing.T) {
+	d, err := dep.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		s := newSnapshotter(filepath.Join(dir, "snapshot"), nil)
+		if indexes := s.Restore(template.NewBrain()); indexes != nil {
+			t.Errorf("expected nil indexes, got %#v", indexes)
+		}
+	})
+
+	t.Run("restores", func(t *testing.T) {
+		path := testSnapshotFile(t, &snapshotData{
+			Version: snapshotVersion,
+			Indexes: map[string]uint64{d.String(): 10, "kv.get(bar)": 20, "kv.get(baz)": 30},
+			Data: map[string][]byte{
+				d.String():    testSnapshotValue(t, "value"),
+				"kv.get(baz)": []byte("corrupt"),
+			},
+		})
+		defer os.Remove(path)
+
+		brain := template.NewBrain()
+		s := newSnapshotter(path, nil)
+
+		indexes := s.Restore(brain)
+		expected := map[string]uint64{d.String(): 10}
+		if !reflect.DeepEqual(indexes, expected) {
+			t.Errorf("\nexp: %#v\nact: %#v", expected, indexes)
+		}
+
+		if !s.Restored(d) {
+			t.Errorf("expected %s to be restored", d)
+		}
+
+		data, ok := brain.Recall(d)
+		if !ok || data != "value" {
+			t.Errorf("expected brain to have %q, got %#v", "value", data)
+		}
+
+		s.Forget(d)
+		if s.Restored(d) {
+			t.Errorf("expected %s to be forgotten", d)
+		}
+	})
+
+	t.Run("version_mismatch", func(t *testing.T) {
+		path := testSnapshotFile(t, &snapshotData{
+			Version: snapshotVersion + 1,
+			Indexes: map[string]uint64{d.String(): 10},
+			Data:    map[string][]byte{d.String(): testSnapshotValue(t, "value")},
+		})
+		defer os.Remove(path)
+
+		brain := template.NewBrain()
+		s := newSnapshotter(path, nil)
+		if indexes := s.Restore(brain); indexes != nil {
+			t.Errorf("expected nil indexes, got %#v", indexes)
+		}
+		if _, ok := brain.Recall(d); ok {
+			t.Errorf("expected brain to be empty")
+		}
+	})
+}
+
+func TestSnapshotValue(t *testing.T) {
+	cases := []struct {
+		name string
+		v    interface{}
+		err  bool
+	}{
+		{"string", "value", false},
+		{"catalog_node", &dep.CatalogNode{Node: &dep.Node{Node: "a"}}, false},
+		{"catalog_service", []*dep.CatalogService{{ServiceName: "web"}}, false},
+		{"health_service", []*dep.HealthService{{Name: "web"}}, false},
+		{"unregistered", struct{ A int }{1}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := encodeSnapshotValue(tc.v)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if err != nil {
+				return
+			}
+
+			v, err := decodeSnapshotValue(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(v, tc.v) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.v, v)
+			}
+		})
+	}
+}
+
+func TestSnapshotDep(t *testing.T) {
+	kv, err := dep.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := dep.NewConnectLeafQuery("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := dep.NewVaultReadQuery("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		d   dep.Dependency
+		exp bool
+	}{
+		{kv, true},
+		{leaf, false},
+		{secret, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.d.String(), func(t *testing.T) {
+			if act := snapshotDep(tc.d); act != tc.exp {
+				t.Errorf("expected %t, got %t", tc.exp, act)
+			}
+		})
+	}
+}
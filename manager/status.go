@@ -0,0 +1,202 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// statusPerms are the permissions of the status file. It holds unsalted
+// hashes of the rendered contents, which are often secrets that could be
+// brute-forced from them, so only the owner can read it.
+const statusPerms = 0600
+
+// RenderRecord is an entry in the history of recent render events, recording
+// when a destination changed and why, or why rendering it failed.
+type RenderRecord struct {
+	// Time is when the event occurred.
+	Time time.Time `json:"time"`
+
+	// Template is the ID of the template, and Source and Destination are those
+	// of its configuration. Destination is empty if the template could not be
+	// executed, which affects all of its destinations.
+	Template    string `json:"template"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination,omitempty"`
+
+	// Rendered is true if the contents of the destination changed.
+	Rendered bool `json:"rendered"`
+
+	// ContentHash is the SHA-256 of the new contents and PreviousHash the one
+	// of the contents which were last rendered by this process, if any.
+	ContentHash  string `json:"content_hash,omitempty"`
+	PreviousHash string `json:"previous_hash,omitempty"`
+
+	// Dependencies are the dependencies of the template whose data changed
+	// since it was last rendered, which is why it rendered again.
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// Error is the error which occurred, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// statusFile is the document written to the status file.
+type statusFile struct {
+	UpdatedAt time.Time       `json:"updated_at"`
+	Events    []*RenderRecord `json:"events"`
+}
+
+// renderHistory keeps a bounded history of recent render records, and writes
+// it to the status file if one is configured.
+type renderHistory struct {
+	sync.RWMutex
+
+	path string
+	size int
+
+	records []*RenderRecord
+
+	// hashes are the hashes of the contents last rendered to each
+	// destination, and changed are the dependencies of each template, by ID,
+	// which changed since it was last rendered.
+	hashes  map[string]string
+	changed map[string]map[string]struct{}
+}
+
+// newRenderHistory creates a history of the given size, which is written to
+// the given path unless it is empty.
+func newRenderHistory(path string, size int) *renderHistory {
+	return &renderHistory{
+		path:    path,
+		size:    size,
+		hashes:  make(map[string]string),
+		changed: make(map[string]map[string]struct{}),
+	}
+}
+
+// dependencyChanged records that the data of the given dependency changed for
+// each of the templates which use it.
+func (h *renderHistory) dependencyChanged(templateID string, d dep.Dependency) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.changed[templateID] == nil {
+		h.changed[templateID] = make(map[string]struct{})
+	}
+	h.changed[templateID][d.String()] = struct{}{}
+}
+
+// rendered records that the contents of the destination changed, and returns
+// the record.
+func (h *renderHistory) rendered(templateID, source, dest string, contents []byte) *RenderRecord {
+	sum := sha256.Sum256(contents)
+	hash := hex.EncodeToString(sum[:])
+
+	h.Lock()
+	deps := make([]string, 0, len(h.changed[templateID]))
+	for d := range h.changed[templateID] {
+		deps = append(deps, d)
+	}
+	sort.Strings(deps)
+
+	previous := h.hashes[dest]
+	h.hashes[dest] = hash
+	h.Unlock()
+
+	r := &RenderRecord{
+		Time:         time.Now().UTC(),
+		Template:     templateID,
+		Source:       source,
+		Destination:  dest,
+		Rendered:     true,
+		ContentHash:  hash,
+		PreviousHash: previous,
+		Dependencies: deps,
+	}
+	h.add(r)
+	return r
+}
+
+// renderedAll forgets the changed dependencies of the template once all of
+// its destinations were rendered.
+func (h *renderHistory) renderedAll(templateID string) {
+	h.Lock()
+	delete(h.changed, templateID)
+	h.Unlock()
+}
+
+// failed records that rendering the template, or one of its destinations if
+// dest is not empty, failed with the given error, and returns the record.
+func (h *renderHistory) failed(templateID, source, dest string, err error) *RenderRecord {
+	r := &RenderRecord{
+		Time:        time.Now().UTC(),
+		Template:    templateID,
+		Source:      source,
+		Destination: dest,
+		Error:       err.Error(),
+	}
+	h.add(r)
+	return r
+}
+
+// add appends the record, dropping the oldest one if the history is full, and
+// writes the status file.
+func (h *renderHistory) add(r *RenderRecord) {
+	h.Lock()
+	if h.size <= 0 {
+		h.Unlock()
+		return
+	}
+	h.records = append(h.records, r)
+	if len(h.records) > h.size {
+		n := copy(h.records, h.records[len(h.records)-h.size:])
+		h.records = h.records[:n]
+	}
+	h.Unlock()
+
+	if h.path == "" {
+		return
+	}
+	if err := h.write(); err != nil {
+		log.Printf("[WARN] (runner) failed to write status: %s", err)
+	}
+}
+
+// list returns a copy of the records, oldest first.
+func (h *renderHistory) list() []*RenderRecord {
+	h.RLock()
+	defer h.RUnlock()
+
+	records := make([]*RenderRecord, len(h.records))
+	copy(records, h.records)
+	return records
+}
+
+// write writes the history to the status file.
+func (h *renderHistory) write() error {
+	b, err := json.MarshalIndent(&statusFile{
+		UpdatedAt: time.Now().UTC(),
+		Events:    h.list(),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("status: %s", err)
+	}
+
+	if err := AtomicWrite(h.path, append(b, '\n'), statusPerms, false); err != nil {
+		return fmt.Errorf("status: %s", err)
+	}
+	return nil
+}
+
+// RenderHistory returns the most recent render records, oldest first. It
+// holds at most the number of records set by the status history option.
+func (r *Runner) RenderHistory() []*RenderRecord {
+	return r.history.list()
+}
@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+func TestRenderHistory(t *testing.T) {
+	t.Parallel()
+
+	d1, err := dep.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d2, err := dep.NewKVGetQuery("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("rendered", func(t *testing.T) {
+		h := newRenderHistory("", 10)
+
+		h.rendered("tmpl", "in.tpl", "/tmp/out", []byte("one"))
+		h.renderedAll("tmpl")
+		h.dependencyChanged("tmpl", d1)
+		h.dependencyChanged("tmpl", d2)
+		h.rendered("tmpl", "in.tpl", "/tmp/out", []byte("two"))
+		h.renderedAll("tmpl")
+
+		records := h.list()
+		if len(records) != 2 {
+			t.Fatalf("expected 2 records, got %d", len(records))
+		}
+
+		first, second := records[0], records[1]
+		if !first.Rendered || first.PreviousHash != "" || len(first.Dependencies) != 0 {
+			t.Errorf("bad first record: %#v", first)
+		}
+		if second.PreviousHash != first.ContentHash {
+			t.Errorf("expected previous hash %q, got %q", first.ContentHash, second.PreviousHash)
+		}
+		if second.ContentHash == first.ContentHash {
+			t.Errorf("expected content hashes to differ")
+		}
+		exp := []string{d2.String(), d1.String()}
+		if fmt.Sprint(second.Dependencies) != fmt.Sprint(exp) {
+			t.Errorf("\nexp: %#v\nact: %#v", exp, second.Dependencies)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		h := newRenderHistory("", 10)
+		h.failed("tmpl", "in.tpl", "", fmt.Errorf("boom"))
+
+		records := h.list()
+		if len(records) != 1 {
+			t.Fatalf("expected 1 record, got %d", len(records))
+		}
+		if records[0].Rendered || records[0].Error != "boom" {
+			t.Errorf("bad record: %#v", records[0])
+		}
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		h := newRenderHistory("", 3)
+		for i := 0; i < 5; i++ {
+			h.rendered("tmpl", "", fmt.Sprintf("/tmp/out%d", i), []byte("x"))
+		}
+
+		records := h.list()
+		if len(records) != 3 {
+			t.Fatalf("expected 3 records, got %d", len(records))
+		}
+		if records[0].Destination != "/tmp/out2" || records[2].Destination != "/tmp/out4" {
+			t.Errorf("expected the oldest records to be dropped: %#v", records)
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "status.json")
+		h := newRenderHistory(path, 10)
+		h.rendered("tmpl", "in.tpl", "/tmp/out", []byte("one"))
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var status statusFile
+		if err := json.Unmarshal(b, &status); err != nil {
+			t.Fatal(err)
+		}
+		if len(status.Events) != 1 || status.Events[0].Destination != "/tmp/out" {
+			t.Errorf("bad status: %s", b)
+		}
+
+		if runtime.GOOS != "windows" {
+			stat, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stat.Mode().Perm() != 0600 {
+				t.Errorf("expected mode 0600, got %s", stat.Mode())
+			}
+		}
+	})
+}
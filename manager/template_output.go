@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// checkTemplateOutputs returns an error if the given template uses the output
+// of a template which does not exist, or its own output.
+func (r *Runner) checkTemplateOutputs(tmpl *template.Template, used *dep.Set) error {
+	for _, d := range used.List() {
+		d, ok := d.(*dep.TemplateOutputQuery)
+		if !ok {
+			continue
+		}
+
+		other, ok := r.namedTemplates[d.Name()]
+		if !ok {
+			return fmt.Errorf("runner: %s: no template is named %q", tmpl.Source(), d.Name())
+		}
+		if other == tmpl {
+			return fmt.Errorf("runner: %s: template cannot use its own output", tmpl.Source())
+		}
+	}
+	return nil
+}
+
+// checkTemplateOutputCycles returns an error if templates use the output of
+// each other in a cycle, which would keep all of them waiting for the others
+// to render first. Only the names given to templateOutput as literals are
+// known before the templates execute.
+func (r *Runner) checkTemplateOutputCycles() error {
+	// Names of the templates whose output each template uses.
+	uses := make(map[*template.Template][]string, len(r.templates))
+	for _, tmpl := range r.templates {
+		uses[tmpl] = tmpl.TemplateOutputs()
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*template.Template]int, len(r.templates))
+
+	// visit walks the templates used by the given template depth first. The
+	// stack holds the templates being visited, and path the names by which
+	// each one was reached from the one before it. It returns the names of a
+	// cycle, if any, ending with the name of the template it starts from.
+	var stack []*template.Template
+	var path []string
+	var visit func(*template.Template) []string
+	visit = func(tmpl *template.Template) []string {
+		state[tmpl] = visiting
+		stack = append(stack, tmpl)
+		for _, name := range uses[tmpl] {
+			// Unknown names and the template's own output are reported
+			// when it renders.
+			other, ok := r.namedTemplates[name]
+			if !ok || other == tmpl {
+				continue
+			}
+			path = append(path, name)
+			switch state[other] {
+			case visiting:
+				for i, t := range stack {
+					if t == other {
+						return path[i:]
+					}
+				}
+			case 0:
+				if cycle := visit(other); cycle != nil {
+					return cycle
+				}
+			}
+			path = path[:len(path)-1]
+		}
+		stack = stack[:len(stack)-1]
+		state[tmpl] = visited
+		return nil
+	}
+
+	for _, tmpl := range r.templates {
+		if state[tmpl] != 0 {
+			continue
+		}
+		if cycle := visit(tmpl); cycle != nil {
+			names := append([]string{cycle[len(cycle)-1]}, cycle...)
+			return fmt.Errorf("runner: templates cannot use each other's output, "+
+				"their templateOutput calls form a cycle through %s",
+				strings.Join(names, " -> "))
+		}
+	}
+	return nil
+}
+
+// publishOutput supplies the rendered output of the given template to the
+// templates which use it by the names of its configurations. If a template
+// which already ran in the current run uses output which changed, another run
+// is triggered, since it is ordered before the template it depends on.
+func (r *Runner) publishOutput(tmpl *template.Template, output []byte, depsMap map[string]dep.Dependency) {
+	for _, c := range r.templateConfigsFor(tmpl) {
+		if c.Name == nil || *c.Name == "" {
+			continue
+		}
+
+		d, err := dep.NewTemplateOutputQuery(*c.Name)
+		if err != nil {
+			continue
+		}
+
+		last, ok := r.brain.Recall(d)
+		if ok && last.(string) == string(output) {
+			continue
+		}
+		r.brain.Remember(d, string(output))
+
+		if _, ok := depsMap[d.String()]; ok {
+			log.Printf("[DEBUG] (runner) %s changed, triggering another run", d)
+			select {
+			case r.outputCh <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
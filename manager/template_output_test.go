@@ -0,0 +1,154 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+func TestRunner_templateOutput(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "backends.src")
+	backends := filepath.Join(dir, "backends.json")
+	final := filepath.Join(dir, "final.cfg")
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Templates: &config.TemplateConfigs{
+			// The template using the output comes first, so it is missing the
+			// output in the run which renders it.
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ range templateOutput "backends" | parseJSON }}server {{ . }}{{ end }}`),
+				Destination: config.String(final),
+			},
+			&config.TemplateConfig{
+				Name:        config.String("backends"),
+				Contents:    config.String(fmt.Sprintf(`{{ file %q | split "," | toJSON }}`, source)),
+				Destination: config.String(backends),
+			},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	d, err := dep.NewFileQuery(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	r.Receive(d, "a")
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The changed output requests another run, which renders the template
+	// using it.
+	select {
+	case <-r.outputCh:
+	default:
+		t.Fatal("expected another run to be requested")
+	}
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "server a"; string(b) != exp {
+		t.Errorf("expected %q, got %q", exp, b)
+	}
+}
+
+func TestRunner_checkTemplateOutputs(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		contents string
+		err      string
+	}{
+		{
+			"unknown",
+			`{{ templateOutput "nope" }}`,
+			`no template is named "nope"`,
+		},
+		{
+			"own",
+			`{{ templateOutput "self" }}`,
+			"cannot use its own output",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Name:     config.String("self"),
+						Contents: config.String(tc.contents),
+					},
+				},
+			})
+			c.Finalize()
+
+			r, err := NewRunner(c, true, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Run()
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestRunner_templateOutputCycle(t *testing.T) {
+	t.Parallel()
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Name:     config.String("a"),
+				Contents: config.String(`{{ templateOutput "b" }}`),
+			},
+			&config.TemplateConfig{
+				Name:     config.String("b"),
+				Contents: config.String(`{{ with templateOutput "c" }}{{ . }}{{ end }}`),
+			},
+			&config.TemplateConfig{
+				Name:     config.String("c"),
+				Contents: config.String(`{{ templateOutput "b" | toUpper }}`),
+			},
+		},
+	})
+	c.Finalize()
+
+	_, err := NewRunner(c, true, true)
+	if exp := "cycle through b -> c -> b"; err == nil || !strings.Contains(err.Error(), exp) {
+		t.Errorf("expected error containing %q, got %v", exp, err)
+	}
+}
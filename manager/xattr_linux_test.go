@@ -0,0 +1,71 @@
+// +build linux
+
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/hashicorp/consul-template/internal/xattr"
+)
+
+func TestAtomicWrite_preserveXattrs(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	file := filepath.Join(outDir, "out")
+	if err := ioutil.WriteFile(file, []byte("before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Setxattr(file, "user.consul-template", []byte("kept"), 0); err != nil {
+		t.Skipf("extended attributes are not supported: %s", err)
+	}
+
+	cases := []struct {
+		name     string
+		preserve bool
+		exp      string
+	}{
+		{
+			"disabled",
+			false,
+			"",
+		},
+		{
+			"enabled",
+			true,
+			"kept",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := syscall.Setxattr(file, "user.consul-template", []byte("kept"), 0); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := atomicWrite(&RenderInput{
+				Contents:       []byte(tc.name),
+				Path:           file,
+				Perms:          0644,
+				PreserveXattrs: tc.preserve,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			value, err := xattr.Get(file, "user.consul-template")
+			if err != nil && err != syscall.ENODATA {
+				t.Fatal(err)
+			}
+			if string(value) != tc.exp {
+				t.Errorf("expected %q, got %q", tc.exp, value)
+			}
+		})
+	}
+}
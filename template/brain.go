@@ -40,7 +40,9 @@ func (b *Brain) Remember(d dep.Dependency, data interface{}) {
 	b.receivedData[d.String()] = struct{}{}
 }
 
-// Recall gets the current value for the given dependency in the Brain.
+// Recall gets the current value for the given dependency in the Brain. The
+// value is not copied, and parts of it may be shared with the values of other
+// dependencies, so it must not be modified.
 func (b *Brain) Recall(d dep.Dependency) (interface{}, bool) {
 	b.RLock()
 	defer b.RUnlock()
@@ -0,0 +1,188 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultEngine is the name of the engine used when a template does not
+	// specify one.
+	DefaultEngine = "gotemplate"
+)
+
+var (
+	// enginesLock protects the engines registry.
+	enginesLock sync.RWMutex
+
+	// engines is the registry of template engines, keyed by name.
+	engines = map[string]Engine{
+		DefaultEngine:    &goTemplateEngine{},
+		HandlebarsEngine: &handlebarsEngine{},
+	}
+)
+
+// Engine is a template language that is capable of evaluating the contents of
+// a template. Engines do not talk to Consul or Vault directly - all data is
+// retrieved through the functions in the EngineInput, which record the
+// dependencies that the template uses. This allows the watcher to work the
+// same way regardless of the template syntax.
+type Engine interface {
+	// Execute parses and evaluates the given input, writing the result to the
+	// given writer.
+	Execute(w io.Writer, i *EngineInput) error
+}
+
+// EngineInput is used as input to an engine's Execute function.
+type EngineInput struct {
+	// Contents are the raw template contents.
+	Contents string
+
+	// LeftDelim and RightDelim are the template delimiters. Engines that do
+	// not support custom delimiters may ignore these values.
+	LeftDelim  string
+	RightDelim string
+
+	// Funcs returns the map of template functions. The given Go template is
+	// used by functions which need access to the parent template (such as
+	// executeTemplate) and may be nil for engines which are not based on Go
+	// templates.
+	Funcs func(*template.Template) template.FuncMap
+}
+
+// RegisterEngine adds the given engine to the registry under the given name.
+// It returns an error if an engine is already registered with that name.
+func RegisterEngine(name string, e Engine) error {
+	enginesLock.Lock()
+	defer enginesLock.Unlock()
+
+	if _, ok := engines[name]; ok {
+		return fmt.Errorf("engine %q is already registered", name)
+	}
+	engines[name] = e
+	return nil
+}
+
+// Engines returns the sorted list of registered engine names.
+func Engines() []string {
+	enginesLock.RLock()
+	defer enginesLock.RUnlock()
+
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupEngine returns the engine with the given name. An empty name returns
+// the default engine.
+func lookupEngine(name string) (Engine, error) {
+	if name == "" {
+		name = DefaultEngine
+	}
+
+	enginesLock.RLock()
+	defer enginesLock.RUnlock()
+
+	e, ok := engines[name]
+	if !ok {
+		return nil, fmt.Errorf("template: unknown engine %q", name)
+	}
+	return e, nil
+}
+
+// goTemplateEngine is the engine for Go's text/template.
+type goTemplateEngine struct{}
+
+// Execute implements the Engine interface.
+func (e *goTemplateEngine) Execute(w io.Writer, i *EngineInput) error {
+	tmpl := template.New("")
+	tmpl.Delims(i.LeftDelim, i.RightDelim)
+	if i.Funcs != nil {
+		tmpl.Funcs(i.Funcs(tmpl))
+	}
+
+	tmpl, err := tmpl.Parse(i.Contents)
+	if err != nil {
+		return errors.Wrap(err, "parse")
+	}
+
+	if err := tmpl.Execute(w, nil); err != nil {
+		return errors.Wrap(err, "execute")
+	}
+
+	return nil
+}
+
+// goTemplateOutputs returns the literal names given to the templateOutput
+// function by the given Go template contents, or nil if they do not parse.
+func goTemplateOutputs(contents, left, right string) []string {
+	tmpl := template.New("")
+	tmpl.Delims(left, right)
+	tmpl.Funcs(funcMap(&funcMapInput{}))
+	if _, err := tmpl.Parse(contents); err != nil {
+		return nil
+	}
+
+	var names []string
+	var walk func(parse.Node)
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, n := range n.Nodes {
+				walk(n)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Cmds {
+				walk(c)
+			}
+		case *parse.CommandNode:
+			if len(n.Args) > 1 {
+				ident, ok := n.Args[0].(*parse.IdentifierNode)
+				s, isString := n.Args[1].(*parse.StringNode)
+				if ok && isString && ident.Ident == "templateOutput" {
+					names = append(names, s.Text)
+				}
+			}
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root)
+		}
+	}
+	return names
+}
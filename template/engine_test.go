@@ -0,0 +1,85 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// testEngine is an engine which renders the value of a single key using the
+// "key" function from the function map.
+type testEngine struct{}
+
+func (e *testEngine) Execute(w io.Writer, i *EngineInput) error {
+	f, ok := i.Funcs(nil)["key"].(func(string) (string, error))
+	if !ok {
+		return fmt.Errorf("missing key function")
+	}
+	v, err := f(i.Contents)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, v)
+	return err
+}
+
+func TestRegisterEngine(t *testing.T) {
+	if err := RegisterEngine(DefaultEngine, &testEngine{}); err == nil {
+		t.Fatal("expected error registering duplicate engine")
+	}
+
+	if err := RegisterEngine("test", &testEngine{}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		enginesLock.Lock()
+		delete(engines, "test")
+		enginesLock.Unlock()
+	}()
+
+	expected := []string{DefaultEngine, HandlebarsEngine, "test"}
+	if names := Engines(); !reflect.DeepEqual(expected, names) {
+		t.Errorf("\nexp: %#v\nact: %#v", expected, names)
+	}
+
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: "foo",
+		Engine:   "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	def, err := NewTemplate(&NewTemplateInput{
+		Contents: "foo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.ID() == def.ID() {
+		t.Errorf("expected engine to change the template ID")
+	}
+
+	d, err := dep.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.EnableBlocking()
+
+	brain := NewBrain()
+	brain.Remember(d, "bar")
+
+	result, err := tpl.Execute(&ExecuteInput{Brain: brain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Output) != "bar" {
+		t.Errorf("expected %q to be %q", result.Output, "bar")
+	}
+	if result.Used.Len() != 1 {
+		t.Errorf("expected 1 used dependency, got %d", result.Used.Len())
+	}
+}
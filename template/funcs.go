@@ -2,21 +2,39 @@ package template
 
 import (
 	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
+	"unicode/utf16"
 
 	"github.com/burntsushi/toml"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/hashicorp/consul-template/child"
 	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/logging"
+	"github.com/hashicorp/golang-lru/simplelru"
 	"github.com/pkg/errors"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -25,12 +43,29 @@ import (
 // primarily for the tests to override times.
 var now = func() time.Time { return time.Now().UTC() }
 
+const (
+	// secretsRecursiveDepth and secretsRecursiveLimit are the default bounds
+	// on the number of levels below the path secretsRecursive lists, and the
+	// number of secrets it returns.
+	secretsRecursiveDepth = 10
+	secretsRecursiveLimit = 1000
+)
+
 // datacentersFunc returns or accumulates datacenter dependencies.
-func datacentersFunc(b *Brain, used, missing *dep.Set) func() ([]string, error) {
-	return func() ([]string, error) {
+func datacentersFunc(b *Brain, used, missing *dep.Set) func(...string) ([]string, error) {
+	return func(s ...string) ([]string, error) {
 		result := []string{}
 
-		d, err := dep.NewCatalogDatacentersQuery()
+		var d *dep.CatalogDatacentersQuery
+		var err error
+		switch mode := strings.Join(s, ""); mode {
+		case "":
+			d, err = dep.NewCatalogDatacentersQuery()
+		case "healthy":
+			d, err = dep.NewHealthyCatalogDatacentersQuery()
+		default:
+			return result, fmt.Errorf("datacenters: invalid mode %q", mode)
+		}
 		if err != nil {
 			return result, err
 		}
@@ -49,8 +84,9 @@ func datacentersFunc(b *Brain, used, missing *dep.Set) func() ([]string, error)
 
 // envFunc returns a function which checks the value of an environment variable.
 // Invokers can specify their own environment, which takes precedences over any
-// real environment variables
-func envFunc(env []string) func(string) (string, error) {
+// real environment variables. If only is true, the real environment variables
+// are not read at all.
+func envFunc(env []string, only bool) func(string) (string, error) {
 	return func(s string) (string, error) {
 		for _, e := range env {
 			split := strings.SplitN(e, "=", 2)
@@ -59,6 +95,9 @@ func envFunc(env []string) func(string) (string, error) {
 				return v, nil
 			}
 		}
+		if only {
+			return "", nil
+		}
 		return os.Getenv(s), nil
 	}
 }
@@ -78,6 +117,9 @@ func executeTemplateFunc(t *template.Template) func(string, ...interface{}) (str
 			return "", fmt.Errorf("executeTemplate: wrong number of arguments, expected 1 or 2"+
 				", but got %d", len(data)+1)
 		}
+		if t == nil {
+			return "", fmt.Errorf("executeTemplate: not supported by this engine")
+		}
 		var b bytes.Buffer
 		if err := t.ExecuteTemplate(&b, s, dot); err != nil {
 			return "", err
@@ -113,6 +155,46 @@ func fileFunc(b *Brain, used, missing *dep.Set) func(string) (string, error) {
 	}
 }
 
+// fileExistsFunc returns or accumulates file stat dependencies, returning true
+// if a file exists at the path.
+func fileExistsFunc(b *Brain, used, missing *dep.Set) func(string) (bool, error) {
+	return func(s string) (bool, error) {
+		stat, err := statFunc(b, used, missing)(s)
+		if err != nil {
+			return false, err
+		}
+		return stat != nil, nil
+	}
+}
+
+// statFunc returns or accumulates file stat dependencies, returning the stat
+// of the file at the path, or nil if it does not exist.
+func statFunc(b *Brain, used, missing *dep.Set) func(string) (*dep.FileStat, error) {
+	return func(s string) (*dep.FileStat, error) {
+		if len(s) == 0 {
+			return nil, nil
+		}
+
+		d, err := dep.NewFileStatQuery(s)
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			if stat, ok := value.(*dep.FileStat); ok && stat.Exists {
+				return stat, nil
+			}
+			return nil, nil
+		}
+
+		missing.Add(d)
+
+		return nil, nil
+	}
+}
+
 // keyFunc returns or accumulates key dependencies.
 func keyFunc(b *Brain, used, missing *dep.Set) func(string) (string, error) {
 	return func(s string) (string, error) {
@@ -141,6 +223,47 @@ func keyFunc(b *Brain, used, missing *dep.Set) func(string) (string, error) {
 	}
 }
 
+// keyCascadeFunc returns the value of the key under the first of the prefixes
+// which contains it, so more specific prefixes take precedence over general
+// ones, or the empty string if none does. Each prefix is a dependency, so the
+// value changes as soon as the key is added to or removed from any of them.
+func keyCascadeFunc(b *Brain, used, missing *dep.Set) func(string, ...string) (string, error) {
+	return func(s string, prefixes ...string) (string, error) {
+		if len(prefixes) == 0 {
+			return "", fmt.Errorf("keyCascade: expected at least one prefix")
+		}
+
+		if len(s) == 0 {
+			return "", nil
+		}
+
+		// Until the data of a prefix is known, the prefixes after it cannot take
+		// precedence, but they are still fetched in the same pass.
+		var value string
+		var done bool
+		for _, prefix := range prefixes {
+			d, err := dep.NewKVGetQuery(path.Join(prefix, s))
+			if err != nil {
+				return "", err
+			}
+
+			used.Add(d)
+
+			v, ok := b.Recall(d)
+			if !ok {
+				missing.Add(d)
+				done = true
+				continue
+			}
+			if !done && v != nil {
+				value, done = v.(string), true
+			}
+		}
+
+		return value, nil
+	}
+}
+
 // keyExistsFunc returns true if a key exists, false otherwise.
 func keyExistsFunc(b *Brain, used, missing *dep.Set) func(string) (bool, error) {
 	return func(s string) (bool, error) {
@@ -193,16 +316,214 @@ func keyWithDefaultFunc(b *Brain, used, missing *dep.Set) func(string, string) (
 	}
 }
 
+// keyWithFallbackDCFunc returns the value of the key in the first of the
+// datacenters which has it, or the empty string if none does. An empty
+// datacenter is the local one. Only the datacenters up to the first which has
+// the key are watched, since the later ones cannot change the value; if the
+// key is removed from that datacenter, its watch renders the template again
+// and the next datacenters are watched then.
+func keyWithFallbackDCFunc(b *Brain, used, missing *dep.Set) func(string, ...string) (string, error) {
+	return func(s string, dcs ...string) (string, error) {
+		if len(dcs) == 0 {
+			return "", fmt.Errorf("keyWithFallbackDC: expected at least one datacenter")
+		}
+
+		if len(s) == 0 {
+			return "", nil
+		}
+
+		if strings.Contains(s, "@") {
+			return "", fmt.Errorf("keyWithFallbackDC: key %q must not include a "+
+				"datacenter", s)
+		}
+
+		for _, dc := range dcs {
+			key := s
+			if dc != "" {
+				key = s + "@" + dc
+			}
+
+			d, err := dep.NewKVGetQuery(key)
+			if err != nil {
+				return "", err
+			}
+
+			used.Add(d)
+
+			// Until the data of a datacenter is known, the ones after it cannot
+			// be used, so they are not fetched yet.
+			v, ok := b.Recall(d)
+			if !ok {
+				missing.Add(d)
+				return "", nil
+			}
+			if v != nil {
+				return v.(string), nil
+			}
+		}
+
+		return "", nil
+	}
+}
+
+// typedKey returns the value of a key for the typed key functions, falling
+// back to the optional default if the key is missing or empty. Without a
+// default, it waits for the key to exist like key. The returned bool is false
+// if there is no value to parse yet.
+func typedKey(b *Brain, used, missing *dep.Set, s string, def []string) (string, bool, error) {
+	if len(def) > 1 {
+		return "", false, fmt.Errorf("key %q: expected at most one default, got %d", s, len(def))
+	}
+
+	if len(s) == 0 {
+		if len(def) > 0 {
+			return def[0], true, nil
+		}
+		return "", false, nil
+	}
+
+	d, err := dep.NewKVGetQuery(s)
+	if err != nil {
+		return "", false, err
+	}
+	if len(def) == 0 {
+		d.EnableBlocking()
+	}
+
+	used.Add(d)
+
+	if value, ok := b.Recall(d); ok {
+		if value != nil && value.(string) != "" {
+			return value.(string), true, nil
+		}
+		if len(def) > 0 {
+			return def[0], true, nil
+		}
+		return "", true, nil
+	}
+
+	missing.Add(d)
+
+	if len(def) > 0 {
+		return def[0], true, nil
+	}
+	return "", false, nil
+}
+
+// keyIntFunc returns the value of a key parsed as an integer, or the optional
+// default if the key is missing or empty.
+func keyIntFunc(b *Brain, used, missing *dep.Set) func(string, ...string) (int64, error) {
+	return func(s string, def ...string) (int64, error) {
+		v, ok, err := typedKey(b, used, missing, s, def)
+		if err != nil || !ok {
+			return 0, err
+		}
+
+		i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("keyInt: key %q: invalid integer %q", s, v)
+		}
+		return i, nil
+	}
+}
+
+// keyBoolFunc returns the value of a key parsed as a boolean, or the optional
+// default if the key is missing or empty.
+func keyBoolFunc(b *Brain, used, missing *dep.Set) func(string, ...string) (bool, error) {
+	return func(s string, def ...string) (bool, error) {
+		v, ok, err := typedKey(b, used, missing, s, def)
+		if err != nil || !ok {
+			return false, err
+		}
+
+		result, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, fmt.Errorf("keyBool: key %q: invalid boolean %q", s, v)
+		}
+		return result, nil
+	}
+}
+
+// keyDurationFunc returns the value of a key parsed as a duration, or the
+// optional default if the key is missing or empty.
+func keyDurationFunc(b *Brain, used, missing *dep.Set) func(string, ...string) (time.Duration, error) {
+	return func(s string, def ...string) (time.Duration, error) {
+		v, ok, err := typedKey(b, used, missing, s, def)
+		if err != nil || !ok {
+			return 0, err
+		}
+
+		result, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("keyDuration: key %q: invalid duration %q", s, v)
+		}
+		return result, nil
+	}
+}
+
+// keyJSONFunc returns the value of a key parsed as JSON, or the optional
+// default, which is also JSON, if the key is missing or empty.
+func keyJSONFunc(b *Brain, used, missing *dep.Set) func(string, ...string) (interface{}, error) {
+	return func(s string, def ...string) (interface{}, error) {
+		v, ok, err := typedKey(b, used, missing, s, def)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return map[string]interface{}{}, nil
+		}
+
+		var result interface{}
+		if err := json.Unmarshal([]byte(v), &result); err != nil {
+			return nil, fmt.Errorf("keyJSON: key %q: invalid JSON: %s", s, err)
+		}
+		return result, nil
+	}
+}
+
+// lockHolderFunc returns the holder of the lock on a key, or nil if the key is
+// not locked.
+func lockHolderFunc(b *Brain, used, missing *dep.Set) func(string) (*dep.KVLock, error) {
+	return func(s string) (*dep.KVLock, error) {
+		if len(s) == 0 {
+			return nil, nil
+		}
+
+		d, err := dep.NewKVLockQuery(s)
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			if value == nil {
+				return nil, nil
+			}
+			return value.(*dep.KVLock), nil
+		}
+
+		missing.Add(d)
+
+		return nil, nil
+	}
+}
+
 // lsFunc returns or accumulates keyPrefix dependencies.
-func lsFunc(b *Brain, used, missing *dep.Set) func(string) ([]*dep.KeyPair, error) {
-	return func(s string) ([]*dep.KeyPair, error) {
+func lsFunc(b *Brain, used, missing *dep.Set) func(string, ...string) ([]*dep.KeyPair, error) {
+	return func(s string, opts ...string) ([]*dep.KeyPair, error) {
 		result := []*dep.KeyPair{}
 
 		if len(s) == 0 {
 			return result, nil
 		}
 
-		d, err := dep.NewKVListQuery(s)
+		exclude, err := kvListExclude(opts)
+		if err != nil {
+			return result, err
+		}
+
+		d, err := dep.NewKVListQuery(s, exclude...)
 		if err != nil {
 			return result, err
 		}
@@ -225,6 +546,24 @@ func lsFunc(b *Brain, used, missing *dep.Set) func(string) ([]*dep.KeyPair, erro
 	}
 }
 
+// kvListExclude parses the "exclude=<pattern>,<pattern>" options of ls and
+// tree into the patterns of keys to exclude.
+func kvListExclude(opts []string) ([]string, error) {
+	var exclude []string
+	for _, opt := range opts {
+		parts := strings.SplitN(opt, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) != "exclude" {
+			return nil, fmt.Errorf("unknown option %q", opt)
+		}
+		for _, p := range strings.Split(parts[1], ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				exclude = append(exclude, p)
+			}
+		}
+	}
+	return exclude, nil
+}
+
 // nodeFunc returns or accumulates catalog node dependency.
 func nodeFunc(b *Brain, used, missing *dep.Set) func(...string) (*dep.CatalogNode, error) {
 	return func(s ...string) (*dep.CatalogNode, error) {
@@ -268,6 +607,30 @@ func nodesFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.Node, e
 	}
 }
 
+// runnersFunc returns the running instances of Consul Template which
+// registered themselves in the given service, or the default one, sorted by
+// ID.
+func runnersFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.TemplateRunner, error) {
+	return func(s ...string) ([]*dep.TemplateRunner, error) {
+		result := []*dep.TemplateRunner{}
+
+		d, err := dep.NewRunnersQuery(strings.Join(s, ""))
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]*dep.TemplateRunner), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
 // secretFunc returns or accumulates secret dependencies from Vault.
 func secretFunc(b *Brain, used, missing *dep.Set) func(...string) (*dep.Secret, error) {
 	return func(s ...string) (*dep.Secret, error) {
@@ -316,6 +679,27 @@ func secretFunc(b *Brain, used, missing *dep.Set) func(...string) (*dep.Secret,
 	}
 }
 
+// generateSecretFunc returns or accumulates a secret from Vault, which is
+// generated according to the spec and written if it does not exist yet.
+func generateSecretFunc(b *Brain, used, missing *dep.Set) func(string, ...string) (string, error) {
+	return func(path string, spec ...string) (string, error) {
+		d, err := dep.NewVaultGenerateQuery(path, spec...)
+		if err != nil {
+			return "", err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.(string), nil
+		}
+
+		missing.Add(d)
+
+		return "", nil
+	}
+}
+
 // secretsFunc returns or accumulates a list of secret dependencies from Vault.
 func secretsFunc(b *Brain, used, missing *dep.Set) func(string) ([]string, error) {
 	return func(s string) ([]string, error) {
@@ -343,16 +727,101 @@ func secretsFunc(b *Brain, used, missing *dep.Set) func(string) ([]string, error
 	}
 }
 
-// serviceFunc returns or accumulates health service dependencies.
-func serviceFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.HealthService, error) {
-	return func(s ...string) ([]*dep.HealthService, error) {
-		result := []*dep.HealthService{}
+// secretsRecursiveFunc returns or accumulates list dependencies for the given
+// Vault path and each path below it, returning the full paths of all secrets
+// in the subtree, sorted. The options "depth=N" and "limit=N" bound how many
+// levels below the path are listed and how many secrets are returned.
+func secretsRecursiveFunc(b *Brain, used, missing *dep.Set) func(string, ...string) ([]string, error) {
+	return func(s string, opts ...string) ([]string, error) {
+		var result []string
 
-		if len(s) == 0 || s[0] == "" {
+		depth, limit := secretsRecursiveDepth, secretsRecursiveLimit
+		for _, opt := range opts {
+			parts := strings.SplitN(opt, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("secretsRecursive: invalid option %q, must be "+
+					"\"key=value\"", opt)
+			}
+			n, err := strconv.Atoi(parts[1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("secretsRecursive: invalid value for %s: %q",
+					parts[0], parts[1])
+			}
+			switch parts[0] {
+			case "depth":
+				depth = n
+			case "limit":
+				limit = n
+			default:
+				return nil, fmt.Errorf("secretsRecursive: unknown option %q", parts[0])
+			}
+		}
+
+		s = strings.Trim(strings.TrimSpace(s), "/")
+		if len(s) == 0 {
 			return result, nil
 		}
 
-		d, err := dep.NewHealthServiceQuery(strings.Join(s, "|"))
+		type level struct {
+			path  string
+			depth int
+		}
+
+		queue := []level{{path: s}}
+		for len(queue) > 0 {
+			l := queue[0]
+			queue = queue[1:]
+
+			d, err := dep.NewVaultListQuery(l.path)
+			if err != nil {
+				return nil, err
+			}
+
+			used.Add(d)
+
+			value, ok := b.Recall(d)
+			if !ok {
+				missing.Add(d)
+				continue
+			}
+
+			for _, key := range value.([]string) {
+				if strings.HasSuffix(key, "/") {
+					if l.depth < depth {
+						queue = append(queue, level{
+							path:  l.path + "/" + strings.TrimSuffix(key, "/"),
+							depth: l.depth + 1,
+						})
+					}
+					continue
+				}
+
+				if len(result) == limit {
+					log.Printf("[WARN] (template) secretsRecursive: %s has more than "+
+						"%d secrets, ignoring the rest", s, limit)
+					sort.Strings(result)
+					return result, nil
+				}
+				result = append(result, l.path+"/"+key)
+			}
+		}
+
+		sort.Strings(result)
+		return result, nil
+	}
+}
+
+// secretVersionsFunc returns or accumulates the latest versions of a KV v2
+// secret from Vault, latest first.
+func secretVersionsFunc(b *Brain, used, missing *dep.Set) func(string, int) ([]*dep.Secret, error) {
+	return func(s string, n int) ([]*dep.Secret, error) {
+		var result []*dep.Secret
+
+		if len(s) == 0 {
+			return result, nil
+		}
+
+		d, err := dep.NewVaultVersionsQuery(s, n)
 		if err != nil {
 			return nil, err
 		}
@@ -360,7 +829,8 @@ func serviceFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.Healt
 		used.Add(d)
 
 		if value, ok := b.Recall(d); ok {
-			return value.([]*dep.HealthService), nil
+			result = value.([]*dep.Secret)
+			return result, nil
 		}
 
 		missing.Add(d)
@@ -369,12 +839,11 @@ func serviceFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.Healt
 	}
 }
 
-// servicesFunc returns or accumulates catalog services dependencies.
-func servicesFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.CatalogSnippet, error) {
-	return func(s ...string) ([]*dep.CatalogSnippet, error) {
-		result := []*dep.CatalogSnippet{}
-
-		d, err := dep.NewCatalogServicesQuery(strings.Join(s, ""))
+// selfTokenFunc returns or accumulates the dependency on the Consul ACL token
+// in use. Until it is fetched, an empty token without policies is returned.
+func selfTokenFunc(b *Brain, used, missing *dep.Set) func() (*dep.ACLToken, error) {
+	return func() (*dep.ACLToken, error) {
+		d, err := dep.NewACLTokenSelfQuery()
 		if err != nil {
 			return nil, err
 		}
@@ -382,50 +851,414 @@ func servicesFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.Cata
 		used.Add(d)
 
 		if value, ok := b.Recall(d); ok {
-			return value.([]*dep.CatalogSnippet), nil
+			return value.(*dep.ACLToken), nil
 		}
 
 		missing.Add(d)
 
-		return result, nil
+		return &dep.ACLToken{}, nil
 	}
 }
 
-// treeFunc returns or accumulates keyPrefix dependencies.
-func treeFunc(b *Brain, used, missing *dep.Set) func(string) ([]*dep.KeyPair, error) {
-	return func(s string) ([]*dep.KeyPair, error) {
-		result := []*dep.KeyPair{}
-
-		if len(s) == 0 {
-			return result, nil
-		}
-
-		d, err := dep.NewKVListQuery(s)
+// configEntryFunc returns or accumulates the dependency on a Consul central
+// config entry of any kind. Until it is fetched, or if it does not exist, nil
+// is returned.
+func configEntryFunc(b *Brain, used, missing *dep.Set) func(string, ...string) (*dep.ConfigEntry, error) {
+	return func(kind string, s ...string) (*dep.ConfigEntry, error) {
+		d, err := dep.NewConfigEntryQuery(kind, strings.Join(s, ""))
 		if err != nil {
-			return result, err
+			return nil, err
 		}
 
 		used.Add(d)
 
-		// Only return non-empty top-level keys
 		if value, ok := b.Recall(d); ok {
-			for _, pair := range value.([]*dep.KeyPair) {
-				parts := strings.Split(pair.Key, "/")
-				if parts[len(parts)-1] != "" {
-					result = append(result, pair)
-				}
+			if value == nil {
+				return nil, nil
 			}
-			return result, nil
+			return value.(*dep.ConfigEntry), nil
 		}
 
 		missing.Add(d)
 
-		return result, nil
+		return nil, nil
 	}
 }
 
-// base64Decode decodes the given string as a base64 string, returning an error
-// if it fails.
+// configEntryKindFunc returns configEntryFunc for the config entries of the
+// given kind.
+func configEntryKindFunc(b *Brain, used, missing *dep.Set, kind string) func(...string) (*dep.ConfigEntry, error) {
+	f := configEntryFunc(b, used, missing)
+	return func(s ...string) (*dep.ConfigEntry, error) {
+		return f(kind, s...)
+	}
+}
+
+// proxyDefaultsFunc returns configEntryFunc for the global proxy-defaults
+// config entry, in the given datacenter if any.
+func proxyDefaultsFunc(b *Brain, used, missing *dep.Set) func(...string) (*dep.ConfigEntry, error) {
+	f := configEntryFunc(b, used, missing)
+	return func(s ...string) (*dep.ConfigEntry, error) {
+		return f(dep.ConfigEntryProxyDefaults, append([]string{"global"}, s...)...)
+	}
+}
+
+// caLeafFunc returns or accumulates the dependency on the Connect leaf
+// certificate of a service.
+func caLeafFunc(b *Brain, used, missing *dep.Set) func(string) (*dep.LeafCert, error) {
+	return func(s string) (*dep.LeafCert, error) {
+		d, err := dep.NewConnectLeafQuery(s)
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.(*dep.LeafCert), nil
+		}
+
+		missing.Add(d)
+
+		return nil, nil
+	}
+}
+
+// caRootsFunc returns or accumulates the dependency on the Connect CA roots.
+func caRootsFunc(b *Brain, used, missing *dep.Set) func() ([]*dep.CARoot, error) {
+	return func() ([]*dep.CARoot, error) {
+		d, err := dep.NewConnectCAQuery()
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]*dep.CARoot), nil
+		}
+
+		missing.Add(d)
+
+		return nil, nil
+	}
+}
+
+// checksFunc returns or accumulates health checks dependencies.
+func checksFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.HealthCheck, error) {
+	return func(s ...string) ([]*dep.HealthCheck, error) {
+		result := []*dep.HealthCheck{}
+
+		if len(s) == 0 || s[0] == "" {
+			return result, nil
+		}
+
+		d, err := dep.NewHealthChecksQuery(strings.Join(s, "|"))
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]*dep.HealthCheck), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// serviceFunc returns or accumulates health service dependencies.
+func serviceFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.HealthService, error) {
+	return func(s ...string) ([]*dep.HealthService, error) {
+		result := []*dep.HealthService{}
+
+		if len(s) == 0 || s[0] == "" {
+			return result, nil
+		}
+
+		d, err := dep.NewHealthServiceQuery(strings.Join(s, "|"))
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]*dep.HealthService), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// servicesFunc returns or accumulates catalog services dependencies.
+func servicesFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.CatalogSnippet, error) {
+	return func(s ...string) ([]*dep.CatalogSnippet, error) {
+		result := []*dep.CatalogSnippet{}
+
+		d, err := dep.NewCatalogServicesQuery(strings.Join(s, ""))
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]*dep.CatalogSnippet), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// serviceCountFunc returns the number of instances of a service in the given
+// health states, accumulating health service summary dependencies.
+func serviceCountFunc(b *Brain, used, missing *dep.Set) func(...string) (int, error) {
+	return func(s ...string) (int, error) {
+		if len(s) == 0 || s[0] == "" {
+			return 0, nil
+		}
+
+		// The filter is applied to the summary, so every filter shares the same
+		// dependency.
+		parts := strings.SplitN(strings.Join(s, "|"), "|", 2)
+		d, err := dep.NewHealthServiceSummaryQuery(parts[0])
+		if err != nil {
+			return 0, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			var filter string
+			if len(parts) > 1 {
+				filter = parts[1]
+			}
+			return value.(*dep.HealthServiceSummary).Count(filter)
+		}
+
+		missing.Add(d)
+
+		return 0, nil
+	}
+}
+
+// serviceHealthSummaryFunc returns or accumulates health service summary
+// dependencies.
+func serviceHealthSummaryFunc(b *Brain, used, missing *dep.Set) func(string) (*dep.HealthServiceSummary, error) {
+	return func(s string) (*dep.HealthServiceSummary, error) {
+		result := &dep.HealthServiceSummary{}
+
+		if len(s) == 0 {
+			return result, nil
+		}
+
+		d, err := dep.NewHealthServiceSummaryQuery(s)
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.(*dep.HealthServiceSummary), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// templateOutputFunc returns or accumulates template output dependencies,
+// returning the rendered output of the template with the given name.
+func templateOutputFunc(b *Brain, used, missing *dep.Set) func(string) (string, error) {
+	return func(s string) (string, error) {
+		d, err := dep.NewTemplateOutputQuery(s)
+		if err != nil {
+			return "", err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.(string), nil
+		}
+
+		missing.Add(d)
+
+		return "", nil
+	}
+}
+
+// treeFunc returns or accumulates keyPrefix dependencies.
+func treeFunc(b *Brain, used, missing *dep.Set) func(string, ...string) ([]*dep.KeyPair, error) {
+	return func(s string, opts ...string) ([]*dep.KeyPair, error) {
+		result := []*dep.KeyPair{}
+
+		if len(s) == 0 {
+			return result, nil
+		}
+
+		exclude, err := kvListExclude(opts)
+		if err != nil {
+			return result, err
+		}
+
+		d, err := dep.NewKVListQuery(s, exclude...)
+		if err != nil {
+			return result, err
+		}
+
+		used.Add(d)
+
+		// Only return non-empty top-level keys
+		if value, ok := b.Recall(d); ok {
+			for _, pair := range value.([]*dep.KeyPair) {
+				parts := strings.Split(pair.Key, "/")
+				if parts[len(parts)-1] != "" {
+					result = append(result, pair)
+				}
+			}
+			return result, nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// varFunc returns or accumulates the file dependency of the template's vars
+// file, returning the value of the given key. Nested keys are separated by
+// dots. The optional default is returned if the key does not exist, which is
+// an error otherwise.
+func varFunc(b *Brain, used, missing *dep.Set, varsFile string, c *varsCache) func(string, ...interface{}) (interface{}, error) {
+	return func(key string, def ...interface{}) (interface{}, error) {
+		if varsFile == "" {
+			return nil, fmt.Errorf("var: template has no vars_file")
+		}
+		if len(def) > 1 {
+			return nil, fmt.Errorf("var: wrong number of args for %q: want 1 or 2, got %d",
+				key, len(def)+1)
+		}
+
+		d, err := dep.NewFileQuery(varsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		value, ok := b.Recall(d)
+		if !ok {
+			missing.Add(d)
+			return "", nil
+		}
+
+		s, _ := value.(string)
+		vars, err := c.parse(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "var: failed parsing %s", varsFile)
+		}
+
+		var result interface{} = vars
+		for _, part := range strings.Split(key, ".") {
+			m, ok := result.(map[string]interface{})
+			if ok {
+				result, ok = m[part]
+			}
+			if !ok {
+				if len(def) > 0 {
+					return def[0], nil
+				}
+				return nil, fmt.Errorf("var: %q not found in %s", key, varsFile)
+			}
+		}
+		return result, nil
+	}
+}
+
+// varsCache is the parsed contents of a vars file. Each template has its own
+// cache, so the file is only parsed again when its contents change, no matter
+// how many times the template is rendered. The zero value is ready to use.
+type varsCache struct {
+	sync.Mutex
+	contents string
+	vars     map[string]interface{}
+}
+
+// parse returns the vars of the given contents, parsing and caching them if
+// the contents changed. A nil cache parses the contents every time.
+func (c *varsCache) parse(s string) (map[string]interface{}, error) {
+	if c == nil {
+		return parseVars(s)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.vars != nil && c.contents == s {
+		return c.vars, nil
+	}
+
+	vars, err := parseVars(s)
+	if err != nil {
+		return nil, err
+	}
+	c.contents, c.vars = s, vars
+	return vars, nil
+}
+
+// parseVars parses the YAML or JSON contents of a vars file, converting the
+// maps with arbitrary keys which YAML decodes to into maps with string keys.
+func parseVars(s string) (map[string]interface{}, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	vars, ok := stringKeys(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("vars must be a map, got %T", raw)
+	}
+	return vars, nil
+}
+
+// stringKeys returns the given value with every nested map converted to a map
+// with string keys.
+func stringKeys(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		m, _ := stringMap(t)
+		r := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			r[k] = stringKeys(v)
+		}
+		return r
+	case []interface{}:
+		r := make([]interface{}, len(t))
+		for i, v := range t {
+			r[i] = stringKeys(v)
+		}
+		return r
+	default:
+		return v
+	}
+}
+
+// base64Decode decodes the given string as a base64 string, returning an error
+// if it fails.
 func base64Decode(s string) (string, error) {
 	v, err := base64.StdEncoding.DecodeString(s)
 	if err != nil {
@@ -453,6 +1286,21 @@ func base64URLEncode(s string) (string, error) {
 	return base64.URLEncoding.EncodeToString([]byte(s)), nil
 }
 
+// base64RawURLDecode decodes the given string as an unpadded URL-safe base64
+// string, such as the segments of a JWT.
+func base64RawURLDecode(s string) (string, error) {
+	v, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", errors.Wrap(err, "base64RawURLDecode")
+	}
+	return string(v), nil
+}
+
+// base64RawURLEncode encodes the given string to be URL-safe, without padding.
+func base64RawURLEncode(s string) (string, error) {
+	return base64.RawURLEncoding.EncodeToString([]byte(s)), nil
+}
+
 // byKey accepts a slice of KV pairs and returns a map of the top-level
 // key to all its subkeys. For example:
 //
@@ -557,11 +1405,57 @@ func containsSomeFunc(retTrue, invert bool) func([]interface{}, interface{}) (bo
 	}
 }
 
-// explode is used to expand a list of keypairs into a deeply-nested hash.
-func explode(pairs []*dep.KeyPair) (map[string]interface{}, error) {
+// explode is used to expand a list of keypairs into a deeply-nested hash. The
+// list is the last argument, and may be preceded by options of the form
+// "key=value": "delimiter" is the separator of the path segments of the keys,
+// which defaults to "/", and "typed", when true, converts the values which
+// look like JSON, integers, floats or booleans into those types.
+func explode(args ...interface{}) (map[string]interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("explode: missing list of key pairs")
+	}
+
+	pairs, ok := args[len(args)-1].([]*dep.KeyPair)
+	if !ok {
+		return nil, fmt.Errorf("explode: expected a list of key pairs, got %T",
+			args[len(args)-1])
+	}
+
+	delim, typed := "/", false
+	for _, arg := range args[:len(args)-1] {
+		opt, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("explode: option %v is not a string", arg)
+		}
+		parts := strings.SplitN(opt, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("explode: invalid option %q, must be "+
+				"\"key=value\"", opt)
+		}
+		switch parts[0] {
+		case "delimiter":
+			if parts[1] == "" {
+				return nil, fmt.Errorf("explode: delimiter cannot be empty")
+			}
+			delim = parts[1]
+		case "typed":
+			b, err := strconv.ParseBool(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("explode: invalid value for typed: %q", parts[1])
+			}
+			typed = b
+		default:
+			return nil, fmt.Errorf("explode: unknown option %q", parts[0])
+		}
+	}
+
 	m := make(map[string]interface{})
 	for _, pair := range pairs {
-		if err := explodeHelper(m, pair.Key, pair.Value, pair.Key); err != nil {
+		var v interface{} = pair.Value
+		if typed {
+			v = inferType(pair.Value)
+		}
+		if err := explodeHelper(m, pair.Key, v, pair.Key, delim); err != nil {
 			return nil, errors.Wrap(err, "explode")
 		}
 	}
@@ -569,20 +1463,20 @@ func explode(pairs []*dep.KeyPair) (map[string]interface{}, error) {
 }
 
 // explodeHelper is a recursive helper for explode.
-func explodeHelper(m map[string]interface{}, k, v, p string) error {
-	if strings.Contains(k, "/") {
-		parts := strings.Split(k, "/")
+func explodeHelper(m map[string]interface{}, k string, v interface{}, p, delim string) error {
+	if strings.Contains(k, delim) {
+		parts := strings.SplitN(k, delim, 2)
 		top := parts[0]
-		key := strings.Join(parts[1:], "/")
+		key := parts[1]
 
 		if _, ok := m[top]; !ok {
 			m[top] = make(map[string]interface{})
 		}
 		nest, ok := m[top].(map[string]interface{})
 		if !ok {
-			return fmt.Errorf("not a map: %q: %q already has value %q", p, top, m[top])
+			return fmt.Errorf("not a map: %q: %q already has value %#v", p, top, m[top])
 		}
-		return explodeHelper(nest, key, v, k)
+		return explodeHelper(nest, key, v, k, delim)
 	}
 
 	if k != "" {
@@ -592,6 +1486,219 @@ func explodeHelper(m map[string]interface{}, k, v, p string) error {
 	return nil
 }
 
+// inferType converts a value stored as a string into the JSON object or array,
+// integer, float or boolean it looks like, or returns it unchanged. Numbers
+// with leading zeros, such as ZIP codes, are kept as strings since converting
+// them would lose information.
+func inferType(s string) interface{} {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+
+	switch trimmed[0] {
+	case '{', '[':
+		var data interface{}
+		if err := json.Unmarshal([]byte(trimmed), &data); err == nil {
+			return data
+		}
+		return s
+	}
+
+	switch trimmed {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	// ParseFloat also accepts words like "Inf" and "NaN", which are not
+	// numbers in JSON or YAML.
+	if strings.Trim(trimmed, "0123456789.eE+-") != "" {
+		return s
+	}
+
+	digits := strings.TrimPrefix(trimmed, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return s
+	}
+	if i, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(trimmed, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// mergeMaps deeply merges the given maps, with the values of later maps taking
+// precedence. Nested maps are merged, while lists are replaced unless the
+// first argument is the list strategy "append", in which case they are
+// concatenated. Nil arguments are skipped, and the given maps are not
+// modified.
+func mergeMaps(args ...interface{}) (map[string]interface{}, error) {
+	appendLists := false
+	if len(args) > 0 {
+		if s, ok := args[0].(string); ok {
+			switch s {
+			case "append":
+				appendLists = true
+			case "replace":
+			default:
+				return nil, fmt.Errorf("mergeMaps: unknown list strategy %q, "+
+					"must be \"append\" or \"replace\"", s)
+			}
+			args = args[1:]
+		}
+	}
+
+	r := make(map[string]interface{})
+	for i, arg := range args {
+		if arg == nil {
+			continue
+		}
+		m, ok := stringMap(arg)
+		if !ok {
+			return nil, fmt.Errorf("mergeMaps: argument %d is a %T, not a map", i+1, arg)
+		}
+		mergeMapsHelper(r, m, appendLists)
+	}
+	return r, nil
+}
+
+// mergeMapsHelper is a recursive helper for mergeMaps, which merges src into
+// dst. The nested maps and lists of dst are always its own.
+func mergeMapsHelper(dst, src map[string]interface{}, appendLists bool) {
+	for k, v := range src {
+		if sm, ok := stringMap(v); ok {
+			dm, ok := dst[k].(map[string]interface{})
+			if !ok {
+				dm = make(map[string]interface{})
+				dst[k] = dm
+			}
+			mergeMapsHelper(dm, sm, appendLists)
+			continue
+		}
+
+		if sl, ok := v.([]interface{}); ok {
+			var l []interface{}
+			if dl, ok := dst[k].([]interface{}); ok && appendLists {
+				l = append(l, dl...)
+			}
+			dst[k] = append(l, sl...)
+			continue
+		}
+
+		dst[k] = v
+	}
+}
+
+// stringMap returns the given value as a map with string keys, converting the
+// maps with arbitrary keys which YAML decodes to.
+func stringMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		r := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			r[fmt.Sprintf("%v", k)] = v
+		}
+		return r, true
+	default:
+		return nil, false
+	}
+}
+
+// debugDump writes the structure of the given value to the log when the log
+// level is DEBUG or lower. It always returns an empty string, so it never
+// changes the rendered output.
+func debugDump(v interface{}) (string, error) {
+	if logging.Enabled("DEBUG") {
+		log.Printf("[DEBUG] (template) debugDump:\n%s", spew.Sdump(v))
+	}
+	return "", nil
+}
+
+// hexDecode decodes the given hexadecimal string.
+func hexDecode(s string) (string, error) {
+	v, err := hex.DecodeString(s)
+	if err != nil {
+		return "", errors.Wrap(err, "hexDecode")
+	}
+	return string(v), nil
+}
+
+// hexEncode encodes the given string as lowercase hexadecimal.
+func hexEncode(s string) (string, error) {
+	return hex.EncodeToString([]byte(s)), nil
+}
+
+// alphaNum are the characters of the strings returned by randAlphaNum and
+// stableRand.
+const alphaNum = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// uuidv4 returns a random version 4 UUID. A new UUID is returned on every
+// execution of the template.
+func uuidv4() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", errors.Wrap(err, "uuidv4")
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// randAlphaNum returns a random string of n letters and digits. A new string
+// is returned on every execution of the template.
+func randAlphaNum(n int) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("randAlphaNum: invalid length %d", n)
+	}
+
+	max := big.NewInt(int64(len(alphaNum)))
+	b := make([]byte, n)
+	for i := range b {
+		c, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", errors.Wrap(err, "randAlphaNum")
+		}
+		b[i] = alphaNum[c.Int64()]
+	}
+	return string(b), nil
+}
+
+// stableRand returns a string of n letters and digits derived from the seed,
+// which is the same for the same seed, so the output of the template only
+// changes when the seed does. The string is only as secret as the seed.
+func stableRand(seed string, n int) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("stableRand: invalid length %d", n)
+	}
+
+	// Hash the seed with a counter for as many blocks as needed, and map each
+	// byte to a character, discarding the bytes above the largest multiple of
+	// the number of characters so each character is equally likely.
+	limit := byte(256 / len(alphaNum) * len(alphaNum))
+	b := make([]byte, 0, n)
+	for counter := uint64(0); len(b) < n; counter++ {
+		h := sha256.New()
+		binary.Write(h, binary.BigEndian, counter)
+		h.Write([]byte(seed))
+		for _, c := range h.Sum(nil) {
+			if c >= limit {
+				continue
+			}
+			b = append(b, alphaNum[int(c)%len(alphaNum)])
+			if len(b) == n {
+				break
+			}
+		}
+	}
+	return string(b), nil
+}
+
 // in searches for a given value in a given interface.
 func in(l, v interface{}) (bool, error) {
 	lv := reflect.ValueOf(l)
@@ -694,6 +1801,64 @@ func trimSpace(s string) (string, error) {
 	return strings.TrimSpace(s), nil
 }
 
+// trimPrefix is a version of strings.TrimPrefix that can be piped
+func trimPrefix(prefix, s string) (string, error) {
+	return strings.TrimPrefix(s, prefix), nil
+}
+
+// trimSuffix is a version of strings.TrimSuffix that can be piped
+func trimSuffix(suffix, s string) (string, error) {
+	return strings.TrimSuffix(s, suffix), nil
+}
+
+// indent prefixes each line of the string with the given number of spaces, so
+// a multi-line value can be embedded in an indented format. Empty lines are
+// left empty instead of ending with spaces.
+func indent(spaces int, s string) (string, error) {
+	if spaces < 0 {
+		return "", fmt.Errorf("indent: negative number of spaces %d", spaces)
+	}
+
+	pad := strings.Repeat(" ", spaces)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// nindent is indent with a leading newline, so the value can start on the
+// line after the key it is given for.
+func nindent(spaces int, s string) (string, error) {
+	result, err := indent(spaces, s)
+	if err != nil {
+		return "", errors.Wrap(err, "nindent")
+	}
+	return "\n" + result, nil
+}
+
+// quote returns the value as a double quoted string, with special characters
+// escaped as in Go and JSON strings. A nil value is the empty string.
+func quote(v interface{}) (string, error) {
+	return strconv.Quote(quotedString(v)), nil
+}
+
+// squote returns the value as a single quoted string, with single quotes
+// doubled as in YAML and SQL strings. A nil value is the empty string.
+func squote(v interface{}) (string, error) {
+	return "'" + strings.Replace(quotedString(v), "'", "''", -1) + "'", nil
+}
+
+// quotedString returns the string of a value to quote.
+func quotedString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
 // parseBool parses a string into a boolean
 func parseBool(s string) (bool, error) {
 	if s == "" {
@@ -781,59 +1946,269 @@ func plugin(name string, args ...string) (string, error) {
 	cmd := exec.Command(name, jsons...)
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
-	if err := cmd.Start(); err != nil {
+	if err := child.StartCommand(cmd); err != nil {
 		return "", fmt.Errorf("exec %q: %s\n\nstdout:\n\n%s\n\nstderr:\n\n%s",
 			name, err, stdout.Bytes(), stderr.Bytes())
 	}
 
 	done := make(chan error, 1)
 	go func() {
-		done <- cmd.Wait()
+		done <- child.WaitCommand(cmd)
 	}()
 
-	select {
-	case <-time.After(30 * time.Second):
-		if cmd.Process != nil {
-			if err := cmd.Process.Kill(); err != nil {
-				return "", fmt.Errorf("exec %q: failed to kill", name)
-			}
-		}
-		<-done // Allow the goroutine to exit
-		return "", fmt.Errorf("exec %q: did not finishin 30s", name)
-	case err := <-done:
-		if err != nil {
-			return "", fmt.Errorf("exec %q: %s\n\nstdout:\n\n%s\n\nstderr:\n\n%s",
-				name, err, stdout.Bytes(), stderr.Bytes())
-		}
+	select {
+	case <-time.After(30 * time.Second):
+		if cmd.Process != nil {
+			if err := cmd.Process.Kill(); err != nil {
+				return "", fmt.Errorf("exec %q: failed to kill", name)
+			}
+		}
+		<-done // Allow the goroutine to exit
+		return "", fmt.Errorf("exec %q: did not finishin 30s", name)
+	case err := <-done:
+		if err != nil {
+			return "", fmt.Errorf("exec %q: %s\n\nstdout:\n\n%s\n\nstderr:\n\n%s",
+				name, err, stdout.Bytes(), stderr.Bytes())
+		}
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// replaceAll replaces all occurrences of a value in a string with the given
+// replacement value.
+func replaceAll(f, t, s string) (string, error) {
+	return strings.Replace(s, f, t, -1), nil
+}
+
+// regexpCache is a cache of compiled regular expressions. Each template has
+// its own cache, so expressions are only compiled once no matter how many
+// times the template is rendered. The zero value is ready to use.
+type regexpCache struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}
+
+// compile returns the compiled regular expression, compiling and caching it
+// if needed. A nil cache compiles the expression every time.
+func (c *regexpCache) compile(re string) (*regexp.Regexp, error) {
+	if c == nil {
+		return regexp.Compile(re)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if compiled, ok := c.compiled[re]; ok {
+		return compiled, nil
+	}
+
+	compiled, err := regexp.Compile(re)
+	if err != nil {
+		return nil, err
+	}
+	if c.compiled == nil {
+		c.compiled = make(map[string]*regexp.Regexp)
+	}
+	c.compiled[re] = compiled
+	return compiled, nil
+}
+
+// regexReplaceAllFunc returns a function which replaces all occurrences of a
+// regular expression with the given replacement value.
+func regexReplaceAllFunc(c *regexpCache) func(string, string, string) (string, error) {
+	return func(re, pl, s string) (string, error) {
+		compiled, err := c.compile(re)
+		if err != nil {
+			return "", err
+		}
+		return compiled.ReplaceAllString(s, pl), nil
+	}
+}
+
+// regexMatchFunc returns a function which returns true or false if the string
+// matches the given regular expression.
+func regexMatchFunc(c *regexpCache) func(string, string) (bool, error) {
+	return func(re, s string) (bool, error) {
+		compiled, err := c.compile(re)
+		if err != nil {
+			return false, err
+		}
+		return compiled.MatchString(s), nil
+	}
+}
+
+// regexFindFunc returns a function which returns the first match of the
+// regular expression in the string. If the expression has capture groups, the
+// first capture group is returned instead of the whole match. An empty string
+// is returned if there is no match.
+func regexFindFunc(c *regexpCache) func(string, string) (string, error) {
+	return func(re, s string) (string, error) {
+		compiled, err := c.compile(re)
+		if err != nil {
+			return "", err
+		}
+
+		m := compiled.FindStringSubmatch(s)
+		switch len(m) {
+		case 0:
+			return "", nil
+		case 1:
+			return m[0], nil
+		default:
+			return m[1], nil
+		}
+	}
+}
+
+// regexFindAllFunc returns a function which returns all of the matches of the
+// regular expression in the string.
+func regexFindAllFunc(c *regexpCache) func(string, string) ([]string, error) {
+	return func(re, s string) ([]string, error) {
+		compiled, err := c.compile(re)
+		if err != nil {
+			return nil, err
+		}
+
+		m := compiled.FindAllString(s, -1)
+		if m == nil {
+			return []string{}, nil
+		}
+		return m, nil
+	}
+}
+
+// regexSplitFunc returns a function which splits the string on each match of
+// the regular expression.
+func regexSplitFunc(c *regexpCache) func(string, string) ([]string, error) {
+	return func(re, s string) ([]string, error) {
+		compiled, err := c.compile(re)
+		if err != nil {
+			return nil, err
+		}
+
+		if s == "" {
+			return []string{}, nil
+		}
+		return compiled.Split(s, -1), nil
+	}
+}
+
+const (
+	// TCPProbeMaxTimeout is the maximum timeout of a single tcpProbe, so an
+	// unreachable address cannot hold up a render for long.
+	TCPProbeMaxTimeout = 5 * time.Second
+
+	// TCPProbeMaxPerRender is the maximum number of addresses a template may
+	// probe in a single render.
+	TCPProbeMaxPerRender = 16
+)
+
+// TCPProbeInterval is the minimum amount of time between two probes of the
+// same address by a template. Renders within the interval reuse the result of
+// the last probe.
+var TCPProbeInterval = 30 * time.Second
+
+// tcpProbeResult is the result of probing an address.
+type tcpProbeResult struct {
+	reachable bool
+	at        time.Time
+}
+
+// tcpProbeCache caches the results of the TCP probes of a template, so each
+// address is probed at most once per TCPProbeInterval no matter how often the
+// template renders. The zero value is ready to use.
+type tcpProbeCache struct {
+	sync.Mutex
+	results map[string]*tcpProbeResult
+}
+
+// tcpProbes are the TCP probes of a single render.
+type tcpProbes struct {
+	sync.Mutex
+
+	cache *tcpProbeCache
+	count int
+}
+
+// probe returns whether a TCP connection to the address could be established
+// within the timeout, from the cache if it was probed within the interval.
+// A nil tcpProbes probes the address every time.
+func (p *tcpProbes) probe(addr string, timeout time.Duration) (bool, error) {
+	if p == nil {
+		return dialTCP(addr, timeout), nil
+	}
+
+	p.cache.Lock()
+	r, ok := p.cache.results[addr]
+	p.cache.Unlock()
+	if ok && now().Sub(r.at) < TCPProbeInterval {
+		return r.reachable, nil
+	}
+
+	p.Lock()
+	if p.count >= TCPProbeMaxPerRender {
+		p.Unlock()
+		return false, fmt.Errorf("tcpProbe: more than %d addresses probed in "+
+			"a single render", TCPProbeMaxPerRender)
 	}
+	p.count++
+	p.Unlock()
 
-	return strings.TrimSpace(stdout.String()), nil
-}
+	r = &tcpProbeResult{reachable: dialTCP(addr, timeout), at: now()}
 
-// replaceAll replaces all occurrences of a value in a string with the given
-// replacement value.
-func replaceAll(f, t, s string) (string, error) {
-	return strings.Replace(s, f, t, -1), nil
+	p.cache.Lock()
+	if p.cache.results == nil {
+		p.cache.results = make(map[string]*tcpProbeResult)
+	}
+	p.cache.results[addr] = r
+	p.cache.Unlock()
+
+	return r.reachable, nil
 }
 
-// regexReplaceAll replaces all occurrences of a regular expression with
-// the given replacement value.
-func regexReplaceAll(re, pl, s string) (string, error) {
-	compiled, err := regexp.Compile(re)
+// dialTCP returns whether a TCP connection to the address could be
+// established within the timeout.
+func dialTCP(addr string, timeout time.Duration) bool {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
 	if err != nil {
-		return "", err
+		log.Printf("[DEBUG] (template) tcpProbe: %s is unreachable: %s", addr, err)
+		return false
 	}
-	return compiled.ReplaceAllString(s, pl), nil
+	conn.Close()
+	return true
 }
 
-// regexMatch returns true or false if the string matches
-// the given regular expression
-func regexMatch(re, s string) (bool, error) {
-	compiled, err := regexp.Compile(re)
-	if err != nil {
-		return false, err
+// tcpProbeFunc returns a function which returns whether a TCP connection to
+// the given host and port could be established within the timeout. The
+// timeout is a duration string like "500ms" and is capped at
+// TCPProbeMaxTimeout. Probing does not add a dependency, so a change in
+// reachability is only noticed when the template renders again for other
+// reasons.
+func tcpProbeFunc(p *tcpProbes) func(string, interface{}, string) (bool, error) {
+	return func(host string, port interface{}, timeout string) (bool, error) {
+		if host == "" {
+			return false, fmt.Errorf("tcpProbe: missing host")
+		}
+
+		portStr := fmt.Sprint(port)
+		if n, err := strconv.ParseUint(portStr, 10, 16); err != nil || n == 0 {
+			return false, fmt.Errorf("tcpProbe: invalid port %q", portStr)
+		}
+
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return false, errors.Wrap(err, "tcpProbe")
+		}
+		if d <= 0 {
+			return false, fmt.Errorf("tcpProbe: timeout must be positive")
+		}
+		if d > TCPProbeMaxTimeout {
+			d = TCPProbeMaxTimeout
+		}
+
+		return p.probe(net.JoinHostPort(host, portStr), d)
 	}
-	return compiled.MatchString(s), nil
 }
 
 // split is a version of strings.Split that can be piped
@@ -862,6 +2237,182 @@ func timestamp(s ...string) (string, error) {
 	}
 }
 
+// currentTime returns the current time in UTC.
+func currentTime() time.Time {
+	return now()
+}
+
+// dateFormat formats the given time with the given layout. The time may be a
+// time.Time, a UNIX timestamp in seconds, or an RFC3339 string. If a time zone
+// name is given before the time, the time is converted to that zone before it
+// is formatted; otherwise it is formatted in UTC.
+func dateFormat(layout string, args ...interface{}) (string, error) {
+	var zone string
+	switch len(args) {
+	case 1:
+	case 2:
+		z, ok := args[0].(string)
+		if !ok {
+			return "", fmt.Errorf("dateFormat: time zone must be a string (%T)", args[0])
+		}
+		zone = z
+	default:
+		return "", fmt.Errorf("dateFormat: wrong number of arguments, expected 2 or 3"+
+			", but got %d", len(args)+1)
+	}
+
+	t, err := toTime("dateFormat", args[len(args)-1])
+	if err != nil {
+		return "", err
+	}
+
+	loc, err := loadLocation("dateFormat", zone)
+	if err != nil {
+		return "", err
+	}
+
+	return t.In(loc).Format(layout), nil
+}
+
+// parseTime parses the value with the given layout. If a time zone name is
+// given before the value, it is used for values which do not include a zone;
+// otherwise UTC is used.
+func parseTime(layout string, args ...string) (time.Time, error) {
+	var zone string
+	switch len(args) {
+	case 1:
+	case 2:
+		zone = args[0]
+	default:
+		return time.Time{}, fmt.Errorf("parseTime: wrong number of arguments, "+
+			"expected 2 or 3, but got %d", len(args)+1)
+	}
+
+	loc, err := loadLocation("parseTime", zone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := time.ParseInLocation(layout, args[len(args)-1], loc)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "parseTime")
+	}
+	return t, nil
+}
+
+// unixToTime converts a UNIX timestamp in seconds to a time in UTC.
+func unixToTime(i interface{}) (time.Time, error) {
+	switch v := i.(type) {
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "unixToTime")
+		}
+		return time.Unix(n, 0).UTC(), nil
+	default:
+		f, isInt, err := toNumber("unixToTime", i)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !isInt {
+			return time.Unix(0, int64(f*float64(time.Second))).UTC(), nil
+		}
+		return time.Unix(toInt64(i), 0).UTC(), nil
+	}
+}
+
+// parseDuration parses a duration string such as "1h30m".
+func parseDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.Wrap(err, "parseDuration")
+	}
+	return d, nil
+}
+
+// timeAdd returns the time plus the given duration. The duration may be a
+// time.Duration or a duration string, and may be negative.
+func timeAdd(d interface{}, t interface{}) (time.Time, error) {
+	var dur time.Duration
+	switch v := d.(type) {
+	case time.Duration:
+		dur = v
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "timeAdd")
+		}
+		dur = parsed
+	default:
+		return time.Time{}, fmt.Errorf("timeAdd: unknown type for duration (%T)", d)
+	}
+
+	tt, err := toTime("timeAdd", t)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return tt.Add(dur), nil
+}
+
+// timeSub returns the duration from b to a.
+func timeSub(b, a interface{}) (time.Duration, error) {
+	at, err := toTime("timeSub", a)
+	if err != nil {
+		return 0, err
+	}
+	bt, err := toTime("timeSub", b)
+	if err != nil {
+		return 0, err
+	}
+	return at.Sub(bt), nil
+}
+
+// toTime converts a time.Time, UNIX timestamp in seconds, or RFC3339 string
+// into a time.
+func toTime(name string, i interface{}) (time.Time, error) {
+	switch v := i.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, name)
+		}
+		return t, nil
+	default:
+		if _, isInt, err := toNumber(name, i); err != nil || !isInt {
+			return time.Time{}, fmt.Errorf("%s: unknown type for time (%T)", name, i)
+		}
+		return time.Unix(toInt64(i), 0).UTC(), nil
+	}
+}
+
+// loadLocation loads the time zone with the given name. An empty name is UTC.
+func loadLocation(name, zone string) (*time.Location, error) {
+	if zone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, errors.Wrap(err, name)
+	}
+	return loc, nil
+}
+
+// urlDecode decodes the given URL query escaped string.
+func urlDecode(s string) (string, error) {
+	v, err := url.QueryUnescape(s)
+	if err != nil {
+		return "", errors.Wrap(err, "urlDecode")
+	}
+	return v, nil
+}
+
+// urlEncode escapes the given string so it can be safely placed in a URL query.
+func urlEncode(s string) (string, error) {
+	return url.QueryEscape(s), nil
+}
+
 // toLower converts the given string (usually by a pipe) to lowercase.
 func toLower(s string) (string, error) {
 	return strings.ToLower(s), nil
@@ -919,6 +2470,296 @@ func toTOML(m map[string]interface{}) (string, error) {
 	return string(bytes.TrimSpace(result)), nil
 }
 
+// htpasswdCacheSize is the number of htpasswd lines a template caches. Lines
+// of passwords which are no longer rendered are evicted once it is reached.
+const htpasswdCacheSize = 256
+
+// htpasswdCache is the most recently used htpasswd lines of a template by user
+// and password. Each template has its own cache, so a line keeps its random
+// salt, and does not change on each render, for as long as the password does
+// not change. The zero value is ready to use.
+type htpasswdCache struct {
+	sync.Mutex
+	entries *simplelru.LRU
+
+	// salt returns the salt of a new line. It is randomSalt unless replaced,
+	// like in tests.
+	salt func() (string, error)
+}
+
+// entry returns the htpasswd line for the user and password, hashing and
+// caching it if needed. A nil cache hashes the password every time.
+func (c *htpasswdCache) entry(user, pass string) (string, error) {
+	if c == nil {
+		salt, err := randomSalt()
+		if err != nil {
+			return "", err
+		}
+		return user + ":" + apr1Crypt(pass, salt), nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.entries == nil {
+		entries, err := simplelru.NewLRU(htpasswdCacheSize, nil)
+		if err != nil {
+			return "", err
+		}
+		c.entries = entries
+	}
+
+	key := sha256.Sum256([]byte(user + "\x00" + pass))
+	if entry, ok := c.entries.Get(key); ok {
+		return entry.(string), nil
+	}
+
+	saltFunc := c.salt
+	if saltFunc == nil {
+		saltFunc = randomSalt
+	}
+	salt, err := saltFunc()
+	if err != nil {
+		return "", err
+	}
+
+	entry := user + ":" + apr1Crypt(pass, salt)
+	c.entries.Add(key, entry)
+	return entry, nil
+}
+
+// randomSalt returns a random salt of 8 characters of the crypt alphabet.
+func randomSalt() (string, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	for i := range salt {
+		salt[i] = cryptAlphabet[salt[i]&0x3f]
+	}
+	return string(salt), nil
+}
+
+// htpasswdEntryFunc returns a function which returns the htpasswd line for
+// the user and password, hashed with the Apache MD5 scheme which both Apache
+// and nginx accept, with a random salt.
+func htpasswdEntryFunc(c *htpasswdCache) func(string, string) (string, error) {
+	return func(user, pass string) (string, error) {
+		if user == "" || strings.ContainsAny(user, ":\r\n") {
+			return "", fmt.Errorf("htpasswdEntry: invalid user %q", user)
+		}
+
+		entry, err := c.entry(user, pass)
+		if err != nil {
+			return "", errors.Wrap(err, "htpasswdEntry")
+		}
+		return entry, nil
+	}
+}
+
+// cryptAlphabet is the alphabet of the encoding of crypt hashes.
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// apr1Crypt returns the Apache MD5 ($apr1$) hash of the password with the
+// given salt of up to 8 characters.
+func apr1Crypt(pass, salt string) string {
+	const magic = "$apr1$"
+	p := []byte(pass)
+
+	alt := md5.Sum([]byte(pass + salt + pass))
+
+	h := md5.New()
+	h.Write([]byte(pass + magic + salt))
+	for i := len(p); i > 0; i -= 16 {
+		if i > 16 {
+			h.Write(alt[:])
+		} else {
+			h.Write(alt[:i])
+		}
+	}
+	for i := len(p); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(p[:1])
+		}
+	}
+	final := h.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		h := md5.New()
+		if i&1 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(final)
+		}
+		if i%3 != 0 {
+			h.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			h.Write(p)
+		}
+		if i&1 != 0 {
+			h.Write(final)
+		} else {
+			h.Write(p)
+		}
+		final = h.Sum(nil)
+	}
+
+	var b bytes.Buffer
+	b.WriteString(magic + salt + "$")
+	encode := func(v uint, n int) {
+		for ; n > 0; n-- {
+			b.WriteByte(cryptAlphabet[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, i := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		encode(uint(final[i[0]])<<16|uint(final[i[1]])<<8|uint(final[i[2]]), 4)
+	}
+	encode(uint(final[11]), 2)
+	return b.String()
+}
+
+// javaProperties returns the map as the lines of a Java properties file,
+// sorted by key. Nested maps are flattened with dots, and keys and values are
+// escaped as by java.util.Properties, so they are read back as they are.
+func javaProperties(v interface{}) (string, error) {
+	entries, err := fileEntries(v, ".")
+	if err != nil {
+		return "", errors.Wrap(err, "javaProperties")
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		lines = append(lines, escapeProperty(e[0], true)+"="+escapeProperty(e[1], false))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// escapeProperty escapes a key or value of a Java properties file. Characters
+// outside of printable ASCII are written as unicode escapes, since properties
+// files are read as ISO-8859-1.
+func escapeProperty(s string, key bool) string {
+	var b bytes.Buffer
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case ' ':
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		default:
+			if r < 0x20 || r > 0x7e {
+				for _, u := range utf16.Encode([]rune{r}) {
+					fmt.Fprintf(&b, `\u%04X`, u)
+				}
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var (
+	// envNameRe matches the names of environment variables.
+	envNameRe = regexp.MustCompile(`\A[A-Za-z_][A-Za-z0-9_]*\z`)
+
+	// envSafeRe matches values of environment variables which need no quoting.
+	envSafeRe = regexp.MustCompile(`\A[A-Za-z0-9_./:@%+,=-]*\z`)
+)
+
+// envFile returns the map as the lines of an environment file, sorted by key.
+// Values are single quoted when needed, so the file can be sourced by a shell
+// or read as a systemd EnvironmentFile.
+func envFile(v interface{}) (string, error) {
+	entries, err := fileEntries(v, "")
+	if err != nil {
+		return "", errors.Wrap(err, "envFile")
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !envNameRe.MatchString(e[0]) {
+			return "", fmt.Errorf("envFile: invalid name %q", e[0])
+		}
+
+		value := e[1]
+		if !envSafeRe.MatchString(value) {
+			value = "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+		}
+		lines = append(lines, e[0]+"="+value)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// fileEntries returns the keys and values of the map for the file formatters,
+// sorted by key. Nested maps are flattened by joining their keys with the
+// separator, or rejected if the separator is empty.
+func fileEntries(v interface{}, sep string) ([][2]string, error) {
+	if m, ok := v.(map[string]string); ok {
+		entries := make([][2]string, 0, len(m))
+		for k, v := range m {
+			entries = append(entries, [2]string{k, v})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i][0] < entries[j][0] })
+		return entries, nil
+	}
+
+	m, ok := stringMap(v)
+	if !ok {
+		return nil, fmt.Errorf("expected a map, got %T", v)
+	}
+
+	var entries [][2]string
+	var walk func(prefix string, m map[string]interface{}) error
+	walk = func(prefix string, m map[string]interface{}) error {
+		for k, v := range m {
+			key := prefix + k
+			if nested, ok := stringMap(v); ok {
+				if sep == "" {
+					return fmt.Errorf("value of %q is a map", key)
+				}
+				if err := walk(key+sep, nested); err != nil {
+					return err
+				}
+				continue
+			}
+
+			switch v.(type) {
+			case nil:
+				entries = append(entries, [2]string{key, ""})
+			case []interface{}:
+				return fmt.Errorf("value of %q is a list", key)
+			default:
+				entries = append(entries, [2]string{key, fmt.Sprintf("%v", v)})
+			}
+		}
+		return nil
+	}
+	if err := walk("", m); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i][0] < entries[j][0] })
+	return entries, nil
+}
+
 // add returns the sum of a and b.
 func add(b, a interface{}) (interface{}, error) {
 	av := reflect.ValueOf(a)
@@ -1123,3 +2964,102 @@ func modulo(b, a interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("modulo: unknown type for %q (%T)", av, a)
 	}
 }
+
+// maximum returns the larger of a and b. The result is an int64 if both
+// values are integers, and a float64 otherwise.
+func maximum(b, a interface{}) (interface{}, error) {
+	return compareNumbers("max", b, a, func(x, y float64) bool { return x > y })
+}
+
+// minimum returns the smaller of a and b. The result is an int64 if both
+// values are integers, and a float64 otherwise.
+func minimum(b, a interface{}) (interface{}, error) {
+	return compareNumbers("min", b, a, func(x, y float64) bool { return x < y })
+}
+
+// compareNumbers returns a if pick(a, b) is true, and b otherwise.
+func compareNumbers(name string, b, a interface{}, pick func(x, y float64) bool) (interface{}, error) {
+	af, aInt, err := toNumber(name, a)
+	if err != nil {
+		return nil, err
+	}
+	bf, bInt, err := toNumber(name, b)
+	if err != nil {
+		return nil, err
+	}
+
+	r, rf := b, bf
+	if pick(af, bf) {
+		r, rf = a, af
+	}
+
+	if aInt && bInt {
+		return toInt64(r), nil
+	}
+	return rf, nil
+}
+
+// round returns the value rounded to the nearest integer, or to the given
+// number of decimal places, with halves rounded away from zero.
+func round(args ...interface{}) (float64, error) {
+	var places int64
+	switch len(args) {
+	case 1:
+	case 2:
+		p, isInt, err := toNumber("round", args[0])
+		if err != nil {
+			return 0, err
+		}
+		if !isInt {
+			return 0, fmt.Errorf("round: places must be an integer (%T)", args[0])
+		}
+		places = int64(p)
+	default:
+		return 0, fmt.Errorf("round: wrong number of arguments, expected 1 or 2"+
+			", but got %d", len(args))
+	}
+
+	v, _, err := toNumber("round", args[len(args)-1])
+	if err != nil {
+		return 0, err
+	}
+
+	shift := math.Pow(10, float64(places))
+	return math.Round(v*shift) / shift, nil
+}
+
+// ceil returns the least integer value greater than or equal to the value.
+func ceil(a interface{}) (float64, error) {
+	v, _, err := toNumber("ceil", a)
+	if err != nil {
+		return 0, err
+	}
+	return math.Ceil(v), nil
+}
+
+// toNumber returns the given numeric value as a float64 and whether it is an
+// integer type.
+func toNumber(name string, i interface{}) (float64, bool, error) {
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true, nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, nil
+	default:
+		return 0, false, fmt.Errorf("%s: unknown type for %q (%T)", name, v, i)
+	}
+}
+
+// toInt64 converts the given integer value to an int64.
+func toInt64(i interface{}) int64 {
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	default:
+		return v.Int()
+	}
+}
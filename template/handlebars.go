@@ -0,0 +1,969 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// HandlebarsEngine is the name of the engine for the Handlebars language.
+	HandlebarsEngine = "handlebars"
+)
+
+// handlebarsEngine is the engine for the Handlebars language. It supports
+// expressions with helpers and subexpressions, raw "{{{ }}}" and "{{& }}"
+// expressions, comments, whitespace control, and the built-in "if", "unless",
+// "each" and "with" block helpers and "lookup" helper. Partials, custom block
+// helpers, hash arguments and block parameters are not supported. The
+// template functions are available as helpers.
+type handlebarsEngine struct{}
+
+// Execute implements the Engine interface.
+func (e *handlebarsEngine) Execute(w io.Writer, i *EngineInput) error {
+	left, right := i.LeftDelim, i.RightDelim
+	if left == "" {
+		left = "{{"
+	}
+	if right == "" {
+		right = "}}"
+	}
+
+	nodes, err := parseHandlebars(i.Contents, left, right)
+	if err != nil {
+		return errors.Wrap(err, "parse")
+	}
+
+	var funcs template.FuncMap
+	if i.Funcs != nil {
+		funcs = i.Funcs(nil)
+	}
+
+	s := &hbState{w: w, funcs: funcs}
+	if err := s.walk(nodes, &hbFrame{}); err != nil {
+		return errors.Wrap(err, "execute")
+	}
+	return nil
+}
+
+// hbTokenKind is the kind of a lexed Handlebars token.
+type hbTokenKind int
+
+const (
+	hbText hbTokenKind = iota
+	hbComment
+	hbExpression
+	hbRawExpression
+	hbBlockOpen
+	hbBlockClose
+	hbElse
+)
+
+// hbToken is a lexed Handlebars token: either text, or a tag and its body.
+type hbToken struct {
+	kind hbTokenKind
+	text string
+	line int
+
+	// trimLeft and trimRight are set by the "~" whitespace control markers.
+	trimLeft, trimRight bool
+}
+
+// standalone returns true if the token is removed with its whole line when it
+// is the only thing on that line.
+func (t *hbToken) standalone() bool {
+	switch t.kind {
+	case hbComment, hbBlockOpen, hbBlockClose, hbElse:
+		return true
+	}
+	return false
+}
+
+// lexHandlebars splits the contents into text and tag tokens.
+func lexHandlebars(contents, left, right string) ([]*hbToken, error) {
+	var tokens []*hbToken
+	line := 1
+	for len(contents) > 0 {
+		idx := strings.Index(contents, left)
+		if idx == -1 {
+			tokens = append(tokens, &hbToken{kind: hbText, text: contents, line: line})
+			break
+		}
+		if idx > 0 {
+			tokens = append(tokens, &hbToken{kind: hbText, text: contents[:idx], line: line})
+			line += strings.Count(contents[:idx], "\n")
+		}
+		contents = contents[idx+len(left):]
+
+		tok := &hbToken{line: line}
+		var closing string
+		switch {
+		case strings.HasPrefix(contents, "{"):
+			tok.kind = hbRawExpression
+			contents = contents[1:]
+			closing = "}"
+		case strings.HasPrefix(contents, "~{"):
+			tok.kind = hbRawExpression
+			tok.trimLeft = true
+			contents = contents[2:]
+			closing = "}"
+		case strings.HasPrefix(contents, "~"):
+			tok.trimLeft = true
+			contents = contents[1:]
+		}
+
+		// Comments may contain the closing delimiter when they are written
+		// with dashes, like "{{!-- }} --}}".
+		if tok.kind != hbRawExpression && strings.HasPrefix(contents, "!--") {
+			closing = "--"
+		}
+
+		end, n := hbClosing(contents, closing, right)
+		if end == -1 {
+			return nil, fmt.Errorf("line %d: unclosed tag", line)
+		}
+		body := contents[:end]
+		line += strings.Count(body, "\n")
+		tok.trimRight = strings.HasPrefix(contents[end+len(closing):], "~")
+		contents = contents[end+n:]
+
+		if tok.kind != hbRawExpression {
+			switch {
+			case strings.HasPrefix(body, "!"):
+				tok.kind = hbComment
+			case strings.HasPrefix(body, "#"):
+				tok.kind = hbBlockOpen
+				body = body[1:]
+			case strings.HasPrefix(body, "/"):
+				tok.kind = hbBlockClose
+				body = body[1:]
+			case strings.HasPrefix(body, "&"):
+				tok.kind = hbRawExpression
+				body = body[1:]
+			case strings.TrimSpace(body) == "else" || strings.TrimSpace(body) == "^":
+				tok.kind = hbElse
+			case strings.HasPrefix(body, ">"):
+				return nil, fmt.Errorf("line %d: partials are not supported", line)
+			case strings.HasPrefix(body, "^"):
+				return nil, fmt.Errorf("line %d: inverted sections are not supported, use \"unless\"", line)
+			default:
+				tok.kind = hbExpression
+			}
+		}
+		tok.text = strings.TrimSpace(body)
+
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+// hbClosing returns the index of the end of the tag body in the contents, and
+// the length of the closing sequence: the given prefix, an optional "~", and
+// the right delimiter. It returns -1 if the tag is not closed.
+func hbClosing(contents, prefix, right string) (int, int) {
+	plain := strings.Index(contents, prefix+right)
+	trim := strings.Index(contents, prefix+"~"+right)
+	if trim != -1 && (plain == -1 || trim < plain) {
+		return trim, len(prefix) + 1 + len(right)
+	}
+	if plain != -1 {
+		return plain, len(prefix) + len(right)
+	}
+	return -1, 0
+}
+
+// trimHandlebars removes the lines of standalone tags, and then applies the
+// whitespace control markers.
+func trimHandlebars(tokens []*hbToken) {
+	// The lines are decided on the original text, so that consecutive
+	// standalone tags are all removed.
+	prefix := make([]int, len(tokens))
+	suffix := make([]int, len(tokens))
+	for i, tok := range tokens {
+		if !tok.standalone() {
+			continue
+		}
+		before, ok := hbLineBefore(tokens, i)
+		if !ok || strings.TrimSpace(before) != "" {
+			continue
+		}
+		after, ok := hbLineAfter(tokens, i)
+		if !ok || strings.TrimSpace(after) != "" {
+			continue
+		}
+		if i > 0 {
+			suffix[i-1] = len(before)
+		}
+		if i < len(tokens)-1 {
+			prefix[i+1] = len(after)
+		}
+	}
+	for i, tok := range tokens {
+		if tok.kind == hbText {
+			tok.text = tok.text[prefix[i] : len(tok.text)-suffix[i]]
+		}
+	}
+
+	for i, tok := range tokens {
+		if tok.trimLeft && i > 0 && tokens[i-1].kind == hbText {
+			tokens[i-1].text = strings.TrimRightFunc(tokens[i-1].text, unicode.IsSpace)
+		}
+		if tok.trimRight && i < len(tokens)-1 && tokens[i+1].kind == hbText {
+			tokens[i+1].text = strings.TrimLeftFunc(tokens[i+1].text, unicode.IsSpace)
+		}
+	}
+}
+
+// hbLineBefore returns the text between the start of the line and the tag at
+// the given index. It returns false if another tag is on the line before it.
+func hbLineBefore(tokens []*hbToken, i int) (string, bool) {
+	if i == 0 {
+		return "", true
+	}
+	if tokens[i-1].kind != hbText {
+		return "", false
+	}
+	text := tokens[i-1].text
+	if idx := strings.LastIndex(text, "\n"); idx != -1 {
+		return text[idx+1:], true
+	}
+	return text, i == 1
+}
+
+// hbLineAfter returns the text between the tag at the given index and the end
+// of the line, including the newline. It returns false if another tag is on
+// the line after it.
+func hbLineAfter(tokens []*hbToken, i int) (string, bool) {
+	if i == len(tokens)-1 {
+		return "", true
+	}
+	if tokens[i+1].kind != hbText {
+		return "", false
+	}
+	text := tokens[i+1].text
+	if idx := strings.Index(text, "\n"); idx != -1 {
+		return text[:idx+1], true
+	}
+	return text, i+1 == len(tokens)-1
+}
+
+// hbTemplateOutputs returns the literal names given to the templateOutput
+// helper by the given Handlebars contents, or nil if they do not parse.
+func hbTemplateOutputs(contents, left, right string) []string {
+	if left == "" {
+		left = "{{"
+	}
+	if right == "" {
+		right = "}}"
+	}
+
+	nodes, err := parseHandlebars(contents, left, right)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	var expr func(*hbExpr)
+	expr = func(e *hbExpr) {
+		if e == nil {
+			return
+		}
+		if e.path == "templateOutput" && len(e.args) > 0 {
+			if s, ok := e.args[0].lit.(string); ok {
+				names = append(names, s)
+			}
+		}
+		for _, arg := range e.args {
+			expr(arg)
+		}
+	}
+	var walk func([]hbNode)
+	walk = func(nodes []hbNode) {
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case *hbExprNode:
+				expr(n.expr)
+			case *hbBlockNode:
+				expr(n.param)
+				walk(n.body)
+				walk(n.inverse)
+			}
+		}
+	}
+	walk(nodes)
+	return names
+}
+
+// hbNode is a node of a parsed Handlebars template.
+type hbNode interface{}
+
+// hbTextNode is literal text.
+type hbTextNode struct {
+	text string
+}
+
+// hbExprNode is an expression whose value is written to the output.
+type hbExprNode struct {
+	expr *hbExpr
+	raw  bool
+	line int
+}
+
+// hbBlockNode is a block helper, with the nodes to render when it holds and
+// the nodes of its "else" section.
+type hbBlockNode struct {
+	name    string
+	param   *hbExpr
+	body    []hbNode
+	inverse []hbNode
+	line    int
+}
+
+// hbExpr is an expression: a literal, a path into the context, or a call of a
+// helper with arguments.
+type hbExpr struct {
+	// lit is the value of a literal.
+	lit   interface{}
+	isLit bool
+
+	// path is the path into the context, or the name of the helper.
+	path string
+
+	// args are the arguments of a helper call. call is set for calls and
+	// subexpressions, which always call a helper, even without arguments.
+	args []*hbExpr
+	call bool
+}
+
+// parseHandlebars parses the contents into a tree of nodes.
+func parseHandlebars(contents, left, right string) ([]hbNode, error) {
+	tokens, err := lexHandlebars(contents, left, right)
+	if err != nil {
+		return nil, err
+	}
+	trimHandlebars(tokens)
+
+	nodes, rest, err := parseHBNodes(tokens, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("line %d: unexpected tag", rest[0].line)
+	}
+	return nodes, nil
+}
+
+// parseHBNodes parses tokens until the end of the given block, which is nil
+// at the top level. It returns the nodes of the body, and the remaining
+// tokens starting with the "else" or closing tag of the block.
+func parseHBNodes(tokens []*hbToken, block *hbBlockNode) ([]hbNode, []*hbToken, error) {
+	var nodes []hbNode
+	for len(tokens) > 0 {
+		tok := tokens[0]
+		switch tok.kind {
+		case hbText:
+			if tok.text != "" {
+				nodes = append(nodes, &hbTextNode{text: tok.text})
+			}
+		case hbComment:
+		case hbExpression, hbRawExpression:
+			expr, err := parseHBExpr(tok.text, true)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %s", tok.line, err)
+			}
+			nodes = append(nodes, &hbExprNode{
+				expr: expr,
+				raw:  tok.kind == hbRawExpression,
+				line: tok.line,
+			})
+		case hbBlockOpen:
+			n, rest, err := parseHBBlock(tokens)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, n)
+			tokens = rest
+			continue
+		case hbElse, hbBlockClose:
+			if block == nil {
+				return nil, nil, fmt.Errorf("line %d: unexpected %q", tok.line, tok.text)
+			}
+			return nodes, tokens, nil
+		}
+		tokens = tokens[1:]
+	}
+
+	if block != nil {
+		return nil, nil, fmt.Errorf("line %d: unclosed block %q", block.line, block.name)
+	}
+	return nodes, nil, nil
+}
+
+// parseHBBlock parses the block opened by the first of the given tokens, and
+// returns the remaining tokens after its closing tag.
+func parseHBBlock(tokens []*hbToken) (*hbBlockNode, []*hbToken, error) {
+	open := tokens[0]
+
+	items, err := splitHBExpr(open.text)
+	if err != nil {
+		return nil, nil, fmt.Errorf("line %d: %s", open.line, err)
+	}
+	if len(items) != 2 {
+		return nil, nil, fmt.Errorf("line %d: block %q expects 1 argument",
+			open.line, open.text)
+	}
+
+	n := &hbBlockNode{name: items[0], line: open.line}
+	switch n.name {
+	case "if", "unless", "each", "with":
+	default:
+		return nil, nil, fmt.Errorf("line %d: unknown block helper %q",
+			open.line, n.name)
+	}
+
+	n.param, err = parseHBItem(items[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("line %d: %s", open.line, err)
+	}
+
+	var rest []*hbToken
+	n.body, rest, err = parseHBNodes(tokens[1:], n)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rest[0].kind == hbElse {
+		n.inverse, rest, err = parseHBNodes(rest[1:], n)
+		if err != nil {
+			return nil, nil, err
+		}
+		if rest[0].kind == hbElse {
+			return nil, nil, fmt.Errorf("line %d: unexpected %q", rest[0].line, rest[0].text)
+		}
+	}
+
+	if close := rest[0]; close.text != n.name {
+		return nil, nil, fmt.Errorf("line %d: %q does not match %q",
+			close.line, close.text, n.name)
+	}
+	return n, rest[1:], nil
+}
+
+// parseHBExpr parses the body of an expression tag, or of a subexpression. A
+// single path at the top level calls the helper of that name if there is one,
+// which is decided when the template is executed.
+func parseHBExpr(s string, top bool) (*hbExpr, error) {
+	items, err := splitHBExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	if len(items) == 1 && top {
+		return parseHBItem(items[0])
+	}
+
+	e := &hbExpr{path: items[0], call: true}
+	if !isHBPath(e.path) {
+		return nil, fmt.Errorf("%q is not a helper name", e.path)
+	}
+	for _, item := range items[1:] {
+		arg, err := parseHBItem(item)
+		if err != nil {
+			return nil, err
+		}
+		e.args = append(e.args, arg)
+	}
+	return e, nil
+}
+
+// parseHBItem parses a single argument: a literal, a path, or a
+// subexpression.
+func parseHBItem(s string) (*hbExpr, error) {
+	switch {
+	case strings.HasPrefix(s, "("):
+		return parseHBExpr(s[1:len(s)-1], false)
+	case strings.HasPrefix(s, `"`), strings.HasPrefix(s, "'"):
+		return &hbExpr{lit: unquoteHB(s[1 : len(s)-1]), isLit: true}, nil
+	case s == "true", s == "false":
+		return &hbExpr{lit: s == "true", isLit: true}, nil
+	case s == "null", s == "undefined":
+		return &hbExpr{isLit: true}, nil
+	}
+
+	if i, err := strconv.Atoi(s); err == nil {
+		return &hbExpr{lit: i, isLit: true}, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return &hbExpr{lit: f, isLit: true}, nil
+	}
+
+	if strings.Contains(s, "=") {
+		return nil, fmt.Errorf("hash arguments are not supported: %q", s)
+	}
+	if !isHBPath(s) {
+		return nil, fmt.Errorf("invalid expression %q", s)
+	}
+	return &hbExpr{path: s}, nil
+}
+
+// isHBPath returns true if the given string is a valid path or helper name.
+func isHBPath(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || strings.ContainsRune(`"'()=`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitHBExpr splits an expression into its whitespace-separated items,
+// keeping quoted strings and parenthesized subexpressions together.
+func splitHBExpr(s string) ([]string, error) {
+	var items []string
+	for {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			return items, nil
+		}
+
+		var end int
+		switch s[0] {
+		case '"', '\'':
+			end = 1
+			for end < len(s) && s[end] != s[0] {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			end++
+		case '(':
+			depth := 0
+		parens:
+			for end < len(s) {
+				switch s[end] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				case '"', '\'':
+					q := s[end]
+					for end++; end < len(s) && s[end] != q; end++ {
+						if s[end] == '\\' {
+							end++
+						}
+					}
+				}
+				end++
+				if depth == 0 {
+					break parens
+				}
+			}
+			if depth != 0 {
+				return nil, fmt.Errorf("unterminated subexpression")
+			}
+		default:
+			end = strings.IndexFunc(s, func(r rune) bool {
+				return unicode.IsSpace(r) || r == '(' || r == ')'
+			})
+			if end == -1 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("unexpected %q", s[0])
+			}
+		}
+
+		items = append(items, s[:end])
+		s = s[end:]
+	}
+}
+
+// unquoteHB removes the backslash escapes from a string literal.
+func unquoteHB(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i < len(s)-1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// hbFrame is a context of a Handlebars template, with its parent context and
+// the data variables of the "each" helper, like "@index".
+type hbFrame struct {
+	ctx    interface{}
+	parent *hbFrame
+	data   map[string]interface{}
+}
+
+// hbState is the state of the execution of a Handlebars template.
+type hbState struct {
+	w     io.Writer
+	funcs template.FuncMap
+}
+
+// hbEscaper escapes the characters which Handlebars escapes in expressions.
+var hbEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&#x27;",
+	"`", "&#x60;",
+	"=", "&#x3D;",
+)
+
+// walk renders the given nodes in the given context.
+func (s *hbState) walk(nodes []hbNode, f *hbFrame) error {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *hbTextNode:
+			if _, err := io.WriteString(s.w, n.text); err != nil {
+				return err
+			}
+		case *hbExprNode:
+			v, err := s.eval(n.expr, f, true)
+			if err != nil {
+				return fmt.Errorf("line %d: %s", n.line, err)
+			}
+			out := hbString(v)
+			if !n.raw {
+				out = hbEscaper.Replace(out)
+			}
+			if _, err := io.WriteString(s.w, out); err != nil {
+				return err
+			}
+		case *hbBlockNode:
+			if err := s.block(n, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// block renders a block helper.
+func (s *hbState) block(n *hbBlockNode, f *hbFrame) error {
+	v, err := s.eval(n.param, f, false)
+	if err != nil {
+		return fmt.Errorf("line %d: %s", n.line, err)
+	}
+
+	switch n.name {
+	case "if":
+		if hbTruth(v) {
+			return s.walk(n.body, f)
+		}
+		return s.walk(n.inverse, f)
+	case "unless":
+		if !hbTruth(v) {
+			return s.walk(n.body, f)
+		}
+		return s.walk(n.inverse, f)
+	case "with":
+		if hbTruth(v) {
+			return s.walk(n.body, &hbFrame{ctx: v, parent: f})
+		}
+		return s.walk(n.inverse, f)
+	}
+
+	// each
+	rv := hbIndirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return s.walk(n.inverse, f)
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Len() == 0 {
+			return s.walk(n.inverse, f)
+		}
+		for i := 0; i < rv.Len(); i++ {
+			err := s.walk(n.body, &hbFrame{
+				ctx:    rv.Index(i).Interface(),
+				parent: f,
+				data: map[string]interface{}{
+					"index": i,
+					"first": i == 0,
+					"last":  i == rv.Len()-1,
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if rv.Len() == 0 {
+			return s.walk(n.inverse, f)
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for i, k := range keys {
+			err := s.walk(n.body, &hbFrame{
+				ctx:    rv.MapIndex(k).Interface(),
+				parent: f,
+				data: map[string]interface{}{
+					"key":   k.Interface(),
+					"index": i,
+					"first": i == 0,
+					"last":  i == len(keys)-1,
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("line %d: cannot iterate over %T", n.line, v)
+}
+
+// eval returns the value of the expression in the given context. If
+// helpers is true, a single path which names a helper calls it.
+func (s *hbState) eval(e *hbExpr, f *hbFrame, helpers bool) (interface{}, error) {
+	if e.isLit {
+		return e.lit, nil
+	}
+
+	if !e.call {
+		if _, ok := s.funcs[e.path]; !ok || !helpers {
+			return hbResolve(e.path, f), nil
+		}
+	}
+
+	args := make([]interface{}, 0, len(e.args))
+	for _, a := range e.args {
+		v, err := s.eval(a, f, false)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	if e.path == "lookup" {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("lookup: expects 2 arguments, got %d", len(args))
+		}
+		return hbField(args[0], fmt.Sprint(args[1])), nil
+	}
+
+	fn, ok := s.funcs[e.path]
+	if !ok {
+		return nil, fmt.Errorf("function %q not defined", e.path)
+	}
+	return hbCall(e.path, reflect.ValueOf(fn), args)
+}
+
+// hbResolve returns the value of the given path in the given context. Missing
+// values are nil, like in Handlebars.
+func hbResolve(path string, f *hbFrame) interface{} {
+	if strings.HasPrefix(path, "@") {
+		for ; f != nil; f = f.parent {
+			if v, ok := f.data[path[1:]]; ok {
+				return v
+			}
+		}
+		return nil
+	}
+
+	for strings.HasPrefix(path, "../") {
+		path = path[3:]
+		if f.parent != nil {
+			f = f.parent
+		}
+	}
+
+	v := f.ctx
+	for i, part := range strings.Split(path, ".") {
+		if part == "" || (i == 0 && part == "this") {
+			continue
+		}
+		v = hbField(v, part)
+	}
+	return v
+}
+
+// hbField returns the value of the given map key or struct field.
+func hbField(v interface{}, name string) interface{} {
+	rv := hbIndirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		mv := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !mv.IsValid() {
+			return nil
+		}
+		return mv.Interface()
+	case reflect.Struct:
+		fv := rv.FieldByName(name)
+		if !fv.IsValid() || !fv.CanInterface() {
+			return nil
+		}
+		return fv.Interface()
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(name)
+		if err != nil || i < 0 || i >= rv.Len() {
+			return nil
+		}
+		return rv.Index(i).Interface()
+	}
+	return nil
+}
+
+// hbCall calls the template function with the given arguments, converting
+// them to the types of its parameters.
+func hbCall(name string, fn reflect.Value, args []interface{}) (v interface{}, err error) {
+	typ := fn.Type()
+	numIn := typ.NumIn()
+	if typ.IsVariadic() {
+		if len(args) < numIn-1 {
+			return nil, fmt.Errorf("%s: expects at least %d arguments, got %d",
+				name, numIn-1, len(args))
+		}
+	} else if len(args) != numIn {
+		return nil, fmt.Errorf("%s: expects %d arguments, got %d",
+			name, numIn, len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, a := range args {
+		var t reflect.Type
+		if typ.IsVariadic() && i >= numIn-1 {
+			t = typ.In(numIn - 1).Elem()
+		} else {
+			t = typ.In(i)
+		}
+
+		in[i], err = hbArg(a, t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %d: %s", name, i+1, err)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", name, r)
+		}
+	}()
+
+	out := fn.Call(in)
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, fmt.Errorf("%s: %s", name, out[1].Interface())
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out[0].Interface(), nil
+}
+
+// hbArg converts the value to the given parameter type.
+func hbArg(v interface{}, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("invalid nil value for %s", t)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		return rv, nil
+	}
+	if hbNumber(rv.Kind()) && hbNumber(t.Kind()) {
+		return rv.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("expected %s, got %s", t, rv.Type())
+}
+
+// hbNumber returns true if the kind is a number.
+func hbNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// hbIndirect dereferences pointers and interfaces.
+func hbIndirect(rv reflect.Value) reflect.Value {
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// hbTruth returns whether the value is truthy: false, nil, zero numbers, empty
+// strings and empty lists and maps are falsy.
+func hbTruth(v interface{}) bool {
+	rv := hbIndirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return false
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() != 0
+	}
+	return true
+}
+
+// hbString returns the output of the value. Nil values render as nothing.
+func hbString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	switch v.(type) {
+	case fmt.Stringer, error:
+		return fmt.Sprint(v)
+	}
+
+	rv := hbIndirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(rv.Interface())
+}
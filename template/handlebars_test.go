@@ -0,0 +1,230 @@
+package template
+
+import (
+	"fmt"
+	"testing"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+func TestHandlebarsEngine(t *testing.T) {
+	kv, err := dep.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv.EnableBlocking()
+
+	svc, err := dep.NewHealthServiceQuery("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	brain := NewBrain()
+	brain.Remember(kv, "<bar>")
+	brain.Remember(svc, []*dep.HealthService{
+		{Name: "web", Address: "1.2.3.4", Port: 8080, Tags: []string{"a", "b"}},
+		{Name: "web", Address: "5.6.7.8", Port: 8081},
+	})
+
+	cases := []struct {
+		name string
+		c    string
+		e    string
+		err  bool
+	}{
+		{
+			"text",
+			"foo",
+			"foo",
+			false,
+		},
+		{
+			"helper",
+			`{{toUpper "foo"}}`,
+			"FOO",
+			false,
+		},
+		{
+			"escaped",
+			`{{key "foo"}}`,
+			"&lt;bar&gt;",
+			false,
+		},
+		{
+			"raw",
+			`{{{key "foo"}}} {{& key "foo"}}`,
+			"<bar> <bar>",
+			false,
+		},
+		{
+			"subexpression",
+			`{{toUpper (key "foo")}}`,
+			"&lt;BAR&gt;",
+			false,
+		},
+		{
+			"number_args",
+			`{{add 1 2}}`,
+			"3",
+			false,
+		},
+		{
+			"comments",
+			"{{! one }}a{{!-- two }} --}}b",
+			"ab",
+			false,
+		},
+		{
+			"each",
+			"{{#each (service \"web\")}}\n{{@index}}: {{Address}}:{{this.Port}}\n{{/each}}\n",
+			"0: 1.2.3.4:8080\n1: 5.6.7.8:8081\n",
+			false,
+		},
+		{
+			"each_else",
+			"{{#each (ls \"nope\")}}x{{else}}empty{{/each}}",
+			"empty",
+			false,
+		},
+		{
+			"each_nested",
+			`{{#each (service "web")}}{{#each Tags}}{{../Port}}{{this}}{{#if @last}};{{/if}}{{/each}}{{/each}}`,
+			"8080a8080b;",
+			false,
+		},
+		{
+			"if_else",
+			`{{#if (key "foo")}}yes{{else}}no{{/if}}{{#if (key "nope")}}yes{{else}}no{{/if}}`,
+			"yesno",
+			false,
+		},
+		{
+			"unless",
+			`{{#unless (key "foo")}}yes{{else}}no{{/unless}}`,
+			"no",
+			false,
+		},
+		{
+			"with",
+			`{{#with (lookup (service "web") 1)}}{{Address}}{{/with}}`,
+			"5.6.7.8",
+			false,
+		},
+		{
+			"lookup",
+			`{{lookup (lookup (service "web") 0) "Address"}}`,
+			"1.2.3.4",
+			false,
+		},
+		{
+			"whitespace_control",
+			"a  {{~ toUpper \"b\" ~}}  c",
+			"aBc",
+			false,
+		},
+		{
+			"standalone",
+			"a\n  {{#if true}}\n  b\n  {{/if}}\nc\n",
+			"a\n  b\nc\n",
+			false,
+		},
+		{
+			"delimiters_comment",
+			"{{!-- delimiters: [[ ]] --}}\n[[toUpper \"a\"]] {{b}}",
+			"A {{b}}",
+			false,
+		},
+		{
+			"unknown_helper",
+			`{{nope "a"}}`,
+			"",
+			true,
+		},
+		{
+			"helper_error",
+			`{{base64Decode "aGVsxxbG8="}}`,
+			"",
+			true,
+		},
+		{
+			"wrong_type",
+			`{{toUpper 1}}`,
+			"",
+			true,
+		},
+		{
+			"unclosed_block",
+			`{{#if true}}a`,
+			"",
+			true,
+		},
+		{
+			"mismatched_block",
+			`{{#if true}}a{{/each}}`,
+			"",
+			true,
+		},
+		{
+			"unknown_block",
+			`{{#nope true}}a{{/nope}}`,
+			"",
+			true,
+		},
+		{
+			"partial",
+			`{{> nope}}`,
+			"",
+			true,
+		},
+		{
+			"hash_args",
+			`{{toUpper s="a"}}`,
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tpl, err := NewTemplate(&NewTemplateInput{
+				Contents: tc.c,
+				Engine:   HandlebarsEngine,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := tpl.Execute(&ExecuteInput{Brain: brain})
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if err != nil {
+				return
+			}
+			if a := string(result.Output); a != tc.e {
+				t.Errorf("\nexp: %q\nact: %q", tc.e, a)
+			}
+		})
+	}
+
+	t.Run("used", func(t *testing.T) {
+		tpl, err := NewTemplate(&NewTemplateInput{
+			Contents: `{{key "foo"}}{{#each (service "web")}}{{Name}}{{/each}}{{key "nope"}}`,
+			Engine:   HandlebarsEngine,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := tpl.Execute(&ExecuteInput{Brain: brain})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Used.Len() != 3 {
+			t.Errorf("expected 3 used dependencies, got %d", result.Used.Len())
+		}
+		if result.Missing.Len() != 1 {
+			t.Errorf("expected 1 missing dependency, got %d", result.Missing.Len())
+		}
+	})
+}
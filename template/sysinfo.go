@@ -0,0 +1,118 @@
+package template
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// sysInfoTTL is how long collected system facts are cached before they are
+// collected again. Templates are rendered often, but the facts rarely change.
+const sysInfoTTL = 1 * time.Minute
+
+// SysInfo is the set of facts about the local system which are available to
+// templates through the sysinfo function.
+type SysInfo struct {
+	// CPUs is the number of logical CPUs usable by the process.
+	CPUs int
+
+	// Hostname is the host name reported by the kernel.
+	Hostname string
+
+	// Interfaces is the list of network interfaces and their addresses.
+	Interfaces []*SysInfoInterface
+
+	// Memory is the total amount of physical memory in bytes, or 0 if it cannot
+	// be determined on this platform.
+	Memory uint64
+}
+
+// SysInfoInterface is a network interface of the local system.
+type SysInfoInterface struct {
+	Name         string
+	HardwareAddr string
+	Addresses    []string
+	Up           bool
+	Loopback     bool
+}
+
+// sysInfoCache is the cached result of the last collection.
+var sysInfoCache struct {
+	sync.Mutex
+	info      *SysInfo
+	collected time.Time
+}
+
+// sysinfo returns facts about the local system, such as the number of CPUs, the
+// total memory, the host name and the network interfaces with their IPs.
+func sysinfo() (*SysInfo, error) {
+	sysInfoCache.Lock()
+	defer sysInfoCache.Unlock()
+
+	if sysInfoCache.info != nil && now().Sub(sysInfoCache.collected) < sysInfoTTL {
+		return sysInfoCache.info, nil
+	}
+
+	info, err := collectSysInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	sysInfoCache.info = info
+	sysInfoCache.collected = now()
+	return info, nil
+}
+
+// collectSysInfo collects the facts about the local system.
+func collectSysInfo() (*SysInfo, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("sysinfo: hostname: %s", err)
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("sysinfo: interfaces: %s", err)
+	}
+
+	interfaces := make([]*SysInfoInterface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("sysinfo: interface %s: %s", iface.Name, err)
+		}
+
+		ips := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			switch a := addr.(type) {
+			case *net.IPNet:
+				ips = append(ips, a.IP.String())
+			case *net.IPAddr:
+				ips = append(ips, a.IP.String())
+			}
+		}
+
+		interfaces = append(interfaces, &SysInfoInterface{
+			Name:         iface.Name,
+			HardwareAddr: iface.HardwareAddr.String(),
+			Addresses:    ips,
+			Up:           iface.Flags&net.FlagUp != 0,
+			Loopback:     iface.Flags&net.FlagLoopback != 0,
+		})
+	}
+
+	memory, err := totalMemory()
+	if err != nil {
+		return nil, fmt.Errorf("sysinfo: memory: %s", err)
+	}
+
+	return &SysInfo{
+		CPUs:       runtime.NumCPU(),
+		Hostname:   hostname,
+		Interfaces: interfaces,
+		Memory:     memory,
+	}, nil
+}
@@ -0,0 +1,47 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseMeminfo(t *testing.T) {
+	cases := []struct {
+		name string
+		i    string
+		e    uint64
+		err  bool
+	}{
+		{
+			"kb",
+			"MemTotal:       16318412 kB\nMemFree:         1024 kB\n",
+			16318412 * 1024,
+			false,
+		},
+		{
+			"missing",
+			"MemFree:         1024 kB\n",
+			0,
+			true,
+		},
+		{
+			"invalid",
+			"MemTotal:       lots kB\n",
+			0,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			a, err := parseMeminfo(strings.NewReader(tc.i))
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if a != tc.e {
+				t.Errorf("expected %d to be %d", a, tc.e)
+			}
+		})
+	}
+}
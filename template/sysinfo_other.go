@@ -0,0 +1,9 @@
+// +build !linux
+
+package template
+
+// totalMemory is not supported on platforms other than Linux, where 0 is
+// reported instead.
+func totalMemory() (uint64, error) {
+	return 0, nil
+}
@@ -0,0 +1,35 @@
+package template
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestSysinfo(t *testing.T) {
+	info, err := sysinfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.CPUs != runtime.NumCPU() {
+		t.Errorf("expected %d to be %d", info.CPUs, runtime.NumCPU())
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Hostname != hostname {
+		t.Errorf("expected %q to be %q", info.Hostname, hostname)
+	}
+
+	// The result is cached.
+	cached, err := sysinfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached != info {
+		t.Errorf("expected the cached result to be returned")
+	}
+}
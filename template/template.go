@@ -1,11 +1,23 @@
+// Package template parses and executes templates against the data in a Brain.
+// NewTemplate, Template.Execute, and their input and result structs are the
+// entry points for embedding Consul Template.
 package template
 
 import (
 	"bytes"
 	"crypto/md5"
 	"encoding/hex"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -21,6 +33,10 @@ var (
 	// does not specify either a "source" or "content" argument, which is not
 	// valid.
 	ErrTemplateMissingContentsAndSource = errors.New("template: must specify exactly one of 'source' or 'content'")
+
+	// delimitersRe matches the magic comment on the first line of a template
+	// which sets its delimiters, like "{{!-- delimiters: [[ ]] --}}".
+	delimitersRe = regexp.MustCompile(`\A[ \t]*\{\{!--[ \t]*delimiters:[ \t]*(\S+)[ \t]+(\S+)[ \t]*--\}\}[ \t]*(\r?\n|\z)`)
 )
 
 // Template is the internal representation of an individual template to process.
@@ -39,8 +55,36 @@ type Template struct {
 	leftDelim  string
 	rightDelim string
 
+	// engine is the name of the template engine. An empty value means the
+	// default engine.
+	engine string
+
+	// guard is a Go template pipeline which must hold for the rendered
+	// contents to be used. An empty value means no guard.
+	guard string
+
+	// varsFile is the path of the YAML or JSON file holding the values of the
+	// var function. An empty value means no vars file.
+	varsFile string
+
 	// hexMD5 stores the hex version of the MD5
 	hexMD5 string
+
+	// regexps caches the regular expressions compiled by the template.
+	regexps regexpCache
+
+	// vars caches the parsed contents of the vars file.
+	vars varsCache
+
+	// htpasswd caches the htpasswd lines rendered by the template.
+	htpasswd htpasswdCache
+
+	// timedOut is the execution of the template which timed out, while it is
+	// still running in the background.
+	timedOut timedOutExecution
+
+	// probes caches the results of the TCP probes of the template.
+	probes tcpProbeCache
 }
 
 // NewTemplateInput is used as input when creating the template.
@@ -54,6 +98,21 @@ type NewTemplateInput struct {
 	// LeftDelim and RightDelim are the template delimiters.
 	LeftDelim  string
 	RightDelim string
+
+	// Engine is the name of the template engine used to evaluate the contents.
+	// If unspecified, the default Go template engine is used.
+	Engine string
+
+	// Guard is a Go template pipeline, such as `ge (len (service "web")) 2`,
+	// which must evaluate to a true value for the rendered contents to be
+	// used. It is evaluated with the same functions and data as the contents,
+	// regardless of the engine.
+	Guard string
+
+	// VarsFile is the path of a YAML or JSON file holding static values, such
+	// as per-environment settings, which the var function returns. The file is
+	// watched for changes like any other file the template reads.
+	VarsFile string
 }
 
 // NewTemplate creates and parses a new Consul Template template at the given
@@ -72,11 +131,25 @@ func NewTemplate(i *NewTemplateInput) (*Template, error) {
 		return nil, ErrTemplateMissingContentsAndSource
 	}
 
+	if _, err := lookupEngine(i.Engine); err != nil {
+		return nil, err
+	}
+
 	var t Template
 	t.source = i.Source
 	t.contents = i.Contents
 	t.leftDelim = i.LeftDelim
 	t.rightDelim = i.RightDelim
+	t.engine = i.Engine
+	t.guard = strings.TrimSpace(i.Guard)
+	t.varsFile = i.VarsFile
+
+	if t.guard != "" {
+		tmpl := template.New("guard").Funcs(funcMap(&funcMapInput{}))
+		if _, err := tmpl.Parse(guardContents(t.guard)); err != nil {
+			return nil, errors.Wrap(err, "invalid guard")
+		}
+	}
 
 	if i.Source != "" {
 		contents, err := ioutil.ReadFile(i.Source)
@@ -86,8 +159,33 @@ func NewTemplate(i *NewTemplateInput) (*Template, error) {
 		t.contents = string(contents)
 	}
 
-	// Compute the MD5, encode as hex
-	hash := md5.Sum([]byte(t.contents))
+	// The ID is computed from the contents with the delimiters comment, so
+	// templates which only differ in their delimiters do not share it.
+	raw := t.contents
+	if left, right, rest, ok := parseDelimiters(t.contents); ok {
+		t.contents = rest
+		if t.leftDelim == "" && t.rightDelim == "" {
+			t.leftDelim, t.rightDelim = left, right
+		} else {
+			log.Printf("[WARN] (template) %s: ignoring the delimiters comment, "+
+				"since delimiters are configured", t.Source())
+		}
+	}
+
+	// Compute the MD5, encode as hex. The engine, guard, and vars file are
+	// only included when they are set so that existing template IDs do not
+	// change.
+	id := raw
+	if t.engine != "" && t.engine != DefaultEngine {
+		id = t.engine + ":" + id
+	}
+	if t.guard != "" {
+		id = "guard(" + t.guard + "):" + id
+	}
+	if t.varsFile != "" {
+		id = "vars(" + t.varsFile + "):" + id
+	}
+	hash := md5.Sum([]byte(id))
 	t.hexMD5 = hex.EncodeToString(hash[:])
 
 	return &t, nil
@@ -103,6 +201,19 @@ func (t *Template) Contents() string {
 	return t.contents
 }
 
+// TemplateOutputs returns the names of the templates whose output this
+// template uses, as far as they are given to templateOutput as literals.
+// Names computed while the template executes are only known then.
+func (t *Template) TemplateOutputs() []string {
+	switch t.engine {
+	case "", DefaultEngine:
+		return goTemplateOutputs(t.contents, t.leftDelim, t.rightDelim)
+	case HandlebarsEngine:
+		return hbTemplateOutputs(t.contents, t.leftDelim, t.rightDelim)
+	}
+	return nil
+}
+
 // Source returns the filepath source of this template.
 func (t *Template) Source() string {
 	if t.source == "" {
@@ -120,6 +231,14 @@ type ExecuteInput struct {
 	// Values specified here will take precedence over any values in the
 	// environment when using the `env` function.
 	Env []string
+
+	// RestrictEnv limits the `env` function to the values in Env, instead of
+	// falling back to the environment of the process.
+	RestrictEnv bool
+
+	// Timeout is the maximum amount of time the execution may take, including
+	// the template functions it calls. Zero means no limit.
+	Timeout time.Duration
 }
 
 // ExecuteResult is the result of the template execution.
@@ -132,6 +251,10 @@ type ExecuteResult struct {
 
 	// Output is the rendered result.
 	Output []byte
+
+	// GuardFailed is true if the template has a guard which did not hold, in
+	// which case the output must not be used.
+	GuardFailed bool
 }
 
 // Execute evaluates this template in the provided context.
@@ -141,42 +264,225 @@ func (t *Template) Execute(i *ExecuteInput) (*ExecuteResult, error) {
 	}
 
 	var used, missing dep.Set
+	probes := &tcpProbes{cache: &t.probes}
 
-	tmpl := template.New("")
-	tmpl.Delims(t.leftDelim, t.rightDelim)
-	tmpl.Funcs(funcMap(&funcMapInput{
-		t:       tmpl,
-		brain:   i.Brain,
-		env:     i.Env,
-		used:    &used,
-		missing: &missing,
-	}))
-
-	tmpl, err := tmpl.Parse(t.contents)
+	engine, err := lookupEngine(t.engine)
 	if err != nil {
-		return nil, errors.Wrap(err, "parse")
+		return nil, err
+	}
+
+	funcs := func(tmpl *template.Template) template.FuncMap {
+		return funcMap(&funcMapInput{
+			t:       tmpl,
+			brain:   i.Brain,
+			env:     i.Env,
+			envOnly: i.RestrictEnv,
+			used:    &used,
+			missing: &missing,
+			regexps: &t.regexps,
+			probes:  probes,
+
+			htpasswd: &t.htpasswd,
+
+			varsFile: t.varsFile,
+			vars:     &t.vars,
+		})
 	}
 
-	// Execute the template into the writer
+	// Execute the template into the writer, and then the guard, if any.
 	var b bytes.Buffer
-	if err := tmpl.Execute(&b, nil); err != nil {
-		return nil, errors.Wrap(err, "execute")
+	w := &cancelWriter{w: &b}
+	var guardFailed bool
+	execute := func() error {
+		if err := engine.Execute(w, &EngineInput{
+			Contents:   t.contents,
+			LeftDelim:  t.leftDelim,
+			RightDelim: t.rightDelim,
+			Funcs:      funcs,
+		}); err != nil {
+			return err
+		}
+
+		if t.guard != "" {
+			ok, err := evaluateGuard(t.guard, funcs)
+			if err != nil {
+				return err
+			}
+			guardFailed = !ok
+		}
+		return nil
+	}
+
+	if i.Timeout > 0 {
+		err = t.executeWithTimeout(execute, w.cancel, i.Timeout)
+	} else {
+		err = execute()
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	return &ExecuteResult{
-		Used:    &used,
-		Missing: &missing,
-		Output:  b.Bytes(),
+		Used:        &used,
+		Missing:     &missing,
+		Output:      b.Bytes(),
+		GuardFailed: guardFailed,
 	}, nil
 }
 
+// parseDelimiters returns the delimiters set by the magic comment on the first
+// line of the given contents, like "{{!-- delimiters: [[ ]] --}}", and the
+// contents without that line. The comment is always written with the default
+// delimiters. It returns false if there is no such comment.
+func parseDelimiters(contents string) (string, string, string, bool) {
+	m := delimitersRe.FindStringSubmatchIndex(contents)
+	if m == nil {
+		return "", "", "", false
+	}
+	left := contents[m[2]:m[3]]
+	right := contents[m[4]:m[5]]
+	return left, right, contents[m[1]:], true
+}
+
+// guardContents returns the contents of a Go template which renders "true"
+// if the guard holds.
+func guardContents(guard string) string {
+	return "{{ if " + guard + " }}true{{ end }}"
+}
+
+// evaluateGuard returns whether the guard holds, using the given functions.
+func evaluateGuard(guard string, funcs func(*template.Template) template.FuncMap) (bool, error) {
+	var b bytes.Buffer
+	err := (&goTemplateEngine{}).Execute(&b, &EngineInput{
+		Contents: guardContents(guard),
+		Funcs:    funcs,
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "guard")
+	}
+	return b.String() == "true", nil
+}
+
+// errExecutionCancelled is the error of the writes of an execution which
+// timed out.
+var errExecutionCancelled = errors.New("template: execution cancelled")
+
+// cancelWriter is a writer which fails once it is cancelled, which stops the
+// execution of a template writing to it at its next output.
+type cancelWriter struct {
+	w         io.Writer
+	cancelled int32
+}
+
+// Write implements io.Writer.
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	if atomic.LoadInt32(&w.cancelled) != 0 {
+		return 0, errExecutionCancelled
+	}
+	return w.w.Write(p)
+}
+
+// cancel makes the following writes fail.
+func (w *cancelWriter) cancel() {
+	atomic.StoreInt32(&w.cancelled, 1)
+}
+
+// timedOutExecution is the execution of a template which timed out and is
+// still running in the background.
+type timedOutExecution struct {
+	sync.Mutex
+
+	// done is closed when the execution finishes, and logged is set once the
+	// executions refused while it runs were logged.
+	done   chan struct{}
+	logged bool
+}
+
+// running returns true if the execution which timed out is still running.
+func (e *timedOutExecution) running() bool {
+	if e.done == nil {
+		return false
+	}
+	select {
+	case <-e.done:
+		e.done = nil
+		e.logged = false
+		return false
+	default:
+		return true
+	}
+}
+
+// executeWithTimeout runs the given execution, returning an error if it does
+// not finish within the timeout. Template execution cannot be interrupted, so
+// an execution which times out is cancelled, which stops it at its next
+// output, and is otherwise left to finish in the background. Until then, the
+// template is not executed again, so a hanging template does not pile up
+// executions on each render.
+func (t *Template) executeWithTimeout(execute func() error, cancel func(), timeout time.Duration) error {
+	t.timedOut.Lock()
+	if t.timedOut.running() {
+		if !t.timedOut.logged {
+			log.Printf("[WARN] (template) %s: not executing the template until "+
+				"its execution which timed out finishes", t.Source())
+			t.timedOut.logged = true
+		}
+		t.timedOut.Unlock()
+		return fmt.Errorf("template: the previous execution, which did not "+
+			"finish within the render timeout of %s, is still running", timeout)
+	}
+	t.timedOut.Unlock()
+
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		errCh <- execute()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(timeout):
+		cancel()
+
+		t.timedOut.Lock()
+		t.timedOut.done = done
+		t.timedOut.Unlock()
+
+		return fmt.Errorf("template: execution did not finish within the "+
+			"render timeout of %s", timeout)
+	}
+}
+
 // funcMapInput is input to the funcMap, which builds the template functions.
 type funcMapInput struct {
 	t       *template.Template
 	brain   *Brain
 	env     []string
+	envOnly bool
 	used    *dep.Set
 	missing *dep.Set
+	regexps *regexpCache
+	probes  *tcpProbes
+
+	htpasswd *htpasswdCache
+
+	// varsFile is the path of the vars file read by the var function, and
+	// vars caches its parsed contents.
+	varsFile string
+	vars     *varsCache
+}
+
+// Functions returns the sorted names of the functions available to templates.
+func Functions() []string {
+	fm := funcMap(&funcMapInput{})
+	names := make([]string, 0, len(fm))
+	for name := range fm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // funcMap is the map of template functions to their respective functions.
@@ -185,60 +491,117 @@ func funcMap(i *funcMapInput) template.FuncMap {
 
 	return template.FuncMap{
 		// API functions
-		"datacenters":  datacentersFunc(i.brain, i.used, i.missing),
-		"file":         fileFunc(i.brain, i.used, i.missing),
-		"key":          keyFunc(i.brain, i.used, i.missing),
-		"keyExists":    keyExistsFunc(i.brain, i.used, i.missing),
-		"keyOrDefault": keyWithDefaultFunc(i.brain, i.used, i.missing),
-		"ls":           lsFunc(i.brain, i.used, i.missing),
-		"node":         nodeFunc(i.brain, i.used, i.missing),
-		"nodes":        nodesFunc(i.brain, i.used, i.missing),
-		"secret":       secretFunc(i.brain, i.used, i.missing),
-		"secrets":      secretsFunc(i.brain, i.used, i.missing),
-		"service":      serviceFunc(i.brain, i.used, i.missing),
-		"services":     servicesFunc(i.brain, i.used, i.missing),
-		"tree":         treeFunc(i.brain, i.used, i.missing),
+		"caLeaf":                caLeafFunc(i.brain, i.used, i.missing),
+		"caRoots":               caRootsFunc(i.brain, i.used, i.missing),
+		"checks":                checksFunc(i.brain, i.used, i.missing),
+		"configEntry":           configEntryFunc(i.brain, i.used, i.missing),
+		"consulProxyDefaults":   proxyDefaultsFunc(i.brain, i.used, i.missing),
+		"consulServiceDefaults": configEntryKindFunc(i.brain, i.used, i.missing, dep.ConfigEntryServiceDefaults),
+		"consulServiceRouter":   configEntryKindFunc(i.brain, i.used, i.missing, dep.ConfigEntryServiceRouter),
+		"datacenters":           datacentersFunc(i.brain, i.used, i.missing),
+		"file":                  fileFunc(i.brain, i.used, i.missing),
+		"fileExists":            fileExistsFunc(i.brain, i.used, i.missing),
+		"generateSecret":        generateSecretFunc(i.brain, i.used, i.missing),
+		"key":                   keyFunc(i.brain, i.used, i.missing),
+		"keyBool":               keyBoolFunc(i.brain, i.used, i.missing),
+		"keyCascade":            keyCascadeFunc(i.brain, i.used, i.missing),
+		"keyDuration":           keyDurationFunc(i.brain, i.used, i.missing),
+		"keyExists":             keyExistsFunc(i.brain, i.used, i.missing),
+		"keyInt":                keyIntFunc(i.brain, i.used, i.missing),
+		"keyJSON":               keyJSONFunc(i.brain, i.used, i.missing),
+		"keyOrDefault":          keyWithDefaultFunc(i.brain, i.used, i.missing),
+		"keyWithFallbackDC":     keyWithFallbackDCFunc(i.brain, i.used, i.missing),
+		"lockHolder":            lockHolderFunc(i.brain, i.used, i.missing),
+		"ls":                    lsFunc(i.brain, i.used, i.missing),
+		"node":                  nodeFunc(i.brain, i.used, i.missing),
+		"nodes":                 nodesFunc(i.brain, i.used, i.missing),
+		"runners":               runnersFunc(i.brain, i.used, i.missing),
+		"secret":                secretFunc(i.brain, i.used, i.missing),
+		"secretVersions":        secretVersionsFunc(i.brain, i.used, i.missing),
+		"secrets":               secretsFunc(i.brain, i.used, i.missing),
+		"secretsRecursive":      secretsRecursiveFunc(i.brain, i.used, i.missing),
+		"selfToken":             selfTokenFunc(i.brain, i.used, i.missing),
+		"service":               serviceFunc(i.brain, i.used, i.missing),
+		"serviceCount":          serviceCountFunc(i.brain, i.used, i.missing),
+		"serviceHealthSummary":  serviceHealthSummaryFunc(i.brain, i.used, i.missing),
+		"services":              servicesFunc(i.brain, i.used, i.missing),
+		"stat":                  statFunc(i.brain, i.used, i.missing),
+		"templateOutput":        templateOutputFunc(i.brain, i.used, i.missing),
+		"tree":                  treeFunc(i.brain, i.used, i.missing),
+		"var":                   varFunc(i.brain, i.used, i.missing, i.varsFile, i.vars),
 
 		// Scratch
 		"scratch": func() *Scratch { return &scratch },
 
 		// Helper functions
-		"base64Decode":    base64Decode,
-		"base64Encode":    base64Encode,
-		"base64URLDecode": base64URLDecode,
-		"base64URLEncode": base64URLEncode,
-		"byKey":           byKey,
-		"byTag":           byTag,
-		"contains":        contains,
-		"containsAll":     containsSomeFunc(true, true),
-		"containsAny":     containsSomeFunc(false, false),
-		"containsNone":    containsSomeFunc(true, false),
-		"containsNotAll":  containsSomeFunc(false, true),
-		"env":             envFunc(i.env),
-		"executeTemplate": executeTemplateFunc(i.t),
-		"explode":         explode,
-		"in":              in,
-		"loop":            loop,
-		"join":            join,
-		"trimSpace":       trimSpace,
-		"parseBool":       parseBool,
-		"parseFloat":      parseFloat,
-		"parseInt":        parseInt,
-		"parseJSON":       parseJSON,
-		"parseUint":       parseUint,
-		"plugin":          plugin,
-		"regexReplaceAll": regexReplaceAll,
-		"regexMatch":      regexMatch,
-		"replaceAll":      replaceAll,
-		"timestamp":       timestamp,
-		"toLower":         toLower,
-		"toJSON":          toJSON,
-		"toJSONPretty":    toJSONPretty,
-		"toTitle":         toTitle,
-		"toTOML":          toTOML,
-		"toUpper":         toUpper,
-		"toYAML":          toYAML,
-		"split":           split,
+		"base64Decode":       base64Decode,
+		"base64RawURLDecode": base64RawURLDecode,
+		"base64RawURLEncode": base64RawURLEncode,
+		"base64Encode":       base64Encode,
+		"base64URLDecode":    base64URLDecode,
+		"base64URLEncode":    base64URLEncode,
+		"byKey":              byKey,
+		"byTag":              byTag,
+		"contains":           contains,
+		"containsAll":        containsSomeFunc(true, true),
+		"containsAny":        containsSomeFunc(false, false),
+		"containsNone":       containsSomeFunc(true, false),
+		"containsNotAll":     containsSomeFunc(false, true),
+		"dateFormat":         dateFormat,
+		"debugDump":          debugDump,
+		"env":                envFunc(i.env, i.envOnly),
+		"envFile":            envFile,
+		"executeTemplate":    executeTemplateFunc(i.t),
+		"explode":            explode,
+		"hexDecode":          hexDecode,
+		"hexEncode":          hexEncode,
+		"htpasswdEntry":      htpasswdEntryFunc(i.htpasswd),
+		"in":                 in,
+		"indent":             indent,
+		"javaProperties":     javaProperties,
+		"loop":               loop,
+		"mergeMaps":          mergeMaps,
+		"nindent":            nindent,
+		"now":                currentTime,
+		"join":               join,
+		"trimPrefix":         trimPrefix,
+		"trimSpace":          trimSpace,
+		"trimSuffix":         trimSuffix,
+		"parseBool":          parseBool,
+		"parseDuration":      parseDuration,
+		"parseFloat":         parseFloat,
+		"parseInt":           parseInt,
+		"parseJSON":          parseJSON,
+		"parseTime":          parseTime,
+		"parseUint":          parseUint,
+		"plugin":             plugin,
+		"quote":              quote,
+		"randAlphaNum":       randAlphaNum,
+		"regexFind":          regexFindFunc(i.regexps),
+		"regexFindAll":       regexFindAllFunc(i.regexps),
+		"regexReplaceAll":    regexReplaceAllFunc(i.regexps),
+		"regexMatch":         regexMatchFunc(i.regexps),
+		"regexSplit":         regexSplitFunc(i.regexps),
+		"replaceAll":         replaceAll,
+		"sysinfo":            sysinfo,
+		"timeAdd":            timeAdd,
+		"timeSub":            timeSub,
+		"timestamp":          timestamp,
+		"toLower":            toLower,
+		"toJSON":             toJSON,
+		"toJSONPretty":       toJSONPretty,
+		"toTitle":            toTitle,
+		"toTOML":             toTOML,
+		"toUpper":            toUpper,
+		"toYAML":             toYAML,
+		"unixToTime":         unixToTime,
+		"urlDecode":          urlDecode,
+		"urlEncode":          urlEncode,
+		"split":              split,
+		"squote":             squote,
+		"stableRand":         stableRand,
+		"tcpProbe":           tcpProbeFunc(i.probes),
+		"uuidv4":             uuidv4,
 
 		// Math functions
 		"add":      add,
@@ -246,6 +609,14 @@ func funcMap(i *funcMapInput) template.FuncMap {
 		"multiply": multiply,
 		"divide":   divide,
 		"modulo":   modulo,
+		"sub":      subtract,
+		"mul":      multiply,
+		"div":      divide,
+		"mod":      modulo,
+		"max":      maximum,
+		"min":      minimum,
+		"round":    round,
+		"ceil":     ceil,
 
 		// Deprecated functions
 		"key_or_default": keyWithDefaultFunc(i.brain, i.used, i.missing),
@@ -4,8 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"log"
+	"net"
 	"os"
 	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -78,6 +84,28 @@ func TestNewTemplate(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"unknown_engine",
+			&NewTemplateInput{
+				Contents: "test",
+				Engine:   "nope",
+			},
+			nil,
+			true,
+		},
+		{
+			"default_engine",
+			&NewTemplateInput{
+				Contents: "test",
+				Engine:   DefaultEngine,
+			},
+			&Template{
+				contents: "test",
+				engine:   DefaultEngine,
+				hexMD5:   "098f6bcd4621d373cade4e832627b4f6",
+			},
+			false,
+		},
 		{
 			"custom_delims",
 			&NewTemplateInput{
@@ -93,6 +121,80 @@ func TestNewTemplate(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"delimiters_comment",
+			&NewTemplateInput{
+				Contents: "{{!-- delimiters: [[ ]] --}}\ntest",
+			},
+			&Template{
+				contents:   "test",
+				hexMD5:     "775b7bfbbc382e93946a8a8c56b31da6",
+				leftDelim:  "[[",
+				rightDelim: "]]",
+			},
+			false,
+		},
+		{
+			"delimiters_comment_configured",
+			&NewTemplateInput{
+				Contents:   "{{!-- delimiters: [[ ]] --}}\ntest",
+				LeftDelim:  "<<",
+				RightDelim: ">>",
+			},
+			&Template{
+				contents:   "test",
+				hexMD5:     "775b7bfbbc382e93946a8a8c56b31da6",
+				leftDelim:  "<<",
+				rightDelim: ">>",
+			},
+			false,
+		},
+		{
+			"delimiters_comment_not_first_line",
+			&NewTemplateInput{
+				Contents: "test\n{{!-- delimiters: [[ ]] --}}",
+			},
+			&Template{
+				contents: "test\n{{!-- delimiters: [[ ]] --}}",
+				hexMD5:   "0efffb9514c4bb2da900b04b7c367ed3",
+			},
+			false,
+		},
+		{
+			"guard",
+			&NewTemplateInput{
+				Contents: "test",
+				Guard:    " true ",
+			},
+			&Template{
+				contents: "test",
+				guard:    "true",
+				hexMD5:   "53e8aff43e83c929f5688141d38ec52e",
+			},
+			false,
+		},
+		{
+			"vars_file",
+			&NewTemplateInput{
+				Contents: "test",
+				VarsFile: "/etc/ct/values.yaml",
+			},
+			&Template{
+				contents: "test",
+				varsFile: "/etc/ct/values.yaml",
+				hexMD5:   "83be65b82a20a8e1e4c303b080279230",
+			},
+			false,
+		},
+		{
+			"invalid_guard",
+			&NewTemplateInput{
+				Contents: "test",
+				Guard:    `ge (len (service "web") 2`,
+			},
+			nil,
+			true,
+		},
 	}
 
 	for i, tc := range cases {
@@ -132,6 +234,13 @@ func TestTemplate_Execute(t *testing.T) {
 			"test",
 			false,
 		},
+		{
+			"delimiters_comment",
+			"{{!-- delimiters: [[ ]] --}}\n[[ \"a\" | toUpper ]] {{ .Values }}",
+			nil,
+			"A {{ .Values }}",
+			false,
+		},
 		{
 			"bad_func",
 			`{{ bad_func }}`,
@@ -210,1089 +319,3111 @@ func TestTemplate_Execute(t *testing.T) {
 			false,
 		},
 		{
-			"func_file",
-			`{{ file "/path/to/file" }}`,
+			"func_datacenters_healthy",
+			`{{ datacenters "healthy" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewFileQuery("/path/to/file")
+					d, err := dep.NewHealthyCatalogDatacentersQuery()
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, "content")
+					b.Remember(d, []string{"dc1"})
 					return b
 				}(),
 			},
-			"content",
+			"[dc1]",
 			false,
 		},
 		{
-			"func_key",
-			`{{ key "key" }}`,
+			"func_file",
+			`{{ file "/path/to/file" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewKVGetQuery("key")
+					d, err := dep.NewFileQuery("/path/to/file")
 					if err != nil {
 						t.Fatal(err)
 					}
-					d.EnableBlocking()
-					b.Remember(d, "5")
+					b.Remember(d, "content")
 					return b
 				}(),
 			},
-			"5",
+			"content",
 			false,
 		},
 		{
-			"func_keyExists",
-			`{{ keyExists "key" }} {{ keyExists "no_key" }}`,
+			"func_fileExists",
+			`{{ fileExists "/path/to/file" }} {{ fileExists "/path/to/missing" }} {{ fileExists "/path/to/unfetched" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewKVGetQuery("key")
+					d, err := dep.NewFileStatQuery("/path/to/file")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, true)
+					b.Remember(d, &dep.FileStat{Exists: true, Name: "file"})
+					d, err = dep.NewFileStatQuery("/path/to/missing")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, &dep.FileStat{Name: "missing"})
 					return b
 				}(),
 			},
-			"true false",
+			"true false false",
 			false,
 		},
 		{
-			"func_keyOrDefault",
-			`{{ keyOrDefault "key" "100" }} {{ keyOrDefault "no_key" "200" }}`,
+			"func_generateSecret",
+			`{{ generateSecret "secret/data/db" "key=password" "length=16" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewKVGetQuery("key")
+					d, err := dep.NewVaultGenerateQuery("secret/data/db", "key=password", "length=16")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, "150")
+					b.Remember(d, "s3cr3t")
 					return b
 				}(),
 			},
-			"150 200",
+			"s3cr3t",
 			false,
 		},
 		{
-			"func_ls",
-			`{{ range ls "list" }}{{ .Key }}={{ .Value }}{{ end }}`,
+			"func_generateSecret_bad_spec",
+			`{{ generateSecret "secret/data/db" "length=none" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"func_selfToken",
+			`{{ with selfToken }}{{ .Description }} {{ .HasPolicy "kv-read" }} {{ .HasPolicy "kv-write" }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewKVListQuery("list")
+					d, err := dep.NewACLTokenSelfQuery()
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.KeyPair{
-						&dep.KeyPair{Key: "", Value: ""},
-						&dep.KeyPair{Key: "foo", Value: "bar"},
-						&dep.KeyPair{Key: "foo/zip", Value: "zap"},
+					b.Remember(d, &dep.ACLToken{
+						Description: "consul-template",
+						Policies: []*dep.ACLLink{
+							&dep.ACLLink{Name: "kv-read"},
+						},
 					})
 					return b
 				}(),
 			},
-			"foo=bar",
+			"consul-template true false",
 			false,
 		},
 		{
-			"func_node",
-			`{{ with node }}{{ .Node.Node }}{{ range .Services }}{{ .Service }}{{ end }}{{ end }}`,
+			"func_selfToken_missing",
+			`{{ selfToken.HasPolicy "kv-read" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"false",
+			false,
+		},
+		{
+			"func_consulServiceDefaults",
+			`{{ with consulServiceDefaults "web" }}{{ .Protocol }}{{ end }} {{ with consulServiceDefaults "db" }}db{{ end }} {{ with consulProxyDefaults "@dc1" }}{{ .Config.protocol }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewCatalogNodeQuery("")
+					for k, v := range map[string]interface{}{
+						"web": &dep.ConfigEntry{Protocol: "http"},
+						"db":  nil,
+					} {
+						d, err := dep.NewConfigEntryQuery(dep.ConfigEntryServiceDefaults, k)
+						if err != nil {
+							t.Fatal(err)
+						}
+						b.Remember(d, v)
+					}
+					d, err := dep.NewConfigEntryQuery(dep.ConfigEntryProxyDefaults, "global@dc1")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, &dep.CatalogNode{
-						Node: &dep.Node{Node: "node1"},
-						Services: []*dep.CatalogNodeService{
-							&dep.CatalogNodeService{
-								Service: "service1",
-							},
-						},
+					b.Remember(d, &dep.ConfigEntry{
+						Config: map[string]interface{}{"protocol": "grpc"},
 					})
 					return b
 				}(),
 			},
-			"node1service1",
+			"http  grpc",
 			false,
 		},
 		{
-			"func_nodes",
-			`{{ range nodes }}{{ .Node }}{{ end }}`,
+			"func_configEntry",
+			`{{ range (configEntry "service-router" "web").Routes }}{{ .Destination.Service }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewCatalogNodesQuery("")
+					d, err := dep.NewConfigEntryQuery(dep.ConfigEntryServiceRouter, "web")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.Node{
-						&dep.Node{Node: "node1"},
-						&dep.Node{Node: "node2"},
+					b.Remember(d, &dep.ConfigEntry{
+						Routes: []map[string]interface{}{
+							{"Destination": map[string]interface{}{"Service": "admin"}},
+						},
 					})
 					return b
 				}(),
 			},
-			"node1node2",
+			"admin",
 			false,
 		},
 		{
-			"func_secret_read",
-			`{{ with secret "secret/foo" }}{{ .Data.zip }}{{ end }}`,
+			"func_configEntry_bad_kind",
+			`{{ configEntry "mesh" "web" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"func_caLeaf",
+			`{{ with caLeaf "web" }}{{ .ServiceURI }}{{ end }}{{ range caRoots }} {{ .ID }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewVaultReadQuery("secret/foo")
+					d, err := dep.NewConnectLeafQuery("web")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, &dep.Secret{
-						LeaseID:       "abcd1234",
-						LeaseDuration: 120,
-						Renewable:     true,
-						Data:          map[string]interface{}{"zip": "zap"},
+					b.Remember(d, &dep.LeafCert{
+						ServiceURI: "spiffe://1111.consul/ns/default/dc/dc1/svc/web",
 					})
+					r, err := dep.NewConnectCAQuery()
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(r, []*dep.CARoot{{ID: "b"}, {ID: "a"}})
 					return b
 				}(),
 			},
-			"zap",
+			"spiffe://1111.consul/ns/default/dc/dc1/svc/web b a",
 			false,
 		},
 		{
-			"func_secret_read_no_exist",
-			`{{ with secret "secret/nope" }}{{ .Data.zip }}{{ end }}`,
+			"func_caLeaf_bad_service",
+			`{{ caLeaf "web@dc1" }}`,
 			&ExecuteInput{
-				Brain: func() *Brain {
-					return NewBrain()
-				}(),
+				Brain: NewBrain(),
 			},
 			"",
-			false,
+			true,
 		},
 		{
-			"func_secret_read_no_exist_falsey",
-			`{{ if secret "secret/nope" }}yes{{ else }}no{{ end }}`,
+			"func_stat",
+			`{{ with stat "/path/to/file" }}{{ .Name }} {{ .Size }} {{ .Mode }}{{ end }}{{ with stat "/path/to/missing" }}missing{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					return NewBrain()
+					b := NewBrain()
+					d, err := dep.NewFileStatQuery("/path/to/file")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, &dep.FileStat{
+						Exists: true,
+						Mode:   0644,
+						Name:   "file",
+						Size:   5,
+					})
+					d, err = dep.NewFileStatQuery("/path/to/missing")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, &dep.FileStat{Name: "missing"})
+					return b
 				}(),
 			},
-			"no",
+			"file 5 -rw-r--r--",
 			false,
 		},
 		{
-			"func_secret_write",
-			`{{ with secret "transit/encrypt/foo" "plaintext=a" }}{{ .Data.ciphertext }}{{ end }}`,
+			"func_templateOutput",
+			`{{ templateOutput "certs" | parseJSON | len }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewVaultWriteQuery("transit/encrypt/foo", map[string]interface{}{
-						"plaintext": "a",
-					})
+					d, err := dep.NewTemplateOutputQuery("certs")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, &dep.Secret{
-						LeaseID:       "abcd1234",
-						LeaseDuration: 120,
-						Renewable:     true,
-						Data:          map[string]interface{}{"ciphertext": "encrypted"},
-					})
+					b.Remember(d, `["a", "b"]`)
 					return b
 				}(),
 			},
-			"encrypted",
+			"2",
 			false,
 		},
 		{
-			"func_secret_write_no_exist",
-			`{{ with secret "secret/nope" "a=b" }}{{ .Data.zip }}{{ end }}`,
+			"func_key",
+			`{{ key "key" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					return NewBrain()
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("key")
+					if err != nil {
+						t.Fatal(err)
+					}
+					d.EnableBlocking()
+					b.Remember(d, "5")
+					return b
 				}(),
 			},
-			"",
+			"5",
 			false,
 		},
 		{
-			"func_secret_write_no_exist_falsey",
-			`{{ if secret "secret/nope" "a=b" }}yes{{ else }}no{{ end }}`,
+			"func_keyBool",
+			`{{ if keyBool "key" }}yes{{ end }} {{ keyBool "no_key" "false" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					return NewBrain()
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("key")
+					if err != nil {
+						t.Fatal(err)
+					}
+					d.EnableBlocking()
+					b.Remember(d, " true")
+					return b
 				}(),
 			},
-			"no",
+			"yes false",
 			false,
 		},
 		{
-			"func_secret_no_exist_falsey_with",
-			`{{ with secret "secret/nope" }}{{ .Data.foo.bar }}{{ end }}`,
+			"func_keyDuration",
+			`{{ keyDuration "key" }} {{ keyDuration "no_key" "1m" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					return NewBrain()
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("key")
+					if err != nil {
+						t.Fatal(err)
+					}
+					d.EnableBlocking()
+					b.Remember(d, "90s")
+					return b
 				}(),
 			},
-			"",
+			"1m30s 1m0s",
 			false,
 		},
 		{
-			"func_secrets",
-			`{{ secrets "secret/" }}`,
+			"func_keyInt",
+			`{{ add (keyInt "key") 1 }} {{ keyInt "no_key" "10" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewVaultListQuery("secret/")
+					d, err := dep.NewKVGetQuery("key")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []string{"bar", "foo"})
+					d.EnableBlocking()
+					b.Remember(d, "8080")
 					return b
 				}(),
 			},
-			"[bar foo]",
+			"8081 10",
 			false,
 		},
 		{
-			"func_secrets_no_exist",
-			`{{ secrets "secret/" }}`,
+			"func_keyInt_default_empty",
+			`{{ keyInt "key" "10" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					return NewBrain()
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("key")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, "")
+					return b
 				}(),
 			},
-			"[]",
+			"10",
 			false,
 		},
 		{
-			"func_secrets_no_exist_falsey",
-			`{{ if secrets "secret/" }}yes{{ else }}no{{ end }}`,
+			"func_keyInt_invalid",
+			`{{ keyInt "key" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					return NewBrain()
-				}(),
-			},
-			"no",
-			false,
-		},
-		{
-			"func_secrets_no_exist_falsey_with",
-			`{{ with secrets "secret/" }}{{ . }}{{ end }}`,
-			&ExecuteInput{
-				Brain: func() *Brain {
-					return NewBrain()
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("key")
+					if err != nil {
+						t.Fatal(err)
+					}
+					d.EnableBlocking()
+					b.Remember(d, "eighty")
+					return b
 				}(),
 			},
 			"",
-			false,
+			true,
 		},
 		{
-			"func_service",
-			`{{ range service "webapp" }}{{ .Address }}{{ end }}`,
+			"func_keyJSON",
+			`{{ with keyJSON "key" }}{{ .port }}{{ end }} {{ (keyJSON "no_key" "{\"a\":[1]}").a }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
+					d, err := dep.NewKVGetQuery("key")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Node:    "node1",
-							Address: "1.2.3.4",
-						},
-						&dep.HealthService{
-							Node:    "node2",
-							Address: "5.6.7.8",
-						},
-					})
+					d.EnableBlocking()
+					b.Remember(d, `{"port":8080}`)
 					return b
 				}(),
 			},
-			"1.2.3.45.6.7.8",
+			"8080 [1]",
 			false,
 		},
 		{
-			"func_service_filter",
-			`{{ range service "webapp" "passing,any" }}{{ .Address }}{{ end }}`,
+			"func_keyExists",
+			`{{ keyExists "key" }} {{ keyExists "no_key" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp|passing,any")
+					d, err := dep.NewKVGetQuery("key")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Node:    "node1",
-							Address: "1.2.3.4",
-						},
-						&dep.HealthService{
-							Node:    "node2",
-							Address: "5.6.7.8",
-						},
-					})
+					b.Remember(d, true)
 					return b
 				}(),
 			},
-			"1.2.3.45.6.7.8",
+			"true false",
 			false,
 		},
 		{
-			"func_services",
-			`{{ range services }}{{ .Name }}{{ end }}`,
+			"func_keyCascade",
+			`{{ keyCascade "port" "host/a/" "role/web/" "global/" }} {{ keyCascade "log" "host/a/" "role/web/" "global/" }} {{ keyCascade "none" "host/a" "global" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewCatalogServicesQuery("")
-					if err != nil {
-						t.Fatal(err)
+					for k, v := range map[string]interface{}{
+						"host/a/port":   nil,
+						"role/web/port": "8080",
+						"global/port":   "80",
+						"host/a/log":    nil,
+						"role/web/log":  nil,
+						"global/log":    "info",
+						"host/a/none":   nil,
+						"global/none":   nil,
+					} {
+						d, err := dep.NewKVGetQuery(k)
+						if err != nil {
+							t.Fatal(err)
+						}
+						b.Remember(d, v)
 					}
-					b.Remember(d, []*dep.CatalogSnippet{
-						&dep.CatalogSnippet{
-							Name: "service1",
-						},
-						&dep.CatalogSnippet{
-							Name: "service2",
-						},
-					})
 					return b
 				}(),
 			},
-			"service1service2",
+			"8080 info ",
 			false,
 		},
 		{
-			"func_tree",
-			`{{ range tree "key" }}{{ .Key }}={{ .Value }}{{ end }}`,
+			"func_keyCascade_missing",
+			`{{ keyCascade "port" "host/a/" "global/" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewKVListQuery("key")
+					d, err := dep.NewKVGetQuery("global/port")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.KeyPair{
-						&dep.KeyPair{Key: "", Value: ""},
-						&dep.KeyPair{Key: "admin/port", Value: "1134"},
-						&dep.KeyPair{Key: "maxconns", Value: "5"},
-						&dep.KeyPair{Key: "minconns", Value: "2"},
-					})
+					b.Remember(d, "80")
 					return b
 				}(),
 			},
-			"admin/port=1134maxconns=5minconns=2",
+			"",
 			false,
 		},
-
-		// scratch
 		{
-			"scratch.Key",
-			`{{ scratch.Set "a" "2" }}{{ scratch.Key "a" }}`,
+			"func_keyCascade_no_prefix",
+			`{{ keyCascade "port" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"true",
-			false,
+			"",
+			true,
 		},
 		{
-			"scratch.Get",
-			`{{ scratch.Set "a" "2" }}{{ scratch.Get "a" }}`,
+			"func_keyWithFallbackDC",
+			`{{ keyWithFallbackDC "port" "" "dc1" "dc2" }} {{ keyWithFallbackDC "log" "dc1" "dc2" }} {{ keyWithFallbackDC "none" "dc1" }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					for k, v := range map[string]interface{}{
+						"port":     nil,
+						"port@dc1": "8080",
+						"log":      "debug",
+						"log@dc1":  nil,
+						"log@dc2":  "info",
+						"none@dc1": nil,
+					} {
+						d, err := dep.NewKVGetQuery(k)
+						if err != nil {
+							t.Fatal(err)
+						}
+						b.Remember(d, v)
+					}
+					return b
+				}(),
 			},
-			"2",
+			"8080 info ",
 			false,
 		},
 		{
-			"scratch.SetX",
-			`{{ scratch.SetX "a" "2" }}{{ scratch.SetX "a" "1" }}{{ scratch.Get "a" }}`,
+			"func_keyWithFallbackDC_missing",
+			`{{ keyWithFallbackDC "port" "dc1" "dc2" }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("port@dc2")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, "80")
+					return b
+				}(),
 			},
-			"2",
+			"",
 			false,
 		},
 		{
-			"scratch.MapSet",
-			`{{ scratch.MapSet "a" "foo" "bar" }}{{ scratch.MapValues "a" }}`,
+			"func_keyWithFallbackDC_no_dc",
+			`{{ keyWithFallbackDC "port" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"[bar]",
-			false,
+			"",
+			true,
 		},
 		{
-			"scratch.MapSetX",
-			`{{ scratch.MapSetX "a" "foo" "bar" }}{{ scratch.MapSetX "a" "foo" "baz" }}{{ scratch.MapValues "a" }}`,
+			"func_keyWithFallbackDC_key_dc",
+			`{{ keyWithFallbackDC "port@dc1" "dc2" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"[bar]",
-			false,
+			"",
+			true,
 		},
-
-		// helpers
 		{
-			"helper_by_key",
-			`{{ range $key, $pairs := tree "list" | byKey }}{{ $key }}:{{ range $pairs }}{{ .Key }}={{ .Value }}{{ end }}{{ end }}`,
+			"func_keyOrDefault",
+			`{{ keyOrDefault "key" "100" }} {{ keyOrDefault "no_key" "200" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewKVListQuery("list")
+					d, err := dep.NewKVGetQuery("key")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.KeyPair{
-						&dep.KeyPair{Key: "", Value: ""},
-						&dep.KeyPair{Key: "foo/bar", Value: "a"},
-						&dep.KeyPair{Key: "zip/zap", Value: "b"},
-					})
+					b.Remember(d, "150")
 					return b
 				}(),
 			},
-			"foo:bar=azip:zap=b",
+			"150 200",
 			false,
 		},
 		{
-			"helper_by_tag",
-			`{{ range $tag, $services := service "webapp" | byTag }}{{ $tag }}:{{ range $services }}{{ .Address }}{{ end }}{{ end }}`,
+			"func_lockHolder",
+			`{{ with lockHolder "service/app/leader" }}{{ .Node }} {{ .Value }}{{ else }}none{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
+					d, err := dep.NewKVLockQuery("service/app/leader")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"prod", "staging"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"staging"},
-						},
+					b.Remember(d, &dep.KVLock{
+						Key:     "service/app/leader",
+						Session: "abcd",
+						Node:    "node1",
+						Value:   "app1",
 					})
 					return b
 				}(),
 			},
-			"prod:1.2.3.4staging:1.2.3.45.6.7.8",
+			"node1 app1",
 			false,
 		},
 		{
-			"helper_contains",
-			`{{ range service "webapp" }}{{ if .Tags | contains "prod" }}{{ .Address }}{{ end }}{{ end }}`,
+			"func_lockHolder_unlocked",
+			`{{ with lockHolder "service/app/leader" }}{{ .Node }}{{ else }}none{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
+					d, err := dep.NewKVLockQuery("service/app/leader")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"prod", "staging"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"staging"},
-						},
-					})
+					b.Remember(d, nil)
 					return b
 				}(),
 			},
-			"1.2.3.4",
+			"none",
 			false,
 		},
 		{
-			"helper_containsAll",
-			`{{ $requiredTags := parseJSON "[\"prod\",\"us-realm\"]" }}{{ range service "webapp" }}{{ if .Tags | containsAll $requiredTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"func_ls",
+			`{{ range ls "list" }}{{ .Key }}={{ .Value }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
+					d, err := dep.NewKVListQuery("list")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"prod", "us-realm"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"prod", "ca-realm"},
-						},
+					b.Remember(d, []*dep.KeyPair{
+						&dep.KeyPair{Key: "", Value: ""},
+						&dep.KeyPair{Key: "foo", Value: "bar"},
+						&dep.KeyPair{Key: "foo/zip", Value: "zap"},
 					})
 					return b
 				}(),
 			},
-			"1.2.3.4",
+			"foo=bar",
 			false,
 		},
 		{
-			"helper_containsAll__empty",
-			`{{ $requiredTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsAll $requiredTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"func_node",
+			`{{ with node }}{{ .Node.Node }}{{ range .Services }}{{ .Service }}{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
+					d, err := dep.NewCatalogNodeQuery("")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"prod", "us-realm"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"prod", "ca-realm"},
+					b.Remember(d, &dep.CatalogNode{
+						Node: &dep.Node{Node: "node1"},
+						Services: []*dep.CatalogNodeService{
+							&dep.CatalogNodeService{
+								Service: "service1",
+							},
 						},
 					})
 					return b
 				}(),
 			},
-			"1.2.3.45.6.7.8",
+			"node1service1",
 			false,
 		},
 		{
-			"helper_containsAny",
-			`{{ $acceptableTags := parseJSON "[\"v2\",\"v3\"]" }}{{ range service "webapp" }}{{ if .Tags | containsAny $acceptableTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"func_nodes",
+			`{{ range nodes }}{{ .Node }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
+					d, err := dep.NewCatalogNodesQuery("")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"prod", "v1"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"prod", "v2"},
-						},
+					b.Remember(d, []*dep.Node{
+						&dep.Node{Node: "node1"},
+						&dep.Node{Node: "node2"},
 					})
 					return b
 				}(),
 			},
-			"5.6.7.8",
+			"node1node2",
 			false,
 		},
 		{
-			"helper_containsAny__empty",
-			`{{ $acceptableTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsAny $acceptableTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"func_runners",
+			`{{ $all := runners }}{{ range $all }}{{ if .Self }}{{ .Index }}/{{ len $all }}{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
+					d, err := dep.NewRunnersQuery("")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"prod", "v1"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"prod", "v2"},
-						},
+					b.Remember(d, []*dep.TemplateRunner{
+						&dep.TemplateRunner{ID: "consul-template-a-1", Index: 0},
+						&dep.TemplateRunner{ID: "consul-template-b-1", Index: 1, Self: true},
+						&dep.TemplateRunner{ID: "consul-template-c-1", Index: 2},
 					})
 					return b
 				}(),
 			},
-			"",
+			"1/3",
 			false,
 		},
 		{
-			"helper_containsNone",
-			`{{ $forbiddenTags := parseJSON "[\"devel\",\"staging\"]" }}{{ range service "webapp" }}{{ if .Tags | containsNone $forbiddenTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"func_secret_read",
+			`{{ with secret "secret/foo" }}{{ .Data.zip }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
+					d, err := dep.NewVaultReadQuery("secret/foo")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"prod", "v1"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"devel", "v2"},
-						},
-					})
-					return b
-				}(),
+					b.Remember(d, &dep.Secret{
+						LeaseID:       "abcd1234",
+						LeaseDuration: 120,
+						Renewable:     true,
+						Data:          map[string]interface{}{"zip": "zap"},
+					})
+					return b
+				}(),
 			},
-			"1.2.3.4",
+			"zap",
 			false,
 		},
 		{
-			"helper_containsNone__empty",
-			`{{ $forbiddenTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsNone $forbiddenTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"func_secret_read_no_exist",
+			`{{ with secret "secret/nope" }}{{ .Data.zip }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
-					if err != nil {
-						t.Fatal(err)
-					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"staging", "v1"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"devel", "v2"},
-						},
-					})
-					return b
+					return NewBrain()
 				}(),
 			},
-			"1.2.3.45.6.7.8",
+			"",
 			false,
 		},
 		{
-			"helper_containsNotAll",
-			`{{ $excludingTags := parseJSON "[\"es-v1\",\"es-v2\"]" }}{{ range service "webapp" }}{{ if .Tags | containsNotAll $excludingTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"func_secret_read_no_exist_falsey",
+			`{{ if secret "secret/nope" }}yes{{ else }}no{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
-					if err != nil {
-						t.Fatal(err)
-					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"prod", "es-v1"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"prod", "hybrid", "es-v1", "es-v2"},
-						},
-					})
-					return b
+					return NewBrain()
 				}(),
 			},
-			"1.2.3.4",
+			"no",
 			false,
 		},
 		{
-			"helper_containsNotAll__empty",
-			`{{ $excludingTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsNotAll $excludingTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"func_secret_write",
+			`{{ with secret "transit/encrypt/foo" "plaintext=a" }}{{ .Data.ciphertext }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
+					d, err := dep.NewVaultWriteQuery("transit/encrypt/foo", map[string]interface{}{
+						"plaintext": "a",
+					})
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"prod", "es-v1"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"prod", "hybrid", "es-v1", "es-v2"},
-						},
+					b.Remember(d, &dep.Secret{
+						LeaseID:       "abcd1234",
+						LeaseDuration: 120,
+						Renewable:     true,
+						Data:          map[string]interface{}{"ciphertext": "encrypted"},
 					})
 					return b
 				}(),
 			},
-			"",
+			"encrypted",
 			false,
 		},
 		{
-			"helper_env",
-			`{{ env "CT_TEST" }}`,
+			"func_secret_write_no_exist",
+			`{{ with secret "secret/nope" "a=b" }}{{ .Data.zip }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					// Cheat and use the brain callback here to set the env.
-					if err := os.Setenv("CT_TEST", "1"); err != nil {
-						t.Fatal(err)
-					}
 					return NewBrain()
 				}(),
 			},
-			"1",
-			false,
-		},
-		{
-			"helper_env__override",
-			`{{ env "CT_TEST" }}`,
-			&ExecuteInput{
-				Env: []string{
-					"CT_TEST=2",
-				},
-				Brain: NewBrain(),
-			},
-			"2",
+			"",
 			false,
 		},
 		{
-			"helper_executeTemplate",
-			`{{ define "custom" }}{{ key "foo" }}{{ end }}{{ executeTemplate "custom" }}`,
+			"func_secret_write_no_exist_falsey",
+			`{{ if secret "secret/nope" "a=b" }}yes{{ else }}no{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					b := NewBrain()
-					d, err := dep.NewKVGetQuery("foo")
-					if err != nil {
-						t.Fatal(err)
-					}
-					d.EnableBlocking()
-					b.Remember(d, "bar")
-					return b
+					return NewBrain()
 				}(),
 			},
-			"bar",
+			"no",
 			false,
 		},
 		{
-			"helper_executeTemplate__dot",
-			`{{ define "custom" }}{{ key . }}{{ end }}{{ executeTemplate "custom" "foo" }}`,
+			"func_secret_no_exist_falsey_with",
+			`{{ with secret "secret/nope" }}{{ .Data.foo.bar }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					b := NewBrain()
-					d, err := dep.NewKVGetQuery("foo")
-					if err != nil {
-						t.Fatal(err)
-					}
-					d.EnableBlocking()
-					b.Remember(d, "bar")
-					return b
+					return NewBrain()
 				}(),
 			},
-			"bar",
+			"",
 			false,
 		},
 		{
-			"helper_explode",
-			`{{ range $k, $v := tree "list" | explode }}{{ $k }}{{ $v }}{{ end }}`,
+			"func_secrets",
+			`{{ secrets "secret/" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewKVListQuery("list")
+					d, err := dep.NewVaultListQuery("secret/")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.KeyPair{
-						&dep.KeyPair{Key: "", Value: ""},
-						&dep.KeyPair{Key: "foo/bar", Value: "a"},
-						&dep.KeyPair{Key: "zip/zap", Value: "b"},
-					})
+					b.Remember(d, []string{"bar", "foo"})
 					return b
 				}(),
 			},
-			"foomap[bar:a]zipmap[zap:b]",
+			"[bar foo]",
 			false,
 		},
 		{
-			"helper_in",
-			`{{ range service "webapp" }}{{ if "prod" | in .Tags }}{{ .Address }}{{ end }}{{ end }}`,
+			"func_secrets_no_exist",
+			`{{ secrets "secret/" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
-					if err != nil {
-						t.Fatal(err)
-					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"prod", "staging"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"staging"},
-						},
-					})
-					return b
+					return NewBrain()
 				}(),
 			},
-			"1.2.3.4",
+			"[]",
 			false,
 		},
 		{
-			"helper_loop",
-			`{{ range loop 3 }}1{{ end }}`,
+			"func_secrets_no_exist_falsey",
+			`{{ if secrets "secret/" }}yes{{ else }}no{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					return NewBrain()
+				}(),
 			},
-			"111",
+			"no",
 			false,
 		},
 		{
-			"helper_loop__i",
-			`{{ range $i := loop 3 }}{{ $i }}{{ end }}`,
+			"func_secrets_no_exist_falsey_with",
+			`{{ with secrets "secret/" }}{{ . }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					return NewBrain()
+				}(),
 			},
-			"012",
+			"",
 			false,
 		},
 		{
-			"helper_join",
-			`{{ "a,b,c" | split "," | join ";" }}`,
+			"func_secretsRecursive",
+			`{{ secretsRecursive "secret/" }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					for path, keys := range map[string][]string{
+						"secret":        {"foo", "app/"},
+						"secret/app":    {"db/", "api"},
+						"secret/app/db": {"password"},
+					} {
+						d, err := dep.NewVaultListQuery(path)
+						if err != nil {
+							t.Fatal(err)
+						}
+						b.Remember(d, keys)
+					}
+					return b
+				}(),
 			},
-			"a;b;c",
+			"[secret/app/api secret/app/db/password secret/foo]",
 			false,
 		},
 		{
-			"helper_parseBool",
-			`{{ "true" | parseBool }}`,
+			"func_secretsRecursive_bounds",
+			`{{ secretsRecursive "secret" "depth=1" }} {{ secretsRecursive "secret" "limit=2" }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					for path, keys := range map[string][]string{
+						"secret":        {"foo", "app/"},
+						"secret/app":    {"db/", "api"},
+						"secret/app/db": {"password"},
+					} {
+						d, err := dep.NewVaultListQuery(path)
+						if err != nil {
+							t.Fatal(err)
+						}
+						b.Remember(d, keys)
+					}
+					return b
+				}(),
 			},
-			"true",
+			"[secret/app/api secret/foo] [secret/app/api secret/foo]",
 			false,
 		},
 		{
-			"helper_parseFloat",
-			`{{ "1.2" | parseFloat }}`,
+			"func_secretsRecursive_missing",
+			`{{ secretsRecursive "secret" }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewVaultListQuery("secret")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []string{"foo", "app/"})
+					return b
+				}(),
 			},
-			"1.2",
+			"[secret/foo]",
 			false,
 		},
 		{
-			"helper_parseInt",
-			`{{ "-1" | parseInt }}`,
+			"func_secretsRecursive_bad_option",
+			`{{ secretsRecursive "secret" "depth=-1" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"-1",
-			false,
+			"",
+			true,
 		},
 		{
-			"helper_parseJSON",
-			`{{ "{\"foo\": \"bar\"}" | parseJSON }}`,
+			"func_secret_versions",
+			`{{ range secretVersions "secret/data/jwt" 2 }}{{ .Data.data.key }},{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewVaultVersionsQuery("secret/data/jwt", 2)
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.Secret{
+						&dep.Secret{
+							Data: map[string]interface{}{
+								"data": map[string]interface{}{"key": "new"},
+							},
+						},
+						&dep.Secret{
+							Data: map[string]interface{}{
+								"data": map[string]interface{}{"key": "old"},
+							},
+						},
+					})
+					return b
+				}(),
 			},
-			"map[foo:bar]",
+			"new,old,",
 			false,
 		},
 		{
-			"helper_parseUint",
-			`{{ "1" | parseUint }}`,
+			"func_secret_versions_invalid",
+			`{{ secretVersions "secret/jwt" 2 }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"1",
-			false,
+			"",
+			true,
 		},
 		{
-			"helper_plugin",
-			`{{ "1" | plugin "echo" }}`,
+			"func_service",
+			`{{ range service "webapp" }}{{ .Address }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
-			},
-			"1",
-			false,
-		},
-		{
-			"helper_regexMatch",
-			`{{ "foo" | regexMatch "[a-z]+" }}`,
-			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Node:    "node1",
+							Address: "1.2.3.4",
+						},
+						&dep.HealthService{
+							Node:    "node2",
+							Address: "5.6.7.8",
+						},
+					})
+					return b
+				}(),
 			},
-			"true",
+			"1.2.3.45.6.7.8",
 			false,
 		},
 		{
-			"helper_regexReplaceAll",
-			`{{ "foo" | regexReplaceAll "\\w" "x" }}`,
+			"func_service_filter",
+			`{{ range service "webapp" "passing,any" }}{{ .Address }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp|passing,any")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Node:    "node1",
+							Address: "1.2.3.4",
+						},
+						&dep.HealthService{
+							Node:    "node2",
+							Address: "5.6.7.8",
+						},
+					})
+					return b
+				}(),
 			},
-			"xxx",
+			"1.2.3.45.6.7.8",
 			false,
 		},
 		{
-			"helper_replaceAll",
-			`{{ "hello my hello" | regexReplaceAll "hello" "bye" }}`,
+			// Equivalent filters share the same dependency.
+			"func_service_negated_filter",
+			`{{ range service "webapp" "not critical" }}{{ .Address }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp|passing,warning,maintenance")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Node:    "node1",
+							Address: "1.2.3.4",
+						},
+					})
+					return b
+				}(),
 			},
-			"bye my bye",
+			"1.2.3.4",
 			false,
 		},
 		{
-			"helper_split",
-			`{{ "a,b,c" | split "," }}`,
+			"func_checks",
+			`{{ range checks "webapp" "not passing" }}{{ .Node }}:{{ .Status }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthChecksQuery("webapp|!passing")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthCheck{
+						&dep.HealthCheck{
+							Node:   "node1",
+							Status: "critical",
+						},
+					})
+					return b
+				}(),
 			},
-			"[a b c]",
+			"node1:critical",
 			false,
 		},
 		{
-			"helper_timestamp",
-			`{{ timestamp }}`,
+			"func_serviceCount",
+			`{{ serviceCount "webapp" }} {{ serviceCount "webapp" "passing,warning" }} {{ serviceCount "webapp|any" }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceSummaryQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, &dep.HealthServiceSummary{
+						Passing:  3,
+						Warning:  1,
+						Critical: 2,
+						Total:    6,
+					})
+					return b
+				}(),
 			},
-			"1970-01-01T00:00:00Z",
+			"3 4 6",
 			false,
 		},
 		{
-			"helper_helper_timestamp__formatted",
-			`{{ timestamp "2006-01-02" }}`,
+			"func_serviceHealthSummary",
+			`{{ with serviceHealthSummary "webapp" }}{{ .Passing }}/{{ .Total }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceSummaryQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, &dep.HealthServiceSummary{
+						Passing: 3,
+						Total:   5,
+					})
+					return b
+				}(),
 			},
-			"1970-01-01",
+			"3/5",
 			false,
 		},
 		{
-			"helper_toJSON",
-			`{{ "a,b,c" | split "," | toJSON }}`,
+			"func_services",
+			`{{ range services }}{{ .Name }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewCatalogServicesQuery("")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.CatalogSnippet{
+						&dep.CatalogSnippet{
+							Name: "service1",
+						},
+						&dep.CatalogSnippet{
+							Name: "service2",
+						},
+					})
+					return b
+				}(),
 			},
-			"[\"a\",\"b\",\"c\"]",
+			"service1service2",
 			false,
 		},
 		{
-			"helper_toLower",
-			`{{ "HI" | toLower }}`,
+			"func_tree",
+			`{{ range tree "key" }}{{ .Key }}={{ .Value }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVListQuery("key")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.KeyPair{
+						&dep.KeyPair{Key: "", Value: ""},
+						&dep.KeyPair{Key: "admin/port", Value: "1134"},
+						&dep.KeyPair{Key: "maxconns", Value: "5"},
+						&dep.KeyPair{Key: "minconns", Value: "2"},
+					})
+					return b
+				}(),
 			},
-			"hi",
+			"admin/port=1134maxconns=5minconns=2",
 			false,
 		},
 		{
-			"helper_toTitle",
-			`{{ "this is a sentence" | toTitle }}`,
+			"func_tree_exclude",
+			`{{ range tree "key" "exclude=*.tmp,admin/*" }}{{ .Key }}={{ .Value }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVListQuery("key", "*.tmp", "admin/*")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.KeyPair{
+						&dep.KeyPair{Key: "maxconns", Value: "5"},
+					})
+					return b
+				}(),
 			},
-			"This Is A Sentence",
+			"maxconns=5",
 			false,
 		},
 		{
-			"helper_toTOML",
-			`{{ "{\"foo\":\"bar\"}" | parseJSON | toTOML }}`,
-			&ExecuteInput{
-				Brain: NewBrain(),
-			},
-			"foo = \"bar\"",
-			false,
+			"func_tree_bad_option",
+			`{{ tree "key" "include=*.tmp" }}`,
+			nil,
+			"",
+			true,
 		},
+
+		// scratch
 		{
-			"helper_toUpper",
-			`{{ "hi" | toUpper }}`,
+			"scratch.Key",
+			`{{ scratch.Set "a" "2" }}{{ scratch.Key "a" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"HI",
+			"true",
 			false,
 		},
 		{
-			"helper_toYAML",
-			`{{ "{\"foo\":\"bar\"}" | parseJSON | toYAML }}`,
+			"scratch.Get",
+			`{{ scratch.Set "a" "2" }}{{ scratch.Get "a" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"foo: bar",
+			"2",
 			false,
 		},
 		{
-			"helper_trimSpace",
-			`{{ "\t hi\n " | trimSpace }}`,
+			"scratch.SetX",
+			`{{ scratch.SetX "a" "2" }}{{ scratch.SetX "a" "1" }}{{ scratch.Get "a" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"hi",
+			"2",
 			false,
 		},
 		{
-			"math_add",
-			`{{ 2 | add 2 }}`,
+			"scratch.MapSet",
+			`{{ scratch.MapSet "a" "foo" "bar" }}{{ scratch.MapValues "a" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"4",
+			"[bar]",
 			false,
 		},
 		{
-			"math_subtract",
-			`{{ 2 | subtract 2 }}`,
+			"scratch.MapSetX",
+			`{{ scratch.MapSetX "a" "foo" "bar" }}{{ scratch.MapSetX "a" "foo" "baz" }}{{ scratch.MapValues "a" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"0",
+			"[bar]",
 			false,
 		},
+
+		// helpers
 		{
-			"math_multiply",
-			`{{ 2 | multiply 2 }}`,
+			"helper_by_key",
+			`{{ range $key, $pairs := tree "list" | byKey }}{{ $key }}:{{ range $pairs }}{{ .Key }}={{ .Value }}{{ end }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVListQuery("list")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.KeyPair{
+						&dep.KeyPair{Key: "", Value: ""},
+						&dep.KeyPair{Key: "foo/bar", Value: "a"},
+						&dep.KeyPair{Key: "zip/zap", Value: "b"},
+					})
+					return b
+				}(),
 			},
-			"4",
+			"foo:bar=azip:zap=b",
 			false,
 		},
 		{
-			"math_divide",
-			`{{ 2 | divide 2 }}`,
+			"helper_by_tag",
+			`{{ range $tag, $services := service "webapp" | byTag }}{{ $tag }}:{{ range $services }}{{ .Address }}{{ end }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "staging"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"staging"},
+						},
+					})
+					return b
+				}(),
 			},
-			"1",
+			"prod:1.2.3.4staging:1.2.3.45.6.7.8",
 			false,
 		},
 		{
-			"math_modulo",
-			`{{ 3 | modulo 2 }}`,
+			"helper_contains",
+			`{{ range service "webapp" }}{{ if .Tags | contains "prod" }}{{ .Address }}{{ end }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "staging"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"staging"},
+						},
+					})
+					return b
+				}(),
+			},
+			"1.2.3.4",
+			false,
+		},
+		{
+			"helper_containsAll",
+			`{{ $requiredTags := parseJSON "[\"prod\",\"us-realm\"]" }}{{ range service "webapp" }}{{ if .Tags | containsAll $requiredTags }}{{ .Address }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "us-realm"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"prod", "ca-realm"},
+						},
+					})
+					return b
+				}(),
+			},
+			"1.2.3.4",
+			false,
+		},
+		{
+			"helper_containsAll__empty",
+			`{{ $requiredTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsAll $requiredTags }}{{ .Address }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "us-realm"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"prod", "ca-realm"},
+						},
+					})
+					return b
+				}(),
+			},
+			"1.2.3.45.6.7.8",
+			false,
+		},
+		{
+			"helper_containsAny",
+			`{{ $acceptableTags := parseJSON "[\"v2\",\"v3\"]" }}{{ range service "webapp" }}{{ if .Tags | containsAny $acceptableTags }}{{ .Address }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "v1"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"prod", "v2"},
+						},
+					})
+					return b
+				}(),
+			},
+			"5.6.7.8",
+			false,
+		},
+		{
+			"helper_containsAny__empty",
+			`{{ $acceptableTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsAny $acceptableTags }}{{ .Address }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "v1"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"prod", "v2"},
+						},
+					})
+					return b
+				}(),
+			},
+			"",
+			false,
+		},
+		{
+			"helper_containsNone",
+			`{{ $forbiddenTags := parseJSON "[\"devel\",\"staging\"]" }}{{ range service "webapp" }}{{ if .Tags | containsNone $forbiddenTags }}{{ .Address }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "v1"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"devel", "v2"},
+						},
+					})
+					return b
+				}(),
+			},
+			"1.2.3.4",
+			false,
+		},
+		{
+			"helper_containsNone__empty",
+			`{{ $forbiddenTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsNone $forbiddenTags }}{{ .Address }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"staging", "v1"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"devel", "v2"},
+						},
+					})
+					return b
+				}(),
+			},
+			"1.2.3.45.6.7.8",
+			false,
+		},
+		{
+			"helper_containsNotAll",
+			`{{ $excludingTags := parseJSON "[\"es-v1\",\"es-v2\"]" }}{{ range service "webapp" }}{{ if .Tags | containsNotAll $excludingTags }}{{ .Address }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "es-v1"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"prod", "hybrid", "es-v1", "es-v2"},
+						},
+					})
+					return b
+				}(),
+			},
+			"1.2.3.4",
+			false,
+		},
+		{
+			"helper_containsNotAll__empty",
+			`{{ $excludingTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsNotAll $excludingTags }}{{ .Address }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "es-v1"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"prod", "hybrid", "es-v1", "es-v2"},
+						},
+					})
+					return b
+				}(),
+			},
+			"",
+			false,
+		},
+		{
+			"helper_env",
+			`{{ env "CT_TEST" }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					// Cheat and use the brain callback here to set the env.
+					if err := os.Setenv("CT_TEST", "1"); err != nil {
+						t.Fatal(err)
+					}
+					return NewBrain()
+				}(),
+			},
+			"1",
+			false,
+		},
+		{
+			"helper_env__override",
+			`{{ env "CT_TEST" }}`,
+			&ExecuteInput{
+				Env: []string{
+					"CT_TEST=2",
+				},
+				Brain: NewBrain(),
+			},
+			"2",
+			false,
+		},
+		{
+			"helper_executeTemplate",
+			`{{ define "custom" }}{{ key "foo" }}{{ end }}{{ executeTemplate "custom" }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("foo")
+					if err != nil {
+						t.Fatal(err)
+					}
+					d.EnableBlocking()
+					b.Remember(d, "bar")
+					return b
+				}(),
+			},
+			"bar",
+			false,
+		},
+		{
+			"helper_executeTemplate__dot",
+			`{{ define "custom" }}{{ key . }}{{ end }}{{ executeTemplate "custom" "foo" }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("foo")
+					if err != nil {
+						t.Fatal(err)
+					}
+					d.EnableBlocking()
+					b.Remember(d, "bar")
+					return b
+				}(),
+			},
+			"bar",
+			false,
+		},
+		{
+			"helper_envFile",
+			`{{ "{\"B\":\"it's $x\",\"A\":\"plain/v1\",\"C\":3,\"D\":null}" | parseJSON | envFile }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"A=plain/v1\nB='it'\\''s $x'\nC=3\nD=",
+			false,
+		},
+		{
+			"helper_envFile_invalid_name",
+			`{{ "{\"A-B\":\"x\"}" | parseJSON | envFile }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_envFile_nested",
+			`{{ "{\"A\":{\"B\":\"x\"}}" | parseJSON | envFile }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_explode",
+			`{{ range $k, $v := tree "list" | explode }}{{ $k }}{{ $v }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVListQuery("list")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.KeyPair{
+						&dep.KeyPair{Key: "", Value: ""},
+						&dep.KeyPair{Key: "foo/bar", Value: "a"},
+						&dep.KeyPair{Key: "zip/zap", Value: "b"},
+					})
+					return b
+				}(),
+			},
+			"foomap[bar:a]zipmap[zap:b]",
+			false,
+		},
+		{
+			"helper_explode_options",
+			`{{ tree "list" | explode "delimiter=." "typed=true" | toJSON }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVListQuery("list")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.KeyPair{
+						&dep.KeyPair{Key: "db.port", Value: "5432"},
+						&dep.KeyPair{Key: "db.ratio", Value: "0.5"},
+						&dep.KeyPair{Key: "db.tls", Value: "true"},
+						&dep.KeyPair{Key: "db.hosts", Value: `["a", "b"]`},
+						&dep.KeyPair{Key: "db.path", Value: "a/b"},
+						&dep.KeyPair{Key: "zip", Value: "01234"},
+						&dep.KeyPair{Key: "name", Value: "Inf"},
+					})
+					return b
+				}(),
+			},
+			`{"db":{"hosts":["a","b"],"path":"a/b","port":5432,"ratio":0.5,"tls":true},"name":"Inf","zip":"01234"}`,
+			false,
+		},
+		{
+			"helper_explode_bad_option",
+			`{{ tree "list" | explode "separator=." }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_mergeMaps",
+			`{{ $a := parseJSON "{\"db\":{\"host\":\"a\",\"port\":1},\"tags\":[\"x\"]}" }}` +
+				`{{ $b := parseJSON "{\"db\":{\"host\":\"b\"},\"tags\":[\"y\"]}" }}` +
+				`{{ mergeMaps $a $b | toJSON }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			`{"db":{"host":"b","port":1},"tags":["y"]}`,
+			false,
+		},
+		{
+			"helper_mergeMaps_append",
+			`{{ $a := parseJSON "{\"tags\":[\"x\"]}" }}` +
+				`{{ $b := parseJSON "{\"tags\":[\"y\"]}" }}` +
+				`{{ mergeMaps "append" $a $b $a | toJSON }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			`{"tags":["x","y","x"]}`,
+			false,
+		},
+		{
+			"helper_mergeMaps_explode",
+			`{{ $b := tree "override" | explode }}{{ tree "base" | explode | mergeMaps $b | toJSON }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					base, err := dep.NewKVListQuery("base")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(base, []*dep.KeyPair{
+						&dep.KeyPair{Key: "db/host", Value: "a"},
+						&dep.KeyPair{Key: "db/port", Value: "1"},
+					})
+					override, err := dep.NewKVListQuery("override")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(override, []*dep.KeyPair{
+						&dep.KeyPair{Key: "db/host", Value: "b"},
+						&dep.KeyPair{Key: "db/user", Value: "c"},
+					})
+					return b
+				}(),
+			},
+			`{"db":{"host":"a","port":"1","user":"c"}}`,
+			false,
+		},
+		{
+			"helper_mergeMaps_bad_strategy",
+			`{{ mergeMaps "prepend" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_mergeMaps_not_map",
+			`{{ mergeMaps "append" 1 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_htpasswdEntry_invalid_user",
+			`{{ htpasswdEntry "ad:min" "s3cr3t" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_in",
+			`{{ range service "webapp" }}{{ if "prod" | in .Tags }}{{ .Address }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "staging"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"staging"},
+						},
+					})
+					return b
+				}(),
+			},
+			"1.2.3.4",
+			false,
+		},
+		{
+			"helper_loop",
+			`{{ range loop 3 }}1{{ end }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"111",
+			false,
+		},
+		{
+			"helper_loop__i",
+			`{{ range $i := loop 3 }}{{ $i }}{{ end }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"012",
+			false,
+		},
+		{
+			"helper_javaProperties",
+			`{{ "{\"db\":{\"url\":\"jdbc:pg://h/db\",\"pass\":\"a=b #c\\\\d\"},\"name\":\" caf\u00e9\\n\"}" | parseJSON | javaProperties }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"db.pass=a\\=b \\#c\\\\d\ndb.url=jdbc\\:pg\\://h/db\nname=\\ caf\\u00E9\\n",
+			false,
+		},
+		{
+			"helper_indent",
+			`{{ "a:\n  b: c\n\nd" | indent 4 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"    a:\n      b: c\n\n    d",
+			false,
+		},
+		{
+			"helper_nindent",
+			`cert:{{ "-----BEGIN-----\nabc\n-----END-----" | nindent 2 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"cert:\n  -----BEGIN-----\n  abc\n  -----END-----",
+			false,
+		},
+		{
+			"helper_quote",
+			`{{ "say \"hi\"\n" | quote }} {{ 3 | quote }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			`"say \"hi\"\n" "3"`,
+			false,
+		},
+		{
+			"helper_squote",
+			`{{ "it's" | squote }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"'it''s'",
+			false,
+		},
+		{
+			"helper_join",
+			`{{ "a,b,c" | split "," | join ";" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"a;b;c",
+			false,
+		},
+		{
+			"helper_parseBool",
+			`{{ "true" | parseBool }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"true",
+			false,
+		},
+		{
+			"helper_parseFloat",
+			`{{ "1.2" | parseFloat }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1.2",
+			false,
+		},
+		{
+			"helper_parseInt",
+			`{{ "-1" | parseInt }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"-1",
+			false,
+		},
+		{
+			"helper_parseJSON",
+			`{{ "{\"foo\": \"bar\"}" | parseJSON }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"map[foo:bar]",
+			false,
+		},
+		{
+			"helper_parseUint",
+			`{{ "1" | parseUint }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1",
+			false,
+		},
+		{
+			"helper_plugin",
+			`{{ "1" | plugin "echo" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1",
+			false,
+		},
+		{
+			"helper_base64RawURLEncode",
+			`{{ "hello?" | base64RawURLEncode }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"aGVsbG8_",
+			false,
+		},
+		{
+			"helper_base64RawURLDecode",
+			`{{ "aGVsbG8_" | base64RawURLDecode }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"hello?",
+			false,
+		},
+		{
+			"helper_hexEncode",
+			`{{ "hello" | hexEncode }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"68656c6c6f",
+			false,
+		},
+		{
+			"helper_hexDecode",
+			`{{ "68656c6c6f" | hexDecode }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"hello",
+			false,
+		},
+		{
+			"helper_uuidv4",
+			`{{ uuidv4 | regexMatch "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"true",
+			false,
+		},
+		{
+			"helper_randAlphaNum",
+			`{{ randAlphaNum 24 | regexMatch "^[A-Za-z0-9]{24}$" }} {{ randAlphaNum 0 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"true ",
+			false,
+		},
+		{
+			"helper_randAlphaNum_negative",
+			`{{ randAlphaNum -1 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_stableRand",
+			`{{ stableRand "db-password" 16 }} {{ stableRand "db-password" 40 }} {{ stableRand "other" 16 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"crfZ7gdwa4fFe1wD crfZ7gdwa4fFe1wDhzYxDVfF2M93zavXqJWK0Jnt DeW4aDdutkbMPtAt",
+			false,
+		},
+		{
+			"helper_urlEncode",
+			`{{ "a b&c=d/e" | urlEncode }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"a+b%26c%3Dd%2Fe",
+			false,
+		},
+		{
+			"helper_urlDecode",
+			`{{ "a+b%26c%3Dd%2Fe" | urlDecode }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"a b&c=d/e",
+			false,
+		},
+		{
+			"helper_regexFind",
+			`{{ "port=8080" | regexFind "[0-9]+" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"8080",
+			false,
+		},
+		{
+			"helper_regexFind__group",
+			`{{ "port=8080" | regexFind "port=([0-9]+)" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"8080",
+			false,
+		},
+		{
+			"helper_regexFind__none",
+			`{{ "foo" | regexFind "[0-9]+" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			false,
+		},
+		{
+			"helper_regexFindAll",
+			`{{ "a1 b22 c333" | regexFindAll "[0-9]+" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"[1 22 333]",
+			false,
+		},
+		{
+			"helper_regexSplit",
+			`{{ "a, b;c" | regexSplit "[,;] ?" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"[a b c]",
+			false,
+		},
+		{
+			"helper_regexMatch",
+			`{{ "foo" | regexMatch "[a-z]+" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"true",
+			false,
+		},
+		{
+			"helper_regexReplaceAll",
+			`{{ "foo" | regexReplaceAll "\\w" "x" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"xxx",
+			false,
+		},
+		{
+			"helper_replaceAll",
+			`{{ "hello my hello" | regexReplaceAll "hello" "bye" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"bye my bye",
+			false,
+		},
+		{
+			"helper_split",
+			`{{ "a,b,c" | split "," }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"[a b c]",
+			false,
+		},
+		{
+			"helper_env__restricted",
+			`{{ env "CT_TEST" }}{{ env "CT_TEST_RESTRICTED" }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					// Cheat and use the brain callback here to set the env.
+					if err := os.Setenv("CT_TEST_RESTRICTED", "2"); err != nil {
+						t.Fatal(err)
+					}
+					return NewBrain()
+				}(),
+				Env:         []string{"CT_TEST=1"},
+				RestrictEnv: true,
+			},
+			"1",
+			false,
+		},
+		{
+			"helper_sysinfo",
+			`{{ with sysinfo }}{{ .CPUs }}{{ end }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			strconv.Itoa(runtime.NumCPU()),
+			false,
+		},
+		{
+			"helper_timestamp",
+			`{{ timestamp }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1970-01-01T00:00:00Z",
+			false,
+		},
+		{
+			"helper_helper_timestamp__formatted",
+			`{{ timestamp "2006-01-02" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1970-01-01",
+			false,
+		},
+		{
+			"helper_now",
+			`{{ now }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1970-01-01 00:00:00 +0000 UTC",
+			false,
+		},
+		{
+			"helper_dateFormat",
+			`{{ now | dateFormat "2006-01-02 15:04" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1970-01-01 00:00",
+			false,
+		},
+		{
+			"helper_dateFormat__zone",
+			`{{ now | dateFormat "15:04 MST" "UTC" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"00:00 UTC",
+			false,
+		},
+		{
+			"helper_dateFormat__unix",
+			`{{ 86400 | dateFormat "2006-01-02" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1970-01-02",
+			false,
+		},
+		{
+			"helper_parseTime",
+			`{{ "2017-03-04" | parseTime "2006-01-02" | dateFormat "Jan 2, 2006" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"Mar 4, 2017",
+			false,
+		},
+		{
+			"helper_unixToTime",
+			`{{ 3600 | unixToTime | dateFormat "15:04" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"01:00",
+			false,
+		},
+		{
+			"helper_parseDuration",
+			`{{ (parseDuration "90m").Hours }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1.5",
+			false,
+		},
+		{
+			"helper_timeAdd",
+			`{{ now | timeAdd "36h" | dateFormat "2006-01-02 15:04" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1970-01-02 12:00",
+			false,
+		},
+		{
+			"helper_timeSub",
+			`{{ "1970-01-02T00:00:00Z" | timeSub now }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"24h0m0s",
+			false,
+		},
+		{
+			"helper_toJSON",
+			`{{ "a,b,c" | split "," | toJSON }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"[\"a\",\"b\",\"c\"]",
+			false,
+		},
+		{
+			"helper_toLower",
+			`{{ "HI" | toLower }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"hi",
+			false,
+		},
+		{
+			"helper_toTitle",
+			`{{ "this is a sentence" | toTitle }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"This Is A Sentence",
+			false,
+		},
+		{
+			"helper_trimPrefix",
+			`{{ "service/web/port" | trimPrefix "service/" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"web/port",
+			false,
+		},
+		{
+			"helper_trimSuffix",
+			`{{ "web.service.consul" | trimSuffix ".consul" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"web.service",
+			false,
+		},
+		{
+			"helper_toTOML",
+			`{{ "{\"foo\":\"bar\"}" | parseJSON | toTOML }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"foo = \"bar\"",
+			false,
+		},
+		{
+			"helper_toUpper",
+			`{{ "hi" | toUpper }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"HI",
+			false,
+		},
+		{
+			"helper_toYAML",
+			`{{ "{\"foo\":\"bar\"}" | parseJSON | toYAML }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"foo: bar",
+			false,
+		},
+		{
+			"helper_trimSpace",
+			`{{ "\t hi\n " | trimSpace }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"hi",
+			false,
+		},
+		{
+			"math_add",
+			`{{ 2 | add 2 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"4",
+			false,
+		},
+		{
+			"math_subtract",
+			`{{ 2 | subtract 2 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"0",
+			false,
+		},
+		{
+			"math_multiply",
+			`{{ 2 | multiply 2 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"4",
+			false,
+		},
+		{
+			"math_divide",
+			`{{ 2 | divide 2 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1",
+			false,
+		},
+		{
+			"math_modulo",
+			`{{ 3 | modulo 2 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1",
+			false,
+		},
+		{
+			"math_sub",
+			`{{ 5 | sub 2 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"3",
+			false,
+		},
+		{
+			"math_mul",
+			`{{ 3 | mul 2 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"6",
+			false,
+		},
+		{
+			"math_div",
+			`{{ 6 | div 2 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"3",
+			false,
+		},
+		{
+			"math_mod",
+			`{{ 7 | mod 4 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"3",
+			false,
+		},
+		{
+			"math_max",
+			`{{ max 2 5 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"5",
+			false,
+		},
+		{
+			"math_max_float",
+			`{{ max 2 5.5 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"5.5",
+			false,
+		},
+		{
+			"math_min",
+			`{{ 3 | min 1 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
 			},
 			"1",
 			false,
 		},
+		{
+			"math_round",
+			`{{ round 2.5 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"3",
+			false,
+		},
+		{
+			"math_round_places",
+			`{{ 3.14159 | round 2 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"3.14",
+			false,
+		},
+		{
+			"math_round_negative",
+			`{{ round -2.5 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"-3",
+			false,
+		},
+		{
+			"math_ceil",
+			`{{ ceil 2.1 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"3",
+			false,
+		},
+		{
+			"math_ceil_bad_type",
+			`{{ ceil "foo" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tpl, err := NewTemplate(&NewTemplateInput{
+				Contents: tc.c,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a, err := tpl.Execute(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if a != nil && !bytes.Equal([]byte(tc.e), a.Output) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, string(a.Output))
+			}
+		})
+	}
+}
+
+func TestTemplate_regexpCache(t *testing.T) {
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: `{{ "foo" | regexMatch "f.o" }}{{ "foo" | regexMatch "f.o" }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := tpl.Execute(&ExecuteInput{Brain: NewBrain()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if l := len(tpl.regexps.compiled); l != 1 {
+		t.Errorf("expected 1 compiled regexp, got %d", l)
+	}
+}
+
+func TestTemplate_varsCache(t *testing.T) {
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: `{{ var "region" }}`,
+		VarsFile: "/etc/ct/values.yaml",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := dep.NewFileQuery("/etc/ct/values.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBrain()
+
+	// The vars are parsed once across executions, and again when the contents
+	// of the file change.
+	var parsed map[string]interface{}
+	for _, contents := range []string{"region: a", "region: a", "region: b"} {
+		b.Remember(d, contents)
+		a, err := tpl.Execute(&ExecuteInput{Brain: b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := strings.TrimPrefix(contents, "region: "); string(a.Output) != exp {
+			t.Errorf("expected %q to be %q", a.Output, exp)
+		}
+
+		same := parsed != nil && reflect.ValueOf(parsed).Pointer() == reflect.ValueOf(tpl.vars.vars).Pointer()
+		if exp := contents == "region: a" && parsed != nil; same != exp {
+			t.Errorf("%q: expected cached vars to be reused: %t", contents, exp)
+		}
+		parsed = tpl.vars.vars
+	}
+}
+
+func TestTemplate_htpasswdEntry(t *testing.T) {
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: `{{ htpasswdEntry "admin" (key "password") }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	salts := []string{"AdqZp/2J", "zzzzzzzz"}
+	tpl.htpasswd.salt = func() (string, error) {
+		salt := salts[0]
+		salts = salts[1:]
+		return salt, nil
+	}
+
+	d, err := dep.NewKVGetQuery("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.EnableBlocking()
+	b := NewBrain()
+
+	// The line keeps its salt across executions, and gets a new salt when the
+	// password changes.
+	for _, tc := range []struct {
+		pass string
+		exp  string
+	}{
+		{"s3cr3t:pa$$", "admin:$apr1$AdqZp/2J$zHdaA7pT82waCGhbR8.950"},
+		{"s3cr3t:pa$$", "admin:$apr1$AdqZp/2J$zHdaA7pT82waCGhbR8.950"},
+		{"other", "admin:$apr1$zzzzzzzz$"},
+	} {
+		b.Remember(d, tc.pass)
+		a, err := tpl.Execute(&ExecuteInput{Brain: b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(a.Output), tc.exp) {
+			t.Errorf("expected %q to start with %q", a.Output, tc.exp)
+		}
+	}
+}
+
+func TestTemplate_htpasswdEntry_evict(t *testing.T) {
+	var c htpasswdCache
+	first, err := c.entry("admin", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotating through more passwords than the cache holds evicts the least
+	// recently used lines, so the first one gets a new salt.
+	for i := 1; i <= htpasswdCacheSize; i++ {
+		if _, err := c.entry("admin", strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := c.entries.Len(); n != htpasswdCacheSize {
+		t.Errorf("expected %d cached lines, got %d", htpasswdCacheSize, n)
+	}
+
+	again, err := c.entry("admin", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == first {
+		t.Errorf("expected %q to be evicted", first)
+	}
+}
+
+func TestTemplate_htpasswdEntry_randomSalt(t *testing.T) {
+	f := htpasswdEntryFunc(nil)
+
+	a, err := f("admin", "s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := f("admin", "s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Errorf("expected %q to have a random salt", a)
+	}
+	if !strings.HasPrefix(a, "admin:$apr1$") {
+		t.Errorf("expected %q to be an htpasswd line", a)
+	}
+}
+
+func TestTemplate_TemplateOutputs(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *NewTemplateInput
+		e    []string
+	}{
+		{
+			"gotemplate",
+			&NewTemplateInput{
+				Contents: `{{ templateOutput "a" }}{{ if true }}{{ range templateOutput "b" | parseJSON }}{{ end }}{{ end }}{{ toUpper (templateOutput "c") }}`,
+			},
+			[]string{"a", "b", "c"},
+		},
+		{
+			"gotemplate_delimiters",
+			&NewTemplateInput{
+				Contents:   `[[ templateOutput "a" ]]{{ templateOutput "b" }}`,
+				LeftDelim:  "[[",
+				RightDelim: "]]",
+			},
+			[]string{"a"},
+		},
+		{
+			"gotemplate_not_literal",
+			&NewTemplateInput{
+				Contents: `{{ templateOutput (key "name") }}`,
+			},
+			nil,
+		},
+		{
+			"handlebars",
+			&NewTemplateInput{
+				Contents: `{{templateOutput "a"}}{{#if (templateOutput "b")}}{{toUpper (templateOutput "c")}}{{/if}}`,
+				Engine:   HandlebarsEngine,
+			},
+			[]string{"a", "b", "c"},
+		},
+		{
+			"invalid",
+			&NewTemplateInput{
+				Contents: `{{ templateOutput "a" `,
+			},
+			nil,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tpl, err := NewTemplate(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if a := tpl.TemplateOutputs(); !reflect.DeepEqual(tc.e, a) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, a)
+			}
+		})
+	}
+}
+
+func TestTemplate_keyWithFallbackDC_used(t *testing.T) {
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: `{{ keyWithFallbackDC "port" "dc1" "dc2" "dc3" }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := dep.NewKVGetQuery("port@dc2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBrain()
+	b.Remember(d, "8080")
+
+	// The first datacenter is missing, so only it is watched.
+	a, err := tpl.Execute(&ExecuteInput{Brain: b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "kv.get(port@dc1)"; a.Used.String() != exp {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, a.Used.String())
+	}
+
+	// Once the first datacenter is known to lack the key, the second one is
+	// watched, and the third one is not needed.
+	d, err = dep.NewKVGetQuery("port@dc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Remember(d, nil)
+
+	a, err = tpl.Execute(&ExecuteInput{Brain: b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "kv.get(port@dc1), kv.get(port@dc2)"; a.Used.String() != exp {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, a.Used.String())
+	}
+	if string(a.Output) != "8080" {
+		t.Errorf("expected 8080, got %q", a.Output)
+	}
+}
+
+func TestTemplate_tcpProbe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// A closed listener gives an address which refuses connections.
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	_, closedPort, _ := net.SplitHostPort(closed.Addr().String())
+
+	cases := []struct {
+		name     string
+		contents string
+		e        string
+		err      bool
+	}{
+		{
+			"reachable",
+			fmt.Sprintf(`{{ tcpProbe %q %s "1s" }}`, host, port),
+			"true",
+			false,
+		},
+		{
+			"unreachable",
+			fmt.Sprintf(`{{ tcpProbe %q %s "1s" }}`, host, closedPort),
+			"false",
+			false,
+		},
+		{
+			"invalid_port",
+			`{{ tcpProbe "127.0.0.1" "http" "1s" }}`,
+			"",
+			true,
+		},
+		{
+			"invalid_timeout",
+			fmt.Sprintf(`{{ tcpProbe %q %s "soon" }}`, host, port),
+			"",
+			true,
+		},
+		{
+			"too_many",
+			`{{ range loop 20 }}{{ tcpProbe "127.0.0.1" (add . 1) "10ms" }}{{ end }}`,
+			"",
+			true,
+		},
 	}
 
 	for i, tc := range cases {
 		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
 			tpl, err := NewTemplate(&NewTemplateInput{
-				Contents: tc.c,
+				Contents: tc.contents,
 			})
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			a, err := tpl.Execute(tc.i)
+			a, err := tpl.Execute(nil)
 			if (err != nil) != tc.err {
 				t.Fatal(err)
 			}
-			if a != nil && !bytes.Equal([]byte(tc.e), a.Output) {
+			if a != nil && tc.e != string(a.Output) {
 				t.Errorf("\nexp: %#v\nact: %#v", tc.e, string(a.Output))
 			}
 		})
 	}
+
+	t.Run("cached", func(t *testing.T) {
+		tpl, err := NewTemplate(&NewTemplateInput{
+			Contents: fmt.Sprintf(`{{ tcpProbe %q %s "1s" }}`, host, port),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tpl.Execute(nil); err != nil {
+			t.Fatal(err)
+		}
+		ln.Close()
+
+		// Within the interval, the closed listener is still reported reachable.
+		a, err := tpl.Execute(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(a.Output) != "true" {
+			t.Errorf("expected the cached result, got %q", a.Output)
+		}
+	})
+}
+
+func TestTemplate_Execute_timeout(t *testing.T) {
+	cases := []struct {
+		name     string
+		contents string
+		timeout  time.Duration
+		err      bool
+	}{
+		{
+			"no_timeout",
+			`{{ "1" | plugin "sleep" }}`,
+			0,
+			false,
+		},
+		{
+			"within_timeout",
+			`foo`,
+			5 * time.Second,
+			false,
+		},
+		{
+			"exceeds_timeout",
+			`{{ "5" | plugin "sleep" }}`,
+			50 * time.Millisecond,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tpl, err := NewTemplate(&NewTemplateInput{
+				Contents: tc.contents,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			_, err = tpl.Execute(&ExecuteInput{
+				Brain:   NewBrain(),
+				Timeout: tc.timeout,
+			})
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if tc.err {
+				if !strings.Contains(err.Error(), "render timeout of "+tc.timeout.String()) {
+					t.Errorf("expected render timeout error, got %q", err)
+				}
+				if d := time.Since(start); d > 2*time.Second {
+					t.Errorf("expected execution to return at the timeout, took %s", d)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplate_Execute_timeoutRunning(t *testing.T) {
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: `{{ "1" | plugin "sleep" }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tpl.Execute(&ExecuteInput{Timeout: 50 * time.Millisecond}); err == nil {
+		t.Fatal("expected a timeout")
+	}
+
+	// The template is not executed again while the execution which timed out
+	// is running.
+	_, err = tpl.Execute(&ExecuteInput{Timeout: 5 * time.Second})
+	if err == nil || !strings.Contains(err.Error(), "still running") {
+		t.Fatalf("expected the execution to be refused, got %v", err)
+	}
+
+	// Once it finished, the template is executed again.
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := tpl.Execute(&ExecuteInput{Timeout: 5 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTemplate_Execute_timeoutCancel(t *testing.T) {
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: `{{ range loop 1000000000 }}x{{ end }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tpl.Execute(&ExecuteInput{Timeout: 50 * time.Millisecond}); err == nil {
+		t.Fatal("expected a timeout")
+	}
+
+	// The execution which timed out stops at its next output.
+	select {
+	case <-tpl.timedOut.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the execution which timed out to stop")
+	}
+}
+
+func TestTemplate_Execute_guard(t *testing.T) {
+	d, err := dep.NewHealthServiceQuery("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		instances int
+		missing   bool
+		failed    bool
+	}{
+		{
+			"holds",
+			2,
+			false,
+			false,
+		},
+		{
+			"fails",
+			1,
+			false,
+			true,
+		},
+		{
+			"fails_empty",
+			0,
+			false,
+			true,
+		},
+		{
+			"missing_data",
+			-1,
+			true,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tpl, err := NewTemplate(&NewTemplateInput{
+				Contents: `{{ range service "web" }}{{ .Address }}{{ end }}`,
+				Guard:    `ge (len (service "web")) 2`,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			brain := NewBrain()
+			if tc.instances >= 0 {
+				services := make([]*dep.HealthService, 0, tc.instances)
+				for j := 0; j < tc.instances; j++ {
+					services = append(services, &dep.HealthService{
+						Address: fmt.Sprintf("10.0.0.%d", j),
+					})
+				}
+				brain.Remember(d, services)
+			}
+
+			a, err := tpl.Execute(&ExecuteInput{Brain: brain})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.GuardFailed != tc.failed {
+				t.Errorf("expected guard failed to be %t, got %t", tc.failed, a.GuardFailed)
+			}
+			if missing := a.Missing.Len() > 0; missing != tc.missing {
+				t.Errorf("expected missing to be %t, got %t", tc.missing, missing)
+			}
+			if a.Used.Len() != 1 {
+				t.Errorf("expected 1 used dependency, got %d", a.Used.Len())
+			}
+		})
+	}
+}
+
+func TestTemplate_Execute_var(t *testing.T) {
+	d, err := dep.NewFileQuery("/etc/ct/values.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yamlVars := `
+region: us-east-1
+replicas: 3
+db:
+  host: db.internal
+  port: 5432
+servers:
+  - a
+  - b
+`
+
+	cases := []struct {
+		name     string
+		c        string
+		varsFile string
+		vars     *string
+		e        string
+		err      bool
+	}{
+		{
+			"scalar",
+			`{{ var "region" }} {{ var "replicas" }}`,
+			"/etc/ct/values.yaml",
+			&yamlVars,
+			"us-east-1 3",
+			false,
+		},
+		{
+			"nested",
+			`{{ var "db.host" }}:{{ var "db.port" }} {{ (var "db").host }}`,
+			"/etc/ct/values.yaml",
+			&yamlVars,
+			"db.internal:5432 db.internal",
+			false,
+		},
+		{
+			"list",
+			`{{ range var "servers" }}{{ . }}{{ end }}`,
+			"/etc/ct/values.yaml",
+			&yamlVars,
+			"ab",
+			false,
+		},
+		{
+			"json",
+			`{{ var "region" }}`,
+			"/etc/ct/values.yaml",
+			func() *string { s := `{"region": "eu-west-1"}`; return &s }(),
+			"eu-west-1",
+			false,
+		},
+		{
+			"default",
+			`{{ var "zone" "a" }} {{ var "db.user" "admin" }}`,
+			"/etc/ct/values.yaml",
+			&yamlVars,
+			"a admin",
+			false,
+		},
+		{
+			"not_found",
+			`{{ var "zone" }}`,
+			"/etc/ct/values.yaml",
+			&yamlVars,
+			"",
+			true,
+		},
+		{
+			"not_a_map",
+			`{{ var "region" }}`,
+			"/etc/ct/values.yaml",
+			func() *string { s := "- a\n- b\n"; return &s }(),
+			"",
+			true,
+		},
+		{
+			"missing_data",
+			`{{ var "region" }}`,
+			"/etc/ct/values.yaml",
+			nil,
+			"",
+			false,
+		},
+		{
+			"no_vars_file",
+			`{{ var "region" }}`,
+			"",
+			nil,
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tpl, err := NewTemplate(&NewTemplateInput{
+				Contents: tc.c,
+				VarsFile: tc.varsFile,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			brain := NewBrain()
+			if tc.vars != nil {
+				brain.Remember(d, *tc.vars)
+			}
+
+			a, err := tpl.Execute(&ExecuteInput{Brain: brain})
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if tc.err {
+				return
+			}
+			if string(a.Output) != tc.e {
+				t.Errorf("expected %q to be %q", a.Output, tc.e)
+			}
+			if tc.vars == nil && a.Missing.Len() != 1 {
+				t.Errorf("expected the vars file to be missing")
+			}
+		})
+	}
+}
+
+func TestTemplate_debugDump(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: `a{{ debugDump "foo" }}b`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := tpl.Execute(&ExecuteInput{Brain: NewBrain()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a.Output) != "ab" {
+		t.Errorf("expected output to be unchanged, got %q", a.Output)
+	}
+	if !strings.Contains(buf.String(), `(string) (len=3) "foo"`) {
+		t.Errorf("expected value to be logged, got %q", buf.String())
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	names := Functions()
+
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("expected sorted names, got %q", names)
+	}
+	for _, exp := range []string{"key", "secret", "toJSON"} {
+		i := sort.SearchStrings(names, exp)
+		if i == len(names) || names[i] != exp {
+			t.Errorf("expected %q in %q", exp, names)
+		}
+	}
+}
+
+func TestApr1Crypt(t *testing.T) {
+	t.Parallel()
+
+	// The expected hashes are those of `openssl passwd -apr1`.
+	cases := []struct {
+		name string
+		pass string
+		salt string
+		exp  string
+	}{
+		{
+			"short",
+			"password",
+			"abcdefgh",
+			"$apr1$abcdefgh$FBwExRW4dCc8aL.OvjpIE1",
+		},
+		{
+			"long",
+			"a much longer password than sixteen bytes",
+			"Xy12./Zq",
+			"$apr1$Xy12./Zq$GFztnnfQ3V8/A58dRFtIK/",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := apr1Crypt(tc.pass, tc.salt); act != tc.exp {
+				t.Errorf("expected %q to be %q", act, tc.exp)
+			}
+		})
+	}
 }
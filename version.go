@@ -1,6 +1,14 @@
 package main
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/manager"
+	"github.com/hashicorp/consul-template/template"
+)
 
 var (
 	Name      string
@@ -9,3 +17,31 @@ var (
 
 	humanVersion = fmt.Sprintf("%s v%s (%s)", Name, Version, GitCommit)
 )
+
+// versionInfo is the machine-readable version and capability information
+// printed by -version -json, so tooling can detect the features of a binary
+// before shipping configuration to it.
+type versionInfo struct {
+	Name                string   `json:"name"`
+	Version             string   `json:"version"`
+	GitCommit           string   `json:"git_commit"`
+	ConfigSchemaVersion int      `json:"config_schema_version"`
+	Backends            []string `json:"backends"`
+	Destinations        []string `json:"destinations"`
+	Engines             []string `json:"engines"`
+	Functions           []string `json:"functions"`
+}
+
+// jsonVersion returns the version and capabilities of this binary as JSON.
+func jsonVersion() ([]byte, error) {
+	return json.MarshalIndent(&versionInfo{
+		Name:                Name,
+		Version:             Version,
+		GitCommit:           GitCommit,
+		ConfigSchemaVersion: config.SchemaVersion,
+		Backends:            dependency.Backends(),
+		Destinations:        manager.Destinations(),
+		Engines:             template.Engines(),
+		Functions:           template.Functions(),
+	}, "", "  ")
+}
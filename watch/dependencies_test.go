@@ -134,3 +134,88 @@ func (d *TestDepRetry) Stop() {}
 func (d *TestDepRetry) Type() dep.Type {
 	return dep.TypeLocal
 }
+
+// TestDepConsul is a special dependency that reads the agent information from
+// the Consul client, to test how server responses are handled.
+type TestDepConsul struct{}
+
+func (d *TestDepConsul) Fetch(clients *dep.ClientSet, opts *dep.QueryOptions) (interface{}, *dep.ResponseMetadata, error) {
+	data, err := clients.Consul().Agent().Self()
+	if err != nil {
+		return nil, nil, err
+	}
+	rm := &dep.ResponseMetadata{LastIndex: 1}
+	return data, rm, nil
+}
+
+func (d *TestDepConsul) CanShare() bool {
+	return true
+}
+
+func (d *TestDepConsul) String() string {
+	return "test_dep_consul"
+}
+
+func (d *TestDepConsul) Stop() {}
+
+func (d *TestDepConsul) Type() dep.Type {
+	return dep.TypeConsul
+}
+
+// TestDepIndex is a special Consul dependency that returns its data at the
+// given index, to test how index changes are handled.
+type TestDepIndex struct {
+	data  string
+	index uint64
+}
+
+func (d *TestDepIndex) Fetch(clients *dep.ClientSet, opts *dep.QueryOptions) (interface{}, *dep.ResponseMetadata, error) {
+	rm := &dep.ResponseMetadata{LastIndex: d.index}
+	return d.data, rm, nil
+}
+
+func (d *TestDepIndex) CanShare() bool {
+	return true
+}
+
+func (d *TestDepIndex) String() string {
+	return fmt.Sprintf("test_dep_index(%d)", d.index)
+}
+
+func (d *TestDepIndex) Stop() {}
+
+func (d *TestDepIndex) Type() dep.Type {
+	return dep.TypeConsul
+}
+
+// TestDepFlapping is a special Consul dependency whose data changes on every
+// fetch, to test how flapping dependencies are held down.
+type TestDepFlapping struct {
+	sync.Mutex
+	index uint64
+}
+
+func (d *TestDepFlapping) Fetch(clients *dep.ClientSet, opts *dep.QueryOptions) (interface{}, *dep.ResponseMetadata, error) {
+	time.Sleep(10 * time.Millisecond)
+
+	d.Lock()
+	defer d.Unlock()
+
+	d.index++
+	rm := &dep.ResponseMetadata{LastIndex: d.index}
+	return fmt.Sprintf("data %d", d.index), rm, nil
+}
+
+func (d *TestDepFlapping) CanShare() bool {
+	return true
+}
+
+func (d *TestDepFlapping) String() string {
+	return "test_dep_flapping"
+}
+
+func (d *TestDepFlapping) Stop() {}
+
+func (d *TestDepFlapping) Type() dep.Type {
+	return dep.TypeConsul
+}
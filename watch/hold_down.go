@@ -0,0 +1,106 @@
+package watch
+
+import (
+	"sync"
+	"time"
+)
+
+// HoldDown is the hold-down of the views of a class of dependencies. After a
+// view reported a change, its further changes within Min are held, and
+// reported together once the dependency has not changed for Min, or at most
+// Max after the first held change. This limits a flapping dependency to one
+// change per Min without delaying its first change.
+type HoldDown struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// holdDown is the hold-down state of a view.
+type holdDown struct {
+	min time.Duration
+	max time.Duration
+
+	lock sync.Mutex
+
+	// until is the end of the hold-down started by the last reported change,
+	// and first is the time of the first held change, or zero if no change is
+	// held.
+	until time.Time
+	first time.Time
+
+	// timer reports the held changes, and gen identifies it, so a timer which
+	// fired while it was being replaced does not report the changes.
+	timer *time.Timer
+	gen   uint64
+}
+
+// newHoldDown creates the hold-down state of a view.
+func newHoldDown(h *HoldDown) *holdDown {
+	return &holdDown{
+		min: h.Min,
+		max: h.Max,
+	}
+}
+
+// hold returns true if the change must be held, in which case the given
+// function is called to report the held changes once they are released.
+// Otherwise the change is reported by the caller, starting a hold-down.
+func (h *holdDown) hold(report func()) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := time.Now()
+	if h.first.IsZero() {
+		if !now.Before(h.until) {
+			h.until = now.Add(h.min)
+			return false
+		}
+		h.first = now
+	}
+
+	wait := h.min
+	if left := h.first.Add(h.max).Sub(now); h.max > 0 && left < wait {
+		wait = left
+	}
+
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.gen++
+	gen := h.gen
+	h.timer = time.AfterFunc(wait, func() {
+		if h.release(gen) {
+			report()
+		}
+	})
+	return true
+}
+
+// release ends the held changes of the timer with the given generation,
+// starting a new hold-down. It returns false if the timer was replaced.
+func (h *holdDown) release(gen uint64) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if gen != h.gen || h.first.IsZero() {
+		return false
+	}
+
+	h.first = time.Time{}
+	h.until = time.Now().Add(h.min)
+	h.timer = nil
+	return true
+}
+
+// stop discards the held changes.
+func (h *holdDown) stop() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	h.first = time.Time{}
+	h.gen++
+}
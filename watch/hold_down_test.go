@@ -0,0 +1,79 @@
+package watch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHoldDown(t *testing.T) {
+	t.Parallel()
+
+	h := newHoldDown(&HoldDown{
+		Min: 100 * time.Millisecond,
+		Max: 250 * time.Millisecond,
+	})
+	reportCh := make(chan time.Time, 10)
+	report := func() { reportCh <- time.Now() }
+
+	// The first change is reported right away, and the next one is held until
+	// the dependency has not changed for the minimum.
+	if h.hold(report) {
+		t.Fatal("expected the first change not to be held")
+	}
+	start := time.Now()
+	if !h.hold(report) {
+		t.Fatal("expected the second change to be held")
+	}
+	select {
+	case at := <-reportCh:
+		if d := at.Sub(start); d < 90*time.Millisecond {
+			t.Errorf("expected the held change to be reported after the minimum, took %s", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	// Changes which keep coming are reported at most the maximum after the
+	// first held one.
+	start = time.Now()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go func() {
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-time.After(20 * time.Millisecond):
+				h.hold(report)
+			}
+		}
+	}()
+	select {
+	case at := <-reportCh:
+		if d := at.Sub(start); d > 350*time.Millisecond {
+			t.Errorf("expected the held changes to be reported by the maximum, took %s", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestHoldDown_stop(t *testing.T) {
+	t.Parallel()
+
+	h := newHoldDown(&HoldDown{Min: 50 * time.Millisecond})
+	reportCh := make(chan struct{}, 1)
+	report := func() { reportCh <- struct{}{} }
+
+	h.hold(report)
+	if !h.hold(report) {
+		t.Fatal("expected the change to be held")
+	}
+	h.stop()
+
+	select {
+	case <-reportCh:
+		t.Errorf("expected the held change to be discarded")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,79 @@
+package watch
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// defaultIndexRegressionWindow is how long the regressions of the Consul index
+// seen by the views of a watcher are collected into a single event, since
+// the views see a restore of Consul from a snapshot within moments of each
+// other.
+const defaultIndexRegressionWindow = 1 * time.Second
+
+// indexRegression is shared by the Consul views of a watcher to report the
+// Consul index going backwards, because Consul was restored from a snapshot
+// or its index was reset, as a single event instead of a log line per view.
+type indexRegression struct {
+	// window is how long regressions are collected after the first one.
+	window time.Duration
+
+	// logf logs the event.
+	logf func(string, ...interface{})
+
+	lock sync.Mutex
+
+	// timer logs the event of the current episode, which is nil if no
+	// regression is being collected. observed is the highest index seen before
+	// the regression, received the lowest index received, and views the views
+	// which saw it.
+	timer    *time.Timer
+	observed uint64
+	received uint64
+	views    map[string]struct{}
+}
+
+// newIndexRegression returns an index regression which collects regressions
+// for the given window.
+func newIndexRegression(window time.Duration) *indexRegression {
+	return &indexRegression{
+		window: window,
+		logf:   log.Printf,
+	}
+}
+
+// report records that the given view received an index lower than the one it
+// observed, starting an episode if none is in progress.
+func (r *indexRegression) report(view string, observed, received uint64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.timer == nil {
+		r.observed, r.received = observed, received
+		r.views = make(map[string]struct{})
+		r.timer = time.AfterFunc(r.window, r.flush)
+	}
+	if observed > r.observed {
+		r.observed = observed
+	}
+	if received < r.received {
+		r.received = received
+	}
+	r.views[view] = struct{}{}
+}
+
+// flush logs the event of the current episode and ends it.
+func (r *indexRegression) flush() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.timer == nil {
+		return
+	}
+	r.timer = nil
+
+	r.logf("[WARN] (watcher) consul index went backwards, resetting watches "+
+		"(observed_index=%d received_index=%d views=%d)",
+		r.observed, r.received, len(r.views))
+}
@@ -0,0 +1,43 @@
+package watch
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIndexRegression(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	var events []string
+	r := newIndexRegression(50 * time.Millisecond)
+	r.logf = func(format string, args ...interface{}) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, fmt.Sprintf(format, args...))
+	}
+
+	// The regressions of the views within the window are a single event.
+	r.report("a", 10, 3)
+	r.report("b", 12, 4)
+	r.report("a", 10, 2)
+	time.Sleep(200 * time.Millisecond)
+
+	// A later regression is another episode.
+	r.report("c", 5, 1)
+	time.Sleep(200 * time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	exp := []string{
+		"[WARN] (watcher) consul index went backwards, resetting watches " +
+			"(observed_index=12 received_index=2 views=2)",
+		"[WARN] (watcher) consul index went backwards, resetting watches " +
+			"(observed_index=5 received_index=1 views=1)",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(exp) {
+		t.Errorf("\nexp: %q\nact: %q", exp, events)
+	}
+}
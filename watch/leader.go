@@ -0,0 +1,93 @@
+package watch
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLeaderPause is how long the Consul views of a watcher pause when the
+// Consul cluster has no leader, before they retry.
+const defaultLeaderPause = 2 * time.Second
+
+// leaderErrors are the messages of the errors Consul returns while it has no
+// leader, lowercased. They are returned with a 500 status code.
+var leaderErrors = []string{
+	"no cluster leader",
+	"leadership lost",
+	"node is not the leader",
+}
+
+// isLeaderError returns true if the error was caused by the Consul cluster
+// having no leader, which is usually resolved by an election within seconds.
+func isLeaderError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, e := range leaderErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// leaderElection is shared by the Consul views of a watcher to pause them
+// together while the Consul cluster elects a leader. The election is logged
+// once when it is detected and once when it is over, instead of each view
+// logging its retries.
+type leaderElection struct {
+	// pause is how long views pause after a leader error.
+	pause time.Duration
+
+	lock sync.Mutex
+
+	// electing is true from the first leader error until a view fetches data
+	// after the pause, since is when it started, and until is the end of the
+	// current pause.
+	electing bool
+	since    time.Time
+	until    time.Time
+}
+
+// newLeaderElection returns a leader election which pauses views for the given
+// duration.
+func newLeaderElection(pause time.Duration) *leaderElection {
+	return &leaderElection{pause: pause}
+}
+
+// wait returns how long a view which received the leader error must wait
+// before it retries, starting a pause if none is in progress.
+func (l *leaderElection) wait(err error) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if !l.electing {
+		l.electing = true
+		l.since = now
+		log.Printf("[WARN] (watcher) consul has no cluster leader, pausing consul "+
+			"watches until one is elected: %s", err)
+	}
+	if !now.Before(l.until) {
+		l.until = now.Add(l.pause)
+	}
+	return l.until.Sub(now)
+}
+
+// resolve ends the election after a view fetched data once the pause is over.
+func (l *leaderElection) resolve() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !l.electing || time.Now().Before(l.until) {
+		return
+	}
+
+	l.electing = false
+	log.Printf("[INFO] (watcher) consul cluster leader elected after %s, "+
+		"resuming consul watches", time.Since(l.since))
+}
@@ -0,0 +1,79 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIsLeaderError(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		err  error
+		exp  bool
+	}{
+		{
+			"nil",
+			nil,
+			false,
+		},
+		{
+			"no_cluster_leader",
+			errors.New("Unexpected response code: 500 (No cluster leader)"),
+			true,
+		},
+		{
+			"leadership_lost",
+			errors.New("Unexpected response code: 500 (leadership lost while committing log)"),
+			true,
+		},
+		{
+			"not_the_leader",
+			errors.New("Unexpected response code: 500 (node is not the leader)"),
+			true,
+		},
+		{
+			"other",
+			errors.New("Unexpected response code: 500 (rpc error)"),
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := isLeaderError(tc.err); act != tc.exp {
+				t.Errorf("expected %t to be %t", act, tc.exp)
+			}
+		})
+	}
+}
+
+func TestLeaderElection_wait(t *testing.T) {
+	t.Parallel()
+
+	l := newLeaderElection(100 * time.Millisecond)
+	err := errors.New("No cluster leader")
+
+	// Views which receive the error during a pause wait for its end.
+	if w := l.wait(err); w != 100*time.Millisecond {
+		t.Errorf("expected the first wait to be the pause, got %s", w)
+	}
+	if w := l.wait(err); w <= 0 || w > 100*time.Millisecond {
+		t.Errorf("expected the wait to end with the pause, got %s", w)
+	}
+
+	// The election is not resolved during the pause.
+	l.resolve()
+	if !l.electing {
+		t.Errorf("expected the election to continue during the pause")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	l.resolve()
+	if l.electing {
+		t.Errorf("expected the election to be resolved after the pause")
+	}
+}
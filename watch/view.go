@@ -5,6 +5,7 @@ import (
 	"log"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	dep "github.com/hashicorp/consul-template/dependency"
@@ -42,6 +43,24 @@ type View struct {
 	// should be attempted.
 	retryFunc RetryFunc
 
+	// fetchErrors is incremented each time fetching returns an error, if set.
+	fetchErrors *uint64
+
+	// leader, if set, is shared by the Consul views of a watcher to pause them
+	// together while the Consul cluster has no leader.
+	leader *leaderElection
+
+	// regression, if set, is shared by the Consul views of a watcher to report
+	// the Consul index going backwards once for all of them.
+	regression *indexRegression
+
+	// fakeData, if set, is returned instead of fetching from the upstream.
+	fakeData *dep.FakeData
+
+	// holdDown, if set, holds the changes of this view after one was
+	// reported.
+	holdDown *holdDown
+
 	// stopCh is used to stop polling on this View
 	stopCh chan struct{}
 }
@@ -62,21 +81,45 @@ type NewViewInput struct {
 	// Once indicates this view should poll for data exactly one time.
 	Once bool
 
+	// LastIndex is the index from which the first blocking query should start.
+	// This is used to resume a watch from a previous process without fetching
+	// data that has not changed.
+	LastIndex uint64
+
 	// RetryFunc is a function which dictates how this view should retry on
 	// upstream errors.
 	RetryFunc RetryFunc
+
+	// FetchErrors is an optional counter which is incremented each time
+	// fetching returns an error, whether or not it is retried.
+	FetchErrors *uint64
+
+	// FakeData is optional data which is returned instead of fetching from the
+	// upstream.
+	FakeData *dep.FakeData
+
+	// HoldDown is the optional hold-down of the changes of this view. It is
+	// ignored in once mode.
+	HoldDown *HoldDown
 }
 
 // NewView constructs a new view with the given inputs.
 func NewView(i *NewViewInput) (*View, error) {
-	return &View{
-		dependency: i.Dependency,
-		clients:    i.Clients,
-		maxStale:   i.MaxStale,
-		once:       i.Once,
-		retryFunc:  i.RetryFunc,
-		stopCh:     make(chan struct{}, 1),
-	}, nil
+	v := &View{
+		dependency:  i.Dependency,
+		clients:     i.Clients,
+		lastIndex:   i.LastIndex,
+		maxStale:    i.MaxStale,
+		once:        i.Once,
+		retryFunc:   i.RetryFunc,
+		fetchErrors: i.FetchErrors,
+		fakeData:    i.FakeData,
+		stopCh:      make(chan struct{}, 1),
+	}
+	if i.HoldDown != nil && i.HoldDown.Min > 0 && !i.Once {
+		v.holdDown = newHoldDown(i.HoldDown)
+	}
+	return v, nil
 }
 
 // Dependency returns the dependency attached to this View.
@@ -117,6 +160,13 @@ func (v *View) poll(viewCh chan<- *View, errCh chan<- error) {
 			// have some successful requests
 			retries = 0
 
+			// If a change was reported recently, hold this one until the
+			// hold-down timer reports it.
+			if v.holdDown != nil && v.holdDown.hold(func() { v.report(viewCh) }) {
+				log.Printf("[TRACE] (view) %s holding down change", v.dependency)
+				continue
+			}
+
 			log.Printf("[TRACE] (view) %s received data", v.dependency)
 			select {
 			case <-v.stopCh:
@@ -130,9 +180,47 @@ func (v *View) poll(viewCh chan<- *View, errCh chan<- error) {
 				return
 			}
 		case err := <-fetchErrCh:
+			if v.fetchErrors != nil {
+				atomic.AddUint64(v.fetchErrors, 1)
+			}
+
+			// During a leader election the views pause together, and the
+			// election is logged once instead of each retry. The pauses are not
+			// retry attempts, so an election does not exhaust the retries, except
+			// in once mode, where a cluster without a leader must not block forever.
+			if v.leaderElection(err) {
+				pause := true
+				if v.once && v.retryFunc != nil {
+					pause, _ = v.retryFunc(retries)
+				}
+				if pause {
+					sleep := v.leader.wait(err)
+					log.Printf("[TRACE] (view) %s (pausing for leader election for %q)",
+						err, sleep)
+					select {
+					case <-time.After(sleep):
+						if v.once {
+							retries++
+						}
+						continue
+					case <-v.stopCh:
+						return
+					}
+				}
+			}
+
 			if v.retryFunc != nil {
 				retry, sleep := v.retryFunc(retries)
 				if retry {
+					// Wait at least as long as the server asked if it is throttling
+					// requests, since retrying sooner only prolongs the throttling.
+					// The clients shorten the delays to the max backoff.
+					if v.clients != nil {
+						if wait := v.clients.RetryAfter(v.dependency.Type()); wait > sleep {
+							sleep = wait
+						}
+					}
+
 					log.Printf("[WARN] (view) %s (retry attempt %d after %q)",
 						err, retries+1, sleep)
 					select {
@@ -169,6 +257,11 @@ func (v *View) poll(viewCh chan<- *View, errCh chan<- error) {
 func (v *View) fetch(doneCh chan<- struct{}, errCh chan<- error) {
 	log.Printf("[TRACE] (view) %s starting fetch", v.dependency)
 
+	if v.fakeData != nil && v.fakeData.Fakes(v.dependency) {
+		v.fetchFake(doneCh, errCh)
+		return
+	}
+
 	var allowStale bool
 	if v.maxStale != 0 {
 		allowStale = true
@@ -197,6 +290,10 @@ func (v *View) fetch(doneCh chan<- struct{}, errCh chan<- error) {
 			return
 		}
 
+		if v.leader != nil && v.dependency.Type() == dep.TypeConsul {
+			v.leader.resolve()
+		}
+
 		if rm == nil {
 			errCh <- fmt.Errorf("received nil response metadata - this is a bug " +
 				"and should be reported")
@@ -213,6 +310,12 @@ func (v *View) fetch(doneCh chan<- struct{}, errCh chan<- error) {
 			allowStale = true
 		}
 
+		// An index of 0 would make the next query return immediately, so it is
+		// treated as 1, as recommended by Consul.
+		if rm.LastIndex == 0 && v.dependency.Type() == dep.TypeConsul {
+			rm.LastIndex = 1
+		}
+
 		if rm.LastIndex == v.lastIndex {
 			log.Printf("[TRACE] (view) %s no new data (index was the same)", v.dependency)
 			continue
@@ -220,10 +323,14 @@ func (v *View) fetch(doneCh chan<- struct{}, errCh chan<- error) {
 
 		v.dataLock.Lock()
 		if rm.LastIndex < v.lastIndex {
-			log.Printf("[TRACE] (view) %s had a lower index, resetting", v.dependency)
-			v.lastIndex = 0
-			v.dataLock.Unlock()
-			continue
+			// The index went backwards, because Consul was restored from a
+			// snapshot or its index was reset. The response is the current
+			// state, so the view restarts from its index instead of refetching.
+			log.Printf("[TRACE] (view) %s index went backwards (observed %d, "+
+				"received %d), resetting watch", v.dependency, v.lastIndex, rm.LastIndex)
+			if v.regression != nil {
+				v.regression.report(v.dependency.String(), v.lastIndex, rm.LastIndex)
+			}
 		}
 		v.lastIndex = rm.LastIndex
 
@@ -248,8 +355,52 @@ func (v *View) fetch(doneCh chan<- struct{}, errCh chan<- error) {
 	}
 }
 
+// fetchFake sets the fake data of the dependency instead of fetching it. Fake
+// data never changes, so once it is set, fetching blocks until the view is
+// stopped.
+func (v *View) fetchFake(doneCh chan<- struct{}, errCh chan<- error) {
+	v.dataLock.Lock()
+	if v.receivedData {
+		v.dataLock.Unlock()
+		<-v.stopCh
+		return
+	}
+
+	data, err := v.fakeData.Fetch(v.dependency)
+	if err != nil {
+		v.dataLock.Unlock()
+		errCh <- err
+		return
+	}
+	v.data = data
+	v.lastIndex = 1
+	v.receivedData = true
+	v.dataLock.Unlock()
+
+	close(doneCh)
+}
+
+// leaderElection returns true if the error was returned by Consul because it
+// has no leader, and the view pauses with the others of its watcher.
+func (v *View) leaderElection(err error) bool {
+	return v.leader != nil && v.dependency.Type() == dep.TypeConsul && isLeaderError(err)
+}
+
+// report sends the view on the channel to report its held changes, unless the
+// view was stopped.
+func (v *View) report(viewCh chan<- *View) {
+	log.Printf("[TRACE] (view) %s releasing held changes", v.dependency)
+	select {
+	case <-v.stopCh:
+	case viewCh <- v:
+	}
+}
+
 // stop halts polling of this view.
 func (v *View) stop() {
+	if v.holdDown != nil {
+		v.holdDown.stop()
+	}
 	v.dependency.Stop()
 	close(v.stopCh)
 }
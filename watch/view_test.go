@@ -1,9 +1,16 @@
 package watch
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	dep "github.com/hashicorp/consul-template/dependency"
 )
 
 func TestPoll_returnsViewCh(t *testing.T) {
@@ -151,6 +158,60 @@ func TestPoll_retries(t *testing.T) {
 	}
 }
 
+func TestPoll_retryAfter(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	clients := dep.NewClientSet()
+	if err := clients.CreateConsulClient(&dep.CreateConsulClientInput{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	view, err := NewView(&NewViewInput{
+		Dependency: &TestDepConsul{},
+		Clients:    clients,
+		RetryFunc: func(retry int) (bool, time.Duration) {
+			return retry < 1, 10 * time.Millisecond
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viewCh := make(chan *View)
+	errCh := make(chan error)
+
+	go view.poll(viewCh, errCh)
+	defer view.stop()
+
+	select {
+	case <-viewCh:
+		t.Errorf("should have waited for the Retry-After delay")
+	case err := <-errCh:
+		t.Fatalf("error while polling: %s", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	select {
+	case <-viewCh:
+		// Got this far, so the test passes
+	case err := <-errCh:
+		t.Errorf("error while polling: %s", err)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout")
+	}
+}
+
 func TestFetch_maxStale(t *testing.T) {
 	view, err := NewView(&NewViewInput{
 		Dependency: &TestDepStale{},
@@ -224,6 +285,80 @@ func TestFetch_returnsErrCh(t *testing.T) {
 	}
 }
 
+func TestFetch_indexRegression(t *testing.T) {
+	view, err := NewView(&NewViewInput{
+		Dependency: &TestDepIndex{data: "restored data", index: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The index Consul returned before it was restored from a snapshot.
+	view.lastIndex = 10
+	view.data = "this is some data"
+	view.receivedData = true
+
+	reported := make(chan string, 1)
+	view.regression = newIndexRegression(time.Millisecond)
+	view.regression.logf = func(format string, args ...interface{}) {
+		reported <- fmt.Sprintf(format, args...)
+	}
+
+	doneCh := make(chan struct{})
+	errCh := make(chan error)
+
+	go view.fetch(doneCh, errCh)
+
+	select {
+	case <-doneCh:
+		expected := "restored data"
+		if !reflect.DeepEqual(view.Data(), expected) {
+			t.Errorf("expected %q to be %q", view.Data(), expected)
+		}
+		if view.lastIndex != 3 {
+			t.Errorf("expected index %d to be reset to 3", view.lastIndex)
+		}
+	case err := <-errCh:
+		t.Errorf("error while fetching: %s", err)
+	case <-time.After(time.Second):
+		t.Fatalf("timeout")
+	}
+
+	select {
+	case event := <-reported:
+		if !strings.Contains(event, "observed_index=10 received_index=3 views=1") {
+			t.Errorf("unexpected event %q", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the regression to be reported")
+	}
+}
+
+func TestFetch_zeroIndex(t *testing.T) {
+	view, err := NewView(&NewViewInput{
+		Dependency: &TestDepIndex{data: "this is some data", index: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doneCh := make(chan struct{})
+	errCh := make(chan error)
+
+	go view.fetch(doneCh, errCh)
+
+	select {
+	case <-doneCh:
+		if view.lastIndex != 1 {
+			t.Errorf("expected index %d to be 1", view.lastIndex)
+		}
+	case err := <-errCh:
+		t.Errorf("error while fetching: %s", err)
+	case <-time.After(time.Second):
+		t.Fatalf("timeout")
+	}
+}
+
 func TestStop_stopsPolling(t *testing.T) {
 	view, err := NewView(&NewViewInput{
 		Dependency: &TestDep{},
@@ -247,3 +382,154 @@ func TestStop_stopsPolling(t *testing.T) {
 		// Successfully stopped
 	}
 }
+
+func TestPoll_leaderElection(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("No cluster leader"))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	clients := dep.NewClientSet()
+	if err := clients.CreateConsulClient(&dep.CreateConsulClientInput{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both views pause together for the election, instead of retrying after
+	// their own sleep, and the pauses do not count as retry attempts.
+	leader := newLeaderElection(300 * time.Millisecond)
+	viewCh := make(chan *View)
+	errCh := make(chan error)
+	for i := 0; i < 2; i++ {
+		view, err := NewView(&NewViewInput{
+			Dependency: &TestDepConsul{},
+			Clients:    clients,
+			RetryFunc: func(retry int) (bool, time.Duration) {
+				return false, 0
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		view.leader = leader
+
+		go view.poll(viewCh, errCh)
+		defer view.stop()
+	}
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-viewCh:
+			if d := time.Since(start); d < 250*time.Millisecond {
+				t.Errorf("expected the views to pause during the election, took %s", d)
+			}
+		case err := <-errCh:
+			t.Fatalf("error while polling: %s", err)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout")
+		}
+	}
+
+	leader.lock.Lock()
+	defer leader.lock.Unlock()
+	if leader.electing {
+		t.Errorf("expected the election to be resolved")
+	}
+}
+
+func TestPoll_leaderElectionOnce(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("No cluster leader"))
+	}))
+	defer ts.Close()
+
+	clients := dep.NewClientSet()
+	if err := clients.CreateConsulClient(&dep.CreateConsulClientInput{
+		Address: strings.TrimPrefix(ts.URL, "http://"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// In once mode the pauses count as retry attempts, so the view gives up
+	// when the cluster never elects a leader.
+	var attempts int32
+	view, err := NewView(&NewViewInput{
+		Dependency: &TestDepConsul{},
+		Clients:    clients,
+		Once:       true,
+		RetryFunc: func(retry int) (bool, time.Duration) {
+			atomic.AddInt32(&attempts, 1)
+			return retry < 2, 0
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	view.leader = newLeaderElection(50 * time.Millisecond)
+
+	viewCh := make(chan *View)
+	errCh := make(chan error)
+	go view.poll(viewCh, errCh)
+	defer view.stop()
+
+	select {
+	case <-viewCh:
+		t.Fatal("expected no data")
+	case err := <-errCh:
+		if !strings.Contains(err.Error(), "No cluster leader") {
+			t.Errorf("expected the leader error, got %q", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the view to exhaust its retries")
+	}
+
+	if n := atomic.LoadInt32(&attempts); n < 3 {
+		t.Errorf("expected the pauses to count as retries, got %d attempts", n)
+	}
+}
+
+func TestPoll_holdDown(t *testing.T) {
+	view, err := NewView(&NewViewInput{
+		Dependency: &TestDepFlapping{},
+		HoldDown: &HoldDown{
+			Min: 100 * time.Millisecond,
+			Max: 200 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viewCh := make(chan *View)
+	errCh := make(chan error)
+
+	go view.poll(viewCh, errCh)
+	defer view.stop()
+
+	// The dependency changes every 10ms, but only its first change and then
+	// its held changes after each maximum are reported.
+	var reports int
+	timeout := time.After(500 * time.Millisecond)
+	for done := false; !done; {
+		select {
+		case <-viewCh:
+			reports++
+		case err := <-errCh:
+			t.Fatalf("error while polling: %s", err)
+		case <-timeout:
+			done = true
+		}
+	}
+	if reports < 2 || reports > 4 {
+		t.Errorf("expected 2 to 4 reports, got %d", reports)
+	}
+}
@@ -3,6 +3,7 @@ package watch
 import (
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	dep "github.com/hashicorp/consul-template/dependency"
@@ -16,6 +17,11 @@ type RetryFunc func(int) (bool, time.Duration)
 
 // Watcher is a top-level manager for views that poll Consul for data.
 type Watcher struct {
+	// fetchErrors is the number of errors returned by upstreams to the views
+	// of this watcher, including those which were retried. It is first in the
+	// struct so it is aligned for atomic operations on 32-bit platforms.
+	fetchErrors uint64
+
 	sync.Mutex
 
 	// clients is the collection of API clients to talk to upstreams.
@@ -38,10 +44,31 @@ type Watcher struct {
 	// one time intead of polling infinitely.
 	once bool
 
+	// lastIndexes is the map of dependency strings to the index new views for
+	// that dependency should resume blocking from. This is populated from a
+	// snapshot of a previous run.
+	lastIndexes map[string]uint64
+
 	// retryFuncs specifies the different ways to retry based on the upstream.
 	retryFuncConsul  RetryFunc
 	retryFuncDefault RetryFunc
 	retryFuncVault   RetryFunc
+
+	// leader pauses the Consul views together while the Consul cluster has no
+	// leader.
+	leader *leaderElection
+
+	// regression reports the Consul index going backwards once for all of the
+	// Consul views.
+	regression *indexRegression
+
+	// fakeData is returned by the views instead of fetching from upstreams, if
+	// set.
+	fakeData *dep.FakeData
+
+	// holdDowns specify the hold-down of the views based on the upstream.
+	holdDownConsul *HoldDown
+	holdDownVault  *HoldDown
 }
 
 type NewWatcherInput struct {
@@ -54,13 +81,26 @@ type NewWatcherInput struct {
 	// Once specifies this watcher should tell views to poll exactly once.
 	Once bool
 
+	// LastIndexes is an optional map of dependency strings to the index from
+	// which to resume blocking queries, usually restored from a snapshot.
+	LastIndexes map[string]uint64
+
 	// RenewVault indicates if this watcher should renew Vault tokens.
 	RenewVault bool
 
+	// FakeData is optional data for the views to return instead of fetching
+	// from upstreams. Errors are not retried, since fake data never changes.
+	FakeData *dep.FakeData
+
 	// RetryFuncs specify the different ways to retry based on the upstream.
 	RetryFuncConsul  RetryFunc
 	RetryFuncDefault RetryFunc
 	RetryFuncVault   RetryFunc
+
+	// HoldDowns specify the optional hold-down of the views based on the
+	// upstream.
+	HoldDownConsul *HoldDown
+	HoldDownVault  *HoldDown
 }
 
 // NewWatcher creates a new watcher using the given API client.
@@ -72,9 +112,15 @@ func NewWatcher(i *NewWatcherInput) (*Watcher, error) {
 		errCh:            make(chan error),
 		maxStale:         i.MaxStale,
 		once:             i.Once,
+		lastIndexes:      i.LastIndexes,
 		retryFuncConsul:  i.RetryFuncConsul,
 		retryFuncDefault: i.RetryFuncDefault,
 		retryFuncVault:   i.RetryFuncVault,
+		leader:           newLeaderElection(defaultLeaderPause),
+		regression:       newIndexRegression(defaultIndexRegressionWindow),
+		fakeData:         i.FakeData,
+		holdDownConsul:   i.HoldDownConsul,
+		holdDownVault:    i.HoldDownVault,
 	}
 
 	// Start a watcher for the Vault renew if that config was specified
@@ -121,27 +167,42 @@ func (w *Watcher) Add(d dep.Dependency) (bool, error) {
 		return false, nil
 	}
 
-	// Choose the correct retry function based off of the dependency's type.
+	// Choose the correct retry function and hold-down based off of the
+	// dependency's type.
 	var retryFunc RetryFunc
+	var holdDown *HoldDown
 	switch d.Type() {
 	case dep.TypeConsul:
 		retryFunc = w.retryFuncConsul
+		holdDown = w.holdDownConsul
 	case dep.TypeVault:
 		retryFunc = w.retryFuncVault
+		holdDown = w.holdDownVault
 	default:
 		retryFunc = w.retryFuncDefault
 	}
+	if w.fakeData != nil && w.fakeData.Fakes(d) {
+		retryFunc = nil
+	}
 
 	v, err := NewView(&NewViewInput{
-		Dependency: d,
-		Clients:    w.clients,
-		MaxStale:   w.maxStale,
-		Once:       w.once,
-		RetryFunc:  retryFunc,
+		Dependency:  d,
+		Clients:     w.clients,
+		MaxStale:    w.maxStale,
+		Once:        w.once,
+		LastIndex:   w.lastIndexes[d.String()],
+		RetryFunc:   retryFunc,
+		FetchErrors: &w.fetchErrors,
+		FakeData:    w.fakeData,
+		HoldDown:    holdDown,
 	})
 	if err != nil {
 		return false, errors.Wrap(err, "watcher")
 	}
+	v.leader = w.leader
+	if d.Type() == dep.TypeConsul {
+		v.regression = w.regression
+	}
 
 	log.Printf("[TRACE] (watcher) %s starting", d)
 
@@ -151,6 +212,26 @@ func (w *Watcher) Add(d dep.Dependency) (bool, error) {
 	return true, nil
 }
 
+// LastIndex returns the last index seen by the view for the given dependency.
+// If the dependency is not being watched, 0 is returned.
+func (w *Watcher) LastIndex(d dep.Dependency) uint64 {
+	w.Lock()
+	defer w.Unlock()
+
+	view, ok := w.depViewMap[d.String()]
+	if !ok || view == nil {
+		return 0
+	}
+	_, index := view.DataAndLastIndex()
+	return index
+}
+
+// FetchErrors returns the total number of errors returned by upstreams to this
+// watcher's views, including errors which were retried.
+func (w *Watcher) FetchErrors() uint64 {
+	return atomic.LoadUint64(&w.fetchErrors)
+}
+
 // Watching determines if the given dependency is being watched.
 func (w *Watcher) Watching(d dep.Dependency) bool {
 	w.Lock()
@@ -3,6 +3,7 @@ package watch
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	dep "github.com/hashicorp/consul-template/dependency"
 )
@@ -109,6 +110,48 @@ func TestWatching_exists(t *testing.T) {
 	}
 }
 
+func TestLastIndex_notExists(t *testing.T) {
+	w, err := NewWatcher(&NewWatcherInput{
+		Clients: dep.NewClientSet(),
+		Once:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if index := w.LastIndex(&TestDep{}); index != 0 {
+		t.Errorf("expected 0, got %d", index)
+	}
+}
+
+func TestLastIndex_restored(t *testing.T) {
+	d := &TestDep{}
+
+	w, err := NewWatcher(&NewWatcherInput{
+		Clients: dep.NewClientSet(),
+		Once:    true,
+		LastIndexes: map[string]uint64{
+			d.String(): 10,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.depViewMap[d.String()], err = NewView(&NewViewInput{
+		Dependency: d,
+		Clients:    w.clients,
+		LastIndex:  w.lastIndexes[d.String()],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if index := w.LastIndex(d); index != 10 {
+		t.Errorf("expected 10, got %d", index)
+	}
+}
+
 func TestRemove_exists(t *testing.T) {
 	w, err := NewWatcher(&NewWatcherInput{
 		Clients: dep.NewClientSet(),
@@ -182,3 +225,30 @@ func TestSize_returnsNumViews(t *testing.T) {
 		t.Errorf("expected %d to be %d", w.Size(), 10)
 	}
 }
+
+func TestFetchErrors(t *testing.T) {
+	w, err := NewWatcher(&NewWatcherInput{
+		Clients: dep.NewClientSet(),
+		RetryFuncDefault: func(retry int) (bool, time.Duration) {
+			return retry < 2, 10 * time.Millisecond
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if _, err := w.Add(&TestDepFetchError{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-w.ErrCh():
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	if n := w.FetchErrors(); n != 3 {
+		t.Errorf("expected 3 fetch errors, got %d", n)
+	}
+}